@@ -1,18 +1,69 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/internal/cmdutil"
+	"github.com/fredericrous/homelab/bootstrap/internal/etcd"
 	"github.com/fredericrous/homelab/bootstrap/internal/homelab"
+	"github.com/fredericrous/homelab/bootstrap/internal/mesh"
 	"github.com/fredericrous/homelab/bootstrap/internal/nas"
+	"github.com/fredericrous/homelab/bootstrap/internal/server"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/audit"
+	"github.com/fredericrous/homelab/bootstrap/pkg/backup"
+	"github.com/fredericrous/homelab/bootstrap/pkg/baseline"
 	bootstrapPkg "github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/certmanager"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/credentials"
+	"github.com/fredericrous/homelab/bootstrap/pkg/dashboard"
 	"github.com/fredericrous/homelab/bootstrap/pkg/destroy"
+	"github.com/fredericrous/homelab/bootstrap/pkg/diff"
+	"github.com/fredericrous/homelab/bootstrap/pkg/drift"
+	"github.com/fredericrous/homelab/bootstrap/pkg/externalsecrets"
+	"github.com/fredericrous/homelab/bootstrap/pkg/falco"
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/health"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
+	"github.com/fredericrous/homelab/bootstrap/pkg/jobs"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/kubeconfig"
 	"github.com/fredericrous/homelab/bootstrap/pkg/logger"
+	"github.com/fredericrous/homelab/bootstrap/pkg/observability"
+	"github.com/fredericrous/homelab/bootstrap/pkg/output"
+	"github.com/fredericrous/homelab/bootstrap/pkg/policy"
+	"github.com/fredericrous/homelab/bootstrap/pkg/prereq"
 	"github.com/fredericrous/homelab/bootstrap/pkg/recovery"
+	"github.com/fredericrous/homelab/bootstrap/pkg/schedule"
+	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
+	"github.com/fredericrous/homelab/bootstrap/pkg/security"
+	"github.com/fredericrous/homelab/bootstrap/pkg/snapshot"
+	"github.com/fredericrous/homelab/bootstrap/pkg/tui"
+	"github.com/fredericrous/homelab/bootstrap/pkg/update"
+	"github.com/fredericrous/homelab/bootstrap/pkg/upgrade"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Build information, injected with -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = "1.0.0"
+	commit  = "dev"
 )
 
 func main() {
@@ -44,9 +95,15 @@ homelab Kubernetes clusters (with Talos, Cilium, FluxCD) and NAS clusters
 	// Add global flags
 	rootCmd.PersistentFlags().Bool("verbose", false, "Enable verbose logging")
 	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().Bool("read-only", os.Getenv("BOOTSTRAP_READ_ONLY") == "true", "Block every request that would change cluster state (env: BOOTSTRAP_READ_ONLY)")
+	rootCmd.PersistentFlags().StringP("output", "o", string(output.FormatTable), "Result format: table, json or yaml (logs go to stderr)")
+	rootCmd.PersistentFlags().Bool("air-gapped", os.Getenv("BOOTSTRAP_AIR_GAPPED") == "true", "Install Flux and charts from the air-gap bundle and refuse internet downloads (env: BOOTSTRAP_AIR_GAPPED)")
+	rootCmd.PersistentFlags().String("profile", os.Getenv(config.ProfileEnv), "Config profile merged over the base config, e.g. lab for configs/homelab.lab.yaml (env: "+config.ProfileEnv+")")
+	rootCmd.PersistentFlags().Bool("audit-configmap", os.Getenv("BOOTSTRAP_AUDIT_CONFIGMAP") == "true", "Also keep the audit trail of each run in the "+audit.ConfigMapNamespace+"/"+audit.ConfigMapName+" ConfigMap of the changed cluster (env: BOOTSTRAP_AUDIT_CONFIGMAP)")
+	cmdutil.AddClusterFlags(rootCmd)
 
 	// Setup logging level based on flags
-	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
 			log.SetLevel(log.DebugLevel)
 		}
@@ -54,6 +111,44 @@ homelab Kubernetes clusters (with Talos, Cilium, FluxCD) and NAS clusters
 			log.SetLevel(log.DebugLevel)
 			log.SetReportCaller(true)
 		}
+		if readOnly, _ := cmd.Flags().GetBool("read-only"); readOnly {
+			k8s.SetReadOnly(true)
+			log.Debug("Read-only mode enabled, mutating requests will be refused")
+		}
+		clientOptions, err := cmdutil.ClientOptionsFromCommand(cmd, version)
+		if err != nil {
+			return err
+		}
+		k8s.SetClientOptions(clientOptions)
+		if clientOptions.Impersonating() {
+			log.Debug("Impersonating on every API request", "as", clientOptions.As, "groups", clientOptions.AsGroups)
+		}
+		if airGapped, _ := cmd.Flags().GetBool("air-gapped"); airGapped {
+			airgap.SetEnabled(true)
+			log.Debug("Air-gapped mode enabled, Flux and charts come from the bundle")
+		}
+		if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+			config.SetProfile(profile)
+			log.Debug("Config profile selected", "profile", profile)
+		}
+		if projectRoot, err := bootstrapPkg.ProjectRoot(); err == nil {
+			if recorder, err := audit.Open(projectRoot, cmd.CommandPath()); err != nil {
+				log.Warn("Failed to open the audit trail", "error", err)
+			} else {
+				audit.SetDefault(recorder)
+			}
+		}
+		if mirror, _ := cmd.Flags().GetBool("audit-configmap"); mirror {
+			audit.SetMirror(true)
+		}
+		outputFlag, _ := cmd.Flags().GetString("output")
+		format, err := output.ParseFormat(outputFlag)
+		if err != nil {
+			return err
+		}
+		output.SetFormat(format)
+		cmd.SetContext(cmdutil.WithOverrides(cmd.Context(), cmdutil.FromCommand(cmd)))
+		return nil
 	}
 
 	// Create homelab subcommand
@@ -62,7 +157,6 @@ homelab Kubernetes clusters (with Talos, Cilium, FluxCD) and NAS clusters
 		Short: "Homelab cluster operations",
 		Long:  "Bootstrap and manage homelab Kubernetes clusters with Talos, Cilium, and FluxCD",
 	}
-	addClusterFlags(homelabCmd)
 
 	// Add homelab subcommands
 	homelabCmd.AddCommand(homelab.NewBootstrapCommand())
@@ -73,8 +167,18 @@ homelab Kubernetes clusters (with Talos, Cilium, FluxCD) and NAS clusters
 	homelabCmd.AddCommand(homelab.NewUpCommand())
 	homelabCmd.AddCommand(homelab.NewInstallCiliumCommand())
 	homelabCmd.AddCommand(homelab.NewSyncSecretsCommand())
+	homelabCmd.AddCommand(homelab.NewSyncCommand())
+	homelabCmd.AddCommand(homelab.NewAdoptCommand())
+	homelabCmd.AddCommand(homelab.NewSecretsCommand())
+	homelabCmd.AddCommand(homelab.NewVaultCommand())
+	homelabCmd.AddCommand(homelab.NewNodesCommand())
+	homelabCmd.AddCommand(homelab.NewCephCommand())
+	homelabCmd.AddCommand(homelab.NewHubbleCommand())
+	homelabCmd.AddCommand(homelab.NewVMCommand())
+	homelabCmd.AddCommand(etcd.NewEtcdCommand("homelab"))
 	homelabCmd.AddCommand(homelab.NewSuspendCommand())
 	homelabCmd.AddCommand(homelab.NewResumeCommand())
+	homelabCmd.AddCommand(homelab.NewFluxCommand())
 	homelabCmd.AddCommand(homelab.NewUninstallCommand())
 	homelabCmd.AddCommand(homelab.NewStatusCommand())
 
@@ -84,7 +188,6 @@ homelab Kubernetes clusters (with Talos, Cilium, FluxCD) and NAS clusters
 		Short: "NAS cluster operations",
 		Long:  "Bootstrap and manage NAS clusters with K3s, MinIO, and FluxCD",
 	}
-	addClusterFlags(nasCmd)
 
 	// Add NAS subcommands
 	nasCmd.AddCommand(nas.NewBootstrapCommand())
@@ -95,29 +198,93 @@ homelab Kubernetes clusters (with Talos, Cilium, FluxCD) and NAS clusters
 	nasCmd.AddCommand(nas.NewUpCommand())
 	nasCmd.AddCommand(nas.NewStatusCommand())
 	nasCmd.AddCommand(nas.NewUninstallCommand())
+	nasCmd.AddCommand(nas.NewLogsCommand())
 	nasCmd.AddCommand(nas.NewVaultSetupCommand())
+	nasCmd.AddCommand(nas.NewUpgradeCommand())
+	nasCmd.AddCommand(etcd.NewEtcdCommand("nas"))
+
+	// Create mesh subcommand
+	meshCmd := &cobra.Command{
+		Use:   "mesh",
+		Short: "Cross-cluster service mesh operations",
+		Long:  "Inspect and maintain the Istio multi-cluster mesh between homelab and NAS",
+	}
+
+	// Add mesh subcommands
+	meshCmd.AddCommand(mesh.NewStatusCommand())
+	meshCmd.AddCommand(mesh.NewSyncCommand())
+	meshCmd.AddCommand(mesh.NewRotateCACommand())
+	meshCmd.AddCommand(mesh.NewRenewGatewayCertCommand())
+	meshCmd.AddCommand(mesh.NewSmokeCommand())
 
 	// Add subcommands to root
 	rootCmd.AddCommand(homelabCmd)
 	rootCmd.AddCommand(nasCmd)
+	rootCmd.AddCommand(meshCmd)
 
 	// Add convenience commands at root level
 	rootCmd.AddCommand(createQuickCommands())
 	rootCmd.AddCommand(createForceCleanupCommand())
 	rootCmd.AddCommand(createRecoveryCommand())
 	rootCmd.AddCommand(createVerifyCommand())
+	rootCmd.AddCommand(createGCCommand())
+	rootCmd.AddCommand(createDiffClustersCommand())
+	rootCmd.AddCommand(createHealthCommand())
+	rootCmd.AddCommand(createStatusCommand())
+	rootCmd.AddCommand(createPolicyCommand())
+	rootCmd.AddCommand(createFalcoCommand())
+	rootCmd.AddCommand(createSecurityCommand())
+	rootCmd.AddCommand(createLoggingCommand())
+	rootCmd.AddCommand(createMetricsCommand())
+	rootCmd.AddCommand(createObservabilityCommand())
+	rootCmd.AddCommand(createSLOCommand())
+	rootCmd.AddCommand(createPreflightCommand())
+	rootCmd.AddCommand(createDoctorCommand())
+	rootCmd.AddCommand(createJobsCommand())
+	rootCmd.AddCommand(createCredsCommand())
+	rootCmd.AddCommand(createWhyCommand())
+	rootCmd.AddCommand(createOutdatedCommand())
+	rootCmd.AddCommand(createUpgradeCommand())
+	rootCmd.AddCommand(createDriftCommand())
+	rootCmd.AddCommand(createRestoreCommand())
+	rootCmd.AddCommand(createBackupCommand())
+	rootCmd.AddCommand(createCertManagerCommand())
+	rootCmd.AddCommand(createNamespacesCommand())
+	rootCmd.AddCommand(createConfigCommand())
+	rootCmd.AddCommand(createKubeconfigCommand())
+	rootCmd.AddCommand(createSecretsCommand())
+	rootCmd.AddCommand(createHistoryCommand())
+	rootCmd.AddCommand(createLogsCommand())
+	rootCmd.AddCommand(createAuditCommand())
+	rootCmd.AddCommand(createServeCommand())
+	rootCmd.AddCommand(createScheduleCommand())
+	rootCmd.AddCommand(createSelfUpdateCommand())
+	rootCmd.AddCommand(createAirGapCommand())
 
 	// Add version command
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
 		Short: "Show version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			log.Info("Bootstrap Tool", "version", "1.0.0", "commit", "dev")
+			log.Info("Bootstrap Tool", "version", version, "commit", commit)
 		},
 	})
 
 	// Execute
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if flushErr := audit.Flush(flushCtx); flushErr != nil {
+		log.Warn("Failed to mirror the audit trail", "error", flushErr)
+	}
+	cancel()
+	if err != nil {
+		var exit *cmdutil.ExitError
+		if errors.As(err, &exit) {
+			if exit.Err != nil {
+				log.Error("Command failed", "error", exit.Err)
+			}
+			os.Exit(exit.Code)
+		}
 		log.Error("Command failed", "error", err)
 		os.Exit(1)
 	}
@@ -142,7 +309,7 @@ func createQuickCommands() *cobra.Command {
 			// Run the homelab bootstrap command
 			homelabBootstrap := homelab.NewBootstrapCommand()
 			homelabBootstrap.SetArgs(args)
-			return homelabBootstrap.Execute()
+			return homelabBootstrap.ExecuteContext(cmd.Context())
 		},
 	})
 
@@ -157,7 +324,7 @@ func createQuickCommands() *cobra.Command {
 			// Run the NAS bootstrap command
 			nasBootstrap := nas.NewBootstrapCommand()
 			nasBootstrap.SetArgs(args)
-			return nasBootstrap.Execute()
+			return nasBootstrap.ExecuteContext(cmd.Context())
 		},
 	})
 
@@ -167,18 +334,22 @@ func createQuickCommands() *cobra.Command {
 		Short: "Deploy both homelab and NAS",
 		Long:  "Deploy both homelab and NAS clusters in sequence",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if overrides := cmdutil.OverridesFrom(cmd.Context()); overrides.Cluster != "" || overrides.Connection() {
+				return fmt.Errorf("--cluster, --kubeconfig and --context cannot be used with deploy all")
+			}
+
 			log.Info("🚀 Starting full deployment (homelab + NAS)")
 
 			// Deploy NAS first (homelab depends on it)
 			log.Info("Step 1: Deploying NAS cluster")
 			nasBootstrap := nas.NewBootstrapCommand()
-			if err := nasBootstrap.Execute(); err != nil {
+			if err := nasBootstrap.ExecuteContext(cmd.Context()); err != nil {
 				return err
 			}
 
 			log.Info("Step 2: Deploying homelab cluster")
 			homelabBootstrap := homelab.NewBootstrapCommand()
-			return homelabBootstrap.Execute()
+			return homelabBootstrap.ExecuteContext(cmd.Context())
 		},
 	})
 
@@ -186,106 +357,3459 @@ func createQuickCommands() *cobra.Command {
 }
 
 func createVerifyCommand() *cobra.Command {
-	return &cobra.Command{
+	verifyCmd := &cobra.Command{
 		Use:   "verify",
 		Short: "Run multi-cluster verification checks",
+		Long: "Run the health, Flux, storage, DNS, certificate, security, observability, resource, backup and mesh " +
+			"checks against both clusters and score the results. Exits 0 when everything passed, 1 when some " +
+			"checks warned and 2 when some failed",
+		SilenceErrors: true,
+		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info("Running mesh verification")
-			return bootstrapPkg.VerifyMesh(cmd.Context())
+			from, _ := cmd.Flags().GetString("from")
+			checkValues, _ := cmd.Flags().GetStringSlice("checks")
+			checks, err := bootstrapPkg.ParseVerifyChecks(checkValues)
+			if err != nil {
+				return err
+			}
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🔎 Verifying the clusters", "checks", checks, "from", from)
+			return printVerifyReport(orchestrator.Verify(cmd.Context(), checks, from))
 		},
 	}
+	verifyCmd.Flags().String("from", "homelab", "Cluster probing its peer through the mesh (homelab or nas)")
+	verifyCmd.Flags().StringSlice("checks", nil, "Checks to run: health, flux, storage, dns, certs, security, observability, resources, backup, mesh (default all)")
+	return verifyCmd
 }
 
-func addClusterFlags(cmd *cobra.Command) {
-	cmd.PersistentFlags().String("kubeconfig", "", "Override kubeconfig path")
-	cmd.PersistentFlags().String("context", "", "Override kubeconfig context")
+// printVerifyReport shows the scored report and turns its worst severity into the exit code
+func printVerifyReport(report *bootstrapPkg.VerifyReport) error {
+	if output.Structured() {
+		if err := output.Print(report); err != nil {
+			return err
+		}
+	} else {
+		for _, section := range report.Sections {
+			title := "━━ " + string(section.Check)
+			if section.Cluster != "" {
+				title += " (" + section.Cluster + ")"
+			}
+			log.Info(title, "score", section.Score)
+			for _, result := range section.Results {
+				switch result.Status {
+				case prereq.CheckPassed:
+					log.Info("✅ "+result.Description, "details", result.Details)
+				case prereq.CheckWarning:
+					log.Warn("⚠️ "+result.Description, "error", result.Error, "fix", result.Details)
+				case prereq.CheckFailed:
+					log.Error("❌ "+result.Description, "error", result.Error, "fix", result.Details)
+				}
+			}
+		}
+		log.Info("📋 Verify summary", "score", fmt.Sprintf("%d/100", report.Score), "clusters", strings.Join(report.Clusters, ","),
+			"passed", report.Passed, "warnings", report.Warnings, "failed", report.Failed)
+	}
+
+	code := report.ExitCode()
+	switch code {
+	case bootstrapPkg.DoctorHealthy:
+		return nil
+	case bootstrapPkg.DoctorWarnings:
+		return &cmdutil.ExitError{Code: code}
+	default:
+		return &cmdutil.ExitError{Code: code, Err: fmt.Errorf("%d check(s) failed", report.Failed)}
+	}
 }
 
-// createForceCleanupCommand adds force cleanup command for stuck namespaces
-func createForceCleanupCommand() *cobra.Command {
+// createGCCommand adds garbage collection for pending remote secrets and generated env keys
+func createGCCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "force-cleanup-namespaces",
-		Short: "Force cleanup stuck terminating namespaces",
-		Long:  "Aggressively clean up namespaces stuck in Terminating state",
+		Use:   "gc",
+		Short: "Clean up stale pending remote secrets and generated env keys",
+		Long:  "Remove applied or expired pending remote secrets and prune obsolete .env.generated keys",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			clusterType, _ := cmd.Flags().GetString("cluster")
-			if clusterType == "" {
-				clusterType = "homelab" // default
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
 			}
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			maxAge, _ := cmd.Flags().GetDuration("max-age")
 
-			log.Info("🔧 Starting force cleanup of terminating namespaces", "cluster", clusterType)
+			log.Info("🧹 Starting garbage collection", "cluster", clusterType, "dry_run", dryRun)
 
-			// Load configuration
-			loader := config.NewLoader()
-			cfg, err := loader.LoadConfig(clusterType)
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
 			if err != nil {
 				return err
 			}
 
-			// Create destroy manager
-			isNAS := clusterType == "nas"
-			destroyManager, err := destroy.NewManager(cfg, isNAS)
+			report, err := orchestrator.GarbageCollect(cmd.Context(), dryRun, maxAge)
 			if err != nil {
 				return err
 			}
 
-			// Force cleanup namespaces
-			return destroyManager.ForceCleanupNamespaces(cmd.Context())
+			if report.Empty() {
+				log.Info("✅ Nothing to clean up")
+				return nil
+			}
+
+			for _, name := range report.DeletedPendingSecrets {
+				log.Info("Pending remote secret", "name", name, "removed", !dryRun)
+			}
+			for _, key := range report.PrunedEnvKeys {
+				log.Info("Generated env key", "key", key, "removed", !dryRun)
+			}
+			log.Info("✅ Garbage collection completed",
+				"pending_secrets", len(report.DeletedPendingSecrets),
+				"env_keys", len(report.PrunedEnvKeys))
+			return nil
 		},
 	}
 
-	cmd.Flags().String("cluster", "homelab", "Cluster type (homelab or nas)")
+	cmd.Flags().Bool("dry-run", false, "Report what would be removed without deleting anything")
+	cmd.Flags().Duration("max-age", secrets.DefaultPendingMaxAge, "Age after which pending remote secrets are considered expired")
 	return cmd
 }
 
-// createRecoveryCommand adds recovery and diagnostic commands
-func createRecoveryCommand() *cobra.Command {
-	recoveryCmd := &cobra.Command{
-		Use:   "recovery",
-		Short: "Recovery and diagnostic commands",
-		Long:  "Diagnose system issues and recover from bootstrap failures",
+// createDiffClustersCommand adds a comparison between the homelab and NAS clusters
+func createDiffClustersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff-clusters",
+		Short: "Compare the homelab and NAS clusters",
+		Long:  "Highlight drift between homelab and NAS: Flux revisions, component versions and CRDs, cluster-vars keys, and storage",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scopeValues, _ := cmd.Flags().GetStringSlice("scope")
+			scopes, err := diff.ParseScopes(scopeValues)
+			if err != nil {
+				return err
+			}
+
+			overrides, err := cmdutil.ClusterOverride(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			log.Info("🔍 Comparing homelab and NAS clusters", "scopes", scopes)
+
+			report, err := bootstrapPkg.DiffClusters(cmd.Context(), scopes, overrides...)
+			if err != nil {
+				return err
+			}
+
+			if len(report.Entries) == 0 {
+				log.Info("✅ No drift detected")
+				return nil
+			}
+
+			for _, entry := range report.Entries {
+				log.Warn("⚠️ Drift", "scope", entry.Scope, "key", entry.Key, "homelab", entry.Homelab, "nas", entry.NAS)
+			}
+			log.Info("📊 Cluster diff completed", "differences", len(report.Entries))
+			return nil
+		},
 	}
 
-	// Diagnostic command
-	recoveryCmd.AddCommand(&cobra.Command{
-		Use:   "diagnose",
-		Short: "Diagnose system state",
-		Long:  "Perform comprehensive diagnostics to identify system issues",
+	cmd.Flags().StringSlice("scope", nil, "Scopes to compare: flux, versions, secrets, storage (default all)")
+	return cmd
+}
+
+// createHealthCommand adds a concurrent component health check
+func createHealthCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Check cluster component health",
+		Long:  "Run API server, node, CNI, DNS, storage, control plane and network checks concurrently with per-check timeouts",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.Info("🔍 Starting system diagnostics...")
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+
+			log.Info("🩺 Checking cluster health", "cluster", clusterType)
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
 
-			// Load configuration for both clusters
-			loader := config.NewLoader()
-			cfg, err := loader.LoadConfig("homelab")
+			status, err := orchestrator.ClusterHealth(cmd.Context(), bootstrapPkg.HealthOptions{
+				Concurrency:  concurrency,
+				CheckTimeout: timeout,
+			})
 			if err != nil {
-				// Try to load individual configs
-				cfg = &config.Config{}
-				if homelabCfg, err := loader.LoadConfig("homelab"); err == nil {
-					cfg.Homelab = homelabCfg.Homelab
+				return err
+			}
+
+			if output.Structured() {
+				if err := output.Print(status); err != nil {
+					return err
+				}
+				if status.Overall == health.HealthStateUnhealthy {
+					return fmt.Errorf("cluster is unhealthy (failed checks: %v)", status.Failures)
 				}
-				if nasCfg, err := loader.LoadConfig("nas"); err == nil {
-					cfg.NAS = nasCfg.NAS
+				return nil
+			}
+
+			components := make([]string, 0, len(status.Components))
+			for component := range status.Components {
+				components = append(components, component)
+			}
+			sort.Strings(components)
+
+			for _, component := range components {
+				switch status.Components[component] {
+				case health.HealthStateHealthy:
+					log.Info("✅ "+component, "details", status.Details[component])
+				case health.HealthStateWarning:
+					log.Warn("⚠️ "+component, "details", status.Details[component])
+				default:
+					log.Error("❌ "+component, "details", status.Details[component])
 				}
 			}
 
-			// Create diagnostic manager
-			diagnosticManager, err := recovery.NewDiagnosticManager(cfg, false)
+			if status.Overall == health.HealthStateUnhealthy {
+				return fmt.Errorf("cluster is unhealthy (failed checks: %v)", status.Failures)
+			}
+			log.Info("📊 Cluster health", "overall", status.Overall)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("concurrency", 0, "Maximum number of checks run in parallel (default 4)")
+	cmd.Flags().Duration("timeout", 0, "Timeout for each individual check (default 10s)")
+	return cmd
+}
+
+// createStatusCommand adds the side-by-side status of both clusters, live with --watch
+func createStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show homelab and NAS status side by side",
+		Long: "Show node readiness, Flux Kustomizations and HelmReleases, Istio gateway endpoints, Ceph health " +
+			"and recent Flux events of both clusters. With --watch the dashboard stays open and updates from cluster watches",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			watchMode, _ := cmd.Flags().GetBool("watch")
+
+			overrides, err := cmdutil.ClusterOverride(cmd.Context())
+			if err != nil {
+				return err
+			}
+			watchers, err := bootstrapPkg.DashboardWatchers(cmd.Context(), overrides...)
 			if err != nil {
-				return fmt.Errorf("failed to create diagnostic manager: %w", err)
+				return err
 			}
 
-			// Run diagnostics
-			results, err := diagnosticManager.DiagnoseSystem(cmd.Context())
+			if watchMode {
+				if output.Structured() {
+					return fmt.Errorf("--watch cannot be combined with --output %s", output.CurrentFormat())
+				}
+				ctx, cancel := context.WithCancel(cmd.Context())
+				defer cancel()
+
+				updates := make(chan dashboard.ClusterState)
+				clusters := make([]string, 0, len(watchers))
+				for _, watcher := range watchers {
+					clusters = append(clusters, watcher.Cluster())
+					go watcher.Run(ctx, updates)
+				}
+				if _, err := tea.NewProgram(tui.NewStatusModel(clusters, updates), tea.WithAltScreen()).Run(); err != nil {
+					return fmt.Errorf("status dashboard failed: %w", err)
+				}
+				return nil
+			}
+
+			states := make([]dashboard.ClusterState, 0, len(watchers))
+			for _, watcher := range watchers {
+				state, err := watcher.Collect(cmd.Context())
+				if err != nil {
+					state = dashboard.ClusterState{Cluster: watcher.Cluster(), Error: err.Error()}
+				}
+				states = append(states, state)
+			}
+
+			if output.Structured() {
+				return output.Print(states)
+			}
+			for _, state := range states {
+				printClusterState(state)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("watch", "w", false, "Keep the dashboard open, updating as the clusters change")
+	return cmd
+}
+
+// printClusterState logs the one-shot status of a cluster
+func printClusterState(state dashboard.ClusterState) {
+	if state.Error != "" {
+		log.Error("❌ "+state.Cluster, "error", state.Error)
+		return
+	}
+
+	readyNodes := 0
+	for _, node := range state.Nodes {
+		if node.Ready {
+			readyNodes++
+		}
+	}
+	log.Info("📊 "+state.Cluster, "nodes", fmt.Sprintf("%d/%d", readyNodes, len(state.Nodes)))
+
+	for _, objects := range [][]dashboard.FluxObject{state.Kustomizations, state.HelmReleases} {
+		for _, object := range objects {
+			if !object.Ready {
+				log.Warn("⚠️ "+object.Namespace+"/"+object.Name, "reason", object.Reason, "suspended", object.Suspended, "message", object.Message)
+			}
+		}
+	}
+	for _, gateway := range state.Gateways {
+		log.Info("🌐 "+gateway.Name, "address", gateway.Address)
+	}
+	if state.CephHealth != "" {
+		log.Info("💾 Ceph", "health", state.CephHealth)
+	}
+	for _, cert := range state.ExpiringCertificates {
+		expires := "unknown"
+		if cert.NotAfter != nil {
+			expires = cert.NotAfter.Format(time.RFC3339)
+		}
+		log.Warn("🔐 "+cert.Namespace+"/"+cert.Name, "issuer", cert.Issuer, "ready", cert.Ready, "expires", expires, "message", cert.Message)
+	}
+}
+
+// createPolicyCommand adds the policy engine baseline commands
+func createPolicyCommand() *cobra.Command {
+	policyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Manage the policy engine baseline",
+		Long:  "Generate Kyverno or Gatekeeper baseline policies into the GitOps repository and report audit-mode violations",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the policy engine and baseline policies",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
 			if err != nil {
-				return fmt.Errorf("diagnostics failed: %w", err)
+				return err
 			}
 
-			// Print results
-			diagnosticManager.PrintDiagnostics(results)
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			opts, err := orchestrator.PolicyOptions()
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("engine") {
+				value, _ := cmd.Flags().GetString("engine")
+				if opts.Engine, err = policy.ParseEngine(value); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("mode") {
+				value, _ := cmd.Flags().GetString("mode")
+				if opts.Mode, err = policy.ParseMode(value); err != nil {
+					return err
+				}
+			}
+
+			log.Info("🛡️ Generating baseline policies", "cluster", clusterType, "engine", opts.Engine, "mode", opts.Mode)
+			written, err := orchestrator.GeneratePolicies(opts)
+			if err != nil {
+				return err
+			}
 
+			for _, path := range written {
+				log.Info("📝 " + path)
+			}
+			log.Info("✅ Baseline policies generated; commit and push them for Flux to apply")
 			return nil
 		},
-	})
+	}
+	generateCmd.Flags().String("engine", "", "Policy engine (kyverno or gatekeeper), overrides security.policy_engine.engine")
+	generateCmd.Flags().String("mode", "", "Policy mode (audit or enforce), overrides security.policy_engine.mode")
 
-	return recoveryCmd
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report baseline policy violations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			engineName, _ := cmd.Flags().GetString("engine")
+			var engine policy.Engine
+			if engineName != "" {
+				if engine, err = policy.ParseEngine(engineName); err != nil {
+					return err
+				}
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			report, err := orchestrator.AuditPolicies(cmd.Context(), engine)
+			if err != nil {
+				return err
+			}
+			failOn, _ := cmd.Flags().GetBool("fail-on-violations")
+			if output.Structured() {
+				if err := output.Print(report); err != nil {
+					return err
+				}
+				if failOn && len(report.Violations) > 0 {
+					return fmt.Errorf("%d baseline policy violations found", len(report.Violations))
+				}
+				return nil
+			}
+			if !report.Installed {
+				log.Warn("⚠️ No baseline policies found", "cluster", clusterType)
+				return nil
+			}
+
+			grouped := report.ByPolicy()
+			for _, baseline := range policy.BaselinePolicies {
+				if !report.HasBaseline(baseline.Name) {
+					continue
+				}
+				violations := grouped[baseline.Name]
+				if len(violations) == 0 {
+					log.Info("✅ "+baseline.Title, "policy", baseline.Name)
+					continue
+				}
+				log.Warn("⚠️ "+baseline.Title, "policy", baseline.Name, "severity", baseline.Severity, "violations", len(violations))
+				for _, violation := range violations {
+					log.Warn("  "+violation.Resource(), "message", violation.Message)
+				}
+			}
+
+			compliance := report.Compliance()
+			checks := make([]string, 0, len(compliance))
+			for check := range compliance {
+				checks = append(checks, check)
+			}
+			sort.Strings(checks)
+			for _, check := range checks {
+				log.Info("📋 Compliance", "check", check, "compliant", compliance[check])
+			}
+
+			log.Info("📊 Policy audit", "engine", report.Engine, "policies", report.Policies, "violations", len(report.Violations))
+			if failOn && len(report.Violations) > 0 {
+				return fmt.Errorf("%d baseline policy violations found", len(report.Violations))
+			}
+			return nil
+		},
+	}
+	reportCmd.Flags().String("engine", "", "Policy engine to read (kyverno or gatekeeper), detected when empty")
+	reportCmd.Flags().Bool("fail-on-violations", false, "Exit with an error when violations are found")
+
+	policyCmd.AddCommand(generateCmd)
+	policyCmd.AddCommand(reportCmd)
+	return policyCmd
+}
+
+// createFalcoCommand adds the Falco runtime security commands
+func createFalcoCommand() *cobra.Command {
+	falcoCmd := &cobra.Command{
+		Use:   "falco",
+		Short: "Manage Falco runtime security",
+		Long:  "Generate the Falco deployment, validate its driver on every node and inspect or record its alerts",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the Falco deployment and configure alert routing",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			opts := orchestrator.FalcoOptions()
+			if cmd.Flags().Changed("driver") {
+				opts.Driver, _ = cmd.Flags().GetString("driver")
+			}
+			if cmd.Flags().Changed("priority") {
+				opts.Priority, _ = cmd.Flags().GetString("priority")
+			}
+
+			log.Info("🦅 Generating Falco deployment", "cluster", clusterType)
+			written, err := orchestrator.GenerateFalco(opts)
+			if err != nil {
+				return err
+			}
+			for _, path := range written {
+				log.Info("📝 " + path)
+			}
+
+			if err := orchestrator.ConfigureFalcoAlertRouting(cmd.Context()); err != nil {
+				return err
+			}
+			log.Info("✅ Falco deployment generated; commit and push it for Flux to apply")
+			return nil
+		},
+	}
+	generateCmd.Flags().String("driver", "", "Falco driver (modern_ebpf, ebpf or kmod), overrides security.falco.driver")
+	generateCmd.Flags().String("priority", "", "Minimum alert priority, overrides security.falco.priority")
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the Falco driver loaded on every node",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			statuses, err := orchestrator.ValidateFalco(cmd.Context())
+			for _, status := range statuses {
+				if status.Ready {
+					log.Info("✅ "+status.Node, "kernel", status.Kernel, "os", status.OSImage)
+				} else {
+					log.Error("❌ "+status.Node, "kernel", status.Kernel, "os", status.OSImage, "error", status.Error)
+				}
+			}
+			return err
+		},
+	}
+
+	alertsCmd := &cobra.Command{
+		Use:   "alerts",
+		Short: "Show recent Falco alerts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runE := func(cmd *cobra.Command, args []string) error {
+				clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+				if err != nil {
+					return err
+				}
+				since, _ := cmd.Flags().GetDuration("since")
+				priority, _ := cmd.Flags().GetString("priority")
+
+				orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+				if err != nil {
+					return err
+				}
+
+				alerts, err := orchestrator.FalcoAlerts(cmd.Context(), since, priority)
+				if err != nil {
+					return err
+				}
+				if output.Structured() {
+					if alerts == nil {
+						alerts = []falco.Alert{}
+					}
+					return output.Print(alerts)
+				}
+				if len(alerts) == 0 {
+					log.Info("✅ No Falco alerts", "since", since, "priority", priority)
+					return nil
+				}
+				for _, alert := range alerts {
+					log.Warn("🚨 "+alert.Rule,
+						"time", alert.Time.Format(time.RFC3339),
+						"priority", alert.Priority,
+						"node", alert.Hostname,
+						"output", alert.Output)
+				}
+				log.Info("📊 Falco alerts", "count", len(alerts))
+				return nil
+			}
+
+			if record, _ := cmd.Flags().GetBool("record"); record {
+				return cmdutil.Recorded("falco alerts", "", runE)(cmd, args)
+			}
+			return runE(cmd, args)
+		},
+	}
+	alertsCmd.Flags().Duration("since", time.Hour, "Only show alerts emitted within this duration")
+	alertsCmd.Flags().String("priority", "warning", "Minimum alert priority")
+	alertsCmd.Flags().Bool("record", false, "Record the alerts in the run history")
+
+	falcoCmd.AddCommand(generateCmd)
+	falcoCmd.AddCommand(validateCmd)
+	falcoCmd.AddCommand(alertsCmd)
+	return falcoCmd
+}
+
+// createSecurityCommand adds the security posture commands
+func createSecurityCommand() *cobra.Command {
+	securityCmd := &cobra.Command{
+		Use:   "security",
+		Short: "Validate the cluster security posture",
+		Long:  "Check RBAC, network policies, admission and policy audits, run the CIS benchmark with kube-bench and scan images with Trivy",
+	}
+
+	scanCmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Scan the cluster security posture",
+		Long: "Run the security checks and, with --benchmark cis, kube-bench as a Job on the cluster. " +
+			"Failed CIS controls are reported as findings with their control ID.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			var benchmark *security.BenchmarkOptions
+			switch name, _ := cmd.Flags().GetString("benchmark"); name {
+			case "":
+			case security.BenchmarkCIS:
+				opts := orchestrator.BenchmarkOptions()
+				if cmd.Flags().Changed("bench-profile") {
+					opts.Profile, _ = cmd.Flags().GetString("bench-profile")
+				}
+				benchmark = &opts
+			default:
+				return fmt.Errorf("unknown benchmark %q, only %s is supported", name, security.BenchmarkCIS)
+			}
+
+			status, err := orchestrator.SecurityScan(cmd.Context(), bootstrapPkg.SecurityScanOptions{Benchmark: benchmark})
+			if err != nil {
+				return err
+			}
+			if output.Structured() {
+				if err := output.Print(status); err != nil {
+					return err
+				}
+			} else {
+				for _, finding := range status.Vulnerabilities {
+					fields := []interface{}{"severity", finding.Severity, "component", finding.Component}
+					if finding.Control != "" {
+						fields = append(fields, "control", finding.Control)
+					}
+					log.Warn("⚠️  "+finding.Description, fields...)
+				}
+				if status.Benchmark != nil {
+					log.Info("📊 CIS benchmark", "profile", status.Benchmark.Profile, "benchmark", status.Benchmark.Benchmark,
+						"pass", status.Benchmark.Pass, "fail", status.Benchmark.Fail, "warn", status.Benchmark.Warn)
+				}
+				log.Info("📊 Security scan", "findings", len(status.Vulnerabilities))
+			}
+
+			if benchmark != nil && status.Benchmark == nil {
+				return fmt.Errorf("CIS benchmark did not complete, see the warnings above")
+			}
+			if failOn, _ := cmd.Flags().GetBool("fail-on-findings"); failOn && status.Benchmark.Fail > 0 {
+				return fmt.Errorf("%d CIS controls failed", status.Benchmark.Fail)
+			}
+			return nil
+		},
+	}
+	scanCmd.Flags().String("benchmark", "", "Benchmark to run with kube-bench (cis)")
+	scanCmd.Flags().String("bench-profile", "", "Benchmark profile (talos, k3s or generic), overrides security.benchmark.profile")
+	scanCmd.Flags().Bool("fail-on-findings", false, "Exit with an error when benchmark controls fail")
+
+	scanImagesCmd := &cobra.Command{
+		Use:   "scan-images",
+		Short: "Scan the images of the running pods for vulnerabilities",
+		Long: "Collect the vulnerabilities of every image running in the cluster from the VulnerabilityReports of trivy-operator, " +
+			"scanning the images it has not reported on with the local trivy CLI.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			opts := orchestrator.ImageScanOptions()
+			if cmd.Flags().Changed("namespace") {
+				opts.Namespace, _ = cmd.Flags().GetString("namespace")
+			}
+			if cmd.Flags().Changed("ignore-unfixed") {
+				opts.IgnoreUnfixed, _ = cmd.Flags().GetBool("ignore-unfixed")
+			}
+			failOn := orchestrator.ImageScanFailOn()
+			if cmd.Flags().Changed("fail-on") {
+				failOn, _ = cmd.Flags().GetString("fail-on")
+			}
+			if failOn != "" && !security.ValidSeverity(failOn) {
+				return fmt.Errorf("unknown severity %q, use critical, high, medium or low", failOn)
+			}
+
+			status, err := orchestrator.SecurityScan(cmd.Context(), bootstrapPkg.SecurityScanOptions{Images: &opts})
+			if err != nil {
+				return err
+			}
+			if status.ImageScan == nil {
+				return fmt.Errorf("image scan did not complete, see the warnings above")
+			}
+			result := status.ImageScan
+			if output.Structured() {
+				if err := output.Print(status); err != nil {
+					return err
+				}
+			} else {
+				for _, report := range result.Images {
+					if len(report.Vulnerabilities) == 0 {
+						continue
+					}
+					log.Warn("🐛 "+report.Image,
+						"critical", report.Severities["CRITICAL"],
+						"high", report.Severities["HIGH"],
+						"medium", report.Severities["MEDIUM"],
+						"low", report.Severities["LOW"],
+						"source", report.Source)
+				}
+				for _, image := range result.Unscanned {
+					log.Warn("❓ Not scanned: " + image)
+				}
+				log.Info("📊 Image scan", "images", len(result.Images),
+					"critical", result.Severities["CRITICAL"],
+					"high", result.Severities["HIGH"],
+					"medium", result.Severities["MEDIUM"],
+					"low", result.Severities["LOW"])
+			}
+
+			if failOn != "" {
+				if count := result.AtOrAbove(failOn); count > 0 {
+					return fmt.Errorf("%d vulnerabilities of %s severity or above found", count, strings.ToLower(failOn))
+				}
+			}
+			return nil
+		},
+	}
+	scanImagesCmd.Flags().StringP("namespace", "n", "", "Only scan the pods of this namespace, overrides security.image_scan.namespace")
+	scanImagesCmd.Flags().Bool("ignore-unfixed", false, "Leave out vulnerabilities without a fixed version")
+	scanImagesCmd.Flags().String("fail-on", "", "Exit with an error on vulnerabilities of this severity or above (critical, high, medium or low)")
+
+	securityCmd.AddCommand(scanCmd)
+	securityCmd.AddCommand(scanImagesCmd)
+	return securityCmd
+}
+
+// createLoggingCommand adds the log shipping commands
+func createLoggingCommand() *cobra.Command {
+	loggingCmd := &cobra.Command{
+		Use:   "logging",
+		Short: "Manage log shipping to the NAS Loki",
+		Long:  "Generate the Alloy or Fluent Bit collector shipping cluster logs to the NAS Loki across the mesh, and verify delivery",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the log collector",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			opts := orchestrator.LoggingOptions()
+			if cmd.Flags().Changed("collector") {
+				opts.Collector, _ = cmd.Flags().GetString("collector")
+			}
+			if cmd.Flags().Changed("loki-endpoint") {
+				opts.LokiEndpoint, _ = cmd.Flags().GetString("loki-endpoint")
+			}
+
+			log.Info("📜 Generating log shipping", "cluster", clusterType, "collector", opts.Collector, "loki", opts.LokiEndpoint)
+			written, err := orchestrator.GenerateLogShipping(opts)
+			if err != nil {
+				return err
+			}
+			for _, path := range written {
+				log.Info("📝 " + path)
+			}
+			log.Info("✅ Log shipping generated; commit and push it for Flux to apply")
+			return nil
+		},
+	}
+	generateCmd.Flags().String("collector", "", "Log collector (alloy or fluent-bit), overrides monitoring.logging.collector")
+	generateCmd.Flags().String("loki-endpoint", "", "Loki push URL, overrides monitoring.logging.loki_endpoint")
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Verify the collector runs and the NAS Loki receives logs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			opts := orchestrator.LoggingOptions()
+			status, err := orchestrator.ValidateLogShipping(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			log.Info("✅ Log shipping healthy",
+				"collector", opts.Collector,
+				"pods", fmt.Sprintf("%d/%d", status.ReadyPods, status.DesiredPods),
+				"clusters_in_loki", status.ClustersInLoki)
+			return nil
+		},
+	}
+
+	loggingCmd.AddCommand(generateCmd)
+	loggingCmd.AddCommand(validateCmd)
+	return loggingCmd
+}
+
+// createMetricsCommand adds the metrics federation commands
+func createMetricsCommand() *cobra.Command {
+	metricsCmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Manage metrics federation into the NAS",
+		Long:  "Generate the NAS metrics receiver and the homelab remote-write agent, create their credentials, and verify homelab metrics are queryable from the NAS Grafana",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the receiver (NAS) or remote-write agent (homelab)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			opts := orchestrator.FederationOptions()
+			if cmd.Flags().Changed("endpoint") {
+				opts.Endpoint, _ = cmd.Flags().GetString("endpoint")
+			}
+
+			log.Info("📈 Generating metrics federation", "cluster", clusterType, "endpoint", opts.Endpoint)
+			written, err := orchestrator.GenerateFederation(opts)
+			if err != nil {
+				return err
+			}
+			for _, path := range written {
+				log.Info("📝 " + path)
+			}
+			log.Info("✅ Metrics federation generated; commit and push it for Flux to apply")
+			return nil
+		},
+	}
+	generateCmd.Flags().String("endpoint", "", "Receiver remote-write URL, overrides monitoring.federation.endpoint")
+
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Create the federation credentials on both clusters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			if err := orchestrator.ConfigureFederationAuth(cmd.Context(), orchestrator.FederationOptions()); err != nil {
+				return err
+			}
+			log.Info("🔐 Metrics federation credentials stored on homelab and NAS")
+			return nil
+		},
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Verify homelab metrics are queryable from the NAS",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			status, err := orchestrator.ValidateFederation(cmd.Context(), orchestrator.FederationOptions())
+			if err != nil {
+				return err
+			}
+			if !status.DatasourceReady {
+				log.Warn("⚠️  Grafana datasource secret missing, run 'bootstrap metrics auth'")
+			}
+			log.Info("✅ Metrics federation healthy", "homelab_targets", status.Series, "grafana_datasource", status.DatasourceReady)
+			return nil
+		},
+	}
+
+	metricsCmd.AddCommand(generateCmd)
+	metricsCmd.AddCommand(authCmd)
+	metricsCmd.AddCommand(validateCmd)
+	return metricsCmd
+}
+
+// createObservabilityCommand adds the observability commands
+func createObservabilityCommand() *cobra.Command {
+	observabilityCmd := &cobra.Command{
+		Use:   "observability",
+		Short: "Manage observability assets shipped with the tool",
+		Long:  "Provision the curated Grafana dashboards, versioned alongside the bootstrap tool",
+	}
+
+	provisionCmd := &cobra.Command{
+		Use:   "provision-dashboards",
+		Short: "Generate the curated Grafana dashboards as ConfigMaps",
+		Long: "Generate the bootstrap steps, Flux reconciliation, mesh gateway traffic and Ceph health dashboards " +
+			"as Grafana sidecar ConfigMaps into the GitOps repository",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			selected := orchestrator.DashboardSelection()
+			if cmd.Flags().Changed("dashboard") {
+				selected, _ = cmd.Flags().GetStringSlice("dashboard")
+			}
+
+			log.Info("📊 Provisioning Grafana dashboards", "cluster", clusterType, "version", version)
+			written, err := orchestrator.ProvisionDashboards(version, selected)
+			if err != nil {
+				return err
+			}
+			for _, path := range written {
+				log.Info("📝 " + path)
+			}
+			log.Info("✅ Dashboards generated; commit and push them for Flux to apply")
+			return nil
+		},
+	}
+	provisionCmd.Flags().StringSlice("dashboard", nil,
+		fmt.Sprintf("Dashboards to provision (%s), overrides monitoring.grafana.dashboards", strings.Join(observability.DashboardNames(), ", ")))
+
+	observabilityCmd.AddCommand(provisionCmd)
+	return observabilityCmd
+}
+
+// createSLOCommand adds the SLO commands
+func createSLOCommand() *cobra.Command {
+	sloCmd := &cobra.Command{
+		Use:   "slo",
+		Short: "Manage service level objectives",
+		Long:  "Generate recording and burn rate alerting rules for the SLOs declared in monitoring.slos, and report their error budgets",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the SLO recording and alerting rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🎯 Generating SLO rules", "cluster", clusterType)
+			written, err := orchestrator.GenerateSLORules()
+			if err != nil {
+				return err
+			}
+			for _, path := range written {
+				log.Info("📝 " + path)
+			}
+			log.Info("✅ SLO rules generated; commit and push them for Flux to apply")
+			return nil
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report the error budget burn of every SLO",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prometheus, _ := cmd.Flags().GetString("prometheus")
+			failOnExhausted, _ := cmd.Flags().GetBool("fail-on-exhausted")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			budgets, err := orchestrator.SLOStatus(cmd.Context(), prometheus)
+			if err != nil {
+				return err
+			}
+			if len(budgets) == 0 {
+				log.Info("No SLOs declared in monitoring.slos")
+				return nil
+			}
+
+			exhausted := 0
+			for _, budget := range budgets {
+				objective := budget.Objective
+				if !budget.HasData {
+					log.Warn("⚪ "+objective.Name(), "target", fmt.Sprintf("%g%%", objective.Target), "window", objective.Window, "sli", "no data")
+					continue
+				}
+
+				icon := "🟢"
+				switch {
+				case budget.Exhausted():
+					icon = "🔴"
+					exhausted++
+				case budget.Remaining < 0.25 || budget.BurnRate > 1:
+					icon = "🟡"
+				}
+				log.Info(icon+" "+objective.Name(),
+					"target", fmt.Sprintf("%g%%", objective.Target),
+					"sli", fmt.Sprintf("%.3f%%", budget.SLI),
+					"budget_left", fmt.Sprintf("%.1f%%", budget.Remaining*100),
+					"burn_rate_1h", fmt.Sprintf("%.2fx", budget.BurnRate),
+					"window", objective.Window)
+			}
+
+			if exhausted > 0 && failOnExhausted {
+				return fmt.Errorf("%d SLO(s) exhausted their error budget", exhausted)
+			}
+			return nil
+		},
+	}
+	statusCmd.Flags().String("prometheus", "", "Prometheus service URL to query (default kube-prometheus-stack in monitoring)")
+	statusCmd.Flags().Bool("fail-on-exhausted", false, "Exit non-zero when an error budget is exhausted")
+
+	sloCmd.AddCommand(generateCmd)
+	sloCmd.AddCommand(statusCmd)
+	return sloCmd
+}
+
+// createPreflightCommand adds the preflight checks run before changing the cluster
+func createPreflightCommand() *cobra.Command {
+	preflightCmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Run preflight checks before changing the cluster",
+	}
+
+	archCmd := &cobra.Command{
+		Use:   "arch",
+		Short: "Find workloads whose images lack a variant for an architecture",
+		Long: "Inspect live workloads and the manifests/HelmReleases of the GitOps repository, query the registries " +
+			"for their manifest lists, and report which apps would fail on nodes of the given architecture",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			arch, _ := cmd.Flags().GetString("arch")
+			repositoryOnly, _ := cmd.Flags().GetBool("repository-only")
+			failOnBlocked, _ := cmd.Flags().GetBool("fail-on-blocked")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			log.Info("🧬 Checking image architectures", "cluster", clusterType, "arch", arch)
+			report, err := orchestrator.ArchPreflight(cmd.Context(), arch, repositoryOnly)
+			if err != nil {
+				return err
+			}
+			if !repositoryOnly {
+				log.Info("Target nodes", "arch", arch, "nodes", report.TargetNodes)
+			}
+
+			for _, finding := range report.Findings {
+				workload := finding.Workload
+				for image, arches := range finding.Missing {
+					log.Warn("❌ "+workload.ID(), "image", image, "available", strings.Join(arches, ","), "source", workload.Source)
+				}
+				for image, reason := range finding.Unknown {
+					log.Info("❔ "+workload.ID(), "image", image, "reason", reason)
+				}
+				if finding.Patch != "" {
+					fmt.Printf("# Suggested patch for %s\n%s\n---\n", workload.ID(), finding.Patch)
+				}
+			}
+
+			blocked := report.Blocked()
+			log.Info("📋 Architecture preflight complete",
+				"arch", arch,
+				"workloads", report.Workloads,
+				"images", report.Images,
+				"would_fail", len(blocked))
+			if len(blocked) > 0 && failOnBlocked {
+				return fmt.Errorf("%d workload(s) would fail to run on %s nodes", len(blocked), arch)
+			}
+			return nil
+		},
+	}
+	archCmd.Flags().String("arch", "arm64", "Architecture of the new nodes")
+	archCmd.Flags().Bool("repository-only", false, "Inspect the GitOps repository without connecting to the cluster")
+	archCmd.Flags().Bool("fail-on-blocked", false, "Exit non-zero when a workload would fail to schedule")
+
+	connectivityCmd := &cobra.Command{
+		Use:   "connectivity",
+		Short: "Check the network path from this workstation to the API server",
+		Long: "Measure API round-trip latency and health, detect HTTP(S)_PROXY settings applying to the API server, " +
+			"tell clock skew apart from expired certificates and test the websocket upgrades exec and port-forward rely on",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			log.Info("📡 Checking connectivity to the API server", "cluster", clusterType)
+			results := orchestrator.ConnectivityPreflight(cmd.Context())
+			if output.Structured() {
+				report := cmdutil.NewCheckReport(clusterType, results)
+				if err := output.Print(report); err != nil {
+					return err
+				}
+				if report.Failed > 0 {
+					return fmt.Errorf("%d connectivity check(s) failed", report.Failed)
+				}
+				return nil
+			}
+
+			failed := 0
+			for _, result := range results {
+				switch result.Status {
+				case prereq.CheckPassed:
+					log.Info("✅ "+result.Description, "details", result.Details)
+				case prereq.CheckWarning:
+					log.Warn("⚠️ "+result.Description, "error", result.Error, "details", result.Details)
+				case prereq.CheckFailed:
+					log.Error("❌ "+result.Description, "error", result.Error, "details", result.Details)
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d connectivity check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	preflightCmd.AddCommand(archCmd)
+	preflightCmd.AddCommand(connectivityCmd)
+	return preflightCmd
+}
+
+// createDoctorCommand adds the end-to-end environment check, exiting by the worst severity found
+func createDoctorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the whole environment bootstrap depends on",
+		Long: "Run the prerequisite, config, kubeconfig, DNS, clock skew between clusters, node disk space and " +
+			"credential checks with a fix hint for each problem. Exits 0 when everything passed, " +
+			"1 when some checks warned and 2 when some failed",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			report := bootstrapPkg.NewDoctorReport(clusterType)
+			log.Info("🩺 Checking the bootstrap environment", "cluster", clusterType)
+			cfg, err := cmdutil.LoadConfig(cmd.Context(), clusterType)
+			if err != nil {
+				report.Add("config", prereq.CheckResult{
+					Name:        "config",
+					Description: "Configuration of " + clusterType,
+					Status:      prereq.CheckFailed,
+					Error:       err,
+					Details:     "Fix configs/" + clusterType + ".yaml, 'bootstrap config migrate' upgrades outdated schemas",
+				})
+				return printDoctorReport(report)
+			}
+			report.Add("config", prereq.CheckResult{
+				Name:        "config",
+				Description: "Configuration of " + clusterType,
+				Status:      prereq.CheckPassed,
+				Details:     "Loaded and validated",
+			})
+
+			isNAS := clusterType == "nas"
+			results, err := prereq.NewChecker(cfg, isNAS).CheckAll(cmd.Context())
+			if err != nil {
+				return err
+			}
+			report.Add("prerequisites", results...)
+
+			orchestrator, err := bootstrapPkg.NewOrchestrator(cfg, isNAS, cmdutil.OrchestratorOptions(cmd.Context(), cfg, isNAS))
+			if err != nil {
+				report.Add("clusters", prereq.CheckResult{
+					Name:        "kubeconfig",
+					Description: "Kubeconfig of " + clusterType,
+					Status:      prereq.CheckFailed,
+					Error:       err,
+					Details:     "Run 'bootstrap " + clusterType + " up' or point --kubeconfig at a working kubeconfig",
+				})
+				return printDoctorReport(report)
+			}
+			orchestrator.Doctor(cmd.Context(), report)
+			return printDoctorReport(report)
+		},
+	}
+	return cmd
+}
+
+// printDoctorReport shows the report and turns its worst severity into the exit code
+func printDoctorReport(report *bootstrapPkg.DoctorReport) error {
+	if output.Structured() {
+		if err := output.Print(report); err != nil {
+			return err
+		}
+	} else {
+		for _, section := range report.Sections {
+			log.Info("━━ " + section.Name)
+			for _, result := range section.Results {
+				switch result.Status {
+				case prereq.CheckPassed:
+					log.Info("✅ "+result.Description, "details", result.Details)
+				case prereq.CheckWarning:
+					log.Warn("⚠️ "+result.Description, "error", result.Error, "fix", result.Details)
+				case prereq.CheckFailed:
+					log.Error("❌ "+result.Description, "error", result.Error, "fix", result.Details)
+				}
+			}
+		}
+		log.Info("📋 Doctor summary", "passed", report.Passed, "warnings", report.Warnings, "failed", report.Failed)
+	}
+
+	code := report.ExitCode()
+	switch code {
+	case bootstrapPkg.DoctorHealthy:
+		return nil
+	case bootstrapPkg.DoctorWarnings:
+		return &cmdutil.ExitError{Code: code}
+	default:
+		return &cmdutil.ExitError{Code: code, Err: fmt.Errorf("%d check(s) failed", report.Failed)}
+	}
+}
+
+// createJobsCommand adds the scheduled job commands
+func createJobsCommand() *cobra.Command {
+	jobsCmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Inspect scheduled jobs across clusters",
+	}
+
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Flag CronJobs and backup/replication schedules that stopped succeeding",
+		Long: "List the CronJobs, Velero schedules and VolSync replications of both clusters and flag the ones " +
+			"without a success within their expected window, stuck active runs and suspended schedules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runE := func(cmd *cobra.Command, args []string) error {
+				clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+				if err != nil {
+					return err
+				}
+				tolerance, _ := cmd.Flags().GetFloat64("tolerance")
+				stuckAfter, _ := cmd.Flags().GetDuration("stuck-after")
+				localOnly, _ := cmd.Flags().GetBool("local-only")
+				showAll, _ := cmd.Flags().GetBool("all")
+
+				orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+				if err != nil {
+					return err
+				}
+
+				schedules, err := orchestrator.AuditJobs(cmd.Context(), jobs.Options{Tolerance: tolerance, StuckAfter: stuckAfter}, !localOnly)
+				if err != nil {
+					return err
+				}
+
+				unhealthy := 0
+				for _, schedule := range schedules {
+					lastSuccess := "never"
+					if schedule.LastSuccessful != nil {
+						lastSuccess = schedule.LastSuccessful.Format(time.RFC3339)
+					}
+					if schedule.Healthy() {
+						if showAll {
+							log.Info("✅ "+schedule.ID(), "schedule", schedule.Schedule, "last_success", lastSuccess)
+						}
+						continue
+					}
+					unhealthy++
+					for _, issue := range schedule.Issues {
+						icon := "❌"
+						if issue.Severity == "warning" {
+							icon = "⏸️ "
+						}
+						log.Warn(icon+" "+schedule.ID(),
+							"issue", issue.Kind,
+							"detail", issue.Message,
+							"schedule", schedule.Schedule,
+							"last_success", lastSuccess)
+					}
+				}
+
+				log.Info("📋 Scheduled job audit complete", "schedules", len(schedules), "unhealthy", unhealthy)
+				if unhealthy > 0 {
+					return fmt.Errorf("%d scheduled job(s) need attention", unhealthy)
+				}
+				return nil
+			}
+
+			if record, _ := cmd.Flags().GetBool("record"); record {
+				return cmdutil.Recorded("jobs audit", "", runE)(cmd, args)
+			}
+			return runE(cmd, args)
+		},
+	}
+	auditCmd.Flags().Float64("tolerance", 2, "Schedule intervals allowed to pass without a successful run")
+	auditCmd.Flags().Duration("stuck-after", 0, "Flag runs active for longer than this (default one schedule interval, at least 1h)")
+	auditCmd.Flags().Bool("local-only", false, "Only audit the selected cluster")
+	auditCmd.Flags().Bool("all", false, "Also list healthy schedules")
+	auditCmd.Flags().Bool("record", false, "Record failures as alerts in the run history")
+
+	jobsCmd.AddCommand(auditCmd)
+	return jobsCmd
+}
+
+// createCredsCommand adds the credential expiry commands
+func createCredsCommand() *cobra.Command {
+	credsCmd := &cobra.Command{
+		Use:   "creds",
+		Short: "Track token and certificate expiry",
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show every known credential with its expiry countdown",
+		Long: "Aggregate the GitHub token, Vault transit token, mesh remote secret tokens, kubeconfig client certificates, " +
+			"east-west gateway TLS and cert-manager certificates of both clusters with rotation hints",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			warn, _ := cmd.Flags().GetDuration("warn")
+			failOnExpiring, _ := cmd.Flags().GetBool("fail-on-expiring")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			attention := 0
+			for _, credential := range orchestrator.CredentialStatus(cmd.Context()) {
+				fields := []interface{}{"kind", credential.Kind}
+				if credential.Cluster != "" {
+					fields = append(fields, "cluster", credential.Cluster)
+				}
+
+				switch state := credential.State(warn); state {
+				case credentials.StateNoExpiry:
+					log.Info("♾️  "+credential.Name, append(fields, "expires", "never")...)
+				case credentials.StateValid:
+					log.Info("✅ "+credential.Name, append(fields, "expires_in", formatCountdown(credential.Remaining()))...)
+				case credentials.StateExpiring, credentials.StateExpired:
+					attention++
+					icon, countdown := "⏳", formatCountdown(credential.Remaining())
+					if state == credentials.StateExpired {
+						icon, countdown = "❌", "expired"
+					}
+					log.Warn(icon+" "+credential.Name, append(fields, "expires_in", countdown, "hint", credential.Hint)...)
+				default:
+					log.Warn("❔ "+credential.Name, append(fields, "error", credential.Error, "hint", credential.Hint)...)
+				}
+			}
+
+			if attention > 0 && failOnExpiring {
+				return fmt.Errorf("%d credential(s) expire within %s", attention, warn)
+			}
+			return nil
+		},
+	}
+	statusCmd.Flags().Duration("warn", 14*24*time.Hour, "Flag credentials expiring within this duration")
+	statusCmd.Flags().Bool("fail-on-expiring", false, "Exit non-zero when a credential is expired or expiring")
+
+	credsCmd.AddCommand(statusCmd)
+	return credsCmd
+}
+
+// formatCountdown renders a remaining duration in days and hours
+func formatCountdown(remaining time.Duration) string {
+	days := int(remaining.Hours()) / 24
+	hours := int(remaining.Hours()) % 24
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh", days, hours)
+	}
+	return remaining.Round(time.Minute).String()
+}
+
+// createWhyCommand adds the interactive troubleshooting of a failing Kustomization
+func createWhyCommand() *cobra.Command {
+	whyCmd := &cobra.Command{
+		Use:   "why <kustomization>",
+		Short: "Explain why a Flux Kustomization is not ready",
+		Long: "Walk the reconciliation chain of a Kustomization: source revision and fetch errors, build errors, " +
+			"unhealthy inventory entries, then the events and container logs of the offending workload",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, _ := cmd.Flags().GetString("namespace")
+			noTui, _ := cmd.Flags().GetBool("no-tui")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			diagnose := func(ctx context.Context) (*flux.Diagnosis, error) {
+				return orchestrator.DiagnoseKustomization(ctx, namespace, args[0])
+			}
+
+			if !noTui && !output.Structured() {
+				if _, err := tea.NewProgram(tui.NewWhyModel(cmd.Context(), args[0], diagnose), tea.WithAltScreen()).Run(); err != nil {
+					return fmt.Errorf("troubleshooting TUI failed: %w", err)
+				}
+				return nil
+			}
+
+			diagnosis, err := diagnose(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if output.Structured() {
+				if err := output.Print(diagnosis); err != nil {
+					return err
+				}
+			} else {
+				printDiagnosis(diagnosis)
+			}
+
+			if root := diagnosis.RootCause(); root >= 0 {
+				return fmt.Errorf("kustomization %s/%s broken at %s", namespace, args[0], diagnosis.Layers[root].Title)
+			}
+			return nil
+		},
+	}
+	whyCmd.Flags().StringP("namespace", "n", "flux-system", "Namespace of the Kustomization")
+	whyCmd.Flags().Bool("no-tui", false, "Print every layer instead of opening the interactive TUI")
+
+	return whyCmd
+}
+
+// printDiagnosis logs every layer of a diagnosis with its details
+func printDiagnosis(diagnosis *flux.Diagnosis) {
+	for _, layer := range diagnosis.Layers {
+		if layer.Status == flux.LayerFailed {
+			log.Warn(tui.LayerIcon(layer.Status)+" "+layer.Title, "summary", layer.Summary)
+		} else {
+			log.Info(tui.LayerIcon(layer.Status)+" "+layer.Title, "summary", layer.Summary)
+		}
+		for _, detail := range layer.Details {
+			fmt.Println("    " + detail)
+		}
+	}
+}
+
+// createOutdatedCommand adds the channel-aware component version check
+func createOutdatedCommand() *cobra.Command {
+	outdatedCmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "List platform components behind their release channel",
+		Long: "Compare the deployed Cilium, Istio, Flux and Rook versions of both clusters with the newest release " +
+			"of the channel each cluster tracks (stable or edge, configured under channels)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			localOnly, _ := cmd.Flags().GetBool("local-only")
+			failOnOutdated, _ := cmd.Flags().GetBool("fail-on-outdated")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			statuses, err := orchestrator.Outdated(cmd.Context(), !localOnly)
+			if err != nil {
+				return err
+			}
+
+			outdated := 0
+			for _, status := range statuses {
+				fields := []interface{}{"cluster", status.Cluster, "channel", status.Channel}
+				switch {
+				case status.Error != "":
+					log.Warn("❔ "+status.Component, append(fields, "current", status.Current, "error", status.Error)...)
+				case !status.Installed:
+					log.Debug("Component not installed", append(fields, "component", status.Component)...)
+				case status.Outdated:
+					outdated++
+					log.Warn("⬆️  "+status.Component, append(fields, "current", status.Current, "proposed", status.Proposed)...)
+				case status.Ahead:
+					log.Info("🧪 "+status.Component, append(fields, "current", status.Current, "channel_latest", status.Proposed)...)
+				default:
+					log.Info("✅ "+status.Component, append(fields, "current", status.Current)...)
+				}
+			}
+
+			if outdated > 0 && failOnOutdated {
+				return fmt.Errorf("%d component(s) behind their channel", outdated)
+			}
+			return nil
+		},
+	}
+	outdatedCmd.Flags().Bool("local-only", false, "Skip the peer cluster")
+	outdatedCmd.Flags().Bool("fail-on-outdated", false, "Exit non-zero when a component is behind its channel")
+
+	return outdatedCmd
+}
+
+// createUpgradeCommand adds the upgrade advisor of the platform components
+func createUpgradeCommand() *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Plan upgrades of the platform components",
+	}
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Compare the installed Flux, Cilium and Istio versions with their latest releases",
+		Long: "Read the installed Flux, Cilium and Istio versions from the cluster and compare them with the newest release of " +
+			"the channel each component tracks, from the GitHub releases and the Cilium Helm index cached under " + upgrade.CacheDir + ". " +
+			"Upgrades crossing a major version or more minor versions than the component supports, and releases whose notes " +
+			"announce breaking changes, are flagged. --pin writes the proposed versions into the cluster config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pin, _ := cmd.Flags().GetBool("pin")
+			refresh, _ := cmd.Flags().GetBool("refresh")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			advice, err := orchestrator.UpgradeAdvice(cmd.Context(), refresh)
+			if err != nil {
+				return err
+			}
+
+			if pin {
+				if err := pinUpgrades(clusterType, advice); err != nil {
+					return err
+				}
+			}
+
+			if output.Structured() {
+				return output.Print(advice)
+			}
+			for _, component := range advice {
+				fields := []interface{}{"cluster", component.Cluster, "channel", component.Channel}
+				if component.Pinned != "" {
+					fields = append(fields, "pinned", component.Pinned)
+				}
+				switch {
+				case component.Error != "":
+					log.Warn("❔ "+component.Component, append(fields, "installed", component.Installed, "error", component.Error)...)
+				case component.Installed == "":
+					log.Info("➖ "+component.Component+" not installed", append(fields, "latest", component.Latest)...)
+				case component.UpgradeAvailable:
+					log.Warn("⬆️  "+component.Component, append(fields, "installed", component.Installed, "latest", component.Latest, "changelog", component.Changelog)...)
+				default:
+					log.Info("✅ "+component.Component, append(fields, "installed", component.Installed)...)
+				}
+				for _, warning := range component.Warnings {
+					fmt.Println("    ⚠️  " + warning)
+				}
+			}
+			return nil
+		},
+	}
+	checkCmd.Flags().Bool("pin", false, "Pin the proposed versions in the cluster config (gitops.flux_version, cilium.version, networking.service_mesh.version)")
+	checkCmd.Flags().Bool("refresh", false, "Fetch the release lists again instead of using the cached ones")
+
+	upgradeCmd.AddCommand(checkCmd)
+	return upgradeCmd
+}
+
+// pinUpgrades writes the latest version of every component with an upgrade available into the config file
+// of the cluster, keeping its comments
+func pinUpgrades(clusterType string, advice []upgrade.Advice) error {
+	isNAS := clusterType == "nas"
+	path, err := config.NewLoader().FindConfigFile(clusterType)
+	if err != nil {
+		return err
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	updated := original
+	for _, component := range advice {
+		if !component.UpgradeAvailable {
+			continue
+		}
+		keys := upgrade.PinPath(component.Component, isNAS)
+		if keys == nil {
+			log.Warn("The config cannot pin this component, upgrade it through its GitOps manifests", "component", component.Component, "cluster", clusterType)
+			continue
+		}
+		if updated, err = config.SetVersion(updated, clusterType, keys, component.Latest); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		log.Info("📌 Pinned "+component.Component, "key", clusterType+"."+strings.Join(keys, "."), "version", component.Latest)
+	}
+
+	if bytes.Equal(original, updated) {
+		log.Info("No version to pin", "config", path)
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, updated, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	log.Info("📝 Pinned versions written, bootstrap again to roll them out", "config", path)
+	return nil
+}
+
+// createDriftCommand adds the comparison of bootstrap-managed objects with their rendered state
+func createDriftCommand() *cobra.Command {
+	driftCmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Detect drift of the objects the bootstrap manages",
+		Long: "List every object carrying the homelab-bootstrap field manager and diff the ones the bootstrap renders " +
+			"(Flux install, Flux sync manifests, cluster-vars) against the cluster, reporting added, removed and modified fields",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fix, _ := cmd.Flags().GetBool("fix")
+			failOnDrift, _ := cmd.Flags().GetBool("fail-on-drift")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			report, err := orchestrator.Drift(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if output.Structured() {
+				if err := output.Print(report); err != nil {
+					return err
+				}
+			} else {
+				printDrift(report)
+			}
+
+			if report.Clean() {
+				return nil
+			}
+			if fix {
+				if err := orchestrator.FixDrift(cmd.Context(), report); err != nil {
+					return fmt.Errorf("failed to fix drift: %w", err)
+				}
+				log.Info("✅ Drifted objects re-applied", "objects", len(report.Drifted))
+				return nil
+			}
+			if failOnDrift {
+				return fmt.Errorf("%d managed object(s) drifted", len(report.Drifted))
+			}
+			return nil
+		},
+	}
+	driftCmd.Flags().Bool("fix", false, "Re-apply the rendered state of drifted objects")
+	driftCmd.Flags().Bool("fail-on-drift", false, "Exit non-zero when an object drifted and --fix is not set")
+
+	return driftCmd
+}
+
+// printDrift logs the drifted objects with their changed fields, then the managed objects without a rendered state
+func printDrift(report *drift.Report) {
+	log.Info("Managed objects", "count", len(report.Managed), "rendered", len(report.Managed)-len(report.Unrendered))
+	if report.Clean() {
+		log.Info("✅ No drift from the rendered state")
+	}
+	for _, object := range report.Drifted {
+		if object.Missing {
+			log.Warn("❌ "+object.String(), "source", object.Source, "status", "missing")
+			continue
+		}
+		log.Warn("🔀 "+object.String(), "source", object.Source, "fields", len(object.Changes))
+		for _, change := range object.Changes {
+			fmt.Printf("    %-8s %s\n", change.Type, change.Path)
+		}
+	}
+	for _, object := range report.Unrendered {
+		log.Info("• "+object.String(), "status", "applied imperatively, not compared")
+	}
+}
+
+// createRestoreCommand re-applies a snapshot exported before a destroy
+func createRestoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <tarball>",
+		Short: "Re-apply a cluster snapshot taken before a destroy",
+		Long: "Apply the CRDs, persistent volumes and claims, secrets and Flux objects of a snapshot written by destroy, in that order. " +
+			"Secrets are decrypted with the SOPS age key of the cluster and skipped when it is unavailable",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			cfg, err := cmdutil.LoadConfig(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+			var client *k8s.Client
+			if clusterType == "nas" {
+				client, err = k8s.NewClientWithContext(cfg.NAS.Cluster.KubeConfig, cfg.NAS.Cluster.KubeContext)
+			} else {
+				client, err = k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to connect to cluster: %w", err)
+			}
+
+			log.Info("📦 Restoring cluster state", "snapshot", args[0], "cluster", clusterType)
+			restorer := snapshot.NewRestorer(client, cmdutil.SnapshotKey(cmd.Context(), cfg, clusterType))
+			result, err := restorer.Restore(cmd.Context(), args[0])
+			if result != nil && result.Manifest != nil && result.Manifest.Cluster != clusterType {
+				log.Warn("Snapshot was taken from another cluster", "snapshot", result.Manifest.Cluster, "target", clusterType)
+			}
+			if output.Structured() && result != nil {
+				if printErr := output.Print(result); printErr != nil {
+					return printErr
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			log.Info("✅ Cluster state restored", "objects", result.Applied, "skipped", result.Skipped)
+			return nil
+		},
+	}
+}
+
+// createCertManagerCommand adds the cert-manager validation commands
+func createCertManagerCommand() *cobra.Command {
+	certManagerCmd := &cobra.Command{
+		Use:   "cert-manager",
+		Short: "Validate cert-manager and its issuers",
+		Long:  "Wait for the cert-manager webhook, check every ClusterIssuer is ready and report certificates close to expiry",
+	}
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the webhook, ClusterIssuers and expiring certificates",
+		Long: "Wait for the cert-manager webhook, then report the readiness of every ClusterIssuer (Vault PKI, ACME, CA) " +
+			"and the certificates not ready or expiring within --within. --test-certificate requests a short-lived " +
+			"certificate from the named issuer and removes it once issued",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+			client := orchestrator.CertManager()
+			installed, err := client.Installed(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if !installed {
+				return fmt.Errorf("cert-manager is not installed on %s", clusterType)
+			}
+
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			if err := client.WaitForWebhook(cmd.Context(), timeout); err != nil {
+				return err
+			}
+			issuers, issuerErr := client.Issuers(cmd.Context())
+			if issuerErr != nil {
+				return issuerErr
+			}
+			certificates, err := client.Certificates(cmd.Context())
+			if err != nil {
+				return err
+			}
+			within, _ := cmd.Flags().GetString("within")
+			window, err := cmdutil.ParseDuration(within)
+			if err != nil {
+				return fmt.Errorf("invalid --within: %w", err)
+			}
+			expiring := certmanager.Expiring(certificates, window)
+
+			if issuer, _ := cmd.Flags().GetString("test-certificate"); issuer != "" {
+				namespace, _ := cmd.Flags().GetString("namespace")
+				dnsName, _ := cmd.Flags().GetString("dns-name")
+				if dnsName == "" {
+					return fmt.Errorf("--test-certificate needs --dns-name, a name the issuer may sign")
+				}
+				if err := client.TestCertificate(cmd.Context(), issuer, namespace, dnsName, timeout); err != nil {
+					return err
+				}
+				log.Info("✅ Test certificate issued", "issuer", issuer, "dns_name", dnsName)
+			}
+
+			if output.Structured() {
+				if err := output.Print(map[string]interface{}{"issuers": issuers, "expiring": expiring}); err != nil {
+					return err
+				}
+			} else {
+				for _, issuer := range issuers {
+					if issuer.Ready {
+						log.Info("✅ "+issuer.Name, "type", issuer.Type, "server", issuer.Server)
+					} else {
+						log.Warn("❌ "+issuer.Name, "type", issuer.Type, "server", issuer.Server, "message", issuer.Message)
+					}
+				}
+				for _, cert := range expiring {
+					expires := "unknown"
+					if cert.NotAfter != nil {
+						expires = cert.NotAfter.Format(time.RFC3339)
+					}
+					log.Warn("🔐 "+cert.Namespace+"/"+cert.Name, "issuer", cert.Issuer, "ready", cert.Ready, "expires", expires, "message", cert.Message)
+				}
+				log.Info("📜 Certificates", "total", len(certificates), "attention", len(expiring))
+			}
+
+			for _, issuer := range issuers {
+				if !issuer.Ready {
+					return fmt.Errorf("ClusterIssuer %s is not ready", issuer.Name)
+				}
+			}
+			return nil
+		},
+	}
+	checkCmd.Flags().Duration("timeout", 5*time.Minute, "How long to wait for the webhook and the test certificate")
+	checkCmd.Flags().String("within", "14d", "Report certificates expiring within this duration")
+	checkCmd.Flags().String("test-certificate", "", "Request a test certificate from this ClusterIssuer")
+	checkCmd.Flags().String("dns-name", "", "DNS name of the test certificate")
+	checkCmd.Flags().String("namespace", certmanager.Namespace, "Namespace of the test certificate")
+
+	certManagerCmd.AddCommand(checkCmd)
+	return certManagerCmd
+}
+
+// createBackupCommand adds the Velero backup commands
+func createBackupCommand() *cobra.Command {
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Manage Velero backups to the NAS MinIO",
+		Long:  "Install Velero with the NAS MinIO as backup storage, trigger on-demand backups, list restore points and restore them",
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install Velero and apply the backup schedules",
+		Long: "Install Velero with the AWS plugin pointed at the MinIO bucket of backup.s3_url, then apply backup.schedules " +
+			"(a daily backup kept 30 days when none is configured). Credentials are read from MINIO_ACCESS_KEY and MINIO_SECRET_KEY",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+			opts, err := orchestrator.BackupOptions()
+			if err != nil {
+				return err
+			}
+
+			log.Info("💾 Installing Velero", "cluster", clusterType, "s3", opts.S3URL, "bucket", opts.Bucket)
+			if err := orchestrator.Velero().Install(cmd.Context(), opts); err != nil {
+				return err
+			}
+			log.Info("✅ Velero ready", "schedules", len(opts.Schedules))
+			return nil
+		},
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Trigger an on-demand backup",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			req := backup.BackupRequest{}
+			if len(args) > 0 {
+				req.Name = args[0]
+			}
+			req.Namespaces, _ = cmd.Flags().GetStringSlice("namespace")
+			req.TTL, _ = cmd.Flags().GetDuration("ttl")
+			if noWait, _ := cmd.Flags().GetBool("no-wait"); !noWait {
+				req.Wait, _ = cmd.Flags().GetDuration("timeout")
+			}
+
+			point, err := orchestrator.Velero().CreateBackup(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+			if output.Structured() {
+				return output.Print(point)
+			}
+			log.Info("✅ Backup "+point.Name, "phase", point.Phase, "errors", point.Errors, "warnings", point.Warnings)
+			return nil
+		},
+	}
+	createCmd.Flags().StringSlice("namespace", nil, "Namespaces to back up (default all)")
+	createCmd.Flags().Duration("ttl", 0, "How long to keep the backup (default the Velero default, 720h)")
+	createCmd.Flags().Bool("no-wait", false, "Return as soon as the backup is created")
+	createCmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for the backup to finish")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the restore points",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			points, err := orchestrator.Velero().ListBackups(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if output.Structured() {
+				return output.Print(points)
+			}
+			if len(points) == 0 {
+				log.Info("No backups yet")
+				return nil
+			}
+			for _, point := range points {
+				log.Info(point.Name,
+					"phase", point.Phase,
+					"schedule", point.Schedule,
+					"started", point.Started.Format(time.RFC3339),
+					"expires", point.Expires.Format(time.RFC3339),
+					"errors", point.Errors)
+			}
+			return nil
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <backup>",
+		Short: "Restore a backup",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			req := backup.RestoreRequest{Backup: args[0]}
+			req.Namespaces, _ = cmd.Flags().GetStringSlice("namespace")
+			if noWait, _ := cmd.Flags().GetBool("no-wait"); !noWait {
+				req.Wait, _ = cmd.Flags().GetDuration("timeout")
+			}
+
+			result, err := orchestrator.Velero().Restore(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+			if output.Structured() {
+				return output.Print(result)
+			}
+			log.Info("✅ Restore "+result.Name, "backup", result.Backup, "phase", result.Phase, "errors", result.Errors, "warnings", result.Warnings)
+			return nil
+		},
+	}
+	restoreCmd.Flags().StringSlice("namespace", nil, "Namespaces to restore (default every namespace of the backup)")
+	restoreCmd.Flags().Bool("no-wait", false, "Return as soon as the restore is created")
+	restoreCmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for the restore to finish")
+
+	backupCmd.AddCommand(installCmd, createCmd, listCmd, restoreCmd)
+	return backupCmd
+}
+
+// createNamespacesCommand adds the namespace baseline management
+func createNamespacesCommand() *cobra.Command {
+	namespacesCmd := &cobra.Command{
+		Use:   "namespaces",
+		Short: "Manage the namespace baseline",
+	}
+
+	baselineCmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "Reconcile all namespaces against the declared baseline",
+		Long: "Apply the Pod Security and mesh labels, default NetworkPolicy, ResourceQuota and LimitRange declared " +
+			"under security.namespace_baseline to every namespace that is not excluded",
+		RunE: cmdutil.Recorded("namespaces baseline", "", func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			orchestrator, err := cmdutil.NewOrchestrator(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			changes, err := orchestrator.ReconcileNamespaceBaseline(cmd.Context(), dryRun)
+			if err != nil {
+				return err
+			}
+
+			verb := "Applied"
+			if dryRun {
+				verb = "Would apply"
+			}
+			for _, change := range changes {
+				fields := []interface{}{"namespace", change.Namespace, "kind", change.Kind, "name", change.Name}
+				if change.Detail != "" {
+					fields = append(fields, "value", change.Detail)
+				}
+				if change.Action == baseline.ActionConflict {
+					log.Warn("⚠️  Unmanaged object in the way", fields...)
+					continue
+				}
+				log.Info("🔧 "+verb+" "+change.Action, fields...)
+			}
+			if len(changes) == 0 {
+				log.Info("✅ All namespaces match the baseline")
+			}
+			return nil
+		}),
+	}
+	baselineCmd.Flags().Bool("dry-run", false, "Only report the changes")
+
+	namespacesCmd.AddCommand(baselineCmd)
+	return namespacesCmd
+}
+
+// createConfigCommand adds config file maintenance
+func createConfigCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Maintain the homelab and nas config files",
+	}
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate [file...]",
+		Short: "Upgrade config files to the current schema version",
+		Long: "Rewrite homelab.yaml and nas.yaml, or the given files, to the current config schema. " +
+			"The diff is only previewed unless --write is set, which keeps the original as <file>.bak",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			write, _ := cmd.Flags().GetBool("write")
+
+			files := args
+			if len(files) == 0 {
+				loader := config.NewLoader()
+				for _, configType := range []string{"homelab", "nas"} {
+					if path, err := loader.FindConfigFile(configType); err == nil {
+						files = append(files, path)
+					}
+				}
+				if len(files) == 0 {
+					return fmt.Errorf("no config file found, pass the files to migrate")
+				}
+			}
+
+			pending := 0
+			for _, path := range files {
+				original, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", path, err)
+				}
+				result, err := config.Migrate(original)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				if !result.Changed() {
+					log.Info("✅ Config already at the current schema", "file", path, "version", result.To)
+					continue
+				}
+
+				pending++
+				log.Info("🔄 Config schema outdated", "file", path, "from", result.From, "to", result.To)
+				for _, migration := range result.Applied {
+					log.Info("  • "+migration.Description, "version", migration.To)
+				}
+				diff, err := result.Diff(original, path)
+				if err != nil {
+					return fmt.Errorf("failed to diff %s: %w", path, err)
+				}
+				fmt.Println(diff)
+
+				if !write {
+					continue
+				}
+				if err := os.WriteFile(path+".bak", original, 0o600); err != nil {
+					return fmt.Errorf("failed to back up %s: %w", path, err)
+				}
+				if err := os.WriteFile(path, result.Data, 0o600); err != nil {
+					return fmt.Errorf("failed to write %s: %w", path, err)
+				}
+				log.Info("✅ Config migrated", "file", path, "backup", path+".bak")
+			}
+
+			if pending > 0 && !write {
+				log.Info("Re-run with --write to apply the migration", "files", pending)
+			}
+			return nil
+		},
+	}
+	migrateCmd.Flags().Bool("write", false, "Write the migrated files, keeping a .bak copy")
+
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Scaffold homelab and nas config files with a wizard",
+		Long: "Ask for the cluster names, node IPs, GitOps repository, storage provider, mesh and NAS host, " +
+			"then write validated homelab.yaml and nas.yaml files and a .env template to fill in. " +
+			"Existing config files are kept unless --force is set, an existing .env is never overwritten",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, _ := cmd.Flags().GetString("dir")
+			force, _ := cmd.Flags().GetBool("force")
+			defaults, _ := cmd.Flags().GetBool("defaults")
+
+			root, err := bootstrapPkg.ProjectRoot()
+			if err != nil {
+				return err
+			}
+			if dir == "" {
+				dir = filepath.Join(root, "configs")
+				if _, err := os.Stat(filepath.Join(root, "bootstrap")); err == nil {
+					dir = filepath.Join(root, "bootstrap", "configs")
+				}
+			}
+
+			answers := config.DefaultScaffoldAnswers()
+			if !defaults {
+				wizard := tui.NewConfigWizardModel(answers)
+				if _, err := tea.NewProgram(wizard).Run(); err != nil {
+					return fmt.Errorf("config wizard failed: %w", err)
+				}
+				var ok bool
+				if answers, ok = wizard.Answers(); !ok {
+					return fmt.Errorf("config init cancelled")
+				}
+			}
+
+			scaffold, err := answers.Render()
+			if err != nil {
+				return err
+			}
+			files := []struct {
+				path string
+				data []byte
+			}{
+				{filepath.Join(dir, "homelab.yaml"), scaffold.Homelab},
+				{filepath.Join(dir, "nas.yaml"), scaffold.NAS},
+			}
+			for _, file := range files {
+				if _, err := os.Stat(file.path); err == nil && !force && file.data != nil {
+					return fmt.Errorf("%s already exists, pass --force to overwrite it", file.path)
+				}
+			}
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+			for _, file := range files {
+				if file.data == nil {
+					continue
+				}
+				if err := os.WriteFile(file.path, file.data, 0o644); err != nil {
+					return fmt.Errorf("failed to write %s: %w", file.path, err)
+				}
+				log.Info("✅ Config written", "file", file.path)
+			}
+
+			envPath := filepath.Join(root, ".env")
+			if _, err := os.Stat(envPath); err == nil {
+				envPath = filepath.Join(root, ".env.template")
+				log.Info("Keeping the existing .env, writing the template next to it", "file", envPath)
+			}
+			if err := os.WriteFile(envPath, scaffold.Env, 0o600); err != nil {
+				return fmt.Errorf("failed to write %s: %w", envPath, err)
+			}
+			log.Info("✅ Env template written, fill in the secrets before bootstrapping", "file", envPath)
+			log.Info("Next: ./bootstrap doctor, then ./bootstrap homelab bootstrap")
+			return nil
+		},
+	}
+	initCmd.Flags().String("dir", "", "Directory to write the config files to (default: bootstrap/configs)")
+	initCmd.Flags().Bool("force", false, "Overwrite existing config files")
+	initCmd.Flags().Bool("defaults", false, "Skip the wizard and write the reference answers")
+
+	renderCmd := &cobra.Command{
+		Use:   "render [homelab|nas]",
+		Short: "Print a config file with the selected profile merged in",
+		Long: "Print homelab.yaml or nas.yaml as the loader reads it once --profile is merged over it. " +
+			"Mappings merge key by key, lists and other values in the profile replace the base ones and null removes a key",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defaultCluster := "homelab"
+			if len(args) == 1 {
+				defaultCluster = args[0]
+			}
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), defaultCluster)
+			if err != nil {
+				return err
+			}
+
+			rendered, err := config.NewLoader().Render(clusterType)
+			if err != nil {
+				return err
+			}
+			if rendered.Profile != "" {
+				log.Info("Rendering config", "base", rendered.Base, "profile", rendered.Profile)
+			} else {
+				log.Info("Rendering config without profile", "base", rendered.Base)
+			}
+			if _, err := config.ParseAndValidate(rendered.Data); err != nil {
+				log.Warn("Rendered config is invalid", "error", err)
+			}
+			_, err = os.Stdout.Write(rendered.Data)
+			return err
+		},
+	}
+
+	configCmd.AddCommand(migrateCmd)
+	configCmd.AddCommand(initCmd)
+	configCmd.AddCommand(renderCmd)
+	return configCmd
+}
+
+// createKubeconfigCommand combines the per-cluster kubeconfigs of the project for day-to-day kubectl use
+func createKubeconfigCommand() *cobra.Command {
+	kubeconfigCmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Export the cluster kubeconfigs",
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export [cluster...]",
+		Short: "Print or merge the kubeconfigs of every cluster",
+		Long: "Combine the infrastructure/<cluster>/" + kubeconfig.FileName + " files into one kubeconfig with a context " +
+			"per cluster named after it (homelab, nas) and the certificates embedded. It is printed, or merged into " +
+			"the kubeconfig given with --merge after backing it up",
+		Example: `  bootstrap kubeconfig export > ~/.kube/homelab.yaml
+  bootstrap kubeconfig export --merge
+  bootstrap kubeconfig export nas --merge ~/.kube/config`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectRoot, err := bootstrapPkg.ProjectRoot()
+			if err != nil {
+				return err
+			}
+			sources, err := kubeconfig.Discover(projectRoot)
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				sources = slices.DeleteFunc(sources, func(source kubeconfig.Source) bool {
+					return !slices.Contains(args, source.Cluster)
+				})
+				if len(sources) < len(args) {
+					return fmt.Errorf("no kubeconfig for some of %v, run the up command of each cluster first", args)
+				}
+			}
+			if len(sources) == 0 {
+				return fmt.Errorf("no kubeconfig found under %s, run homelab up or nas up first", filepath.Join(projectRoot, "infrastructure"))
+			}
+
+			exported, err := kubeconfig.Export(sources)
+			if err != nil {
+				return err
+			}
+			for _, source := range sources {
+				log.Info("📋 Exporting "+source.Cluster, "from", source.Path, "server", exported.Clusters[source.Cluster].Server)
+			}
+
+			target, _ := cmd.Flags().GetString("merge")
+			if target == "" {
+				data, err := clientcmd.Write(*exported)
+				if err != nil {
+					return err
+				}
+				_, err = output.GetManager().GetStdout().Write(data)
+				logContextHints(sources, "")
+				return err
+			}
+
+			if strings.HasPrefix(target, "~/") {
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return err
+				}
+				target = filepath.Join(home, target[2:])
+			}
+			result, err := kubeconfig.Merge(target, exported)
+			if err != nil {
+				return err
+			}
+			if output.Structured() {
+				return output.Print(result)
+			}
+			if result.Backup != "" {
+				log.Info("💾 Previous kubeconfig backed up", "file", result.Backup)
+			}
+			log.Info("✅ Kubeconfig merged", "file", result.Path, "added", result.Added, "replaced", result.Replaced)
+			logContextHints(sources, result.Path)
+			return nil
+		},
+	}
+	exportCmd.Flags().String("merge", "", "Merge into this kubeconfig instead of printing (default "+clientcmd.RecommendedHomeFile+" when given without a value)")
+	exportCmd.Flags().Lookup("merge").NoOptDefVal = clientcmd.RecommendedHomeFile
+
+	kubeconfigCmd.AddCommand(exportCmd)
+	return kubeconfigCmd
+}
+
+// logContextHints shows how to reach each exported cluster with kubectl
+func logContextHints(sources []kubeconfig.Source, merged string) {
+	if merged == "" {
+		log.Info("💡 Save the output and point KUBECONFIG at it, or pass --merge to add the contexts to ~/.kube/config")
+	} else if merged != clientcmd.RecommendedHomeFile && os.Getenv("KUBECONFIG") != merged {
+		log.Info("💡 kubectl reads "+merged+" once KUBECONFIG points at it", "export", "KUBECONFIG="+merged)
+	}
+	for _, source := range sources {
+		log.Info("💡 Switch to "+source.Cluster, "command", "kubectl config use-context "+source.Cluster)
+	}
+}
+
+// createSecretsCommand adds SOPS helpers to keep .env material encrypted in the repo
+func createSecretsCommand() *cobra.Command {
+	secretsCmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Encrypt and decrypt .env files with SOPS and age",
+	}
+
+	encryptCmd := &cobra.Command{
+		Use:   "encrypt [file]",
+		Short: "Encrypt an env file so it can be committed",
+		Long: "Encrypt .env, or the given dotenv file, with sops for the age recipients. " +
+			"The result is written next to it as <file>.enc unless --out is set",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := secretsFileArg(args, ".env")
+			if err != nil {
+				return err
+			}
+			out, _ := cmd.Flags().GetString("out")
+			if out == "" {
+				out = secrets.EncryptedEnvPath(path)
+			}
+
+			recipients, _ := cmd.Flags().GetStringSlice("age")
+			if len(recipients) == 0 {
+				keyFile, _ := cmd.Flags().GetString("age-key-file")
+				key, err := secrets.LoadAgeKey(cmd.Context(), keyFile)
+				if err != nil {
+					return err
+				}
+				recipients = []string{key.Recipient}
+			}
+
+			if err := secrets.EncryptEnvFile(cmd.Context(), path, out, recipients); err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", path, err)
+			}
+			log.Info("🔒 Env file encrypted", "file", path, "output", out, "recipients", len(recipients))
+			return nil
+		},
+	}
+	encryptCmd.Flags().String("out", "", "Encrypted output file (default <file>.enc)")
+	encryptCmd.Flags().StringSlice("age", nil, "Age recipients to encrypt for (default the public key of the age key file)")
+	encryptCmd.Flags().String("age-key-file", "", "Age identity file (default $SOPS_AGE_KEY_FILE or the sops key file)")
+
+	decryptCmd := &cobra.Command{
+		Use:   "decrypt [file]",
+		Short: "Decrypt an env file encrypted with secrets encrypt",
+		Long: "Decrypt .env.enc, or the given file, with the age key. " +
+			"The plaintext is written without the .enc suffix unless --out is set, readable only by its owner",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := secretsFileArg(args, ".env"+secrets.EncryptedEnvSuffix)
+			if err != nil {
+				return err
+			}
+			out, _ := cmd.Flags().GetString("out")
+			if out == "" {
+				out = secrets.DecryptedEnvPath(path)
+			}
+			force, _ := cmd.Flags().GetBool("force")
+			if _, err := os.Stat(out); err == nil && !force {
+				return fmt.Errorf("%s already exists, pass --force to overwrite it", out)
+			}
+
+			keyFile, _ := cmd.Flags().GetString("age-key-file")
+			key, err := secrets.LoadAgeKey(cmd.Context(), keyFile)
+			if err != nil {
+				return err
+			}
+			if err := secrets.DecryptEnvFile(cmd.Context(), path, out, key); err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", path, err)
+			}
+			log.Info("🔓 Env file decrypted", "file", path, "output", out)
+			return nil
+		},
+	}
+	decryptCmd.Flags().String("out", "", "Plaintext output file (default the file without .enc)")
+	decryptCmd.Flags().String("age-key-file", "", "Age identity file (default $SOPS_AGE_KEY_FILE or the sops key file)")
+	decryptCmd.Flags().Bool("force", false, "Overwrite an existing plaintext file")
+
+	secretsCmd.AddCommand(encryptCmd, decryptCmd)
+	return secretsCmd
+}
+
+// secretsFileArg returns the file argument, or name in the project root
+func secretsFileArg(args []string, name string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	projectRoot, err := bootstrapPkg.ProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(projectRoot, name), nil
+}
+
+// createLogsCommand shows the per-run JSON-lines logs kept under .bootstrap/logs
+func createLogsCommand() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the log of a recorded run",
+		Long: "Print the structured log of a run from .bootstrap/logs, the most recent by default. " +
+			"Every line carries the run, cluster and bootstrap step it belongs to; the last " +
+			fmt.Sprint(logger.MaxRunLogs) + " runs are kept",
+		Example: `  bootstrap logs
+  bootstrap logs --run last --step install-fluxcd
+  bootstrap logs --follow
+  bootstrap logs --list`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectRoot, err := bootstrapPkg.ProjectRoot()
+			if err != nil {
+				return err
+			}
+			dir := filepath.Join(projectRoot, logger.RunLogDir)
+
+			if list, _ := cmd.Flags().GetBool("list"); list {
+				files, err := logger.ListRunLogs(dir)
+				if err != nil {
+					return err
+				}
+				if len(files) == 0 {
+					log.Info("No run logs yet")
+					return nil
+				}
+				for i := len(files) - 1; i >= 0; i-- {
+					log.Info(files[i].Run, "started", files[i].Started.Local().Format(time.RFC3339), "path", files[i].Path)
+				}
+				return nil
+			}
+
+			run, _ := cmd.Flags().GetString("run")
+			step, _ := cmd.Flags().GetString("step")
+			follow, _ := cmd.Flags().GetBool("follow")
+			file, err := logger.FindRunLog(dir, run)
+			if err != nil {
+				return err
+			}
+			log.Debug("Reading run log", "path", file.Path)
+
+			var at time.Time
+			viewer := log.NewWithOptions(os.Stdout, log.Options{
+				ReportTimestamp: true,
+				TimeFormat:      time.TimeOnly,
+				TimeFunction:    func(time.Time) time.Time { return at },
+				Level:           log.DebugLevel,
+			})
+			return logger.ReadRunLog(cmd.Context(), file.Path, follow, func(entry logger.Entry, raw []byte) {
+				if value, _ := entry.Value("step"); step != "" && fmt.Sprint(value) != step {
+					return
+				}
+				if output.Structured() {
+					fmt.Println(string(raw))
+					return
+				}
+				at = entry.Time.Local()
+				entry.Render(viewer)
+			})
+		},
+	}
+	logsCmd.Flags().String("run", "last", "Run ID (or prefix) to show, last for the most recent")
+	logsCmd.Flags().String("step", "", "Only show lines of this bootstrap step")
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep printing new lines as the run writes them")
+	logsCmd.Flags().Bool("list", false, "List the kept run logs")
+	return logsCmd
+}
+
+// createAuditCommand adds the review of the audit trail
+func createAuditCommand() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Review what the tool changed on the clusters",
+		Long: "Every create, update, patch, apply and delete the tool sends, and every helm, kubectl or task " +
+			"action it runs, is appended to " + audit.LogFile + " with its cluster, object, field manager, " +
+			"body hash and outcome",
+	}
+
+	logCmd := &cobra.Command{
+		Use:   "log",
+		Short: "List the changes of the last run",
+		Long: "List the mutating actions of the most recent invocation that made any, or of the invocation or " +
+			"run given with --run. Requests refused in read-only mode show as blocked",
+		Example: `  bootstrap audit log
+  bootstrap audit log --run 20260301-101500
+  bootstrap audit log --failed
+  bootstrap audit log --all -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectRoot, err := bootstrapPkg.ProjectRoot()
+			if err != nil {
+				return err
+			}
+			entries, err := audit.Read(projectRoot)
+			if err != nil {
+				return err
+			}
+			if all, _ := cmd.Flags().GetBool("all"); !all {
+				run, _ := cmd.Flags().GetString("run")
+				if entries, err = audit.Select(entries, run); err != nil {
+					return err
+				}
+			}
+			if failed, _ := cmd.Flags().GetBool("failed"); failed {
+				entries = slices.DeleteFunc(entries, func(entry audit.Entry) bool {
+					return entry.Outcome == audit.OutcomeSucceeded || entry.Outcome == audit.OutcomeStarted
+				})
+			}
+
+			if output.Structured() {
+				return output.Print(entries)
+			}
+			if len(entries) == 0 {
+				log.Info("No matching actions recorded")
+				return nil
+			}
+			invocation := ""
+			for _, entry := range entries {
+				if entry.Invocation != invocation {
+					invocation = entry.Invocation
+					log.Info("📜 "+invocation, "command", entry.Command, "run", entry.Run, "started", entry.Time.Local().Format(time.RFC3339))
+				}
+				message := entry.Verb + " " + entry.Name
+				fields := []interface{}{"time", entry.Time.Local().Format(time.TimeOnly)}
+				if entry.Verb != audit.VerbExternal {
+					message = entry.Verb + " " + entry.GVK() + " " + entry.Object()
+					fields = append(fields, "cluster", entry.Cluster, "manager", entry.FieldManager, "hash", entry.DiffHash)
+					if entry.As != "" {
+						fields = append(fields, "as", entry.As)
+					}
+				}
+				switch entry.Outcome {
+				case audit.OutcomeSucceeded, audit.OutcomeStarted:
+					log.Info("✅ "+message, fields...)
+				case audit.OutcomeBlocked:
+					log.Warn("🚫 "+message, append(fields, "error", entry.Error)...)
+				default:
+					log.Error("❌ "+message, append(fields, "code", entry.Code, "error", entry.Error)...)
+				}
+			}
+			return nil
+		},
+	}
+	logCmd.Flags().String("run", "last", "Invocation or run ID (or prefix) to show, last for the most recent")
+	logCmd.Flags().Bool("all", false, "Show the whole trail")
+	logCmd.Flags().Bool("failed", false, "Only show failed and blocked actions")
+	auditCmd.AddCommand(logCmd)
+
+	return auditCmd
+}
+
+// createServeCommand adds the HTTP API for remote control
+func createServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the bootstrap operations over an authenticated HTTP API",
+		Long: "Expose status, health, drift, Flux reconcile/suspend/resume and bootstraps over HTTP, so automation such as " +
+			"Home Assistant or CI can drive the clusters. Clients authenticate with a bearer token or, with --client-ca, " +
+			"a client certificate. Bootstraps run one at a time in the background and are recorded in the run history",
+		Example: `  BOOTSTRAP_SERVE_TOKEN=$(openssl rand -hex 32) bootstrap serve --listen :8888
+  bootstrap serve --token-file ~/.config/bootstrap/token --tls-cert server.crt --tls-key server.key
+  bootstrap serve --tls-cert server.crt --tls-key server.key --client-ca clients-ca.crt`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+			tokenFile, _ := cmd.Flags().GetString("token-file")
+			opts := server.Options{Listen: listen, Token: os.Getenv(server.TokenEnv)}
+			opts.TLSCert, _ = cmd.Flags().GetString("tls-cert")
+			opts.TLSKey, _ = cmd.Flags().GetString("tls-key")
+			opts.ClientCA, _ = cmd.Flags().GetString("client-ca")
+			if tokenFile != "" {
+				data, err := os.ReadFile(tokenFile)
+				if err != nil {
+					return fmt.Errorf("failed to read token file: %w", err)
+				}
+				opts.Token = strings.TrimSpace(string(data))
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if withSchedule, _ := cmd.Flags().GetBool("schedule"); withSchedule {
+				scheduler, err := newScheduler(ctx)
+				if err != nil {
+					return err
+				}
+				opts.Scheduler = scheduler
+			}
+			srv, err := server.New(ctx, opts)
+			if err != nil {
+				return err
+			}
+			if opts.Scheduler != nil {
+				go func() {
+					if err := opts.Scheduler.Run(ctx); err != nil {
+						log.Error("Scheduler stopped", "error", err)
+					}
+				}()
+			}
+			return srv.Run(ctx)
+		},
+	}
+	cmd.Flags().String("listen", ":8888", "Address to listen on")
+	cmd.Flags().Bool("schedule", false, "Also run the scheduled operations of the config, as bootstrap schedule run does")
+	cmd.Flags().String("token-file", "", "File holding the bearer token clients must send (default from "+server.TokenEnv+")")
+	cmd.Flags().String("tls-cert", "", "Server certificate, serves HTTPS when set")
+	cmd.Flags().String("tls-key", "", "Server certificate key")
+	cmd.Flags().String("client-ca", "", "CA verifying client certificates (mTLS), accepted alongside the token when one is set")
+	return cmd
+}
+
+// createScheduleCommand adds the runner of the scheduled operations
+func createScheduleCommand() *cobra.Command {
+	scheduleCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run the scheduled operations of the config",
+		Long: "Run the operations listed under schedules in the cluster sections of the config (" + strings.Join(schedule.Tasks, ", ") +
+			") at their cron times. Each run is recorded in the run history as schedule <name>, and notifications go to the " +
+			"cluster sinks, on failure by default",
+	}
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "run",
+		Short: "Run the scheduled operations in the foreground until interrupted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			scheduler, err := newScheduler(ctx)
+			if err != nil {
+				return err
+			}
+			return scheduler.Run(ctx)
+		},
+	})
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the scheduled operations with their next and last run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scheduler, err := newScheduler(cmd.Context())
+			if err != nil {
+				return err
+			}
+			registry, err := historyRegistry()
+			if err != nil {
+				return err
+			}
+			runs, err := registry.List()
+			if err != nil {
+				return err
+			}
+
+			type scheduled struct {
+				*schedule.Job
+				Next    time.Time    `json:"next"`
+				LastRun *history.Run `json:"lastRun,omitempty"`
+			}
+			now := time.Now()
+			var list []scheduled
+			for _, job := range scheduler.Jobs(now) {
+				entry := scheduled{Job: job, Next: job.Next(now)}
+				for _, run := range runs {
+					if run.Command == "schedule "+job.Name && run.Cluster == job.Cluster {
+						entry.LastRun = run
+						break
+					}
+				}
+				list = append(list, entry)
+			}
+
+			if output.Structured() {
+				return output.Print(list)
+			}
+			if len(list) == 0 {
+				log.Info("No schedules configured")
+				return nil
+			}
+			for _, entry := range list {
+				fields := []interface{}{"task", entry.Task, "schedule", entry.Schedule, "next", entry.Next.Format(time.RFC3339)}
+				if entry.LastRun != nil {
+					fields = append(fields, "last", entry.LastRun.StartedAt.Format(time.RFC3339), "status", entry.LastRun.Status, "run", entry.LastRun.ID)
+				}
+				log.Info("⏰ "+entry.ID(), fields...)
+			}
+			return nil
+		},
+	})
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "trigger <name>",
+		Short: "Run a scheduled operation now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scheduler, err := newScheduler(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return scheduler.Trigger(cmd.Context(), args[0])
+		},
+	})
+
+	return scheduleCmd
+}
+
+// newScheduler loads the schedules of the configured clusters, each run recorded in the history
+func newScheduler(ctx context.Context) (*schedule.Scheduler, error) {
+	selected := cmdutil.OverridesFrom(ctx)
+	forCluster := func(ctx context.Context, cluster string) context.Context {
+		overrides := selected
+		overrides.Cluster = cluster
+		return cmdutil.WithOverrides(ctx, overrides)
+	}
+
+	var jobs []*schedule.Job
+	for _, cluster := range []string{"homelab", "nas"} {
+		if selected.Cluster != "" && selected.Cluster != cluster {
+			continue
+		}
+		cfg, err := cmdutil.LoadConfig(forCluster(ctx, cluster), cluster)
+		if err != nil {
+			log.Debug("No schedules loaded", "cluster", cluster, "error", err)
+			continue
+		}
+		for _, entry := range cfg.SchedulesFor(cluster == "nas") {
+			job, err := schedule.NewJob(cluster, entry.Name, entry.Task, entry.Schedule)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	return schedule.New(jobs, func(ctx context.Context, job *schedule.Job) error {
+		run := history.NewRun("schedule "+job.Name, job.Cluster)
+		run.Flags["task"] = job.Task
+		run.Flags["schedule"] = job.Schedule
+		return cmdutil.Record(forCluster(ctx, job.Cluster), run, func(ctx context.Context) error {
+			orchestrator, err := cmdutil.NewOrchestrator(ctx, job.Cluster)
+			if err != nil {
+				return err
+			}
+			return orchestrator.RunScheduledTask(ctx, job.Name, job.Task)
+		})
+	})
+}
+
+// createHistoryCommand adds the bootstrap run history
+func createHistoryCommand() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recorded bootstrap and destroy runs",
+		Long: "Show the operations changelog: every bootstrap, install and destroy run with its outcome and duration. " +
+			"With --trends or --step, show per step how durations evolve across the runs, the failure rate, and the last " +
+			"run against the median of the previous ones. With --cluster, only the runs of that cluster are considered",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+			trends, _ := cmd.Flags().GetBool("trends")
+			step, _ := cmd.Flags().GetString("step")
+			var cluster string
+			if cmdutil.OverridesFrom(cmd.Context()).Cluster != "" {
+				var err error
+				if cluster, err = cmdutil.ResolveCluster(cmd.Context(), ""); err != nil {
+					return err
+				}
+			}
+
+			registry, err := historyRegistry()
+			if err != nil {
+				return err
+			}
+			runs, err := registry.List()
+			if err != nil {
+				return err
+			}
+			if cluster != "" {
+				runs = slices.DeleteFunc(runs, func(run *history.Run) bool { return run.Cluster != cluster })
+			}
+			if len(runs) == 0 {
+				log.Info("No runs recorded yet")
+				return nil
+			}
+			if trends || step != "" {
+				return printHistoryTrends(runs, step, limit)
+			}
+
+			if limit > 0 && len(runs) > limit {
+				runs = runs[:limit]
+			}
+			for _, run := range runs {
+				log.Info(run.ID,
+					"command", run.Command,
+					"status", run.Status,
+					"started", run.StartedAt.Format(time.RFC3339),
+					"duration", run.Duration.Round(time.Second),
+					"steps", len(run.Steps))
+			}
+			return nil
+		},
+	}
+	historyCmd.Flags().Int("limit", 20, "Maximum number of runs to list, or of durations drawn per trend (0 for all)")
+	historyCmd.Flags().Bool("trends", false, "Show the duration trend and failure rate of every step")
+	historyCmd.Flags().String("step", "", "Show the trend of one step, or total for whole runs")
+
+	historyCmd.AddCommand(&cobra.Command{
+		Use:   "show <id>",
+		Short: "Show details of a recorded run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			registry, err := historyRegistry()
+			if err != nil {
+				return err
+			}
+			run, err := registry.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			log.Info("📜 Run "+run.ID,
+				"command", run.Command,
+				"cluster", run.Cluster,
+				"status", run.Status,
+				"started", run.StartedAt.Format(time.RFC3339),
+				"duration", run.Duration.Round(time.Second),
+				"git_revision", run.GitRevision)
+			if len(run.Flags) > 0 {
+				log.Info("Flags", "flags", run.Flags)
+			}
+			if run.Error != "" {
+				log.Error("❌ Run failed", "error", run.Error)
+			}
+			for _, step := range run.Steps {
+				if step.Success {
+					log.Info("✅ "+step.Name, "duration", step.Duration.Round(time.Millisecond))
+				} else {
+					log.Error("❌ "+step.Name, "duration", step.Duration.Round(time.Millisecond), "error", step.Error)
+				}
+			}
+
+			components := make([]string, 0, len(run.Versions))
+			for component := range run.Versions {
+				components = append(components, component)
+			}
+			sort.Strings(components)
+			for _, component := range components {
+				log.Info("📦 "+component, "version", run.Versions[component])
+			}
+
+			for _, alert := range run.Alerts {
+				log.Warn("🚨 "+alert.Rule,
+					"time", alert.Time.Format(time.RFC3339),
+					"source", alert.Source,
+					"priority", alert.Priority,
+					"node", alert.Node,
+					"output", alert.Output)
+			}
+			return nil
+		},
+	})
+
+	return historyCmd
+}
+
+// printHistoryTrends shows the step trends of runs, drawing the last width durations of each
+func printHistoryTrends(runs []*history.Run, step string, width int) error {
+	trends := history.Trends(runs, step)
+	if output.Structured() {
+		return output.Print(trends)
+	}
+	if len(trends) == 0 {
+		return fmt.Errorf("no recorded run has a step %q", step)
+	}
+
+	for _, trend := range trends {
+		name := trend.Step
+		if trend.Command != "" {
+			name += " (" + trend.Command + ")"
+		}
+		fields := []interface{}{
+			"cluster", trend.Cluster,
+			"runs", trend.Runs,
+			"failure_rate", fmt.Sprintf("%.0f%%", trend.FailureRate*100),
+			"last", trend.Last.Round(time.Second),
+		}
+		if trend.Median > 0 {
+			fields = append(fields,
+				"median", trend.Median.Round(time.Second),
+				"range", trend.Min.Round(time.Second).String()+"–"+trend.Max.Round(time.Second).String())
+			if !trend.LastFailed {
+				fields = append(fields, "last_vs_median", fmt.Sprintf("%+.0f%%", trend.LastDelta*100))
+			}
+		}
+		if trend.Drift != 0 {
+			fields = append(fields, "drift", fmt.Sprintf("%+.0f%%", trend.Drift*100))
+		}
+		fields = append(fields, "trend", history.Sparkline(trend.Durations, width))
+
+		switch {
+		case trend.LastFailed:
+			log.Error("❌ "+name, fields...)
+		case trend.LastDelta > slowerThanMedian:
+			log.Warn("🐢 "+name, fields...)
+		default:
+			log.Info("⏱️  "+name, fields...)
+		}
+	}
+	return nil
+}
+
+// slowerThanMedian is how much slower than its median a step must get to be flagged
+const slowerThanMedian = 0.5
+
+func historyRegistry() (*history.Registry, error) {
+	projectRoot, err := bootstrapPkg.ProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	return history.NewRegistry(projectRoot), nil
+}
+
+// createSelfUpdateCommand adds a verified in-place upgrade of the bootstrap binary
+func createSelfUpdateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update the bootstrap binary to the latest release",
+		Long:  "Download the release binary for this platform, verify its minisign/cosign signature and checksum, and atomically replace the running binary",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, _ := cmd.Flags().GetString("version")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			log.Info("⬆️ Checking for bootstrap updates", "current", version, "platform", update.AssetName())
+
+			result, err := update.NewUpdater(version).Update(cmd.Context(), update.Options{
+				Version: target,
+				DryRun:  dryRun,
+			})
+			if err != nil {
+				return fmt.Errorf("self-update failed: %w", err)
+			}
+
+			if result.Updated {
+				log.Info("✅ Bootstrap updated",
+					"from", result.Previous,
+					"to", result.Current,
+					"verified_with", result.Verifier,
+					"path", result.Path)
+			} else if result.Verifier != "" {
+				log.Info("✅ Release verified", "version", result.Current, "verified_with", result.Verifier)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("version", "", "Install a specific release tag instead of the latest")
+	cmd.Flags().Bool("dry-run", false, "Verify the release without replacing the binary")
+	return cmd
+}
+
+// createAirGapCommand adds the commands preparing offline bootstraps
+func createAirGapCommand() *cobra.Command {
+	airgapCmd := &cobra.Command{
+		Use:   "airgap",
+		Short: "Prepare bootstraps without internet access",
+	}
+
+	bundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Download the Flux manifests and charts an air-gapped bootstrap installs",
+		Long: "Download the Flux release manifests and the Cilium, MetalLB, Velero and External Secrets charts at the versions of the " +
+			"homelab config into the bundle directory. Run it on a connected machine, copy the directory next to the configs " +
+			"and bootstrap with --air-gapped. Images are not bundled, mirror them to the registry of registry.mirror",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.NewLoader().LoadConfig("homelab")
+			if err != nil || cfg.Homelab == nil {
+				log.Warn("Homelab config not loaded, bundling the default versions", "error", err)
+				cfg = &config.Config{Homelab: &config.HomelabConfig{}}
+			}
+			homelabCfg := cfg.Homelab
+
+			dir, _ := cmd.Flags().GetString("dir")
+			if dir == "" {
+				dir = homelabCfg.AirGap.BundleDir
+			}
+			if dir == "" {
+				dir = airgap.DefaultBundleDir
+			}
+			fluxVersion, _ := cmd.Flags().GetString("flux-version")
+			if fluxVersion == "" {
+				fluxVersion = homelabCfg.GitOps.FluxVersion
+			}
+			if fluxVersion == "" {
+				fluxVersion = airgap.DefaultFluxVersion()
+			}
+
+			cilium := infra.CiliumConfig{Version: homelabCfg.Cilium.Version}
+			loadBalancer := infra.LoadBalancerConfig{Version: homelabCfg.LoadBalancer.Version}
+			manifest, err := airgap.Build(cmd.Context(), dir, airgap.BundleOptions{
+				FluxVersion: fluxVersion,
+				Charts: []airgap.BundleChart{
+					cilium.BundleChart(),
+					loadBalancer.BundleChart(),
+					backup.BundleChart(homelabCfg.Backup.ChartVersion),
+					externalsecrets.BundleChart(homelabCfg.Integration.ExternalSecrets.ChartVersion),
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to build the air-gap bundle: %w", err)
+			}
+
+			if output.Structured() {
+				return output.Print(manifest)
+			}
+			log.Info("✅ Air-gap bundle ready", "dir", dir, "flux", manifest.Flux)
+			names := make([]string, 0, len(manifest.Charts))
+			for name := range manifest.Charts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				log.Info("Bundled chart", "chart", name, "version", manifest.Charts[name])
+			}
+			return nil
+		},
+	}
+	bundleCmd.Flags().String("dir", "", "Bundle directory, airgap.bundle_dir or ./"+airgap.DefaultBundleDir+" by default")
+	bundleCmd.Flags().String("flux-version", "", "Flux release to bundle, gitops.flux_version or the one this binary was built with by default")
+
+	airgapCmd.AddCommand(bundleCmd)
+	return airgapCmd
+}
+
+// createForceCleanupCommand adds force cleanup command for stuck namespaces
+func createForceCleanupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "force-cleanup-namespaces",
+		Short: "Force cleanup stuck terminating namespaces",
+		Long:  "Aggressively clean up namespaces stuck in Terminating state",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+
+			log.Info("🔧 Starting force cleanup of terminating namespaces", "cluster", clusterType)
+
+			// Load configuration
+			cfg, err := cmdutil.LoadConfig(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+
+			// Create destroy manager
+			isNAS := clusterType == "nas"
+			destroyManager, err := destroy.NewManager(cfg, isNAS)
+			if err != nil {
+				return err
+			}
+
+			// Force cleanup namespaces
+			return destroyManager.ForceCleanupNamespaces(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+// createRecoveryCommand adds recovery and diagnostic commands
+func createRecoveryCommand() *cobra.Command {
+	recoveryCmd := &cobra.Command{
+		Use:   "recovery",
+		Short: "Recovery and diagnostic commands",
+		Long:  "Diagnose system issues and recover from bootstrap failures",
+	}
+
+	// Diagnostic command
+	recoveryCmd.AddCommand(&cobra.Command{
+		Use:   "diagnose",
+		Short: "Diagnose system state",
+		Long:  "Perform comprehensive diagnostics to identify system issues",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log.Info("🔍 Starting system diagnostics...")
+
+			diagnosticManager, err := newDiagnosticManager(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			// Run diagnostics
+			results, err := diagnosticManager.DiagnoseSystem(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("diagnostics failed: %w", err)
+			}
+
+			// Print results
+			diagnosticManager.PrintDiagnostics(results)
+
+			return nil
+		},
+	})
+	recoveryCmd.AddCommand(createRepairCommand())
+	recoveryCmd.AddCommand(createUnstickCommand())
+
+	return recoveryCmd
+}
+
+// newDiagnosticManager connects to both clusters, whichever configuration loads
+func newDiagnosticManager(ctx context.Context) (*recovery.DiagnosticManager, error) {
+	clusterType, err := cmdutil.ResolveCluster(ctx, "homelab")
+	if err != nil {
+		return nil, err
+	}
+
+	// Load configuration for both clusters
+	loader := config.NewLoader()
+	cfg, err := loader.LoadConfig("homelab")
+	if err != nil {
+		// Try to load individual configs
+		cfg = &config.Config{}
+		if homelabCfg, err := loader.LoadConfig("homelab"); err == nil {
+			cfg.Homelab = homelabCfg.Homelab
+		}
+		if nasCfg, err := loader.LoadConfig("nas"); err == nil {
+			cfg.NAS = nasCfg.NAS
+		}
+	}
+	if err := cmdutil.ApplyOverrides(ctx, cfg, clusterType); err != nil {
+		return nil, err
+	}
+
+	diagnosticManager, err := recovery.NewDiagnosticManager(cfg, clusterType == "nas")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diagnostic manager: %w", err)
+	}
+	return diagnosticManager, nil
+}
+
+// createRepairCommand runs diagnostics and fixes the known findings
+func createRepairCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Fix known problems found by diagnose",
+		Long: "Diagnose both clusters and map known findings to fixes: restart crash looping Flux controllers, " +
+			"strip the finalizers of namespaces stuck terminating, restore a missing Istio cacerts secret, " +
+			"resume suspended Kustomizations and restart webhooks serving expired certificates. " +
+			"Each fix is confirmed unless --auto is set; --dry-run sends every change with server-side dry run",
+		Example: `  bootstrap recovery repair --dry-run
+  bootstrap recovery repair --auto --only crashlooping-controller,stuck-namespace`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			auto, _ := cmd.Flags().GetBool("auto")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			only, _ := cmd.Flags().GetStringSlice("only")
+			for _, finding := range only {
+				if !slices.Contains(recovery.Findings, recovery.Finding(finding)) {
+					return fmt.Errorf("unknown finding %q, expected one of %v", finding, recovery.Findings)
+				}
+			}
+
+			diagnosticManager, err := newDiagnosticManager(cmd.Context())
+			if err != nil {
+				return err
+			}
+			results, err := diagnosticManager.DiagnoseSystem(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("diagnostics failed: %w", err)
+			}
+
+			var remediations []*recovery.Remediation
+			for _, remediation := range diagnosticManager.Remediations(results) {
+				if len(only) == 0 || slices.Contains(only, string(remediation.Finding)) {
+					remediations = append(remediations, remediation)
+				}
+			}
+			if len(remediations) == 0 {
+				log.Info("✅ No known problem to repair")
+				return nil
+			}
+
+			applied, failed := 0, 0
+			for _, remediation := range remediations {
+				log.Warn("⚠️ "+remediation.Component, "finding", remediation.Finding, "fix", remediation.Action)
+				if !auto && !dryRun {
+					if err := cmdutil.Confirm(cmd.InOrStdin(), "Apply this fix? Type y", "y"); err != nil {
+						remediation.Status = "skipped"
+						log.Info("Skipped", "component", remediation.Component)
+						continue
+					}
+				}
+				if err := diagnosticManager.Remediate(cmd.Context(), remediation, dryRun); err != nil {
+					failed++
+					log.Error("❌ Fix failed", "component", remediation.Component, "error", err)
+					continue
+				}
+				applied++
+			}
+
+			if output.Structured() {
+				if err := output.Print(remediations); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d fixes failed", failed, len(remediations))
+			}
+			log.Info("✅ Repair completed", "fixes", applied, "skipped", len(remediations)-applied, "dry_run", dryRun)
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("auto", false, "Apply every fix without confirmation")
+	cmd.Flags().Bool("dry-run", false, "Validate each fix server-side without persisting it")
+	cmd.Flags().StringSlice("only", nil, fmt.Sprintf("Only repair these findings: %v", recovery.Findings))
+	return cmd
+}
+
+// unstickReport is the structured result of recovery unstick
+type unstickReport struct {
+	Namespaces []*recovery.StuckNamespace `json:"namespaces,omitempty"`
+	Objects    []*recovery.StuckObject    `json:"objects,omitempty"`
+}
+
+// createUnstickCommand finds what holds a deletion and optionally strips the finalizers
+func createUnstickCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unstick [namespace/<name> | <resource>[/<name>]]",
+		Short: "Explain and release namespaces or resources stuck deleting",
+		Long: "List the finalizers holding a terminating namespace or resource and the controller expected to remove them. " +
+			"Without arguments every terminating namespace is inspected; <resource> is a kind, plural or short name such as cephcluster or ks. " +
+			"With --strip the finalizers are removed after confirmation",
+		Example: `  bootstrap recovery unstick
+  bootstrap recovery unstick namespace/rook-ceph --strip
+  bootstrap recovery unstick cephblockpool -n rook-ceph
+  bootstrap recovery unstick kustomization/apps -n flux-system --strip --force`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, _ := cmd.Flags().GetString("namespace")
+			strip, _ := cmd.Flags().GetBool("strip")
+			force, _ := cmd.Flags().GetBool("force")
+
+			clusterType, err := cmdutil.ResolveCluster(cmd.Context(), "homelab")
+			if err != nil {
+				return err
+			}
+			cfg, err := cmdutil.LoadConfig(cmd.Context(), clusterType)
+			if err != nil {
+				return err
+			}
+			var client *k8s.Client
+			if clusterType == "nas" {
+				client, err = k8s.NewClientWithContext(cfg.NAS.Cluster.KubeConfig, cfg.NAS.Cluster.KubeContext)
+			} else {
+				client, err = k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to connect to cluster: %w", err)
+			}
+
+			unsticker := recovery.NewUnsticker(client)
+			report := &unstickReport{}
+			resource, name, _ := strings.Cut(strings.Join(args, ""), "/")
+			switch strings.ToLower(resource) {
+			case "", "ns", "namespace", "namespaces":
+				var names []string
+				if name != "" {
+					names = append(names, name)
+				}
+				report.Namespaces, err = unsticker.StuckNamespaces(cmd.Context(), names...)
+			default:
+				report.Objects, err = unsticker.StuckObjects(cmd.Context(), resource, namespace, name)
+			}
+			if err != nil {
+				return err
+			}
+
+			if output.Structured() {
+				if err := output.Print(report); err != nil {
+					return err
+				}
+			} else {
+				printUnstickReport(report)
+			}
+
+			count := len(report.Objects)
+			for _, ns := range report.Namespaces {
+				count += len(ns.Blocking)
+				if ns.Deleting != nil && len(ns.Finalizers) > 0 {
+					count++
+				}
+			}
+			if !strip || count == 0 {
+				if count > 0 {
+					log.Info("Pass --strip to remove these finalizers once the controllers cannot release them")
+				}
+				return nil
+			}
+
+			if !force {
+				if err := cmdutil.Confirm(cmd.InOrStdin(), fmt.Sprintf("Strip the finalizers of %d objects on %s? Type yes", count, clusterType), "yes"); err != nil {
+					return fmt.Errorf("%w, nothing was changed (pass --force to skip the confirmation)", err)
+				}
+			}
+			for _, ns := range report.Namespaces {
+				if err := unsticker.StripNamespace(cmd.Context(), ns); err != nil {
+					return err
+				}
+			}
+			for _, obj := range report.Objects {
+				if err := unsticker.Strip(cmd.Context(), obj); err != nil {
+					return err
+				}
+			}
+			log.Info("✅ Finalizers stripped", "objects", count)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("namespace", "n", "", "Namespace of the resource (default all namespaces)")
+	cmd.Flags().Bool("strip", false, "Remove the blocking finalizers after confirmation")
+	cmd.Flags().Bool("force", false, "Skip the confirmation, for non-interactive use")
+	return cmd
+}
+
+func printUnstickReport(report *unstickReport) {
+	if len(report.Namespaces) == 0 && len(report.Objects) == 0 {
+		log.Info("✅ Nothing is stuck deleting")
+		return
+	}
+	for _, ns := range report.Namespaces {
+		log.Warn("Namespace "+ns.Name, "terminating", ns.Deleting != nil, "finalizers", ns.Finalizers, "blocking", len(ns.Blocking))
+		for _, condition := range ns.Conditions {
+			fmt.Printf("    %s\n", condition)
+		}
+		for _, obj := range ns.Blocking {
+			printStuckObject(obj)
+		}
+	}
+	for _, obj := range report.Objects {
+		printStuckObject(obj)
+	}
+}
+
+func printStuckObject(obj *recovery.StuckObject) {
+	ref := obj.Kind + " " + obj.Name
+	if obj.Namespace != "" {
+		ref = obj.Kind + " " + obj.Namespace + "/" + obj.Name
+	}
+	fields := []interface{}{"finalizers", obj.Finalizers, "controller", obj.Controller.Name, "controller_status", obj.Controller.Status}
+	if obj.Deleting != nil {
+		fields = append(fields, "deleting_for", time.Since(*obj.Deleting).Round(time.Second))
+	}
+	log.Warn("⏳ "+ref, fields...)
 }
@@ -0,0 +1,315 @@
+package cmdutil
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type overridesKey struct{}
+
+// Overrides holds the cluster selection flags shared by every command
+type Overrides struct {
+	Cluster    string
+	Kubeconfig string
+	Context    string
+}
+
+// Connection returns true when a kubeconfig or context override is set
+func (o Overrides) Connection() bool {
+	return o.Kubeconfig != "" || o.Context != ""
+}
+
+// AddClusterFlags registers the persistent cluster selection flags on cmd
+func AddClusterFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("cluster", "", "Cluster to operate on: homelab, nas or a cluster declared under clusters")
+	cmd.PersistentFlags().String("kubeconfig", "", "Override kubeconfig path")
+	cmd.PersistentFlags().String("context", "", "Override kubeconfig context")
+	cmd.PersistentFlags().String("as", "", "Impersonate this user or service account (system:serviceaccount:<namespace>:<name>) on every API request")
+	cmd.PersistentFlags().StringSlice("as-group", nil, "Impersonate this group too, repeatable, requires --as")
+	cmd.PersistentFlags().Float32("qps", 0, "Kubernetes API requests per second (default 5)")
+	cmd.PersistentFlags().Int("burst", 0, "Kubernetes API requests allowed above --qps in bursts (default 10)")
+	cmd.PersistentFlags().Duration("request-timeout", 0, "Timeout of each Kubernetes API request, 0 for none")
+}
+
+// ClientOptionsFromCommand reads the API client flags of cmd, version going into the user agent
+func ClientOptionsFromCommand(cmd *cobra.Command, version string) (k8s.ClientOptions, error) {
+	as, _ := cmd.Flags().GetString("as")
+	asGroups, _ := cmd.Flags().GetStringSlice("as-group")
+	qps, _ := cmd.Flags().GetFloat32("qps")
+	burst, _ := cmd.Flags().GetInt("burst")
+	timeout, _ := cmd.Flags().GetDuration("request-timeout")
+
+	if len(asGroups) > 0 && as == "" {
+		return k8s.ClientOptions{}, fmt.Errorf("--as-group requires --as, the API server only impersonates groups of a user")
+	}
+	if qps < 0 || burst < 0 || timeout < 0 {
+		return k8s.ClientOptions{}, fmt.Errorf("--qps, --burst and --request-timeout cannot be negative")
+	}
+	return k8s.ClientOptions{
+		QPS:      qps,
+		Burst:    burst,
+		Timeout:  timeout,
+		Version:  version,
+		As:       as,
+		AsGroups: asGroups,
+	}, nil
+}
+
+// FromCommand reads the cluster selection flags of cmd
+func FromCommand(cmd *cobra.Command) Overrides {
+	cluster, _ := cmd.Flags().GetString("cluster")
+	kubeconfig, _ := cmd.Flags().GetString("kubeconfig")
+	kubeContext, _ := cmd.Flags().GetString("context")
+	return Overrides{Cluster: cluster, Kubeconfig: kubeconfig, Context: kubeContext}
+}
+
+// WithOverrides stores the overrides in ctx for subcommands to pick up
+func WithOverrides(ctx context.Context, overrides Overrides) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, overridesKey{}, overrides)
+}
+
+// OverridesFrom returns the overrides stored in ctx, if any
+func OverridesFrom(ctx context.Context) Overrides {
+	if ctx == nil {
+		return Overrides{}
+	}
+	overrides, _ := ctx.Value(overridesKey{}).(Overrides)
+	return overrides
+}
+
+// ResolveCluster returns the selected cluster, falling back to defaultCluster
+func ResolveCluster(ctx context.Context, defaultCluster string) (string, error) {
+	cluster := OverridesFrom(ctx).Cluster
+	if cluster == "" {
+		cluster = defaultCluster
+	}
+	if err := ValidateCluster(cluster); err != nil {
+		return "", err
+	}
+	return cluster, nil
+}
+
+// ValidateCluster checks that cluster is homelab, nas or a member declared in their configs
+func ValidateCluster(cluster string) error {
+	known := config.NewLoader().KnownClusters()
+	if !slices.Contains(known, cluster) {
+		return fmt.Errorf("unknown cluster %q (expected one of %s)", cluster, strings.Join(known, ", "))
+	}
+	return nil
+}
+
+// LoadConfig loads the configuration for cluster and applies the connection overrides to it
+func LoadConfig(ctx context.Context, cluster string) (*config.Config, error) {
+	overrides := OverridesFrom(ctx)
+	if overrides.Cluster != "" && overrides.Cluster != cluster {
+		return nil, fmt.Errorf("--cluster=%s conflicts with %s command", overrides.Cluster, cluster)
+	}
+
+	loader := config.NewLoader()
+	cfg, err := loader.LoadConfig(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	isNAS := cluster == "nas"
+	if isNAS && cfg.NAS == nil {
+		return nil, fmt.Errorf("NAS configuration not found")
+	}
+	if !isNAS && cfg.Homelab == nil {
+		return nil, fmt.Errorf("homelab configuration not found")
+	}
+	airgap.Configure(cfg.AirGapFor(isNAS))
+	mirror := cfg.RegistryFor(isNAS)
+	registry.SetMirror(mirror.Mirror, mirror.PullSecretName())
+
+	if err := ApplyOverrides(ctx, cfg, cluster); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ApplyOverrides points the cluster section of cfg at the overridden kubeconfig and context
+func ApplyOverrides(ctx context.Context, cfg *config.Config, cluster string) error {
+	overrides := OverridesFrom(ctx)
+	if !overrides.Connection() {
+		return nil
+	}
+
+	kubeconfig := overrides.Kubeconfig
+	if kubeconfig != "" {
+		abs, err := filepath.Abs(kubeconfig)
+		if err != nil {
+			return fmt.Errorf("failed to resolve kubeconfig path: %w", err)
+		}
+		kubeconfig = abs
+	}
+
+	if kubeconfig == "" && overrides.Context != "" {
+		kubeconfig = kubeconfigWithContext(clusterKubeconfig(cfg, cluster), overrides.Context)
+	}
+
+	switch {
+	case cluster == "nas" && cfg.NAS != nil:
+		if kubeconfig != "" {
+			cfg.NAS.Cluster.KubeConfig = kubeconfig
+		}
+		if overrides.Context != "" {
+			cfg.NAS.Cluster.KubeContext = overrides.Context
+		}
+	case cluster == "homelab" && cfg.Homelab != nil:
+		if kubeconfig != "" {
+			cfg.Homelab.Cluster.KubeConfig = kubeconfig
+		}
+		if overrides.Context != "" {
+			cfg.Homelab.Cluster.KubeContext = overrides.Context
+		}
+	}
+	return nil
+}
+
+// OrchestratorOptions builds orchestrator options for the local cluster from the kubeconfigs of cfg, which
+// LoadConfig points at the overrides
+func OrchestratorOptions(ctx context.Context, cfg *config.Config, isNAS bool) *bootstrap.OrchestratorOptions {
+	options := &bootstrap.OrchestratorOptions{
+		HomelabKubeconfigPath: kubeconfigFor("homelab"),
+		NASKubeconfigPath:     kubeconfigFor("nas"),
+	}
+	if path := clusterKubeconfig(cfg, "homelab"); path != "" {
+		options.HomelabKubeconfigPath = path
+	}
+	if path := clusterKubeconfig(cfg, "nas"); path != "" {
+		options.NASKubeconfigPath = path
+	}
+
+	overrides := OverridesFrom(ctx)
+	if overrides.Kubeconfig != "" {
+		if isNAS {
+			options.NASKubeconfigPath = overrides.Kubeconfig
+		} else {
+			options.HomelabKubeconfigPath = overrides.Kubeconfig
+		}
+	}
+	options.KubeconfigPath = options.HomelabKubeconfigPath
+	if isNAS {
+		options.KubeconfigPath = options.NASKubeconfigPath
+	}
+	options.Context = overrides.Context
+
+	return options
+}
+
+// NewOrchestrator loads the configuration for cluster and creates an orchestrator honoring the overrides
+func NewOrchestrator(ctx context.Context, cluster string) (*bootstrap.Orchestrator, error) {
+	cfg, err := LoadConfig(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	isNAS := cluster == "nas"
+	orchestrator, err := bootstrap.NewOrchestrator(cfg, isNAS, OrchestratorOptions(ctx, cfg, isNAS))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orchestrator: %w", err)
+	}
+	return orchestrator, nil
+}
+
+// WatchConfig hot-reloads the configuration for cluster, re-applying the overrides before calling onReload
+func WatchConfig(ctx context.Context, cluster string, onReload config.ReloadFunc) *config.Watcher {
+	watcher := config.NewWatcher(cluster)
+	watcher.OnReload(func(cfg *config.Config) {
+		if err := ApplyOverrides(ctx, cfg, cluster); err != nil {
+			log.Warn("Failed to apply overrides to reloaded configuration", "error", err)
+			return
+		}
+		onReload(cfg)
+	})
+
+	go func() {
+		if err := watcher.Run(ctx); err != nil {
+			log.Warn("Configuration hot-reload disabled", "error", err)
+		}
+	}()
+
+	return watcher
+}
+
+// ClusterOverride returns the connection override for the selected cluster, if any
+func ClusterOverride(ctx context.Context) ([]bootstrap.ClusterOverride, error) {
+	overrides := OverridesFrom(ctx)
+	if !overrides.Connection() {
+		return nil, nil
+	}
+	if overrides.Cluster == "" {
+		return nil, fmt.Errorf("--kubeconfig and --context require --cluster when targeting both clusters")
+	}
+	cluster, err := ResolveCluster(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return []bootstrap.ClusterOverride{{
+		Cluster:    cluster,
+		Kubeconfig: overrides.Kubeconfig,
+		Context:    overrides.Context,
+	}}, nil
+}
+
+func kubeconfigFor(cluster string) string {
+	return filepath.Join("infrastructure", cluster, "kubeconfig.yaml")
+}
+
+// clusterKubeconfig returns the kubeconfig path configured for cluster, empty when cfg has no such section
+func clusterKubeconfig(cfg *config.Config, cluster string) string {
+	switch {
+	case cluster == "nas" && cfg.NAS != nil:
+		return cfg.NAS.Cluster.KubeConfig
+	case cluster == "homelab" && cfg.Homelab != nil:
+		return cfg.Homelab.Cluster.KubeConfig
+	}
+	return ""
+}
+
+// kubeconfigWithContext returns the kubeconfig defining kubeContext when only --context is given: the
+// configured one when it does, else the first of the files kubectl reads ($KUBECONFIG or ~/.kube/config).
+// It returns an empty path to keep the configured kubeconfig, e.g. before its cluster is provisioned.
+func kubeconfigWithContext(configured, kubeContext string) string {
+	candidates := clientcmd.NewDefaultClientConfigLoadingRules().GetLoadingPrecedence()
+	if configured != "" {
+		candidates = append([]string{configured}, candidates...)
+	}
+	for i, path := range candidates {
+		kubeconfig, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			continue
+		}
+		if _, ok := kubeconfig.Contexts[kubeContext]; !ok {
+			continue
+		}
+		if i == 0 && configured != "" {
+			return ""
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		if configured != "" {
+			log.Warn("Context not in the configured kubeconfig, using the kubeconfig defining it", "context", kubeContext, "configured", configured, "kubeconfig", abs)
+		}
+		return abs
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package cmdutil
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/output"
+)
+
+// Confirm prompts on stderr and fails unless the line read from in is expected.
+// It reads byte by byte so successive prompts can share one reader.
+func Confirm(in io.Reader, prompt, expected string) error {
+	fmt.Fprintf(output.GetManager().GetStderr(), "%s: ", prompt)
+	var line strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 && buf[0] == '\n' {
+			break
+		}
+		if n > 0 {
+			line.WriteByte(buf[0])
+		}
+		if err != nil {
+			if line.Len() == 0 {
+				return fmt.Errorf("no confirmation received")
+			}
+			break
+		}
+	}
+	if answer := strings.TrimSpace(line.String()); answer != expected {
+		return fmt.Errorf("confirmation %q does not match %q", answer, expected)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmdutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/destroy"
+	"github.com/fredericrous/homelab/bootstrap/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// DestroyGate decides whether a destroy goes ahead once its plan is known
+type DestroyGate struct {
+	PlanOnly bool
+	Force    bool
+	In       io.Reader
+}
+
+// AddDestroyFlags registers the flags that limit what a destroy removes and how it is confirmed
+func AddDestroyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("only", nil, "Only destroy these stacks: flux, istio, storage, apps (default everything)")
+	cmd.Flags().StringSlice("namespace", nil, "Destroy this namespace, on top of the --only stacks (repeatable)")
+	cmd.Flags().Bool("keep-crds", false, "Leave the CustomResourceDefinitions in place")
+	cmd.Flags().Bool("plan", false, "List the namespaces, CRDs and PersistentVolumes that would be deleted, then exit")
+	cmd.Flags().Bool("force", false, "Skip the typed confirmation, for non-interactive use")
+}
+
+// DestroyOptions reads the destroy scoping flags of cmd
+func DestroyOptions(cmd *cobra.Command) (destroy.Options, error) {
+	only, _ := cmd.Flags().GetStringSlice("only")
+	namespaces, _ := cmd.Flags().GetStringSlice("namespace")
+	keepCRDs, _ := cmd.Flags().GetBool("keep-crds")
+
+	scopes, err := destroy.ParseScopes(only)
+	if err != nil {
+		return destroy.Options{}, err
+	}
+	return destroy.Options{Only: scopes, Namespaces: namespaces, KeepCRDs: keepCRDs}, nil
+}
+
+// DestroyGateFor reads the --plan and --force flags of cmd
+func DestroyGateFor(cmd *cobra.Command) DestroyGate {
+	planOnly, _ := cmd.Flags().GetBool("plan")
+	force, _ := cmd.Flags().GetBool("force")
+	return DestroyGate{PlanOnly: planOnly, Force: force, In: cmd.InOrStdin()}
+}
+
+// Proceed shows what m would delete and asks for the cluster name to be typed back.
+// It returns false without error when only the plan was requested.
+func (g DestroyGate) Proceed(ctx context.Context, m *destroy.Manager) (bool, error) {
+	plan, err := m.Plan(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to plan destruction: %w", err)
+	}
+
+	if g.PlanOnly && output.Structured() {
+		return false, output.Print(plan)
+	}
+	logPlan(plan)
+	if g.PlanOnly {
+		return false, nil
+	}
+	if g.Force {
+		log.Warn("Confirmation skipped with --force")
+		return true, nil
+	}
+
+	if err := Confirm(g.In, fmt.Sprintf("Type the cluster name (%s) to confirm destruction", plan.Cluster), plan.Cluster); err != nil {
+		return false, fmt.Errorf("%w, nothing was destroyed (pass --force to skip the confirmation)", err)
+	}
+	return true, nil
+}
+
+func logPlan(plan *destroy.Plan) {
+	log.Warn("Destruction plan", "cluster", plan.Cluster, "namespaces", len(plan.Namespaces), "crds", len(plan.CRDs), "persistent_volumes", len(plan.PersistentVolumes))
+	for _, group := range []struct {
+		kind  string
+		names []string
+	}{
+		{"Namespace", plan.Namespaces},
+		{"CustomResourceDefinition", plan.CRDs},
+		{"PersistentVolume", plan.PersistentVolumes},
+	} {
+		for _, name := range group.names {
+			log.Info("  will delete", "kind", group.kind, "name", name)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package cmdutil
+
+import "fmt"
+
+// ExitError ends the process with Code instead of the default 1, Err is logged when set
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,77 @@
+package cmdutil
+
+import (
+	"context"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	"github.com/fredericrous/homelab/bootstrap/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// RunFunc is the signature of a cobra RunE handler
+type RunFunc func(cmd *cobra.Command, args []string) error
+
+// Recorded wraps runE so every invocation is stored in the run history registry
+func Recorded(command, cluster string, runE RunFunc) RunFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		runCluster := cluster
+		if runCluster == "" {
+			runCluster = OverridesFrom(cmd.Context()).Cluster
+		}
+
+		run := history.NewRun(command, runCluster)
+		run.Args = args
+		cmd.Flags().Visit(func(flag *pflag.Flag) {
+			run.Flags[flag.Name] = flag.Value.String()
+		})
+		return Record(cmd.Context(), run, func(ctx context.Context) error {
+			cmd.SetContext(ctx)
+			return runE(cmd, args)
+		})
+	}
+}
+
+// Record runs fn with run attached to its context, then stores run with its log and summary in the run history
+func Record(ctx context.Context, run *history.Run, fn func(ctx context.Context) error) error {
+	ctx = history.WithRun(ctx, run)
+
+	projectRoot, rootErr := bootstrap.ProjectRoot()
+	if rootErr == nil {
+		if runLog, logErr := logger.StartRunLog(projectRoot, run.ID, run.Cluster); logErr != nil {
+			log.Warn("Failed to open the run log", "error", logErr)
+		} else {
+			run.AddLog(runLog.Path())
+			defer runLog.Close()
+		}
+	}
+
+	err := fn(ctx)
+	run.Finish(err)
+
+	if rootErr != nil {
+		log.Warn("Failed to record run history", "error", rootErr)
+		return err
+	}
+	if run.GitRevision == "" {
+		run.GitRevision = history.GitRevision(ctx, projectRoot)
+	}
+	if saveErr := history.NewRegistry(projectRoot).Save(run); saveErr != nil {
+		log.Warn("Failed to record run history", "error", saveErr)
+	} else {
+		log.Debug("Recorded run", "id", run.ID, "status", run.Status)
+	}
+
+	path, summaryErr := history.WriteSummary(projectRoot, run)
+	if summaryErr != nil {
+		log.Warn("Failed to write run summary", "error", summaryErr)
+	}
+	// Only multi-step runs get the table, the log of single actions already tells the story
+	if len(run.Steps) > 0 {
+		history.PrintSummary(os.Stdout, history.NewSummary(projectRoot, run), path)
+	}
+	return err
+}
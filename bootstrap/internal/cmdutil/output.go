@@ -0,0 +1,51 @@
+package cmdutil
+
+import (
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k3s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/prereq"
+	"github.com/fredericrous/homelab/bootstrap/pkg/recovery"
+)
+
+// CheckReport is the structured result of the prerequisite checks
+type CheckReport struct {
+	Cluster  string               `json:"cluster"`
+	Passed   int                  `json:"passed"`
+	Warnings int                  `json:"warnings"`
+	Failed   int                  `json:"failed"`
+	Results  []prereq.CheckResult `json:"results"`
+}
+
+// NewCheckReport counts the outcomes of results
+func NewCheckReport(cluster string, results []prereq.CheckResult) *CheckReport {
+	report := &CheckReport{Cluster: cluster, Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case prereq.CheckPassed:
+			report.Passed++
+		case prereq.CheckFailed:
+			report.Failed++
+		case prereq.CheckWarning:
+			report.Warnings++
+		}
+	}
+	return report
+}
+
+// StatusReport is the structured result of a cluster status command
+type StatusReport struct {
+	Cluster       string                       `json:"cluster"`
+	Container     *k3s.ContainerStatus         `json:"container,omitempty"`
+	APIReady      bool                         `json:"api_ready"`
+	Nodes         []string                     `json:"nodes,omitempty"`
+	FluxInstalled bool                         `json:"flux_installed"`
+	Flux          *flux.SyncStatus             `json:"flux,omitempty"`
+	Diagnostics   []*recovery.DiagnosticResult `json:"diagnostics,omitempty"`
+	Errors        []string                     `json:"errors,omitempty"`
+}
+
+// ValidateReport is the structured result of a deployment validation
+type ValidateReport struct {
+	Cluster string           `json:"cluster"`
+	Flux    *flux.SyncStatus `json:"flux"`
+}
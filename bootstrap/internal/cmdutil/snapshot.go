@@ -0,0 +1,45 @@
+package cmdutil
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
+	"github.com/fredericrous/homelab/bootstrap/pkg/snapshot"
+)
+
+// SnapshotDir returns where the pre-destroy snapshots of the project are written
+func SnapshotDir() (string, error) {
+	projectRoot, err := bootstrap.ProjectRoot()
+	if err != nil {
+		return "", fmt.Errorf("failed to find project root: %w", err)
+	}
+	return snapshot.Dir(projectRoot), nil
+}
+
+// SnapshotKey loads the SOPS age key of cluster that snapshot secrets are encrypted with, nil when there is none
+func SnapshotKey(ctx context.Context, cfg *config.Config, cluster string) *secrets.AgeKey {
+	var keyFile string
+	switch {
+	case cluster == "nas" && cfg.NAS != nil:
+		keyFile = cfg.NAS.GitOps.SOPS.AgeKeyFile
+	case cluster == "homelab" && cfg.Homelab != nil:
+		keyFile = cfg.Homelab.GitOps.SOPS.AgeKeyFile
+	}
+	if keyFile != "" && !filepath.IsAbs(keyFile) {
+		if projectRoot, err := bootstrap.ProjectRoot(); err == nil {
+			keyFile = filepath.Join(projectRoot, keyFile)
+		}
+	}
+
+	key, err := secrets.LoadAgeKey(ctx, keyFile)
+	if err != nil {
+		log.Warn("No age key available, snapshots leave secrets out", "error", err)
+		return nil
+	}
+	return key
+}
@@ -0,0 +1,147 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/internal/cmdutil"
+	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	etcdPkg "github.com/fredericrous/homelab/bootstrap/pkg/etcd"
+	"github.com/fredericrous/homelab/bootstrap/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// SnapshotReport is the structured output of a snapshot
+type SnapshotReport struct {
+	Snapshot *etcdPkg.Snapshot `json:"snapshot"`
+	Pruned   []string          `json:"pruned,omitempty"`
+}
+
+// NewEtcdCommand creates the etcd snapshot command group of cluster
+func NewEtcdCommand(cluster string) *cobra.Command {
+	how := "through the Talos API"
+	if cluster == "nas" {
+		how = "with k3s etcd-snapshot"
+	}
+	cmd := &cobra.Command{
+		Use:   "etcd",
+		Short: "Manage etcd snapshots stored in the NAS MinIO",
+		Long:  fmt.Sprintf("Take etcd snapshots %s, verify and upload them to the NAS MinIO bucket, prune them by retention and restore them", how),
+	}
+
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Take, verify and upload an etcd snapshot, then prune old ones",
+		RunE: cmdutil.Recorded(cluster+" etcd snapshot", cluster, func(cmd *cobra.Command, args []string) error {
+			retention, _ := cmd.Flags().GetInt("retention")
+			return runSnapshot(cmd.Context(), cluster, retention)
+		}),
+	}
+	snapshotCmd.Flags().Int("retention", 0, "Snapshots to keep in the bucket (default backup.etcd_retention, 7)")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the stored etcd snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runList(cmd.Context(), cluster)
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <snapshot>",
+		Short: "Verify and restore a stored etcd snapshot",
+		Long:  restoreHelp(cluster),
+		Args:  cobra.ExactArgs(1),
+		RunE: cmdutil.Recorded(cluster+" etcd restore", cluster, func(cmd *cobra.Command, args []string) error {
+			return runRestore(cmd.Context(), cluster, args[0])
+		}),
+	}
+
+	cmd.AddCommand(snapshotCmd, listCmd, restoreCmd)
+	return cmd
+}
+
+func restoreHelp(cluster string) string {
+	if cluster == "nas" {
+		return "Download the snapshot, check its checksum and etcd hash, then stop K3s, reset the cluster from it with --cluster-reset and start K3s again"
+	}
+	return "Download the snapshot, check its checksum and etcd hash, then recover etcd from it on the first control plane, " +
+		"like talosctl bootstrap --recover-from. etcd must be stopped: reset the control plane nodes first"
+}
+
+func runSnapshot(ctx context.Context, cluster string, retention int) error {
+	orchestrator, manager, closeTunnel, err := etcdManager(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	defer closeTunnel()
+	if retention == 0 {
+		retention = orchestrator.EtcdRetention()
+	}
+
+	snapshot, pruned, err := manager.Snapshot(ctx, retention)
+	if err != nil {
+		return err
+	}
+	if output.Structured() {
+		return output.Print(&SnapshotReport{Snapshot: snapshot, Pruned: pruned})
+	}
+	for _, name := range pruned {
+		log.Info("🗑️ Pruned "+name, "retention", retention)
+	}
+	log.Info("✅ etcd snapshot stored", "name", snapshot.Name, "size", snapshot.Size, "sha256", snapshot.SHA256)
+	return nil
+}
+
+func runList(ctx context.Context, cluster string) error {
+	_, manager, closeTunnel, err := etcdManager(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	defer closeTunnel()
+
+	snapshots, err := manager.List(ctx)
+	if err != nil {
+		return err
+	}
+	if output.Structured() {
+		return output.Print(snapshots)
+	}
+	if len(snapshots) == 0 {
+		log.Info("No etcd snapshots stored yet")
+		return nil
+	}
+	for _, snapshot := range snapshots {
+		log.Info(snapshot.Name, "size", snapshot.Size, "created", snapshot.Created.Format(time.RFC3339), "sha256", snapshot.SHA256)
+	}
+	return nil
+}
+
+func runRestore(ctx context.Context, cluster, name string) error {
+	_, manager, closeTunnel, err := etcdManager(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	defer closeTunnel()
+
+	log.Warn("♻️ Restoring etcd, the cluster state goes back to the snapshot", "cluster", cluster, "snapshot", name)
+	if err := manager.Restore(ctx, name); err != nil {
+		return err
+	}
+	log.Info("✅ etcd restored", "snapshot", name)
+	return nil
+}
+
+func etcdManager(ctx context.Context, cluster string) (*bootstrap.Orchestrator, *etcdPkg.Manager, func(), error) {
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, cluster)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	manager, closeTunnel, err := orchestrator.EtcdManager(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return orchestrator, manager, closeTunnel, nil
+}
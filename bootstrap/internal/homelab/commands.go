@@ -1,25 +1,40 @@
 package homelab
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/blang/semver/v4"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/internal/cmdutil"
 	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/ceph"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
 	"github.com/fredericrous/homelab/bootstrap/pkg/destroy"
 	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/hubble"
 	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
+	"github.com/fredericrous/homelab/bootstrap/pkg/infra/terraform"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
 	"github.com/fredericrous/homelab/bootstrap/pkg/output"
 	"github.com/fredericrous/homelab/bootstrap/pkg/prereq"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
+	"github.com/fredericrous/homelab/bootstrap/pkg/proxmox"
 	"github.com/fredericrous/homelab/bootstrap/pkg/recovery"
 	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
+	"github.com/fredericrous/homelab/bootstrap/pkg/talos"
 	"github.com/fredericrous/homelab/bootstrap/pkg/tui"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,13 +46,29 @@ func NewBootstrapCommand() *cobra.Command {
 		Use:   "bootstrap",
 		Short: "Bootstrap the homelab cluster",
 		Long:  "Bootstrap a new homelab cluster with Talos, Cilium, and FluxCD",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: cmdutil.Recorded("homelab bootstrap", "homelab", func(cmd *cobra.Command, args []string) error {
 			noTui, _ := cmd.Flags().GetBool("no-tui")
-			return runBootstrap(cmd.Context(), noTui)
-		},
+			resume, _ := cmd.Flags().GetBool("resume")
+			fromStep, _ := cmd.Flags().GetString("from-step")
+			renew, _ := cmd.Flags().GetString("auto-renew-before")
+			renewBefore, err := cmdutil.ParseDuration(renew)
+			if err != nil {
+				return fmt.Errorf("--auto-renew-before: %w", err)
+			}
+			reportPath, _ := cmd.Flags().GetString("report")
+			reportMarkdown, _ := cmd.Flags().GetBool("report-markdown")
+			report := bootstrap.ReportOptions{Path: reportPath, Markdown: reportMarkdown}
+			return runBootstrap(cmd.Context(), noTui, resume, fromStep, renewBefore, report)
+		}),
 	}
 
 	cmd.Flags().Bool("no-tui", false, "Disable interactive TUI mode")
+	cmd.Flags().Bool("resume", false, "Resume an interrupted bootstrap at the step it failed")
+	cmd.Flags().String("from-step", "", "Start the bootstrap at the named step, skipping the ones before it")
+	cmd.Flags().String("auto-renew-before", "30d", "Renew the east-west gateway certificate when it expires within this duration, 0 disables")
+	cmd.Flags().String("report", "", "Path of the JSON bootstrap report, defaults to .bootstrap/reports/<cluster>.json")
+	cmd.Flags().Bool("report-markdown", false, "Also write the bootstrap report as Markdown next to the JSON file")
+	cmd.MarkFlagsMutuallyExclusive("resume", "from-step")
 	return cmd
 }
 
@@ -61,9 +92,9 @@ func NewInstallCommand() *cobra.Command {
 		Use:   "install",
 		Short: "Install homelab infrastructure",
 		Long:  "Install and configure homelab infrastructure components",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: cmdutil.Recorded("homelab install", "homelab", func(cmd *cobra.Command, args []string) error {
 			return runInstall(cmd.Context())
-		},
+		}),
 	}
 
 	return cmd
@@ -88,16 +119,23 @@ func NewDestroyCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "destroy",
 		Short: "Destroy homelab cluster",
-		Long:  "Destroy the homelab cluster and clean up resources",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDestroy(cmd.Context())
-		},
+		Long:  "Destroy the homelab cluster and clean up resources. The plan is shown first and the cluster name must be typed to confirm, unless --force is set.",
+		RunE: cmdutil.Recorded("homelab destroy", "homelab", func(cmd *cobra.Command, args []string) error {
+			skipBackup, _ := cmd.Flags().GetBool("skip-backup")
+			opts, err := cmdutil.DestroyOptions(cmd)
+			if err != nil {
+				return err
+			}
+			return runDestroy(cmd.Context(), skipBackup, opts, cmdutil.DestroyGateFor(cmd))
+		}),
 	}
+	cmd.Flags().Bool("skip-backup", false, "Destroy without exporting the cluster state first")
+	cmdutil.AddDestroyFlags(cmd)
 
 	return cmd
 }
 
-func runBootstrap(ctx context.Context, noTui bool) error {
+func runBootstrap(ctx context.Context, noTui, resume bool, fromStep string, renewBefore time.Duration, report bootstrap.ReportOptions) error {
 	// Auto-detect environment if no .env file
 	wd, _ := os.Getwd()
 	projectRoot := findProjectRoot(wd)
@@ -109,14 +147,15 @@ func runBootstrap(ctx context.Context, noTui bool) error {
 	}
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+	if !noTui && (resume || fromStep != "") {
+		// The TUI runs its own step list, checkpoints belong to the orchestrator
+		log.Info("Resuming runs in non-interactive mode")
+		noTui = true
 	}
 
 	if noTui {
@@ -128,37 +167,49 @@ func runBootstrap(ctx context.Context, noTui bool) error {
 			"distribution", cfg.Homelab.Cluster.Distribution)
 
 		// Create orchestrator and run bootstrap
-		orchestrator, err := bootstrap.NewOrchestrator(cfg, false, orchestratorOptions(false))
+		options := cmdutil.OrchestratorOptions(ctx, cfg, false)
+		options.Resume = resume
+		options.FromStep = fromStep
+		options.GatewayCertRenewBefore = renewBefore
+		options.Report = report
+		orchestrator, err := bootstrap.NewOrchestrator(cfg, false, options)
 		if err != nil {
 			return fmt.Errorf("failed to create orchestrator: %w", err)
 		}
 
-		return orchestrator.Bootstrap(ctx)
+		bus := orchestrator.Progress()
+		printed := make(chan struct{})
+		go func() {
+			progress.Print(bus.Subscribe())
+			close(printed)
+		}()
+		err = orchestrator.Bootstrap(ctx)
+		bus.Close()
+		<-printed
+		return err
 	}
 
 	// Start interactive bootstrap TUI
-	model := tui.NewBootstrapModel(ctx, cfg, false)
+	options := cmdutil.OrchestratorOptions(ctx, cfg, false)
+	options.GatewayCertRenewBefore = renewBefore
+	model := tui.NewBootstrapModel(ctx, cfg, false, options)
 	p := tea.NewProgram(model)
+	model.StreamEventsTo(p)
 
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("bootstrap failed: %w", err)
 	}
 
-	return nil
+	return model.Err()
 }
 
 func runCheck(ctx context.Context) error {
 	log.Info("Checking homelab prerequisites")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+		return err
 	}
 
 	// Run comprehensive prerequisite checks
@@ -168,6 +219,17 @@ func runCheck(ctx context.Context) error {
 		return fmt.Errorf("failed to run checks: %w", err)
 	}
 
+	if output.Structured() {
+		report := cmdutil.NewCheckReport("homelab", results)
+		if err := output.Print(report); err != nil {
+			return err
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("prerequisite checks failed")
+		}
+		return nil
+	}
+
 	// Display results
 	log.Info("Prerequisite Check Results")
 	log.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
@@ -209,14 +271,9 @@ func runCheck(ctx context.Context) error {
 func runInstall(ctx context.Context) error {
 	log.Info("Installing homelab infrastructure (non-interactive bootstrap)")
 
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+		return err
 	}
 
 	if err := ensureHomelabKubeconfig(ctx, cfg); err != nil {
@@ -227,25 +284,20 @@ func runInstall(ctx context.Context) error {
 		return err
 	}
 
-	return runBootstrap(ctx, true)
+	return runBootstrap(ctx, true, false, "", bootstrap.DefaultGatewayCertRenewBefore, bootstrap.ReportOptions{})
 }
 
 func runValidate(ctx context.Context) error {
 	log.Info("Validating homelab deployment")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+		return err
 	}
 
 	// Connect to cluster
-	client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
 	if err != nil {
 		return fmt.Errorf("failed to connect to cluster: %w", err)
 	}
@@ -257,6 +309,10 @@ func runValidate(ctx context.Context) error {
 		return fmt.Errorf("failed to get flux status: %w", err)
 	}
 
+	if output.Structured() {
+		return output.Print(&cmdutil.ValidateReport{Cluster: "homelab", Flux: status})
+	}
+
 	if status.Ready {
 		log.Info("FluxCD is running", "status", "ready")
 	} else {
@@ -267,18 +323,13 @@ func runValidate(ctx context.Context) error {
 	return nil
 }
 
-func runDestroy(ctx context.Context) error {
+func runDestroy(ctx context.Context, skipBackup bool, opts destroy.Options, gate cmdutil.DestroyGate) error {
 	log.Warn("🗑️ Destroying homelab cluster")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+		return err
 	}
 
 	// Create destroy manager
@@ -286,6 +337,18 @@ func runDestroy(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create destroy manager: %w", err)
 	}
+	destroyManager.SetOptions(opts)
+	proceed, err := gate.Proceed(ctx, destroyManager)
+	if err != nil || !proceed {
+		return err
+	}
+	if !skipBackup {
+		dir, err := cmdutil.SnapshotDir()
+		if err != nil {
+			return err
+		}
+		destroyManager.SetSnapshot(dir, cmdutil.SnapshotKey(ctx, cfg, "homelab"))
+	}
 
 	// Perform destruction
 	if err := destroyManager.DestroyCluster(ctx); err != nil {
@@ -301,12 +364,22 @@ func NewUpCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "up",
 		Short: "Create homelab cluster infrastructure",
-		Long:  "Create cluster infrastructure (VMs + Talos, ready for CNI)",
+		Long: "Create cluster infrastructure (VMs + Talos, ready for CNI): plan and apply the VMs with Terraform or OpenTofu, " +
+			"record their addresses and MACs in homelab.yaml, then generate and apply Talos machine configs, bootstrap etcd and fetch the kubeconfig",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runUp(cmd.Context())
+			skipProvision, _ := cmd.Flags().GetBool("skip-provision")
+			noTui, _ := cmd.Flags().GetBool("no-tui")
+			planOnly, _ := cmd.Flags().GetBool("plan")
+			if planOnly {
+				return runUpPlan(cmd.Context())
+			}
+			return runUp(cmd.Context(), skipProvision, noTui)
 		},
 	}
 
+	cmd.Flags().Bool("skip-provision", false, "Configure already running Talos machines without provisioning VMs")
+	cmd.Flags().Bool("no-tui", false, "Disable interactive TUI mode")
+	cmd.Flags().Bool("plan", false, "Only show the Terraform plan of the VMs")
 	return cmd
 }
 
@@ -338,381 +411,1694 @@ func NewSyncSecretsCommand() *cobra.Command {
 	return cmd
 }
 
-// NewSuspendCommand creates the suspend command
-func NewSuspendCommand() *cobra.Command {
+// NewSecretsCommand creates the secrets command group
+func NewSecretsCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "suspend",
-		Short: "Suspend Flux reconciliation",
-		Long:  "Suspend Flux reconciliation (services keep running)",
+		Use:   "secrets",
+		Short: "Manage how cluster secrets are sourced",
+	}
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate-eso",
+		Short: "Move cluster-vars into the NAS Vault behind an ExternalSecret",
+		Long: "Write the flux-system/cluster-vars secret to the NAS Vault KV engine and apply an ExternalSecret " +
+			"that keeps it in sync from there, installing the External Secrets Operator and its ClusterSecretStore when needed",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSuspend(cmd.Context())
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runMigrateESO(cmd.Context(), dryRun)
 		},
 	}
+	migrateCmd.Flags().Bool("dry-run", false, "List the keys that would be migrated")
 
+	cmd.AddCommand(migrateCmd)
 	return cmd
 }
 
-// NewResumeCommand creates the resume command
-func NewResumeCommand() *cobra.Command {
+// NewVaultCommand creates the vault command group
+func NewVaultCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "resume",
-		Short: "Resume Flux reconciliation",
-		Long:  "Resume Flux reconciliation",
+		Use:   "vault",
+		Short: "Manage the homelab Vault",
+	}
+
+	pkiCmd := &cobra.Command{
+		Use:   "pki-setup",
+		Short: "Make the homelab Vault an intermediate CA signed by the NAS Vault",
+		Long: "Mount the PKI engine of the homelab Vault, sign its intermediate CA with the root under security.vault.pki_path " +
+			"of the NAS Vault (VAULT_TOKEN), write the cert-manager roles and record the issuing CA in cluster-vars. " +
+			"A still valid intermediate CA is kept unless --force is set",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runResume(cmd.Context())
+			force, _ := cmd.Flags().GetBool("force")
+			return runVaultPKISetup(cmd.Context(), force)
+		},
+	}
+	pkiCmd.Flags().Bool("force", false, "Re-sign the intermediate CA even when it is still valid")
+
+	rotateCmd := &cobra.Command{
+		Use:   "rotate-transit-token",
+		Short: "Replace the token the homelab Vault auto-unseals with",
+		Long: "Create a token with the transit unseal policy against the NAS Vault (VAULT_TOKEN), update the vault and " +
+			"flux-system vault-transit-token secrets, restart the Vault pods one at a time, check they come back unsealed " +
+			"and only then revoke the previous token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRotateTransitToken(cmd.Context())
 		},
 	}
 
+	cmd.AddCommand(pkiCmd)
+	cmd.AddCommand(rotateCmd)
 	return cmd
 }
 
-// NewUninstallCommand creates the uninstall command
-func NewUninstallCommand() *cobra.Command {
+// NewNodesCommand creates the nodes command group
+func NewNodesCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "uninstall",
-		Short: "Uninstall homelab cluster",
-		Long:  "Uninstall everything (cluster + VMs + configs)",
+		Use:   "nodes",
+		Short: "Manage Talos nodes",
+	}
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade Talos on nodes one batch at a time",
+		Long: "Cordon and drain each node, install the Talos installer image through the Talos API, " +
+			"wait for the node to reboot and rejoin Ready, then uncordon it",
+		Example: `  bootstrap homelab nodes upgrade --image factory.talos.dev/installer/<schematic>:v1.11.0 --node 192.168.1.68
+  bootstrap homelab nodes upgrade --all --serial 1`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runUninstall(cmd.Context())
+			image, _ := cmd.Flags().GetString("image")
+			nodes, _ := cmd.Flags().GetStringSlice("node")
+			all, _ := cmd.Flags().GetBool("all")
+			serial, _ := cmd.Flags().GetInt("serial")
+			talosconfig, _ := cmd.Flags().GetString("talosconfig")
+			return runNodesUpgrade(cmd.Context(), image, nodes, all, serial, talosconfig)
 		},
 	}
+	upgradeCmd.Flags().String("image", "", "Talos installer image, defaults to talos.install_image")
+	upgradeCmd.Flags().StringSlice("node", nil, "Address of a node to upgrade (repeatable)")
+	upgradeCmd.Flags().Bool("all", false, "Upgrade every node, control planes first")
+	upgradeCmd.Flags().Int("serial", 1, "Number of nodes upgraded at the same time")
+	upgradeCmd.Flags().String("talosconfig", "", "Path to the talosconfig, defaults to the one written by homelab up")
 
+	cmd.AddCommand(upgradeCmd)
 	return cmd
 }
 
-// NewStatusCommand creates the status command
-func NewStatusCommand() *cobra.Command {
+// NewCephCommand creates the ceph command group
+func NewCephCommand() *cobra.Command {
 	cmd := &cobra.Command{
+		Use:   "ceph",
+		Short: "Inspect and maintain the Rook-Ceph cluster",
+	}
+
+	statusCmd := &cobra.Command{
 		Use:   "status",
-		Short: "Check homelab status",
-		Long:  "Check status of homelab cluster and components",
+		Short: "Show Ceph health, OSDs, placement groups and capacity",
+		Long: "Read the Ceph status with the rook-ceph-tools toolbox. Without a toolbox only the health and capacity " +
+			"Rook reports on the CephCluster are shown",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runStatus(cmd.Context())
+			return runCephStatus(cmd.Context())
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set-maintenance",
+		Short: "Set noout and norebalance before draining or rebooting nodes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCephMaintenance(cmd.Context(), true)
+		},
+	}
+
+	unsetCmd := &cobra.Command{
+		Use:   "unset-maintenance",
+		Short: "Clear noout and norebalance once the nodes are back",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCephMaintenance(cmd.Context(), false)
 		},
 	}
 
+	cmd.AddCommand(statusCmd)
+	cmd.AddCommand(setCmd)
+	cmd.AddCommand(unsetCmd)
 	return cmd
 }
 
-func runUp(ctx context.Context) error {
-	log.Info("🚀 Creating homelab cluster infrastructure (VMs + Talos)")
+// NewHubbleCommand creates the hubble command group reading flows from the Hubble relay
+func NewHubbleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hubble",
+		Short: "Observe network flows with Hubble",
+		Long:  "Read flows and relay status from the Hubble relay over a port-forward, without installing the hubble CLI",
+	}
 
-	// Delegate to infrastructure Taskfile
-	if err := runInfrastructureTask(ctx, "homelab", "up"); err != nil {
-		return err
+	flowsCmd := &cobra.Command{
+		Use:   "flows",
+		Short: "Show dropped flows, policy verdicts and DNS queries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, _ := cmd.Flags().GetString("namespace")
+			since, _ := cmd.Flags().GetDuration("since")
+			kinds, _ := cmd.Flags().GetStringSlice("type")
+			follow, _ := cmd.Flags().GetBool("follow")
+			if all, _ := cmd.Flags().GetBool("all"); all {
+				kinds = nil
+			}
+			for _, kind := range kinds {
+				if !slices.Contains(hubble.DefaultKinds, kind) {
+					return fmt.Errorf("unknown flow type %q, use drop, policy or dns", kind)
+				}
+			}
+			return runHubbleFlows(cmd.Context(), hubble.FlowOptions{Namespace: namespace, Since: since, Kinds: kinds, Follow: follow})
+		},
 	}
+	flowsCmd.Flags().StringP("namespace", "n", "", "Only show flows from or to pods of this namespace")
+	flowsCmd.Flags().Duration("since", 5*time.Minute, "Show flows observed within this duration")
+	flowsCmd.Flags().StringSlice("type", hubble.DefaultKinds, "Flow types to show (drop, policy, dns)")
+	flowsCmd.Flags().Bool("all", false, "Show every flow, not only drops, policy verdicts and DNS")
+	flowsCmd.Flags().BoolP("follow", "f", false, "Keep printing flows as they are observed")
 
-	if err := syncHomelabKubeconfigFile(); err != nil {
-		return fmt.Errorf("failed to sync homelab kubeconfig: %w", err)
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the Hubble relay status and its connected nodes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHubbleStatus(cmd.Context())
+		},
 	}
 
-	return nil
+	cmd.AddCommand(flowsCmd)
+	cmd.AddCommand(statusCmd)
+	return cmd
 }
 
-// runInfrastructureTask executes a task in the specified infrastructure Taskfile
-func runInfrastructureTask(ctx context.Context, infra, task string) error {
-	// Find project root to work from both repo root and bootstrap directory
-	wd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
+// NewVMCommand creates the vm command group managing the cluster VMs on Proxmox
+func NewVMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vm",
+		Short: "Manage the cluster VMs on Proxmox",
+		Long: "Act on the VMs of the cluster nodes, listed in homelab.proxmox.vms or the hostnames of homelab.talos.nodes, " +
+			"through the Proxmox API at homelab.proxmox.url with an API token (PROXMOX_TOKEN_ID and PROXMOX_TOKEN_SECRET)",
 	}
 
-	projectRoot := findProjectRoot(wd)
-	if projectRoot == "" {
-		return fmt.Errorf("project root not found - ensure you're running from within the homelab project")
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show the status, host node and latest snapshot of each cluster VM",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVMList(cmd.Context())
+		},
 	}
 
-	// Determine the infrastructure directory relative to project root
-	infrastructureDir := filepath.Join(projectRoot, "infrastructure", infra)
+	startCmd := &cobra.Command{
+		Use:   "start [vm...]",
+		Short: "Start stopped VMs, every cluster VM when none is named",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVMStart(cmd.Context(), args)
+		},
+	}
 
-	// Check if the Taskfile exists
-	taskfilePath := filepath.Join(infrastructureDir, "Taskfile.yml")
-	if _, err := os.Stat(taskfilePath); os.IsNotExist(err) {
-		return fmt.Errorf("infrastructure Taskfile not found: %s", taskfilePath)
+	stopCmd := &cobra.Command{
+		Use:   "stop <vm...>",
+		Short: "Shut VMs down, stopping them hard once the timeout passes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, _ := cmd.Flags().GetBool("all")
+			force, _ := cmd.Flags().GetBool("force")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			if len(args) == 0 && !all {
+				return fmt.Errorf("name the VMs to stop, or pass --all to stop the whole cluster")
+			}
+			return runVMStop(cmd.Context(), args, force, timeout)
+		},
 	}
+	stopCmd.Flags().Bool("all", false, "Stop every cluster VM")
+	stopCmd.Flags().Bool("force", false, "Power off immediately instead of asking the guest to shut down")
+	stopCmd.Flags().Duration("timeout", 3*time.Minute, "How long the guest gets to shut down before it is stopped hard")
 
-	// Execute the task using the task command
-	cmd := exec.CommandContext(ctx, "task", "-d", infrastructureDir, task)
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot [vm...]",
+		Short: "Snapshot VMs under one name, every cluster VM when none is named",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			description, _ := cmd.Flags().GetString("description")
+			if name == "" {
+				name = "bootstrap-" + time.Now().Format("20060102-150405")
+			}
+			return runVMSnapshot(cmd.Context(), args, name, description)
+		},
+	}
+	snapshotCmd.Flags().String("name", "", "Snapshot name, bootstrap-<timestamp> by default")
+	snapshotCmd.Flags().String("description", "", "Snapshot description")
 
-	// Use output manager to respect TUI mode
-	outputMgr := output.GetManager()
-	cmd.Stdout = outputMgr.GetStdout()
-	cmd.Stderr = outputMgr.GetStderr()
-	cmd.Stdin = os.Stdin
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback <snapshot> [vm...]",
+		Short: "Roll VMs back to a snapshot, then start them again",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, _ := cmd.Flags().GetBool("all")
+			if len(args) == 1 && !all {
+				return fmt.Errorf("name the VMs to roll back, or pass --all to roll back the whole cluster")
+			}
+			return runVMRollback(cmd.Context(), args[0], args[1:])
+		},
+	}
+	rollbackCmd.Flags().Bool("all", false, "Roll back every cluster VM")
 
-	log.Debug("Executing infrastructure task", "infra", infra, "task", task, "dir", infrastructureDir, "projectRoot", projectRoot)
+	cmd.AddCommand(listCmd)
+	cmd.AddCommand(startCmd)
+	cmd.AddCommand(stopCmd)
+	cmd.AddCommand(snapshotCmd)
+	cmd.AddCommand(rollbackCmd)
+	return cmd
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("infrastructure task failed: %w", err)
+// NewSyncCommand creates the sync command for config-only changes
+func NewSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Apply config-only changes without a full bootstrap",
+		Long:  "Detect which bootstrap-managed artifacts (cluster-vars, mesh variables, Cilium values, Flux sync manifests) differ from the local config and .env files, apply only those and reconcile the Flux objects consuming them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runSync(cmd.Context(), dryRun)
+		},
 	}
 
-	return nil
+	cmd.Flags().Bool("dry-run", false, "Show the changes without applying them")
+	return cmd
 }
 
-// findProjectRoot finds the project root directory by looking for common project files
-func findProjectRoot(startDir string) string {
-	current := startDir
-	var lastMatch string
-	for {
-		// Check for project indicators
-		indicators := []string{".git", "go.mod", "bootstrap", "Taskfile.yml"}
-		for _, indicator := range indicators {
-			if _, err := os.Stat(filepath.Join(current, indicator)); err == nil {
-				if indicator == ".git" {
-					return current
-				}
-				lastMatch = current
-			}
-		}
-
-		// Move up one directory
-		parent := filepath.Dir(current)
-		if parent == current {
-			// Reached filesystem root
-			break
-		}
-		current = parent
+// NewAdoptCommand creates the adopt command for clusters installed by other means
+func NewAdoptCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "adopt",
+		Short: "Take over a cluster whose Flux and Cilium were installed by other means",
+		Long: "Detect an existing Flux and Cilium installation, record the Flux objects in the apply inventory and hand " +
+			"their kubectl or flux CLI fields to the bootstrap field manager without applying them, then create only the " +
+			"missing secrets and Istio prerequisites and report what was adopted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runAdopt(cmd.Context(), dryRun)
+		},
 	}
 
-	return lastMatch
+	cmd.Flags().Bool("dry-run", false, "Report what would be adopted and created without changing the cluster")
+	return cmd
 }
 
-func orchestratorOptions(isNAS bool) *bootstrap.OrchestratorOptions {
-	if isNAS {
-		return &bootstrap.OrchestratorOptions{
-			KubeconfigPath:        kubeconfigFor("nas"),
-			HomelabKubeconfigPath: kubeconfigFor("homelab"),
-			NASKubeconfigPath:     kubeconfigFor("nas"),
-		}
-	}
-	return &bootstrap.OrchestratorOptions{
-		KubeconfigPath:        kubeconfigFor("homelab"),
-		HomelabKubeconfigPath: kubeconfigFor("homelab"),
-		NASKubeconfigPath:     kubeconfigFor("nas"),
+// NewSuspendCommand creates the suspend command
+func NewSuspendCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "suspend",
+		Short: "Suspend Flux reconciliation",
+		Long:  "Suspend Flux reconciliation (services keep running)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSuspend(cmd.Context())
+		},
 	}
+
+	return cmd
 }
 
-func ensureHomelabKubeconfig(ctx context.Context, cfg *config.Config) error {
-	dest := cfg.Homelab.Cluster.KubeConfig
-	if dest == "" {
-		return fmt.Errorf("homelab kubeconfig path not configured")
+// NewResumeCommand creates the resume command
+func NewResumeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume Flux reconciliation",
+		Long:  "Resume Flux reconciliation",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runResume(cmd.Context())
+		},
 	}
 
-	if _, err := os.Stat(dest); err == nil {
-		return nil
-	}
+	return cmd
+}
 
-	if err := syncHomelabKubeconfigFile(); err == nil {
-		if _, err := os.Stat(dest); err == nil {
-			log.Info("✨ Synced existing kubeconfig", "path", dest)
-			return nil
-		}
-	}
+// NewFluxCommand creates the flux command group acting on single Flux resources
+func NewFluxCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flux",
+		Short: "Reconcile, suspend, resume or inspect Flux resources",
+		Long:  "Act on a single Flux resource, given as <kind>/<name> or with --kind and --name, or show the Flux tree",
+	}
+
+	cmd.AddCommand(newFluxResourceCommand("reconcile", "Request an immediate reconciliation of a Flux resource",
+		func(ctx context.Context, c *flux.Client, kind, namespace, name string) error {
+			return c.Reconcile(ctx, kind, namespace, name)
+		}))
+	cmd.AddCommand(newFluxResourceCommand("suspend", "Suspend reconciliation of a Flux resource",
+		func(ctx context.Context, c *flux.Client, kind, namespace, name string) error {
+			return c.Suspend(ctx, kind, namespace, name)
+		}))
+	cmd.AddCommand(newFluxResourceCommand("resume", "Resume reconciliation of a Flux resource",
+		func(ctx context.Context, c *flux.Client, kind, namespace, name string) error {
+			return c.Resume(ctx, kind, namespace, name)
+		}))
+	cmd.AddCommand(newFluxTreeCommand())
+	cmd.AddCommand(newImageAutomationCommand())
+	cmd.AddCommand(newFluxUpgradeCommand())
 
-	log.Info("Homelab kubeconfig missing, provisioning infrastructure with 'task homelab:up'")
-	if err := runUp(ctx); err != nil {
-		return fmt.Errorf("failed to provision infrastructure: %w", err)
+	return cmd
+}
+
+func newImageAutomationCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image-automation",
+		Short: "Configure Flux image automation",
 	}
 
-	if _, err := os.Stat(dest); err != nil {
-		if err := syncHomelabKubeconfigFile(); err != nil {
-			return fmt.Errorf("failed to sync kubeconfig after provisioning: %w", err)
-		}
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the image scanning and update objects for the configured images",
+		Long: "Generate an ImageRepository and ImagePolicy per image of gitops.image_automation.images and the ImageUpdateAutomation " +
+			"committing new tags to the GitOps repository, wiring the GitHub token to the flux-system GitRepository for the push",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runImageAutomationInit(cmd.Context(), dryRun)
+		},
 	}
+	initCmd.Flags().Bool("dry-run", false, "Print the manifests without applying them")
+	cmd.AddCommand(initCmd)
 
-	return nil
+	return cmd
 }
 
-func ensureHomelabCilium(ctx context.Context, cfg *config.Config) error {
-	client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+func runImageAutomationInit(ctx context.Context, dryRun bool) error {
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to connect to cluster: %w", err)
-	}
-
-	if err := client.IsReady(ctx); err != nil {
-		return fmt.Errorf("cluster not ready: %w", err)
+		return err
 	}
 
-	ds, err := client.GetClientset().AppsV1().DaemonSets("kube-system").Get(ctx, "cilium", metav1.GetOptions{})
-	if err == nil && ds != nil && ds.Status.NumberReady > 0 {
-		log.Info("✅ Cilium already installed", "ready", ds.Status.NumberReady)
+	if dryRun {
+		manifest, err := flux.NewClient(nil, &cfg.Homelab.GitOps).ImageAutomationManifest("flux-system")
+		if err != nil {
+			return err
+		}
+		fmt.Print(manifest)
 		return nil
 	}
 
-	log.Info("🌐 Cilium not detected, installing CNI")
-	if err := runInstallCilium(ctx); err != nil {
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+	if err := flux.NewClient(client, &cfg.Homelab.GitOps).InitImageAutomation(ctx, "flux-system"); err != nil {
 		return err
 	}
 
+	log.Info("✅ Image automation configured")
+	log.Info("Mark the image fields Flux may update with a setter comment, then push:")
+	for _, image := range cfg.Homelab.GitOps.ImageAutomation.Images {
+		fmt.Printf("    image: %s:<tag> # {\"$imagepolicy\": \"flux-system:%s\"}\n", image.Image, image.Name)
+	}
 	return nil
 }
 
-func syncHomelabKubeconfigFile() error {
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
-	if err != nil {
-		return fmt.Errorf("failed to load homelab config: %w", err)
+func newFluxUpgradeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the Flux controllers to a newer release",
+		Long: "Render the install manifests of the release, diff their CRDs with the installed ones, migrate the objects " +
+			"stored at CRD versions the release drops, server-side apply the manifests, wait for every controller to roll " +
+			"out and verify the GitOps sync again. Defaults to gitops.flux_version, or the latest release when unpinned.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, _ := cmd.Flags().GetString("version")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return runFluxUpgrade(cmd.Context(), version, dryRun)
+		},
 	}
 
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
-	}
+	cmd.Flags().String("version", "", "Flux release to upgrade to, e.g. v2.7.2 (default gitops.flux_version or the latest release)")
+	cmd.Flags().Bool("dry-run", false, "Show the version and CRD changes without applying them")
+	return cmd
+}
 
-	dest := cfg.Homelab.Cluster.KubeConfig
-	if dest == "" {
-		return fmt.Errorf("homelab kubeconfig path not configured")
+func runFluxUpgrade(ctx context.Context, version string, dryRun bool) error {
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
 	}
 
-	wd, err := os.Getwd()
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
 	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
+		return fmt.Errorf("failed to connect to cluster: %w", err)
 	}
 
+	fluxClient := flux.NewClient(client, &cfg.Homelab.GitOps)
+	plan, err := fluxClient.PlanUpgrade(ctx, "flux-system", version)
+	if err != nil {
+		return err
+	}
+	if from, err := semver.ParseTolerant(plan.From); err == nil {
+		if to, err := semver.ParseTolerant(plan.To); err == nil && to.LT(from) {
+			return fmt.Errorf("the cluster runs Flux %s, refusing to downgrade to %s", plan.From, plan.To)
+		}
+	}
+
+	if output.Structured() && dryRun {
+		return output.Print(plan)
+	}
+	log.Info("Flux upgrade", "from", plan.From, "to", plan.To)
+	for _, crd := range plan.CRDs {
+		switch {
+		case crd.New:
+			log.Info("  new CRD", "crd", crd.Name, "storage", crd.StorageTo)
+		case crd.Changed():
+			log.Info("  changed CRD", "crd", crd.Name,
+				"added", strings.Join(crd.AddedVersions, ","), "removed", strings.Join(crd.RemovedVersions, ","),
+				"storage", crd.StorageFrom+" → "+crd.StorageTo, "schema_changed", crd.SchemaChanged)
+		}
+		if len(crd.Migrate) > 0 {
+			log.Warn("  stored objects to migrate", "crd", crd.Name, "versions", strings.Join(crd.Migrate, ","), "to", crd.StorageFrom)
+		}
+	}
+	if plan.UpToDate() {
+		log.Info("✅ Flux is up to date", "version", plan.To)
+		return nil
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := fluxClient.Upgrade(ctx, "flux-system", plan); err != nil {
+		return err
+	}
+
+	log.Info("✅ Flux upgraded", "version", plan.To)
+	if pinned := cfg.Homelab.GitOps.FluxVersion; pinned == "" || strings.TrimPrefix(pinned, "v") != strings.TrimPrefix(plan.To, "v") {
+		log.Warn("Pin the release so a re-bootstrap installs it too", "key", "homelab.gitops.flux_version", "version", plan.To)
+	}
+	if output.Structured() {
+		return output.Print(plan)
+	}
+	return nil
+}
+
+func newFluxTreeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tree [<kind>/<name>]",
+		Short: "Show sources, Kustomizations, HelmReleases and workloads as a tree",
+		Long: "Walk GitRepositories to the Kustomizations they feed, the Kustomizations and HelmReleases those apply, " +
+			"and the workloads behind them, with readiness, last applied revision and error message per node",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, _ := cmd.Flags().GetString("namespace")
+			return runFluxTree(cmd.Context(), args, namespace)
+		},
+	}
+
+	cmd.Flags().StringP("namespace", "n", "flux-system", "Namespace of the root resource")
+	return cmd
+}
+
+func runFluxTree(ctx context.Context, args []string, namespace string) error {
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+
+	roots, err := flux.NewClient(client, &cfg.Homelab.GitOps).Tree(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		argKind, name, ok := strings.Cut(args[0], "/")
+		if !ok || argKind == "" || name == "" {
+			return fmt.Errorf("expected <kind>/<name>, got %q", args[0])
+		}
+		kind, err := flux.ParseKind(argKind)
+		if err != nil {
+			return err
+		}
+		var root *flux.TreeNode
+		for _, candidate := range roots {
+			if root = candidate.Find(kind, namespace, name); root != nil {
+				break
+			}
+		}
+		if root == nil {
+			return fmt.Errorf("%s %s/%s not found in the Flux tree", kind, namespace, name)
+		}
+		roots = []*flux.TreeNode{root}
+	}
+
+	if output.Structured() {
+		return output.Print(roots)
+	}
+	for _, root := range roots {
+		printTreeNode(root, "", "")
+	}
+	return nil
+}
+
+// printTreeNode prints node and its children with box-drawing branches
+func printTreeNode(node *flux.TreeNode, prefix, childPrefix string) {
+	icon := "✅"
+	switch {
+	case node.Suspended:
+		icon = "⏸️"
+	case !node.Ready:
+		icon = "❌"
+	}
+
+	line := fmt.Sprintf("%s%s %s %s/%s", prefix, icon, node.Kind, node.Namespace, node.Name)
+	if node.Revision != "" {
+		line += "  " + node.Revision
+	}
+	if node.Message != "" {
+		line += "  (" + node.Message + ")"
+	}
+	fmt.Println(line)
+
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			printTreeNode(child, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			printTreeNode(child, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+type fluxResourceAction func(ctx context.Context, c *flux.Client, kind, namespace, name string) error
+
+func newFluxResourceCommand(use, short string, action fluxResourceAction) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use + " [<kind>/<name>]",
+		Short: short,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, _ := cmd.Flags().GetString("kind")
+			name, _ := cmd.Flags().GetString("name")
+			namespace, _ := cmd.Flags().GetString("namespace")
+			if len(args) == 1 {
+				argKind, argName, ok := strings.Cut(args[0], "/")
+				if !ok || argKind == "" || argName == "" {
+					return fmt.Errorf("expected <kind>/<name>, got %q", args[0])
+				}
+				kind, name = argKind, argName
+			}
+			if name == "" {
+				return fmt.Errorf("resource name required, pass <kind>/<name> or --name")
+			}
+			return runFluxResource(cmd.Context(), use, action, kind, namespace, name)
+		},
+	}
+
+	cmd.Flags().String("kind", "Kustomization", "Flux kind (Kustomization, HelmRelease, GitRepository, ...)")
+	cmd.Flags().String("name", "", "Resource name")
+	cmd.Flags().StringP("namespace", "n", "flux-system", "Resource namespace")
+	return cmd
+}
+
+func runFluxResource(ctx context.Context, verb string, action fluxResourceAction, kind, namespace, name string) error {
+	kind, err := flux.ParseKind(kind)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+
+	fluxClient := flux.NewClient(client, &cfg.Homelab.GitOps)
+	if err := action(ctx, fluxClient, kind, namespace, name); err != nil {
+		return err
+	}
+
+	log.Info("✅ Flux resource updated", "action", verb, "kind", kind, "namespace", namespace, "name", name)
+	return nil
+}
+
+// NewUninstallCommand creates the uninstall command
+func NewUninstallCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Uninstall homelab cluster",
+		Long:  "Uninstall everything (cluster + VMs + configs)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUninstall(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+// NewStatusCommand creates the status command
+func NewStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Check homelab status",
+		Long:  "Check status of homelab cluster and components",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+func runUp(ctx context.Context, skipProvision, noTui bool) error {
+	log.Info("🚀 Creating homelab cluster infrastructure (VMs + Talos)")
+
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	projectRoot := findProjectRoot(wd)
+	if projectRoot == "" {
+		return fmt.Errorf("project root not found - ensure you're running from within the homelab project")
+	}
+
+	opts, err := talos.OptionsFromConfig(cfg.Homelab, projectRoot)
+	if err != nil {
+		return err
+	}
+	lifecycle := talos.NewLifecycle(opts)
+
+	showPlan := func(plan *terraform.Plan) {
+		log.Info("📋 " + plan.Summary())
+		for _, line := range plan.Lines() {
+			log.Info("  " + line)
+		}
+	}
+
+	var steps []talos.Step
+	if !skipProvision {
+		// VMs are created by Terraform, Talos is configured natively afterwards
+		runner, err := newTerraformRunner(cfg.Homelab, projectRoot)
+		if err != nil {
+			return err
+		}
+		var plan *terraform.Plan
+		steps = append(steps,
+			talos.Step{
+				Name:        "plan-vms",
+				Description: "Plan the VMs with Terraform",
+				Run: func(ctx context.Context) error {
+					if err := runner.Init(ctx); err != nil {
+						return err
+					}
+					computed, err := runner.Plan(ctx, terraform.PlanOptions{Targets: []string{vmsTarget}})
+					if err != nil {
+						return err
+					}
+					plan = computed
+					showPlan(plan)
+					return nil
+				},
+			},
+			talos.Step{
+				Name:        "provision-vms",
+				Description: "Apply the VM plan",
+				Run: func(ctx context.Context) error {
+					if plan.Empty() {
+						log.Info("VMs already match the Terraform configuration")
+						return nil
+					}
+					if err := k8s.GuardMutation("terraform apply"); err != nil {
+						return err
+					}
+					return runner.Apply(ctx, plan)
+				},
+			},
+			talos.Step{
+				Name:        "record-nodes",
+				Description: "Record the VM addresses in the config",
+				Run: func(ctx context.Context) error {
+					opts, err := recordNodes(ctx, runner, cfg, projectRoot)
+					if err != nil {
+						return err
+					}
+					lifecycle.SetOptions(opts)
+					return nil
+				},
+			},
+		)
+	}
+	steps = append(steps, lifecycle.Steps()...)
+
+	if noTui {
+		for i, step := range steps {
+			log.Info("Executing up step", "step", i+1, "total", len(steps), "name", step.Name, "description", step.Description)
+			if err := step.Run(ctx); err != nil {
+				return fmt.Errorf("%s failed: %w", step.Name, err)
+			}
+		}
+	} else {
+		model := tui.NewUpModel(ctx, steps)
+		program := tea.NewProgram(model)
+		lifecycle.SetProgressSink(func(message string) {
+			program.Send(tui.LogMsg{Message: message})
+		})
+		showPlan = func(plan *terraform.Plan) {
+			program.Send(tui.PlanMsg{Summary: plan.Summary(), Changes: plan.Lines()})
+		}
+		if _, err := program.Run(); err != nil {
+			return fmt.Errorf("up failed: %w", err)
+		}
+		if err := model.Err(); err != nil {
+			return err
+		}
+	}
+
+	log.Info("✅ Cluster infrastructure ready. Use 'bootstrap homelab install-cilium' to install CNI.", "kubeconfig", opts.KubeconfigPath, "talosconfig", opts.TalosconfigPath())
+	return nil
+}
+
+// vmsTarget is the module of the homelab root module creating the VMs, Talos is configured without Terraform
+const vmsTarget = "module.vms"
+
+// runUpPlan shows the changes homelab up would make to the VMs
+func runUpPlan(ctx context.Context) error {
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
 	projectRoot := findProjectRoot(wd)
 	if projectRoot == "" {
 		return fmt.Errorf("project root not found - ensure you're running from within the homelab project")
 	}
 
-	sourceCandidates := []string{
-		filepath.Join(projectRoot, "kubeconfig"),
-		filepath.Join(projectRoot, "infrastructure", "homelab", "kubeconfig"),
+	runner, err := newTerraformRunner(cfg.Homelab, projectRoot)
+	if err != nil {
+		return err
+	}
+	if err := runner.Init(ctx); err != nil {
+		return err
+	}
+	plan, err := runner.Plan(ctx, terraform.PlanOptions{Targets: []string{vmsTarget}})
+	if err != nil {
+		return err
+	}
+	if output.Structured() {
+		return output.Print(plan)
+	}
+	fmt.Println(plan.Summary())
+	for _, line := range plan.Lines() {
+		fmt.Println("  " + line)
+	}
+	return nil
+}
+
+// newTerraformRunner drives the root module of the homelab VMs, infrastructure/homelab unless configured
+func newTerraformRunner(cfg *config.HomelabConfig, projectRoot string) (*terraform.Runner, error) {
+	dir := filepath.Join(projectRoot, "infrastructure", "homelab")
+	binary := ""
+	if infrastructure := cfg.Infrastructure; infrastructure != nil {
+		if infrastructure.TerraformDir != "" {
+			dir = infrastructure.TerraformDir
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(projectRoot, dir)
+			}
+		}
+		binary = infrastructure.Binary
+	}
+	runner, err := terraform.New(dir, binary)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use output manager to respect TUI mode
+	outputMgr := output.GetManager()
+	runner.Stdout = outputMgr.GetStdout()
+	runner.Stderr = outputMgr.GetStderr()
+	return runner, nil
+}
+
+// recordNodes writes the VMs found in the Terraform outputs to homelab.yaml and returns the lifecycle options for them
+func recordNodes(ctx context.Context, runner *terraform.Runner, cfg *config.Config, projectRoot string) (talos.Options, error) {
+	outputs, err := runner.Outputs(ctx)
+	if err != nil {
+		return talos.Options{}, err
+	}
+	vms, err := outputs.VMs()
+	if err != nil {
+		return talos.Options{}, err
+	}
+
+	nodes := make([]config.TalosNode, 0, len(vms))
+	addresses := make([]string, 0, len(vms))
+	for _, vm := range vms {
+		node := config.TalosNode{Address: vm.IP, Role: vm.Role, Hostname: vm.Hostname, MAC: vm.MAC}
+		for _, existing := range cfg.Homelab.Talos.Nodes {
+			if (existing.Hostname != "" && existing.Hostname == vm.Hostname) || existing.Address == vm.IP {
+				node.Patches = existing.Patches
+				break
+			}
+		}
+		nodes = append(nodes, node)
+		addresses = append(addresses, vm.IP)
+	}
+
+	path, err := config.NewLoader().FindConfigFile("homelab")
+	if err != nil {
+		return talos.Options{}, err
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return talos.Options{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	updated, err := config.SetNodes(original, "homelab", nodes)
+	if err != nil {
+		return talos.Options{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if !bytes.Equal(original, updated) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return talos.Options{}, err
+		}
+		if err := os.WriteFile(path, updated, info.Mode().Perm()); err != nil {
+			return talos.Options{}, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		log.Info("📝 Recorded the VM addresses in "+path, "nodes", len(nodes))
+	}
+
+	cfg.Homelab.Cluster.Nodes = addresses
+	cfg.Homelab.Talos.Nodes = nodes
+	return talos.OptionsFromConfig(cfg.Homelab, projectRoot)
+}
+
+// runInfrastructureTask executes a task in the specified infrastructure Taskfile
+func runInfrastructureTask(ctx context.Context, infra, task string) error {
+	if err := k8s.GuardMutation("task " + task); err != nil {
+		return err
+	}
+
+	// Find project root to work from both repo root and bootstrap directory
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	projectRoot := findProjectRoot(wd)
+	if projectRoot == "" {
+		return fmt.Errorf("project root not found - ensure you're running from within the homelab project")
+	}
+
+	// Determine the infrastructure directory relative to project root
+	infrastructureDir := filepath.Join(projectRoot, "infrastructure", infra)
+
+	// Check if the Taskfile exists
+	taskfilePath := filepath.Join(infrastructureDir, "Taskfile.yml")
+	if _, err := os.Stat(taskfilePath); os.IsNotExist(err) {
+		return fmt.Errorf("infrastructure Taskfile not found: %s", taskfilePath)
+	}
+
+	// Execute the task using the task command
+	cmd := exec.CommandContext(ctx, "task", "-d", infrastructureDir, task)
+
+	// Use output manager to respect TUI mode
+	outputMgr := output.GetManager()
+	cmd.Stdout = outputMgr.GetStdout()
+	cmd.Stderr = outputMgr.GetStderr()
+	cmd.Stdin = os.Stdin
+
+	log.Debug("Executing infrastructure task", "infra", infra, "task", task, "dir", infrastructureDir, "projectRoot", projectRoot)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("infrastructure task failed: %w", err)
+	}
+
+	return nil
+}
+
+// findProjectRoot finds the project root directory by looking for common project files
+func findProjectRoot(startDir string) string {
+	current := startDir
+	var lastMatch string
+	for {
+		// Check for project indicators
+		indicators := []string{".git", "go.mod", "bootstrap", "Taskfile.yml"}
+		for _, indicator := range indicators {
+			if _, err := os.Stat(filepath.Join(current, indicator)); err == nil {
+				if indicator == ".git" {
+					return current
+				}
+				lastMatch = current
+			}
+		}
+
+		// Move up one directory
+		parent := filepath.Dir(current)
+		if parent == current {
+			// Reached filesystem root
+			break
+		}
+		current = parent
+	}
+
+	return lastMatch
+}
+
+func ensureHomelabKubeconfig(ctx context.Context, cfg *config.Config) error {
+	dest := cfg.Homelab.Cluster.KubeConfig
+	if dest == "" {
+		return fmt.Errorf("homelab kubeconfig path not configured")
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+
+	if err := syncHomelabKubeconfigFile(dest); err == nil {
+		if _, err := os.Stat(dest); err == nil {
+			log.Info("✨ Synced existing kubeconfig", "path", dest)
+			return nil
+		}
+	}
+
+	log.Info("Homelab kubeconfig missing, provisioning infrastructure with 'homelab up'")
+	if err := runUp(ctx, false, true); err != nil {
+		return fmt.Errorf("failed to provision infrastructure: %w", err)
+	}
+
+	if _, err := os.Stat(dest); err != nil {
+		if err := syncHomelabKubeconfigFile(dest); err != nil {
+			return fmt.Errorf("failed to sync kubeconfig after provisioning: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func ensureHomelabCilium(ctx context.Context, cfg *config.Config) error {
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+
+	if err := client.IsReady(ctx); err != nil {
+		return fmt.Errorf("cluster not ready: %w", err)
+	}
+
+	ds, err := client.GetClientset().AppsV1().DaemonSets("kube-system").Get(ctx, "cilium", metav1.GetOptions{})
+	if err == nil && ds != nil && ds.Status.NumberReady > 0 {
+		log.Info("✅ Cilium already installed", "ready", ds.Status.NumberReady)
+		return nil
+	}
+
+	log.Info("🌐 Cilium not detected, installing CNI")
+	if err := runInstallCilium(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// syncHomelabKubeconfigFile copies the kubeconfig Talos generated to dest, the configured or overridden path
+func syncHomelabKubeconfigFile(dest string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	projectRoot := findProjectRoot(wd)
+	if projectRoot == "" {
+		return fmt.Errorf("project root not found - ensure you're running from within the homelab project")
+	}
+
+	sourceCandidates := []string{
+		filepath.Join(projectRoot, "kubeconfig"),
+		filepath.Join(projectRoot, "infrastructure", "homelab", "kubeconfig"),
+	}
+
+	for _, src := range sourceCandidates {
+		if _, err := os.Stat(src); err == nil {
+			if err := copyKubeconfig(src, dest); err != nil {
+				return err
+			}
+			log.Info("🔄 Synced kubeconfig", "from", src, "to", dest)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("generated kubeconfig not found; looked for %s", sourceCandidates)
+}
+
+func copyKubeconfig(src, dest string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig %s: %w", src, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), fs.FileMode(0o755)); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+
+	if err := os.WriteFile(dest, data, fs.FileMode(0o600)); err != nil {
+		return fmt.Errorf("failed to write kubeconfig to %s: %w", dest, err)
+	}
+
+	return nil
+}
+
+func runInstallCilium(ctx context.Context) error {
+	log.Info("🌐 Installing Cilium CNI")
+
+	// Load configuration
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	// Connect to cluster
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+
+	// Check if cluster is accessible
+	if err := client.IsReady(ctx); err != nil {
+		return fmt.Errorf("cluster not ready: %w", err)
+	}
+
+	// Create Cilium installer
+	ciliumInstaller := infra.NewCiliumInstaller(client)
+
+	// Configure Cilium
+	ciliumConfig := infra.CiliumConfig{
+		ClusterPodCIDR: "10.244.0.0/16", // Default pod CIDR
+		Hubble:         true,            // Enable Hubble observability
+		LoadBalancer:   cfg.Homelab.LoadBalancer.Provider == infra.LoadBalancerCilium,
+		Version:        cfg.Homelab.Cilium.Version,
+		Values:         cfg.Homelab.Cilium.Values,
+	}
+
+	// Override with config values if available
+	if cfg.Homelab.Infrastructure != nil && cfg.Homelab.Infrastructure.PodCIDR != "" {
+		ciliumConfig.ClusterPodCIDR = cfg.Homelab.Infrastructure.PodCIDR
+	}
+
+	// Install Cilium
+	if err := ciliumInstaller.Install(ctx, ciliumConfig); err != nil {
+		return fmt.Errorf("failed to install Cilium: %w", err)
+	}
+
+	log.Info("✅ Cilium CNI installation completed")
+	return nil
+}
+
+func runSyncSecrets(ctx context.Context) error {
+	log.Info("🔐 Syncing environment secrets")
+
+	// Load configuration
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	// Connect to cluster
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+
+	// Check if cluster is accessible
+	if err := client.IsReady(ctx); err != nil {
+		return fmt.Errorf("cluster not ready: %w", err)
+	}
+
+	// Find project root for secrets manager
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	projectRoot := findProjectRoot(wd)
+	if projectRoot == "" {
+		return fmt.Errorf("project root not found - ensure you're running from within the homelab project")
+	}
+
+	// Create secrets manager
+	secretsManager := secrets.NewManager(client, projectRoot)
+
+	// Create cluster-vars secret
+	log.Info("Creating cluster-vars secret from .env")
+	if err := secretsManager.CreateClusterVarsSecret(ctx, "flux-system"); err != nil {
+		return fmt.Errorf("failed to create cluster-vars secret: %w", err)
+	}
+
+	// Setup cross-cluster connectivity
+	log.Info("Setting up cross-cluster connectivity with NAS")
+	// TODO: Implement cross-cluster secret creation in Go
+	log.Warn("Cross-cluster secret creation not yet implemented in Go")
+	log.Info("Please run: ./bootstrap/scripts/homelab/ensure-nas-remote-secret.sh")
+
+	log.Info("✅ Environment secrets synced successfully")
+	return nil
+}
+
+func runSync(ctx context.Context, dryRun bool) error {
+	log.Info("🔄 Detecting config changes to sync")
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	plan, err := orchestrator.PlanSync(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to plan sync: %w", err)
+	}
+	if plan.Empty() {
+		log.Info("✅ Cluster already matches the local configuration")
+		return nil
+	}
+
+	for _, change := range plan.Changes {
+		log.Info("Pending change", "artifact", change.Artifact, "change", change.Detail)
+	}
+	if dryRun {
+		log.Info("Dry run, nothing applied", "changes", len(plan.Changes))
+		return nil
+	}
+
+	if err := orchestrator.ApplySync(ctx, plan); err != nil {
+		return err
+	}
+	log.Info("✅ Config changes synced")
+	return nil
+}
+
+func runAdopt(ctx context.Context, dryRun bool) error {
+	log.Info("🔎 Detecting existing installations to adopt")
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	report, err := orchestrator.Adopt(ctx, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to adopt the cluster: %w", err)
+	}
+	if output.Structured() {
+		return output.Print(report)
 	}
 
-	for _, src := range sourceCandidates {
-		if _, err := os.Stat(src); err == nil {
-			if err := copyKubeconfig(src, dest); err != nil {
-				return err
-			}
-			log.Info("🔄 Synced kubeconfig", "from", src, "to", dest)
-			return nil
+	if report.Flux.Version != "" {
+		log.Info("Flux", "version", report.Flux.Version)
+		for _, set := range slices.Sorted(maps.Keys(report.Flux.Sets)) {
+			result := report.Flux.Sets[set]
+			log.Info("  • "+set, "adopted", len(result.Adopted), "fields_transferred", len(result.Transferred), "missing", len(result.Missing))
 		}
 	}
-
-	return fmt.Errorf("generated kubeconfig not found; looked for %s", sourceCandidates)
+	if report.Cilium != nil {
+		log.Info("Cilium", "version", report.Cilium.Version, "chart", report.Cilium.Chart)
+	}
+	created := "Missing piece created"
+	if dryRun {
+		created = "Missing piece to create"
+	}
+	for _, piece := range report.Reconciled {
+		log.Info(created, "piece", piece)
+	}
+	for _, warning := range report.Warnings {
+		log.Warn(warning)
+	}
+	if dryRun {
+		log.Info("Dry run, nothing adopted", "missing_pieces", len(report.Reconciled))
+		return nil
+	}
+	log.Info("✅ Cluster adopted", "cluster", report.Cluster)
+	return nil
 }
 
-func copyKubeconfig(src, dest string) error {
-	data, err := os.ReadFile(src)
+func runMigrateESO(ctx context.Context, dryRun bool) error {
+	log.Info("🔐 Migrating cluster-vars to External Secrets")
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to read kubeconfig %s: %w", src, err)
+		return err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(dest), fs.FileMode(0o755)); err != nil {
-		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	keys, err := orchestrator.MigrateClusterVarsToESO(ctx, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to migrate cluster-vars: %w", err)
+	}
+	for _, key := range keys {
+		log.Info("  • " + key)
+	}
+	if dryRun {
+		log.Info("Dry run, nothing migrated", "keys", len(keys))
+		return nil
 	}
+	log.Info("✅ cluster-vars now synced from Vault", "keys", len(keys))
+	return nil
+}
 
-	if err := os.WriteFile(dest, data, fs.FileMode(0o600)); err != nil {
-		return fmt.Errorf("failed to write kubeconfig to %s: %w", dest, err)
+func runVaultPKISetup(ctx context.Context, force bool) error {
+	log.Info("🔐 Setting up the homelab Vault PKI")
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, "homelab")
+	if err != nil {
+		return err
 	}
 
+	result, err := orchestrator.SetupVaultPKI(ctx, bootstrap.VaultPKIOptions{Force: force})
+	if err != nil {
+		return fmt.Errorf("failed to set up the Vault PKI: %w", err)
+	}
+	if output.Structured() {
+		return output.Print(result)
+	}
+	log.Info("✅ Intermediate CA ready", "mount", result.Mount, "signed", result.Signed, "expires", result.NotAfter.Format(time.RFC3339), "roles", strings.Join(result.Roles, ","))
 	return nil
 }
 
-func kubeconfigFor(cluster string) string {
-	return filepath.Join("infrastructure", cluster, "kubeconfig.yaml")
-}
+func runRotateTransitToken(ctx context.Context) error {
+	log.Info("🔑 Rotating the Vault transit token")
 
-func runInstallCilium(ctx context.Context) error {
-	log.Info("🌐 Installing Cilium CNI")
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, "homelab")
+	if err != nil {
+		return err
+	}
 
-	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	result, err := orchestrator.RotateTransitToken(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return fmt.Errorf("failed to rotate the transit token: %w", err)
+	}
+	if output.Structured() {
+		return output.Print(result)
+	}
+	log.Info("✅ Transit token rotated", "accessor", result.Accessor, "seal", result.SealType, "restarted", strings.Join(result.Restarted, ","))
+	if !result.OldRevoked {
+		log.Warn("The previous transit token was not revoked", "accessor", result.OldAccessor)
 	}
+	return nil
+}
 
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+func runNodesUpgrade(ctx context.Context, image string, nodes []string, all bool, serial int, talosconfig string) error {
+	if all == (len(nodes) > 0) {
+		return fmt.Errorf("select nodes with either --node or --all")
 	}
 
-	// Connect to cluster
-	client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	opts, err := talos.OptionsFromConfig(cfg.Homelab, findProjectRoot(wd))
+	if err != nil {
+		return err
+	}
+	if image == "" {
+		image = opts.InstallImage
+	}
+	if talosconfig == "" {
+		talosconfig = opts.TalosconfigPath()
+	}
+	if all {
+		for _, node := range opts.ControlPlanes() {
+			nodes = append(nodes, node.Address)
+		}
+		for _, node := range opts.Nodes {
+			if node.Role != talos.RoleControlPlane {
+				nodes = append(nodes, node.Address)
+			}
+		}
+	}
+
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
 	if err != nil {
 		return fmt.Errorf("failed to connect to cluster: %w", err)
 	}
 
-	// Check if cluster is accessible
-	if err := client.IsReady(ctx); err != nil {
-		return fmt.Errorf("cluster not ready: %w", err)
+	log.Info("⬆️ Upgrading Talos nodes", "nodes", nodes, "image", image, "serial", serial)
+	upgrade := talos.UpgradeOptions{Image: image, Nodes: nodes, Serial: serial}
+	if err := talos.NewUpgrader(client, talosconfig).Upgrade(ctx, upgrade); err != nil {
+		return fmt.Errorf("node upgrade failed: %w", err)
 	}
+	log.Info("✅ All selected nodes upgraded", "nodes", len(nodes))
+	return nil
+}
 
-	// Create Cilium installer
-	ciliumInstaller := infra.NewCiliumInstaller(client)
+// cephClient connects to the homelab cluster and returns its Ceph client
+func cephClient(ctx context.Context) (*ceph.Client, error) {
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return nil, err
+	}
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+	return ceph.NewClient(client), nil
+}
 
-	// Configure Cilium
-	ciliumConfig := infra.CiliumConfig{
-		ClusterPodCIDR: "10.244.0.0/16", // Default pod CIDR
-		Hubble:         true,            // Enable Hubble observability
-		LoadBalancer:   false,           // Use with MetalLB instead
+func runCephStatus(ctx context.Context) error {
+	client, err := cephClient(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := client.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if output.Structured() {
+		return output.Print(status)
 	}
 
-	// Override with config values if available
-	if cfg.Homelab.Infrastructure != nil && cfg.Homelab.Infrastructure.PodCIDR != "" {
-		ciliumConfig.ClusterPodCIDR = cfg.Homelab.Infrastructure.PodCIDR
+	icon := "✅"
+	switch status.Health {
+	case ceph.HealthWarn:
+		icon = "⚠️"
+	case ceph.HealthErr:
+		icon = "❌"
+	}
+	log.Info(icon+" Ceph "+status.Health, "source", status.Source)
+	if status.Source == "toolbox" {
+		log.Info("💾 OSDs", "up", status.OSDsUp, "in", status.OSDsIn, "total", status.OSDs)
+		states := make([]string, 0, len(status.PGStates))
+		for state, count := range status.PGStates {
+			states = append(states, fmt.Sprintf("%d %s", count, state))
+		}
+		sort.Strings(states)
+		log.Info("📦 Placement groups", "total", status.PGs, "states", strings.Join(states, ", "))
+	}
+	if status.BytesTotal > 0 {
+		log.Info("📊 Capacity", "used", fmt.Sprintf("%.1f%%", status.UsedRatio()*100),
+			"used_gib", status.BytesUsed>>30, "total_gib", status.BytesTotal>>30)
 	}
+	if status.InMaintenance() {
+		log.Warn("🔧 Maintenance flags set, run 'bootstrap homelab ceph unset-maintenance' once the nodes are back")
+	}
+	for _, check := range status.Checks {
+		log.Warn("Health check", "check", check.Name, "severity", check.Severity, "message", check.Message)
+	}
+	return nil
+}
 
-	// Install Cilium
-	if err := ciliumInstaller.Install(ctx, ciliumConfig); err != nil {
-		return fmt.Errorf("failed to install Cilium: %w", err)
+func runCephMaintenance(ctx context.Context, enable bool) error {
+	client, err := cephClient(ctx)
+	if err != nil {
+		return err
+	}
+	if enable {
+		if err := client.SetMaintenance(ctx); err != nil {
+			return err
+		}
+		log.Info("✅ Ceph in maintenance, OSDs stopped by node reboots are kept in", "flags", strings.Join(ceph.MaintenanceFlags, ","))
+		return nil
 	}
+	if err := client.UnsetMaintenance(ctx); err != nil {
+		return err
+	}
+	log.Info("✅ Ceph maintenance ended, recovery resumes", "flags", strings.Join(ceph.MaintenanceFlags, ","))
+	return nil
+}
 
-	log.Info("✅ Cilium CNI installation completed")
+func hubbleClient(ctx context.Context) (*hubble.Client, error) {
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
+	if err != nil {
+		return nil, err
+	}
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+	return hubble.NewClient(client), nil
+}
+
+func runHubbleFlows(ctx context.Context, opts hubble.FlowOptions) error {
+	client, err := hubbleClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	var flows []hubble.Flow
+	err = client.Flows(ctx, opts, func(flow hubble.Flow) {
+		switch {
+		case output.Structured() && opts.Follow:
+			if err := output.Print(flow); err != nil {
+				log.Warn("Failed to print flow", "error", err)
+			}
+		case output.Structured():
+			flows = append(flows, flow)
+		default:
+			fmt.Println(formatFlow(flow))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if output.Structured() && !opts.Follow {
+		if flows == nil {
+			flows = []hubble.Flow{}
+		}
+		return output.Print(flows)
+	}
 	return nil
 }
 
-func runSyncSecrets(ctx context.Context) error {
-	log.Info("🔐 Syncing environment secrets")
+// formatFlow prints a flow on one line, like hubble observe
+func formatFlow(flow hubble.Flow) string {
+	line := fmt.Sprintf("%s %s %s -> %s", flow.Time.Local().Format("Jan 02 15:04:05.000"), flow.Node, flow.Source, flow.Destination)
+	switch flow.Kind {
+	case hubble.KindDrop:
+		line += fmt.Sprintf(" %s ❌ DROPPED (%s)", flow.Protocol, flow.DropReason)
+	case hubble.KindPolicy:
+		line += fmt.Sprintf(" %s 🛡️ policy-verdict %s %s", flow.Protocol, flow.Direction, flow.Verdict)
+	case hubble.KindDNS:
+		if flow.DNS.Response {
+			line += fmt.Sprintf(" 🔎 DNS answer %s %s %s", flow.DNS.Query, flow.DNS.RCode, strings.Join(flow.DNS.IPs, ","))
+		} else {
+			line += fmt.Sprintf(" 🔎 DNS query %s %s", flow.DNS.Query, strings.Join(flow.DNS.Types, ","))
+		}
+	default:
+		line += fmt.Sprintf(" %s %s", flow.Protocol, flow.Verdict)
+	}
+	return line
+}
 
-	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+func runHubbleStatus(ctx context.Context) error {
+	client, err := hubbleClient(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := client.Status(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
+	}
+	if output.Structured() {
+		return output.Print(status)
 	}
 
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+	icon := "✅"
+	if !status.Healthy() {
+		icon = "⚠️"
+	}
+	log.Info(icon+" Hubble relay", "version", status.Version, "connected_nodes", status.ConnectedNodes,
+		"flows", fmt.Sprintf("%d/%d", status.NumFlows, status.MaxFlows), "rate", fmt.Sprintf("%.1f/s", status.FlowsRate))
+	for _, node := range status.Nodes {
+		if node.State == "connected" {
+			log.Info("🖥️ "+node.Name, "version", node.Version, "flows", fmt.Sprintf("%d/%d", node.NumFlows, node.MaxFlows),
+				"uptime", node.Uptime.Round(time.Second))
+		} else {
+			log.Warn("🖥️ "+node.Name, "state", node.State, "address", node.Address)
+		}
+	}
+	for _, node := range status.UnavailableNodes {
+		log.Warn("Node unavailable", "node", node)
 	}
+	return nil
+}
 
-	// Connect to cluster
-	client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+// proxmoxVMs connects to the Proxmox API and returns the cluster VMs named, all of them when names is empty
+func proxmoxVMs(ctx context.Context, names []string) (*proxmox.Client, []proxmox.VM, error) {
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to connect to cluster: %w", err)
+		return nil, nil, err
+	}
+	client, err := proxmox.NewClientFromConfig(cfg.Homelab.Proxmox)
+	if err != nil {
+		return nil, nil, err
 	}
+	vms, err := client.ClusterVMs(ctx, cfg.Homelab, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, vms, nil
+}
 
-	// Check if cluster is accessible
-	if err := client.IsReady(ctx); err != nil {
-		return fmt.Errorf("cluster not ready: %w", err)
+// vmListEntry is a cluster VM with its latest snapshot
+type vmListEntry struct {
+	proxmox.VM
+	LatestSnapshot string `json:"latestSnapshot,omitempty"`
+}
+
+func runVMList(ctx context.Context) error {
+	client, vms, err := proxmoxVMs(ctx, nil)
+	if err != nil {
+		return err
+	}
+	entries := make([]vmListEntry, 0, len(vms))
+	for _, vm := range vms {
+		entry := vmListEntry{VM: vm}
+		snapshots, err := client.Snapshots(ctx, vm)
+		if err != nil {
+			log.Warn("Failed to list snapshots", "vm", vm.Name, "error", err)
+		} else if len(snapshots) > 0 {
+			entry.LatestSnapshot = snapshots[len(snapshots)-1].Name
+		}
+		entries = append(entries, entry)
+	}
+	if output.Structured() {
+		return output.Print(entries)
 	}
 
-	// Find project root for secrets manager
-	wd, err := os.Getwd()
+	for _, entry := range entries {
+		icon := "🟢"
+		if entry.Status != proxmox.StatusRunning {
+			icon = "🔴"
+		}
+		fields := []any{"vmid", entry.ID, "node", entry.Node, "status", entry.Status}
+		if entry.Status == proxmox.StatusRunning {
+			fields = append(fields, "uptime", (time.Duration(entry.Uptime) * time.Second).String(),
+				"cpu", fmt.Sprintf("%.0f%%", entry.CPU*100), "memory", fmt.Sprintf("%d/%d MiB", entry.Mem>>20, entry.MaxMem>>20))
+		}
+		if entry.LatestSnapshot != "" {
+			fields = append(fields, "snapshot", entry.LatestSnapshot)
+		}
+		log.Info(icon+" "+entry.Name, fields...)
+	}
+	return nil
+}
+
+func runVMStart(ctx context.Context, names []string) error {
+	client, vms, err := proxmoxVMs(ctx, names)
 	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
+		return err
+	}
+	for _, vm := range vms {
+		if vm.Status == proxmox.StatusRunning {
+			log.Info("VM already running", "vm", vm.Name)
+			continue
+		}
+		if err := k8s.GuardMutation("proxmox start " + vm.Name); err != nil {
+			return err
+		}
 	}
+	started, err := client.StartStopped(ctx, vms)
+	if err != nil {
+		return err
+	}
+	log.Info("✅ VMs started", "count", len(started))
+	return nil
+}
 
-	projectRoot := findProjectRoot(wd)
-	if projectRoot == "" {
-		return fmt.Errorf("project root not found - ensure you're running from within the homelab project")
+func runVMStop(ctx context.Context, names []string, force bool, timeout time.Duration) error {
+	client, vms, err := proxmoxVMs(ctx, names)
+	if err != nil {
+		return err
+	}
+	for _, vm := range vms {
+		if vm.Status == proxmox.StatusStopped {
+			log.Info("VM already stopped", "vm", vm.Name)
+			continue
+		}
+		if err := k8s.GuardMutation("proxmox stop " + vm.Name); err != nil {
+			return err
+		}
+		if force {
+			log.Info("⏹️ Stopping VM "+vm.Name, "vmid", vm.ID)
+			err = client.Stop(ctx, vm)
+		} else {
+			log.Info("⏹️ Shutting down VM "+vm.Name, "vmid", vm.ID, "timeout", timeout)
+			err = client.Shutdown(ctx, vm, timeout)
+		}
+		if err != nil {
+			return err
+		}
 	}
+	log.Info("✅ VMs stopped")
+	return nil
+}
 
-	// Create secrets manager
-	secretsManager := secrets.NewManager(client, projectRoot)
+func runVMSnapshot(ctx context.Context, names []string, name, description string) error {
+	client, vms, err := proxmoxVMs(ctx, names)
+	if err != nil {
+		return err
+	}
+	for _, vm := range vms {
+		if err := k8s.GuardMutation("proxmox snapshot " + vm.Name); err != nil {
+			return err
+		}
+		log.Info("📸 Snapshotting VM "+vm.Name, "vmid", vm.ID, "snapshot", name)
+		if err := client.Snapshot(ctx, vm, name, description); err != nil {
+			return err
+		}
+	}
+	log.Info("✅ Snapshot taken", "snapshot", name, "vms", len(vms))
+	return nil
+}
 
-	// Create cluster-vars secret
-	log.Info("Creating cluster-vars secret from .env")
-	if err := secretsManager.CreateClusterVarsSecret(ctx, "flux-system"); err != nil {
-		return fmt.Errorf("failed to create cluster-vars secret: %w", err)
+func runVMRollback(ctx context.Context, snapshot string, names []string) error {
+	client, vms, err := proxmoxVMs(ctx, names)
+	if err != nil {
+		return err
+	}
+	// Check every VM has the snapshot before rolling any back, so the cluster is not left half restored
+	for _, vm := range vms {
+		snapshots, err := client.Snapshots(ctx, vm)
+		if err != nil {
+			return err
+		}
+		found := false
+		var available []string
+		for _, s := range snapshots {
+			found = found || s.Name == snapshot
+			available = append(available, s.Name)
+		}
+		if !found {
+			return fmt.Errorf("VM %s has no snapshot %s (available: %v)", vm.Name, snapshot, available)
+		}
 	}
 
-	// Setup cross-cluster connectivity
-	log.Info("Setting up cross-cluster connectivity with NAS")
-	// TODO: Implement cross-cluster secret creation in Go
-	log.Warn("Cross-cluster secret creation not yet implemented in Go")
-	log.Info("Please run: ./bootstrap/scripts/homelab/ensure-nas-remote-secret.sh")
+	ids := make([]string, 0, len(vms))
+	for _, vm := range vms {
+		if err := k8s.GuardMutation("proxmox rollback " + vm.Name); err != nil {
+			return err
+		}
+		log.Info("⏪ Rolling back VM "+vm.Name, "vmid", vm.ID, "snapshot", snapshot)
+		if err := client.Rollback(ctx, vm, snapshot); err != nil {
+			return err
+		}
+		ids = append(ids, strconv.Itoa(vm.ID))
+	}
 
-	log.Info("✅ Environment secrets synced successfully")
+	// A rollback to a disk-only snapshot leaves the VM stopped, one saving the RAM resumes it
+	all, err := client.VMs(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := proxmox.Find(all, ids)
+	if err != nil {
+		return err
+	}
+	if _, err := client.StartStopped(ctx, current); err != nil {
+		return err
+	}
+	log.Info("✅ VMs rolled back", "snapshot", snapshot, "vms", len(vms))
 	return nil
 }
 
@@ -720,18 +2106,13 @@ func runSuspend(ctx context.Context) error {
 	log.Info("⏸️ Suspending Flux reconciliation")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+		return err
 	}
 
 	// Connect to cluster
-	client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
 	if err != nil {
 		return fmt.Errorf("failed to connect to cluster: %w", err)
 	}
@@ -757,18 +2138,13 @@ func runResume(ctx context.Context) error {
 	log.Info("▶️ Resuming Flux reconciliation")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+		return err
 	}
 
 	// Connect to cluster
-	client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
 	if err != nil {
 		return fmt.Errorf("failed to connect to cluster: %w", err)
 	}
@@ -798,54 +2174,72 @@ func runStatus(ctx context.Context) error {
 	log.Info("🔍 Checking homelab status")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("homelab")
+	cfg, err := cmdutil.LoadConfig(ctx, "homelab")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	if cfg.Homelab == nil {
-		return fmt.Errorf("homelab configuration not found")
+	report := &cmdutil.StatusReport{Cluster: "homelab"}
+	printReport := func() error {
+		if output.Structured() {
+			return output.Print(report)
+		}
+		return nil
 	}
 
 	// Try to connect to cluster
-	client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+	client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
 	if err != nil {
 		log.Error("❌ Cannot connect to cluster", "error", err)
+		report.Errors = append(report.Errors, err.Error())
+		if printErr := printReport(); printErr != nil {
+			return printErr
+		}
 		return fmt.Errorf("failed to connect to cluster: %w", err)
 	}
 
 	// Check if cluster is accessible
 	if err := client.IsReady(ctx); err != nil {
 		log.Error("❌ Cluster API not ready", "error", err)
+		report.Errors = append(report.Errors, err.Error())
+		if printErr := printReport(); printErr != nil {
+			return printErr
+		}
 		return fmt.Errorf("cluster not ready: %w", err)
 	}
 
 	log.Info("✅ Cluster API is accessible")
+	report.APIReady = true
 
 	// Check nodes
 	nodes, err := client.GetNodes(ctx)
 	if err != nil {
 		log.Error("❌ Failed to get nodes", "error", err)
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to get nodes: %v", err))
 	} else {
 		log.Info("📋 Nodes", "count", len(nodes), "nodes", nodes)
+		report.Nodes = nodes
 	}
 
 	// Check FluxCD
 	exists, err := client.NamespaceExists(ctx, "flux-system")
 	if err != nil {
 		log.Error("❌ Failed to check flux-system namespace", "error", err)
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to check flux-system namespace: %v", err))
 	} else if !exists {
 		log.Warn("⚠️ FluxCD is not installed (flux-system namespace missing)")
 	} else {
 		log.Info("✅ FluxCD namespace exists")
+		report.FluxInstalled = true
 
 		// Check Flux status
 		fluxClient := flux.NewClient(client, &cfg.Homelab.GitOps)
 		status, err := fluxClient.GetSyncStatus(ctx, "flux-system")
 		if err != nil {
 			log.Error("❌ Failed to get Flux status", "error", err)
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to get Flux status: %v", err))
 		} else {
+			report.Flux = status
 			if status.Ready {
 				log.Info("✅ FluxCD is synced and ready")
 			} else {
@@ -858,13 +2252,18 @@ func runStatus(ctx context.Context) error {
 	diagnosticManager, err := recovery.NewDiagnosticManager(cfg, false)
 	if err != nil {
 		log.Warn("Failed to create diagnostic manager", "error", err)
-		return nil
+		return printReport()
 	}
 
 	results, err := diagnosticManager.DiagnoseSystem(ctx)
 	if err != nil {
 		log.Warn("Failed to run diagnostics", "error", err)
-		return nil
+		return printReport()
+	}
+	report.Diagnostics = results
+
+	if output.Structured() {
+		return printReport()
 	}
 
 	// Print detailed diagnostics
@@ -0,0 +1,311 @@
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/internal/cmdutil"
+	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// NewStatusCommand creates the mesh status command
+func NewStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show service mesh status",
+		Long:  "Summarize istiod, ztunnel, east-west gateway, CA, remote secret and namespace enrollment state for both clusters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(cmd.Context())
+		},
+	}
+
+	return cmd
+}
+
+// NewSyncCommand creates the mesh sync command
+func NewSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Rotate remote secrets before their tokens expire",
+		Long:  "Reissue cross-cluster remote secrets when missing or close to expiry, optionally watching continuously and applying config and .env changes without restarting",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			watch, _ := cmd.Flags().GetBool("watch")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			force, _ := cmd.Flags().GetBool("force")
+			return runSync(cmd.Context(), watch, interval, force)
+		},
+	}
+
+	cmd.Flags().Bool("watch", false, "Keep running and rotate secrets before expiry")
+	cmd.Flags().Duration("interval", time.Hour, "Check interval when watching")
+	cmd.Flags().Bool("force", false, "Rotate even if tokens are not close to expiry")
+	return cmd
+}
+
+// NewRotateCACommand creates the mesh rotate-ca command
+func NewRotateCACommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-ca",
+		Short: "Rotate the Istio root CA across every mesh cluster",
+		Long: `Replace the shared Istio root CA without interrupting mTLS. Every cluster first trusts both roots,
+then signs with the new intermediate, then drops the previous root. Each phase restarts istiod and the
+mesh workloads in waves and checks every proxy is in sync before moving on.
+
+The new CA is generated and saved to CACERTS_DIR, or loaded from it with --from-dir. Rerunning with
+--from-dir resumes an interrupted rotation.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromDir, _ := cmd.Flags().GetBool("from-dir")
+			waveSize, _ := cmd.Flags().GetInt("wave-size")
+			return runRotateCA(cmd.Context(), bootstrap.CARotationOptions{FromDir: fromDir, WaveSize: waveSize})
+		},
+	}
+
+	cmd.Flags().Bool("from-dir", false, "Load the new root and intermediate from CACERTS_DIR instead of generating them")
+	cmd.Flags().Int("wave-size", 5, "Workloads of a namespace restarted at once")
+	return cmd
+}
+
+// NewRenewGatewayCertCommand creates the mesh renew-gateway-cert command
+func NewRenewGatewayCertCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "renew-gateway-cert",
+		Short: "Renew the east-west gateway certificate on every mesh cluster",
+		Long:  "Regenerate the east-west gateway TLS certificate, store it in .env.generated, apply it on both clusters and restart the gateways",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRenewGatewayCert(cmd.Context())
+		},
+	}
+}
+
+// NewSmokeCommand creates the mesh smoke command
+func NewSmokeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "smoke",
+		Short: "Send traffic across the mesh in both directions",
+		Long: `Deploy a short-lived echo server on each cluster and a curl client on its peer, then check every
+direction answers through the east-west gateway with the expected mTLS identities and report its latency`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keep, _ := cmd.Flags().GetBool("keep")
+			return runSmoke(cmd.Context(), keep)
+		},
+	}
+
+	cmd.Flags().Bool("keep", false, "Leave the mesh-smoke namespace in place for debugging")
+	return cmd
+}
+
+func runStatus(ctx context.Context) error {
+	cluster, err := cmdutil.ResolveCluster(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	log.Info("🕸️ Checking service mesh status", "cluster", cluster)
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	report := orchestrator.MeshStatusReport(ctx)
+	for _, c := range report.Clusters {
+		printClusterStatus(c)
+	}
+
+	if report.CAMatch {
+		log.Info("✅ Root CA fingerprints match across clusters")
+	} else {
+		log.Error("❌ Root CA fingerprints differ or are missing")
+	}
+
+	return nil
+}
+
+func printClusterStatus(c bootstrap.ClusterMeshReport) {
+	log.Info(fmt.Sprintf("📋 Cluster %s", c.Cluster))
+	if c.Error != "" {
+		log.Error("❌ Cluster unavailable", "cluster", c.Cluster, "error", c.Error)
+		printRemoteSecret(c.RemoteSecret)
+		return
+	}
+
+	if c.IstiodReady {
+		log.Info("✅ istiod ready", "version", c.IstiodVersion, "replicas", c.IstiodReplicas)
+	} else if c.IstiodReplicas == "" {
+		log.Error("❌ istiod not installed")
+	} else {
+		log.Warn("⚠️ istiod not ready", "version", c.IstiodVersion, "replicas", c.IstiodReplicas)
+	}
+
+	switch {
+	case c.ZtunnelDesired == 0:
+		log.Info("ℹ️ ztunnel not deployed (sidecar mode)")
+	case c.ZtunnelReady < int32(c.Nodes):
+		log.Warn("⚠️ ztunnel coverage incomplete", "ready", c.ZtunnelReady, "nodes", c.Nodes)
+	default:
+		log.Info("✅ ztunnel covers all nodes", "ready", c.ZtunnelReady, "nodes", c.Nodes)
+	}
+
+	switch {
+	case c.GatewayEndpoint == "":
+		log.Error("❌ East-west gateway has no endpoint")
+	case c.GatewayReachable:
+		log.Info("✅ East-west gateway reachable", "endpoint", c.GatewayEndpoint, "probed_from", c.GatewayProbe)
+	case c.GatewayProbe == "":
+		log.Warn("⚠️ East-west gateway reachability not checked", "endpoint", c.GatewayEndpoint)
+	default:
+		log.Error("❌ East-west gateway unreachable", "endpoint", c.GatewayEndpoint, "probed_from", c.GatewayProbe)
+	}
+
+	if c.CAFingerprint == "" {
+		log.Warn("⚠️ No cacerts root certificate found")
+	} else {
+		log.Info("🔑 Root CA", "fingerprint", c.CAFingerprint[:16])
+	}
+
+	printRemoteSecret(c.RemoteSecret)
+
+	log.Info("📦 Enrolled namespaces", "count", len(c.EnrolledNamespaces), "namespaces", c.EnrolledNamespaces)
+}
+
+func printRemoteSecret(info bootstrap.RemoteSecretInfo) {
+	if info.Name == "" {
+		return
+	}
+	switch {
+	case info.Error != "":
+		log.Warn("⚠️ Remote secret unavailable", "secret", info.Name, "installed_in", info.InstalledIn, "error", info.Error)
+	case !info.Present:
+		log.Error("❌ Remote secret missing", "secret", info.Name, "installed_in", info.InstalledIn)
+	case !info.Tracked:
+		log.Warn("⚠️ Remote secret has no token expiry (long-lived token)",
+			"secret", info.Name,
+			"installed_in", info.InstalledIn,
+			"age", info.Age().Round(time.Minute))
+	case info.Remaining() <= 0:
+		log.Error("❌ Remote secret token expired",
+			"secret", info.Name,
+			"installed_in", info.InstalledIn,
+			"expired_at", info.ExpiresAt.Format(time.RFC3339))
+	default:
+		log.Info("✅ Remote secret token valid",
+			"secret", info.Name,
+			"installed_in", info.InstalledIn,
+			"age", info.Age().Round(time.Minute),
+			"expires_in", info.Remaining().Round(time.Minute))
+	}
+}
+
+func runSync(ctx context.Context, watch bool, interval time.Duration, force bool) error {
+	cluster, err := cmdutil.ResolveCluster(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	log.Info("🔄 Syncing remote secrets", "cluster", cluster, "watch", watch)
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	if watch {
+		reloads := make(chan *config.Config, 1)
+		cmdutil.WatchConfig(ctx, cluster, func(cfg *config.Config) {
+			select {
+			case <-reloads:
+			default:
+			}
+			reloads <- cfg
+		})
+		return orchestrator.WatchRemoteSecrets(ctx, interval, reloads)
+	}
+
+	rotated, err := orchestrator.RotateRemoteSecrets(ctx, force)
+	if err != nil {
+		return err
+	}
+	if rotated {
+		log.Info("✅ Remote secrets rotated")
+	} else {
+		log.Info("✅ Remote secrets are fresh, nothing to rotate")
+	}
+	return nil
+}
+
+func runRotateCA(ctx context.Context, opts bootstrap.CARotationOptions) error {
+	cluster, err := cmdutil.ResolveCluster(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	log.Info("🔐 Rotating the Istio root CA", "cluster", cluster, "from_dir", opts.FromDir)
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if err := orchestrator.RotateCA(ctx, opts); err != nil {
+		return err
+	}
+	log.Info("✅ Root CA rotated")
+	return nil
+}
+
+func runRenewGatewayCert(ctx context.Context) error {
+	cluster, err := cmdutil.ResolveCluster(ctx, "homelab")
+	if err != nil {
+		return err
+	}
+
+	log.Info("🔏 Renewing the east-west gateway certificate", "cluster", cluster)
+
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if err := orchestrator.RenewGatewayCert(ctx); err != nil {
+		return err
+	}
+	log.Info("✅ East-west gateway certificate renewed")
+	return nil
+}
+
+func runSmoke(ctx context.Context, keep bool) error {
+	overrides, err := cmdutil.ClusterOverride(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info("💨 Running the cross-cluster mesh smoke test")
+	report, err := bootstrap.SmokeTestMesh(ctx, keep, overrides...)
+	if err != nil {
+		return err
+	}
+
+	if output.Structured() {
+		if err := output.Print(report); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range report.Results {
+			direction := r.From + " → " + r.To
+			if r.Passed {
+				log.Info("✅ "+direction, "latency_ms", fmt.Sprintf("%.1f", r.LatencyMillis),
+					"server", r.ServerIdentity, "client", r.ClientIdentity)
+			} else {
+				log.Error("❌ "+direction, "error", r.Error)
+			}
+		}
+	}
+
+	if !report.Passed {
+		return fmt.Errorf("mesh smoke test failed")
+	}
+	return nil
+}
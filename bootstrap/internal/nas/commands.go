@@ -3,19 +3,28 @@ package nas
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/internal/cmdutil"
 	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
 	"github.com/fredericrous/homelab/bootstrap/pkg/destroy"
+	"github.com/fredericrous/homelab/bootstrap/pkg/docker"
 	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k3s"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
 	"github.com/fredericrous/homelab/bootstrap/pkg/output"
 	"github.com/fredericrous/homelab/bootstrap/pkg/prereq"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
+	"github.com/fredericrous/homelab/bootstrap/pkg/talos"
 	"github.com/fredericrous/homelab/bootstrap/pkg/tui"
 	"github.com/spf13/cobra"
 )
@@ -26,13 +35,29 @@ func NewBootstrapCommand() *cobra.Command {
 		Use:   "bootstrap",
 		Short: "Bootstrap the NAS cluster",
 		Long:  "Bootstrap a new NAS cluster with K3s, MinIO, and FluxCD",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: cmdutil.Recorded("nas bootstrap", "nas", func(cmd *cobra.Command, args []string) error {
 			noTui, _ := cmd.Flags().GetBool("no-tui")
-			return runBootstrap(cmd.Context(), noTui)
-		},
+			resume, _ := cmd.Flags().GetBool("resume")
+			fromStep, _ := cmd.Flags().GetString("from-step")
+			renew, _ := cmd.Flags().GetString("auto-renew-before")
+			renewBefore, err := cmdutil.ParseDuration(renew)
+			if err != nil {
+				return fmt.Errorf("--auto-renew-before: %w", err)
+			}
+			reportPath, _ := cmd.Flags().GetString("report")
+			reportMarkdown, _ := cmd.Flags().GetBool("report-markdown")
+			report := bootstrap.ReportOptions{Path: reportPath, Markdown: reportMarkdown}
+			return runBootstrap(cmd.Context(), noTui, resume, fromStep, renewBefore, report)
+		}),
 	}
 
 	cmd.Flags().Bool("no-tui", false, "Disable interactive TUI mode")
+	cmd.Flags().Bool("resume", false, "Resume an interrupted bootstrap at the step it failed")
+	cmd.Flags().String("from-step", "", "Start the bootstrap at the named step, skipping the ones before it")
+	cmd.Flags().String("auto-renew-before", "30d", "Renew the east-west gateway certificate when it expires within this duration, 0 disables")
+	cmd.Flags().String("report", "", "Path of the JSON bootstrap report, defaults to .bootstrap/reports/<cluster>.json")
+	cmd.Flags().Bool("report-markdown", false, "Also write the bootstrap report as Markdown next to the JSON file")
+	cmd.MarkFlagsMutuallyExclusive("resume", "from-step")
 	return cmd
 }
 
@@ -56,9 +81,9 @@ func NewInstallCommand() *cobra.Command {
 		Use:   "install",
 		Short: "Install NAS infrastructure",
 		Long:  "Install and configure NAS infrastructure components",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: cmdutil.Recorded("nas install", "nas", func(cmd *cobra.Command, args []string) error {
 			return runInstall(cmd.Context())
-		},
+		}),
 	}
 
 	return cmd
@@ -83,25 +108,33 @@ func NewDestroyCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "destroy",
 		Short: "Destroy NAS cluster",
-		Long:  "Destroy the NAS cluster and clean up resources",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDestroy(cmd.Context())
-		},
+		Long:  "Destroy the NAS cluster and clean up resources. The plan is shown first and the cluster name must be typed to confirm, unless --force is set.",
+		RunE: cmdutil.Recorded("nas destroy", "nas", func(cmd *cobra.Command, args []string) error {
+			skipBackup, _ := cmd.Flags().GetBool("skip-backup")
+			opts, err := cmdutil.DestroyOptions(cmd)
+			if err != nil {
+				return err
+			}
+			return runDestroy(cmd.Context(), skipBackup, opts, cmdutil.DestroyGateFor(cmd))
+		}),
 	}
+	cmd.Flags().Bool("skip-backup", false, "Destroy without exporting the cluster state first")
+	cmdutil.AddDestroyFlags(cmd)
 
 	return cmd
 }
 
-func runBootstrap(ctx context.Context, noTui bool) error {
+func runBootstrap(ctx context.Context, noTui, resume bool, fromStep string, renewBefore time.Duration, report bootstrap.ReportOptions) error {
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("nas")
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	if cfg.NAS == nil {
-		return fmt.Errorf("NAS configuration not found")
+	if !noTui && (resume || fromStep != "") {
+		// The TUI runs its own step list, checkpoints belong to the orchestrator
+		log.Info("Resuming runs in non-interactive mode")
+		noTui = true
 	}
 
 	if noTui {
@@ -113,37 +146,49 @@ func runBootstrap(ctx context.Context, noTui bool) error {
 			"docker_host", cfg.NAS.Cluster.DockerHost)
 
 		// Create orchestrator and run bootstrap
-		orchestrator, err := bootstrap.NewOrchestrator(cfg, true, orchestratorOptions(true))
+		options := cmdutil.OrchestratorOptions(ctx, cfg, true)
+		options.Resume = resume
+		options.FromStep = fromStep
+		options.GatewayCertRenewBefore = renewBefore
+		options.Report = report
+		orchestrator, err := bootstrap.NewOrchestrator(cfg, true, options)
 		if err != nil {
 			return fmt.Errorf("failed to create orchestrator: %w", err)
 		}
 
-		return orchestrator.Bootstrap(ctx)
+		bus := orchestrator.Progress()
+		printed := make(chan struct{})
+		go func() {
+			progress.Print(bus.Subscribe())
+			close(printed)
+		}()
+		err = orchestrator.Bootstrap(ctx)
+		bus.Close()
+		<-printed
+		return err
 	}
 
 	// Start interactive bootstrap TUI
-	model := tui.NewBootstrapModel(ctx, cfg, true)
+	options := cmdutil.OrchestratorOptions(ctx, cfg, true)
+	options.GatewayCertRenewBefore = renewBefore
+	model := tui.NewBootstrapModel(ctx, cfg, true, options)
 	p := tea.NewProgram(model)
+	model.StreamEventsTo(p)
 
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("bootstrap failed: %w", err)
 	}
 
-	return nil
+	return model.Err()
 }
 
 func runCheck(ctx context.Context) error {
 	log.Info("Checking NAS prerequisites")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("nas")
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.NAS == nil {
-		return fmt.Errorf("NAS configuration not found")
+		return err
 	}
 
 	// Run comprehensive prerequisite checks
@@ -153,6 +198,17 @@ func runCheck(ctx context.Context) error {
 		return fmt.Errorf("failed to run checks: %w", err)
 	}
 
+	if output.Structured() {
+		report := cmdutil.NewCheckReport("nas", results)
+		if err := output.Print(report); err != nil {
+			return err
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("prerequisite checks failed")
+		}
+		return nil
+	}
+
 	// Display results
 	log.Info("Prerequisite Check Results")
 	log.Print("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
@@ -193,25 +249,20 @@ func runCheck(ctx context.Context) error {
 
 func runInstall(ctx context.Context) error {
 	log.Info("Installing NAS infrastructure (non-interactive bootstrap)")
-	return runBootstrap(ctx, true)
+	return runBootstrap(ctx, true, false, "", bootstrap.DefaultGatewayCertRenewBefore, bootstrap.ReportOptions{})
 }
 
 func runValidate(ctx context.Context) error {
 	log.Info("Validating NAS deployment")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("nas")
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.NAS == nil {
-		return fmt.Errorf("NAS configuration not found")
+		return err
 	}
 
 	// Connect to cluster
-	client, err := k8s.NewClient(cfg.NAS.Cluster.KubeConfig)
+	client, err := k8s.NewClientWithContext(cfg.NAS.Cluster.KubeConfig, cfg.NAS.Cluster.KubeContext)
 	if err != nil {
 		return fmt.Errorf("failed to connect to cluster: %w", err)
 	}
@@ -223,6 +274,10 @@ func runValidate(ctx context.Context) error {
 		return fmt.Errorf("failed to get flux status: %w", err)
 	}
 
+	if output.Structured() {
+		return output.Print(&cmdutil.ValidateReport{Cluster: "nas", Flux: status})
+	}
+
 	if status.Ready {
 		log.Info("FluxCD is running", "status", "ready")
 	} else {
@@ -233,18 +288,13 @@ func runValidate(ctx context.Context) error {
 	return nil
 }
 
-func runDestroy(ctx context.Context) error {
+func runDestroy(ctx context.Context, skipBackup bool, opts destroy.Options, gate cmdutil.DestroyGate) error {
 	log.Warn("🗑️ Destroying NAS cluster")
 
 	// Load configuration
-	loader := config.NewLoader()
-	cfg, err := loader.LoadConfig("nas")
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	if cfg.NAS == nil {
-		return fmt.Errorf("NAS configuration not found")
+		return err
 	}
 
 	// Create destroy manager
@@ -252,6 +302,18 @@ func runDestroy(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create destroy manager: %w", err)
 	}
+	destroyManager.SetOptions(opts)
+	proceed, err := gate.Proceed(ctx, destroyManager)
+	if err != nil || !proceed {
+		return err
+	}
+	if !skipBackup {
+		dir, err := cmdutil.SnapshotDir()
+		if err != nil {
+			return err
+		}
+		destroyManager.SetSnapshot(dir, cmdutil.SnapshotKey(ctx, cfg, "nas"))
+	}
 
 	// Perform destruction
 	if err := destroyManager.DestroyCluster(ctx); err != nil {
@@ -267,12 +329,15 @@ func NewUpCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "up",
 		Short: "Create NAS cluster infrastructure",
-		Long:  "Create K3s cluster infrastructure (Docker Compose + K3s)",
+		Long: `Create the K3s cluster through the Docker API of the NAS: check the K3s ports are free, pull the image
+and start the k3s service of infrastructure/nas/docker-compose.yaml, then fetch the kubeconfig once the API answers`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runNASUp(cmd.Context())
+			noTui, _ := cmd.Flags().GetBool("no-tui")
+			return runNASUp(cmd.Context(), noTui)
 		},
 	}
 
+	cmd.Flags().Bool("no-tui", false, "Disable interactive TUI mode")
 	return cmd
 }
 
@@ -281,7 +346,7 @@ func NewStatusCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Check NAS status",
-		Long:  "Check status of NAS cluster and GitOps",
+		Long:  "Check the K3s container on the NAS Docker host and the cluster it runs",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runNASStatus(cmd.Context())
 		},
@@ -295,12 +360,32 @@ func NewUninstallCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "uninstall",
 		Short: "Uninstall NAS cluster",
-		Long:  "Uninstall everything (K3s cluster + containers + configs)",
+		Long:  "Stop and remove the K3s container and its kubeconfig, the cluster data stays in the NAS volumes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
+			return runNASUninstall(cmd.Context(), force, cmd.InOrStdin())
+		},
+	}
+
+	cmd.Flags().Bool("force", false, "Skip the typed confirmation, for non-interactive use")
+	return cmd
+}
+
+// NewLogsCommand creates the logs command for NAS
+func NewLogsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the K3s container logs",
+		Long:  "Print the output of the K3s container from the NAS Docker host",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runNASUninstall(cmd.Context())
+			follow, _ := cmd.Flags().GetBool("follow")
+			tail, _ := cmd.Flags().GetString("tail")
+			return runNASLogs(cmd.Context(), follow, tail)
 		},
 	}
 
+	cmd.Flags().BoolP("follow", "f", false, "Keep streaming new lines")
+	cmd.Flags().String("tail", "100", "Number of lines to show from the end, or all")
 	return cmd
 }
 
@@ -318,15 +403,198 @@ func NewVaultSetupCommand() *cobra.Command {
 	return cmd
 }
 
-func runNASUp(ctx context.Context) error {
-	log.Info("🚀 Creating NAS cluster infrastructure (Docker Compose + K3s)")
+// NewUpgradeCommand creates the K3s upgrade command for NAS
+func NewUpgradeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade K3s on the NAS cluster",
+		Long: "Upgrade K3s to the latest release of a channel or to an exact version, either by recreating the " +
+			"K3s container on the NAS Docker host or through the system-upgrade-controller (installed if missing), " +
+			"then wait for the nodes to run it Ready and re-validate Flux",
+		Example: `  bootstrap nas upgrade --channel stable
+  bootstrap nas upgrade --version v1.34.2+k3s1 --method controller`,
+		RunE: cmdutil.Recorded("nas upgrade", "nas", func(cmd *cobra.Command, args []string) error {
+			channel, _ := cmd.Flags().GetString("channel")
+			version, _ := cmd.Flags().GetString("version")
+			method, _ := cmd.Flags().GetString("method")
+			return runNASUpgrade(cmd.Context(), channel, version, method)
+		}),
+	}
+
+	cmd.Flags().String("channel", "stable", "K3s release channel: stable, latest or a minor such as v1.34")
+	cmd.Flags().String("version", "", "Exact K3s version, e.g. v1.34.2+k3s1")
+	cmd.Flags().String("method", "", "Upgrade method: docker or controller (default docker when the K3s docker-compose.yaml exists)")
+	cmd.MarkFlagsMutuallyExclusive("channel", "version")
+	return cmd
+}
 
-	// Delegate to infrastructure Taskfile
-	return runInfrastructureTask(ctx, "nas", "up")
+const (
+	// k3sService is the service of the NAS compose file running the K3s server
+	k3sService = "k3s"
+	// k3sStartTimeout bounds the wait for the container, then for the kubeconfig K3s writes once serving
+	k3sStartTimeout = 3 * time.Minute
+	// k3sReadyTimeout bounds each wait for the API, the node and CoreDNS
+	k3sReadyTimeout = 5 * time.Minute
+)
+
+// nasComposeFile locates the NAS compose file, in infrastructure.compose_dir when set
+func nasComposeFile(cfg *config.Config) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	projectRoot := findProjectRoot(wd)
+	if projectRoot == "" {
+		return "", fmt.Errorf("project root not found - ensure you're running from within the homelab project")
+	}
+	dir := filepath.Join(projectRoot, "infrastructure", "nas")
+	if infra := cfg.NAS.Infrastructure; infra != nil && infra.ComposeDir != "" {
+		dir = infra.ComposeDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(projectRoot, dir)
+		}
+	}
+	return filepath.Join(dir, "docker-compose.yaml"), nil
+}
+
+// nasContainer connects to the NAS Docker host and loads the K3s service of the compose file
+func nasContainer(cfg *config.Config) (*k3s.Container, error) {
+	composeFile, err := nasComposeFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	service, err := k3s.LoadComposeService(composeFile, k3sService)
+	if err != nil {
+		return nil, err
+	}
+	client, err := docker.NewClient(cfg.NAS.Cluster.DockerHost, cfg.NAS.Cluster.CertPath)
+	if err != nil {
+		return nil, err
+	}
+	return k3s.NewContainer(client, service), nil
+}
+
+func runNASUp(ctx context.Context, noTui bool) error {
+	log.Info("🚀 Creating NAS cluster infrastructure (Docker API + K3s)")
+
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
+	if err != nil {
+		return err
+	}
+	if err := k8s.GuardMutation("nas up"); err != nil {
+		return err
+	}
+	container, err := nasContainer(cfg)
+	if err != nil {
+		return err
+	}
+	cluster := cfg.NAS.Cluster
+	server := "https://" + net.JoinHostPort(cluster.Host, strconv.Itoa(cluster.Port))
+	contextName := cluster.KubeContext
+	if contextName == "" {
+		contextName = "nas"
+	}
+
+	// The container output is followed from its start until the cluster is ready
+	logCtx, stopLogs := context.WithCancel(ctx)
+	defer stopLogs()
+	showLog := func(line string) {
+		log.Debug(line, "container", container.Name())
+	}
+
+	steps := []talos.Step{
+		{
+			Name:        "check-ports",
+			Description: "Check the K3s ports are free on the NAS",
+			Run:         container.CheckPorts,
+		},
+		{
+			Name:        "start-k3s",
+			Description: "Pull the image and start the K3s container",
+			Run: func(ctx context.Context) error {
+				if err := container.Up(ctx); err != nil {
+					return err
+				}
+				go func() {
+					if err := container.FollowLogs(logCtx, "20", showLog); err != nil {
+						log.Debug("Stopped following the K3s logs", "error", err)
+					}
+				}()
+				return container.WaitRunning(ctx, k3sStartTimeout)
+			},
+		},
+		{
+			Name:        "kubeconfig",
+			Description: "Fetch the kubeconfig",
+			Run: func(ctx context.Context) error {
+				data, err := container.Kubeconfig(ctx, server, contextName, k3sStartTimeout)
+				if err != nil {
+					return err
+				}
+				if err := os.MkdirAll(filepath.Dir(cluster.KubeConfig), 0o755); err != nil {
+					return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+				}
+				if err := os.WriteFile(cluster.KubeConfig, data, 0o600); err != nil {
+					return fmt.Errorf("failed to write kubeconfig: %w", err)
+				}
+				log.Info("📋 Kubeconfig written", "path", cluster.KubeConfig, "server", server, "context", contextName)
+				return nil
+			},
+		},
+		{
+			Name:        "validate-cluster",
+			Description: "Wait for the node and CoreDNS",
+			Run: func(ctx context.Context) error {
+				client, err := k8s.NewClientWithContext(cluster.KubeConfig, contextName)
+				if err != nil {
+					return fmt.Errorf("failed to connect to cluster: %w", err)
+				}
+				if err := client.WaitForReady(ctx, k3sReadyTimeout); err != nil {
+					return fmt.Errorf("kubernetes API not reachable at %s: %w", server, err)
+				}
+				if err := client.WaitForNodes(ctx, 1, k3sReadyTimeout); err != nil {
+					return err
+				}
+				// The K3s helm-install pods complete rather than turn ready, CoreDNS tells the system pods run
+				return client.WaitForDeployment(ctx, "kube-system", "coredns", k3sReadyTimeout)
+			},
+		},
+	}
+
+	if noTui {
+		for i, step := range steps {
+			log.Info("Executing up step", "step", i+1, "total", len(steps), "name", step.Name, "description", step.Description)
+			if err := step.Run(ctx); err != nil {
+				return fmt.Errorf("%s failed: %w", step.Name, err)
+			}
+		}
+	} else {
+		model := tui.NewUpModel(ctx, steps).WithTitle("🚀 NAS Up", "✅ NAS cluster ready")
+		program := tea.NewProgram(model)
+		container.SetProgressSink(func(message string) {
+			program.Send(tui.LogMsg{Message: message})
+		})
+		showLog = func(line string) {
+			program.Send(tui.LogMsg{Message: line})
+		}
+		if _, err := program.Run(); err != nil {
+			return fmt.Errorf("up failed: %w", err)
+		}
+		if err := model.Err(); err != nil {
+			return err
+		}
+	}
+
+	log.Info("✅ NAS cluster ready. Use 'bootstrap nas bootstrap' to install FluxCD.", "kubeconfig", cluster.KubeConfig)
+	return nil
 }
 
 // runInfrastructureTask executes a task in the specified infrastructure Taskfile
 func runInfrastructureTask(ctx context.Context, infra, task string) error {
+	if err := k8s.GuardMutation("task " + task); err != nil {
+		return err
+	}
+
 	// Find project root to work from both repo root and bootstrap directory
 	wd, err := os.Getwd()
 	if err != nil {
@@ -389,37 +657,127 @@ func findProjectRoot(startDir string) string {
 	return "" // Project root not found
 }
 
-func orchestratorOptions(isNAS bool) *bootstrap.OrchestratorOptions {
-	if isNAS {
-		return &bootstrap.OrchestratorOptions{
-			KubeconfigPath:        kubeconfigFor("nas"),
-			HomelabKubeconfigPath: kubeconfigFor("homelab"),
-			NASKubeconfigPath:     kubeconfigFor("nas"),
+func runNASStatus(ctx context.Context) error {
+	log.Info("🔍 Checking NAS status")
+
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
+	if err != nil {
+		return err
+	}
+	container, err := nasContainer(cfg)
+	if err != nil {
+		return err
+	}
+
+	report := &cmdutil.StatusReport{Cluster: "nas"}
+	printReport := func() error {
+		if output.Structured() {
+			return output.Print(report)
 		}
+		return nil
 	}
-	return &bootstrap.OrchestratorOptions{
-		KubeconfigPath:        kubeconfigFor("homelab"),
-		HomelabKubeconfigPath: kubeconfigFor("homelab"),
-		NASKubeconfigPath:     kubeconfigFor("nas"),
+
+	status, err := container.Status(ctx)
+	if err != nil {
+		log.Error("❌ Cannot reach the NAS Docker host", "docker_host", cfg.NAS.Cluster.DockerHost, "error", err)
+		report.Errors = append(report.Errors, err.Error())
+		if printErr := printReport(); printErr != nil {
+			return printErr
+		}
+		return fmt.Errorf("failed to inspect the K3s container: %w", err)
+	}
+	report.Container = status
+	switch {
+	case !status.Exists:
+		log.Warn("⚠️ K3s container does not exist, run 'bootstrap nas up'", "container", status.Name)
+	case status.State == "running":
+		log.Info("✅ K3s container running", "container", status.Name, "image", status.Image, "since", status.StartedAt.Format(time.RFC3339), "restarts", status.RestartCount)
+	default:
+		log.Error("❌ K3s container not running", "container", status.Name, "state", status.State, "exit_code", status.ExitCode, "error", status.Error)
+	}
+	if status.Exists && !status.UpToDate {
+		log.Warn("⚠️ K3s container differs from the compose file, run 'bootstrap nas up' to recreate it")
 	}
-}
 
-func kubeconfigFor(cluster string) string {
-	return filepath.Join("infrastructure", cluster, "kubeconfig.yaml")
-}
+	client, err := k8s.NewClientWithContext(cfg.NAS.Cluster.KubeConfig, cfg.NAS.Cluster.KubeContext)
+	if err != nil {
+		log.Error("❌ Cannot connect to cluster", "error", err)
+		report.Errors = append(report.Errors, err.Error())
+		return printReport()
+	}
+	if err := client.IsReady(ctx); err != nil {
+		log.Error("❌ Cluster API not ready", "error", err)
+		report.Errors = append(report.Errors, err.Error())
+		return printReport()
+	}
+	log.Info("✅ Cluster API is accessible")
+	report.APIReady = true
 
-func runNASStatus(ctx context.Context) error {
-	log.Info("🔍 Checking NAS status")
+	nodes, err := client.GetNodes(ctx)
+	if err != nil {
+		log.Error("❌ Failed to get nodes", "error", err)
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to get nodes: %v", err))
+	} else {
+		log.Info("📋 Nodes", "count", len(nodes), "nodes", nodes)
+		report.Nodes = nodes
+	}
 
-	// Delegate to infrastructure Taskfile
-	return runInfrastructureTask(ctx, "nas", "status")
+	exists, err := client.NamespaceExists(ctx, "flux-system")
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to check flux-system namespace: %v", err))
+	} else if exists {
+		log.Info("✅ FluxCD namespace exists")
+		report.FluxInstalled = true
+	} else {
+		log.Warn("⚠️ FluxCD is not installed (flux-system namespace missing)")
+	}
+	return printReport()
 }
 
-func runNASUninstall(ctx context.Context) error {
+func runNASUninstall(ctx context.Context, force bool, in io.Reader) error {
 	log.Warn("🗑️ Uninstalling NAS cluster")
 
-	// Delegate to infrastructure Taskfile
-	return runInfrastructureTask(ctx, "nas", "uninstall")
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
+	if err != nil {
+		return err
+	}
+	if err := k8s.GuardMutation("nas uninstall"); err != nil {
+		return err
+	}
+	container, err := nasContainer(cfg)
+	if err != nil {
+		return err
+	}
+
+	if force {
+		log.Warn("Confirmation skipped with --force")
+	} else {
+		name := cfg.NAS.Cluster.Name
+		if err := cmdutil.Confirm(in, fmt.Sprintf("Type the cluster name (%s) to remove the K3s container", name), name); err != nil {
+			return fmt.Errorf("%w, nothing was removed (pass --force to skip the confirmation)", err)
+		}
+	}
+
+	if err := container.Down(ctx); err != nil {
+		return err
+	}
+	if err := os.Remove(cfg.NAS.Cluster.KubeConfig); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove kubeconfig: %w", err)
+	}
+	log.Info("✅ NAS cluster uninstalled, its data remains in the NAS volumes", "container", container.Name())
+	return nil
+}
+
+func runNASLogs(ctx context.Context, follow bool, tail string) error {
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
+	if err != nil {
+		return err
+	}
+	container, err := nasContainer(cfg)
+	if err != nil {
+		return err
+	}
+	return container.Logs(ctx, tail, follow, output.GetManager().GetStdout())
 }
 
 func runVaultSetup(ctx context.Context) error {
@@ -428,3 +786,55 @@ func runVaultSetup(ctx context.Context) error {
 	// Delegate to infrastructure Taskfile
 	return runInfrastructureTask(ctx, "nas", "vault-setup")
 }
+
+func runNASUpgrade(ctx context.Context, channel, version, method string) error {
+	cfg, err := cmdutil.LoadConfig(ctx, "nas")
+	if err != nil {
+		return err
+	}
+
+	composeFile, err := nasComposeFile(cfg)
+	if err != nil {
+		return err
+	}
+	if method == "" {
+		method = k3s.MethodController
+		if _, err := k3s.ComposeVersion(composeFile); err == nil {
+			method = k3s.MethodDocker
+		}
+	}
+
+	opts := k3s.UpgradeOptions{
+		Channel:     channel,
+		Version:     version,
+		Method:      method,
+		ComposeFile: composeFile,
+		DockerHost:  cfg.NAS.Cluster.DockerHost,
+		CertPath:    cfg.NAS.Cluster.CertPath,
+	}
+	target, err := k3s.TargetVersion(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	client, err := k8s.NewClientWithContext(cfg.NAS.Cluster.KubeConfig, cfg.NAS.Cluster.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+
+	log.Info("⬆️ Upgrading NAS K3s", "version", target, "method", method)
+	if err := k3s.NewUpgrader(client).Upgrade(ctx, opts, target); err != nil {
+		return fmt.Errorf("K3s upgrade failed: %w", err)
+	}
+
+	// Controllers restart with the API server, give Flux a full reconciliation before calling it healthy
+	fluxClient := flux.NewClient(client, &cfg.NAS.GitOps)
+	if err := fluxClient.WaitForKustomization(ctx, "flux-system", "flux-system", 10*time.Minute); err != nil {
+		return fmt.Errorf("Flux unhealthy after the K3s upgrade: %w", err)
+	}
+	log.Info("✅ NAS cluster upgraded", "version", target)
+	if method == k3s.MethodDocker {
+		log.Info("Commit the updated image tag", "file", composeFile)
+	}
+	return nil
+}
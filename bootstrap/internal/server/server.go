@@ -0,0 +1,459 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/internal/cmdutil"
+	"github.com/fredericrous/homelab/bootstrap/pkg/audit"
+	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/dashboard"
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	"github.com/fredericrous/homelab/bootstrap/pkg/schedule"
+)
+
+const (
+	// TokenEnv holds the bearer token clients must send when no token file is given
+	TokenEnv = "BOOTSTRAP_SERVE_TOKEN"
+
+	// maxJobs is how many finished jobs stay listed
+	maxJobs = 50
+)
+
+// Options configures the API server
+type Options struct {
+	Listen string
+	// Token is the bearer token accepted from clients, empty to only accept client certificates
+	Token string
+	// TLSCert and TLSKey serve HTTPS, ClientCA additionally verifies client certificates against it
+	TLSCert  string
+	TLSKey   string
+	ClientCA string
+	// Scheduler, when set, is listed under /api/v1/schedules
+	Scheduler *schedule.Scheduler
+}
+
+// Job is an operation started through the API that outlives its request, identified by its history run ID
+type Job struct {
+	ID         string         `json:"id"`
+	Operation  string         `json:"operation"`
+	Cluster    string         `json:"cluster"`
+	Status     history.Status `json:"status"`
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// BootstrapRequest is the body of a bootstrap request
+type BootstrapRequest struct {
+	Resume   bool   `json:"resume"`
+	FromStep string `json:"fromStep"`
+}
+
+// FluxRequest is the body of a Flux action, the kind defaulting to Kustomization and the namespace to flux-system
+type FluxRequest struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// Server exposes the orchestrator, status, health, drift and Flux operations over an authenticated HTTP API
+type Server struct {
+	opts Options
+	// base carries the cluster overrides of the serve command and ends when the server stops
+	base context.Context
+
+	mu     sync.Mutex
+	jobs   []*Job
+	active *Job
+}
+
+// New validates opts and creates a server running jobs under ctx
+func New(ctx context.Context, opts Options) (*Server, error) {
+	if opts.Token == "" && opts.ClientCA == "" {
+		return nil, fmt.Errorf("refusing to serve without authentication, set a token (%s or --token-file) or --client-ca", TokenEnv)
+	}
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+	if opts.ClientCA != "" && opts.TLSCert == "" {
+		return nil, fmt.Errorf("--client-ca requires --tls-cert and --tls-key")
+	}
+	return &Server{opts: opts, base: ctx}, nil
+}
+
+// Run serves the API until ctx is done, then stops accepting requests and cancels the running job
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.opts.Listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return s.base },
+	}
+	if s.opts.ClientCA != "" {
+		tlsConfig, err := s.clientTLS()
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if s.opts.TLSCert != "" {
+			errCh <- srv.ListenAndServeTLS(s.opts.TLSCert, s.opts.TLSKey)
+			return
+		}
+		log.Warn("Serving plain HTTP, the token travels in clear text; set --tls-cert and --tls-key outside a trusted network")
+		errCh <- srv.ListenAndServe()
+	}()
+	log.Info("🌐 Serving the bootstrap API", "listen", s.opts.Listen, "tls", s.opts.TLSCert != "", "mtls", s.opts.ClientCA != "")
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// clientTLS verifies client certificates against the client CA, requiring one unless a token is accepted too
+func (s *Server) clientTLS() (*tls.Config, error) {
+	pem, err := os.ReadFile(s.opts.ClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", s.opts.ClientCA)
+	}
+	clientAuth := tls.RequireAndVerifyClientCert
+	if s.opts.Token != "" {
+		clientAuth = tls.VerifyClientCertIfGiven
+	}
+	return &tls.Config{ClientCAs: pool, ClientAuth: clientAuth, MinVersion: tls.VersionTLS12}, nil
+}
+
+// Handler returns the API routes, every one but /healthz behind authentication
+func (s *Server) Handler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/status", s.handleStatus)
+	api.HandleFunc("GET /api/v1/clusters/{cluster}/health", s.handleHealth)
+	api.HandleFunc("GET /api/v1/clusters/{cluster}/drift", s.handleDrift)
+	api.HandleFunc("POST /api/v1/clusters/{cluster}/bootstrap", s.handleBootstrap)
+	api.HandleFunc("POST /api/v1/clusters/{cluster}/flux/{action}", s.handleFlux)
+	api.HandleFunc("GET /api/v1/jobs", s.handleJobs)
+	api.HandleFunc("GET /api/v1/jobs/{id}", s.handleJob)
+	api.HandleFunc("GET /api/v1/schedules", s.handleSchedules)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("/api/", s.authenticate(api))
+	return mux
+}
+
+// authenticate accepts requests with a verified client certificate or the bearer token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && s.opts.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		log.Warn("Rejected unauthenticated API request", "remote", r.RemoteAddr, "path", r.URL.Path)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	overrides, err := cmdutil.ClusterOverride(s.base)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	watchers, err := bootstrap.DashboardWatchers(r.Context(), overrides...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	states := make([]dashboard.ClusterState, 0, len(watchers))
+	for _, watcher := range watchers {
+		state, err := watcher.Collect(r.Context())
+		if err != nil {
+			state = dashboard.ClusterState{Cluster: watcher.Cluster(), Error: err.Error()}
+		}
+		states = append(states, state)
+	}
+	writeJSON(w, http.StatusOK, states)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	orchestrator, status, err := s.orchestrator(r.Context(), r.PathValue("cluster"))
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	health, err := orchestrator.ClusterHealth(r.Context(), bootstrap.HealthOptions{})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+func (s *Server) handleDrift(w http.ResponseWriter, r *http.Request) {
+	orchestrator, status, err := s.orchestrator(r.Context(), r.PathValue("cluster"))
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	report, err := orchestrator.Drift(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (s *Server) handleFlux(w http.ResponseWriter, r *http.Request) {
+	action := r.PathValue("action")
+	var run func(c *flux.Client, ctx context.Context, kind, namespace, name string) error
+	switch action {
+	case "reconcile":
+		run = (*flux.Client).Reconcile
+	case "suspend":
+		run = (*flux.Client).Suspend
+	case "resume":
+		run = (*flux.Client).Resume
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown Flux action %q (expected reconcile, suspend or resume)", action))
+		return
+	}
+
+	request := FluxRequest{Kind: "Kustomization", Namespace: "flux-system"}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if request.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("resource name required"))
+		return
+	}
+	kind, err := flux.ParseKind(request.Kind)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	orchestrator, status, err := s.orchestrator(r.Context(), r.PathValue("cluster"))
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	fluxClient, err := orchestrator.FluxClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := run(fluxClient, r.Context(), kind, request.Namespace, request.Name); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"action": action, "kind": kind, "namespace": request.Namespace, "name": request.Name})
+}
+
+// handleBootstrap starts a non-interactive bootstrap in the background, one job at a time across clusters
+func (s *Server) handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	cluster := r.PathValue("cluster")
+	var request BootstrapRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+	if request.Resume && request.FromStep != "" {
+		writeError(w, http.StatusBadRequest, errors.New("resume and fromStep are mutually exclusive"))
+		return
+	}
+
+	ctx, err := s.clusterContext(s.base, cluster)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	cfg, err := cmdutil.LoadConfig(ctx, cluster)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	isNAS := cluster == "nas"
+	options := cmdutil.OrchestratorOptions(ctx, cfg, isNAS)
+	options.Resume = request.Resume
+	options.FromStep = request.FromStep
+	orchestrator, err := bootstrap.NewOrchestrator(cfg, isNAS, options)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create orchestrator: %w", err))
+		return
+	}
+
+	command := cluster + " bootstrap"
+	run := history.NewRun(command, cluster)
+	run.Flags["source"] = "api"
+	if request.Resume {
+		run.Flags["resume"] = "true"
+	}
+	if request.FromStep != "" {
+		run.Flags["from-step"] = request.FromStep
+	}
+	job, err := s.start(run, command)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	go func() {
+		err := cmdutil.Record(ctx, run, orchestrator.Bootstrap)
+		s.finish(job, err)
+		if flushErr := audit.Flush(s.base); flushErr != nil {
+			log.Warn("Failed to mirror the audit trail", "error", flushErr)
+		}
+	}()
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, *s.jobs[i])
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.ID == id {
+			writeJSON(w, http.StatusOK, *job)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", id))
+}
+
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	type scheduled struct {
+		*schedule.Job
+		Next time.Time `json:"next"`
+	}
+	list := []scheduled{}
+	if s.opts.Scheduler != nil {
+		now := time.Now()
+		for _, job := range s.opts.Scheduler.Jobs(now) {
+			list = append(list, scheduled{Job: job, Next: job.Next(now)})
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// start registers a job for run, failing while another job is running
+func (s *Server) start(run *history.Run, operation string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		return nil, fmt.Errorf("%s is already running as job %s", s.active.Operation, s.active.ID)
+	}
+	job := &Job{ID: run.ID, Operation: operation, Cluster: run.Cluster, Status: history.StatusRunning, StartedAt: run.StartedAt}
+	s.active = job
+	s.jobs = append(s.jobs, job)
+	if len(s.jobs) > maxJobs {
+		s.jobs = s.jobs[len(s.jobs)-maxJobs:]
+	}
+	log.Info("▶️ Job started", "id", job.ID, "operation", operation)
+	return job, nil
+}
+
+func (s *Server) finish(job *Job, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = history.StatusSucceeded
+	if err != nil {
+		job.Status = history.StatusFailed
+		job.Error = err.Error()
+		log.Error("❌ Job failed", "id", job.ID, "operation", job.Operation, "error", err)
+	} else {
+		log.Info("✅ Job succeeded", "id", job.ID, "operation", job.Operation)
+	}
+	if s.active == job {
+		s.active = nil
+	}
+}
+
+// orchestrator creates an orchestrator for cluster, with the HTTP status to answer when it fails
+func (s *Server) orchestrator(ctx context.Context, cluster string) (*bootstrap.Orchestrator, int, error) {
+	ctx, err := s.clusterContext(ctx, cluster)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	orchestrator, err := cmdutil.NewOrchestrator(ctx, cluster)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return orchestrator, 0, nil
+}
+
+// clusterContext selects cluster in ctx, keeping the connection overrides of the serve command
+func (s *Server) clusterContext(ctx context.Context, cluster string) (context.Context, error) {
+	overrides := cmdutil.OverridesFrom(s.base)
+	if err := cmdutil.ValidateCluster(cluster); err != nil {
+		return nil, err
+	}
+	if overrides.Cluster != "" && overrides.Cluster != cluster {
+		return nil, fmt.Errorf("this server only serves the %s cluster", overrides.Cluster)
+	}
+	overrides.Cluster = cluster
+	return cmdutil.WithOverrides(ctx, overrides), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug("Failed to write API response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,140 @@
+package airgap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fluxcd/pkg/tar"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultBundleDir holds the bundle when the config does not point elsewhere
+const DefaultBundleDir = "airgap"
+
+// ErrAirGapped is returned for any download attempted while air-gapped mode is enabled
+var ErrAirGapped = errors.New("refusing to reach the internet in air-gapped mode")
+
+var (
+	mu        sync.RWMutex
+	enabled   bool
+	bundleDir = DefaultBundleDir
+)
+
+// SetEnabled enables or disables air-gapped mode, --air-gapped enables it for every cluster
+func SetEnabled(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = on
+}
+
+// Configure applies the airgap section of the cluster config, it can enable the mode but never disables
+// what --air-gapped enabled
+func Configure(cfg config.AirGapConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = enabled || cfg.Enabled
+	if cfg.BundleDir != "" {
+		bundleDir = cfg.BundleDir
+	}
+}
+
+// Enabled reports whether air-gapped mode is enabled
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled
+}
+
+// BundleDir returns the directory the Flux manifests and charts are read from
+func BundleDir() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return bundleDir
+}
+
+// GuardNetwork fails when air-gapped mode is enabled, for everything reaching GitHub, Helm repositories
+// or any other internet endpoint
+func GuardNetwork(action string) error {
+	if Enabled() {
+		return fmt.Errorf("%s: %w", action, ErrAirGapped)
+	}
+	return nil
+}
+
+// Manifest lists what a bundle holds, it is written as bundle.yaml at the root of the bundle
+type Manifest struct {
+	Flux   string            `yaml:"flux" json:"flux"`
+	Charts map[string]string `yaml:"charts" json:"charts"`
+}
+
+const (
+	manifestFile  = "bundle.yaml"
+	fluxManifests = "flux/manifests.tar.gz"
+	chartsDir     = "charts"
+	bundleCommand = "bootstrap airgap bundle"
+	missingBundle = "run '" + bundleCommand + "' on a connected machine and copy the directory over"
+)
+
+// ReadManifest reads the bundle.yaml of the bundle directory
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("air-gap bundle not found in %s, %s: %w", dir, missingBundle, err)
+	}
+	manifest := &Manifest{}
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, manifestFile), err)
+	}
+	return manifest, nil
+}
+
+// FluxManifests extracts the Flux manifests of the bundle to a temporary directory the caller removes,
+// and returns it with the Flux version they belong to
+func FluxManifests() (string, string, error) {
+	dir := BundleDir()
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return "", "", err
+	}
+	archive, err := os.Open(filepath.Join(dir, fluxManifests))
+	if err != nil {
+		return "", "", fmt.Errorf("flux manifests missing from the air-gap bundle, %s: %w", missingBundle, err)
+	}
+	defer archive.Close()
+
+	tmp, err := os.MkdirTemp("", "flux-manifests-")
+	if err != nil {
+		return "", "", err
+	}
+	if err := tar.Untar(archive, tmp, tar.WithMaxUntarSize(-1)); err != nil {
+		os.RemoveAll(tmp)
+		return "", "", fmt.Errorf("failed to extract the flux manifests: %w", err)
+	}
+	return tmp, manifest.Flux, nil
+}
+
+// Chart returns the path of the packaged chart name in the bundle, at version or at the version
+// the bundle recorded when version is empty
+func Chart(name, version string) (string, error) {
+	dir := BundleDir()
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return "", err
+	}
+	if version == "" {
+		version = manifest.Charts[name]
+	}
+	if version == "" {
+		return "", fmt.Errorf("chart %s missing from the air-gap bundle, %s", name, missingBundle)
+	}
+	path := filepath.Join(dir, chartsDir, fmt.Sprintf("%s-%s.tgz", name, version))
+	if _, err := os.Stat(path); err != nil {
+		bundled := manifest.Charts[name]
+		return "", fmt.Errorf("chart %s %s missing from the air-gap bundle (it holds %q), %s", name, version, bundled, missingBundle)
+	}
+	return path, nil
+}
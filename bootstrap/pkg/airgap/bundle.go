@@ -0,0 +1,132 @@
+package airgap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// BundleChart is a Helm chart packaged into the bundle
+type BundleChart struct {
+	Name    string
+	RepoURL string
+	Version string
+}
+
+// BundleOptions pick the pinned versions packaged into a bundle
+type BundleOptions struct {
+	FluxVersion string
+	Charts      []BundleChart
+}
+
+// DefaultFluxVersion is the Flux release this binary was built against
+func DefaultFluxVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/fluxcd/flux2/v2" {
+				return dep.Version
+			}
+		}
+	}
+	return ""
+}
+
+// Build downloads the Flux manifests and charts into dir so a later run can bootstrap without
+// internet access. It needs the access it saves the clusters from.
+func Build(ctx context.Context, dir string, opts BundleOptions) (*Manifest, error) {
+	if err := GuardNetwork("build the air-gap bundle"); err != nil {
+		return nil, err
+	}
+	if opts.FluxVersion == "" {
+		return nil, fmt.Errorf("a Flux version is required")
+	}
+	if !strings.HasPrefix(opts.FluxVersion, "v") {
+		opts.FluxVersion = "v" + opts.FluxVersion
+	}
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(fluxManifests)), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, chartsDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	manifest := &Manifest{Flux: opts.FluxVersion, Charts: map[string]string{}}
+	url := fmt.Sprintf("https://github.com/fluxcd/flux2/releases/download/%s/manifests.tar.gz", opts.FluxVersion)
+	log.Info("Downloading Flux manifests", "version", opts.FluxVersion)
+	if err := download(ctx, url, filepath.Join(dir, fluxManifests)); err != nil {
+		return nil, err
+	}
+
+	settings := cli.New()
+	for _, chart := range opts.Charts {
+		log.Info("Downloading chart", "chart", chart.Name, "version", chart.Version)
+		options := action.ChartPathOptions{RepoURL: chart.RepoURL, Version: chart.Version}
+		cached, err := options.LocateChart(chart.Name, settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download chart %s %s: %w", chart.Name, chart.Version, err)
+		}
+		version := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(cached), chart.Name+"-"), ".tgz")
+		target := filepath.Join(dir, chartsDir, fmt.Sprintf("%s-%s.tgz", chart.Name, version))
+		if err := copyFile(cached, target); err != nil {
+			return nil, err
+		}
+		manifest.Charts[chart.Name] = version
+	}
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", manifestFile, err)
+	}
+	return manifest, nil
+}
+
+func download(ctx context.Context, url, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return writeFile(target, resp.Body)
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(target, in)
+}
+
+func writeFile(target string, content io.Reader) error {
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(out, content); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return out.Close()
+}
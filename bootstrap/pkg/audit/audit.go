@@ -0,0 +1,292 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+)
+
+const (
+	// LogFile is the append-only audit trail, relative to the project root
+	LogFile = ".bootstrap/audit/audit.jsonl"
+)
+
+// Verbs of the recorded actions
+const (
+	VerbCreate           = "create"
+	VerbUpdate           = "update"
+	VerbPatch            = "patch"
+	VerbApply            = "apply"
+	VerbDelete           = "delete"
+	VerbDeleteCollection = "deletecollection"
+	// VerbExternal is a mutation made outside the API clients, through helm, kubectl or task
+	VerbExternal = "external"
+)
+
+// Outcomes of the recorded actions
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	// OutcomeBlocked is a mutation refused by read-only mode before reaching the cluster
+	OutcomeBlocked = "blocked"
+	// OutcomeStarted is an external action, whose outcome the tool running it reports
+	OutcomeStarted = "started"
+)
+
+// Entry is one mutating action of the tool
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Invocation identifies the process that made the change, Run the recorded run when the command has one
+	Invocation   string `json:"invocation"`
+	Command      string `json:"command,omitempty"`
+	Run          string `json:"run,omitempty"`
+	Cluster      string `json:"cluster,omitempty"`
+	Verb         string `json:"verb"`
+	Group        string `json:"group,omitempty"`
+	Version      string `json:"version,omitempty"`
+	Kind         string `json:"kind,omitempty"`
+	Resource     string `json:"resource,omitempty"`
+	Subresource  string `json:"subresource,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Name         string `json:"name,omitempty"`
+	FieldManager string `json:"fieldManager,omitempty"`
+	// As is the user the request impersonated, empty when sent with the kubeconfig credentials
+	As string `json:"as,omitempty"`
+	// DiffHash is the short SHA-256 of the request body, equal for two actions sending the same change
+	DiffHash string `json:"diffHash,omitempty"`
+	Outcome  string `json:"outcome"`
+	Code     int    `json:"code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// GVK returns the group, version and kind of the changed object, the resource standing in for an unknown kind
+func (e Entry) GVK() string {
+	kind := e.Kind
+	if kind == "" {
+		kind = e.Resource
+	}
+	if e.Subresource != "" {
+		kind += "/" + e.Subresource
+	}
+	if e.Group == "" {
+		return e.Version + "/" + kind
+	}
+	return e.Group + "/" + e.Version + "/" + kind
+}
+
+// Object returns the namespaced name of the changed object
+func (e Entry) Object() string {
+	if e.Namespace == "" {
+		return e.Name
+	}
+	return e.Namespace + "/" + e.Name
+}
+
+// Sink receives the entries of one cluster when the recorder is flushed
+type Sink func(ctx context.Context, entries []Entry) error
+
+// Recorder appends the mutating actions of one invocation to the audit trail
+type Recorder struct {
+	path       string
+	invocation string
+	command    string
+
+	mu      sync.Mutex
+	entries []Entry
+	sinks   map[string]Sink
+}
+
+var (
+	current atomic.Pointer[Recorder]
+	mirror  atomic.Bool
+)
+
+// Open starts recording the actions of command to the audit trail under projectRoot
+func Open(projectRoot, command string) (*Recorder, error) {
+	path := filepath.Join(projectRoot, LogFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	return &Recorder{
+		path:       path,
+		invocation: newInvocation(time.Now()),
+		command:    command,
+		sinks:      map[string]Sink{},
+	}, nil
+}
+
+// SetDefault makes r record every action reported through Record
+func SetDefault(r *Recorder) {
+	current.Store(r)
+}
+
+// SetMirror enables or disables mirroring the trail of each invocation into a ConfigMap of the changed cluster
+func SetMirror(enabled bool) {
+	mirror.Store(enabled)
+}
+
+// Mirror reports whether the trail is mirrored into the clusters
+func Mirror() bool {
+	return mirror.Load()
+}
+
+// Record appends entry to the trail of the default recorder, a no-op when none is open
+func Record(ctx context.Context, entry Entry) {
+	r := current.Load()
+	if r == nil {
+		return
+	}
+	if run := history.FromContext(ctx); run != nil {
+		entry.Run = run.ID
+	}
+	r.record(entry)
+}
+
+// RegisterSink sets where the entries of cluster go when the default recorder is flushed, keeping the first sink
+func RegisterSink(cluster string, sink Sink) {
+	r := current.Load()
+	if r == nil || !Mirror() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sinks[cluster]; !ok {
+		r.sinks[cluster] = sink
+	}
+}
+
+// Flush hands the entries of the default recorder to the sink of their cluster
+func Flush(ctx context.Context) error {
+	r := current.Load()
+	if r == nil || !Mirror() {
+		return nil
+	}
+	r.mu.Lock()
+	byCluster := map[string][]Entry{}
+	for _, entry := range r.entries {
+		byCluster[entry.Cluster] = append(byCluster[entry.Cluster], entry)
+	}
+	sinks := make(map[string]Sink, len(r.sinks))
+	for cluster, sink := range r.sinks {
+		sinks[cluster] = sink
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	for cluster, entries := range byCluster {
+		sink, ok := sinks[cluster]
+		if !ok {
+			continue
+		}
+		if err := sink(ctx, entries); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cluster, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Invocation returns the identifier of the recorder's invocation
+func (r *Recorder) Invocation() string {
+	return r.invocation
+}
+
+func (r *Recorder) record(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	entry.Invocation = r.invocation
+	entry.Command = r.command
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Debug("Failed to encode audit entry", "error", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+
+	// Opened per entry so concurrent invocations interleave whole lines and nothing is lost on exit
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Warn("Failed to write the audit trail", "error", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Warn("Failed to write the audit trail", "error", err)
+	}
+}
+
+// Read returns every entry of the audit trail under projectRoot, oldest first
+func Read(projectRoot string) ([]Entry, error) {
+	file, err := os.Open(filepath.Join(projectRoot, LogFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			log.Debug("Skipping unreadable audit entry", "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Select returns the entries of the invocation or run id (or a prefix of either), "last" or empty meaning the
+// most recent invocation that recorded anything
+func Select(entries []Entry, id string) ([]Entry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no actions recorded in %s yet", LogFile)
+	}
+	if id == "" || id == "last" {
+		id = entries[len(entries)-1].Invocation
+	}
+
+	var selected []Entry
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Invocation, id) || (entry.Run != "" && strings.HasPrefix(entry.Run, id)) {
+			selected = append(selected, entry)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no actions recorded for %s", id)
+	}
+	return selected, nil
+}
+
+func newInvocation(now time.Time) string {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return now.UTC().Format("20060102-150405")
+	}
+	return now.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
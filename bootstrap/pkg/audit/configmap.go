@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ConfigMapNamespace and ConfigMapName locate the in-cluster copy of the trail
+	ConfigMapNamespace = "kube-system"
+	ConfigMapName      = "homelab-bootstrap-audit"
+	// MaxMirroredInvocations is how many invocations the ConfigMap keeps, older ones are dropped
+	MaxMirroredInvocations = 20
+
+	// maxConfigMapBytes leaves headroom under the 1MiB object limit
+	maxConfigMapBytes = 900 * 1024
+)
+
+// WriteConfigMap stores entries under their invocation in the audit ConfigMap, creating it when missing
+func WriteConfigMap(ctx context.Context, clientset kubernetes.Interface, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	key := entries[0].Invocation + ".jsonl"
+
+	configMaps := clientset.CoreV1().ConfigMaps(ConfigMapNamespace)
+	existing, err := configMaps.Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ConfigMapName,
+				Namespace: ConfigMapNamespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "homelab-bootstrap"},
+			},
+			Data: map[string]string{key: buf.String()},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create the audit ConfigMap: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the audit ConfigMap: %w", err)
+	}
+
+	if existing.Data == nil {
+		existing.Data = map[string]string{}
+	}
+	existing.Data[key] = buf.String()
+	pruneMirrored(existing.Data, key)
+	if _, err := configMaps.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update the audit ConfigMap: %w", err)
+	}
+	return nil
+}
+
+// pruneMirrored drops the oldest invocations beyond the kept count or size, never the one just written
+func pruneMirrored(data map[string]string, keep string) {
+	keys := make([]string, 0, len(data))
+	size := 0
+	for key, value := range data {
+		keys = append(keys, key)
+		size += len(key) + len(value)
+	}
+	// Invocation identifiers start with their UTC time, so they sort oldest first
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(data) <= MaxMirroredInvocations && size <= maxConfigMapBytes {
+			return
+		}
+		if key == keep {
+			continue
+		}
+		size -= len(key) + len(data[key])
+		delete(data, key)
+	}
+}
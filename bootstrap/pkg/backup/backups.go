@@ -0,0 +1,201 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Phases Velero ends a backup or restore in
+var terminalPhases = []string{"Completed", "PartiallyFailed", "Failed", "FailedValidation"}
+
+// scheduleLabel is set by Velero on the backups a schedule creates
+const scheduleLabel = "velero.io/schedule-name"
+
+// RestorePoint is a Velero backup
+type RestorePoint struct {
+	Name       string    `json:"name"`
+	Phase      string    `json:"phase"`
+	Schedule   string    `json:"schedule,omitempty"`
+	Namespaces []string  `json:"namespaces,omitempty"`
+	Started    time.Time `json:"started,omitempty"`
+	Completed  time.Time `json:"completed,omitempty"`
+	Expires    time.Time `json:"expires,omitempty"`
+	Errors     int64     `json:"errors"`
+	Warnings   int64     `json:"warnings"`
+}
+
+// BackupRequest describes an on-demand backup
+type BackupRequest struct {
+	Name       string
+	Namespaces []string
+	TTL        time.Duration
+	Wait       time.Duration // Zero returns as soon as the backup is created
+}
+
+// RestoreRequest describes a restore from a backup
+type RestoreRequest struct {
+	Backup     string
+	Namespaces []string
+	Wait       time.Duration // Zero returns as soon as the restore is created
+}
+
+// RestoreResult is the state of a Velero restore
+type RestoreResult struct {
+	Name     string `json:"name"`
+	Backup   string `json:"backup"`
+	Phase    string `json:"phase"`
+	Errors   int64  `json:"errors"`
+	Warnings int64  `json:"warnings"`
+}
+
+// CreateBackup triggers an on-demand backup, waiting for it to finish when req.Wait is set
+func (v *Velero) CreateBackup(ctx context.Context, req BackupRequest) (*RestorePoint, error) {
+	if req.Name == "" {
+		req.Name = "manual-" + time.Now().UTC().Format("20060102-150405")
+	}
+	spec := map[string]interface{}{
+		"storageLocation": storageLocation,
+	}
+	if req.TTL > 0 {
+		spec["ttl"] = req.TTL.String()
+	}
+	if len(req.Namespaces) > 0 {
+		spec["includedNamespaces"] = stringSlice(req.Namespaces)
+	}
+	obj, err := v.create(ctx, backupGVR, "Backup", req.Name, spec)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Backup started", "name", req.Name)
+
+	if req.Wait > 0 {
+		if obj, err = v.waitForPhase(ctx, backupGVR, req.Name, req.Wait); err != nil {
+			return nil, err
+		}
+	}
+	return restorePointOf(obj), nil
+}
+
+// ListBackups returns the restore points, newest first
+func (v *Velero) ListBackups(ctx context.Context) ([]RestorePoint, error) {
+	list, err := v.client.GetDynamicClient().Resource(backupGVR).Namespace(VeleroNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	points := make([]RestorePoint, 0, len(list.Items))
+	for i := range list.Items {
+		points = append(points, *restorePointOf(&list.Items[i]))
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Started.After(points[j].Started) })
+	return points, nil
+}
+
+// Restore restores a completed backup, waiting for it to finish when req.Wait is set
+func (v *Velero) Restore(ctx context.Context, req RestoreRequest) (*RestoreResult, error) {
+	backup, err := v.client.GetDynamicClient().Resource(backupGVR).Namespace(VeleroNamespace).Get(ctx, req.Backup, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup %s: %w", req.Backup, err)
+	}
+	if phase, _, _ := unstructured.NestedString(backup.Object, "status", "phase"); phase != "Completed" && phase != "PartiallyFailed" {
+		return nil, fmt.Errorf("backup %s is %s, only completed backups can be restored", req.Backup, phase)
+	}
+
+	spec := map[string]interface{}{
+		"backupName": req.Backup,
+		"restorePVs": true,
+	}
+	if len(req.Namespaces) > 0 {
+		spec["includedNamespaces"] = stringSlice(req.Namespaces)
+	}
+	name := req.Backup + "-" + time.Now().UTC().Format("20060102150405")
+	obj, err := v.create(ctx, restoreGVR, "Restore", name, spec)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Restore started", "name", name, "backup", req.Backup)
+
+	if req.Wait > 0 {
+		if obj, err = v.waitForPhase(ctx, restoreGVR, name, req.Wait); err != nil {
+			return nil, err
+		}
+	}
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	errors, _, _ := unstructured.NestedInt64(obj.Object, "status", "errors")
+	warnings, _, _ := unstructured.NestedInt64(obj.Object, "status", "warnings")
+	return &RestoreResult{Name: name, Backup: req.Backup, Phase: phase, Errors: errors, Warnings: warnings}, nil
+}
+
+func (v *Velero) create(ctx context.Context, gvr schema.GroupVersionResource, kind, name string, spec map[string]interface{}) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": veleroGroupVersion.String(),
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": VeleroNamespace,
+		},
+		"spec": spec,
+	}}
+	created, err := v.client.GetDynamicClient().Resource(gvr).Namespace(VeleroNamespace).Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s %s: %w", kind, name, err)
+	}
+	return created, nil
+}
+
+// waitForPhase waits until a backup or restore reaches a terminal phase, failing unless it completed
+func (v *Velero) waitForPhase(ctx context.Context, gvr schema.GroupVersionResource, name string, timeout time.Duration) (*unstructured.Unstructured, error) {
+	var obj *unstructured.Unstructured
+	var phase string
+	err := k8s.Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		current, err := v.client.GetDynamicClient().Resource(gvr).Namespace(VeleroNamespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		obj = current
+		phase, _, _ = unstructured.NestedString(obj.Object, "status", "phase")
+		return slices.Contains(terminalPhases, phase), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s %s did not finish, last phase %q: %w", gvr.Resource, name, phase, err)
+	}
+	switch phase {
+	case "Completed":
+		return obj, nil
+	case "PartiallyFailed":
+		log.Warn("Finished with errors, check velero describe for details", "name", name)
+		return obj, nil
+	default:
+		reason, _, _ := unstructured.NestedStringSlice(obj.Object, "status", "validationErrors")
+		return nil, fmt.Errorf("%s %s %s %v", gvr.Resource, name, phase, reason)
+	}
+}
+
+func restorePointOf(obj *unstructured.Unstructured) *RestorePoint {
+	point := &RestorePoint{Name: obj.GetName(), Schedule: obj.GetLabels()[scheduleLabel]}
+	point.Phase, _, _ = unstructured.NestedString(obj.Object, "status", "phase")
+	point.Namespaces, _, _ = unstructured.NestedStringSlice(obj.Object, "spec", "includedNamespaces")
+	point.Errors, _, _ = unstructured.NestedInt64(obj.Object, "status", "errors")
+	point.Warnings, _, _ = unstructured.NestedInt64(obj.Object, "status", "warnings")
+	point.Started = timestamp(obj, "startTimestamp")
+	point.Completed = timestamp(obj, "completionTimestamp")
+	point.Expires = timestamp(obj, "expiration")
+	if point.Started.IsZero() {
+		point.Started = obj.GetCreationTimestamp().Time
+	}
+	return point
+}
+
+func timestamp(obj *unstructured.Unstructured, field string) time.Time {
+	value, _, _ := unstructured.NestedString(obj.Object, "status", field)
+	parsed, _ := time.Parse(time.RFC3339, value)
+	return parsed
+}
@@ -0,0 +1,299 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// VeleroNamespace is where Velero runs and its backup, restore and schedule objects live
+	VeleroNamespace = "velero"
+
+	// DefaultS3URL is the S3 API of the NAS MinIO, reached across the mesh
+	DefaultS3URL = "http://minio.minio.svc.cluster.local:9000"
+	// DefaultBucket is the NAS MinIO bucket holding backups, each cluster writing under its own prefix
+	DefaultBucket = "backups"
+	// DefaultRegion is ignored by MinIO but required by the AWS plugin
+	DefaultRegion = "minio"
+	// DefaultChartVersion is the tested Velero chart
+	DefaultChartVersion = "10.1.2"
+
+	releaseName       = "velero"
+	chartRepoURL      = "https://vmware-tanzu.github.io/helm-charts"
+	veleroImage       = "velero/velero"
+	awsPluginImage    = "velero/velero-plugin-for-aws:v1.12.2"
+	credentialsSecret = "velero-minio-credentials"
+	storageLocation   = "default"
+	installTimeout    = 10 * time.Minute
+	// scheduleSet is the apply set of the schedules, pruning the ones removed from the config
+	scheduleSet = "velero-schedules"
+)
+
+var (
+	veleroGroupVersion = schema.GroupVersion{Group: "velero.io", Version: "v1"}
+	backupGVR          = veleroGroupVersion.WithResource("backups")
+	restoreGVR         = veleroGroupVersion.WithResource("restores")
+	scheduleGVR        = veleroGroupVersion.WithResource("schedules")
+	storageLocationGVR = veleroGroupVersion.WithResource("backupstoragelocations")
+)
+
+// Options configures Velero and its MinIO backend
+type Options struct {
+	Cluster      string
+	ChartVersion string
+	S3URL        string
+	Bucket       string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	Schedules    []Schedule
+}
+
+// Schedule is a recurring backup
+type Schedule struct {
+	Name       string
+	Cron       string
+	TTL        time.Duration
+	Namespaces []string
+}
+
+// DefaultSchedules backs up every namespace daily and keeps a month of restore points
+func DefaultSchedules() []Schedule {
+	return []Schedule{{Name: "daily", Cron: "0 3 * * *", TTL: 720 * time.Hour}}
+}
+
+// Velero drives the Velero server of a cluster
+type Velero struct {
+	client *k8s.Client
+}
+
+// NewVelero creates a Velero client for the cluster behind client
+func NewVelero(client *k8s.Client) *Velero {
+	return &Velero{client: client}
+}
+
+// Install deploys Velero with the AWS plugin pointed at MinIO, then applies the schedules
+func (v *Velero) Install(ctx context.Context, opts Options) error {
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return fmt.Errorf("MinIO credentials are required, set MINIO_ACCESS_KEY and MINIO_SECRET_KEY")
+	}
+	if err := v.client.CreateNamespace(ctx, VeleroNamespace); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %w", VeleroNamespace, err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      credentialsSecret,
+			Namespace: VeleroNamespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"cloud": []byte(fmt.Sprintf("[default]\naws_access_key_id=%s\naws_secret_access_key=%s\n", opts.AccessKey, opts.SecretKey)),
+		},
+	}
+	if err := v.client.CreateOrUpdateSecret(ctx, secret); err != nil {
+		return fmt.Errorf("failed to create %s secret: %w", credentialsSecret, err)
+	}
+
+	managedByFlux, err := v.managedByFlux(ctx)
+	if err != nil {
+		return err
+	}
+	if managedByFlux {
+		log.Info("Velero is deployed by Flux, leaving the chart to it")
+	} else if err := v.installChart(ctx, opts); err != nil {
+		return err
+	}
+
+	if err := v.waitForStorageLocation(ctx); err != nil {
+		return err
+	}
+	return v.ApplySchedules(ctx, opts.Schedules)
+}
+
+// managedByFlux reports whether a Flux HelmRelease owns the Velero deployment
+func (v *Velero) managedByFlux(ctx context.Context) (bool, error) {
+	deployment, err := v.client.GetClientset().AppsV1().Deployments(VeleroNamespace).Get(ctx, releaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get Velero deployment: %w", err)
+	}
+	_, ok := deployment.Labels["helm.toolkit.fluxcd.io/name"]
+	return ok, nil
+}
+
+func (v *Velero) installChart(ctx context.Context, opts Options) error {
+	if _, err := exec.LookPath("helm"); err != nil {
+		return fmt.Errorf("helm CLI not found - install with: brew install helm")
+	}
+	if err := k8s.GuardMutation("helm install velero"); err != nil {
+		return err
+	}
+
+	valuesFile, err := os.CreateTemp("", "velero-values-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create values file: %w", err)
+	}
+	defer os.Remove(valuesFile.Name())
+	if _, err := valuesFile.WriteString(veleroValues(opts)); err != nil {
+		valuesFile.Close()
+		return fmt.Errorf("failed to write values file: %w", err)
+	}
+	valuesFile.Close()
+
+	log.Info("Installing Velero", "version", opts.ChartVersion, "s3", opts.S3URL, "bucket", opts.Bucket)
+	chart := "vmware-tanzu/velero"
+	if airgap.Enabled() {
+		if chart, err = airgap.Chart("velero", opts.ChartVersion); err != nil {
+			return err
+		}
+	} else {
+		addCmd := exec.CommandContext(ctx, "helm", "repo", "add", "vmware-tanzu", chartRepoURL)
+		if output, err := addCmd.CombinedOutput(); err != nil && !strings.Contains(string(output), "already exists") {
+			return fmt.Errorf("failed to add helm repo: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		updateCmd := exec.CommandContext(ctx, "helm", "repo", "update", "vmware-tanzu")
+		if output, err := updateCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to update helm repo: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	args := []string{"upgrade", "--install", releaseName, chart,
+		"--namespace", VeleroNamespace,
+		"--version", opts.ChartVersion,
+		"--values", valuesFile.Name(),
+		"--wait",
+		"--timeout", installTimeout.String(),
+	}
+	if output, err := exec.CommandContext(ctx, "helm", args...).CombinedOutput(); err != nil {
+		log.Error("Velero Helm install failed", "error", err, "output", string(output))
+		return fmt.Errorf("helm install failed: %w", err)
+	}
+
+	log.Info("Velero installed")
+	return nil
+}
+
+// BundleChart is the Velero chart at version, the tested one when empty, for the air-gap bundle
+func BundleChart(version string) airgap.BundleChart {
+	if version == "" {
+		version = DefaultChartVersion
+	}
+	return airgap.BundleChart{Name: "velero", RepoURL: chartRepoURL, Version: version}
+}
+
+// veleroValues points the default storage location at the MinIO bucket and enables file system backups of annotated volumes
+func veleroValues(opts Options) string {
+	return fmt.Sprintf(`initContainers:
+  - name: velero-plugin-for-aws
+    image: %s
+    volumeMounts:
+      - mountPath: /target
+        name: plugins
+credentials:
+  useSecret: true
+  existingSecret: %s
+configuration:
+  backupStorageLocation:
+    - name: %s
+      provider: aws
+      default: true
+      bucket: %s
+      prefix: %s
+      config:
+        region: %s
+        s3ForcePathStyle: "true"
+        s3Url: %s
+  volumeSnapshotLocation: []
+snapshotsEnabled: false
+deployNodeAgent: true
+`, registry.Rewrite(awsPluginImage), credentialsSecret, storageLocation, opts.Bucket, opts.Cluster, opts.Region, opts.S3URL) + mirrorValues()
+}
+
+// mirrorValues points the Velero image at the registry mirror
+func mirrorValues() string {
+	if registry.Mirror() == "" {
+		return ""
+	}
+	return fmt.Sprintf("image:\n  repository: %s\n", registry.Rewrite(veleroImage))
+}
+
+// waitForStorageLocation waits until Velero reaches the bucket
+func (v *Velero) waitForStorageLocation(ctx context.Context) error {
+	var phase string
+	err := k8s.Poll(ctx, 5*time.Second, 2*time.Minute, func(ctx context.Context) (bool, error) {
+		location, err := v.client.GetDynamicClient().Resource(storageLocationGVR).Namespace(VeleroNamespace).Get(ctx, storageLocation, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		phase, _, _ = unstructured.NestedString(location.Object, "status", "phase")
+		return phase == "Available", nil
+	})
+	if err != nil {
+		return fmt.Errorf("backup storage location is %q, check the MinIO URL, credentials and that the bucket exists: %w", phase, err)
+	}
+	log.Info("Backup storage location available")
+	return nil
+}
+
+// ApplySchedules creates or updates the recurring backups, deleting the schedules removed from the config
+func (v *Velero) ApplySchedules(ctx context.Context, schedules []Schedule) error {
+	objs := make([]*unstructured.Unstructured, 0, len(schedules))
+	for _, schedule := range schedules {
+		template := map[string]interface{}{
+			"storageLocation": storageLocation,
+			"ttl":             schedule.TTL.String(),
+		}
+		if len(schedule.Namespaces) > 0 {
+			template["includedNamespaces"] = stringSlice(schedule.Namespaces)
+		}
+		objs = append(objs, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": veleroGroupVersion.String(),
+			"kind":       "Schedule",
+			"metadata": map[string]interface{}{
+				"name":      schedule.Name,
+				"namespace": VeleroNamespace,
+			},
+			"spec": map[string]interface{}{
+				"schedule": schedule.Cron,
+				"template": template,
+			},
+		}})
+	}
+
+	result, err := v.client.ApplySet(ctx, scheduleSet, objs)
+	if err != nil {
+		return fmt.Errorf("failed to apply backup schedules: %w", err)
+	}
+	for _, schedule := range schedules {
+		log.Info("Backup schedule applied", "name", schedule.Name, "schedule", schedule.Cron, "ttl", schedule.TTL)
+	}
+	for _, ref := range result.Pruned {
+		log.Info("Backup schedule removed", "name", ref.Name)
+	}
+	return nil
+}
+
+func stringSlice(values []string) []interface{} {
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+	return items
+}
@@ -0,0 +1,412 @@
+package baseline
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/policy"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Names of the objects the baseline owns in every namespace
+const (
+	NetworkPolicyName = "baseline-default"
+	QuotaName         = "baseline"
+	LimitRangeName    = "baseline"
+
+	managedBy = "homelab-bootstrap"
+)
+
+// Default network policies of a namespace
+const (
+	NetworkPolicyNone          = "none"
+	NetworkPolicyDenyIngress   = "deny-ingress"
+	NetworkPolicySameNamespace = "allow-same-namespace"
+)
+
+// Actions applied to a namespace or one of its objects
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionConflict = "conflict"
+)
+
+// meshLabels are the labels selecting the Istio data plane of a namespace
+var meshLabels = map[string]map[string]string{
+	"ambient": {"istio.io/dataplane-mode": "ambient"},
+	"sidecar": {"istio-injection": "enabled"},
+}
+
+// Change is one remediation applied, or planned in dry-run, to reach the baseline
+type Change struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Action    string
+	Detail    string
+}
+
+// Reconciler brings every application namespace in line with the declared baseline
+type Reconciler struct {
+	client *k8s.Client
+	cfg    config.NamespaceBaselineConfig
+}
+
+// NewReconciler creates a reconciler for the baseline declared in cfg
+func NewReconciler(client *k8s.Client, cfg config.NamespaceBaselineConfig) *Reconciler {
+	if len(cfg.ExcludedNamespaces) == 0 {
+		cfg.ExcludedNamespaces = policy.DefaultExcludedNamespaces
+	}
+	return &Reconciler{client: client, cfg: cfg}
+}
+
+// Reconcile remediates the namespaces drifting from the baseline, only reporting the changes when dryRun is set
+func (r *Reconciler) Reconcile(ctx context.Context, dryRun bool) ([]Change, error) {
+	quota, err := parseResources(r.cfg.ResourceQuota)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource_quota: %w", err)
+	}
+	limits, err := r.limitRange()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := r.client.GetClientset().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	excluded := make(map[string]bool, len(r.cfg.ExcludedNamespaces))
+	for _, name := range r.cfg.ExcludedNamespaces {
+		excluded[name] = true
+	}
+
+	var changes []Change
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		if excluded[ns.Name] || ns.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		override := r.cfg.Overrides[ns.Name]
+
+		nsChanges, err := r.reconcileLabels(ctx, ns, override, dryRun)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, nsChanges...)
+
+		if desired := r.networkPolicy(ns.Name, override); desired != nil {
+			change, err := r.reconcileNetworkPolicy(ctx, desired, dryRun)
+			if err != nil {
+				return changes, err
+			}
+			changes = appendChange(changes, change)
+		}
+		if len(quota) > 0 && !override.SkipQuota {
+			change, err := r.reconcileQuota(ctx, ns.Name, quota, dryRun)
+			if err != nil {
+				return changes, err
+			}
+			changes = appendChange(changes, change)
+		}
+		if len(limits) > 0 && !override.SkipQuota {
+			change, err := r.reconcileLimitRange(ctx, ns.Name, limits, dryRun)
+			if err != nil {
+				return changes, err
+			}
+			changes = appendChange(changes, change)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Namespace < changes[j].Namespace })
+	return changes, nil
+}
+
+// desiredLabels returns the labels the namespace must carry
+func (r *Reconciler) desiredLabels(override config.NamespaceOverride) map[string]string {
+	labels := make(map[string]string, len(r.cfg.Labels)+4)
+	for key, value := range r.cfg.Labels {
+		labels[key] = value
+	}
+
+	level := firstNonEmpty(override.PodSecurity, r.cfg.PodSecurity)
+	if level != "" {
+		labels["pod-security.kubernetes.io/enforce"] = level
+		labels["pod-security.kubernetes.io/enforce-version"] = "latest"
+	}
+	if warn := firstNonEmpty(r.cfg.PodSecurityWarn, level); warn != "" {
+		labels["pod-security.kubernetes.io/warn"] = warn
+		labels["pod-security.kubernetes.io/audit"] = warn
+	}
+
+	for key, value := range meshLabels[firstNonEmpty(override.MeshMode, r.cfg.MeshMode)] {
+		labels[key] = value
+	}
+	return labels
+}
+
+func (r *Reconciler) reconcileLabels(ctx context.Context, ns *corev1.Namespace, override config.NamespaceOverride, dryRun bool) ([]Change, error) {
+	var changes []Change
+	for key, value := range r.desiredLabels(override) {
+		current, exists := ns.Labels[key]
+		if exists && current == value {
+			continue
+		}
+		change := Change{Namespace: ns.Name, Kind: "Label", Name: key, Action: ActionCreate, Detail: value}
+		if exists {
+			change.Action = ActionUpdate
+			change.Detail = current + " → " + value
+		}
+		changes = append(changes, change)
+		if ns.Labels == nil {
+			ns.Labels = map[string]string{}
+		}
+		ns.Labels[key] = value
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+
+	if len(changes) == 0 || dryRun {
+		return changes, nil
+	}
+	if _, err := r.client.GetClientset().CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to label namespace %s: %w", ns.Name, err)
+	}
+	return changes, nil
+}
+
+// networkPolicy renders the default ingress policy of a namespace, nil when none applies
+func (r *Reconciler) networkPolicy(namespace string, override config.NamespaceOverride) *networkingv1.NetworkPolicy {
+	mode := firstNonEmpty(override.NetworkPolicy, r.cfg.NetworkPolicy, NetworkPolicyNone)
+	if mode == NetworkPolicyNone {
+		return nil
+	}
+
+	var peers []networkingv1.NetworkPolicyPeer
+	if mode == NetworkPolicySameNamespace {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}})
+	}
+	if len(r.cfg.AllowFrom) > 0 {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      corev1.LabelMetadataName,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   r.cfg.AllowFrom,
+				}},
+			},
+		})
+	}
+
+	spec := networkingv1.NetworkPolicySpec{
+		PodSelector: metav1.LabelSelector{},
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+	}
+	if len(peers) > 0 {
+		spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{From: peers}}
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: managedMeta(NetworkPolicyName, namespace),
+		Spec:       spec,
+	}
+}
+
+func (r *Reconciler) reconcileNetworkPolicy(ctx context.Context, desired *networkingv1.NetworkPolicy, dryRun bool) (*Change, error) {
+	policies := r.client.GetClientset().NetworkingV1().NetworkPolicies(desired.Namespace)
+	change := &Change{Namespace: desired.Namespace, Kind: "NetworkPolicy", Name: desired.Name}
+
+	existing, err := policies.Get(ctx, desired.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		change.Action = ActionCreate
+		err = nil
+		if !dryRun {
+			_, err = policies.Create(ctx, desired, metav1.CreateOptions{})
+		}
+	case err != nil:
+	case !managed(existing.ObjectMeta):
+		return conflict(change), nil
+	case reflect.DeepEqual(existing.Spec, desired.Spec):
+		return nil, nil
+	default:
+		change.Action = ActionUpdate
+		existing.Spec = desired.Spec
+		if !dryRun {
+			_, err = policies.Update(ctx, existing, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile network policy in %s: %w", desired.Namespace, err)
+	}
+	return change, nil
+}
+
+func (r *Reconciler) reconcileQuota(ctx context.Context, namespace string, hard corev1.ResourceList, dryRun bool) (*Change, error) {
+	quotas := r.client.GetClientset().CoreV1().ResourceQuotas(namespace)
+	change := &Change{Namespace: namespace, Kind: "ResourceQuota", Name: QuotaName}
+
+	existing, err := quotas.Get(ctx, QuotaName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		change.Action = ActionCreate
+		err = nil
+		if !dryRun {
+			_, err = quotas.Create(ctx, &corev1.ResourceQuota{
+				ObjectMeta: managedMeta(QuotaName, namespace),
+				Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+			}, metav1.CreateOptions{})
+		}
+	case err != nil:
+	case !managed(existing.ObjectMeta):
+		return conflict(change), nil
+	case sameResources(existing.Spec.Hard, hard):
+		return nil, nil
+	default:
+		change.Action = ActionUpdate
+		existing.Spec.Hard = hard
+		if !dryRun {
+			_, err = quotas.Update(ctx, existing, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile resource quota in %s: %w", namespace, err)
+	}
+	return change, nil
+}
+
+func (r *Reconciler) reconcileLimitRange(ctx context.Context, namespace string, limits []corev1.LimitRangeItem, dryRun bool) (*Change, error) {
+	ranges := r.client.GetClientset().CoreV1().LimitRanges(namespace)
+	change := &Change{Namespace: namespace, Kind: "LimitRange", Name: LimitRangeName}
+
+	existing, err := ranges.Get(ctx, LimitRangeName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		change.Action = ActionCreate
+		err = nil
+		if !dryRun {
+			_, err = ranges.Create(ctx, &corev1.LimitRange{
+				ObjectMeta: managedMeta(LimitRangeName, namespace),
+				Spec:       corev1.LimitRangeSpec{Limits: limits},
+			}, metav1.CreateOptions{})
+		}
+	case err != nil:
+	case !managed(existing.ObjectMeta):
+		return conflict(change), nil
+	case sameLimits(existing.Spec.Limits, limits):
+		return nil, nil
+	default:
+		change.Action = ActionUpdate
+		existing.Spec.Limits = limits
+		if !dryRun {
+			_, err = ranges.Update(ctx, existing, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile limit range in %s: %w", namespace, err)
+	}
+	return change, nil
+}
+
+// limitRange builds the container limits of the baseline LimitRange
+func (r *Reconciler) limitRange() ([]corev1.LimitRangeItem, error) {
+	item := corev1.LimitRangeItem{Type: corev1.LimitTypeContainer}
+	var err error
+	if item.Default, err = parseResources(r.cfg.LimitRange.Default); err != nil {
+		return nil, fmt.Errorf("invalid limit_range.default: %w", err)
+	}
+	if item.DefaultRequest, err = parseResources(r.cfg.LimitRange.DefaultRequest); err != nil {
+		return nil, fmt.Errorf("invalid limit_range.default_request: %w", err)
+	}
+	if item.Max, err = parseResources(r.cfg.LimitRange.Max); err != nil {
+		return nil, fmt.Errorf("invalid limit_range.max: %w", err)
+	}
+	if len(item.Default)+len(item.DefaultRequest)+len(item.Max) == 0 {
+		return nil, nil
+	}
+	return []corev1.LimitRangeItem{item}, nil
+}
+
+func parseResources(values map[string]string) (corev1.ResourceList, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	list := make(corev1.ResourceList, len(values))
+	for name, value := range values {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+func sameResources(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, quantity := range b {
+		current, ok := a[name]
+		if !ok || current.Cmp(quantity) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sameLimits(a, b []corev1.LimitRangeItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range b {
+		if a[i].Type != b[i].Type || !sameResources(a[i].Default, b[i].Default) ||
+			!sameResources(a[i].DefaultRequest, b[i].DefaultRequest) || !sameResources(a[i].Max, b[i].Max) {
+			return false
+		}
+	}
+	return true
+}
+
+func managedMeta(name, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{policy.ManagedByLabel: managedBy},
+	}
+}
+
+// managed reports whether the object was created by the baseline rather than by hand
+func managed(meta metav1.ObjectMeta) bool {
+	return meta.Labels[policy.ManagedByLabel] == managedBy
+}
+
+func conflict(change *Change) *Change {
+	log.Warn("Baseline object exists but is not managed by bootstrap, leaving it untouched",
+		"namespace", change.Namespace, "kind", change.Kind, "name", change.Name)
+	change.Action = ActionConflict
+	return change
+}
+
+func appendChange(changes []Change, change *Change) []Change {
+	if change == nil {
+		return changes
+	}
+	return append(changes, *change)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/channels"
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// AdoptReport lists what adopting an existing cluster found and changed
+type AdoptReport struct {
+	Cluster string          `json:"cluster"`
+	DryRun  bool            `json:"dry_run,omitempty"`
+	Flux    *flux.Adoption  `json:"flux,omitempty"`
+	Cilium  *CiliumAdoption `json:"cilium,omitempty"`
+	// Reconciled lists the missing pieces created, or that would be on a dry run
+	Reconciled []string `json:"reconciled,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// CiliumAdoption describes the Cilium installation found
+type CiliumAdoption struct {
+	Version string `json:"version"`
+	// Chart is the chart version of the cilium Helm release, empty when Cilium was installed without Helm
+	Chart string `json:"chart,omitempty"`
+}
+
+// adoptPiece is a piece the bootstrap creates when one of its secrets is missing
+type adoptPiece struct {
+	name    string
+	secrets []string // <namespace>/<name>
+	create  func(ctx context.Context) error
+}
+
+// Adopt takes over a cluster installed by other means, such as the former bash scripts: the Flux objects found
+// are recorded in the apply inventory with their fields handed to the bootstrap field manager, Cilium is
+// reported, and only the secrets and Istio prerequisites that do not exist are created. Nothing else is
+// applied, so the defaults of the bootstrap do not overwrite the running configuration.
+func (o *Orchestrator) Adopt(ctx context.Context, dryRun bool) (*AdoptReport, error) {
+	report := &AdoptReport{Cluster: o.localClusterName(), DryRun: dryRun}
+
+	fluxClient, err := o.newFluxClient()
+	if err != nil {
+		return nil, err
+	}
+	clusterType := "homelab"
+	if o.isNAS {
+		clusterType = "nas"
+	}
+	if report.Flux, err = fluxClient.Adopt(ctx, "flux-system", clusterType, dryRun); err != nil {
+		return nil, err
+	}
+	o.fluxWarnings(report)
+
+	if !o.isNAS {
+		if err := o.adoptCilium(ctx, report); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, piece := range o.adoptPieces() {
+		missing, err := o.missingSecrets(ctx, piece.secrets)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		report.Reconciled = append(report.Reconciled, fmt.Sprintf("%s (%s)", piece.name, strings.Join(missing, ", ")))
+		if dryRun {
+			continue
+		}
+		log.Info("Creating missing piece", "piece", piece.name, "secrets", strings.Join(missing, ","))
+		if err := piece.create(ctx); err != nil {
+			return report, fmt.Errorf("failed to create %s: %w", piece.name, err)
+		}
+	}
+	return report, nil
+}
+
+// fluxWarnings points out what adopting Flux left for a later bootstrap to do
+func (o *Orchestrator) fluxWarnings(report *AdoptReport) {
+	if report.Flux.Version == "" {
+		report.Warnings = append(report.Warnings, "Flux is not installed, bootstrap installs it")
+		return
+	}
+	if pinned := o.gitOpsConfig().FluxVersion; pinned != "" && strings.TrimPrefix(pinned, "v") != strings.TrimPrefix(report.Flux.Version, "v") {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("gitops.flux_version pins %s but the cluster runs %s, run flux upgrade to move to the pinned release", pinned, report.Flux.Version))
+	}
+	for _, set := range slices.Sorted(maps.Keys(report.Flux.Sets)) {
+		if missing := len(report.Flux.Sets[set].Missing); missing > 0 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%d objects of %s do not exist, bootstrap --from-step bootstrap-gitops creates them", missing, set))
+		}
+	}
+}
+
+// adoptCilium reports the Cilium version and the Helm release it runs from
+func (o *Orchestrator) adoptCilium(ctx context.Context, report *AdoptReport) error {
+	version, err := channels.DeployedVersion(ctx, o.k8sClient, "cilium")
+	if apierrors.IsNotFound(err) {
+		report.Warnings = append(report.Warnings, "Cilium is not installed, bootstrap installs it")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the Cilium version: %w", err)
+	}
+	chart, err := infra.NewCiliumInstaller(o.k8sClient).Release(ctx)
+	if err != nil {
+		return err
+	}
+	report.Cilium = &CiliumAdoption{Version: version, Chart: chart}
+	if chart == "" {
+		report.Warnings = append(report.Warnings, "Cilium runs without a cilium Helm release in kube-system, the bootstrap leaves it alone and cilium sync cannot manage it")
+	}
+	return nil
+}
+
+// adoptPieces lists the secrets and Istio prerequisites the bootstrap creates, each created only when
+// one of its secrets is missing
+func (o *Orchestrator) adoptPieces() []adoptPiece {
+	pieces := []adoptPiece{{
+		name:    "cluster-vars",
+		secrets: []string{"flux-system/cluster-vars"},
+		create: func(ctx context.Context) error {
+			return o.secretsManager.CreateClusterVarsSecret(ctx, "flux-system")
+		},
+	}}
+	if cfg := o.gitOpsConfig(); cfg != nil && cfg.SOPS.Enabled {
+		pieces = append(pieces, adoptPiece{name: "sops-age", secrets: []string{"flux-system/" + cfg.SOPS.Secret()}, create: o.createSOPSAgeSecret})
+	}
+	if !o.isNAS {
+		pieces = append(pieces, adoptPiece{
+			name:    "vault-transit-token",
+			secrets: []string{"vault/vault-transit-token", "flux-system/vault-transit-token"},
+			create: func(ctx context.Context) error {
+				o.setupVaultTransitToken(ctx)
+				return nil
+			},
+		})
+	}
+	if o.isServiceMeshEnabled() {
+		secrets := []string{istioNamespace + "/cacerts"}
+		for _, peer := range o.meshPeers() {
+			secrets = append(secrets, istioNamespace+"/istio-remote-secret-"+peer.name)
+		}
+		pieces = append(pieces, adoptPiece{name: "istio-prereqs", secrets: secrets, create: o.ensureIstioPrereqs})
+	}
+	return pieces
+}
+
+// missingSecrets returns the secrets of refs, given as <namespace>/<name>, that do not exist
+func (o *Orchestrator) missingSecrets(ctx context.Context, refs []string) ([]string, error) {
+	var missing []string
+	for _, ref := range refs {
+		namespace, name, _ := strings.Cut(ref, "/")
+		if _, err := o.k8sClient.GetSecret(ctx, namespace, name); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to read secret %s: %w", ref, err)
+			}
+			missing = append(missing, ref)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,55 @@
+package bootstrap
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/backup"
+)
+
+// BackupOptions returns the Velero settings of the active cluster configuration
+func (o *Orchestrator) BackupOptions() (backup.Options, error) {
+	settings := o.config.BackupFor(o.isNAS)
+	opts := backup.Options{
+		Cluster:      o.localClusterName(),
+		ChartVersion: settings.ChartVersion,
+		S3URL:        settings.S3URL,
+		Bucket:       settings.Bucket,
+		Region:       settings.Region,
+		AccessKey:    settings.AccessKey,
+		SecretKey:    settings.SecretKey,
+	}
+	if opts.ChartVersion == "" {
+		opts.ChartVersion = backup.DefaultChartVersion
+	}
+	if opts.S3URL == "" {
+		opts.S3URL = backup.DefaultS3URL
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = backup.DefaultBucket
+	}
+	if opts.Region == "" {
+		opts.Region = backup.DefaultRegion
+	}
+
+	for _, schedule := range settings.Schedules {
+		var ttl time.Duration
+		if schedule.TTL != "" {
+			parsed, err := time.ParseDuration(schedule.TTL)
+			if err != nil {
+				return opts, fmt.Errorf("invalid ttl for backup schedule %s: %w", schedule.Name, err)
+			}
+			ttl = parsed
+		}
+		opts.Schedules = append(opts.Schedules, backup.Schedule{Name: schedule.Name, Cron: schedule.Schedule, TTL: ttl, Namespaces: schedule.Namespaces})
+	}
+	if len(opts.Schedules) == 0 {
+		opts.Schedules = backup.DefaultSchedules()
+	}
+	return opts, nil
+}
+
+// Velero returns a client for the Velero server of the active cluster
+func (o *Orchestrator) Velero() *backup.Velero {
+	return backup.NewVelero(o.k8sClient)
+}
@@ -0,0 +1,48 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/baseline"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// withNamespaceBaselineStep inserts the namespace baseline reconciliation once the infrastructure namespaces exist
+func (o *Orchestrator) withNamespaceBaselineStep(steps []BootstrapStep) []BootstrapStep {
+	if security := o.securityConfig(); security == nil || !security.NamespaceBaseline.Enabled {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "namespace-baseline",
+		Description: "Reconcile namespaces against the declared baseline",
+		Required:    false,
+		Execute:     o.reconcileNamespaceBaseline,
+	}, "wait-infrastructure")
+}
+
+// ReconcileNamespaceBaseline labels every application namespace and creates its default policy, quota and limits
+func (o *Orchestrator) ReconcileNamespaceBaseline(ctx context.Context, dryRun bool) ([]baseline.Change, error) {
+	var settings config.NamespaceBaselineConfig
+	if security := o.securityConfig(); security != nil {
+		settings = security.NamespaceBaseline
+	}
+	return baseline.NewReconciler(o.k8sClient, settings).Reconcile(ctx, dryRun)
+}
+
+func (o *Orchestrator) reconcileNamespaceBaseline(ctx context.Context) error {
+	changes, err := o.ReconcileNamespaceBaseline(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	conflicts := 0
+	for _, change := range changes {
+		if change.Action == baseline.ActionConflict {
+			conflicts++
+		}
+	}
+	log.Info("Namespace baseline reconciled", "changes", len(changes)-conflicts, "conflicts", conflicts)
+	return nil
+}
@@ -0,0 +1,492 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// defaultRotationWaveSize is how many workloads of a namespace restart together
+	defaultRotationWaveSize = 5
+	rootCAValidity          = 10 * 365 * 24 * time.Hour
+	intermediateCAValidity  = 5 * 365 * 24 * time.Hour
+)
+
+// CARotationOptions controls bootstrap mesh rotate-ca
+type CARotationOptions struct {
+	// FromDir loads the new root and intermediate from CACERTS_DIR instead of generating them
+	FromDir bool
+	// WaveSize is how many workloads of a namespace restart at once
+	WaveSize int
+}
+
+// caMaterial is the content of an Istio plugged-in CA secret
+type caMaterial struct {
+	root      []byte // Self-signed root certificate
+	cert      []byte // Intermediate signing certificate
+	key       []byte // Intermediate private key
+	certChain []byte // Intermediate then root
+}
+
+// secretData renders the Istio cacerts keys, key.pem is kept for the CA bootstrap job and older tooling
+func (m *caMaterial) secretData(rootBundle []byte) map[string][]byte {
+	return map[string][]byte{
+		"ca-cert.pem":    m.cert,
+		"ca-key.pem":     m.key,
+		"key.pem":        m.key,
+		"cert-chain.pem": m.certChain,
+		"root-cert.pem":  rootBundle,
+	}
+}
+
+// rotationCluster is a mesh member taking part in the rotation
+type rotationCluster struct {
+	name   string
+	client *k8s.Client
+	secret *corev1.Secret
+}
+
+// RotateCA replaces the Istio root CA of every mesh cluster without breaking mTLS between workloads.
+// The rollout has three phases, each restarting istiod then the mesh workloads in waves:
+// trust both roots, sign with the new intermediate, then drop the old root.
+func (o *Orchestrator) RotateCA(ctx context.Context, opts CARotationOptions) error {
+	if opts.WaveSize <= 0 {
+		opts.WaveSize = defaultRotationWaveSize
+	}
+
+	clusters, err := o.rotationClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	var next *caMaterial
+	if opts.FromDir {
+		data, err := o.readCACertsFromDir()
+		if err != nil {
+			return err
+		}
+		next, err = caMaterialFromFiles(data)
+		if err != nil {
+			return err
+		}
+		log.Info("Loaded the new root CA from disk", "fingerprint", fingerprint(next.root))
+	} else {
+		next, err = generateCAMaterial()
+		if err != nil {
+			return err
+		}
+		// Written before the rollout so an interrupted rotation resumes with --from-dir
+		if err := o.writeCACertsToDir(next); err != nil {
+			return err
+		}
+		log.Info("Generated a new root CA", "fingerprint", fingerprint(next.root))
+	}
+
+	// Every root currently trusted stays trusted until the last phase, which also makes a rerun resume cleanly
+	var previous [][]byte
+	for _, cluster := range clusters {
+		for _, root := range splitPEM(cluster.secret.Data["root-cert.pem"]) {
+			if !bytes.Equal(root, next.root) && !containsPEM(previous, root) {
+				previous = append(previous, root)
+			}
+		}
+	}
+	if len(previous) == 0 {
+		log.Info("Every cluster already trusts only the new root CA, nothing to rotate")
+		return nil
+	}
+	dualBundle := bytes.Join(append([][]byte{next.root}, previous...), nil)
+
+	phases := []struct {
+		name string
+		data func(secret *corev1.Secret) map[string][]byte
+	}{
+		{"trust the new root alongside the current one", func(secret *corev1.Secret) map[string][]byte {
+			data := copyData(secret.Data)
+			data["root-cert.pem"] = dualBundle
+			return data
+		}},
+		{"sign with the new intermediate", func(*corev1.Secret) map[string][]byte {
+			return next.secretData(dualBundle)
+		}},
+		{"drop the previous root", func(*corev1.Secret) map[string][]byte {
+			return next.secretData(next.root)
+		}},
+	}
+
+	for i, phase := range phases {
+		log.Info(fmt.Sprintf("🔐 Phase %d/%d: %s", i+1, len(phases), phase.name))
+		var bundle []byte
+		for _, cluster := range clusters {
+			cluster.secret.Data = phase.data(cluster.secret)
+			bundle = cluster.secret.Data["root-cert.pem"]
+			if err := cluster.client.CreateOrUpdateSecret(ctx, cluster.secret); err != nil {
+				return fmt.Errorf("%s: failed to update cacerts: %w", cluster.name, err)
+			}
+		}
+		for _, cluster := range clusters {
+			if err := restartIstiod(ctx, cluster, bundle); err != nil {
+				return err
+			}
+		}
+		for _, cluster := range clusters {
+			if err := restartMeshWorkloads(ctx, cluster, opts.WaveSize); err != nil {
+				return err
+			}
+			if err := verifyProxySync(ctx, cluster.client, cluster.name); err != nil {
+				return fmt.Errorf("mTLS continuity check failed after phase %d: %w", i+1, err)
+			}
+		}
+		log.Info("Phase complete", "phase", i+1, "root_fingerprint", fingerprint(bundle))
+	}
+
+	if err := verifyMeshWithRoot(ctx, o.projectRoot, o.localClusterName(), o.meshMode(ctx), o.localOverride()); err != nil {
+		return fmt.Errorf("mesh verification failed after the rotation: %w", err)
+	}
+	log.Info("Root CA rotated across the mesh", "clusters", len(clusters), "fingerprint", fingerprint(next.root))
+	return nil
+}
+
+// rotationClusters connects to the local cluster and every peer, all of which must already hold cacerts
+func (o *Orchestrator) rotationClusters(ctx context.Context) ([]*rotationCluster, error) {
+	clusters := []*rotationCluster{{name: o.localClusterName(), client: o.k8sClient}}
+	for _, peer := range o.meshPeers() {
+		client, err := peer.client()
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s, every mesh cluster must rotate together: %w", peer.name, err)
+		}
+		clusters = append(clusters, &rotationCluster{name: peer.name, client: client})
+	}
+
+	for _, cluster := range clusters {
+		secret, err := cluster.client.GetSecret(ctx, istioNamespace, "cacerts")
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read cacerts, run the bootstrap before rotating: %w", cluster.name, err)
+		}
+		if len(secret.Data["root-cert.pem"]) == 0 {
+			return nil, fmt.Errorf("%s: cacerts has no root-cert.pem", cluster.name)
+		}
+		cluster.secret = secret
+	}
+	return clusters, nil
+}
+
+// restartIstiod restarts istiod so it loads the new cacerts, then waits until it publishes the expected trust bundle
+func restartIstiod(ctx context.Context, cluster *rotationCluster, bundle []byte) error {
+	if err := restartWorkload(ctx, cluster.client, "Deployment", istioNamespace, "istiod"); err != nil {
+		return fmt.Errorf("%s: %w", cluster.name, err)
+	}
+	if err := waitForRollout(ctx, cluster.client, "Deployment", istioNamespace, "istiod"); err != nil {
+		return fmt.Errorf("%s: %w", cluster.name, err)
+	}
+
+	err := k8s.Poll(ctx, 5*time.Second, waitTimeout(ctx, defaultWaitTimeout), func(ctx context.Context) (bool, error) {
+		cm, err := cluster.client.GetClientset().CoreV1().ConfigMaps(istioNamespace).Get(ctx, "istio-ca-root-cert", metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		return bytes.Equal(bytes.TrimSpace([]byte(cm.Data["root-cert.pem"])), bytes.TrimSpace(bundle)), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: istiod did not publish the new trust bundle: %w", cluster.name, err)
+	}
+	log.Info("istiod serves the new trust bundle", "cluster", cluster.name)
+	return nil
+}
+
+// restartMeshWorkloads restarts the gateways, ztunnel and the workloads of enrolled namespaces, waveSize at a time
+func restartMeshWorkloads(ctx context.Context, cluster *rotationCluster, waveSize int) error {
+	clientset := cluster.client.GetClientset()
+
+	namespaces := []string{istioNamespace}
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("%s: failed to list namespaces: %w", cluster.name, err)
+	}
+	for _, ns := range list.Items {
+		labels := ns.GetLabels()
+		if labels["istio-injection"] == "enabled" || labels["istio.io/dataplane-mode"] == "ambient" || labels["istio.io/rev"] != "" {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	for _, namespace := range namespaces {
+		workloads, err := meshWorkloads(ctx, cluster.client, namespace)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cluster.name, err)
+		}
+		for start := 0; start < len(workloads); start += waveSize {
+			wave := workloads[start:min(start+waveSize, len(workloads))]
+			for _, w := range wave {
+				if err := restartWorkload(ctx, cluster.client, w.kind, namespace, w.name); err != nil {
+					return fmt.Errorf("%s: %w", cluster.name, err)
+				}
+			}
+			for _, w := range wave {
+				if err := waitForRollout(ctx, cluster.client, w.kind, namespace, w.name); err != nil {
+					return fmt.Errorf("%s: %w", cluster.name, err)
+				}
+			}
+			log.Info("Wave restarted", "cluster", cluster.name, "namespace", namespace, "workloads", len(wave))
+		}
+	}
+	return nil
+}
+
+type workloadRef struct {
+	kind string
+	name string
+}
+
+// meshWorkloads lists the deployments, statefulsets and daemonsets of a namespace, istiod excepted
+func meshWorkloads(ctx context.Context, client *k8s.Client, namespace string) ([]workloadRef, error) {
+	apps := client.GetClientset().AppsV1()
+	var refs []workloadRef
+
+	deployments, err := apps.Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments in %s: %w", namespace, err)
+	}
+	for _, d := range deployments.Items {
+		if (namespace == istioNamespace && d.Name == "istiod") || (d.Spec.Replicas != nil && *d.Spec.Replicas == 0) {
+			continue
+		}
+		refs = append(refs, workloadRef{"Deployment", d.Name})
+	}
+
+	statefulSets, err := apps.StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets in %s: %w", namespace, err)
+	}
+	for _, s := range statefulSets.Items {
+		if s.Spec.Replicas != nil && *s.Spec.Replicas == 0 {
+			continue
+		}
+		refs = append(refs, workloadRef{"StatefulSet", s.Name})
+	}
+
+	daemonSets, err := apps.DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets in %s: %w", namespace, err)
+	}
+	for _, d := range daemonSets.Items {
+		refs = append(refs, workloadRef{"DaemonSet", d.Name})
+	}
+	return refs, nil
+}
+
+// restartWorkload rolls the pods of a workload the way kubectl rollout restart does
+func restartWorkload(ctx context.Context, client *k8s.Client, kind, namespace, name string) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`, time.Now().Format(time.RFC3339)))
+	apps := client.GetClientset().AppsV1()
+	var err error
+	switch kind {
+	case "Deployment":
+		_, err = apps.Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case "StatefulSet":
+		_, err = apps.StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case "DaemonSet":
+		_, err = apps.DaemonSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	default:
+		return fmt.Errorf("cannot restart %s %s/%s", kind, namespace, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restart %s %s/%s: %w", kind, namespace, name, err)
+	}
+	return nil
+}
+
+// waitForRollout waits until every replica of a workload runs the latest template and is ready
+func waitForRollout(ctx context.Context, client *k8s.Client, kind, namespace, name string) error {
+	apps := client.GetClientset().AppsV1()
+	err := k8s.Poll(ctx, 5*time.Second, waitTimeout(ctx, defaultWaitTimeout), func(ctx context.Context) (bool, error) {
+		switch kind {
+		case "Deployment":
+			d, err := apps.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return deploymentRolledOut(d), nil
+		case "StatefulSet":
+			s, err := apps.StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			replicas := int32(1)
+			if s.Spec.Replicas != nil {
+				replicas = *s.Spec.Replicas
+			}
+			return s.Status.ObservedGeneration >= s.Generation && s.Status.UpdatedReplicas == replicas && s.Status.ReadyReplicas == replicas, nil
+		default:
+			d, err := apps.DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return d.Status.ObservedGeneration >= d.Generation && d.Status.UpdatedNumberScheduled == d.Status.DesiredNumberScheduled &&
+				d.Status.NumberReady == d.Status.DesiredNumberScheduled, nil
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%s %s/%s did not roll out: %w", kind, namespace, name, err)
+	}
+	return nil
+}
+
+func deploymentRolledOut(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation && d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas && d.Status.ReadyReplicas == replicas
+}
+
+// generateCAMaterial creates a root CA and the intermediate istiod signs workload certificates with
+func generateCAMaterial() (*caMaterial, error) {
+	rootKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate root key: %w", err)
+	}
+	now := time.Now()
+	rootTemplate := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"Istio"}, CommonName: "Root CA"},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(rootCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if rootTemplate.SerialNumber, err = rand.Int(rand.Reader, big.NewInt(1<<62)); err != nil {
+		return nil, fmt.Errorf("failed to generate certificate serial: %w", err)
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create root certificate: %w", err)
+	}
+	rootCert, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 4096)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate intermediate key: %w", err)
+	}
+	template := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"Istio"}, CommonName: "Intermediate CA"},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(intermediateCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	if template.SerialNumber, err = rand.Int(rand.Reader, big.NewInt(1<<62)); err != nil {
+		return nil, fmt.Errorf("failed to generate certificate serial: %w", err)
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, rootCert, &key.PublicKey, rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create intermediate certificate: %w", err)
+	}
+
+	root := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER})
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	return &caMaterial{
+		root:      root,
+		cert:      cert,
+		key:       pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		certChain: append(append([]byte{}, cert...), root...),
+	}, nil
+}
+
+// caMaterialFromFiles builds the CA from the root-cert.pem, cert-chain.pem and key.pem of CACERTS_DIR
+func caMaterialFromFiles(data map[string][]byte) (*caMaterial, error) {
+	chain := splitPEM(data["cert-chain.pem"])
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("cert-chain.pem holds no certificate")
+	}
+	roots := splitPEM(data["root-cert.pem"])
+	if len(roots) != 1 {
+		return nil, fmt.Errorf("root-cert.pem must hold exactly the new root, found %d certificates", len(roots))
+	}
+	return &caMaterial{
+		root:      roots[0],
+		cert:      chain[0],
+		key:       data["key.pem"],
+		certChain: data["cert-chain.pem"],
+	}, nil
+}
+
+// writeCACertsToDir stores the CA in CACERTS_DIR, which the bootstrap reads when a cluster has no cacerts
+func (o *Orchestrator) writeCACertsToDir(m *caMaterial) error {
+	dir := envOrDefault("CACERTS_DIR", filepath.Join(o.projectRoot, "cacerts"))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	files := map[string][]byte{
+		"root-cert.pem":  m.root,
+		"ca-cert.pem":    m.cert,
+		"cert-chain.pem": m.certChain,
+		"key.pem":        m.key,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if old, err := os.ReadFile(path); err == nil {
+			if err := os.WriteFile(path+".old", old, 0o600); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", path, err)
+			}
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	log.Info("Saved the new CA", "dir", dir)
+	return nil
+}
+
+// splitPEM returns each PEM block of a bundle re-encoded on its own
+func splitPEM(bundle []byte) [][]byte {
+	var blocks [][]byte
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			return blocks
+		}
+		blocks = append(blocks, pem.EncodeToMemory(block))
+	}
+}
+
+func containsPEM(blocks [][]byte, block []byte) bool {
+	for _, b := range blocks {
+		if bytes.Equal(b, block) {
+			return true
+		}
+	}
+	return false
+}
+
+func copyData(data map[string][]byte) map[string][]byte {
+	copied := make(map[string][]byte, len(data))
+	for k, v := range data {
+		copied[k] = v
+	}
+	return copied
+}
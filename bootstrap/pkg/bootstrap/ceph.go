@@ -0,0 +1,50 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/ceph"
+	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
+)
+
+// withCephHealthStep gates the end of the bootstrap on the Ceph health when the cluster stores on Ceph
+func (o *Orchestrator) withCephHealthStep(steps []BootstrapStep) []BootstrapStep {
+	if o.storageProvider() != "ceph" {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "verify-ceph-health",
+		Description: "Wait for Ceph to report HEALTH_OK, failing on HEALTH_ERR",
+		Required:    true,
+		Execute:     o.verifyCephHealth,
+		Namespaces:  []string{ceph.Namespace},
+	}, "wait-infrastructure", "garbage-collect")
+}
+
+// verifyCephHealth waits for HEALTH_OK. A cluster still warning afterwards only logs its checks,
+// one in HEALTH_ERR fails the step.
+func (o *Orchestrator) verifyCephHealth(ctx context.Context) error {
+	log.Info("Verifying Ceph health")
+
+	status, err := ceph.NewClient(o.k8sClient).WaitForHealthy(ctx, waitTimeout(ctx, infra.DefaultTimeouts().Ceph))
+	if status == nil {
+		return err
+	}
+	if status.Healthy() {
+		log.Info("Ceph is healthy", "osds_up", status.OSDsUp, "osds_in", status.OSDsIn, "osds", status.OSDs,
+			"pgs_clean", status.CleanPGs(), "pgs", status.PGs, "used", fmt.Sprintf("%.1f%%", status.UsedRatio()*100))
+		return nil
+	}
+
+	for _, check := range status.Checks {
+		log.Warn("Ceph health check", "check", check.Name, "severity", check.Severity, "message", check.Message)
+	}
+	if status.Health == ceph.HealthErr {
+		return fmt.Errorf("ceph reports %s with %d health checks, see 'bootstrap homelab ceph status'", status.Health, len(status.Checks))
+	}
+	log.Warn("Ceph is not healthy, continuing", "health", status.Health, "source", status.Source)
+	return nil
+}
@@ -0,0 +1,39 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/certmanager"
+)
+
+// CertManager returns a client for cert-manager on the active cluster
+func (o *Orchestrator) CertManager() *certmanager.Client {
+	return certmanager.NewClient(o.k8sClient)
+}
+
+// validateCertManager waits for the cert-manager webhook and checks every ClusterIssuer is ready
+func (o *Orchestrator) validateCertManager(ctx context.Context) error {
+	client := o.CertManager()
+	installed, err := client.Installed(ctx)
+	if err != nil {
+		return err
+	}
+	if !installed {
+		log.Info("cert-manager is not installed, skipping")
+		return nil
+	}
+
+	if err := client.WaitForWebhook(ctx, waitTimeout(ctx, defaultWaitTimeout)); err != nil {
+		return err
+	}
+	issuers, err := client.ValidateIssuers(ctx)
+	for _, issuer := range issuers {
+		log.Info("ClusterIssuer", "name", issuer.Name, "type", issuer.Type, "ready", issuer.Ready)
+	}
+	if err != nil {
+		return fmt.Errorf("cert-manager cannot issue certificates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/channels"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// Outdated compares the platform components of the local cluster and, when reachable, its peer with their release channels
+func (o *Orchestrator) Outdated(ctx context.Context, includePeer bool) ([]channels.Status, error) {
+	local := o.channelsConfig(o.localClusterName())
+	if err := channels.Validate(local); err != nil {
+		return nil, err
+	}
+
+	checker := channels.NewChecker(channels.NewReleaseIndex(o.lookupEnvValue("GITHUB_TOKEN")))
+	statuses := checker.Check(ctx, o.localClusterName(), o.k8sClient, local)
+
+	if includePeer {
+		peer, err := o.buildPeerClient()
+		if err != nil {
+			log.Warn("Peer cluster not reachable, checking local components only", "cluster", o.peerClusterName(), "error", err)
+			return statuses, nil
+		}
+		peerChannels := o.channelsConfig(o.peerClusterName())
+		if err := channels.Validate(peerChannels); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, checker.Check(ctx, o.peerClusterName(), peer, peerChannels)...)
+	}
+	return statuses, nil
+}
+
+// channelsConfig returns the channels of a cluster, empty (all stable) when its config is not loaded
+func (o *Orchestrator) channelsConfig(cluster string) config.ChannelsConfig {
+	switch {
+	case cluster == "nas" && o.config.NAS != nil:
+		return o.config.NAS.Channels
+	case cluster == "homelab" && o.config.Homelab != nil:
+		return o.config.Homelab.Channels
+	}
+	return config.ChannelsConfig{}
+}
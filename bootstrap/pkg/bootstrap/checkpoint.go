@@ -0,0 +1,114 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// checkpointDir holds the step state of unfinished bootstraps, relative to the project root
+const checkpointDir = ".bootstrap/state"
+
+// Checkpoint is the step state of a bootstrap, saved after every step so an interrupted run can resume
+type Checkpoint struct {
+	Cluster string `json:"cluster"`
+	// Completed lists the steps that need not run again, optional steps that failed included
+	Completed []string  `json:"completed"`
+	Failed    string    `json:"failed,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (o *Orchestrator) checkpointPath() string {
+	return filepath.Join(o.projectRoot, checkpointDir, o.getClusterType()+".json")
+}
+
+// LoadCheckpoint returns the saved state of the last unfinished bootstrap, nil when there is none
+func (o *Orchestrator) LoadCheckpoint() (*Checkpoint, error) {
+	data, err := os.ReadFile(o.checkpointPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read bootstrap checkpoint: %w", err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse bootstrap checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+func (o *Orchestrator) saveCheckpoint(checkpoint *Checkpoint) {
+	checkpoint.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(o.checkpointPath()), 0o755); err == nil {
+			err = os.WriteFile(o.checkpointPath(), data, 0o644)
+		}
+	}
+	if err != nil {
+		log.Warn("Failed to save bootstrap checkpoint", "error", err)
+	}
+}
+
+func (o *Orchestrator) clearCheckpoint() {
+	if err := os.Remove(o.checkpointPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("Failed to remove bootstrap checkpoint", "error", err)
+	}
+}
+
+// resumeIndex returns the index of the step the bootstrap starts at, honoring --from-step and --resume
+func (o *Orchestrator) resumeIndex(steps []BootstrapStep) (int, *Checkpoint, error) {
+	checkpoint := &Checkpoint{Cluster: o.getClusterType()}
+
+	if from := o.options.FromStep; from != "" {
+		for i, step := range steps {
+			if step.Name == from {
+				checkpoint.Completed = stepNames(steps[:i])
+				return i, checkpoint, nil
+			}
+		}
+		return 0, nil, fmt.Errorf("unknown step %q, expected one of: %s", from, strings.Join(stepNames(steps), ", "))
+	}
+
+	if !o.options.Resume {
+		return 0, checkpoint, nil
+	}
+	saved, err := o.LoadCheckpoint()
+	if err != nil {
+		return 0, nil, err
+	}
+	if saved == nil {
+		log.Warn("No interrupted bootstrap to resume, starting from the first step")
+		return 0, checkpoint, nil
+	}
+
+	completed := make(map[string]bool, len(saved.Completed))
+	for _, name := range saved.Completed {
+		completed[name] = true
+	}
+	for i, step := range steps {
+		if step.Name == saved.Failed || !completed[step.Name] {
+			log.Info("Resuming interrupted bootstrap", "from_step", step.Name, "failed", saved.Failed, "saved_at", saved.UpdatedAt.Local().Format(time.RFC822))
+			checkpoint.Completed = stepNames(steps[:i])
+			return i, checkpoint, nil
+		}
+	}
+	log.Info("Every step completed in the previous run, starting from the first step")
+	return 0, checkpoint, nil
+}
+
+func stepNames(steps []BootstrapStep) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	return names
+}
@@ -0,0 +1,40 @@
+package bootstrap
+
+import (
+	"github.com/fredericrous/homelab/bootstrap/pkg/discovery"
+)
+
+// ClusterOverride replaces the discovered kubeconfig and context of a cluster
+type ClusterOverride struct {
+	Cluster    string
+	Kubeconfig string
+	Context    string
+}
+
+// localOverride pins the local cluster to the connection the orchestrator was created with
+func (o *Orchestrator) localOverride() ClusterOverride {
+	return ClusterOverride{
+		Cluster:    o.localClusterName(),
+		Kubeconfig: o.kubeconfigPath,
+		Context:    o.kubeContext,
+	}
+}
+
+func applyClusterOverrides(contexts map[string]*discovery.ClusterInfo, overrides []ClusterOverride) {
+	for _, override := range overrides {
+		if override.Kubeconfig == "" && override.Context == "" {
+			continue
+		}
+		info := contexts[override.Cluster]
+		if info == nil {
+			info = &discovery.ClusterInfo{Name: override.Cluster, IsNAS: override.Cluster == "nas"}
+			contexts[override.Cluster] = info
+		}
+		if override.Kubeconfig != "" {
+			info.Kubeconfig = override.Kubeconfig
+		}
+		if override.Context != "" {
+			info.Context = override.Context
+		}
+	}
+}
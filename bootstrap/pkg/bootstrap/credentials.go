@@ -0,0 +1,94 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/credentials"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CredentialStatus aggregates every token and certificate the tool manages with their expiry
+func (o *Orchestrator) CredentialStatus(ctx context.Context) []credentials.Credential {
+	found := []credentials.Credential{
+		credentials.GitHubToken(ctx, o.lookupEnvValue("GITHUB_TOKEN")),
+	}
+
+	clients := map[string]*k8s.Client{o.localClusterName(): o.k8sClient}
+	kubeconfigs := map[string]string{o.localClusterName(): o.kubeconfigPath}
+	if peer, err := o.buildPeerClient(); err != nil {
+		log.Warn("Peer cluster not reachable, its credentials are skipped", "cluster", o.peerClusterName(), "error", err)
+	} else {
+		clients[o.peerClusterName()] = peer
+	}
+	if path := o.peerKubeconfigPath(); path != "" {
+		kubeconfigs[o.peerClusterName()] = path
+	}
+
+	if homelab, ok := clients["homelab"]; ok {
+		found = append(found, o.transitTokenCredential(ctx, homelab))
+	}
+
+	for _, info := range o.RemoteSecretStatus(ctx) {
+		credential := credentials.Credential{
+			Name:      info.Name,
+			Kind:      "remote-secret-token",
+			Cluster:   info.InstalledIn,
+			ExpiresAt: info.ExpiresAt,
+			Error:     info.Error,
+			Hint:      "run 'bootstrap mesh sync' to reissue the token",
+		}
+		if credential.Error == "" && !info.Present {
+			credential.Error = "secret not found"
+		} else if credential.Error == "" && !info.Tracked {
+			credential.Error = "token expiry not tracked, reissue it with 'bootstrap mesh sync --force'"
+		}
+		found = append(found, credential)
+	}
+
+	for cluster, path := range kubeconfigs {
+		hint := "regenerate it with 'talosctl kubeconfig'"
+		if cluster == "nas" {
+			hint = "copy a fresh /etc/rancher/k3s/k3s.yaml from the NAS"
+		}
+		found = append(found, credentials.KubeconfigCertificates(path, cluster, hint)...)
+	}
+
+	for cluster, client := range clients {
+		found = append(found,
+			credentials.SecretCertificate(ctx, client, cluster, istioNamespace, eastWestGatewayTLSSecretName, corev1.TLSCertKey,
+				"eastwest-tls", "delete the secret and re-run bootstrap to reissue the east-west gateway certificate"),
+			credentials.SecretCertificate(ctx, client, cluster, istioNamespace, "cacerts", "ca-cert.pem",
+				"mesh-ca", "rotate the mesh intermediate CA from the root in cacerts/"),
+		)
+
+		certificates, err := credentials.CertManagerCertificates(ctx, client, cluster)
+		if err != nil {
+			log.Warn("Failed to list certificates", "cluster", cluster, "error", err)
+			continue
+		}
+		found = append(found, certificates...)
+	}
+
+	credentials.Sort(found)
+	return found
+}
+
+// transitTokenCredential checks the homelab auto-unseal token against the NAS Vault
+func (o *Orchestrator) transitTokenCredential(ctx context.Context, homelab *k8s.Client) credentials.Credential {
+	address := o.lookupEnvValue("NAS_VAULT_ADDR")
+	if address == "" && o.config.NAS != nil {
+		address = o.config.NAS.Security.Vault.Address
+	}
+
+	token := o.lookupEnvValue("VAULT_TRANSIT_TOKEN")
+	if secret, err := homelab.GetSecret(ctx, "vault", "vault-transit-token"); err == nil {
+		token = string(secret.Data["vault_transit_token"])
+	}
+
+	credential := credentials.VaultToken(ctx, "vault/vault-transit-token", address, token,
+		"run 'bootstrap homelab vault rotate-transit-token' to mint a new transit token")
+	credential.Cluster = "homelab"
+	return credential
+}
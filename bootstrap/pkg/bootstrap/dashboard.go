@@ -0,0 +1,25 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/dashboard"
+)
+
+// DashboardWatchers creates a status watcher for the homelab and NAS clusters, in that order
+func DashboardWatchers(ctx context.Context, overrides ...ClusterOverride) ([]*dashboard.Watcher, error) {
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	homelabClient, nasClient, err := clusterPairClients(ctx, projectRoot, overrides...)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*dashboard.Watcher{
+		dashboard.NewWatcher("homelab", homelabClient),
+		dashboard.NewWatcher("nas", nasClient),
+	}, nil
+}
@@ -0,0 +1,51 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/observability"
+)
+
+// monitoringConfig returns the monitoring section of the active cluster configuration
+func (o *Orchestrator) monitoringConfig() *config.MonitoringConfig {
+	if o.isNAS && o.config.NAS != nil {
+		return &o.config.NAS.Monitoring
+	}
+	if !o.isNAS && o.config.Homelab != nil {
+		return &o.config.Homelab.Monitoring
+	}
+	return nil
+}
+
+// DashboardSelection returns the dashboards listed in monitoring.grafana.dashboards, empty meaning all
+func (o *Orchestrator) DashboardSelection() []string {
+	if settings := o.monitoringConfig(); settings != nil {
+		return settings.Grafana.Dashboards
+	}
+	return nil
+}
+
+// ProvisionDashboards writes the curated Grafana dashboards into the GitOps repository
+func (o *Orchestrator) ProvisionDashboards(version string, selected []string) ([]string, error) {
+	return observability.NewDashboardGenerator(o.projectRoot, o.localClusterName(), version, selected).Generate()
+}
+
+// pushStepMetrics sends the step durations to the configured Pushgateway for the bootstrap-steps dashboard
+func (o *Orchestrator) pushStepMetrics(ctx context.Context, metrics []stepMetric) {
+	settings := o.monitoringConfig()
+	if settings == nil || settings.Prometheus.Pushgateway == "" || len(metrics) == 0 {
+		return
+	}
+
+	steps := make([]observability.StepMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		steps = append(steps, observability.StepMetric{Step: metric.name, Duration: metric.duration, Success: metric.success})
+	}
+	if err := observability.PushStepMetrics(ctx, settings.Prometheus.Pushgateway, o.localClusterName(), steps); err != nil {
+		log.Warn("Failed to push bootstrap metrics", "pushgateway", settings.Prometheus.Pushgateway, "error", err)
+		return
+	}
+	log.Debug("Pushed bootstrap metrics", "pushgateway", settings.Prometheus.Pushgateway, "steps", len(steps))
+}
@@ -0,0 +1,54 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/diff"
+	"github.com/fredericrous/homelab/bootstrap/pkg/discovery"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+)
+
+// DiffClusters compares the homelab and NAS clusters for the requested scopes.
+func DiffClusters(ctx context.Context, scopes []diff.Scope, overrides ...ClusterOverride) (*diff.Report, error) {
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	homelabClient, nasClient, err := clusterPairClients(ctx, projectRoot, overrides...)
+	if err != nil {
+		return nil, err
+	}
+
+	return diff.NewClusterDiffer(homelabClient, nasClient).Diff(ctx, scopes)
+}
+
+func clusterPairClients(ctx context.Context, projectRoot string, overrides ...ClusterOverride) (*k8s.Client, *k8s.Client, error) {
+	discoveryService := discovery.NewClusterDiscovery(projectRoot)
+	contexts, err := discoveryService.ListContexts(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list kube contexts: %w", err)
+	}
+	applyClusterOverrides(contexts, overrides)
+
+	homelabInfo, ok := contexts["homelab"]
+	if !ok {
+		return nil, nil, fmt.Errorf("homelab context not found; run bootstrap homelab install first")
+	}
+	nasInfo, ok := contexts["nas"]
+	if !ok {
+		return nil, nil, fmt.Errorf("nas context not found; run bootstrap nas install first")
+	}
+
+	homelabClient, err := k8s.NewClientWithContext(homelabInfo.Kubeconfig, homelabInfo.Context)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build homelab Kubernetes client: %w", err)
+	}
+	nasClient, err := k8s.NewClientWithContext(nasInfo.Kubeconfig, nasInfo.Context)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build NAS Kubernetes client: %w", err)
+	}
+
+	return homelabClient, nasClient, nil
+}
@@ -0,0 +1,394 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/credentials"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/prereq"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// clusterSkewWarning is the clock offset between clusters worth fixing before tokens are exchanged
+	clusterSkewWarning = 10 * time.Second
+	// clusterSkewFailure exceeds the leeway the API servers give service account tokens minted by the other cluster
+	clusterSkewFailure = time.Minute
+	// diskFreeWarning and diskFreeFailure are the free space ratios below which a node filesystem is reported
+	diskFreeWarning = 0.15
+	diskFreeFailure = 0.05
+	// credentialWarning flags credentials expiring before the next bootstrap is likely
+	credentialWarning = 14 * 24 * time.Hour
+	dnsTimeout        = 5 * time.Second
+)
+
+// Exit codes of bootstrap doctor, by the worst severity found
+const (
+	DoctorHealthy  = 0
+	DoctorWarnings = 1
+	DoctorFailures = 2
+)
+
+// DoctorSection groups the doctor checks of one area
+type DoctorSection struct {
+	Name    string               `json:"name"`
+	Results []prereq.CheckResult `json:"results"`
+}
+
+// DoctorReport is the outcome of bootstrap doctor
+type DoctorReport struct {
+	Cluster  string          `json:"cluster"`
+	Passed   int             `json:"passed"`
+	Warnings int             `json:"warnings"`
+	Failed   int             `json:"failed"`
+	Sections []DoctorSection `json:"sections"`
+}
+
+// NewDoctorReport creates an empty report for cluster
+func NewDoctorReport(cluster string) *DoctorReport {
+	return &DoctorReport{Cluster: cluster}
+}
+
+// Add records the results of a section and counts their outcomes
+func (r *DoctorReport) Add(section string, results ...prereq.CheckResult) {
+	r.Sections = append(r.Sections, DoctorSection{Name: section, Results: results})
+	for _, result := range results {
+		switch result.Status {
+		case prereq.CheckPassed:
+			r.Passed++
+		case prereq.CheckWarning:
+			r.Warnings++
+		case prereq.CheckFailed:
+			r.Failed++
+		}
+	}
+}
+
+// ExitCode returns DoctorFailures when a check failed, DoctorWarnings when one warned, DoctorHealthy otherwise
+func (r *DoctorReport) ExitCode() int {
+	switch {
+	case r.Failed > 0:
+		return DoctorFailures
+	case r.Warnings > 0:
+		return DoctorWarnings
+	default:
+		return DoctorHealthy
+	}
+}
+
+// Doctor adds the checks needing a cluster client to report: peer reachability, DNS, clock skew
+// between clusters, node disk space and credential validity
+func (o *Orchestrator) Doctor(ctx context.Context, report *DoctorReport) {
+	clients := map[string]*k8s.Client{o.localClusterName(): o.k8sClient}
+	peerResult, peer := o.doctorPeer(ctx)
+	report.Add("clusters", peerResult)
+	if peer != nil {
+		clients[o.peerClusterName()] = peer
+	}
+
+	report.Add("dns", o.doctorDNS(ctx, clients)...)
+	report.Add("clock", o.doctorClock(ctx, clients)...)
+	report.Add("disk", doctorDisk(ctx, clients)...)
+	report.Add("credentials", o.doctorCredentials(ctx)...)
+}
+
+// doctorPeer checks the kubeconfig of the peer cluster reaches its API server
+func (o *Orchestrator) doctorPeer(ctx context.Context) (prereq.CheckResult, *k8s.Client) {
+	name := o.peerClusterName()
+	result := prereq.CheckResult{Name: "peer-cluster", Description: fmt.Sprintf("Peer cluster %s reachable", name)}
+	path := o.peerKubeconfigPath()
+	if path == "" {
+		result.Status = prereq.CheckWarning
+		result.Error = fmt.Errorf("no kubeconfig found for %s", name)
+		result.Details = fmt.Sprintf("Set %s_KUBECONFIG_PATH or bootstrap %s first, the mesh and cross-cluster checks need it", config.EnvKeyPrefix(name), name)
+		return result, nil
+	}
+	client, err := k8s.NewClient(path)
+	if err != nil {
+		result.Status = prereq.CheckFailed
+		result.Error = err
+		result.Details = "Fix or regenerate " + path
+		return result, nil
+	}
+	version, err := client.GetClientset().Discovery().ServerVersion()
+	if err != nil {
+		result.Status = prereq.CheckFailed
+		result.Error = fmt.Errorf("API server not reachable: %w", err)
+		result.Details = fmt.Sprintf("Check %s is up and %s points at it", name, path)
+		return result, nil
+	}
+	result.Status = prereq.CheckPassed
+	result.Details = fmt.Sprintf("%s at %s", version.GitVersion, client.GetConfig().Host)
+	return result, client
+}
+
+// doctorDNS resolves the hosts bootstrap talks to: API servers, Vault and GitHub
+func (o *Orchestrator) doctorDNS(ctx context.Context, clients map[string]*k8s.Client) []prereq.CheckResult {
+	hosts := []string{"github.com"}
+	for _, client := range clients {
+		hosts = append(hosts, hostOf(client.GetConfig().Host))
+	}
+	if o.config.Homelab != nil {
+		hosts = append(hosts, hostOf(o.config.Homelab.Integration.Vault.Address))
+	}
+	if o.config.NAS != nil {
+		hosts = append(hosts, hostOf(o.config.NAS.Security.Vault.Address))
+	}
+
+	var results []prereq.CheckResult
+	seen := map[string]bool{}
+	for _, host := range hosts {
+		if host == "" || seen[host] || net.ParseIP(host) != nil {
+			continue
+		}
+		seen[host] = true
+
+		result := prereq.CheckResult{Name: "dns-" + host, Description: "DNS resolution of " + host}
+		lookupCtx, cancel := context.WithTimeout(ctx, dnsTimeout)
+		addresses, err := net.DefaultResolver.LookupHost(lookupCtx, host)
+		cancel()
+		if err != nil {
+			result.Status = prereq.CheckFailed
+			result.Error = err
+			result.Details = "Check the resolver of this workstation or add a record for " + host
+		} else {
+			result.Status = prereq.CheckPassed
+			result.Details = fmt.Sprint(addresses)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// doctorClock compares the clocks of the clusters through the Date header of their API servers
+func (o *Orchestrator) doctorClock(ctx context.Context, clients map[string]*k8s.Client) []prereq.CheckResult {
+	names := make([]string, 0, len(clients))
+	for name := range clients {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	skews := map[string]time.Duration{}
+	var results []prereq.CheckResult
+	for _, name := range names {
+		skew, err := prereq.NewConnectivityChecker(clients[name].GetConfig()).ClockSkew(ctx)
+		if err != nil {
+			results = append(results, prereq.CheckResult{
+				Name:        "clock-" + name,
+				Description: "Clock of " + name,
+				Status:      prereq.CheckWarning,
+				Error:       err,
+			})
+			continue
+		}
+		skews[name] = skew
+	}
+	if len(skews) < 2 {
+		if len(results) == 0 {
+			results = append(results, prereq.CheckResult{
+				Name:        "clock-skew",
+				Description: "Clock skew between clusters",
+				Status:      prereq.CheckPassed,
+				Details:     "Only one cluster reachable, nothing to compare",
+			})
+		}
+		return results
+	}
+
+	local, peer := o.localClusterName(), o.peerClusterName()
+	// Each skew is the workstation ahead of a cluster, so their difference is the peer ahead of the local cluster
+	offset := skews[local] - skews[peer]
+	if offset < 0 {
+		offset = -offset
+	}
+	result := prereq.CheckResult{
+		Name:        "clock-skew",
+		Description: fmt.Sprintf("Clock skew between %s and %s", local, peer),
+		Status:      prereq.CheckPassed,
+		Details:     fmt.Sprintf("%s apart", offset),
+	}
+	switch {
+	case offset > clusterSkewFailure:
+		result.Status = prereq.CheckFailed
+		result.Error = fmt.Errorf("clocks are %s apart, tokens minted by one cluster are rejected by the other", offset)
+		result.Details = "Sync NTP on the nodes: check 'talosctl time' on homelab and timedatectl on the NAS"
+	case offset > clusterSkewWarning:
+		result.Status = prereq.CheckWarning
+		result.Error = fmt.Errorf("clocks are %s apart", offset)
+		result.Details = "Sync NTP on the nodes: check 'talosctl time' on homelab and timedatectl on the NAS"
+	}
+	return append(results, result)
+}
+
+// doctorDisk reports node filesystems running out of space, from the kubelet stats or the DiskPressure condition
+func doctorDisk(ctx context.Context, clients map[string]*k8s.Client) []prereq.CheckResult {
+	var results []prereq.CheckResult
+	for cluster, client := range clients {
+		nodes, err := client.GetClientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			results = append(results, prereq.CheckResult{
+				Name:        "disk-" + cluster,
+				Description: "Node disk space on " + cluster,
+				Status:      prereq.CheckWarning,
+				Error:       fmt.Errorf("failed to list nodes: %w", err),
+			})
+			continue
+		}
+		for _, node := range nodes.Items {
+			results = append(results, nodeDiskResult(ctx, client, cluster, &node))
+		}
+	}
+	slices.SortFunc(results, func(a, b prereq.CheckResult) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return results
+}
+
+func nodeDiskResult(ctx context.Context, client *k8s.Client, cluster string, node *corev1.Node) prereq.CheckResult {
+	result := prereq.CheckResult{
+		Name:        "disk-" + cluster + "-" + node.Name,
+		Description: fmt.Sprintf("Disk space on %s/%s", cluster, node.Name),
+		Status:      prereq.CheckPassed,
+	}
+	hint := "Free space on the node: prune unused images, old logs or resize its disk"
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeDiskPressure && condition.Status == corev1.ConditionTrue {
+			result.Status = prereq.CheckFailed
+			result.Error = fmt.Errorf("node reports DiskPressure: %s", condition.Message)
+			result.Details = hint
+			return result
+		}
+	}
+
+	root, images, err := client.NodeFilesystems(ctx, node.Name)
+	if err != nil {
+		result.Details = "No DiskPressure, kubelet stats unavailable"
+		return result
+	}
+	worst, label := freeRatio(root), "root"
+	if ratio := freeRatio(images); images.CapacityBytes > 0 && ratio < worst {
+		worst, label = ratio, "image"
+	}
+	result.Details = fmt.Sprintf("%s free on the root filesystem", humanBytes(root.AvailableBytes))
+	switch {
+	case worst < diskFreeFailure:
+		result.Status = prereq.CheckFailed
+		result.Error = fmt.Errorf("%s filesystem %.0f%% free", label, worst*100)
+		result.Details = hint
+	case worst < diskFreeWarning:
+		result.Status = prereq.CheckWarning
+		result.Error = fmt.Errorf("%s filesystem %.0f%% free", label, worst*100)
+		result.Details = hint
+	}
+	return result
+}
+
+// doctorCredentials turns the credential status into checks, adding the VAULT_TOKEN TTL and GitHub token scopes
+func (o *Orchestrator) doctorCredentials(ctx context.Context) []prereq.CheckResult {
+	found := o.CredentialStatus(ctx)
+	if address, token := o.vaultAddress(), o.lookupEnvValue("VAULT_TOKEN"); address != "" && token != "" {
+		found = append(found, credentials.VaultToken(ctx, "VAULT_TOKEN", address, token,
+			"create a new token on the NAS Vault and update VAULT_TOKEN"))
+	}
+
+	results := make([]prereq.CheckResult, 0, len(found))
+	for _, credential := range found {
+		description := credential.Name
+		if credential.Cluster != "" {
+			description = credential.Cluster + "/" + credential.Name
+		}
+		result := prereq.CheckResult{Name: "credential-" + credential.Kind, Description: description, Status: prereq.CheckPassed}
+
+		switch credential.State(credentialWarning) {
+		case credentials.StateExpired:
+			result.Status = prereq.CheckFailed
+			result.Error = fmt.Errorf("expired")
+			if credential.Error != "" {
+				result.Error = fmt.Errorf("%s", credential.Error)
+			}
+			result.Details = credential.Hint
+		case credentials.StateExpiring:
+			result.Status = prereq.CheckWarning
+			result.Error = fmt.Errorf("expires in %s", credential.Remaining().Round(time.Hour))
+			result.Details = credential.Hint
+		case credentials.StateUnknown:
+			result.Status = prereq.CheckWarning
+			result.Error = fmt.Errorf("%s", credential.Error)
+			result.Details = credential.Hint
+		case credentials.StateNoExpiry:
+			result.Details = "never expires"
+		default:
+			result.Details = fmt.Sprintf("expires in %s", credential.Remaining().Round(time.Hour))
+		}
+
+		if credential.Kind == "github-token" && result.Status == prereq.CheckPassed {
+			switch {
+			case credential.Scopes == nil:
+				result.Details += ", fine-grained token: make sure it has read/write Contents and Administration on the GitOps repository"
+			case !slices.Contains(credential.Scopes, "repo"):
+				result.Status = prereq.CheckFailed
+				result.Error = fmt.Errorf("token scopes %v lack repo", credential.Scopes)
+				result.Details = "Flux bootstrap pushes manifests and a deploy key, create a token with the repo scope"
+			default:
+				result.Details += fmt.Sprintf(", scopes %v", credential.Scopes)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// vaultAddress returns the NAS Vault address bootstrap authenticates to with VAULT_TOKEN
+func (o *Orchestrator) vaultAddress() string {
+	if o.config.Homelab != nil && o.config.Homelab.Integration.Vault.Address != "" {
+		return o.config.Homelab.Integration.Vault.Address
+	}
+	if o.config.NAS != nil {
+		return o.config.NAS.Security.Vault.Address
+	}
+	return ""
+}
+
+// hostOf returns the host name of an address with or without scheme
+func hostOf(address string) string {
+	if address == "" {
+		return ""
+	}
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Host == "" {
+		parsed, err = url.Parse("//" + address)
+		if err != nil {
+			return ""
+		}
+	}
+	return parsed.Hostname()
+}
+
+func freeRatio(stats k8s.FilesystemStats) float64 {
+	if stats.CapacityBytes == 0 {
+		return 1
+	}
+	return float64(stats.AvailableBytes) / float64(stats.CapacityBytes)
+}
+
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for value := n / unit; value >= unit; value /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/drift"
+	"github.com/fredericrous/homelab/bootstrap/pkg/notify"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Drift compares everything the bootstrap applied on the local cluster with what it renders from the current config
+func (o *Orchestrator) Drift(ctx context.Context) (*drift.Report, error) {
+	detector, err := o.driftDetector()
+	if err != nil {
+		return nil, err
+	}
+	report, err := detector.Detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !report.Clean() {
+		details := make([]string, 0, len(report.Drifted))
+		for _, object := range report.Drifted {
+			details = append(details, fmt.Sprintf("%s: %s (%d fields)", object.Source, object.Object, len(object.Changes)))
+		}
+		o.notify(ctx, notify.Event{Type: notify.DriftDetected, Message: "Managed objects drifted from their rendered state", Details: details})
+	}
+	return report, nil
+}
+
+// FixDrift re-applies the rendered state of the drifted objects of report
+func (o *Orchestrator) FixDrift(ctx context.Context, report *drift.Report) error {
+	detector, err := o.driftDetector()
+	if err != nil {
+		return err
+	}
+	return detector.Fix(ctx, report)
+}
+
+// driftDetector renders the Flux install, the Flux sync objects and cluster-vars the way the bootstrap applies them
+func (o *Orchestrator) driftDetector() (*drift.Detector, error) {
+	fluxClient, err := o.newFluxClient()
+	if err != nil {
+		return nil, err
+	}
+	clusterType := "homelab"
+	if o.isNAS {
+		clusterType = "nas"
+	}
+
+	return drift.NewDetector(o.k8sClient,
+		drift.Source{
+			Name: "flux-install",
+			Render: func(ctx context.Context) ([]*unstructured.Unstructured, error) {
+				return fluxClient.InstallObjects("flux-system")
+			},
+		},
+		drift.Source{
+			Name: "flux-sync",
+			Render: func(ctx context.Context) ([]*unstructured.Unstructured, error) {
+				return fluxClient.SyncObjects("flux-system", clusterType)
+			},
+		},
+		drift.Source{
+			Name:   "cluster-vars",
+			Render: o.clusterVarsObjects,
+			// An update replaces the secret, dropping the keys no longer in the .env files
+			Reapply: func(ctx context.Context) error {
+				return o.secretsManager.CreateClusterVarsSecret(ctx, "flux-system")
+			},
+		},
+	), nil
+}
+
+// clusterVarsObjects renders the cluster-vars secret, nothing when no variable is set
+func (o *Orchestrator) clusterVarsObjects(ctx context.Context) ([]*unstructured.Unstructured, error) {
+	secret, err := o.secretsManager.ClusterVarsSecret("flux-system")
+	if err != nil || secret == nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert cluster-vars secret: %w", err)
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	return []*unstructured.Unstructured{obj}, nil
+}
@@ -0,0 +1,217 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	"github.com/fredericrous/homelab/bootstrap/pkg/logger"
+	"github.com/fredericrous/homelab/bootstrap/pkg/notify"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
+)
+
+// StepDriver runs the bootstrap one step at a time, so a caller such as the TUI can retry a failed step, skip
+// an optional one or pause between steps. Bootstrap drives it straight through.
+type StepDriver struct {
+	o          *Orchestrator
+	steps      []BootstrapStep
+	next       int
+	checkpoint *Checkpoint
+	rollbacks  []func(context.Context) error
+	// rolledBack names the steps of rollbacks, in the order they ran
+	rolledBack []string
+	metrics    []stepMetric
+	run        *history.Run
+	started    time.Time
+	// lastErr is the error of the last attempt at the next step
+	lastErr error
+}
+
+// NewStepDriver prepares a bootstrap, starting at the step --from-step or --resume select
+func (o *Orchestrator) NewStepDriver(ctx context.Context) (*StepDriver, error) {
+	log.Info("Starting bootstrap process", "type", o.getClusterType())
+
+	steps := o.getBootstrapSteps()
+	start, checkpoint, err := o.resumeIndex(steps)
+	if err != nil {
+		return nil, err
+	}
+	for i, step := range steps[:start] {
+		log.Info("Skipping step completed in a previous run", "step", i+1, "total", len(steps), "name", step.Name)
+	}
+	return &StepDriver{
+		o:          o,
+		steps:      steps,
+		next:       start,
+		checkpoint: checkpoint,
+		rollbacks:  make([]func(context.Context) error, 0, len(steps)),
+		metrics:    make([]stepMetric, 0, len(steps)),
+		run:        history.FromContext(ctx),
+		started:    time.Now(),
+	}, nil
+}
+
+// Steps describes every step of the bootstrap, the ones completed in a previous run included
+func (d *StepDriver) Steps() []StepInfo {
+	return stepInfos(d.steps)
+}
+
+// Next returns the index of the step Run executes, len(Steps()) once every step is done
+func (d *StepDriver) Next() int {
+	return d.next
+}
+
+// Done reports whether every step completed or was skipped
+func (d *StepDriver) Done() bool {
+	return d.next >= len(d.steps)
+}
+
+// Current describes the step Run executes
+func (d *StepDriver) Current() StepInfo {
+	return stepInfos(d.steps[d.next : d.next+1])[0]
+}
+
+// Run executes the next step and moves past it when it succeeds. A failed step stays next, to be run again,
+// skipped when optional, or failed for good with Fail.
+func (d *StepDriver) Run(ctx context.Context) error {
+	if d.Done() {
+		return fmt.Errorf("every bootstrap step already ran")
+	}
+	step := d.steps[d.next]
+	log.Info("Executing bootstrap step",
+		"step", d.next+1,
+		"total", len(d.steps),
+		"name", step.Name,
+		"description", step.Description)
+
+	d.o.notify(ctx, notify.Event{Type: notify.StepStarted, Step: step.Name, Message: "Bootstrap step started"})
+	event := progress.Event{Step: step.Name, Index: d.next + 1, Total: len(d.steps), Percent: -1}
+	started := event
+	started.Type, started.Message = progress.StepStarted, step.Description
+	d.o.progress.Publish(started)
+	stepCtx := progress.WithReporter(ctx, func(percent int, message string) {
+		update := event
+		update.Type, update.Percent, update.Message = progress.StepProgress, percent, message
+		d.o.progress.Publish(update)
+	})
+
+	startTime := time.Now()
+	logger.SetStep(step.Name)
+	err := d.o.runStep(stepCtx, step)
+	logger.SetStep("")
+	duration := time.Since(startTime)
+	d.metrics = append(d.metrics, stepMetric{name: step.Name, duration: duration, success: err == nil, err: err})
+	if d.run != nil {
+		d.run.AddStep(step.Name, duration, err)
+	}
+	d.lastErr = err
+	completed := event
+	completed.Type, completed.Err, completed.Duration = progress.StepCompleted, err, duration
+	if err == nil {
+		completed.Percent = 100
+	}
+	d.o.progress.Publish(completed)
+
+	if err != nil {
+		log.Error("Bootstrap step failed",
+			"step", step.Name,
+			"error", err,
+			"duration", duration)
+		d.o.emitStepMetric(step.Name, duration, false)
+		d.o.notify(ctx, notify.Event{Type: notify.StepFailed, Step: step.Name, Message: "Bootstrap step failed", Error: err.Error(), Duration: duration})
+		return err
+	}
+
+	log.Info("Bootstrap step completed",
+		"step", step.Name,
+		"completed_in", duration)
+	d.o.emitStepMetric(step.Name, duration, true)
+	d.o.notify(ctx, notify.Event{Type: notify.StepSucceeded, Step: step.Name, Message: "Bootstrap step completed", Duration: duration})
+	if step.Rollback != nil {
+		d.rollbacks = append([]func(context.Context) error{step.Rollback}, d.rollbacks...)
+		d.rolledBack = append(d.rolledBack, step.Name)
+	}
+	d.advance()
+	return nil
+}
+
+// Skip moves past the next step without running it again, which only optional steps allow. A skipped step
+// counts as completed when the bootstrap resumes.
+func (d *StepDriver) Skip() error {
+	if d.Done() {
+		return fmt.Errorf("every bootstrap step already ran")
+	}
+	step := d.Current()
+	if step.Required {
+		return fmt.Errorf("step %s is required and cannot be skipped", step.Name)
+	}
+	if d.lastErr != nil {
+		log.Warn("Optional step failed, continuing", "step", step.Name)
+		if d.run != nil {
+			d.run.AddWarning("optional step %s failed: %v", step.Name, d.lastErr)
+		}
+	} else {
+		log.Warn("Skipping optional step", "step", step.Name)
+		if d.run != nil {
+			d.run.AddWarning("optional step %s skipped", step.Name)
+		}
+	}
+	d.advance()
+	return nil
+}
+
+// Fail ends the bootstrap on the failed next step: the checkpoint records it for --resume, the completed
+// steps are rolled back and the run is reported as failed. Rolled back steps are dropped from the checkpoint
+// so --resume runs them again.
+func (d *StepDriver) Fail(ctx context.Context) error {
+	if d.Done() || d.lastErr == nil {
+		return fmt.Errorf("no failed bootstrap step to give up on")
+	}
+	step, err := d.Current(), d.lastErr
+	d.checkpoint.Failed = step.Name
+	d.checkpoint.Error = err.Error()
+	d.checkpoint.Completed = slices.DeleteFunc(d.checkpoint.Completed, func(name string) bool {
+		return slices.Contains(d.rolledBack, name)
+	})
+	d.o.saveCheckpoint(d.checkpoint)
+	resumeFrom := step.Name
+	if len(d.rolledBack) > 0 {
+		resumeFrom = d.rolledBack[0]
+	}
+	log.Info("Re-run with --resume to continue from this step", "step", resumeFrom)
+	d.o.pushStepMetrics(ctx, d.metrics)
+	d.o.runRollbacks(ctx, d.rollbacks)
+	d.o.recordRunDetails(ctx, d.run)
+	d.o.writeReport(ctx, d.run, d.started, d.metrics, step.Name, err)
+	d.o.notify(ctx, notify.Event{Type: notify.BootstrapFailed, Step: step.Name, Message: "Bootstrap failed", Error: err.Error(), Duration: time.Since(d.started)})
+	return fmt.Errorf("required step '%s' failed: %w", step.Name, err)
+}
+
+// Finish completes a bootstrap whose steps are all done
+func (d *StepDriver) Finish(ctx context.Context) error {
+	if !d.Done() {
+		return fmt.Errorf("bootstrap step %s has not run yet", d.Current().Name)
+	}
+	if d.run == nil {
+		// Recorded runs print their summary table once the command finishes
+		d.o.logBootstrapSummary(d.metrics)
+	}
+	d.o.pushStepMetrics(ctx, d.metrics)
+	d.o.recordRunDetails(ctx, d.run)
+	d.o.writeReport(ctx, d.run, d.started, d.metrics, "", nil)
+	d.o.clearCheckpoint()
+	d.o.notify(ctx, notify.Event{Type: notify.BootstrapSucceeded, Message: "Bootstrap completed", Duration: time.Since(d.started)})
+	log.Info("Bootstrap process completed successfully")
+	return nil
+}
+
+// advance records the next step as completed in the checkpoint and moves to the one after it
+func (d *StepDriver) advance() {
+	d.checkpoint.Completed = append(d.checkpoint.Completed, d.Current().Name)
+	d.o.saveCheckpoint(d.checkpoint)
+	d.next++
+	d.lastErr = nil
+}
@@ -0,0 +1,109 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/etcd"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k3s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/talos"
+)
+
+// etcdWorkDir holds snapshots while they are verified, uploaded or restored
+const etcdWorkDir = ".bootstrap/etcd"
+
+// EtcdRetention returns how many etcd snapshots the active cluster keeps
+func (o *Orchestrator) EtcdRetention() int {
+	if retention := o.config.BackupFor(o.isNAS).EtcdRetention; retention > 0 {
+		return retention
+	}
+	return etcd.DefaultRetention
+}
+
+// EtcdManager snapshots etcd through the Talos API on the homelab and k3s etcd-snapshot on the NAS, storing them in the NAS MinIO.
+// The returned function closes the tunnel to MinIO
+func (o *Orchestrator) EtcdManager(ctx context.Context) (*etcd.Manager, func(), error) {
+	source, err := o.etcdSource()
+	if err != nil {
+		return nil, nil, err
+	}
+	opts, err := o.BackupOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	endpoint, closeTunnel, err := o.minioEndpoint(ctx, opts.S3URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := etcd.NewStore(etcd.StoreOptions{
+		Endpoint:  endpoint,
+		Bucket:    opts.Bucket,
+		Prefix:    "etcd/" + opts.Cluster,
+		Region:    opts.Region,
+		AccessKey: opts.AccessKey,
+		SecretKey: opts.SecretKey,
+	})
+	if err != nil {
+		closeTunnel()
+		return nil, nil, err
+	}
+	return etcd.NewManager(opts.Cluster, source, store, filepath.Join(o.projectRoot, etcdWorkDir)), closeTunnel, nil
+}
+
+func (o *Orchestrator) etcdSource() (etcd.Source, error) {
+	if o.isNAS {
+		if o.config.NAS == nil {
+			return nil, fmt.Errorf("NAS configuration not found")
+		}
+		composeFile := filepath.Join(o.projectRoot, "infrastructure", "nas", "docker-compose.yaml")
+		return k3s.NewEtcdSnapshots(composeFile, o.config.NAS.Cluster.DockerHost, o.config.NAS.Cluster.CertPath), nil
+	}
+
+	if o.config.Homelab == nil {
+		return nil, fmt.Errorf("homelab configuration not found")
+	}
+	opts, err := talos.OptionsFromConfig(o.config.Homelab, o.projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	controlPlanes := opts.ControlPlanes()
+	if len(controlPlanes) == 0 {
+		return nil, fmt.Errorf("no Talos control plane configured")
+	}
+	return talos.NewEtcdSnapshots(opts.TalosconfigPath(), controlPlanes[0].Address), nil
+}
+
+// minioEndpoint returns a URL reaching MinIO from this machine, tunneling through the NAS cluster for cluster-local URLs
+func (o *Orchestrator) minioEndpoint(ctx context.Context, s3URL string) (string, func(), error) {
+	name, namespace, port, err := gitops.ServiceFromURL(s3URL)
+	if err != nil {
+		// Not a service URL, MinIO is reachable as is
+		return s3URL, func() {}, nil
+	}
+
+	nasClient := o.k8sClient
+	if !o.isNAS {
+		if nasClient, err = o.buildPeerClient(); err != nil {
+			return "", nil, fmt.Errorf("failed to connect to the NAS cluster to reach MinIO: %w", err)
+		}
+	}
+	servicePort, err := strconv.Atoi(port)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid MinIO port %q", port)
+	}
+	localPort, stop, err := nasClient.PortForward(ctx, namespace, name, servicePort)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to reach MinIO: %w", err)
+	}
+
+	scheme := "http"
+	if parsed, _ := url.Parse(s3URL); parsed != nil && parsed.Scheme != "" {
+		scheme = parsed.Scheme
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%d", scheme, localPort), stop, nil
+}
@@ -0,0 +1,45 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// allNamespaces streams the events of every namespace, for steps waiting on the whole platform
+var allNamespaces = []string{metav1.NamespaceAll}
+
+// Progress returns the bus the steps starting, progressing and completing and the Warning events observed
+// during steps are published on. Warnings go to the log while nothing subscribes.
+func (o *Orchestrator) Progress() *progress.Bus {
+	return o.progress
+}
+
+// streamEvents reports Warning events raised in namespaces until the returned stop function is called
+func (o *Orchestrator) streamEvents(ctx context.Context, step string, namespaces []string) func() {
+	if len(namespaces) == 0 || o.k8sClient == nil {
+		return func() {}
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	o.k8sClient.WatchWarningEvents(watchCtx, namespaces, time.Now(), func(event *corev1.Event) {
+		message := k8s.FormatEvent(event)
+		if o.progress.Publish(progress.Event{Type: progress.Warning, Step: step, Percent: -1, Message: message}) {
+			return
+		}
+		log.Warn("⚠️ Kubernetes event", "step", step, "event", message)
+	})
+	return cancel
+}
+
+// withEvents runs execute while streaming the Warning events of namespaces
+func (o *Orchestrator) withEvents(ctx context.Context, step string, namespaces []string, execute func(context.Context) error) error {
+	stop := o.streamEvents(ctx, step, namespaces)
+	defer stop()
+	return execute(ctx)
+}
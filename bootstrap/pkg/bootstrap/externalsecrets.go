@@ -0,0 +1,115 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/externalsecrets"
+)
+
+const storeReadyTimeout = 2 * time.Minute
+
+// externalSecretsConfig returns the External Secrets settings of the homelab, nil when disabled
+func (o *Orchestrator) externalSecretsConfig() *config.ExternalSecretsConfig {
+	if o.isNAS || o.config.Homelab == nil || !o.config.Homelab.Integration.ExternalSecrets.Enabled {
+		return nil
+	}
+	return &o.config.Homelab.Integration.ExternalSecrets
+}
+
+// nasVaultStore describes the ClusterSecretStore reading the NAS Vault
+func (o *Orchestrator) nasVaultStore(cfg *config.ExternalSecretsConfig) externalsecrets.VaultStore {
+	vault := o.config.Homelab.Integration.Vault
+	store := externalsecrets.VaultStore{
+		Name:    cfg.StoreName,
+		Server:  vault.Address,
+		KVMount: cfg.KVMount,
+		Token:   vault.Token,
+	}
+	if store.Name == "" {
+		store.Name = externalsecrets.DefaultStoreName
+	}
+	if store.KVMount == "" {
+		store.KVMount = externalsecrets.DefaultKVMount
+	}
+	if store.Server == "" {
+		store.Server = o.lookupEnvValue("NAS_VAULT_ADDR")
+	}
+	return store
+}
+
+// setupExternalSecrets makes sure ESO runs and can read the NAS Vault
+func (o *Orchestrator) setupExternalSecrets(ctx context.Context) error {
+	cfg := o.externalSecretsConfig()
+	if cfg == nil {
+		log.Info("External Secrets integration disabled, skipping")
+		return nil
+	}
+
+	client := externalsecrets.NewClient(o.k8sClient)
+	if err := client.Install(ctx, cfg.ChartVersion); err != nil {
+		return fmt.Errorf("failed to install External Secrets Operator: %w", err)
+	}
+
+	store := o.nasVaultStore(cfg)
+	if err := client.CreateVaultStore(ctx, store); err != nil {
+		return err
+	}
+	if err := client.WaitForStore(ctx, store.Name, storeReadyTimeout); err != nil {
+		return err
+	}
+	if err := client.Validate(ctx, store.Name); err != nil {
+		return err
+	}
+	log.Info("External Secrets Operator connected to the NAS Vault", "store", store.Name)
+	return nil
+}
+
+// MigrateClusterVarsToESO moves cluster-vars into the NAS Vault and lets an ExternalSecret maintain it, returning the migrated keys
+func (o *Orchestrator) MigrateClusterVarsToESO(ctx context.Context, dryRun bool) ([]string, error) {
+	cfg := o.externalSecretsConfig()
+	if cfg == nil {
+		return nil, fmt.Errorf("integration.external_secrets is not enabled in the homelab config")
+	}
+
+	vars, err := o.secretsManager.ClusterVars(ctx, "flux-system")
+	if err != nil {
+		return nil, err
+	}
+	if vars == nil {
+		return nil, fmt.Errorf("cluster-vars secret not found in flux-system, bootstrap the cluster first")
+	}
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	store := o.nasVaultStore(cfg)
+	migration := externalsecrets.Migration{
+		Namespace: "flux-system",
+		Name:      "cluster-vars",
+		Store:     store,
+		RemoteKey: externalsecrets.RemoteKeyFor(o.getClusterType(), "cluster-vars"),
+	}
+	if dryRun {
+		log.Info("Would migrate cluster-vars", "path", store.KVMount+"/"+migration.RemoteKey, "store", store.Name)
+		return keys, nil
+	}
+
+	if err := o.setupExternalSecrets(ctx); err != nil {
+		return nil, err
+	}
+	client := externalsecrets.NewClient(o.k8sClient)
+	if err := client.MigrateSecret(ctx, migration); err != nil {
+		return nil, err
+	}
+	if err := client.WaitForExternalSecret(ctx, migration.Namespace, migration.Name, storeReadyTimeout); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
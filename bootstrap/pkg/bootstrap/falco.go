@@ -0,0 +1,126 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/falco"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// withFalcoStep inserts the optional runtime security step when Falco is enabled
+func (o *Orchestrator) withFalcoStep(steps []BootstrapStep) []BootstrapStep {
+	security := o.securityConfig()
+	if security == nil || !security.Falco.Enabled {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "runtime-security",
+		Description: "Deploy Falco runtime security and route its alerts",
+		Required:    false,
+		Execute:     o.deployFalco,
+	}, "bootstrap-gitops", "policy-engine")
+}
+
+// FalcoOptions returns the Falco options of the active cluster configuration
+func (o *Orchestrator) FalcoOptions() falco.Options {
+	var opts falco.Options
+	if security := o.securityConfig(); security != nil {
+		opts.Driver = security.Falco.Driver
+		opts.Priority = security.Falco.Priority
+	}
+	return opts
+}
+
+// GenerateFalco writes the Falco deployment into the GitOps repository
+func (o *Orchestrator) GenerateFalco(opts falco.Options) ([]string, error) {
+	return falco.NewGenerator(o.projectRoot, o.localClusterName(), opts).Generate()
+}
+
+// ConfigureFalcoAlertRouting stores the alert webhook consumed by Falcosidekick
+func (o *Orchestrator) ConfigureFalcoAlertRouting(ctx context.Context) error {
+	webhook := o.falcoWebhook()
+	if webhook == "" {
+		log.Debug("No alert webhook configured, Falco alerts stay in the pod logs")
+		return nil
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      falco.RoutingSecret,
+			Namespace: "flux-system",
+		},
+		StringData: map[string]string{
+			falco.RoutingSecretKey: webhook,
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+	if err := o.k8sClient.CreateOrUpdateSecret(ctx, secret); err != nil {
+		return fmt.Errorf("failed to store Falco alert routing: %w", err)
+	}
+	log.Info("Falco alerts routed to webhook", "secret", falco.RoutingSecret)
+	return nil
+}
+
+// ValidateFalco checks the Falco driver on every node
+func (o *Orchestrator) ValidateFalco(ctx context.Context) ([]falco.DriverStatus, error) {
+	return falco.NewValidator(o.k8sClient).ValidateDriver(ctx)
+}
+
+// FalcoAlerts returns recent Falco alerts and records them on the run attached to ctx
+func (o *Orchestrator) FalcoAlerts(ctx context.Context, since time.Duration, minPriority string) ([]falco.Alert, error) {
+	alerts, err := falco.NewValidator(o.k8sClient).RecentAlerts(ctx, since, minPriority)
+	if err != nil {
+		return nil, err
+	}
+
+	if run := history.FromContext(ctx); run != nil {
+		for _, alert := range alerts {
+			run.AddAlert(history.Alert{
+				Time:     alert.Time,
+				Source:   "falco",
+				Priority: alert.Priority,
+				Rule:     alert.Rule,
+				Output:   alert.Output,
+				Node:     alert.Hostname,
+			})
+		}
+	}
+	return alerts, nil
+}
+
+func (o *Orchestrator) deployFalco(ctx context.Context) error {
+	written, err := o.GenerateFalco(o.FalcoOptions())
+	if err != nil {
+		return err
+	}
+	log.Info("Falco deployment generated; commit and push it for Flux to apply", "files", len(written))
+
+	if err := o.ConfigureFalcoAlertRouting(ctx); err != nil {
+		return err
+	}
+
+	pods, err := o.k8sClient.GetPods(ctx, falco.Namespace, "app.kubernetes.io/name=falco")
+	if err != nil || len(pods) == 0 {
+		log.Info("Falco not running yet, driver validation skipped until Flux deploys it")
+		return nil
+	}
+	_, err = o.ValidateFalco(ctx)
+	return err
+}
+
+// falcoWebhook returns the Falco webhook, falling back to the homelab alerting webhook
+func (o *Orchestrator) falcoWebhook() string {
+	if security := o.securityConfig(); security != nil && security.Falco.Webhook != "" {
+		return security.Falco.Webhook
+	}
+	if !o.isNAS && o.config.Homelab != nil && o.config.Homelab.Monitoring.Alerting.Enabled {
+		return o.config.Homelab.Monitoring.Alerting.Webhook
+	}
+	return ""
+}
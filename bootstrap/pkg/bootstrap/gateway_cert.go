@@ -0,0 +1,133 @@
+package bootstrap
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultGatewayCertRenewBefore is how long before expiry the east-west gateway certificate is renewed
+const DefaultGatewayCertRenewBefore = 30 * 24 * time.Hour
+
+// certificateNotAfter returns the expiry of the first certificate of a PEM bundle
+func certificateNotAfter(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	return cert.NotAfter, nil
+}
+
+// gatewayCertDue reports whether the base64 east-west certificate expires within renewBefore
+func gatewayCertDue(certB64 string, renewBefore time.Duration) (bool, time.Time) {
+	certPEM, err := base64.StdEncoding.DecodeString(certB64)
+	if err != nil {
+		return false, time.Time{}
+	}
+	notAfter, err := certificateNotAfter(certPEM)
+	if err != nil {
+		return false, time.Time{}
+	}
+	return time.Until(notAfter) < renewBefore, notAfter
+}
+
+// RenewGatewayCert regenerates the east-west gateway certificate, applies it on every mesh cluster
+// and restarts the gateways so they serve it
+func (o *Orchestrator) RenewGatewayCert(ctx context.Context) error {
+	certB64, _, err := o.generateGatewayTLSMaterial()
+	if err != nil {
+		return err
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(certB64)
+	if err != nil {
+		return fmt.Errorf("failed to decode the new certificate: %w", err)
+	}
+	notAfter, err := certificateNotAfter(certPEM)
+	if err != nil {
+		return err
+	}
+	log.Info("Generated east-west gateway certificate", "expires", notAfter.Format(time.DateOnly))
+
+	targets := []struct {
+		name   string
+		client *k8s.Client
+	}{{o.localClusterName(), o.k8sClient}}
+	for _, peer := range o.meshPeers() {
+		client, err := peer.client()
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", peer.name, err)
+		}
+		targets = append(targets, struct {
+			name   string
+			client *k8s.Client
+		}{peer.name, client})
+	}
+
+	for _, target := range targets {
+		if err := o.ensureGatewayTLSSecret(ctx, target.client, target.name); err != nil {
+			return fmt.Errorf("%s: %w", target.name, err)
+		}
+		if err := restartWorkload(ctx, target.client, "Deployment", istioNamespace, eastWestServiceName); err != nil {
+			return fmt.Errorf("%s: %w", target.name, err)
+		}
+		if err := waitForRollout(ctx, target.client, "Deployment", istioNamespace, eastWestServiceName); err != nil {
+			return fmt.Errorf("%s: %w", target.name, err)
+		}
+		log.Info("East-west gateway serves the renewed certificate", "cluster", target.name)
+	}
+	return nil
+}
+
+// CheckGatewayCert returns the expiry of the east-west gateway certificate of the local cluster, failing when it
+// expires within the renewal window
+func (o *Orchestrator) CheckGatewayCert(ctx context.Context) (time.Time, error) {
+	secret, err := o.k8sClient.GetClientset().CoreV1().Secrets(istioNamespace).Get(ctx, eastWestGatewayTLSSecretName, metav1.GetOptions{})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read secret %s/%s: %w", istioNamespace, eastWestGatewayTLSSecretName, err)
+	}
+	notAfter, err := certificateNotAfter(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("secret %s/%s: %w", istioNamespace, eastWestGatewayTLSSecretName, err)
+	}
+	renewBefore := o.options.GatewayCertRenewBefore
+	if renewBefore <= 0 {
+		renewBefore = DefaultGatewayCertRenewBefore
+	}
+	if time.Until(notAfter) < renewBefore {
+		return notAfter, fmt.Errorf("east-west gateway certificate expires on %s, run bootstrap mesh renew-gateway-cert",
+			notAfter.Format(time.DateOnly))
+	}
+	return notAfter, nil
+}
+
+// verifyGatewayCertExpiry fails on an expired east-west certificate and warns when it is due for renewal
+func verifyGatewayCertExpiry(secret *corev1.Secret, cluster string) error {
+	notAfter, err := certificateNotAfter(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return fmt.Errorf("%s: secret %s/%s: %w", cluster, istioNamespace, secret.Name, err)
+	}
+	remaining := time.Until(notAfter)
+	switch {
+	case remaining <= 0:
+		return fmt.Errorf("%s: east-west gateway certificate expired on %s, run bootstrap mesh renew-gateway-cert",
+			cluster, notAfter.Format(time.DateOnly))
+	case remaining < DefaultGatewayCertRenewBefore:
+		log.Warn("East-west gateway certificate expires soon", "cluster", cluster,
+			"expires", notAfter.Format(time.DateOnly), "fix", "bootstrap mesh renew-gateway-cert")
+	default:
+		log.Debug("East-west gateway certificate valid", "cluster", cluster, "expires", notAfter.Format(time.DateOnly))
+	}
+	return nil
+}
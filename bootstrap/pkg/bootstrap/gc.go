@@ -0,0 +1,92 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// GarbageCollect removes stale pending remote secrets and obsolete .env.generated keys
+func (o *Orchestrator) GarbageCollect(ctx context.Context, dryRun bool, maxPendingAge time.Duration) (*secrets.GCReport, error) {
+	if o.secretsManager == nil {
+		return nil, fmt.Errorf("secrets manager not initialised")
+	}
+
+	report, err := o.secretsManager.GarbageCollect(ctx, secrets.GCOptions{
+		KnownClusters: o.config.MeshClusterNames(),
+		MaxPendingAge: maxPendingAge,
+		IsApplied:     o.pendingSecretApplied,
+		DryRun:        dryRun,
+	})
+	if err != nil {
+		return report, fmt.Errorf("garbage collection failed: %w", err)
+	}
+
+	return report, nil
+}
+
+func (o *Orchestrator) garbageCollect(ctx context.Context) error {
+	log.Info("Collecting stale pending remote secrets and generated env keys")
+
+	report, err := o.GarbageCollect(ctx, false, 0)
+	if err != nil {
+		return err
+	}
+
+	if report.Empty() {
+		log.Info("Nothing to clean up")
+		return nil
+	}
+
+	log.Info("Garbage collection completed",
+		"pending_secrets", len(report.DeletedPendingSecrets),
+		"env_keys", len(report.PrunedEnvKeys))
+	return nil
+}
+
+// pendingSecretApplied checks whether the peer already holds the local remote secret carried by the payload.
+func (o *Orchestrator) pendingSecretApplied(ctx context.Context, cluster, payloadB64 string) (bool, error) {
+	var peer *meshPeer
+	for _, candidate := range o.meshPeers() {
+		if candidate.name == cluster {
+			peer = &candidate
+			break
+		}
+	}
+	if peer == nil {
+		return false, nil
+	}
+
+	pending, err := secretFromBase64(payloadB64)
+	if err != nil {
+		return false, err
+	}
+
+	peerClient, err := peer.client()
+	if err != nil {
+		return false, err
+	}
+
+	applied, err := peerClient.GetSecret(ctx, istioNamespace, pending.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if len(applied.Data) != len(pending.Data) {
+		return false, nil
+	}
+	for key, value := range pending.Data {
+		if !bytes.Equal(applied.Data[key], value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
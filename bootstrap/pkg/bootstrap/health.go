@@ -0,0 +1,45 @@
+package bootstrap
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/health"
+)
+
+// HealthOptions tunes the concurrent component health checks
+type HealthOptions struct {
+	Concurrency  int
+	CheckTimeout time.Duration
+}
+
+// ClusterHealth runs the component health checks against the local cluster.
+// Zero-valued options fall back to HEALTH_CHECK_CONCURRENCY / HEALTH_CHECK_TIMEOUT, then to the checker defaults.
+func (o *Orchestrator) ClusterHealth(ctx context.Context, opts HealthOptions) (*health.HealthStatus, error) {
+	checker := health.NewHealthChecker(o.k8sClient)
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		if v := o.lookupEnvValue("HEALTH_CHECK_CONCURRENCY"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				log.Warn("Invalid HEALTH_CHECK_CONCURRENCY, using default", "value", v)
+			}
+			concurrency = n
+		}
+	}
+	checker.SetConcurrency(concurrency)
+
+	timeout := opts.CheckTimeout
+	if timeout <= 0 {
+		timeout = o.parseDuration(o.lookupEnvValue("HEALTH_CHECK_TIMEOUT"), 0)
+	}
+	checker.SetCheckTimeout(timeout)
+	if opts, ok := o.ingressOptions(); ok {
+		checker.SetIngress(opts)
+	}
+
+	return checker.CheckClusterHealth(ctx)
+}
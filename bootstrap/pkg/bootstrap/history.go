@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// trackedComponents are the workloads whose image versions are recorded in the run history
+var trackedComponents = []struct {
+	name      string
+	namespace string
+	kind      string
+}{
+	{"cilium", "kube-system", "daemonset"},
+	{"source-controller", "flux-system", "deployment"},
+	{"kustomize-controller", "flux-system", "deployment"},
+	{"helm-controller", "flux-system", "deployment"},
+	{"istiod", istioNamespace, "deployment"},
+	{"ztunnel", istioNamespace, "daemonset"},
+	{"vault", "vault", "statefulset"},
+}
+
+// ProjectRoot returns the root of the homelab project containing the working directory
+func ProjectRoot() (string, error) {
+	return findProjectRoot()
+}
+
+// recordRunDetails stores what the run left behind on the cluster, whether it succeeded or not
+func (o *Orchestrator) recordRunDetails(ctx context.Context, run *history.Run) {
+	if run == nil {
+		return
+	}
+	o.recordDeployedVersions(ctx, run)
+	o.recordEndpoints(ctx, run)
+}
+
+// recordEndpoints stores the API server, east-west gateway and peer Vault addresses on the run
+func (o *Orchestrator) recordEndpoints(ctx context.Context, run *history.Run) {
+	if config := o.k8sClient.GetConfig(); config != nil && config.Host != "" {
+		run.SetEndpoint("api_server", config.Host)
+	}
+	if svc, err := o.k8sClient.GetService(ctx, istioNamespace, eastWestServiceName); err == nil {
+		if endpoint := endpointFromService(svc); endpoint != nil && endpoint.Host != "" {
+			run.SetEndpoint("eastwest_gateway", net.JoinHostPort(endpoint.Host, strconv.Itoa(int(endpoint.Port))))
+		}
+	}
+	if !o.isNAS {
+		if address := o.lookupEnvValue("NAS_VAULT_ADDR"); address != "" {
+			run.SetEndpoint("nas_vault", address)
+		}
+	}
+}
+
+// recordDeployedVersions stores the Kubernetes version, component images and GitOps revision on the run
+func (o *Orchestrator) recordDeployedVersions(ctx context.Context, run *history.Run) {
+	if run == nil {
+		return
+	}
+
+	clientset := o.k8sClient.GetClientset()
+	if version, err := clientset.Discovery().ServerVersion(); err == nil {
+		run.SetVersion("kubernetes", version.GitVersion)
+	}
+
+	for _, component := range trackedComponents {
+		var images []string
+		switch component.kind {
+		case "deployment":
+			if d, err := clientset.AppsV1().Deployments(component.namespace).Get(ctx, component.name, metav1.GetOptions{}); err == nil {
+				for _, c := range d.Spec.Template.Spec.Containers {
+					images = append(images, c.Image)
+				}
+			}
+		case "daemonset":
+			if ds, err := clientset.AppsV1().DaemonSets(component.namespace).Get(ctx, component.name, metav1.GetOptions{}); err == nil {
+				for _, c := range ds.Spec.Template.Spec.Containers {
+					images = append(images, c.Image)
+				}
+			}
+		case "statefulset":
+			if sts, err := clientset.AppsV1().StatefulSets(component.namespace).Get(ctx, component.name, metav1.GetOptions{}); err == nil {
+				for _, c := range sts.Spec.Template.Spec.Containers {
+					images = append(images, c.Image)
+				}
+			}
+		}
+		if len(images) > 0 {
+			run.SetVersion(component.name, k8s.ImageTag(images[0]))
+		}
+	}
+
+	repo, err := o.k8sClient.GetDynamicClient().Resource(flux.SourceGVR(o.gitOpsConfig())).Namespace("flux-system").Get(ctx, "flux-system", metav1.GetOptions{})
+	if err == nil {
+		if revision, _, _ := unstructured.NestedString(repo.Object, "status", "artifact", "revision"); revision != "" {
+			run.GitRevision = strings.TrimSpace(revision)
+		}
+	}
+}
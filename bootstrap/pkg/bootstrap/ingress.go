@@ -0,0 +1,71 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/health"
+)
+
+// ingressOptions returns the DNS and ingress checks of the homelab, false without a test hostname
+func (o *Orchestrator) ingressOptions() (health.IngressOptions, bool) {
+	if o.isNAS || o.config.Homelab == nil || o.config.Homelab.Networking.DNS.TestHostname == "" {
+		return health.IngressOptions{}, false
+	}
+	networking := o.config.Homelab.Networking
+	caFile := networking.Ingress.CAFile
+	if caFile != "" && !filepath.IsAbs(caFile) {
+		caFile = filepath.Join(o.projectRoot, caFile)
+	}
+	return health.IngressOptions{
+		Provider:    networking.Ingress.Provider,
+		ExternalDNS: networking.DNS.Provider == "external-dns",
+		Hostname:    networking.DNS.TestHostname,
+		Nameserver:  networking.DNS.Nameserver,
+		CAFile:      caFile,
+	}, true
+}
+
+// withIngressValidationStep inserts the end-to-end DNS and ingress validation when a test hostname is configured
+func (o *Orchestrator) withIngressValidationStep(steps []BootstrapStep) []BootstrapStep {
+	if _, ok := o.ingressOptions(); !ok {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "validate-ingress",
+		Description: "Resolve the test hostname inside and outside the cluster and verify its TLS chain",
+		Required:    false,
+		Execute:     o.validateIngress,
+	}, "validate-deployment")
+}
+
+func (o *Orchestrator) validateIngress(ctx context.Context) error {
+	opts, ok := o.ingressOptions()
+	if !ok {
+		log.Info("No test hostname configured, skipping ingress validation")
+		return nil
+	}
+
+	checker := health.NewHealthChecker(o.k8sClient)
+	checker.SetIngress(opts)
+	status, err := checker.CheckIngress(ctx)
+	if err != nil {
+		return err
+	}
+	for _, component := range sortedKeys(status.Components) {
+		state := status.Components[component]
+		if state == health.HealthStateHealthy {
+			log.Info("Ingress check passed", "check", component, "details", status.Details[component])
+		} else {
+			log.Warn("Ingress check not passing", "check", component, "state", state, "details", status.Details[component])
+		}
+	}
+	if status.Overall == health.HealthStateUnhealthy {
+		return fmt.Errorf("DNS and ingress validation failed for %s: %s", opts.Hostname, strings.Join(status.Failures, ", "))
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -21,7 +22,6 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
-	"github.com/fredericrous/homelab/bootstrap/pkg/discovery"
 	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
 	"github.com/fredericrous/homelab/bootstrap/pkg/istio"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
@@ -29,7 +29,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/utils/pointer"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/yaml"
 )
 
@@ -39,6 +39,8 @@ const (
 	eastWestServiceName          = "istio-eastwestgateway"
 	eastWestGatewayTLSSecretName = "istio-eastwestgateway-certs"
 	sidecarWebhookName           = "istio-sidecar-injector"
+	// gatewayFallbackAfter is how long a routable gateway address is awaited before using a node port
+	gatewayFallbackAfter = 2 * time.Minute
 )
 
 func (o *Orchestrator) ensureIstioPrereqs(ctx context.Context) error {
@@ -71,21 +73,57 @@ func (o *Orchestrator) finalizeIstioMesh(ctx context.Context) error {
 	}
 
 	if o.isNAS {
-		// For NAS: Just ensure local gateway is ready and store endpoint
+		// For NAS: Ensure local gateway is ready and store endpoint
 		log.Info("Setting up Istio mesh components on NAS cluster")
-		return o.ensureLocalGatewayReady(ctx)
+		if err := o.ensureLocalGatewayReady(ctx); err != nil {
+			return err
+		}
+		return o.verifyMeshIfLast(ctx)
 	}
 
 	// For Homelab: Full mesh establishment
 	if status == MeshReady {
 		log.Info("Mesh already established, verifying health")
-		return verifyMeshWithRoot(ctx, o.projectRoot)
+		return verifyMeshWithRoot(ctx, o.projectRoot, o.localClusterName(), o.meshMode(ctx), o.localOverride())
 	}
 
-	log.Info("Establishing cross-cluster mesh connectivity between homelab and NAS")
+	log.Info("Establishing cross-cluster mesh connectivity", "local", o.localClusterName(), "peers", peerNames(o.meshPeers()))
 	return o.establishBidirectionalMesh(ctx)
 }
 
+// verifyMeshIfLast verifies the mesh when every peer already joined it, leaving verification to the last peer otherwise
+func (o *Orchestrator) verifyMeshIfLast(ctx context.Context) error {
+	peers := o.meshPeers()
+
+	// A pending remote secret means a peer has not received ours yet and will verify once it bootstraps
+	for _, peer := range peers {
+		if pending, err := o.secretsManager.FetchPendingRemoteSecret(ctx, peer.name); err != nil {
+			log.Warn("Unable to read pending remote secret", "peer", peer.name, "error", err)
+		} else if pending != "" {
+			log.Info("Mesh verification deferred to the peer bootstrap, our remote secret is still pending", "peer", peer.name)
+			return nil
+		}
+	}
+
+	status, err := o.checkMeshStatus(ctx)
+	if err != nil {
+		log.Warn("Unable to determine mesh status", "error", err)
+	}
+	if status != MeshReady {
+		log.Info("Mesh verification deferred until the peers join the mesh",
+			"peers", peerNames(peers),
+			"hint", "bootstrap verify --from "+o.localClusterName())
+		return nil
+	}
+
+	log.Info("Peers already joined the mesh, verifying it from this cluster", "peers", peerNames(peers))
+	if err := verifyMeshWithRoot(ctx, o.projectRoot, o.localClusterName(), o.meshMode(ctx), o.localOverride()); err != nil {
+		return fmt.Errorf("mesh verification failed: %w", err)
+	}
+	log.Info("Cross-cluster mesh verification succeeded")
+	return nil
+}
+
 // checkMeshStatus determines the current state of the service mesh
 func (o *Orchestrator) checkMeshStatus(ctx context.Context) (MeshStatus, error) {
 	// Check if Istio is installed
@@ -98,20 +136,21 @@ func (o *Orchestrator) checkMeshStatus(ctx context.Context) (MeshStatus, error)
 		return MeshNotReady, nil
 	}
 
-	// Check if remote secret exists
-	remoteSecretName := fmt.Sprintf("istio-remote-secret-%s", o.peerClusterName())
-	if _, err := o.k8sClient.GetSecret(ctx, istioNamespace, remoteSecretName); err != nil {
-		return MeshPartial, nil
-	}
-
-	// Check if we can reach peer cluster
-	if peerClient, err := o.buildPeerClient(); err == nil {
-		if err := peerClient.IsReady(ctx); err == nil {
-			return MeshReady, nil
+	// Every peer needs its remote secret installed here and a reachable API
+	for _, peer := range o.meshPeers() {
+		if _, err := o.k8sClient.GetSecret(ctx, istioNamespace, remoteSecretName(peer.name)); err != nil {
+			return MeshPartial, nil
+		}
+		peerClient, err := peer.client()
+		if err != nil {
+			return MeshPartial, nil
+		}
+		if err := peerClient.IsReady(ctx); err != nil {
+			return MeshPartial, nil
 		}
 	}
 
-	return MeshPartial, nil
+	return MeshReady, nil
 }
 
 // ensureLocalGatewayReady waits for local gateway and stores its endpoint
@@ -121,13 +160,16 @@ func (o *Orchestrator) ensureLocalGatewayReady(ctx context.Context) error {
 		log.Warn("Failed to ensure east-west TLS secret", "error", err)
 	}
 
-	// Ensure webhook service
-	if err := o.ensureWebhookTargetsService(ctx, o.k8sClient, o.localClusterName()); err != nil {
-		log.Warn("Failed to reconcile mutating webhook", "error", err)
+	// Ensure webhook service, ambient workloads are captured by ztunnel instead of an injected sidecar
+	ambient := o.meshMode(ctx) == config.MeshModeAmbient
+	if !ambient {
+		if err := o.ensureWebhookTargetsService(ctx, o.k8sClient, o.localClusterName()); err != nil {
+			log.Warn("Failed to reconcile mutating webhook", "error", err)
+		}
 	}
 
 	// Wait for gateway endpoint
-	localEndpoint, err := o.waitForGatewayEndpoint(ctx, o.k8sClient, o.localGatewayFallbacks(), true)
+	localEndpoint, err := o.waitForGatewayEndpoint(ctx, o.k8sClient, o.localGatewayFallbacks(), !o.loadBalancerConfig().Enabled())
 	if err != nil {
 		return fmt.Errorf("failed to detect local east-west gateway address: %w", err)
 	}
@@ -147,14 +189,20 @@ func (o *Orchestrator) ensureLocalGatewayReady(ctx context.Context) error {
 	}
 
 	// Wait for local Istio components
-	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, "istiod", 5*time.Minute); err != nil {
+	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, "istiod", waitTimeout(ctx, defaultWaitTimeout)); err != nil {
 		return fmt.Errorf("istiod not ready: %w", err)
 	}
 
-	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, eastWestServiceName, 5*time.Minute); err != nil {
+	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, eastWestServiceName, waitTimeout(ctx, defaultWaitTimeout)); err != nil {
 		return fmt.Errorf("east-west gateway not ready: %w", err)
 	}
 
+	if ambient {
+		if err := o.verifyAmbientDataPlane(ctx); err != nil {
+			return err
+		}
+	}
+
 	log.Info("Local Istio mesh components ready", "cluster", o.localClusterName(), "gateway", localEndpoint.Host, "port", localEndpoint.Port)
 	log.Info("NAS cluster is now mesh-ready for future cross-cluster connections")
 	
@@ -168,14 +216,17 @@ func (o *Orchestrator) establishBidirectionalMesh(ctx context.Context) error {
 		log.Warn("Failed to ensure east-west TLS secret", "error", err)
 	}
 
-	if err := o.ensureWebhookTargetsService(ctx, o.k8sClient, o.localClusterName()); err != nil {
-		log.Warn("Failed to reconcile mutating webhook", "error", err)
+	ambient := o.meshMode(ctx) == config.MeshModeAmbient
+	if !ambient {
+		if err := o.ensureWebhookTargetsService(ctx, o.k8sClient, o.localClusterName()); err != nil {
+			log.Warn("Failed to reconcile mutating webhook", "error", err)
+		}
 	}
 
 	updates := map[string]string{}
 
 	// Get local gateway endpoint
-	localEndpoint, err := o.waitForGatewayEndpoint(ctx, o.k8sClient, o.localGatewayFallbacks(), true)
+	localEndpoint, err := o.waitForGatewayEndpoint(ctx, o.k8sClient, o.localGatewayFallbacks(), !o.loadBalancerConfig().Enabled())
 	if err != nil {
 		return fmt.Errorf("failed to detect local east-west gateway address: %w", err)
 	}
@@ -184,33 +235,39 @@ func (o *Orchestrator) establishBidirectionalMesh(ctx context.Context) error {
 	updates[localAddrKey] = localEndpoint.Host
 	updates[localPortKey] = strconv.Itoa(int(localEndpoint.Port))
 
-	// Connect to peer cluster and setup bidirectional connectivity
-	peerClient, err := o.buildPeerClient()
-	if err != nil {
-		return fmt.Errorf("failed to build peer client: %w", err)
-	}
+	// Connect to every peer cluster and setup bidirectional connectivity
+	var peerEndpoints []string
+	for _, peer := range o.meshPeers() {
+		peerClient, err := peer.client()
+		if err != nil {
+			return fmt.Errorf("failed to build peer client: %w", err)
+		}
 
-	// Ensure peer gateway TLS
-	if err := o.ensureGatewayTLSSecret(ctx, peerClient, o.peerClusterName()); err != nil {
-		log.Warn("Failed to ensure peer TLS secret", "peer", o.peerClusterName(), "error", err)
-	}
+		// Ensure peer gateway TLS
+		if err := o.ensureGatewayTLSSecret(ctx, peerClient, peer.name); err != nil {
+			log.Warn("Failed to ensure peer TLS secret", "peer", peer.name, "error", err)
+		}
 
-	// Ensure peer webhook
-	if err := o.ensureWebhookTargetsService(ctx, peerClient, o.peerClusterName()); err != nil {
-		log.Warn("Failed to reconcile peer webhook", "peer", o.peerClusterName(), "error", err)
-	}
+		// Ensure peer webhook
+		if !ambient {
+			if err := o.ensureWebhookTargetsService(ctx, peerClient, peer.name); err != nil {
+				log.Warn("Failed to reconcile peer webhook", "peer", peer.name, "error", err)
+			}
+		}
 
-	// Get peer gateway endpoint
-	peerEndpoint, err := o.waitForGatewayEndpoint(ctx, peerClient, o.peerGatewayFallbacks(), false)
-	if err != nil {
-		return fmt.Errorf("failed to detect peer east-west gateway: %w", err)
-	}
+		// Get peer gateway endpoint
+		peerEndpoint, err := o.waitForGatewayEndpoint(ctx, peerClient, peer.fallbacks, false)
+		if err != nil {
+			return fmt.Errorf("failed to detect %s east-west gateway: %w", peer.name, err)
+		}
 
-	peerAddrKey, peerPortKey := o.peerGatewayVarKeys()
-	updates[peerAddrKey] = peerEndpoint.Host
-	updates[peerPortKey] = strconv.Itoa(int(peerEndpoint.Port))
+		peerAddrKey, peerPortKey := gatewayVarKeys(peer.name)
+		updates[peerAddrKey] = peerEndpoint.Host
+		updates[peerPortKey] = strconv.Itoa(int(peerEndpoint.Port))
+		peerEndpoints = append(peerEndpoints, fmt.Sprintf("%s=%s:%d", peer.name, peerEndpoint.Host, peerEndpoint.Port))
+	}
 
-	// Update cluster vars with both endpoints
+	// Update cluster vars with all endpoints
 	if err := o.secretsManager.UpdateClusterVars(ctx, "flux-system", updates); err != nil {
 		return fmt.Errorf("failed to update gateway variables: %w", err)
 	}
@@ -242,24 +299,28 @@ func (o *Orchestrator) establishBidirectionalMesh(ctx context.Context) error {
 	}
 
 	// Wait for all components to be ready
-	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, "istiod", 5*time.Minute); err != nil {
+	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, "istiod", waitTimeout(ctx, defaultWaitTimeout)); err != nil {
 		return fmt.Errorf("istiod not ready: %w", err)
 	}
 
-	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, eastWestServiceName, 5*time.Minute); err != nil {
+	if err := o.k8sClient.WaitForDeployment(ctx, istioNamespace, eastWestServiceName, waitTimeout(ctx, defaultWaitTimeout)); err != nil {
 		return fmt.Errorf("east-west gateway not ready: %w", err)
 	}
 
-	if err := o.k8sClient.WaitForDaemonSet(ctx, istioNamespace, "ztunnel", 5*time.Minute); err != nil {
+	if ambient {
+		if err := o.verifyAmbientDataPlane(ctx); err != nil {
+			return err
+		}
+	} else if err := o.k8sClient.WaitForDaemonSet(ctx, istioNamespace, "ztunnel", waitTimeout(ctx, defaultWaitTimeout)); err != nil {
 		log.Warn("ztunnel not ready", "error", err)
 	}
 
 	log.Info("Istio mesh established", 
 		"local", fmt.Sprintf("%s:%d", localEndpoint.Host, localEndpoint.Port),
-		"peer", fmt.Sprintf("%s:%d", peerEndpoint.Host, peerEndpoint.Port))
+		"peers", strings.Join(peerEndpoints, ", "))
 
 	// Verify mesh connectivity
-	if err := verifyMeshWithRoot(ctx, o.projectRoot); err != nil {
+	if err := verifyMeshWithRoot(ctx, o.projectRoot, o.localClusterName(), o.meshMode(ctx), o.localOverride()); err != nil {
 		return fmt.Errorf("mesh verification failed: %w", err)
 	}
 
@@ -307,97 +368,67 @@ func (o *Orchestrator) ensureCACerts(ctx context.Context) error {
 	fp := fingerprint(secret.Data["root-cert.pem"])
 	log.Info("Istio root CA found", "fingerprint", fp)
 
-	// Check peer cluster CA for consistency
-	peerPath := o.peerKubeconfigPath()
-	peerContext := ""
-	if discoveryService := discovery.NewClusterDiscovery(o.projectRoot); discoveryService != nil {
-		if info, err := discoveryService.GetCluster(o.peerClusterName()); err == nil {
-			if peerPath == "" {
-				peerPath = info.Kubeconfig
-			}
-			peerContext = info.Context
+	// Every peer must share the root CA, peers without one receive ours
+	var mismatched []string
+	for _, peer := range o.meshPeers() {
+		if peerFP := o.peerCAFingerprint(ctx, peer, secret); peerFP != "" && peerFP != fp {
+			mismatched = append(mismatched, fmt.Sprintf("%s=%s", peer.name, peerFP))
 		}
 	}
-	if peerPath == "" {
-		return nil
+	if len(mismatched) > 0 {
+		return fmt.Errorf("cacerts mismatch between clusters: local=%s %s", fp, strings.Join(mismatched, " "))
 	}
 
-	if _, err := os.Stat(peerPath); err != nil {
-		return nil
+	return nil
+}
+
+// peerCAFingerprint returns the root CA fingerprint of a peer, copying our CA to peers that have none
+func (o *Orchestrator) peerCAFingerprint(ctx context.Context, peer meshPeer, localSecret *corev1.Secret) string {
+	if peer.kubeconfig == "" {
+		return ""
+	}
+	if _, err := os.Stat(peer.kubeconfig); err != nil {
+		return ""
 	}
 
-	peerClient, err := k8s.NewClientWithContext(peerPath, peerContext)
+	peerClient, err := peer.client()
 	if err != nil {
-		log.Warn("Unable to connect to peer cluster for CA comparison", "peer", o.peerClusterName(), "error", err)
-		return nil
+		log.Warn("Unable to connect to peer cluster for CA comparison", "peer", peer.name, "error", err)
+		return ""
 	}
 
 	peerSecret, err := peerClient.GetSecret(ctx, istioNamespace, "cacerts")
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			log.Warn("Peer cluster is missing cacerts secret", "peer", o.peerClusterName())
+			log.Warn("Peer cluster is missing cacerts secret", "peer", peer.name)
 			// Try to copy our CA to peer cluster
-			if err := o.syncCAToPeer(ctx, peerClient, secret); err != nil {
-				log.Warn("Failed to sync CA to peer cluster", "peer", o.peerClusterName(), "error", err)
+			if err := o.syncCAToPeer(ctx, peer.name, peerClient, localSecret); err != nil {
+				log.Warn("Failed to sync CA to peer cluster", "peer", peer.name, "error", err)
 			}
-			return nil
+			return ""
 		}
-		log.Warn("Failed to fetch peer cacerts", "peer", o.peerClusterName(), "error", err)
-		return nil
+		log.Warn("Failed to fetch peer cacerts", "peer", peer.name, "error", err)
+		return ""
 	}
 
-	peerFP := fingerprint(peerSecret.Data["root-cert.pem"])
-	if fp != peerFP {
-		return fmt.Errorf("cacerts mismatch between clusters: local=%s peer=%s", fp, peerFP)
-	}
-
-	return nil
+	return fingerprint(peerSecret.Data["root-cert.pem"])
 }
 
 func (o *Orchestrator) ensureRemoteSecret(ctx context.Context) error {
 	log.Info("Ensuring cross-cluster remote secrets")
 
-	// Check if remote secrets already exist (idempotency)
-	peerSecretName := fmt.Sprintf("istio-remote-secret-%s", o.peerClusterName())
-
-	// Check if peer's remote secret already exists in local cluster
-	if _, err := o.k8sClient.GetSecret(ctx, istioNamespace, peerSecretName); err == nil {
-		log.Info("Remote secret for peer cluster already exists", "secret", peerSecretName)
-		// Still need to ensure local secret is in peer cluster
-	}
-
-	// Apply any cached remote secret payload for the peer cluster if present
-	if envKey := fmt.Sprintf("ISTIO_REMOTE_SECRET_%s_B64", strings.ToUpper(o.peerClusterName())); true {
-		if payload, err := o.secretsManager.GetGeneratedEnvValue(envKey); err == nil {
-			if strings.TrimSpace(payload) != "" {
-				if secret, decodeErr := secretFromBase64(payload); decodeErr != nil {
-					log.Warn("Failed to decode cached remote secret", "peer", o.peerClusterName(), "error", decodeErr)
-				} else {
-					if secret.Namespace == "" {
-						secret.Namespace = istioNamespace
-					}
-					if err := o.k8sClient.CreateOrUpdateSecret(ctx, secret); err != nil {
-						log.Warn("Failed to apply cached remote secret", "peer", o.peerClusterName(), "error", err)
-					} else {
-						log.Debug("Applied cached remote secret", "peer", o.peerClusterName())
-					}
-				}
-			}
-		}
-	}
-
 	// Create multi-cluster manager
-	mcManager := istio.NewMultiClusterManager(o.k8sClient)
+	mcManager := o.newMultiClusterManager(o.k8sClient)
 
-	// Create remote secret for local cluster (this will be installed in peer)
+	// Create remote secret for local cluster (this will be installed in every peer).
+	// istioctl is only a fallback since it embeds a long-lived token.
 	localSecret, err := mcManager.CreateRemoteSecret(ctx, o.localClusterName())
 	if err != nil {
-		return fmt.Errorf("failed to create local cluster remote secret: %w", err)
-	}
-
-	if istioctlSecret, cmdErr := o.remoteSecretFromIstioctl(ctx, o.kubeconfigPath, o.kubeContext, o.localClusterName()); cmdErr != nil {
-		log.Debug("Failed to render remote secret via istioctl", "cluster", o.localClusterName(), "error", cmdErr)
-	} else {
+		istioctlSecret, cmdErr := o.remoteSecretFromIstioctl(ctx, o.kubeconfigPath, o.kubeContext, o.localClusterName())
+		if cmdErr != nil {
+			return fmt.Errorf("failed to create local cluster remote secret: %w", err)
+		}
+		log.Warn("Falling back to istioctl remote secret without token expiry", "cluster", o.localClusterName(), "error", err)
 		localSecret = istioctlSecret
 	}
 
@@ -405,107 +436,126 @@ func (o *Orchestrator) ensureRemoteSecret(ctx context.Context) error {
 	if err != nil {
 		log.Warn("Failed to encode local remote secret", "error", err)
 	} else {
-		key := fmt.Sprintf("ISTIO_REMOTE_SECRET_%s_B64", strings.ToUpper(o.localClusterName()))
-		if err := o.secretsManager.UpdateGeneratedEnv(map[string]string{key: localSecretB64}); err != nil {
+		if err := o.secretsManager.UpdateGeneratedEnv(map[string]string{remoteSecretEnvKey(o.localClusterName()): localSecretB64}); err != nil {
 			log.Warn("Failed to record local remote secret", "error", err)
 		}
 	}
 
-	// Try to connect to peer cluster if available
-	peerPath := o.peerKubeconfigPath()
-	peerContext := ""
-	if discoveryService := discovery.NewClusterDiscovery(o.projectRoot); discoveryService != nil {
-		if info, err := discoveryService.GetCluster(o.peerClusterName()); err == nil {
-			if peerPath == "" {
-				peerPath = info.Kubeconfig
-			}
-			peerContext = info.Context
+	installedEverywhere := true
+	for _, peer := range o.meshPeers() {
+		installed, err := o.exchangeRemoteSecrets(ctx, peer, localSecret, localSecretB64)
+		if err != nil {
+			return err
 		}
+		installedEverywhere = installedEverywhere && installed
 	}
-	if peerPath == "" {
-		log.Info("Peer cluster not configured, storing pending remote secret")
-		if localSecretB64 != "" {
-			if err := o.secretsManager.StorePendingRemoteSecret(ctx, o.peerClusterName(), localSecretB64); err != nil {
-				log.Warn("Failed to store pending remote secret", "peer", o.peerClusterName(), "error", err)
-			}
+
+	// Older reader tokens stay valid until their TTL unless revoked, which is only safe once every peer
+	// holds the new secret
+	if anchor := localSecret.Annotations[istio.TokenAnchorAnnotation]; anchor != "" && installedEverywhere {
+		if err := mcManager.RevokeTokens(ctx, o.localClusterName(), anchor); err != nil {
+			log.Warn("Failed to revoke previous reader tokens", "cluster", o.localClusterName(), "error", err)
 		}
-		return nil
 	}
 
-	if peerPath != "" && !filepath.IsAbs(peerPath) {
-		if absPeer, err := filepath.Abs(peerPath); err == nil {
-			peerPath = absPeer
+	log.Info("Cross-cluster remote secrets configuration complete")
+	return nil
+}
+
+// exchangeRemoteSecrets installs the peer remote secret locally and ours in the peer, keeping ours pending when the peer is unreachable.
+// It reports whether ours was installed in the peer.
+func (o *Orchestrator) exchangeRemoteSecrets(ctx context.Context, peer meshPeer, localSecret *corev1.Secret, localSecretB64 string) (bool, error) {
+	storePending := func() {
+		if localSecretB64 == "" {
+			return
+		}
+		if err := o.secretsManager.StorePendingRemoteSecret(ctx, peer.name, localSecretB64); err != nil {
+			log.Warn("Failed to store pending remote secret", "peer", peer.name, "error", err)
 		}
 	}
 
-	// Check if peer kubeconfig exists
-	if _, err := os.Stat(peerPath); os.IsNotExist(err) {
-		log.Info("Peer kubeconfig not found yet, deferring remote secret sync", "path", peerPath)
-		if localSecretB64 != "" {
-			if err := o.secretsManager.StorePendingRemoteSecret(ctx, o.peerClusterName(), localSecretB64); err != nil {
-				log.Warn("Failed to store pending remote secret", "peer", o.peerClusterName(), "error", err)
+	// Check if peer's remote secret already exists in local cluster (idempotency)
+	if _, err := o.k8sClient.GetSecret(ctx, istioNamespace, remoteSecretName(peer.name)); err == nil {
+		log.Info("Remote secret for peer cluster already exists", "secret", remoteSecretName(peer.name))
+		// Still need to ensure local secret is in peer cluster
+	}
+
+	// Apply any cached remote secret payload for the peer cluster if present
+	if payload, err := o.secretsManager.GetGeneratedEnvValue(remoteSecretEnvKey(peer.name)); err == nil && strings.TrimSpace(payload) != "" {
+		if secret, decodeErr := secretFromBase64(payload); decodeErr != nil {
+			log.Warn("Failed to decode cached remote secret", "peer", peer.name, "error", decodeErr)
+		} else {
+			if secret.Namespace == "" {
+				secret.Namespace = istioNamespace
+			}
+			if err := o.k8sClient.CreateOrUpdateSecret(ctx, secret); err != nil {
+				log.Warn("Failed to apply cached remote secret", "peer", peer.name, "error", err)
+			} else {
+				log.Debug("Applied cached remote secret", "peer", peer.name)
 			}
 		}
-		return nil
+	}
+
+	if peer.kubeconfig == "" {
+		log.Info("Peer cluster not configured, storing pending remote secret", "peer", peer.name)
+		storePending()
+		return false, nil
+	}
+
+	// Check if peer kubeconfig exists
+	if _, err := os.Stat(peer.kubeconfig); os.IsNotExist(err) {
+		log.Info("Peer kubeconfig not found yet, deferring remote secret sync", "peer", peer.name, "path", peer.kubeconfig)
+		storePending()
+		return false, nil
 	}
 
 	// Connect to peer cluster
-	peerClient, err := k8s.NewClientWithContext(peerPath, peerContext)
+	peerClient, err := peer.client()
 	if err != nil {
-		log.Warn("Failed to connect to peer cluster", "peer", o.peerClusterName(), "error", err)
-		if localSecretB64 != "" {
-			if err := o.secretsManager.StorePendingRemoteSecret(ctx, o.peerClusterName(), localSecretB64); err != nil {
-				log.Warn("Failed to store pending remote secret", "peer", o.peerClusterName(), "error", err)
-			}
-		}
-		return nil
+		log.Warn("Failed to connect to peer cluster", "peer", peer.name, "error", err)
+		storePending()
+		return false, nil
 	}
 
 	// Create peer's multi-cluster manager
-	peerMCManager := istio.NewMultiClusterManager(peerClient)
+	peerMCManager := o.newMultiClusterManager(peerClient)
 
 	// Create remote secret for peer cluster (to be installed locally)
-	peerSecret, err := peerMCManager.CreateRemoteSecret(ctx, o.peerClusterName())
+	peerSecret, err := peerMCManager.CreateRemoteSecret(ctx, peer.name)
 	if err != nil {
-		log.Warn("Failed to create peer cluster remote secret", "peer", o.peerClusterName(), "error", err)
-	} else {
-		if istioctlSecret, cmdErr := o.remoteSecretFromIstioctl(ctx, peerPath, peerContext, o.peerClusterName()); cmdErr != nil {
-			log.Debug("Failed to render peer remote secret via istioctl", "peer", o.peerClusterName(), "error", cmdErr)
-		} else {
-			peerSecret = istioctlSecret
+		if istioctlSecret, cmdErr := o.remoteSecretFromIstioctl(ctx, peer.kubeconfig, peer.kubeContext, peer.name); cmdErr == nil {
+			log.Warn("Falling back to istioctl remote secret without token expiry", "peer", peer.name, "error", err)
+			peerSecret, err = istioctlSecret, nil
 		}
+	}
+	if err != nil {
+		log.Warn("Failed to create peer cluster remote secret", "peer", peer.name, "error", err)
+	} else {
 		if peerSecretB64, encErr := secretToBase64(peerSecret); encErr == nil {
-			key := fmt.Sprintf("ISTIO_REMOTE_SECRET_%s_B64", strings.ToUpper(o.peerClusterName()))
-			if err := o.secretsManager.UpdateGeneratedEnv(map[string]string{key: peerSecretB64}); err != nil {
-				log.Warn("Failed to record peer remote secret", "peer", o.peerClusterName(), "error", err)
+			if err := o.secretsManager.UpdateGeneratedEnv(map[string]string{remoteSecretEnvKey(peer.name): peerSecretB64}); err != nil {
+				log.Warn("Failed to record peer remote secret", "peer", peer.name, "error", err)
 			}
 		} else {
-			log.Warn("Failed to encode peer remote secret", "peer", o.peerClusterName(), "error", encErr)
+			log.Warn("Failed to encode peer remote secret", "peer", peer.name, "error", encErr)
 		}
 		// Install peer's remote secret in local cluster
 		if err := o.k8sClient.CreateOrUpdateSecret(ctx, peerSecret); err != nil {
-			return fmt.Errorf("failed to install peer remote secret locally: %w", err)
+			return false, fmt.Errorf("failed to install %s remote secret locally: %w", peer.name, err)
 		}
-		log.Info("Installed peer remote secret in local cluster", "peer", o.peerClusterName())
+		log.Info("Installed peer remote secret in local cluster", "peer", peer.name)
 	}
 
 	// Install local remote secret in peer cluster
 	if err := peerClient.CreateOrUpdateSecret(ctx, localSecret); err != nil {
-		log.Warn("Failed to install local remote secret in peer cluster", "error", err)
-		if localSecretB64 != "" {
-			if err := o.secretsManager.StorePendingRemoteSecret(ctx, o.peerClusterName(), localSecretB64); err != nil {
-				log.Warn("Failed to store pending remote secret", "peer", o.peerClusterName(), "error", err)
-			}
-		}
-	} else {
-		log.Info("Installed local remote secret in peer cluster", "local", o.localClusterName())
-		if err := o.secretsManager.ClearPendingRemoteSecret(ctx, o.peerClusterName()); err != nil {
-			log.Warn("Failed to clear pending remote secret", "peer", o.peerClusterName(), "error", err)
-		}
+		log.Warn("Failed to install local remote secret in peer cluster", "peer", peer.name, "error", err)
+		storePending()
+		return false, nil
 	}
-
-	log.Info("Cross-cluster remote secrets configuration complete")
-	return nil
+	log.Info("Installed local remote secret in peer cluster", "local", o.localClusterName(), "peer", peer.name)
+	if err := o.secretsManager.ClearPendingRemoteSecret(ctx, peer.name); err != nil {
+		log.Warn("Failed to clear pending remote secret", "peer", peer.name, "error", err)
+	}
+	return true, nil
 }
 
 func (o *Orchestrator) remoteSecretFromIstioctl(ctx context.Context, kubeconfig, kubeContext, clusterName string) (*corev1.Secret, error) {
@@ -513,6 +563,11 @@ func (o *Orchestrator) remoteSecretFromIstioctl(ctx context.Context, kubeconfig,
 		return nil, fmt.Errorf("kubeconfig path not provided for %s", clusterName)
 	}
 
+	// create-remote-secret provisions a service account and token on the source cluster
+	if err := k8s.GuardMutation("istioctl create-remote-secret"); err != nil {
+		return nil, err
+	}
+
 	args := []string{"x", "create-remote-secret", "--kubeconfig", kubeconfig, "--name", clusterName}
 	if strings.TrimSpace(kubeContext) != "" {
 		args = append(args, "--context", kubeContext)
@@ -538,53 +593,54 @@ func (o *Orchestrator) remoteSecretFromIstioctl(ctx context.Context, kubeconfig,
 }
 
 func (o *Orchestrator) waitForGatewayEndpoint(ctx context.Context, client *k8s.Client, fallbacks []string, allowFallback bool) (*gatewayEndpoint, error) {
-	deadline := time.Now().Add(5 * time.Minute)
-	fallbackAfter := time.Now().Add(2 * time.Minute)
-
-	for {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
+	timeout := waitTimeout(ctx, defaultWaitTimeout)
+	var endpoint *gatewayEndpoint
+	var svc *corev1.Service
+	routable := func(current *corev1.Service) (bool, error) {
+		svc = current
+		found := endpointFromService(current)
+		if found == nil || (found.Source == "nodePort" && !allowFallback) {
+			return false, nil
 		}
-
-		svc, err := client.GetService(ctx, istioNamespace, eastWestServiceName)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				if time.Now().After(deadline) {
-					return nil, fmt.Errorf("gateway service %s not created", eastWestServiceName)
-				}
-				time.Sleep(5 * time.Second)
-				continue
+		if found.Source == "nodePort" {
+			if len(fallbacks) == 0 {
+				return false, fmt.Errorf("no node fallback addresses available for gateway")
 			}
-			return nil, err
+			found.Host = fallbacks[0]
 		}
+		endpoint = found
+		return true, nil
+	}
 
-		endpoint := endpointFromService(svc)
-		if endpoint != nil {
-			if endpoint.Source == "nodePort" && allowFallback {
-				if len(fallbacks) == 0 {
-					return nil, fmt.Errorf("no node fallback addresses available for gateway")
-				}
-				endpoint.Host = fallbacks[0]
-				return endpoint, nil
-			}
-			if endpoint.Source != "nodePort" {
-				return endpoint, nil
+	// With node addresses to fall back on, a routable address only gets a head start over the node port
+	firstWait := timeout
+	fallback := allowFallback && len(fallbacks) > 0
+	if fallback && gatewayFallbackAfter < timeout {
+		firstWait = gatewayFallbackAfter
+	}
+	err := client.WaitForService(ctx, istioNamespace, eastWestServiceName, firstWait, routable)
+	if fallback && errors.Is(err, k8s.ErrWaitTimeout) {
+		err = client.WaitForService(ctx, istioNamespace, eastWestServiceName, timeout-firstWait, func(current *corev1.Service) (bool, error) {
+			if ok, err := routable(current); ok || err != nil {
+				return ok, err
 			}
-		}
-
-		if allowFallback && len(fallbacks) > 0 && time.Now().After(fallbackAfter) {
-			port := nodePortForGateway(svc)
-			if port != 0 {
-				return &gatewayEndpoint{Host: fallbacks[0], Port: port, Source: "nodePort"}, nil
+			if port := nodePortForGateway(current); port != 0 {
+				endpoint = &gatewayEndpoint{Host: fallbacks[0], Port: port, Source: "nodePort"}
+				return true, nil
 			}
-		}
-
-		if time.Now().After(deadline) {
-			return nil, fmt.Errorf("timed out waiting for gateway address")
-		}
-
-		time.Sleep(5 * time.Second)
+			return false, nil
+		})
+	}
+	if err == nil {
+		return endpoint, nil
+	}
+	if !errors.Is(err, k8s.ErrWaitTimeout) {
+		return nil, err
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("gateway service %s not created", eastWestServiceName)
 	}
+	return nil, fmt.Errorf("timed out waiting for gateway address")
 }
 
 func endpointFromService(svc *corev1.Service) *gatewayEndpoint {
@@ -656,7 +712,7 @@ func (o *Orchestrator) readCACertsFromDir() (map[string][]byte, error) {
 	return data, nil
 }
 
-func (o *Orchestrator) syncCAToPeer(ctx context.Context, peerClient *k8s.Client, localSecret *corev1.Secret) error {
+func (o *Orchestrator) syncCAToPeer(ctx context.Context, peer string, peerClient *k8s.Client, localSecret *corev1.Secret) error {
 	// Create istio-system namespace if it doesn't exist
 	if err := peerClient.CreateNamespace(ctx, istioNamespace); err != nil {
 		return fmt.Errorf("failed to create istio-system namespace on peer: %w", err)
@@ -678,7 +734,7 @@ func (o *Orchestrator) syncCAToPeer(ctx context.Context, peerClient *k8s.Client,
 		return fmt.Errorf("failed to sync CA secret to peer: %w", err)
 	}
 
-	log.Info("Successfully synced CA to peer cluster", "peer", o.peerClusterName())
+	log.Info("Successfully synced CA to peer cluster", "peer", peer)
 	return nil
 }
 
@@ -692,6 +748,17 @@ func (o *Orchestrator) ensureGatewayTLSSecret(ctx context.Context, client *k8s.C
 		return err
 	}
 
+	if renewBefore := o.options.GatewayCertRenewBefore; renewBefore > 0 && strings.TrimSpace(certB64) != "" {
+		if due, notAfter := gatewayCertDue(certB64, renewBefore); due {
+			log.Info("Renewing east-west gateway TLS certificate", "expires", notAfter.Format(time.DateOnly))
+			var genErr error
+			certB64, keyB64, genErr = o.generateGatewayTLSMaterial()
+			if genErr != nil {
+				return genErr
+			}
+		}
+	}
+
 	if strings.TrimSpace(certB64) == "" || strings.TrimSpace(keyB64) == "" {
 		if o.isNAS {
 			log.Info("Generating east-west gateway TLS certificate")
@@ -792,6 +859,14 @@ func (o *Orchestrator) generateGatewayTLSMaterial() (string, string, error) {
 	return certB64, keyB64, nil
 }
 
+// verifyAmbientDataPlane waits for ztunnel on every node and checks the waypoint proxies are programmed
+func (o *Orchestrator) verifyAmbientDataPlane(ctx context.Context) error {
+	if err := o.k8sClient.WaitForDaemonSet(ctx, istioNamespace, "ztunnel", waitTimeout(ctx, defaultWaitTimeout)); err != nil {
+		return fmt.Errorf("ztunnel not ready: %w", err)
+	}
+	return verifyWaypoints(ctx, o.k8sClient, o.localClusterName())
+}
+
 func (o *Orchestrator) ensureWebhookTargetsService(ctx context.Context, client *k8s.Client, cluster string) error {
 	mwcClient := client.GetClientset().AdmissionregistrationV1().MutatingWebhookConfigurations()
 	config, err := mwcClient.Get(ctx, sidecarWebhookName, metav1.GetOptions{})
@@ -810,8 +885,8 @@ func (o *Orchestrator) ensureWebhookTargetsService(ctx context.Context, client *
 			wh.ClientConfig.Service = &admissionv1.ServiceReference{
 				Name:      "istiod",
 				Namespace: istioNamespace,
-				Path:      pointer.String("/inject"),
-				Port:      pointer.Int32(443),
+				Path:      ptr.To("/inject"),
+				Port:      ptr.To[int32](443),
 			}
 			updated = true
 		} else if wh.ClientConfig.Service != nil {
@@ -819,8 +894,8 @@ func (o *Orchestrator) ensureWebhookTargetsService(ctx context.Context, client *
 			if ref.Name != "istiod" || ref.Namespace != istioNamespace || ref.Port == nil || *ref.Port != 443 {
 				ref.Name = "istiod"
 				ref.Namespace = istioNamespace
-				ref.Path = pointer.String("/inject")
-				ref.Port = pointer.Int32(443)
+				ref.Path = ptr.To("/inject")
+				ref.Port = ptr.To[int32](443)
 				updated = true
 			}
 		}
@@ -879,18 +954,57 @@ func (o *Orchestrator) isServiceMeshEnabled() bool {
 	return o.config.Homelab.Networking.ServiceMesh.Enabled
 }
 
+// meshMode returns the Istio data plane mode of the homelab config. The NAS config does not describe
+// the mesh, so the NAS follows the data plane already deployed on it.
+func (o *Orchestrator) meshMode(ctx context.Context) string {
+	if o.config.Homelab != nil {
+		if o.config.Homelab.Networking.ServiceMesh.IsAmbient() {
+			return config.MeshModeAmbient
+		}
+		return config.MeshModeSidecar
+	}
+	return detectMeshMode(ctx, o.k8sClient)
+}
+
 func (o *Orchestrator) localClusterName() string {
+	if o.config.MeshDeclared() {
+		if o.isNAS && o.config.NAS != nil && o.config.NAS.Cluster.Name != "" {
+			return o.config.NAS.Cluster.Name
+		}
+		if !o.isNAS && o.config.Homelab != nil && o.config.Homelab.Cluster.Name != "" {
+			return o.config.Homelab.Cluster.Name
+		}
+	}
 	if o.isNAS {
 		return "nas"
 	}
 	return "homelab"
 }
 
+// peerClusterName returns the main peer, the other of homelab and nas, or the first declared peer
 func (o *Orchestrator) peerClusterName() string {
+	peer := "nas"
 	if o.isNAS {
-		return "homelab"
+		peer = "homelab"
+	}
+	if !o.config.MeshDeclared() {
+		return peer
+	}
+
+	local := o.localClusterName()
+	var first string
+	for _, name := range o.config.MeshClusterNames() {
+		if name == peer {
+			return peer
+		}
+		if first == "" && name != local {
+			first = name
+		}
 	}
-	return "nas"
+	if first != "" {
+		return first
+	}
+	return peer
 }
 
 func (o *Orchestrator) localKubeconfigPath() string {
@@ -903,6 +1017,14 @@ func (o *Orchestrator) localKubeconfigPath() string {
 }
 
 func (o *Orchestrator) peerKubeconfigPath() string {
+	if o.config.MeshDeclared() {
+		name := o.peerClusterName()
+		for _, peer := range o.meshPeers() {
+			if peer.name == name {
+				return peer.kubeconfig
+			}
+		}
+	}
 	if o.isNAS {
 		return o.resolveKubeconfig(o.options.HomelabKubeconfigPath, "HOMELAB_KUBECONFIG_PATH",
 			filepath.Join("infrastructure", "homelab", "kubeconfig.yaml"), "kubeconfig")
@@ -912,6 +1034,12 @@ func (o *Orchestrator) peerKubeconfigPath() string {
 }
 
 func (o *Orchestrator) localGatewayFallbacks() []string {
+	for _, cluster := range o.config.Clusters {
+		if cluster.Name == o.localClusterName() && len(cluster.GatewayHosts) > 0 {
+			return append([]string{}, cluster.GatewayHosts...)
+		}
+	}
+
 	if o.isNAS {
 		if o.config.NAS != nil && o.config.NAS.Cluster.Host != "" {
 			return []string{o.config.NAS.Cluster.Host}
@@ -942,17 +1070,7 @@ func (o *Orchestrator) peerGatewayFallbacks() []string {
 }
 
 func (o *Orchestrator) localGatewayVarKeys() (string, string) {
-	if o.isNAS {
-		return "NAS_EW_GATEWAY_ADDR", "NAS_EW_GATEWAY_PORT"
-	}
-	return "HOMELAB_EW_GATEWAY_ADDR", "HOMELAB_EW_GATEWAY_PORT"
-}
-
-func (o *Orchestrator) peerGatewayVarKeys() (string, string) {
-	if o.isNAS {
-		return "HOMELAB_EW_GATEWAY_ADDR", "HOMELAB_EW_GATEWAY_PORT"
-	}
-	return "NAS_EW_GATEWAY_ADDR", "NAS_EW_GATEWAY_PORT"
+	return gatewayVarKeys(o.localClusterName())
 }
 
 func (o *Orchestrator) lookupEnvValue(key string) string {
@@ -1021,6 +1139,19 @@ func (o *Orchestrator) buildPeerClient() (*k8s.Client, error) {
 	return k8s.NewClient(path)
 }
 
+func (o *Orchestrator) newMultiClusterManager(client *k8s.Client) *istio.MultiClusterManager {
+	manager := istio.NewMultiClusterManager(client)
+	if ttl := o.lookupEnvValue("ISTIO_REMOTE_SECRET_TTL"); ttl != "" {
+		manager.SetTokenTTL(o.parseDuration(ttl, istio.DefaultTokenTTL))
+	}
+	return manager
+}
+
+// FluxClient returns a Flux client for the orchestrated cluster
+func (o *Orchestrator) FluxClient() (*flux.Client, error) {
+	return o.newFluxClient()
+}
+
 func (o *Orchestrator) newFluxClient() (*flux.Client, error) {
 	cfg := o.gitOpsConfig()
 	if cfg == nil {
@@ -0,0 +1,46 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	"github.com/fredericrous/homelab/bootstrap/pkg/jobs"
+)
+
+// AuditJobs audits the scheduled jobs of the local cluster and, when reachable, its peer, recording failures on the run attached to ctx
+func (o *Orchestrator) AuditJobs(ctx context.Context, opts jobs.Options, includePeer bool) ([]jobs.Schedule, error) {
+	schedules, err := jobs.NewAuditor(o.localClusterName(), o.k8sClient, opts).Audit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if includePeer {
+		peer, err := o.buildPeerClient()
+		if err != nil {
+			log.Warn("Peer cluster not reachable, auditing local jobs only", "cluster", o.peerClusterName(), "error", err)
+		} else {
+			peerSchedules, err := jobs.NewAuditor(o.peerClusterName(), peer, opts).Audit(ctx)
+			if err != nil {
+				log.Warn("Failed to audit peer cluster jobs", "cluster", o.peerClusterName(), "error", err)
+			} else {
+				schedules = append(schedules, peerSchedules...)
+			}
+		}
+	}
+
+	if run := history.FromContext(ctx); run != nil {
+		for _, schedule := range schedules {
+			for _, issue := range schedule.Issues {
+				run.AddAlert(history.Alert{
+					Time:     run.StartedAt,
+					Source:   "jobs",
+					Priority: issue.Severity,
+					Rule:     issue.Kind,
+					Output:   schedule.ID() + ": " + issue.Message,
+				})
+			}
+		}
+	}
+	return schedules, nil
+}
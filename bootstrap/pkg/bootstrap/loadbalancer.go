@@ -0,0 +1,47 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
+)
+
+// loadBalancerConfig returns the load balancer settings of the active cluster
+func (o *Orchestrator) loadBalancerConfig() config.LoadBalancerConfig {
+	return o.config.LoadBalancerFor(o.isNAS)
+}
+
+// withLoadBalancerStep inserts the load balancer step once the CNI runs, ahead of the gateways needing an address
+func (o *Orchestrator) withLoadBalancerStep(steps []BootstrapStep) []BootstrapStep {
+	cfg := o.loadBalancerConfig()
+	if !cfg.Enabled() {
+		return steps
+	}
+
+	step := BootstrapStep{
+		Name:        "setup-load-balancer",
+		Description: "Set up the L2 load balancer and check LoadBalancer services get an address",
+		Required:    true,
+		Execute:     o.setupLoadBalancer,
+	}
+	if cfg.Provider == infra.LoadBalancerMetalLB {
+		step.Namespaces = []string{infra.MetalLBNamespace}
+	}
+	return insertStepAfter(steps, step, "verify-cluster", "setup-registry", "wait-nodes")
+}
+
+func (o *Orchestrator) setupLoadBalancer(ctx context.Context) error {
+	cfg := o.loadBalancerConfig()
+	if !cfg.Enabled() {
+		log.Info("Load balancer not configured, skipping")
+		return nil
+	}
+	return infra.NewLoadBalancerInstaller(o.k8sClient).Install(ctx, infra.LoadBalancerConfig{
+		Provider:   cfg.Provider,
+		Addresses:  cfg.Addresses,
+		Interfaces: cfg.Interfaces,
+		Version:    cfg.Version,
+	})
+}
@@ -0,0 +1,85 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/logging"
+)
+
+// loggingConfig returns the log shipping section of the active cluster configuration
+func (o *Orchestrator) loggingConfig() *config.LoggingConfig {
+	if o.isNAS && o.config.NAS != nil {
+		return &o.config.NAS.Monitoring.Logging
+	}
+	if !o.isNAS && o.config.Homelab != nil {
+		return &o.config.Homelab.Monitoring.Logging
+	}
+	return nil
+}
+
+// withLoggingStep inserts the optional log shipping step when logging is enabled
+func (o *Orchestrator) withLoggingStep(steps []BootstrapStep) []BootstrapStep {
+	settings := o.loggingConfig()
+	if settings == nil || !settings.Enabled {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "log-shipping",
+		Description: "Ship cluster logs to the NAS Loki",
+		Required:    false,
+		Execute:     o.setupLogShipping,
+	}, "bootstrap-gitops", "policy-engine", "runtime-security")
+}
+
+// LoggingOptions returns the log shipping options of the active cluster configuration
+func (o *Orchestrator) LoggingOptions() logging.Options {
+	var opts logging.Options
+	if settings := o.loggingConfig(); settings != nil {
+		opts.Collector = settings.Collector
+		opts.LokiEndpoint = settings.LokiEndpoint
+	}
+	if opts.Collector == "" {
+		opts.Collector = logging.CollectorAlloy
+	}
+	if opts.LokiEndpoint == "" {
+		opts.LokiEndpoint = logging.DefaultLokiEndpoint
+	}
+	return opts
+}
+
+// GenerateLogShipping writes the log collector into the GitOps repository
+func (o *Orchestrator) GenerateLogShipping(opts logging.Options) ([]string, error) {
+	return logging.NewGenerator(o.projectRoot, o.localClusterName(), opts).Generate()
+}
+
+// ValidateLogShipping checks the collector and that the NAS Loki receives logs from this cluster
+func (o *Orchestrator) ValidateLogShipping(ctx context.Context, opts logging.Options) (*logging.Status, error) {
+	lokiClient := o.k8sClient
+	if !o.isNAS {
+		peer, err := o.buildPeerClient()
+		if err != nil {
+			log.Warn("Failed to connect to the NAS cluster, skipping Loki delivery check", "error", err)
+		}
+		lokiClient = peer
+	}
+	return logging.NewValidator(o.k8sClient, lokiClient).Validate(ctx, o.localClusterName(), opts.Collector, opts.LokiEndpoint)
+}
+
+func (o *Orchestrator) setupLogShipping(ctx context.Context) error {
+	opts := o.LoggingOptions()
+	written, err := o.GenerateLogShipping(opts)
+	if err != nil {
+		return err
+	}
+	log.Info("Log shipping generated; commit and push it for Flux to apply", "files", len(written))
+
+	if exists, err := o.k8sClient.NamespaceExists(ctx, logging.Namespace); err != nil || !exists {
+		log.Info("Log collector not deployed yet, validation skipped until Flux applies it")
+		return nil
+	}
+	_, err = o.ValidateLogShipping(ctx, opts)
+	return err
+}
@@ -0,0 +1,104 @@
+package bootstrap
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/discovery"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+)
+
+// meshPeer is another mesh member the local cluster shares its CA and remote secrets with
+type meshPeer struct {
+	name        string
+	kubeconfig  string
+	kubeContext string
+	// fallbacks are east-west gateway addresses used when the gateway service publishes none
+	fallbacks []string
+}
+
+// client connects to the peer cluster
+func (p meshPeer) client() (*k8s.Client, error) {
+	if p.kubeconfig == "" {
+		return nil, fmt.Errorf("%s kubeconfig path not configured", p.name)
+	}
+	return k8s.NewClientWithContext(p.kubeconfig, p.kubeContext)
+}
+
+// meshPeers returns every other member of the mesh, the single homelab/NAS peer unless clusters are declared
+func (o *Orchestrator) meshPeers() []meshPeer {
+	if !o.config.MeshDeclared() {
+		return []meshPeer{o.resolvePeer(meshPeer{
+			name:       o.peerClusterName(),
+			kubeconfig: o.peerKubeconfigPath(),
+			fallbacks:  o.peerGatewayFallbacks(),
+		})}
+	}
+
+	local := o.localClusterName()
+	var peers []meshPeer
+	for _, cluster := range o.config.MeshClusters() {
+		if cluster.Name == local {
+			continue
+		}
+		peer := meshPeer{
+			name:        cluster.Name,
+			kubeconfig:  cluster.KubeConfig,
+			kubeContext: cluster.KubeContext,
+			fallbacks:   cluster.GatewayHosts,
+		}
+		if peer.kubeconfig == "" {
+			peer.kubeconfig = o.resolveKubeconfig("", config.EnvKeyPrefix(cluster.Name)+"_KUBECONFIG_PATH",
+				filepath.Join("infrastructure", cluster.Name, "kubeconfig.yaml"))
+		}
+		if len(peer.fallbacks) == 0 {
+			addrKey, _ := gatewayVarKeys(cluster.Name)
+			if host := o.lookupEnvValue(addrKey); host != "" {
+				peer.fallbacks = []string{host}
+			}
+		}
+		peers = append(peers, o.resolvePeer(peer))
+	}
+	return peers
+}
+
+// resolvePeer completes the peer with what cluster discovery recorded and makes its kubeconfig absolute
+func (o *Orchestrator) resolvePeer(peer meshPeer) meshPeer {
+	if discoveryService := discovery.NewClusterDiscovery(o.projectRoot); discoveryService != nil {
+		if info, err := discoveryService.GetCluster(peer.name); err == nil {
+			if peer.kubeconfig == "" {
+				peer.kubeconfig = info.Kubeconfig
+			}
+			if peer.kubeContext == "" {
+				peer.kubeContext = info.Context
+			}
+		}
+	}
+	if peer.kubeconfig != "" && !filepath.IsAbs(peer.kubeconfig) {
+		if abs, err := filepath.Abs(peer.kubeconfig); err == nil {
+			peer.kubeconfig = abs
+		}
+	}
+	return peer
+}
+
+// peerNames lists the names of peers for logging
+func peerNames(peers []meshPeer) []string {
+	names := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		names = append(names, peer.name)
+	}
+	return names
+}
+
+// gatewayVarKeys returns the cluster-vars keys holding the east-west gateway address and port of a cluster
+func gatewayVarKeys(cluster string) (string, string) {
+	prefix := config.EnvKeyPrefix(cluster)
+	return prefix + "_EW_GATEWAY_ADDR", prefix + "_EW_GATEWAY_PORT"
+}
+
+// remoteSecretEnvKey returns the .env.generated key caching the remote secret of a cluster
+func remoteSecretEnvKey(cluster string) string {
+	return fmt.Sprintf("ISTIO_REMOTE_SECRET_%s_B64", config.EnvKeyPrefix(cluster))
+}
@@ -0,0 +1,275 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/discovery"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	smokeNamespace   = "mesh-smoke"
+	smokeClient      = "smoke-client"
+	smokeEchoImage   = "mccutchen/go-httpbin:v2.15.0"
+	smokeClientImage = "curlimages/curl:8.10.1"
+	smokeEchoPort    = 8080
+	// smokeRequests are sent per direction, the reported latency is their median
+	smokeRequests = 5
+)
+
+// xfccPattern extracts the server (By) and client (URI) SPIFFE identities of an X-Forwarded-Client-Cert header
+var xfccPattern = regexp.MustCompile(`By=(spiffe://[^;,"]+).*?URI=(spiffe://[^;,"]+)`)
+
+// SmokeReport is the outcome of the cross-cluster smoke test, one result per direction
+type SmokeReport struct {
+	Results []SmokeResult `json:"results"`
+	Passed  bool          `json:"passed"`
+}
+
+// SmokeResult is a request path from a client cluster to an echo server on its peer
+type SmokeResult struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	Passed         bool    `json:"passed"`
+	ServerIdentity string  `json:"server_identity,omitempty"`
+	ClientIdentity string  `json:"client_identity,omitempty"`
+	LatencyMillis  float64 `json:"latency_ms,omitempty"`
+	Error          string  `json:"error,omitempty"`
+}
+
+type smokeCluster struct {
+	name   string
+	client *k8s.Client
+	info   *discovery.ClusterInfo
+}
+
+// SmokeTestMesh deploys a short-lived echo server on each cluster and a curl client on its peer, then checks
+// every direction answers through the east-west gateway with the expected mTLS identities.
+// The test namespace is removed afterwards unless keep is set.
+func SmokeTestMesh(ctx context.Context, keep bool, overrides ...ClusterOverride) (*SmokeReport, error) {
+	if k8s.ReadOnly() {
+		return nil, fmt.Errorf("the smoke test deploys workloads, which read-only mode forbids")
+	}
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	contexts, err := discovery.NewClusterDiscovery(projectRoot).ListContexts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kube contexts: %w", err)
+	}
+	applyClusterOverrides(contexts, overrides)
+
+	var clusters []*smokeCluster
+	for _, name := range []string{"homelab", "nas"} {
+		info, ok := contexts[name]
+		if !ok {
+			return nil, fmt.Errorf("%s context not found; run bootstrap %s install first", name, name)
+		}
+		client, err := k8s.NewClientWithContext(info.Kubeconfig, info.Context)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s Kubernetes client: %w", name, err)
+		}
+		clusters = append(clusters, &smokeCluster{name: name, client: client, info: info})
+	}
+
+	if !keep {
+		defer func() {
+			for _, cluster := range clusters {
+				err := cluster.client.GetClientset().CoreV1().Namespaces().Delete(context.Background(), smokeNamespace, metav1.DeleteOptions{})
+				if err != nil && !apierrors.IsNotFound(err) {
+					log.Warn("Failed to remove the smoke test namespace", "cluster", cluster.name, "error", err)
+				}
+			}
+		}()
+	}
+
+	// Every cluster declares every echo service so the name resolves, only the owner runs its pods
+	for _, cluster := range clusters {
+		log.Info("Deploying smoke test workloads", "cluster", cluster.name)
+		if err := deploySmokeWorkloads(ctx, cluster, clusters); err != nil {
+			return nil, fmt.Errorf("%s: %w", cluster.name, err)
+		}
+	}
+	for _, cluster := range clusters {
+		for _, name := range []string{"echo-" + cluster.name, smokeClient} {
+			if err := cluster.client.WaitForDeployment(ctx, smokeNamespace, name, waitTimeout(ctx, defaultWaitTimeout)); err != nil {
+				return nil, fmt.Errorf("%s: %s not ready: %w", cluster.name, name, err)
+			}
+		}
+	}
+
+	report := &SmokeReport{Passed: true}
+	for _, from := range clusters {
+		for _, to := range clusters {
+			if from == to {
+				continue
+			}
+			result := smokeDirection(ctx, from, to)
+			if !result.Passed {
+				report.Passed = false
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report, nil
+}
+
+// deploySmokeWorkloads creates the enrolled namespace, the echo server of cluster, every echo service and the client
+func deploySmokeWorkloads(ctx context.Context, cluster *smokeCluster, all []*smokeCluster) error {
+	core := cluster.client.GetClientset().CoreV1()
+	labels := map[string]string{"istio-injection": "enabled"}
+	if detectMeshMode(ctx, cluster.client) == config.MeshModeAmbient {
+		labels = map[string]string{"istio.io/dataplane-mode": "ambient"}
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: smokeNamespace, Labels: labels}}
+	if _, err := core.Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create namespace %s: %w", smokeNamespace, err)
+	}
+
+	echo := "echo-" + cluster.name
+	for _, name := range []string{echo, smokeClient} {
+		sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: smokeNamespace}}
+		if _, err := core.ServiceAccounts(smokeNamespace).Create(ctx, sa, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create service account %s: %w", name, err)
+		}
+	}
+
+	for _, owner := range all {
+		name := "echo-" + owner.name
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: smokeNamespace, Labels: map[string]string{"app": name}},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": name},
+				Ports:    []corev1.ServicePort{{Name: "http", Port: smokeEchoPort, TargetPort: intstr.FromInt(smokeEchoPort)}},
+			},
+		}
+		if _, err := core.Services(smokeNamespace).Create(ctx, svc, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create service %s: %w", name, err)
+		}
+	}
+
+	deployments := []*appsv1.Deployment{
+		smokeDeployment(echo, corev1.Container{
+			Name:  "echo",
+			Image: smokeEchoImage,
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: smokeEchoPort}},
+		}),
+		smokeDeployment(smokeClient, corev1.Container{
+			Name:    "curl",
+			Image:   smokeClientImage,
+			Command: []string{"sleep", "infinity"},
+		}),
+	}
+	for _, deployment := range deployments {
+		_, err := cluster.client.GetClientset().AppsV1().Deployments(smokeNamespace).Create(ctx, deployment, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create deployment %s: %w", deployment.Name, err)
+		}
+	}
+	return nil
+}
+
+func smokeDeployment(name string, container corev1.Container) *appsv1.Deployment {
+	labels := map[string]string{"app": name}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: smokeNamespace, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To[int32](1),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: name,
+					Containers:         []corev1.Container{container},
+				},
+			},
+		},
+	}
+}
+
+// smokeDirection sends requests from the client of from to the echo server of to, which only runs on to
+func smokeDirection(ctx context.Context, from, to *smokeCluster) SmokeResult {
+	result := SmokeResult{From: from.name, To: to.name}
+	url := fmt.Sprintf("http://echo-%s.%s.svc.cluster.local:%d/headers", to.name, smokeNamespace, smokeEchoPort)
+
+	var latencies []float64
+	var body string
+	for i := 0; i < smokeRequests; i++ {
+		out, err := smokeCurl(ctx, from.info, url)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		// The last line holds the total time curl measured
+		idx := strings.LastIndex(out, "\n")
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(out[idx+1:]), 64)
+		if err != nil {
+			result.Error = fmt.Sprintf("unexpected curl output: %s", trimOutput(out, 5))
+			return result
+		}
+		latencies = append(latencies, seconds*1000)
+		body = out[:max(idx, 0)]
+	}
+	sort.Float64s(latencies)
+	result.LatencyMillis = latencies[len(latencies)/2]
+
+	match := xfccPattern.FindStringSubmatch(body)
+	if match == nil {
+		if detectMeshMode(ctx, to.client) == config.MeshModeAmbient {
+			// ztunnel tunnels mTLS without rewriting headers, reaching the remote echo proves the HBONE path
+			result.Passed = true
+			return result
+		}
+		result.Error = "the reply carries no X-Forwarded-Client-Cert, the request was not sent over mTLS"
+		return result
+	}
+	result.ServerIdentity, result.ClientIdentity = match[1], match[2]
+	switch {
+	case !strings.HasSuffix(result.ServerIdentity, "/ns/"+smokeNamespace+"/sa/echo-"+to.name):
+		result.Error = fmt.Sprintf("server identity %s is not the echo server of %s", result.ServerIdentity, to.name)
+	case !strings.HasSuffix(result.ClientIdentity, "/ns/"+smokeNamespace+"/sa/"+smokeClient):
+		result.Error = fmt.Sprintf("client identity %s is not the smoke test client", result.ClientIdentity)
+	default:
+		result.Passed = true
+	}
+	return result
+}
+
+// smokeCurl runs curl in the client pod, printing the body then the total time on its own line
+func smokeCurl(ctx context.Context, info *discovery.ClusterInfo, url string) (string, error) {
+	args := []string{"--kubeconfig", info.Kubeconfig}
+	if strings.TrimSpace(info.Context) != "" {
+		args = append(args, "--context", info.Context)
+	}
+	args = append(args,
+		"-n", smokeNamespace,
+		"exec", "deploy/"+smokeClient, "-c", "curl",
+		"--",
+		"curl", "-sS", "--fail", "--max-time", "10", "-w", `\n%{time_total}`, url,
+	)
+
+	cmdCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(cmdCtx, "kubectl", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("curl %s failed: %w (output: %s)", url, err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,197 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// MeshReport summarises the multi-cluster mesh state as seen from both clusters
+type MeshReport struct {
+	Clusters []ClusterMeshReport `json:"clusters"`
+	CAMatch  bool                `json:"ca_match"`
+}
+
+// ClusterMeshReport holds the mesh state of a single cluster
+type ClusterMeshReport struct {
+	Cluster            string           `json:"cluster"`
+	Error              string           `json:"error,omitempty"`
+	IstiodVersion      string           `json:"istiod_version,omitempty"`
+	IstiodReady        bool             `json:"istiod_ready"`
+	IstiodReplicas     string           `json:"istiod_replicas,omitempty"`
+	ZtunnelReady       int32            `json:"ztunnel_ready"`
+	ZtunnelDesired     int32            `json:"ztunnel_desired"`
+	Nodes              int              `json:"nodes"`
+	GatewayEndpoint    string           `json:"gateway_endpoint,omitempty"`
+	GatewayReachable   bool             `json:"gateway_reachable"`
+	GatewayProbe       string           `json:"gateway_probe,omitempty"`
+	CAFingerprint      string           `json:"ca_fingerprint,omitempty"`
+	RemoteSecret       RemoteSecretInfo `json:"remote_secret"`
+	EnrolledNamespaces []string         `json:"enrolled_namespaces"`
+}
+
+type meshTarget struct {
+	name   string
+	client *k8s.Client
+}
+
+// MeshStatusReport collects istiod, ztunnel, gateway, CA, remote secret and enrollment state for both clusters
+func (o *Orchestrator) MeshStatusReport(ctx context.Context) *MeshReport {
+	local := meshTarget{name: o.localClusterName(), client: o.k8sClient}
+	peer := meshTarget{name: o.peerClusterName()}
+
+	peerClient, peerErr := o.buildPeerClient()
+	if peerErr == nil {
+		peer.client = peerClient
+	}
+
+	secrets := map[string]RemoteSecretInfo{}
+	for _, info := range o.RemoteSecretStatus(ctx) {
+		secrets[info.InstalledIn] = info
+	}
+
+	localReport := collectClusterMesh(ctx, local)
+	localReport.RemoteSecret = secrets[local.name]
+
+	var peerReport ClusterMeshReport
+	if peer.client == nil {
+		peerReport = ClusterMeshReport{Cluster: peer.name, Error: peerErr.Error(), RemoteSecret: secrets[peer.name]}
+	} else {
+		peerReport = collectClusterMesh(ctx, peer)
+		peerReport.RemoteSecret = secrets[peer.name]
+		probeGateway(ctx, &localReport, peer)
+		probeGateway(ctx, &peerReport, local)
+	}
+
+	return &MeshReport{
+		Clusters: []ClusterMeshReport{localReport, peerReport},
+		CAMatch:  localReport.CAFingerprint != "" && localReport.CAFingerprint == peerReport.CAFingerprint,
+	}
+}
+
+func collectClusterMesh(ctx context.Context, target meshTarget) ClusterMeshReport {
+	report := ClusterMeshReport{Cluster: target.name}
+	clientset := target.client.GetClientset()
+
+	if err := target.client.IsReady(ctx); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	if istiod, err := clientset.AppsV1().Deployments(istioNamespace).Get(ctx, "istiod", metav1.GetOptions{}); err == nil {
+		desired := int32(1)
+		if istiod.Spec.Replicas != nil {
+			desired = *istiod.Spec.Replicas
+		}
+		report.IstiodReady = istiod.Status.ReadyReplicas >= desired
+		report.IstiodReplicas = fmt.Sprintf("%d/%d", istiod.Status.ReadyReplicas, desired)
+		for _, container := range istiod.Spec.Template.Spec.Containers {
+			if container.Name == "discovery" {
+				report.IstiodVersion = k8s.ImageTag(container.Image)
+			}
+		}
+	}
+
+	if ztunnel, err := clientset.AppsV1().DaemonSets(istioNamespace).Get(ctx, "ztunnel", metav1.GetOptions{}); err == nil {
+		report.ZtunnelReady = ztunnel.Status.NumberReady
+		report.ZtunnelDesired = ztunnel.Status.DesiredNumberScheduled
+	}
+
+	if nodes, err := target.client.GetNodes(ctx); err == nil {
+		report.Nodes = len(nodes)
+	}
+
+	if svc, err := target.client.GetService(ctx, istioNamespace, eastWestServiceName); err == nil {
+		if endpoint := endpointFromService(svc); endpoint != nil && endpoint.Host != "" {
+			report.GatewayEndpoint = net.JoinHostPort(endpoint.Host, strconv.Itoa(int(endpoint.Port)))
+		}
+	}
+
+	if ca, err := target.client.GetSecret(ctx, istioNamespace, "cacerts"); err == nil {
+		if root := ca.Data["root-cert.pem"]; len(root) > 0 {
+			report.CAFingerprint = fingerprint(root)
+		}
+	} else if !apierrors.IsNotFound(err) {
+		report.Error = fmt.Sprintf("failed to read cacerts: %v", err)
+	}
+
+	if namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, ns := range namespaces.Items {
+			labels := ns.GetLabels()
+			if labels["istio-injection"] == "enabled" || labels["istio.io/dataplane-mode"] == "ambient" || labels["istio.io/rev"] != "" {
+				report.EnrolledNamespaces = append(report.EnrolledNamespaces, ns.Name)
+			}
+		}
+		sort.Strings(report.EnrolledNamespaces)
+	}
+
+	return report
+}
+
+// probeGateway checks that the gateway of report is reachable from inside the peer's east-west gateway,
+// falling back to a dial from this machine when exec into the peer is not possible, the proxy image
+// shipping no bash for instance
+func probeGateway(ctx context.Context, report *ClusterMeshReport, from meshTarget) {
+	if report.GatewayEndpoint == "" {
+		return
+	}
+	host, port, err := net.SplitHostPort(report.GatewayEndpoint)
+	if err != nil {
+		return
+	}
+
+	// exec runs a command in the peer's pod, which read-only mode forbids
+	if !k8s.ReadOnly() {
+		if pod := eastWestGatewayPod(ctx, from.client); pod != "" {
+			execCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+			defer cancel()
+
+			command := []string{"timeout", "5", "bash", "-c", fmt.Sprintf("</dev/tcp/%s/%s", host, port)}
+			_, err := from.client.Exec(execCtx, istioNamespace, pod, "istio-proxy", command)
+			if err == nil {
+				report.GatewayReachable = true
+				report.GatewayProbe = from.name
+				return
+			}
+			log.Debug("Could not probe the east-west gateway from the peer, dialing it from here", "cluster", from.name, "error", err)
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", report.GatewayEndpoint, 5*time.Second)
+	report.GatewayProbe = "local"
+	if err != nil {
+		return
+	}
+	conn.Close()
+	report.GatewayReachable = true
+}
+
+// eastWestGatewayPod returns a running pod of the east-west gateway, empty when there is none
+func eastWestGatewayPod(ctx context.Context, client *k8s.Client) string {
+	svc, err := client.GetService(ctx, istioNamespace, eastWestServiceName)
+	if err != nil || len(svc.Spec.Selector) == 0 {
+		return ""
+	}
+	pods, err := client.GetClientset().CoreV1().Pods(istioNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return ""
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			return pod.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/metrics"
+)
+
+// federationConfig returns the metrics federation section of the active cluster configuration
+func (o *Orchestrator) federationConfig() *config.FederationConfig {
+	if o.isNAS && o.config.NAS != nil {
+		return &o.config.NAS.Monitoring.Federation
+	}
+	if !o.isNAS && o.config.Homelab != nil {
+		return &o.config.Homelab.Monitoring.Federation
+	}
+	return nil
+}
+
+// withFederationStep inserts the optional metrics federation step when federation is enabled
+func (o *Orchestrator) withFederationStep(steps []BootstrapStep) []BootstrapStep {
+	settings := o.federationConfig()
+	if settings == nil || !settings.Enabled {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "metrics-federation",
+		Description: "Federate homelab metrics into the NAS receiver",
+		Required:    false,
+		Execute:     o.setupMetricsFederation,
+	}, "bootstrap-gitops", "policy-engine", "runtime-security", "log-shipping")
+}
+
+// FederationOptions returns the metrics federation options of the active cluster configuration
+func (o *Orchestrator) FederationOptions() metrics.Options {
+	var opts metrics.Options
+	if settings := o.federationConfig(); settings != nil {
+		opts.Endpoint = settings.Endpoint
+		opts.Retention = settings.Retention
+		opts.StorageClass = settings.StorageClass
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = metrics.DefaultEndpoint
+	}
+	return opts
+}
+
+// GenerateFederation writes the receiver (NAS) or the remote-write agent (homelab) into the GitOps repository
+func (o *Orchestrator) GenerateFederation(opts metrics.Options) ([]string, error) {
+	return metrics.NewGenerator(o.projectRoot, o.localClusterName(), opts).Generate()
+}
+
+// ConfigureFederationAuth creates the federation credentials on both clusters
+func (o *Orchestrator) ConfigureFederationAuth(ctx context.Context, opts metrics.Options) error {
+	federation, err := o.newFederation(opts)
+	if err != nil {
+		return err
+	}
+
+	var username string
+	if settings := o.federationConfig(); settings != nil {
+		username = settings.Username
+	}
+	return federation.ConfigureAuth(ctx, username)
+}
+
+// ValidateFederation proves homelab metrics are queryable from the NAS
+func (o *Orchestrator) ValidateFederation(ctx context.Context, opts metrics.Options) (*metrics.Status, error) {
+	federation, err := o.newFederation(opts)
+	if err != nil {
+		return nil, err
+	}
+	return federation.Validate(ctx, "homelab")
+}
+
+func (o *Orchestrator) setupMetricsFederation(ctx context.Context) error {
+	opts := o.FederationOptions()
+	written, err := o.GenerateFederation(opts)
+	if err != nil {
+		return err
+	}
+	log.Info("Metrics federation generated; commit and push it for Flux to apply", "files", len(written))
+
+	if err := o.ConfigureFederationAuth(ctx, opts); err != nil {
+		return err
+	}
+
+	if _, err := o.ValidateFederation(ctx, opts); err != nil {
+		log.Warn("Metrics federation not verified yet, re-run 'bootstrap metrics validate' once Flux has applied it", "error", err)
+	}
+	return nil
+}
+
+// newFederation resolves the homelab and NAS clients regardless of the local cluster
+func (o *Orchestrator) newFederation(opts metrics.Options) (*metrics.Federation, error) {
+	peer, err := o.buildPeerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the %s cluster: %w", o.peerClusterName(), err)
+	}
+
+	var homelab, nas *k8s.Client = o.k8sClient, peer
+	if o.isNAS {
+		homelab, nas = peer, o.k8sClient
+	}
+	return metrics.NewFederation(homelab, nas, opts.Endpoint), nil
+}
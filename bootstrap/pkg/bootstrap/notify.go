@@ -0,0 +1,27 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/notify"
+)
+
+// newNotifier builds the notifier of the cluster, an invalid sink disables notifications rather than the bootstrap
+func newNotifier(cfg *config.Config, isNAS bool) *notify.Notifier {
+	notifier, err := notify.NewNotifier(cfg.NotificationsFor(isNAS))
+	if err != nil {
+		log.Warn("Notifications disabled", "error", err)
+		return nil
+	}
+	return notifier
+}
+
+// notify sends an event about the local cluster to the configured sinks
+func (o *Orchestrator) notify(ctx context.Context, event notify.Event) {
+	if event.Cluster == "" {
+		event.Cluster = o.localClusterName()
+	}
+	o.notifier.Notify(ctx, event)
+}
@@ -9,16 +9,20 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/fredericrous/homelab/bootstrap/pkg/backup"
+	"github.com/fredericrous/homelab/bootstrap/pkg/certmanager"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
 	"github.com/fredericrous/homelab/bootstrap/pkg/discovery"
+	"github.com/fredericrous/homelab/bootstrap/pkg/externalsecrets"
 	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
 	"github.com/fredericrous/homelab/bootstrap/pkg/health"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
 	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/notify"
 	"github.com/fredericrous/homelab/bootstrap/pkg/observability"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
 	"github.com/fredericrous/homelab/bootstrap/pkg/resources"
 	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
-	"github.com/fredericrous/homelab/bootstrap/pkg/security"
 	"github.com/fredericrous/homelab/bootstrap/pkg/vault"
 )
 
@@ -44,6 +48,8 @@ type Orchestrator struct {
 	kubeconfigPath string
 	kubeContext    string
 	options        *OrchestratorOptions
+	notifier       *notify.Notifier
+	progress       *progress.Bus
 }
 
 // OrchestratorOptions allows callers to override kubeconfig discovery.
@@ -52,6 +58,14 @@ type OrchestratorOptions struct {
 	Context               string
 	HomelabKubeconfigPath string
 	NASKubeconfigPath     string
+	// Resume restarts the bootstrap at the step the last interrupted run stopped at
+	Resume bool
+	// FromStep restarts the bootstrap at the named step
+	FromStep string
+	// GatewayCertRenewBefore regenerates the east-west gateway certificate when it expires sooner, zero disables renewal
+	GatewayCertRenewBefore time.Duration
+	// Report controls where Bootstrap writes its report
+	Report ReportOptions
 }
 
 // NewOrchestrator creates a new bootstrap orchestrator
@@ -81,13 +95,21 @@ func NewOrchestrator(cfg *config.Config, isNAS bool, opts ...*OrchestratorOption
 
 	if isNAS {
 		clusterName = "nas"
-		if kubeconfig == "" && cfg.NAS != nil {
-			kubeconfig = cfg.NAS.Cluster.KubeConfig
+		if cfg.NAS != nil {
+			if kubeconfig == "" {
+				kubeconfig = cfg.NAS.Cluster.KubeConfig
+			}
+			if kubeContext == "" {
+				kubeContext = cfg.NAS.Cluster.KubeContext
+			}
 		}
 	} else if cfg.Homelab != nil {
 		if kubeconfig == "" {
 			kubeconfig = cfg.Homelab.Cluster.KubeConfig
 		}
+		if kubeContext == "" {
+			kubeContext = cfg.Homelab.Cluster.KubeContext
+		}
 	} else {
 		return nil, fmt.Errorf("invalid configuration for orchestrator")
 	}
@@ -129,6 +151,7 @@ func NewOrchestrator(cfg *config.Config, isNAS bool, opts ...*OrchestratorOption
 		"context", kubeContext)
 
 	secretsManager := secrets.NewManager(k8sClient, projectRoot)
+	secretsManager.SetMeshClusters(cfg.MeshClusterNames())
 
 	toRelative := func(path string) string {
 		if path == "" {
@@ -173,6 +196,8 @@ func NewOrchestrator(cfg *config.Config, isNAS bool, opts ...*OrchestratorOption
 		kubeconfigPath: absKubeconfig,
 		kubeContext:    kubeContext,
 		options:        options,
+		notifier:       newNotifier(cfg, isNAS),
+		progress:       progress.NewBus(),
 	}, nil
 }
 
@@ -183,71 +208,85 @@ type BootstrapStep struct {
 	Required    bool
 	Execute     func(ctx context.Context) error
 	Rollback    func(ctx context.Context) error
+	// Namespaces whose Warning events are streamed while the step runs
+	Namespaces []string
+	// Timeout bounds each attempt, waits inside the step share it; none when zero
+	Timeout time.Duration
+	// Retries is how many times a failed attempt is retried
+	Retries int
+	// Backoff is the first retry delay, doubled with jitter on each retry
+	Backoff time.Duration
+}
+
+// StepInfo describes a bootstrap step to callers that display or select steps
+type StepInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// Steps describes the steps a bootstrap of this cluster runs, in order
+func (o *Orchestrator) Steps() []StepInfo {
+	return stepInfos(o.getBootstrapSteps())
+}
+
+func stepInfos(steps []BootstrapStep) []StepInfo {
+	infos := make([]StepInfo, 0, len(steps))
+	for _, step := range steps {
+		infos = append(infos, StepInfo{Name: step.Name, Description: step.Description, Required: step.Required})
+	}
+	return infos
 }
 
 type stepMetric struct {
 	name     string
 	duration time.Duration
 	success  bool
+	err      error
 }
 
 // Bootstrap executes the complete bootstrap process
 func (o *Orchestrator) Bootstrap(ctx context.Context) error {
-	log.Info("Starting bootstrap process", "type", o.getClusterType())
-
-	steps := o.getBootstrapSteps()
-	rollbacks := make([]func(context.Context) error, 0, len(steps))
-	metrics := make([]stepMetric, 0, len(steps))
-
-	for i, step := range steps {
-		log.Info("Executing bootstrap step",
-			"step", i+1,
-			"total", len(steps),
-			"name", step.Name,
-			"description", step.Description)
-
-		startTime := time.Now()
-		err := step.Execute(ctx)
-		duration := time.Since(startTime)
-		metrics = append(metrics, stepMetric{name: step.Name, duration: duration, success: err == nil})
+	driver, err := o.NewStepDriver(ctx)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			log.Error("Bootstrap step failed",
-				"step", step.Name,
-				"error", err,
-				"duration", duration)
-			o.emitStepMetric(step.Name, duration, false)
-
-			if step.Required {
-				o.runRollbacks(ctx, rollbacks)
-				return fmt.Errorf("required step '%s' failed: %w", step.Name, err)
+	for !driver.Done() {
+		if err := driver.Run(ctx); err != nil {
+			if driver.Current().Required {
+				return driver.Fail(ctx)
+			}
+			if err := driver.Skip(); err != nil {
+				return err
 			}
-
-			log.Warn("Optional step failed, continuing", "step", step.Name)
-			continue
-		}
-
-		log.Info("Bootstrap step completed",
-			"step", step.Name,
-			"completed_in", duration)
-		o.emitStepMetric(step.Name, duration, true)
-
-		if step.Rollback != nil {
-			rollbacks = append([]func(context.Context) error{step.Rollback}, rollbacks...)
 		}
 	}
-
-	o.logBootstrapSummary(metrics)
-	log.Info("Bootstrap process completed successfully")
-	return nil
+	return driver.Finish(ctx)
 }
 
 // getBootstrapSteps returns the steps for bootstrap based on cluster type
 func (o *Orchestrator) getBootstrapSteps() []BootstrapStep {
+	var steps []BootstrapStep
 	if o.isNAS {
-		return o.getNASBootstrapSteps()
+		steps = o.getNASBootstrapSteps()
+	} else {
+		steps = o.getHomelabBootstrapSteps()
+	}
+	return o.withStepPolicies(o.withNamespaceBaselineStep(o.withSLOStep(o.withFederationStep(o.withLoggingStep(o.withFalcoStep(o.withPolicyStep(o.withIngressValidationStep(o.withCephHealthStep(o.withLoadBalancerStep(o.withRegistryStep(o.withProxmoxStep(steps))))))))))))
+}
+
+// insertStepAfter inserts step after the last of the named steps present, or appends it
+func insertStepAfter(steps []BootstrapStep, step BootstrapStep, after ...string) []BootstrapStep {
+	position := len(steps)
+	for i, existing := range steps {
+		for _, name := range after {
+			if existing.Name == name {
+				position = i + 1
+			}
+		}
 	}
-	return o.getHomelabBootstrapSteps()
+	return append(steps[:position], append([]BootstrapStep{step}, steps[position:]...)...)
 }
 
 // getHomelabBootstrapSteps returns homelab-specific bootstrap steps
@@ -264,24 +303,29 @@ func (o *Orchestrator) getHomelabBootstrapSteps() []BootstrapStep {
 			Description: "Install Cilium CNI",
 			Required:    true,
 			Execute:     o.installCilium,
+			Namespaces:  []string{"kube-system"},
 		},
 		{
 			Name:        "wait-nodes",
 			Description: "Wait for all nodes to be ready",
 			Required:    true,
 			Execute:     o.waitForNodes,
+			Namespaces:  []string{"kube-system"},
+			Timeout:     10 * time.Minute,
 		},
 		{
 			Name:        "install-fluxcd",
 			Description: "Install FluxCD GitOps controller",
 			Required:    true,
 			Execute:     o.installFluxCD,
+			Namespaces:  []string{"flux-system"},
 		},
 		{
 			Name:        "bootstrap-gitops",
 			Description: "Bootstrap GitOps repository sync",
 			Required:    true,
 			Execute:     o.bootstrapGitOps,
+			Namespaces:  []string{"flux-system"},
 		},
 		{
 			Name:        "setup-secrets",
@@ -307,17 +351,48 @@ func (o *Orchestrator) getHomelabBootstrapSteps() []BootstrapStep {
 			Description: "Wait for infrastructure components to be ready",
 			Required:    false,
 			Execute:     o.waitForInfrastructure,
+			Namespaces:  allNamespaces,
+		},
+		{
+			Name:        "setup-external-secrets",
+			Description: "Connect the External Secrets Operator to the NAS Vault",
+			Required:    false,
+			Execute:     o.setupExternalSecrets,
+			Namespaces:  []string{externalsecrets.Namespace},
+		},
+		{
+			Name:        "setup-vault-pki",
+			Description: "Sign the homelab Vault intermediate CA with the NAS root",
+			Required:    false,
+			Execute:     o.setupVaultPKI,
+			Namespaces:  []string{homelabVaultNamespace},
+		},
+		{
+			Name:        "validate-cert-manager",
+			Description: "Wait for the cert-manager webhook and validate ClusterIssuers",
+			Required:    false,
+			Execute:     o.validateCertManager,
+			Namespaces:  []string{certmanager.Namespace},
 		},
 		{
 			Name:        "finalize-istio-mesh",
 			Description: "Publish gateway endpoints and verify cross-cluster readiness",
 			Required:    true,
 			Execute:     o.finalizeIstioMesh,
+			Namespaces:  []string{istioNamespace},
+			Timeout:     15 * time.Minute,
+			Retries:     1,
+		},
+		{
+			Name:        "garbage-collect",
+			Description: "Remove stale pending remote secrets and generated env keys",
+			Required:    false,
+			Execute:     o.garbageCollect,
 		},
 		{
 			Name:        "validate-deployment",
 			Description: "Validate complete deployment",
-			Required:    false,
+			Required:    o.smokeTestConfig().Enabled, // A failed storage smoke test stops the bootstrap
 			Execute:     o.validateDeployment,
 		},
 		{
@@ -343,12 +418,14 @@ func (o *Orchestrator) getNASBootstrapSteps() []BootstrapStep {
 			Description: "Install FluxCD GitOps controller",
 			Required:    true,
 			Execute:     o.installFluxCD,
+			Namespaces:  []string{"flux-system"},
 		},
 		{
 			Name:        "bootstrap-gitops",
 			Description: "Bootstrap GitOps repository sync",
 			Required:    true,
 			Execute:     o.bootstrapGitOps,
+			Namespaces:  []string{"flux-system"},
 		},
 		{
 			Name:        "setup-secrets",
@@ -368,59 +445,32 @@ func (o *Orchestrator) getNASBootstrapSteps() []BootstrapStep {
 			Description: "Wait for NAS infrastructure to be ready",
 			Required:    false,
 			Execute:     o.waitForInfrastructure,
+			Namespaces:  allNamespaces,
 		},
 		{
 			Name:        "finalize-istio-mesh",
 			Description: "Publish gateway endpoints and verify cross-cluster readiness",
 			Required:    true,
 			Execute:     o.finalizeIstioMesh,
+			Namespaces:  []string{istioNamespace},
+			Timeout:     15 * time.Minute,
+			Retries:     1,
+		},
+		{
+			Name:        "garbage-collect",
+			Description: "Remove stale pending remote secrets and generated env keys",
+			Required:    false,
+			Execute:     o.garbageCollect,
 		},
 		{
 			Name:        "validate-deployment",
 			Description: "Validate NAS deployment",
-			Required:    false,
+			Required:    o.smokeTestConfig().Enabled, // A failed storage smoke test stops the bootstrap
 			Execute:     o.validateDeployment,
 		},
 	}
 }
 
-// Public methods for TUI integration
-
-// VerifyCluster verifies cluster connectivity (public method for TUI)
-func (o *Orchestrator) VerifyCluster(ctx context.Context) error {
-	return o.verifyCluster(ctx)
-}
-
-// InstallCilium installs Cilium CNI (public method for TUI)
-func (o *Orchestrator) InstallCilium(ctx context.Context) error {
-	return o.installCilium(ctx)
-}
-
-// InstallFluxCD installs FluxCD (public method for TUI)
-func (o *Orchestrator) InstallFluxCD(ctx context.Context) error {
-	return o.installFluxCD(ctx)
-}
-
-// BootstrapGitOps bootstraps GitOps repository sync (public method for TUI)
-func (o *Orchestrator) BootstrapGitOps(ctx context.Context) error {
-	return o.bootstrapGitOps(ctx)
-}
-
-// SetupSecrets sets up cluster secrets (public method for TUI)
-func (o *Orchestrator) SetupSecrets(ctx context.Context) error {
-	return o.setupSecrets(ctx)
-}
-
-// WaitForInfrastructure waits for infrastructure to be ready (public method for TUI)
-func (o *Orchestrator) WaitForInfrastructure(ctx context.Context) error {
-	return o.waitForInfrastructure(ctx)
-}
-
-// ValidateDeployment validates the deployment (public method for TUI)
-func (o *Orchestrator) ValidateDeployment(ctx context.Context) error {
-	return o.validateDeployment(ctx)
-}
-
 // Step implementations
 
 func (o *Orchestrator) verifyCluster(ctx context.Context) error {
@@ -448,15 +498,19 @@ func (o *Orchestrator) installCilium(ctx context.Context) error {
 	log.Info("Installing Cilium CNI")
 
 	installer := infra.NewCiliumInstaller(o.k8sClient)
+	return installer.Install(ctx, o.ciliumConfig())
+}
 
-	ciliumConfig := infra.CiliumConfig{
+// ciliumConfig builds the Cilium settings from the homelab config
+func (o *Orchestrator) ciliumConfig() infra.CiliumConfig {
+	return infra.CiliumConfig{
 		ClusterPodCIDR: o.config.Homelab.Cluster.Networking.PodCIDR,
 		NodeEncryption: false, // TODO: make configurable
 		Hubble:         true,  // TODO: make configurable
-		LoadBalancer:   true,  // TODO: make configurable
+		LoadBalancer:   o.config.Homelab.LoadBalancer.Provider == infra.LoadBalancerCilium,
+		Version:        o.config.Homelab.Cilium.Version,
+		Values:         o.config.Homelab.Cilium.Values,
 	}
-
-	return installer.Install(ctx, ciliumConfig)
 }
 
 func (o *Orchestrator) waitForNodes(ctx context.Context) error {
@@ -469,8 +523,7 @@ func (o *Orchestrator) waitForNodes(ctx context.Context) error {
 		expectedNodes = len(o.config.Homelab.Cluster.Nodes)
 	}
 
-	timeout := 10 * time.Minute
-	return o.k8sClient.WaitForNodes(ctx, expectedNodes, timeout)
+	return o.k8sClient.WaitForNodes(ctx, expectedNodes, waitTimeout(ctx, 10*time.Minute))
 }
 
 func (o *Orchestrator) installFluxCD(ctx context.Context) error {
@@ -518,6 +571,24 @@ func (o *Orchestrator) bootstrapGitOps(ctx context.Context) error {
 	return nil
 }
 
+// createSOPSAgeSecret stores the age identity the flux-system Kustomization decrypts SOPS secrets with
+func (o *Orchestrator) createSOPSAgeSecret(ctx context.Context) error {
+	cfg := o.gitOpsConfig()
+	if cfg == nil || !cfg.SOPS.Enabled {
+		return nil
+	}
+
+	keyFile := cfg.SOPS.AgeKeyFile
+	if keyFile != "" && !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(o.projectRoot, keyFile)
+	}
+	key, err := secrets.LoadAgeKey(ctx, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load SOPS age key: %w", err)
+	}
+	return o.secretsManager.CreateSOPSAgeSecret(ctx, "flux-system", cfg.SOPS.Secret(), key)
+}
+
 func (o *Orchestrator) setupSecrets(ctx context.Context) error {
 	log.Info("Setting up cluster secrets and configurations")
 
@@ -532,31 +603,13 @@ func (o *Orchestrator) setupSecrets(ctx context.Context) error {
 		return fmt.Errorf("failed to create cluster-vars secret: %w", err)
 	}
 
+	if err := o.createSOPSAgeSecret(ctx); err != nil {
+		return err
+	}
+
 	// Create vault-transit-token secret (only for homelab)
 	if !o.isNAS {
-		log.Info("Setting up Vault transit token")
-
-		// Try existing secret manager first
-		if err := o.secretsManager.CreateVaultTransitTokenSecret(ctx, ""); err != nil {
-			log.Info("Attempting to auto-generate Vault transit token")
-
-			// Create transit manager for auto-generation
-			transitMgr := vault.NewTransitManager(o.k8sClient, o.projectRoot, o.isNAS)
-			token, genErr := transitMgr.EnsureTransitToken(ctx)
-
-			if genErr != nil {
-				log.Warn("Failed to auto-generate transit token", "error", genErr)
-				log.Info("You can manually set VAULT_TRANSIT_TOKEN in .env file later")
-				// Continue - vault integration can be set up later
-			} else {
-				// Store the generated token
-				if storeErr := o.secretsManager.CreateVaultTransitTokenSecret(ctx, token); storeErr != nil {
-					log.Warn("Failed to store generated transit token", "error", storeErr)
-				} else {
-					log.Info("Successfully generated and stored Vault transit token")
-				}
-			}
-		}
+		o.setupVaultTransitToken(ctx)
 	}
 
 	// Setup cross-cluster secrets is now handled by ensureRemoteSecret in Istio helpers
@@ -566,6 +619,35 @@ func (o *Orchestrator) setupSecrets(ctx context.Context) error {
 	return nil
 }
 
+// setupVaultTransitToken stores the Vault transit token from .env, or one generated on the NAS Vault, in the
+// vault-transit-token secrets. Failures only warn since the Vault integration can be set up later.
+func (o *Orchestrator) setupVaultTransitToken(ctx context.Context) {
+	log.Info("Setting up Vault transit token")
+
+	// Try existing secret manager first
+	if err := o.secretsManager.CreateVaultTransitTokenSecret(ctx, ""); err == nil {
+		return
+	}
+	log.Info("Attempting to auto-generate Vault transit token")
+
+	// Create transit manager for auto-generation
+	transitMgr := vault.NewTransitManager(o.k8sClient, o.projectRoot, o.isNAS)
+	token, genErr := transitMgr.EnsureTransitToken(ctx)
+
+	if genErr != nil {
+		log.Warn("Failed to auto-generate transit token", "error", genErr)
+		log.Info("You can manually set VAULT_TRANSIT_TOKEN in .env file later")
+		// Continue - vault integration can be set up later
+		return
+	}
+	// Store the generated token
+	if storeErr := o.secretsManager.CreateVaultTransitTokenSecret(ctx, token); storeErr != nil {
+		log.Warn("Failed to store generated transit token", "error", storeErr)
+	} else {
+		log.Info("Successfully generated and stored Vault transit token")
+	}
+}
+
 func (o *Orchestrator) waitForInfrastructure(ctx context.Context) error {
 	log.Info("Waiting for infrastructure components to be ready")
 
@@ -584,23 +666,60 @@ func (o *Orchestrator) waitForInfrastructure(ctx context.Context) error {
 
 	platformName := "platform-foundation"
 	controllersName := "controllers"
-	storageProvider := "ceph"
 	if o.isNAS {
 		platformName = "nas-platform-foundation"
 		controllersName = ""
-		if o.config.NAS != nil && o.config.NAS.Storage.Provider != "" {
-			storageProvider = o.config.NAS.Storage.Provider
-		} else {
-			storageProvider = "local-path"
-		}
-	} else if o.config.Homelab != nil && o.config.Homelab.Storage.Provider != "" {
-		storageProvider = o.config.Homelab.Storage.Provider
 	}
 
-	waiter := infra.NewWaiter(o.k8sClient, timeouts, platformName, controllersName, storageProvider)
+	waiter := infra.NewWaiter(o.k8sClient, timeouts, platformName, controllersName, o.storageProvider())
 	return waiter.WaitForInfrastructure(ctx)
 }
 
+// smokeTestConfig returns the storage smoke test settings of the active cluster
+func (o *Orchestrator) smokeTestConfig() config.StorageSmokeTestConfig {
+	if o.isNAS && o.config.NAS != nil {
+		return o.config.NAS.Storage.SmokeTest
+	}
+	if !o.isNAS && o.config.Homelab != nil {
+		return o.config.Homelab.Storage.SmokeTest
+	}
+	return config.StorageSmokeTestConfig{}
+}
+
+// storageSmokeTest provisions a volume and measures its synchronous writes when the smoke test is enabled
+func (o *Orchestrator) storageSmokeTest(ctx context.Context) error {
+	cfg := o.smokeTestConfig()
+	if !cfg.Enabled || o.storageProvider() == "none" {
+		return nil
+	}
+
+	log.Info("Running the storage smoke test", "provider", o.storageProvider())
+	_, err := health.StorageSmokeTest(ctx, o.k8sClient, health.StorageSmokeOptions{
+		StorageClass: cfg.StorageClass,
+		MinIOPS:      cfg.MinIOPS,
+		Timeout:      o.parseDuration(cfg.Timeout, 0),
+	})
+	if err != nil {
+		return fmt.Errorf("storage smoke test failed: %w", err)
+	}
+	return nil
+}
+
+// storageProvider returns the storage provider of the active cluster, ceph for the homelab and
+// local-path for the NAS unless configured
+func (o *Orchestrator) storageProvider() string {
+	if o.isNAS {
+		if o.config.NAS != nil && o.config.NAS.Storage.Provider != "" {
+			return o.config.NAS.Storage.Provider
+		}
+		return "local-path"
+	}
+	if o.config.Homelab != nil && o.config.Homelab.Storage.Provider != "" {
+		return o.config.Homelab.Storage.Provider
+	}
+	return "ceph"
+}
+
 func (o *Orchestrator) validateDeployment(ctx context.Context) error {
 	log.Info("Validating deployment")
 
@@ -624,6 +743,10 @@ func (o *Orchestrator) validateDeployment(ctx context.Context) error {
 		return fmt.Errorf("FluxCD validation failed: %s", status.Message)
 	}
 
+	if err := o.storageSmokeTest(ctx); err != nil {
+		return err
+	}
+
 	log.Info("Deployment validation completed")
 	return nil
 }
@@ -632,19 +755,18 @@ func (o *Orchestrator) comprehensiveHealthCheck(ctx context.Context) error {
 	log.Info("Performing comprehensive platform health validation")
 
 	// Health Check
-	healthChecker := health.NewHealthChecker(o.k8sClient)
-	healthStatus, err := healthChecker.CheckClusterHealth(ctx)
+	healthStatus, err := o.ClusterHealth(ctx, HealthOptions{})
 	if err != nil {
 		log.Warn("Health check completed with errors", "error", err)
 	} else {
 		log.Info("Cluster health validated",
 			"overall", healthStatus.Overall,
-			"healthy_components", len(healthStatus.Components))
+			"healthy_components", len(healthStatus.Components),
+			"failed_checks", healthStatus.Failures)
 	}
 
 	// Security Validation
-	securityValidator := security.NewSecurityValidator(o.k8sClient)
-	securityStatus, err := securityValidator.ValidateClusterSecurity(ctx)
+	securityStatus, err := o.SecurityScan(ctx, o.configuredScans())
 	if err != nil {
 		log.Warn("Security validation completed with errors", "error", err)
 	} else {
@@ -732,6 +854,9 @@ func (o *Orchestrator) runRollbacks(ctx context.Context, rollbacks []func(contex
 			log.Warn("Rollback step failed",
 				"index", idx+1,
 				"error", err)
+			if run := history.FromContext(ctx); run != nil {
+				run.AddWarning("rollback step %d failed: %v", idx+1, err)
+			}
 			continue
 		}
 		log.Info("Rollback step completed",
@@ -744,8 +869,10 @@ func (o *Orchestrator) rollbackIstioPrereqs(ctx context.Context) error {
 	if o.secretsManager == nil {
 		return nil
 	}
-	if err := o.secretsManager.ClearPendingRemoteSecret(ctx, o.peerClusterName()); err != nil {
-		return err
+	for _, peer := range o.meshPeers() {
+		if err := o.secretsManager.ClearPendingRemoteSecret(ctx, peer.name); err != nil {
+			return err
+		}
 	}
 	return nil
 }
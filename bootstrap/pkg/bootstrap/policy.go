@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/policy"
+)
+
+// securityConfig returns the security section of the active cluster configuration
+func (o *Orchestrator) securityConfig() *config.SecurityConfig {
+	if o.isNAS && o.config.NAS != nil {
+		return &o.config.NAS.Security
+	}
+	if !o.isNAS && o.config.Homelab != nil {
+		return &o.config.Homelab.Security
+	}
+	return nil
+}
+
+// withPolicyStep inserts the optional policy engine step after the GitOps bootstrap when policies are enabled
+func (o *Orchestrator) withPolicyStep(steps []BootstrapStep) []BootstrapStep {
+	security := o.securityConfig()
+	if security == nil || !security.Policies {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "policy-engine",
+		Description: "Generate policy engine and baseline policies",
+		Required:    false,
+		Execute:     o.generatePolicies,
+	}, "bootstrap-gitops")
+}
+
+// PolicyOptions returns the baseline policy options of the active cluster configuration
+func (o *Orchestrator) PolicyOptions() (policy.Options, error) {
+	var settings config.PolicyEngineConfig
+	if security := o.securityConfig(); security != nil {
+		settings = security.PolicyEngine
+	}
+
+	engine, err := policy.ParseEngine(settings.Engine)
+	if err != nil {
+		return policy.Options{}, err
+	}
+	mode, err := policy.ParseMode(settings.Mode)
+	if err != nil {
+		return policy.Options{}, err
+	}
+	return policy.Options{
+		Engine:             engine,
+		Mode:               mode,
+		ExcludedNamespaces: settings.ExcludedNamespaces,
+		Policies:           settings.Policies,
+		AllowedRegistries:  settings.AllowedRegistries,
+	}, nil
+}
+
+// GeneratePolicies writes the policy engine and baseline policies into the GitOps repository
+func (o *Orchestrator) GeneratePolicies(opts policy.Options) ([]string, error) {
+	return policy.NewGenerator(o.projectRoot, o.localClusterName(), opts).Generate()
+}
+
+// AuditPolicies reports the baseline policy violations found by the policy engine
+func (o *Orchestrator) AuditPolicies(ctx context.Context, engine policy.Engine) (*policy.Report, error) {
+	return policy.NewAuditor(o.k8sClient).Audit(ctx, engine)
+}
+
+func (o *Orchestrator) generatePolicies(ctx context.Context) error {
+	opts, err := o.PolicyOptions()
+	if err != nil {
+		return err
+	}
+
+	written, err := o.GeneratePolicies(opts)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Baseline policies generated; commit and push them for Flux to apply",
+		"engine", opts.Engine,
+		"mode", opts.Mode,
+		"files", len(written))
+	return nil
+}
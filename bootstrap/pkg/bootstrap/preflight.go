@@ -0,0 +1,22 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/prereq"
+)
+
+// ArchPreflight reports the workloads whose images are not published for arch
+func (o *Orchestrator) ArchPreflight(ctx context.Context, arch string, repositoryOnly bool) (*prereq.ArchReport, error) {
+	var client *k8s.Client
+	if !repositoryOnly {
+		client = o.k8sClient
+	}
+	return prereq.NewArchChecker(client, o.projectRoot).Check(ctx, arch)
+}
+
+// ConnectivityPreflight checks the network path to the API server for proxies, clock skew, latency and blocked upgrades
+func (o *Orchestrator) ConnectivityPreflight(ctx context.Context) []prereq.CheckResult {
+	return prereq.NewConnectivityChecker(o.k8sClient.GetConfig()).CheckAll(ctx)
+}
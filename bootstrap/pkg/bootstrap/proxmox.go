@@ -0,0 +1,73 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/proxmox"
+)
+
+// vmBootTimeout bounds the wait for the Kubernetes API once stopped VMs were started
+const vmBootTimeout = 5 * time.Minute
+
+// withProxmoxStep starts stopped cluster VMs ahead of verify-cluster when proxmox.auto_start is set
+func (o *Orchestrator) withProxmoxStep(steps []BootstrapStep) []BootstrapStep {
+	if o.isNAS || !o.config.Homelab.Proxmox.Enabled() || !o.config.Homelab.Proxmox.AutoStart {
+		return steps
+	}
+	step := BootstrapStep{
+		Name:        "start-vms",
+		Description: "Start stopped cluster VMs on Proxmox",
+		Required:    true,
+		Execute:     o.startVMs,
+		Timeout:     vmBootTimeout + 5*time.Minute,
+	}
+	return append([]BootstrapStep{step}, steps...)
+}
+
+func (o *Orchestrator) startVMs(ctx context.Context) error {
+	client, err := proxmox.NewClientFromConfig(o.config.Homelab.Proxmox)
+	if err != nil {
+		return err
+	}
+	vms, err := client.ClusterVMs(ctx, o.config.Homelab, nil)
+	if err != nil {
+		return err
+	}
+	for _, vm := range vms {
+		if vm.Status != proxmox.StatusRunning {
+			if err := k8s.GuardMutation("proxmox start " + vm.Name); err != nil {
+				return err
+			}
+		}
+	}
+	started, err := client.StartStopped(ctx, vms)
+	if err != nil {
+		return err
+	}
+	if len(started) == 0 {
+		log.Info("All cluster VMs running", "vms", len(vms))
+		return nil
+	}
+
+	log.Info("⏳ Waiting for the Kubernetes API after starting VMs", "started", len(started))
+	deadline := time.Now().Add(waitTimeout(ctx, vmBootTimeout))
+	for {
+		err := o.k8sClient.IsReady(ctx)
+		if err == nil {
+			log.Info("✅ Kubernetes API reachable")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("kubernetes API not reachable after starting VMs: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
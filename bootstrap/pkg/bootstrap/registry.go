@@ -0,0 +1,155 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// pullSecretNamespaces always receive the pull secret, Flux and Cilium run there
+var pullSecretNamespaces = []string{"flux-system", "kube-system"}
+
+// registryConfig returns the registry mirror settings of the active cluster
+func (o *Orchestrator) registryConfig() config.RegistryConfig {
+	return o.config.RegistryFor(o.isNAS)
+}
+
+// withRegistryStep inserts the registry step before anything pulls an image when a mirror is configured
+func (o *Orchestrator) withRegistryStep(steps []BootstrapStep) []BootstrapStep {
+	cfg := o.registryConfig()
+	if cfg.Mirror == "" {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "setup-registry",
+		Description: "Create the registry pull secrets and check the mirror serves the bootstrap images",
+		Required:    true,
+		Execute:     o.setupRegistry,
+		Namespaces:  o.registryNamespaces(cfg),
+	}, "verify-cluster")
+}
+
+// registryNamespaces lists the namespaces receiving the pull secret, MetalLB's when the bootstrap installs it
+func (o *Orchestrator) registryNamespaces(cfg config.RegistryConfig) []string {
+	namespaces := append([]string(nil), pullSecretNamespaces...)
+	if lb := o.loadBalancerConfig(); lb.Enabled() && lb.Provider == infra.LoadBalancerMetalLB {
+		namespaces = append(namespaces, infra.MetalLBNamespace)
+	}
+	return append(namespaces, cfg.Namespaces...)
+}
+
+func (o *Orchestrator) setupRegistry(ctx context.Context) error {
+	cfg := o.registryConfig()
+	if cfg.Mirror == "" {
+		log.Info("Registry mirror not configured, skipping")
+		return nil
+	}
+	if err := o.createPullSecrets(ctx, cfg); err != nil {
+		return err
+	}
+	return o.verifyMirrorImages(ctx, cfg)
+}
+
+// createPullSecrets writes the mirror credentials as a dockerconfigjson secret into every namespace pulling from it
+func (o *Orchestrator) createPullSecrets(ctx context.Context, cfg config.RegistryConfig) error {
+	name := cfg.PullSecretName()
+	if name == "" {
+		log.Info("Registry mirror has no credentials, pulling anonymously", "mirror", cfg.Mirror)
+		return nil
+	}
+	host, _, _ := strings.Cut(cfg.Mirror, "/")
+	dockerConfig, err := registry.DockerConfigJSON(host, cfg.Username, cfg.Password)
+	if err != nil {
+		return fmt.Errorf("failed to render the pull secret: %w", err)
+	}
+
+	namespaces := o.registryNamespaces(cfg)
+	for _, namespace := range namespaces {
+		if err := o.k8sClient.CreateNamespace(ctx, namespace); err != nil {
+			return fmt.Errorf("failed to create namespace %s: %w", namespace, err)
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": k8s.FieldManager},
+			},
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+		}
+		if err := o.k8sClient.CreateOrUpdateSecret(ctx, secret); err != nil {
+			return err
+		}
+	}
+	log.Info("Registry pull secrets ready", "secret", name, "namespaces", strings.Join(namespaces, ","))
+	return nil
+}
+
+// verifyMirrorImages checks the mirror serves the Flux and Cilium images and the configured ones before
+// the bootstrap installs anything pulling them
+func (o *Orchestrator) verifyMirrorImages(ctx context.Context, cfg config.RegistryConfig) error {
+	images, err := o.mirrorImages(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	host, _, _ := strings.Cut(cfg.Mirror, "/")
+	client := registry.NewClient()
+	if cfg.Username != "" {
+		client.WithCredentials(host, cfg.Username, cfg.Password)
+	}
+	if cfg.Insecure {
+		client.WithInsecure(host)
+	}
+
+	var errs []error
+	for _, image := range images {
+		if err := client.Check(ctx, image); err != nil {
+			log.Error("Image not pullable from the mirror", "image", image, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Debug("Image available on the mirror", "image", image)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d images missing from mirror %s: %w", len(errs), len(images), cfg.Mirror, errors.Join(errs...))
+	}
+	log.Info("Registry mirror serves the bootstrap images", "mirror", cfg.Mirror, "images", len(images))
+	return nil
+}
+
+// mirrorImages lists the images the bootstrap pulls from the mirror
+func (o *Orchestrator) mirrorImages(ctx context.Context, cfg config.RegistryConfig) ([]string, error) {
+	var gitopsConfig *config.GitOpsConfig
+	if o.isNAS {
+		gitopsConfig = &o.config.NAS.GitOps
+	} else {
+		gitopsConfig = &o.config.Homelab.GitOps
+	}
+	images, err := flux.NewClient(o.k8sClient, gitopsConfig).InstallImages("flux-system")
+	if err != nil {
+		return nil, err
+	}
+	if !o.isNAS {
+		cilium, err := infra.NewCiliumInstaller(o.k8sClient).Images(ctx, o.ciliumConfig())
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, cilium...)
+	}
+	for _, image := range cfg.VerifyImages {
+		images = append(images, registry.Rewrite(image))
+	}
+	return images, nil
+}
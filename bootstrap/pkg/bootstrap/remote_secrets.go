@@ -0,0 +1,185 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/istio"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// defaultRotationWindow is how long before expiry a remote secret token is rotated
+const defaultRotationWindow = 48 * time.Hour
+
+// RemoteSecretInfo describes the token freshness of a remote secret installed in a cluster
+type RemoteSecretInfo struct {
+	Name        string    `json:"name"`
+	Cluster     string    `json:"cluster"`
+	InstalledIn string    `json:"installed_in"`
+	Present     bool      `json:"present"`
+	Tracked     bool      `json:"tracked"`
+	IssuedAt    time.Time `json:"issued_at,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Age returns how long ago the embedded token was issued
+func (r RemoteSecretInfo) Age() time.Duration {
+	if r.IssuedAt.IsZero() {
+		return 0
+	}
+	return time.Since(r.IssuedAt)
+}
+
+// Remaining returns the time left before the embedded token expires
+func (r RemoteSecretInfo) Remaining() time.Duration {
+	if r.ExpiresAt.IsZero() {
+		return 0
+	}
+	return time.Until(r.ExpiresAt)
+}
+
+// NeedsRotation reports whether the secret is missing, untracked or close to expiry
+func (r RemoteSecretInfo) NeedsRotation(window time.Duration) bool {
+	if r.Error != "" {
+		return false
+	}
+	if !r.Present || !r.Tracked {
+		return true
+	}
+	return r.Remaining() < window
+}
+
+// RemoteSecretStatus reports token age and expiry for the remote secrets on both sides of every peering
+func (o *Orchestrator) RemoteSecretStatus(ctx context.Context) []RemoteSecretInfo {
+	var infos []RemoteSecretInfo
+	for _, peer := range o.meshPeers() {
+		infos = append(infos, inspectRemoteSecret(ctx, o.k8sClient, peer.name, o.localClusterName()))
+
+		peerClient, err := peer.client()
+		if err != nil {
+			infos = append(infos, RemoteSecretInfo{
+				Name:        remoteSecretName(o.localClusterName()),
+				Cluster:     o.localClusterName(),
+				InstalledIn: peer.name,
+				Error:       err.Error(),
+			})
+			continue
+		}
+		infos = append(infos, inspectRemoteSecret(ctx, peerClient, o.localClusterName(), peer.name))
+	}
+	return infos
+}
+
+// RotateRemoteSecrets reissues remote secrets when a token is missing or close to expiry
+func (o *Orchestrator) RotateRemoteSecrets(ctx context.Context, force bool) (bool, error) {
+	if !o.isServiceMeshEnabled() {
+		log.Debug("Service mesh disabled, skipping remote secret rotation")
+		return false, nil
+	}
+
+	window := o.parseDuration(o.lookupEnvValue("ISTIO_REMOTE_SECRET_ROTATION_WINDOW"), defaultRotationWindow)
+
+	due := force
+	for _, info := range o.RemoteSecretStatus(ctx) {
+		if info.Error != "" {
+			log.Debug("Skipping remote secret check", "secret", info.Name, "installed_in", info.InstalledIn, "error", info.Error)
+			continue
+		}
+		if info.NeedsRotation(window) {
+			log.Info("Remote secret token due for rotation",
+				"secret", info.Name,
+				"installed_in", info.InstalledIn,
+				"present", info.Present,
+				"remaining", info.Remaining().Round(time.Minute))
+			due = true
+		}
+	}
+
+	if !due {
+		return false, nil
+	}
+
+	if err := o.ensureRemoteSecret(ctx); err != nil {
+		return false, fmt.Errorf("failed to rotate remote secrets: %w", err)
+	}
+	return true, nil
+}
+
+// WatchRemoteSecrets periodically rotates remote secrets until the context is cancelled,
+// applying configuration received on reloads without restarting
+func (o *Orchestrator) WatchRemoteSecrets(ctx context.Context, interval time.Duration, reloads <-chan *config.Config) error {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	log.Info("Watching remote secrets for expiry", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if rotated, err := o.RotateRemoteSecrets(ctx, false); err != nil {
+			log.Warn("Remote secret rotation failed", "error", err)
+		} else if rotated {
+			log.Info("Remote secrets rotated")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case cfg := <-reloads:
+			o.applyConfig(cfg)
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyConfig swaps in a reloaded configuration for the next iteration of a long-running loop
+func (o *Orchestrator) applyConfig(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	if (o.isNAS && cfg.NAS == nil) || (!o.isNAS && cfg.Homelab == nil) {
+		log.Warn("Reloaded configuration has no section for this cluster, keeping previous values", "cluster", o.localClusterName())
+		return
+	}
+	o.config = cfg
+	o.secretsManager.SetMeshClusters(cfg.MeshClusterNames())
+	o.notifier = newNotifier(cfg, o.isNAS)
+	log.Info("Applied reloaded configuration", "cluster", o.localClusterName())
+}
+
+func inspectRemoteSecret(ctx context.Context, client *k8s.Client, cluster, installedIn string) RemoteSecretInfo {
+	info := RemoteSecretInfo{
+		Name:        remoteSecretName(cluster),
+		Cluster:     cluster,
+		InstalledIn: installedIn,
+	}
+
+	secret, err := client.GetSecret(ctx, istioNamespace, info.Name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			info.Error = err.Error()
+		}
+		return info
+	}
+
+	info.Present = true
+	if issued, expires, ok := istio.TokenExpiry(secret); ok {
+		info.Tracked = true
+		info.IssuedAt = issued
+		info.ExpiresAt = expires
+	} else {
+		info.IssuedAt = secret.CreationTimestamp.Time
+	}
+	return info
+}
+
+func remoteSecretName(cluster string) string {
+	return fmt.Sprintf("istio-remote-secret-%s", cluster)
+}
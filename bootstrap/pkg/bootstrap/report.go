@@ -0,0 +1,225 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/health"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+)
+
+// reportDir is where bootstrap reports are written by default, relative to the project root
+const reportDir = ".bootstrap/reports"
+
+// ReportOptions controls the report Bootstrap writes once it finishes
+type ReportOptions struct {
+	// Path of the JSON report, .bootstrap/reports/<cluster>.json when empty
+	Path string
+	// Markdown also writes the report as Markdown next to the JSON file
+	Markdown bool
+}
+
+// Report is the machine-readable outcome of a bootstrap, meant to be attached to a GitOps PR or CI run
+type Report struct {
+	Cluster         string                `json:"cluster"`
+	Type            string                `json:"type"`
+	Status          history.Status        `json:"status"`
+	Error           string                `json:"error,omitempty"`
+	FailedStep      string                `json:"failed_step,omitempty"`
+	StartedAt       time.Time             `json:"started_at"`
+	FinishedAt      time.Time             `json:"finished_at"`
+	DurationSeconds float64               `json:"duration_seconds"`
+	GitRevision     string                `json:"git_revision,omitempty"`
+	Steps           []history.SummaryStep `json:"steps"`
+	Versions        map[string]string     `json:"versions,omitempty"`
+	Endpoints       map[string]string     `json:"endpoints,omitempty"`
+	// GeneratedSecrets are the keys of .env.generated, values are never reported
+	GeneratedSecrets []string `json:"generated_secrets,omitempty"`
+	// ClusterVars are the keys of the flux-system/cluster-vars secret
+	ClusterVars []string             `json:"cluster_vars,omitempty"`
+	Health      *health.HealthStatus `json:"health,omitempty"`
+}
+
+// writeReport builds the report of the bootstrap that started at started and writes it, logging failures
+// since the bootstrap outcome matters more than its report
+func (o *Orchestrator) writeReport(ctx context.Context, run *history.Run, started time.Time, metrics []stepMetric, failedStep string, bootstrapErr error) {
+	report := o.buildReport(ctx, run, started, metrics)
+	if bootstrapErr != nil {
+		report.Status = history.StatusFailed
+		report.Error = bootstrapErr.Error()
+		report.FailedStep = failedStep
+	}
+
+	path, err := o.saveReport(report)
+	if err != nil {
+		log.Warn("Failed to write bootstrap report", "error", err)
+		return
+	}
+	log.Info("Bootstrap report written", "path", path)
+}
+
+func (o *Orchestrator) buildReport(ctx context.Context, run *history.Run, started time.Time, metrics []stepMetric) *Report {
+	finished := time.Now()
+	report := &Report{
+		Cluster:         o.localClusterName(),
+		Type:            o.getClusterType(),
+		Status:          history.StatusSucceeded,
+		StartedAt:       started,
+		FinishedAt:      finished,
+		DurationSeconds: finished.Sub(started).Seconds(),
+		GitRevision:     history.GitRevision(ctx, o.projectRoot),
+		Steps:           make([]history.SummaryStep, 0, len(metrics)),
+	}
+	for _, metric := range metrics {
+		status := history.StatusSucceeded
+		if !metric.success {
+			status = history.StatusFailed
+		}
+		step := history.SummaryStep{
+			Name:            metric.name,
+			Status:          status,
+			DurationSeconds: metric.duration.Seconds(),
+		}
+		if metric.err != nil {
+			step.Error = metric.err.Error()
+		}
+		report.Steps = append(report.Steps, step)
+	}
+
+	// Recorded runs already carry the versions and endpoints, others collect them now
+	details := run
+	if details == nil {
+		details = history.NewRun("bootstrap", report.Cluster)
+		o.recordRunDetails(ctx, details)
+	}
+	report.Versions = details.Versions
+	report.Endpoints = details.Endpoints
+
+	if keys, err := o.secretsManager.GeneratedEnvKeys(); err != nil {
+		log.Debug("Failed to list generated secrets for the report", "error", err)
+	} else {
+		report.GeneratedSecrets = keys
+	}
+	if vars, err := o.secretsManager.ClusterVars(ctx, "flux-system"); err != nil {
+		log.Debug("Failed to list cluster-vars for the report", "error", err)
+	} else {
+		report.ClusterVars = sortedKeys(vars)
+	}
+	if status, err := o.ClusterHealth(ctx, HealthOptions{}); err != nil {
+		log.Debug("Failed to check cluster health for the report", "error", err)
+	} else {
+		report.Health = status
+	}
+	return report
+}
+
+// saveReport writes the JSON report, and its Markdown rendering when asked, returning the JSON path
+func (o *Orchestrator) saveReport(report *Report) (string, error) {
+	opts := o.options.Report
+	path := opts.Path
+	if path == "" {
+		path = filepath.Join(o.projectRoot, reportDir, report.Cluster+".json")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode bootstrap report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write bootstrap report: %w", err)
+	}
+
+	if opts.Markdown {
+		markdownPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".md"
+		if err := os.WriteFile(markdownPath, []byte(report.Markdown()), 0o644); err != nil {
+			return "", fmt.Errorf("failed to write bootstrap report: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// Markdown renders the report for a PR comment or CI summary
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	icon := "✅"
+	if r.Status != history.StatusSucceeded {
+		icon = "❌"
+	}
+	fmt.Fprintf(&b, "# Bootstrap report: %s\n\n", r.Cluster)
+	fmt.Fprintf(&b, "%s **%s** in %s, finished %s", icon, r.Status, reportDuration(r.DurationSeconds), r.FinishedAt.UTC().Format(time.RFC3339))
+	if r.GitRevision != "" {
+		fmt.Fprintf(&b, " at `%s`", r.GitRevision)
+	}
+	b.WriteString("\n")
+	if r.Error != "" {
+		fmt.Fprintf(&b, "\nFailed at `%s`: %s\n", r.FailedStep, markdownCell(r.Error))
+	}
+
+	if len(r.Steps) > 0 {
+		b.WriteString("\n## Steps\n\n| Step | Status | Duration | Error |\n|---|---|---|---|\n")
+		for _, step := range r.Steps {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", step.Name, step.Status, reportDuration(step.DurationSeconds), markdownCell(step.Error))
+		}
+	}
+	writeMarkdownMap(&b, "Versions", "Component", "Version", r.Versions)
+	writeMarkdownMap(&b, "Endpoints", "Name", "Address", r.Endpoints)
+
+	if r.Health != nil {
+		fmt.Fprintf(&b, "\n## Health\n\nOverall: **%s**\n\n| Component | State | Details |\n|---|---|---|\n", r.Health.Overall)
+		for _, component := range sortedKeys(r.Health.Components) {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", component, r.Health.Components[component], markdownCell(r.Health.Details[component]))
+		}
+	}
+	writeMarkdownKeys(&b, "Generated secrets", r.GeneratedSecrets)
+	writeMarkdownKeys(&b, "Cluster variables", r.ClusterVars)
+	return b.String()
+}
+
+func writeMarkdownMap(b *strings.Builder, title, keyHeader, valueHeader string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n| %s | %s |\n|---|---|\n", title, keyHeader, valueHeader)
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(b, "| %s | `%s` |\n", key, values[key])
+	}
+}
+
+func writeMarkdownKeys(b *strings.Builder, title string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(b, "- `%s`\n", key)
+	}
+}
+
+// markdownCell keeps the first line of text and escapes the pipes that would break a table row
+func markdownCell(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.ReplaceAll(line, "|", "\\|")
+}
+
+func reportDuration(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
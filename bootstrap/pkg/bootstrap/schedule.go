@@ -0,0 +1,102 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/health"
+	"github.com/fredericrous/homelab/bootstrap/pkg/notify"
+	"github.com/fredericrous/homelab/bootstrap/pkg/schedule"
+)
+
+// RunScheduledTask runs the task of the schedule name on the orchestrated cluster and notifies its outcome
+func (o *Orchestrator) RunScheduledTask(ctx context.Context, name, task string) error {
+	start := time.Now()
+	err := o.runScheduledTask(ctx, task)
+	event := notify.Event{Type: notify.ScheduleSucceeded, Message: "Scheduled " + task + " succeeded", Step: name, Duration: time.Since(start)}
+	if err != nil {
+		event.Type = notify.ScheduleFailed
+		event.Message = "Scheduled " + task + " failed"
+		event.Error = err.Error()
+	}
+	o.notify(ctx, event)
+	return err
+}
+
+// runScheduledTask runs one of the operations a schedule can name, failing when it finds a problem worth a notification
+func (o *Orchestrator) runScheduledTask(ctx context.Context, task string) error {
+	switch task {
+	case schedule.TaskVerify:
+		from := "homelab"
+		if o.isNAS {
+			from = "nas"
+		}
+		return VerifyMesh(ctx, from)
+
+	case schedule.TaskSecurityScan:
+		status, err := o.SecurityScan(ctx, o.configuredScans())
+		if err != nil {
+			return err
+		}
+		critical := 0
+		for _, finding := range status.Vulnerabilities {
+			if finding.Severity == "Critical" {
+				critical++
+			}
+		}
+		log.Info("📊 Security scan", "findings", len(status.Vulnerabilities), "critical", critical)
+		if critical > 0 {
+			return fmt.Errorf("%d critical security findings", critical)
+		}
+		if failOn := o.ImageScanFailOn(); failOn != "" && status.ImageScan != nil {
+			if count := status.ImageScan.AtOrAbove(failOn); count > 0 {
+				return fmt.Errorf("%d image vulnerabilities at or above %s", count, failOn)
+			}
+		}
+		return nil
+
+	case schedule.TaskGatewayCert:
+		notAfter, err := o.CheckGatewayCert(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("🔏 East-west gateway certificate valid", "expires", notAfter.Format(time.DateOnly))
+		return nil
+
+	case schedule.TaskEtcdSnapshot:
+		manager, closeTunnel, err := o.EtcdManager(ctx)
+		if err != nil {
+			return err
+		}
+		defer closeTunnel()
+		snapshot, pruned, err := manager.Snapshot(ctx, o.EtcdRetention())
+		if err != nil {
+			return err
+		}
+		log.Info("✅ etcd snapshot stored", "name", snapshot.Name, "size", snapshot.Size, "pruned", len(pruned))
+		return nil
+
+	case schedule.TaskHealth:
+		status, err := o.ClusterHealth(ctx, HealthOptions{})
+		if err != nil {
+			return err
+		}
+		if status.Overall == health.HealthStateUnhealthy {
+			return fmt.Errorf("cluster is unhealthy (failed checks: %v)", status.Failures)
+		}
+		return nil
+
+	case schedule.TaskDrift:
+		report, err := o.Drift(ctx)
+		if err != nil {
+			return err
+		}
+		if !report.Clean() {
+			return fmt.Errorf("%d managed object(s) drifted", len(report.Drifted))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown scheduled task %q", task)
+}
@@ -0,0 +1,73 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/security"
+)
+
+// SecurityScanOptions selects the optional scans run with the security validation
+type SecurityScanOptions struct {
+	Benchmark *security.BenchmarkOptions
+	Images    *security.ImageScanOptions
+}
+
+// BenchmarkOptions returns the kube-bench options from the security configuration
+func (o *Orchestrator) BenchmarkOptions() security.BenchmarkOptions {
+	var opts security.BenchmarkOptions
+	if cfg := o.securityConfig(); cfg != nil {
+		opts.Profile = cfg.Benchmark.Profile
+		opts.Image = cfg.Benchmark.Image
+		opts.Timeout = o.parseDuration(cfg.Benchmark.Timeout, 0)
+	}
+	return opts
+}
+
+// ImageScanOptions returns the image scan options from the security configuration
+func (o *Orchestrator) ImageScanOptions() security.ImageScanOptions {
+	var opts security.ImageScanOptions
+	if cfg := o.securityConfig(); cfg != nil {
+		opts.Namespace = cfg.ImageScan.Namespace
+		opts.IgnoreUnfixed = cfg.ImageScan.IgnoreUnfixed
+		opts.Timeout = o.parseDuration(cfg.ImageScan.Timeout, 0)
+	}
+	return opts
+}
+
+// ImageScanFailOn returns the configured severity `security scan-images` fails at, empty to never fail
+func (o *Orchestrator) ImageScanFailOn() string {
+	if cfg := o.securityConfig(); cfg != nil {
+		return cfg.ImageScan.FailOn
+	}
+	return ""
+}
+
+// configuredScans returns the scans the security configuration enables with every validation
+func (o *Orchestrator) configuredScans() SecurityScanOptions {
+	var opts SecurityScanOptions
+	cfg := o.securityConfig()
+	if cfg == nil {
+		return opts
+	}
+	if cfg.Benchmark.Enabled {
+		benchmark := o.BenchmarkOptions()
+		opts.Benchmark = &benchmark
+	}
+	if cfg.ImageScan.Enabled {
+		images := o.ImageScanOptions()
+		opts.Images = &images
+	}
+	return opts
+}
+
+// SecurityScan validates the security posture of the cluster, with the optional scans opts selects
+func (o *Orchestrator) SecurityScan(ctx context.Context, opts SecurityScanOptions) (*security.SecurityStatus, error) {
+	validator := security.NewSecurityValidator(o.k8sClient)
+	if opts.Benchmark != nil {
+		validator.SetBenchmark(*opts.Benchmark)
+	}
+	if opts.Images != nil {
+		validator.SetImageScan(*opts.Images)
+	}
+	return validator.ValidateClusterSecurity(ctx)
+}
@@ -0,0 +1,59 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/slo"
+)
+
+// withSLOStep inserts the optional SLO rules step when SLOs are declared
+func (o *Orchestrator) withSLOStep(steps []BootstrapStep) []BootstrapStep {
+	settings := o.monitoringConfig()
+	if settings == nil || len(settings.SLOs) == 0 {
+		return steps
+	}
+
+	return insertStepAfter(steps, BootstrapStep{
+		Name:        "slo-rules",
+		Description: "Generate SLO recording and alerting rules",
+		Required:    false,
+		Execute:     o.setupSLORules,
+	}, "bootstrap-gitops", "policy-engine", "runtime-security", "log-shipping", "metrics-federation")
+}
+
+// SLOObjectives parses the SLOs declared in monitoring.slos
+func (o *Orchestrator) SLOObjectives() ([]slo.Objective, error) {
+	settings := o.monitoringConfig()
+	if settings == nil {
+		return nil, nil
+	}
+	return slo.Parse(settings.SLOs)
+}
+
+// GenerateSLORules writes the SLO recording and alerting rules into the GitOps repository
+func (o *Orchestrator) GenerateSLORules() ([]string, error) {
+	objectives, err := o.SLOObjectives()
+	if err != nil {
+		return nil, err
+	}
+	return slo.NewGenerator(o.projectRoot, o.localClusterName(), objectives).Generate()
+}
+
+// SLOStatus reports the error budget of every declared SLO
+func (o *Orchestrator) SLOStatus(ctx context.Context, prometheus string) ([]slo.Budget, error) {
+	objectives, err := o.SLOObjectives()
+	if err != nil {
+		return nil, err
+	}
+	return slo.NewReporter(o.k8sClient, prometheus).Report(ctx, objectives)
+}
+
+func (o *Orchestrator) setupSLORules(ctx context.Context) error {
+	written, err := o.GenerateSLORules()
+	if err != nil {
+		return err
+	}
+	log.Info("SLO rules generated; commit and push them for Flux to apply", "files", len(written))
+	return nil
+}
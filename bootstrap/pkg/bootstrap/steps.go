@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
+)
+
+const (
+	// defaultStepBackoff is the first retry delay of steps without a configured backoff
+	defaultStepBackoff = 10 * time.Second
+	// maxStepBackoff caps the exponential backoff between attempts
+	maxStepBackoff = 5 * time.Minute
+	// defaultWaitTimeout bounds waits of steps that run without a timeout
+	defaultWaitTimeout = 5 * time.Minute
+)
+
+// withStepPolicies applies the timeout, retries and backoff configured under steps.<name>
+func (o *Orchestrator) withStepPolicies(steps []BootstrapStep) []BootstrapStep {
+	policies := o.config.StepsFor(o.isNAS)
+	known := make(map[string]bool, len(steps))
+	for i := range steps {
+		known[steps[i].Name] = true
+		policy, ok := policies[steps[i].Name]
+		if !ok {
+			continue
+		}
+		steps[i].Timeout = o.parseDuration(policy.Timeout, steps[i].Timeout)
+		steps[i].Backoff = o.parseDuration(policy.Backoff, steps[i].Backoff)
+		if policy.Retries != nil {
+			steps[i].Retries = *policy.Retries
+		}
+	}
+	for name := range policies {
+		if !known[name] {
+			log.Warn("Ignoring policy of unknown bootstrap step", "step", name)
+		}
+	}
+	return steps
+}
+
+// runStep executes a step, bounding each attempt by its timeout and retrying with exponential backoff
+func (o *Orchestrator) runStep(ctx context.Context, step BootstrapStep) error {
+	var err error
+	for attempt := 0; attempt <= step.Retries; attempt++ {
+		if attempt > 0 {
+			delay := stepBackoff(step.Backoff, attempt)
+			log.Warn("Retrying bootstrap step", "step", step.Name, "attempt", attempt+1, "of", step.Retries+1, "in", delay.Round(time.Second), "error", err)
+			progress.Report(ctx, -1, fmt.Sprintf("Attempt %d of %d in %s: %v", attempt+1, step.Retries+1, delay.Round(time.Second), err))
+			if run := history.FromContext(ctx); run != nil {
+				run.AddWarning("step %s attempt %d failed: %v", step.Name, attempt, err)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		err = o.runAttempt(ctx, step)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (o *Orchestrator) runAttempt(ctx context.Context, step BootstrapStep) error {
+	if step.Timeout <= 0 {
+		return o.withEvents(ctx, step.Name, step.Namespaces, step.Execute)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, step.Timeout)
+	defer cancel()
+	err := o.withEvents(attemptCtx, step.Name, step.Namespaces, step.Execute)
+	if err != nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("timed out after %s: %w", step.Timeout, err)
+	}
+	return err
+}
+
+// stepBackoff doubles base for each retry, capped at maxStepBackoff, with up to 50% jitter
+func stepBackoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = defaultStepBackoff
+	}
+	delay := base
+	for i := 1; i < attempt && delay < maxStepBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxStepBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// waitTimeout returns the time left before the step deadline, or fallback when the step has none
+func waitTimeout(ctx context.Context, fallback time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return fallback
+}
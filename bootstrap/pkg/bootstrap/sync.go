@@ -0,0 +1,242 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/infra"
+	"github.com/fredericrous/homelab/bootstrap/pkg/notify"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Artifacts managed by the bootstrap that a config-only change can affect
+const (
+	ArtifactClusterVars   = "cluster-vars"
+	ArtifactMeshVars      = "mesh-vars"
+	ArtifactCiliumValues  = "cilium-values"
+	ArtifactSyncManifests = "sync-manifests"
+)
+
+// SyncChange is a single difference between the local config and the cluster
+type SyncChange struct {
+	Artifact string
+	Detail   string
+}
+
+// SyncPlan lists what a differential sync would apply
+type SyncPlan struct {
+	Changes []SyncChange
+	// MeshVars holds the live east-west gateway endpoint when it no longer matches cluster-vars
+	MeshVars map[string]string
+}
+
+// Empty reports whether the cluster already matches the local config
+func (p *SyncPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Affects reports whether the plan changes artifact
+func (p *SyncPlan) Affects(artifact string) bool {
+	for _, change := range p.Changes {
+		if change.Artifact == artifact {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *SyncPlan) add(artifact, format string, args ...interface{}) {
+	p.Changes = append(p.Changes, SyncChange{Artifact: artifact, Detail: fmt.Sprintf(format, args...)})
+}
+
+// PlanSync compares the local config and .env files with what the bootstrap deployed
+func (o *Orchestrator) PlanSync(ctx context.Context) (*SyncPlan, error) {
+	plan := &SyncPlan{}
+
+	if err := o.planClusterVars(ctx, plan); err != nil {
+		return nil, err
+	}
+	if o.isServiceMeshEnabled() {
+		o.planMeshVars(ctx, plan)
+	}
+
+	if !o.isNAS {
+		drift, err := infra.NewCiliumInstaller(o.k8sClient).ValuesDrift(ctx, o.ciliumConfig())
+		if err != nil {
+			log.Warn("Failed to compare Cilium values", "error", err)
+		}
+		for _, detail := range drift {
+			plan.add(ArtifactCiliumValues, "%s", detail)
+		}
+	}
+
+	fluxClient, err := o.newFluxClient()
+	if err != nil {
+		return nil, err
+	}
+	drift, err := fluxClient.SyncDrift(ctx, "flux-system")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare sync manifests: %w", err)
+	}
+	for _, detail := range drift {
+		plan.add(ArtifactSyncManifests, "%s", detail)
+	}
+
+	if !plan.Empty() {
+		details := make([]string, 0, len(plan.Changes))
+		for _, change := range plan.Changes {
+			details = append(details, change.Artifact+": "+change.Detail)
+		}
+		o.notify(ctx, notify.Event{Type: notify.DriftDetected, Message: "Configuration drift detected", Details: details})
+	}
+	return plan, nil
+}
+
+// planClusterVars diffs the merged .env files against the deployed cluster-vars secret, values are never shown
+func (o *Orchestrator) planClusterVars(ctx context.Context, plan *SyncPlan) error {
+	desired, err := o.secretsManager.DesiredClusterVars()
+	if err != nil {
+		return fmt.Errorf("failed to load environment variables: %w", err)
+	}
+	deployed, err := o.secretsManager.ClusterVars(ctx, "flux-system")
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(desired)+len(deployed))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	for key := range deployed {
+		if _, ok := desired[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		want, inDesired := desired[key]
+		have, inDeployed := deployed[key]
+		artifact := ArtifactClusterVars
+		if isMeshVar(key) {
+			artifact = ArtifactMeshVars
+		}
+		switch {
+		case !inDeployed:
+			plan.add(artifact, "%s added", key)
+		case !inDesired:
+			plan.add(artifact, "%s removed", key)
+		case want != have:
+			plan.add(artifact, "%s changed", key)
+		}
+	}
+	return nil
+}
+
+// planMeshVars records the live east-west gateway endpoint when cluster-vars still advertises an older one
+func (o *Orchestrator) planMeshVars(ctx context.Context, plan *SyncPlan) {
+	svc, err := o.k8sClient.GetService(ctx, istioNamespace, eastWestServiceName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Warn("Failed to read east-west gateway", "error", err)
+		}
+		return
+	}
+	endpoint := endpointFromService(svc)
+	if endpoint == nil || endpoint.Host == "" {
+		return
+	}
+
+	addrKey, portKey := o.localGatewayVarKeys()
+	live := map[string]string{
+		addrKey: endpoint.Host,
+		portKey: strconv.Itoa(int(endpoint.Port)),
+	}
+	for _, key := range []string{addrKey, portKey} {
+		if o.lookupEnvValue(key) != live[key] {
+			if plan.MeshVars == nil {
+				plan.MeshVars = map[string]string{}
+			}
+			plan.MeshVars[key] = live[key]
+			plan.add(ArtifactMeshVars, "%s follows east-west gateway (%s)", key, endpoint.Source)
+		}
+	}
+}
+
+// isMeshVar reports whether a cluster-vars key configures the cross-cluster mesh
+func isMeshVar(key string) bool {
+	return strings.Contains(key, "_EW_GATEWAY_") || strings.HasPrefix(key, "NETWORK_") || strings.HasPrefix(key, "ISTIO_")
+}
+
+// ApplySync applies only the artifacts affected by plan and asks Flux to reconcile what consumes them
+func (o *Orchestrator) ApplySync(ctx context.Context, plan *SyncPlan) error {
+	if plan.Empty() {
+		log.Info("Cluster already matches the local configuration")
+		return nil
+	}
+
+	fluxClient, err := o.newFluxClient()
+	if err != nil {
+		return err
+	}
+
+	varsChanged := plan.Affects(ArtifactClusterVars) || plan.Affects(ArtifactMeshVars)
+	if len(plan.MeshVars) > 0 {
+		if err := o.secretsManager.UpdateGeneratedEnv(plan.MeshVars); err != nil {
+			log.Warn("Failed to persist gateway variables to .env.generated", "error", err)
+		}
+	}
+	if varsChanged {
+		log.Info("Updating cluster-vars secret")
+		if err := o.secretsManager.CreateClusterVarsSecret(ctx, "flux-system"); err != nil {
+			return fmt.Errorf("failed to update cluster-vars secret: %w", err)
+		}
+		if len(plan.MeshVars) > 0 {
+			if err := o.secretsManager.UpdateClusterVars(ctx, "flux-system", plan.MeshVars); err != nil {
+				return fmt.Errorf("failed to update gateway variables: %w", err)
+			}
+		}
+	}
+
+	if plan.Affects(ArtifactCiliumValues) {
+		log.Info("Upgrading Cilium with updated values")
+		if err := infra.NewCiliumInstaller(o.k8sClient).Upgrade(ctx, o.ciliumConfig()); err != nil {
+			return fmt.Errorf("failed to upgrade Cilium: %w", err)
+		}
+	}
+
+	if plan.Affects(ArtifactSyncManifests) {
+		log.Info("Applying Flux sync manifests")
+		if err := o.createSOPSAgeSecret(ctx); err != nil {
+			return err
+		}
+		if err := fluxClient.ApplySyncManifests(ctx, "flux-system"); err != nil {
+			return err
+		}
+		if err := fluxClient.ReconcileSource(ctx, "flux-system", "flux-system"); err != nil {
+			log.Warn("Failed to reconcile flux-system source", "error", err)
+		}
+		if err := fluxClient.TriggerReconcile(ctx, "flux-system", "flux-system"); err != nil {
+			log.Warn("Failed to reconcile Kustomization", "name", "flux-system", "error", err)
+		}
+	}
+
+	if varsChanged {
+		consumers, err := fluxClient.SubstitutionConsumers(ctx, "cluster-vars")
+		if err != nil {
+			log.Warn("Failed to find cluster-vars consumers", "error", err)
+		}
+		for _, consumer := range consumers {
+			if err := fluxClient.TriggerReconcile(ctx, consumer.Namespace, consumer.Name); err != nil {
+				log.Warn("Failed to reconcile Kustomization", "namespace", consumer.Namespace, "name", consumer.Name, "error", err)
+			}
+		}
+	}
+
+	log.Info("Differential sync applied", "changes", len(plan.Changes))
+	return nil
+}
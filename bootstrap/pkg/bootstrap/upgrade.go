@@ -0,0 +1,22 @@
+package bootstrap
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/channels"
+	"github.com/fredericrous/homelab/bootstrap/pkg/upgrade"
+)
+
+// UpgradeAdvice compares the Flux, Cilium and Istio versions of the local cluster with the newest releases of
+// their channels. refresh fetches the release lists again instead of reading the cached ones.
+func (o *Orchestrator) UpgradeAdvice(ctx context.Context, refresh bool) ([]upgrade.Advice, error) {
+	local := o.channelsConfig(o.localClusterName())
+	if err := channels.Validate(local); err != nil {
+		return nil, err
+	}
+
+	index := upgrade.NewIndex(filepath.Join(o.projectRoot, upgrade.CacheDir), o.lookupEnvValue("GITHUB_TOKEN"))
+	index.SetRefresh(refresh)
+	return upgrade.NewAdvisor(index).Check(ctx, o.localClusterName(), o.k8sClient, local, upgrade.Pinned(o.config, o.isNAS)), nil
+}
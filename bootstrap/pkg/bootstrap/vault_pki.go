@@ -0,0 +1,259 @@
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/vault"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	homelabVaultNamespace = "vault"
+	homelabVaultService   = "vault-vault"
+	homelabVaultPort      = 8200
+	homelabVaultHost      = homelabVaultService + "." + homelabVaultNamespace + ".svc.cluster.local"
+
+	defaultPKIMount      = "pki"
+	defaultPKICommonName = "Homelab Intermediate CA"
+	defaultPKITTL        = "8760h"
+	defaultPKIRoleTTL    = "2160h"
+	// intermediateRenewBefore re-signs the intermediate CA when it expires sooner than this
+	intermediateRenewBefore = 30 * 24 * time.Hour
+)
+
+// VaultPKIOptions tunes SetupVaultPKI
+type VaultPKIOptions struct {
+	// Force re-signs the intermediate CA even when the current one is still valid
+	Force bool
+}
+
+// VaultPKIResult describes the intermediate CA the homelab Vault issues from
+type VaultPKIResult struct {
+	Mount    string    `json:"mount"`
+	Roles    []string  `json:"roles"`
+	Signed   bool      `json:"signed"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// vaultPKIConfig returns the intermediate CA settings with their defaults, nil when Vault is disabled
+func (o *Orchestrator) vaultPKIConfig() *config.VaultPKIConfig {
+	if o.isNAS || o.config.Homelab == nil || !o.config.Homelab.Security.Vault.Enabled {
+		return nil
+	}
+	cfg := o.config.Homelab.Security.Vault.PKI
+	if cfg.Mount == "" {
+		cfg.Mount = defaultPKIMount
+	}
+	if cfg.CommonName == "" {
+		cfg.CommonName = defaultPKICommonName
+	}
+	if cfg.TTL == "" {
+		cfg.TTL = defaultPKITTL
+	}
+	if len(cfg.Roles) == 0 {
+		cfg.Roles = []config.VaultPKIRole{{
+			Name:            "cert-manager",
+			AllowedDomains:  o.config.Homelab.Networking.DNS.Domains,
+			AllowSubdomains: true,
+		}}
+	}
+	return &cfg
+}
+
+// setupVaultPKI is the bootstrap step, skipped until both Vaults are reachable
+func (o *Orchestrator) setupVaultPKI(ctx context.Context) error {
+	if o.vaultPKIConfig() == nil {
+		log.Info("Vault disabled, skipping PKI setup")
+		return nil
+	}
+	if o.config.Homelab.Integration.Vault.Token == "" {
+		log.Info("VAULT_TOKEN not set, skipping PKI setup; run 'bootstrap homelab vault pki-setup' once it is")
+		return nil
+	}
+	if _, err := o.k8sClient.GetService(ctx, homelabVaultNamespace, homelabVaultService); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Homelab Vault not deployed yet, skipping PKI setup")
+			return nil
+		}
+		return err
+	}
+	_, err := o.SetupVaultPKI(ctx, VaultPKIOptions{})
+	return err
+}
+
+// SetupVaultPKI makes the homelab Vault an intermediate CA signed by the NAS Vault root, writes the roles
+// cert-manager issues from and records the issuing CA in cluster-vars
+func (o *Orchestrator) SetupVaultPKI(ctx context.Context, opts VaultPKIOptions) (*VaultPKIResult, error) {
+	cfg := o.vaultPKIConfig()
+	if cfg == nil {
+		return nil, fmt.Errorf("security.vault is disabled in the homelab config")
+	}
+	if err := k8s.GuardMutation("configure the Vault PKI"); err != nil {
+		return nil, err
+	}
+	root, err := o.nasVault()
+	if err != nil {
+		return nil, err
+	}
+	rootMount := o.config.Homelab.Security.Vault.PKIPath
+	if rootMount == "" {
+		rootMount = defaultPKIMount
+	}
+	for _, role := range cfg.Roles {
+		if len(role.AllowedDomains) == 0 {
+			return nil, fmt.Errorf("PKI role %s has no allowed domains; set networking.dns.domains or security.vault.pki.roles", role.Name)
+		}
+	}
+
+	token, err := o.homelabVaultToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	homelab, stop, err := o.homelabVault(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	homelab = homelab.WithToken(token)
+
+	if err := homelab.EnsurePKIMount(ctx, cfg.Mount, cfg.TTL); err != nil {
+		return nil, err
+	}
+	result := &VaultPKIResult{Mount: cfg.Mount}
+	chain, err := homelab.IssuingCA(ctx, cfg.Mount)
+	if err != nil {
+		return nil, err
+	}
+	if chain != "" {
+		result.NotAfter, _ = certificateNotAfter([]byte(chain))
+	}
+
+	if chain == "" || opts.Force || time.Until(result.NotAfter) < intermediateRenewBefore {
+		log.Info("Signing the homelab intermediate CA with the NAS root", "root", root.Address()+"/"+rootMount, "mount", cfg.Mount)
+		csr, err := homelab.GenerateIntermediateCSR(ctx, cfg.Mount, cfg.CommonName, cfg.TTL)
+		if err != nil {
+			return nil, err
+		}
+		if chain, err = root.SignIntermediate(ctx, rootMount, csr, cfg.CommonName, cfg.TTL); err != nil {
+			return nil, err
+		}
+		if err := homelab.SetSignedIntermediate(ctx, cfg.Mount, chain); err != nil {
+			return nil, err
+		}
+		if result.NotAfter, err = certificateNotAfter([]byte(chain)); err != nil {
+			return nil, err
+		}
+		result.Signed = true
+	} else {
+		log.Info("Intermediate CA still valid", "mount", cfg.Mount, "expires", result.NotAfter.Format(time.RFC3339))
+	}
+
+	if err := homelab.ConfigureURLs(ctx, cfg.Mount, "https://"+homelabVaultHost+fmt.Sprintf(":%d", homelabVaultPort)); err != nil {
+		return nil, err
+	}
+	for _, role := range cfg.Roles {
+		maxTTL := role.MaxTTL
+		if maxTTL == "" {
+			maxTTL = defaultPKIRoleTTL
+		}
+		err := homelab.WritePKIRole(ctx, cfg.Mount, vault.PKIRole{
+			Name:            role.Name,
+			AllowedDomains:  role.AllowedDomains,
+			AllowSubdomains: role.AllowSubdomains,
+			MaxTTL:          maxTTL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Roles = append(result.Roles, role.Name)
+	}
+	if err := o.grantCertManagerPKI(ctx, homelab, cfg); err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{
+		"VAULT_PKI_MOUNT":     cfg.Mount,
+		"VAULT_PKI_ROLE":      cfg.Roles[0].Name,
+		"VAULT_PKI_CA_BUNDLE": base64.StdEncoding.EncodeToString([]byte(chain)),
+	}
+	if err := o.secretsManager.UpdateGeneratedEnv(vars); err != nil {
+		log.Warn("Failed to persist PKI variables to .env.generated", "error", err)
+	}
+	if err := o.secretsManager.UpdateClusterVars(ctx, "flux-system", vars); err != nil {
+		return nil, fmt.Errorf("failed to record the issuing CA in cluster-vars: %w", err)
+	}
+	return result, nil
+}
+
+// grantCertManagerPKI lets the cert-manager service account sign with the PKI roles through kubernetes auth
+func (o *Orchestrator) grantCertManagerPKI(ctx context.Context, homelab *vault.Client, cfg *config.VaultPKIConfig) error {
+	enabled, err := homelab.AuthEnabled(ctx, "kubernetes")
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		log.Warn("Kubernetes auth is not enabled on the homelab Vault, cert-manager needs its own token to use the PKI roles")
+		return nil
+	}
+
+	var policy strings.Builder
+	for _, role := range cfg.Roles {
+		fmt.Fprintf(&policy, "path %q {\n  capabilities = [\"create\", \"update\"]\n}\n", cfg.Mount+"/sign/"+role.Name)
+		fmt.Fprintf(&policy, "path %q {\n  capabilities = [\"create\", \"update\"]\n}\n", cfg.Mount+"/issue/"+role.Name)
+	}
+	if err := homelab.WritePolicy(ctx, "cert-manager-pki", policy.String()); err != nil {
+		return err
+	}
+	return homelab.WriteKubernetesRole(ctx, "kubernetes", "cert-manager", "cert-manager", "cert-manager", []string{"cert-manager-pki"})
+}
+
+// nasVault returns a client for the NAS Vault of integration.vault, authenticated with VAULT_TOKEN
+func (o *Orchestrator) nasVault() (*vault.Client, error) {
+	cfg := o.config.Homelab.Integration.Vault
+	if cfg.Address == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("the NAS Vault needs integration.vault.address and VAULT_TOKEN")
+	}
+	return vault.NewClient(cfg.Address, cfg.Token, nil), nil
+}
+
+// homelabVaultToken returns HOMELAB_VAULT_TOKEN, else the token of the vault-admin-token secret of vault-config-operator
+func (o *Orchestrator) homelabVaultToken(ctx context.Context) (string, error) {
+	if token := o.lookupEnvValue("HOMELAB_VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	secret, err := o.k8sClient.GetSecret(ctx, "vault-config-operator", "vault-admin-token")
+	if err != nil {
+		return "", fmt.Errorf("no homelab Vault token: set HOMELAB_VAULT_TOKEN or create vault-config-operator/vault-admin-token: %w", err)
+	}
+	return strings.TrimSpace(string(secret.Data["token"])), nil
+}
+
+// homelabVault port-forwards to the in-cluster Vault, trusting the mesh root its certificate chains to.
+// The client has no token, see homelabVaultToken.
+func (o *Orchestrator) homelabVault(ctx context.Context) (*vault.Client, func(), error) {
+	roots := x509.NewCertPool()
+	rootCert, err := o.k8sClient.GetClientset().CoreV1().ConfigMaps(homelabVaultNamespace).Get(ctx, "istio-ca-root-cert", metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the mesh root the homelab Vault certificate chains to: %w", err)
+	}
+	if !roots.AppendCertsFromPEM([]byte(rootCert.Data["root-cert.pem"])) {
+		return nil, nil, fmt.Errorf("istio-ca-root-cert in %s holds no certificate", homelabVaultNamespace)
+	}
+
+	localPort, stop, err := o.k8sClient.PortForward(ctx, homelabVaultNamespace, homelabVaultService, homelabVaultPort)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reach the homelab Vault: %w", err)
+	}
+	tlsConfig := &tls.Config{RootCAs: roots, ServerName: homelabVaultHost, MinVersion: tls.VersionTLS12}
+	return vault.NewClient(fmt.Sprintf("https://127.0.0.1:%d", localPort), "", tlsConfig), stop, nil
+}
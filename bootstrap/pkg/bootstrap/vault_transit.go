@@ -0,0 +1,182 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/vault"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	transitTokenSecret = "vault-transit-token"
+	transitUnsealKey   = "autounseal"
+	transitPolicy      = "k8s-vault-unseal"
+	transitTokenPeriod = "8760h"
+	// vaultServerSelector matches the server pods of the Vault Helm chart, whose readiness requires an unsealed Vault
+	vaultServerSelector = "app.kubernetes.io/name=vault,component=server"
+)
+
+// TransitRotationResult describes a transit token rotation
+type TransitRotationResult struct {
+	Accessor    string   `json:"accessor"`
+	Policies    []string `json:"policies"`
+	Restarted   []string `json:"restarted"`
+	SealType    string   `json:"seal_type"`
+	OldRevoked  bool     `json:"old_revoked"`
+	OldAccessor string   `json:"old_accessor,omitempty"`
+}
+
+// RotateTransitToken replaces the token the homelab Vault auto-unseals with against the NAS Vault transit engine.
+// The new token is proven against the transit key and stored, the Vault pods are restarted one at a time and
+// must come back unsealed before the old token is revoked. Until then the old token is restored on failure.
+func (o *Orchestrator) RotateTransitToken(ctx context.Context) (*TransitRotationResult, error) {
+	if o.isNAS || o.config.Homelab == nil {
+		return nil, fmt.Errorf("the transit token is used by the homelab Vault, run this against the homelab cluster")
+	}
+	if err := k8s.GuardMutation("rotate the Vault transit token"); err != nil {
+		return nil, err
+	}
+	nas, err := o.nasVault()
+	if err != nil {
+		return nil, err
+	}
+	transitMount := o.config.Homelab.Security.Vault.TransitPath
+	if transitMount == "" {
+		transitMount = "transit"
+	}
+
+	var oldToken string
+	if secret, err := o.k8sClient.GetSecret(ctx, homelabVaultNamespace, transitTokenSecret); err == nil {
+		oldToken = string(secret.Data["vault_transit_token"])
+	}
+	if oldToken == "" {
+		oldToken = o.lookupEnvValue("VAULT_TRANSIT_TOKEN")
+	}
+
+	result := &TransitRotationResult{}
+	policies := []string{transitPolicy}
+	if oldToken != "" {
+		if old, err := nas.LookupToken(ctx, oldToken); err == nil {
+			result.OldAccessor = old.Accessor
+			if filtered := withoutDefaultPolicy(old.Policies); len(filtered) > 0 {
+				policies = filtered
+			}
+		} else {
+			log.Warn("The current transit token is not known to the NAS Vault, it will not be revoked", "error", err)
+			oldToken = ""
+		}
+	}
+
+	log.Info("Creating transit token", "vault", nas.Address(), "policies", policies)
+	token, err := nas.CreateToken(ctx, vault.TokenRequest{
+		Policies:    policies,
+		DisplayName: transitPolicy,
+		Period:      transitTokenPeriod,
+		Renewable:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Accessor, result.Policies = token.Accessor, token.Policies
+	if err := nas.WithToken(token.Token).TransitEncrypt(ctx, transitMount, transitUnsealKey, []byte("transit token rotation")); err != nil {
+		_ = nas.RevokeToken(ctx, token.Token)
+		return nil, fmt.Errorf("the new token cannot use the unseal key, revoked it: %w", err)
+	}
+
+	if err := o.secretsManager.CreateVaultTransitTokenSecret(ctx, token.Token); err != nil {
+		return nil, err
+	}
+	restore := func(cause error) error {
+		if oldToken == "" {
+			return cause
+		}
+		if err := o.secretsManager.CreateVaultTransitTokenSecret(context.Background(), oldToken); err != nil {
+			log.Error("Failed to restore the previous transit token", "error", err)
+		}
+		return fmt.Errorf("%w; the previous transit token was restored and not revoked", cause)
+	}
+
+	if result.Restarted, err = o.restartVaultServers(ctx); err != nil {
+		return nil, restore(err)
+	}
+	homelab, stop, err := o.homelabVault(ctx)
+	if err != nil {
+		return nil, restore(err)
+	}
+	status, err := homelab.SealStatus(ctx)
+	stop()
+	if err != nil {
+		return nil, restore(err)
+	}
+	if status.Sealed {
+		return nil, restore(fmt.Errorf("the homelab Vault is still sealed after the restart"))
+	}
+	result.SealType = status.Type
+
+	if oldToken != "" && oldToken != token.Token {
+		if err := nas.RevokeToken(ctx, oldToken); err != nil {
+			log.Warn("Failed to revoke the previous transit token", "accessor", result.OldAccessor, "error", err)
+		} else {
+			result.OldRevoked = true
+		}
+	}
+	return result, nil
+}
+
+// restartVaultServers deletes the Vault server pods one at a time, the chart's OnDelete strategy leaving
+// rollouts to us, and waits for each to come back ready, which its probe only reports once unsealed
+func (o *Orchestrator) restartVaultServers(ctx context.Context) ([]string, error) {
+	pods := o.k8sClient.GetClientset().CoreV1().Pods(homelabVaultNamespace)
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: vaultServerSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Vault pods: %w", err)
+	}
+	if len(list.Items) == 0 {
+		return nil, fmt.Errorf("no Vault server pods found in %s", homelabVaultNamespace)
+	}
+
+	var restarted []string
+	for _, pod := range list.Items {
+		log.Info("Restarting Vault pod", "pod", pod.Name)
+		if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil {
+			return restarted, fmt.Errorf("failed to delete %s: %w", pod.Name, err)
+		}
+		err := k8s.Poll(ctx, 5*time.Second, waitTimeout(ctx, defaultWaitTimeout), func(ctx context.Context) (bool, error) {
+			current, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+			if err != nil || current.UID == pod.UID {
+				return false, nil
+			}
+			return podReady(current), nil
+		})
+		if err != nil {
+			return restarted, fmt.Errorf("%s did not come back unsealed: %w", pod.Name, err)
+		}
+		restarted = append(restarted, pod.Name)
+	}
+	return restarted, nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// withoutDefaultPolicy drops the default policy Vault attaches to every token
+func withoutDefaultPolicy(policies []string) []string {
+	var filtered []string
+	for _, policy := range policies {
+		if policy != "default" {
+			filtered = append(filtered, policy)
+		}
+	}
+	return filtered
+}
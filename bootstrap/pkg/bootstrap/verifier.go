@@ -2,40 +2,61 @@ package bootstrap
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
 	"github.com/fredericrous/homelab/bootstrap/pkg/discovery"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var (
 	serviceEntryGVR    = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1beta1", Resource: "serviceentries"}
 	destinationRuleGVR = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1beta1", Resource: "destinationrules"}
+	gatewayGVR         = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"}
 )
 
-// VerifyMesh runs acceptance checks across the homelab and NAS clusters.
-func VerifyMesh(ctx context.Context) error {
+// waypointGatewayClass is the class of the Gateways Istio deploys as waypoint proxies
+const waypointGatewayClass = "istio-waypoint"
+
+// VerifyMesh runs acceptance checks across the homelab and NAS clusters, probing the peer from the from cluster.
+// The data plane mode of each cluster is detected from its ztunnel deployment.
+func VerifyMesh(ctx context.Context, from string, overrides ...ClusterOverride) error {
 	projectRoot, err := findProjectRoot()
 	if err != nil {
 		return err
 	}
-	return verifyMeshWithRoot(ctx, projectRoot)
+	return verifyMeshWithRoot(ctx, projectRoot, from, "", overrides...)
 }
 
-func verifyMeshWithRoot(ctx context.Context, projectRoot string) error {
+// verifyMeshWithRoot verifies the mesh for the given data plane mode, detected per cluster when empty
+func verifyMeshWithRoot(ctx context.Context, projectRoot, from, mode string, overrides ...ClusterOverride) error {
+	if from == "" {
+		from = "homelab"
+	}
+	if from != "homelab" && from != "nas" {
+		return fmt.Errorf("unknown cluster %q to verify from, expected homelab or nas", from)
+	}
+
 	discoveryService := discovery.NewClusterDiscovery(projectRoot)
 	contexts, err := discoveryService.ListContexts(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list kube contexts: %w", err)
 	}
+	applyClusterOverrides(contexts, overrides)
 
 	nasInfo, ok := contexts["nas"]
 	if !ok {
@@ -95,14 +116,43 @@ func verifyMeshWithRoot(ctx context.Context, projectRoot string) error {
 		errs = append(errs, err)
 	}
 
-	if err := runIstioctlProxyStatus(ctx, nasInfo, "nas"); err != nil {
+	for _, target := range []struct {
+		client *k8s.Client
+		name   string
+	}{{nasClient, "nas"}, {homelabClient, "homelab"}} {
+		clusterMode := mode
+		if clusterMode == "" {
+			clusterMode = detectMeshMode(ctx, target.client)
+		}
+		if clusterMode != config.MeshModeAmbient {
+			continue
+		}
+		if err := verifyZtunnel(ctx, target.client, target.name); err != nil {
+			errs = append(errs, err)
+		}
+		if err := verifyWaypoints(ctx, target.client, target.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := verifyProxySync(ctx, nasClient, "nas"); err != nil {
 		errs = append(errs, err)
 	}
-	if err := runIstioctlProxyStatus(ctx, homelabInfo, "homelab"); err != nil {
+	if err := verifyProxySync(ctx, homelabClient, "homelab"); err != nil {
+		errs = append(errs, err)
+	}
+	if err := verifyRemoteCluster(ctx, nasClient, "nas", "homelab"); err != nil {
+		errs = append(errs, err)
+	}
+	if err := verifyRemoteCluster(ctx, homelabClient, "homelab", "nas"); err != nil {
 		errs = append(errs, err)
 	}
 
-	if err := verifyGatewayCurl(ctx, homelabInfo); err != nil {
+	if from == "nas" {
+		if err := verifyPeerGatewayFrom(ctx, homelabClient, meshTarget{name: "nas", client: nasClient}); err != nil {
+			errs = append(errs, err)
+		}
+	} else if err := verifyGatewayCurl(ctx, homelabInfo); err != nil {
 		errs = append(errs, err)
 	}
 
@@ -146,6 +196,62 @@ func verifyGatewayPods(ctx context.Context, client *k8s.Client, cluster string)
 	return nil
 }
 
+// detectMeshMode reports ambient when ztunnel is deployed, the way mesh status tells the modes apart
+func detectMeshMode(ctx context.Context, client *k8s.Client) string {
+	if _, err := client.GetClientset().AppsV1().DaemonSets(istioNamespace).Get(ctx, "ztunnel", metav1.GetOptions{}); err == nil {
+		return config.MeshModeAmbient
+	}
+	return config.MeshModeSidecar
+}
+
+// verifyZtunnel checks that ztunnel runs on every node, ambient workloads of a node without it lose connectivity
+func verifyZtunnel(ctx context.Context, client *k8s.Client, cluster string) error {
+	ztunnel, err := client.GetClientset().AppsV1().DaemonSets(istioNamespace).Get(ctx, "ztunnel", metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("%s: failed to get daemonset %s/ztunnel: %w", cluster, istioNamespace, err)
+	}
+	if ztunnel.Status.DesiredNumberScheduled == 0 || ztunnel.Status.NumberReady < ztunnel.Status.DesiredNumberScheduled {
+		return fmt.Errorf("%s: daemonset %s/ztunnel not ready (%d/%d)", cluster, istioNamespace, ztunnel.Status.NumberReady, ztunnel.Status.DesiredNumberScheduled)
+	}
+	return nil
+}
+
+// verifyWaypoints checks that every waypoint Gateway is programmed, a cluster without waypoints only runs L4 policies
+func verifyWaypoints(ctx context.Context, client *k8s.Client, cluster string) error {
+	list, err := client.GetDynamicClient().Resource(gatewayGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Debug("Gateway API not installed, no waypoint to verify", "cluster", cluster)
+			return nil
+		}
+		return fmt.Errorf("%s: failed to list waypoints: %w", cluster, err)
+	}
+
+	var pending []string
+	for _, gateway := range list.Items {
+		class, _, _ := unstructured.NestedString(gateway.Object, "spec", "gatewayClassName")
+		if class != waypointGatewayClass {
+			continue
+		}
+		conditions, _, _ := unstructured.NestedSlice(gateway.Object, "status", "conditions")
+		programmed := false
+		for _, raw := range conditions {
+			condition, _ := raw.(map[string]interface{})
+			if condition["type"] == "Programmed" && condition["status"] == "True" {
+				programmed = true
+			}
+		}
+		if !programmed {
+			pending = append(pending, gateway.GetNamespace()+"/"+gateway.GetName())
+		}
+	}
+	if len(pending) > 0 {
+		sort.Strings(pending)
+		return fmt.Errorf("%s: waypoints not programmed: %s", cluster, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
 func verifySecretExists(ctx context.Context, client *k8s.Client, name, cluster string) error {
 	secret, err := client.GetClientset().CoreV1().Secrets(istioNamespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
@@ -168,29 +274,169 @@ func verifyTLSSecret(ctx context.Context, client *k8s.Client, name, cluster stri
 	if _, ok := secret.Data["tls.key"]; !ok {
 		return fmt.Errorf("%s: secret %s/%s missing tls.key", cluster, istioNamespace, name)
 	}
-	return nil
+	return verifyGatewayCertExpiry(secret, cluster)
 }
 
-func runIstioctlProxyStatus(ctx context.Context, info *discovery.ClusterInfo, cluster string) error {
-	args := []string{"--kubeconfig", info.Kubeconfig}
-	if strings.TrimSpace(info.Context) != "" {
-		args = append(args, "--context", info.Context)
+// syncStatus is an entry of the legacy istiod /debug/syncz format
+type syncStatus struct {
+	Proxy         string `json:"proxy"`
+	ClusterSent   string `json:"cluster_sent"`
+	ClusterAcked  string `json:"cluster_acked"`
+	ListenerSent  string `json:"listener_sent"`
+	ListenerAcked string `json:"listener_acked"`
+	RouteSent     string `json:"route_sent"`
+	RouteAcked    string `json:"route_acked"`
+	EndpointSent  string `json:"endpoint_sent"`
+	EndpointAcked string `json:"endpoint_acked"`
+}
+
+// syncDiscovery is the xDS ClientStatus format of /debug/syncz served by recent istiod releases
+type syncDiscovery struct {
+	Resources []struct {
+		Node struct {
+			ID string `json:"id"`
+		} `json:"node"`
+		GenericXdsConfigs []struct {
+			TypeURL      string `json:"typeUrl"`
+			ConfigStatus string `json:"configStatus"`
+		} `json:"genericXdsConfigs"`
+	} `json:"resources"`
+}
+
+// remoteClusterStatus is an entry of istiod /debug/clusterz
+type remoteClusterStatus struct {
+	ID         string `json:"id"`
+	SecretName string `json:"secretName"`
+	SyncStatus string `json:"syncStatus"`
+}
+
+// verifyProxySync checks through the API server proxy that istiod has every proxy in sync, replacing istioctl proxy-status
+func verifyProxySync(ctx context.Context, client *k8s.Client, cluster string) error {
+	var stale []string
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+		}
+		data, err := istiodDebug(ctx, client, "syncz")
+		if err != nil {
+			return fmt.Errorf("%s: proxy sync status unavailable: %w", cluster, err)
+		}
+		stale, err = staleProxies(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cluster, err)
+		}
+		if len(stale) == 0 {
+			return nil
+		}
 	}
-	args = append(args, "proxy-status")
+	sort.Strings(stale)
+	return fmt.Errorf("%s: %d proxies not in sync with istiod: %s", cluster, len(stale), trimOutput(strings.Join(stale, "\n"), 10))
+}
 
-	cmdCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+// staleProxies returns the proxies whose last pushed config was not acknowledged
+func staleProxies(data []byte) ([]string, error) {
+	var legacy []syncStatus
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		var stale []string
+		for _, status := range legacy {
+			if status.ClusterSent != status.ClusterAcked || status.ListenerSent != status.ListenerAcked ||
+				status.RouteSent != status.RouteAcked || status.EndpointSent != status.EndpointAcked {
+				stale = append(stale, status.Proxy)
+			}
+		}
+		return stale, nil
+	}
 
-	cmd := exec.CommandContext(cmdCtx, "istioctl", args...)
-	output, err := cmd.CombinedOutput()
-	trimmed := strings.TrimSpace(string(output))
+	var statuses syncDiscovery
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to decode istiod sync status: %w", err)
+	}
+	var stale []string
+	for _, resource := range statuses.Resources {
+		for _, config := range resource.GenericXdsConfigs {
+			if config.ConfigStatus == "STALE" || config.ConfigStatus == "ERROR" {
+				stale = append(stale, resource.Node.ID)
+				break
+			}
+		}
+	}
+	return stale, nil
+}
+
+// verifyRemoteCluster checks that istiod of cluster watches the peer through its remote secret
+func verifyRemoteCluster(ctx context.Context, client *k8s.Client, cluster, peer string) error {
+	data, err := istiodDebug(ctx, client, "clusterz")
+	if apierrors.IsNotFound(err) {
+		log.Debug("istiod does not serve /debug/clusterz, skipping remote cluster check", "cluster", cluster)
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("%s: istioctl proxy-status failed: %w\n%s", cluster, err, trimOutput(trimmed, 20))
+		return fmt.Errorf("%s: remote cluster status unavailable: %w", cluster, err)
+	}
+
+	var clusters []remoteClusterStatus
+	if err := json.Unmarshal(data, &clusters); err != nil {
+		return fmt.Errorf("%s: failed to decode istiod remote clusters: %w", cluster, err)
+	}
+	for _, remote := range clusters {
+		if remote.ID != peer {
+			continue
+		}
+		if remote.SyncStatus != "" && remote.SyncStatus != "synced" {
+			return fmt.Errorf("%s: istiod has not synced peer %s (%s, secret %s)", cluster, peer, remote.SyncStatus, remote.SecretName)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: istiod does not watch peer %s, check istio-remote-secret-%s", cluster, peer, peer)
+}
+
+// istiodDebug reads an istiod debug endpoint through the API server pod proxy
+func istiodDebug(ctx context.Context, client *k8s.Client, endpoint string) ([]byte, error) {
+	pods, err := client.GetClientset().CoreV1().Pods(istioNamespace).List(ctx, metav1.ListOptions{LabelSelector: "app=istiod"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list istiod pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		data, err := client.GetClientset().CoreV1().Pods(istioNamespace).ProxyGet("http", pod.Name, "15014", "debug/"+endpoint, nil).DoRaw(reqCtx)
+		cancel()
+		return data, err
+	}
+	return nil, fmt.Errorf("no running istiod pod")
+}
+
+// verifyPeerGatewayFrom checks that the east-west gateway of the peer is reachable from the from cluster
+func verifyPeerGatewayFrom(ctx context.Context, peerClient *k8s.Client, from meshTarget) error {
+	svc, err := peerClient.GetService(ctx, istioNamespace, eastWestServiceName)
+	if err != nil {
+		return fmt.Errorf("%s: failed to read peer east-west gateway: %w", from.name, err)
+	}
+	endpoint := endpointFromService(svc)
+	if endpoint == nil || endpoint.Host == "" {
+		return fmt.Errorf("%s: peer east-west gateway has no address yet", from.name)
+	}
+
+	report := &ClusterMeshReport{GatewayEndpoint: net.JoinHostPort(endpoint.Host, strconv.Itoa(int(endpoint.Port)))}
+	probeGateway(ctx, report, from)
+	if !report.GatewayReachable {
+		return fmt.Errorf("%s: peer east-west gateway %s unreachable (probe: %s)", from.name, report.GatewayEndpoint, report.GatewayProbe)
 	}
 	return nil
 }
 
 func verifyGatewayCurl(ctx context.Context, info *discovery.ClusterInfo) error {
+	if k8s.ReadOnly() {
+		log.Warn("Skipping vault gateway curl, it needs kubectl exec which read-only mode forbids")
+		return nil
+	}
+
 	args := []string{"--kubeconfig", info.Kubeconfig}
 	if strings.TrimSpace(info.Context) != "" {
 		args = append(args, "--context", info.Context)
@@ -0,0 +1,428 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/backup"
+	"github.com/fredericrous/homelab/bootstrap/pkg/certmanager"
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+	"github.com/fredericrous/homelab/bootstrap/pkg/health"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/observability"
+	"github.com/fredericrous/homelab/bootstrap/pkg/prereq"
+	"github.com/fredericrous/homelab/bootstrap/pkg/resources"
+	"github.com/fredericrous/homelab/bootstrap/pkg/security"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VerifyCheck selects a group of bootstrap verify checks
+type VerifyCheck string
+
+const (
+	VerifyHealth        VerifyCheck = "health"
+	VerifySecurity      VerifyCheck = "security"
+	VerifyObservability VerifyCheck = "observability"
+	VerifyResources     VerifyCheck = "resources"
+	VerifyBackup        VerifyCheck = "backup"
+	VerifyMeshCheck     VerifyCheck = "mesh"
+	VerifyFlux          VerifyCheck = "flux"
+	VerifyStorage       VerifyCheck = "storage"
+	VerifyDNS           VerifyCheck = "dns"
+	VerifyCerts         VerifyCheck = "certs"
+)
+
+// AllVerifyChecks lists every check group, in the order they run
+var AllVerifyChecks = []VerifyCheck{VerifyHealth, VerifyFlux, VerifyStorage, VerifyDNS, VerifyCerts, VerifySecurity, VerifyObservability, VerifyResources, VerifyBackup, VerifyMeshCheck}
+
+// backupMaxAge is how old the last backup may get before verify warns
+const backupMaxAge = 48 * time.Hour
+
+// ParseVerifyChecks validates check names, returning every check when none are given
+func ParseVerifyChecks(values []string) ([]VerifyCheck, error) {
+	if len(values) == 0 {
+		return AllVerifyChecks, nil
+	}
+	var checks []VerifyCheck
+	for _, value := range values {
+		check := VerifyCheck(strings.ToLower(strings.TrimSpace(value)))
+		if !slices.Contains(AllVerifyChecks, check) {
+			names := make([]string, 0, len(AllVerifyChecks))
+			for _, known := range AllVerifyChecks {
+				names = append(names, string(known))
+			}
+			return nil, fmt.Errorf("unknown check %q (expected one of %s)", value, strings.Join(names, ", "))
+		}
+		if !slices.Contains(checks, check) {
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// VerifySection holds the results of one check group on one cluster
+type VerifySection struct {
+	Check VerifyCheck `json:"check"`
+	// Cluster is empty for checks spanning both clusters
+	Cluster string               `json:"cluster,omitempty"`
+	Score   int                  `json:"score"`
+	Results []prereq.CheckResult `json:"results"`
+}
+
+// VerifyReport is the consolidated outcome of bootstrap verify across both clusters
+type VerifyReport struct {
+	Checks   []VerifyCheck   `json:"checks"`
+	Clusters []string        `json:"clusters"`
+	Score    int             `json:"score"`
+	Passed   int             `json:"passed"`
+	Warnings int             `json:"warnings"`
+	Failed   int             `json:"failed"`
+	Sections []VerifySection `json:"sections"`
+}
+
+// add records the results of a check group and updates the scores
+func (r *VerifyReport) add(check VerifyCheck, cluster string, results ...prereq.CheckResult) {
+	r.Sections = append(r.Sections, VerifySection{Check: check, Cluster: cluster, Score: verifyScore(results), Results: results})
+	var all []prereq.CheckResult
+	for _, section := range r.Sections {
+		all = append(all, section.Results...)
+	}
+	r.Score = verifyScore(all)
+	for _, result := range results {
+		switch result.Status {
+		case prereq.CheckPassed:
+			r.Passed++
+		case prereq.CheckWarning:
+			r.Warnings++
+		case prereq.CheckFailed:
+			r.Failed++
+		}
+	}
+}
+
+// ExitCode returns DoctorFailures when a check failed, DoctorWarnings when one warned, DoctorHealthy otherwise
+func (r *VerifyReport) ExitCode() int {
+	switch {
+	case r.Failed > 0:
+		return DoctorFailures
+	case r.Warnings > 0:
+		return DoctorWarnings
+	default:
+		return DoctorHealthy
+	}
+}
+
+// verifyScore rates results from 0 to 100, a warning counting half a pass
+func verifyScore(results []prereq.CheckResult) int {
+	if len(results) == 0 {
+		return 100
+	}
+	points := 0
+	for _, result := range results {
+		switch result.Status {
+		case prereq.CheckPassed:
+			points += 2
+		case prereq.CheckWarning:
+			points++
+		}
+	}
+	return points * 100 / (2 * len(results))
+}
+
+// verifyTarget is a cluster the per-cluster checks run against
+type verifyTarget struct {
+	name   string
+	client *k8s.Client
+}
+
+// Verify runs the selected checks against the local cluster and its peer, the mesh being probed from the
+// from cluster. An unreachable peer is reported as a failure and its checks are skipped.
+func (o *Orchestrator) Verify(ctx context.Context, checks []VerifyCheck, from string) *VerifyReport {
+	report := &VerifyReport{Checks: checks}
+	targets := []verifyTarget{{name: o.localClusterName(), client: o.k8sClient}}
+	peerResult, peer := o.doctorPeer(ctx)
+	if peer != nil {
+		targets = append(targets, verifyTarget{name: o.peerClusterName(), client: peer})
+	} else {
+		report.add("clusters", "", peerResult)
+	}
+	for _, target := range targets {
+		report.Clusters = append(report.Clusters, target.name)
+	}
+
+	for _, check := range checks {
+		log.Info("Running verify checks", "check", check)
+		switch check {
+		case VerifyDNS:
+			clients := make(map[string]*k8s.Client, len(targets))
+			for _, target := range targets {
+				clients[target.name] = target.client
+			}
+			report.add(check, "", o.doctorDNS(ctx, clients)...)
+		case VerifyMeshCheck:
+			report.add(check, "", o.verifyMeshResults(ctx, from)...)
+		default:
+			for _, target := range targets {
+				report.add(check, target.name, o.runVerifyCheck(ctx, check, target)...)
+			}
+		}
+	}
+	return report
+}
+
+// runVerifyCheck runs a per-cluster check group
+func (o *Orchestrator) runVerifyCheck(ctx context.Context, check VerifyCheck, target verifyTarget) []prereq.CheckResult {
+	switch check {
+	case VerifyHealth:
+		return verifyHealthResults(ctx, target.client)
+	case VerifyFlux:
+		return verifyFluxResults(ctx, flux.NewClient(target.client, o.gitOpsConfig()))
+	case VerifyStorage:
+		return verifyStorageResults(ctx, target.client)
+	case VerifyCerts:
+		return verifyCertResults(ctx, target.client)
+	case VerifySecurity:
+		return verifySecurityResults(ctx, target.client)
+	case VerifyObservability:
+		return verifyObservabilityResults(ctx, target.client)
+	case VerifyResources:
+		return verifyResourceResults(ctx, target.client)
+	case VerifyBackup:
+		return verifyBackupResults(ctx, target.client)
+	}
+	return nil
+}
+
+// checkResult builds a result passing when ok, with status otherwise
+func checkResult(name, description string, ok bool, status prereq.CheckStatus, err error, details string) prereq.CheckResult {
+	result := prereq.CheckResult{Name: name, Description: description, Status: prereq.CheckPassed, Details: details}
+	if !ok {
+		result.Status, result.Error = status, err
+	}
+	return result
+}
+
+func verifyHealthResults(ctx context.Context, client *k8s.Client) []prereq.CheckResult {
+	status, err := health.NewHealthChecker(client).CheckClusterHealth(ctx)
+	if status == nil {
+		return []prereq.CheckResult{{Name: "health", Description: "Cluster health", Status: prereq.CheckFailed, Error: err}}
+	}
+	components := make([]string, 0, len(status.Components))
+	for component := range status.Components {
+		components = append(components, component)
+	}
+	slices.Sort(components)
+
+	results := make([]prereq.CheckResult, 0, len(components))
+	for _, component := range components {
+		result := prereq.CheckResult{Name: "health-" + component, Description: "Component " + component, Details: status.Details[component]}
+		switch status.Components[component] {
+		case health.HealthStateHealthy:
+			result.Status = prereq.CheckPassed
+		case health.HealthStateUnhealthy:
+			result.Status = prereq.CheckFailed
+			result.Error = fmt.Errorf("unhealthy")
+		default:
+			result.Status = prereq.CheckWarning
+			result.Error = fmt.Errorf("%s", status.Components[component])
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func verifyFluxResults(ctx context.Context, client *flux.Client) []prereq.CheckResult {
+	roots, err := client.Tree(ctx)
+	if err != nil {
+		return []prereq.CheckResult{{Name: "flux", Description: "Flux reconciliation", Status: prereq.CheckFailed, Error: err,
+			Details: "Check Flux is installed with 'bootstrap homelab flux tree'"}}
+	}
+
+	var results []prereq.CheckResult
+	ready := 0
+	var walk func(nodes []*flux.TreeNode)
+	walk = func(nodes []*flux.TreeNode) {
+		for _, node := range nodes {
+			walk(node.Children)
+			if node.Kind != "Kustomization" && node.Kind != "HelmRelease" {
+				continue
+			}
+			switch {
+			case node.Ready:
+				ready++
+			case node.Suspended:
+				results = append(results, prereq.CheckResult{Name: "flux-" + strings.ToLower(node.Kind), Description: node.Ref(),
+					Status: prereq.CheckWarning, Error: fmt.Errorf("suspended"), Details: "Resume it once the reason it was suspended is gone"})
+			default:
+				results = append(results, prereq.CheckResult{Name: "flux-" + strings.ToLower(node.Kind), Description: node.Ref(),
+					Status: prereq.CheckFailed, Error: fmt.Errorf("%s", node.Message), Details: "Run 'bootstrap why " + node.Name + "' to find the cause"})
+			}
+		}
+	}
+	walk(roots)
+	return append([]prereq.CheckResult{checkResult("flux", "Flux objects ready", ready > 0, prereq.CheckFailed,
+		fmt.Errorf("no ready Kustomization or HelmRelease"), fmt.Sprintf("%d Kustomizations and HelmReleases ready", ready))}, results...)
+}
+
+func verifyStorageResults(ctx context.Context, client *k8s.Client) []prereq.CheckResult {
+	classes, err := client.GetClientset().StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []prereq.CheckResult{{Name: "storage", Description: "Storage classes", Status: prereq.CheckFailed, Error: err}}
+	}
+	defaultClass := ""
+	for _, class := range classes.Items {
+		if class.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" {
+			defaultClass = class.Name
+		}
+	}
+	results := []prereq.CheckResult{checkResult("storage-default-class", "Default storage class", defaultClass != "", prereq.CheckFailed,
+		fmt.Errorf("none of the %d storage classes is the default", len(classes.Items)), defaultClass)}
+
+	claims, err := client.GetClientset().CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return append(results, prereq.CheckResult{Name: "storage-claims", Description: "Persistent volume claims", Status: prereq.CheckFailed, Error: err})
+	}
+	var pending []string
+	for _, claim := range claims.Items {
+		if claim.Status.Phase != corev1.ClaimBound {
+			pending = append(pending, claim.Namespace+"/"+claim.Name)
+		}
+	}
+	return append(results, checkResult("storage-claims", "Persistent volume claims bound", len(pending) == 0, prereq.CheckWarning,
+		fmt.Errorf("%d not bound: %s", len(pending), strings.Join(pending, ", ")), fmt.Sprintf("%d bound", len(claims.Items))))
+}
+
+func verifyCertResults(ctx context.Context, client *k8s.Client) []prereq.CheckResult {
+	manager := certmanager.NewClient(client)
+	installed, err := manager.Installed(ctx)
+	if err != nil || !installed {
+		return []prereq.CheckResult{checkResult("certs", "cert-manager installed", false, prereq.CheckWarning,
+			errors.Join(fmt.Errorf("cert-manager API not served"), err), "")}
+	}
+	certificates, err := manager.Certificates(ctx)
+	if err != nil {
+		return []prereq.CheckResult{{Name: "certs", Description: "Certificates", Status: prereq.CheckFailed, Error: err}}
+	}
+
+	expiring := certmanager.Expiring(certificates, credentialWarning)
+	results := []prereq.CheckResult{checkResult("certs", "Certificates ready and valid", len(expiring) == 0, prereq.CheckWarning,
+		fmt.Errorf("%d of %d need attention", len(expiring), len(certificates)), fmt.Sprintf("%d certificates", len(certificates)))}
+	for _, cert := range expiring {
+		result := prereq.CheckResult{Name: "cert", Description: "Certificate " + cert.Namespace + "/" + cert.Name, Details: "Check issuer " + cert.Issuer}
+		if !cert.Ready {
+			result.Status, result.Error = prereq.CheckFailed, fmt.Errorf("not ready: %s", cert.Message)
+		} else {
+			result.Status, result.Error = prereq.CheckWarning, fmt.Errorf("expires in %s", time.Until(*cert.NotAfter).Round(time.Hour))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func verifySecurityResults(ctx context.Context, client *k8s.Client) []prereq.CheckResult {
+	status, err := security.NewSecurityValidator(client).ValidateClusterSecurity(ctx)
+	if err != nil {
+		return []prereq.CheckResult{{Name: "security", Description: "Security posture", Status: prereq.CheckFailed, Error: err}}
+	}
+	severe, other := 0, 0
+	for _, finding := range status.Vulnerabilities {
+		switch strings.ToLower(finding.Severity) {
+		case "critical", "high":
+			severe++
+		default:
+			other++
+		}
+	}
+	return []prereq.CheckResult{
+		checkResult("security-rbac", "RBAC enabled", status.RBACEnabled, prereq.CheckFailed, fmt.Errorf("RBAC not enabled"), ""),
+		checkResult("security-network-policies", "Network policies in place", status.NetworkPolicies, prereq.CheckWarning, fmt.Errorf("no network policies"), ""),
+		checkResult("security-policy-violations", "Baseline policies respected", status.PolicyViolations == 0, prereq.CheckWarning,
+			fmt.Errorf("%d violations", status.PolicyViolations), "Run 'bootstrap security scan' for the details"),
+		checkResult("security-findings", "No critical or high findings", severe == 0, prereq.CheckFailed,
+			fmt.Errorf("%d critical or high, %d other", severe, other), fmt.Sprintf("%d lower severity findings", other)),
+	}
+}
+
+func verifyObservabilityResults(ctx context.Context, client *k8s.Client) []prereq.CheckResult {
+	status, err := observability.NewObservabilityMonitor(client).ValidateObservabilityStack(ctx)
+	if err != nil {
+		return []prereq.CheckResult{{Name: "observability", Description: "Observability stack", Status: prereq.CheckFailed, Error: err}}
+	}
+	return []prereq.CheckResult{
+		checkResult("observability-prometheus", "Prometheus healthy", status.PrometheusHealthy, prereq.CheckWarning, fmt.Errorf("not healthy"), ""),
+		checkResult("observability-grafana", "Grafana healthy", status.GrafanaHealthy, prereq.CheckWarning, fmt.Errorf("not healthy"), ""),
+		checkResult("observability-alertmanager", "Alertmanager ready", status.AlertManagerReady, prereq.CheckWarning, fmt.Errorf("not ready"), ""),
+		checkResult("observability-logging", "Logging stack healthy", status.LoggingHealthy, prereq.CheckWarning, fmt.Errorf("not healthy"), ""),
+		checkResult("observability-alerts", "No active alerts", status.ActiveAlerts == 0, prereq.CheckWarning, fmt.Errorf("%d firing", status.ActiveAlerts), ""),
+	}
+}
+
+func verifyResourceResults(ctx context.Context, client *k8s.Client) []prereq.CheckResult {
+	status, err := resources.NewResourceManager(client).ValidateResourceManagement(ctx)
+	if err != nil {
+		return []prereq.CheckResult{{Name: "resources", Description: "Resource management", Status: prereq.CheckFailed, Error: err}}
+	}
+	results := []prereq.CheckResult{
+		checkResult("resources-metrics-server", "Metrics server healthy", status.MetricsServerHealthy, prereq.CheckWarning, fmt.Errorf("not healthy"), ""),
+	}
+	for _, alert := range status.ResourcePressure {
+		level := prereq.CheckWarning
+		if strings.EqualFold(alert.Severity, "critical") {
+			level = prereq.CheckFailed
+		}
+		subject := alert.Node
+		if subject == "" {
+			subject = alert.Namespace
+		}
+		results = append(results, prereq.CheckResult{Name: "resources-pressure", Description: fmt.Sprintf("%s pressure on %s", alert.Resource, subject),
+			Status: level, Error: fmt.Errorf("%s", alert.Description)})
+	}
+	if len(status.ResourcePressure) == 0 {
+		results = append(results, prereq.CheckResult{Name: "resources-pressure", Description: "No resource pressure", Status: prereq.CheckPassed})
+	}
+	return results
+}
+
+func verifyBackupResults(ctx context.Context, client *k8s.Client) []prereq.CheckResult {
+	status, err := backup.NewBackupValidator(client).ValidateBackupSystems(ctx)
+	if err != nil {
+		return []prereq.CheckResult{{Name: "backup", Description: "Backup systems", Status: prereq.CheckFailed, Error: err}}
+	}
+	lastBackup := "never"
+	if !status.LastBackup.IsZero() {
+		lastBackup = time.Since(status.LastBackup).Round(time.Minute).String() + " ago"
+	}
+	return []prereq.CheckResult{
+		checkResult("backup-velero", "Velero healthy", status.VeleroHealthy, prereq.CheckFailed, fmt.Errorf("not healthy"), strings.Join(status.BackupLocations, ", ")),
+		checkResult("backup-storage", "Backup storage ready", status.StorageReady, prereq.CheckWarning, fmt.Errorf("not ready"), ""),
+		checkResult("backup-recent", "Recent backup", !status.LastBackup.IsZero() && time.Since(status.LastBackup) < backupMaxAge, prereq.CheckWarning,
+			fmt.Errorf("last backup %s", lastBackup), "last backup "+lastBackup),
+	}
+}
+
+// verifyMeshResults runs the mesh acceptance checks across both clusters, one failed result per problem
+func (o *Orchestrator) verifyMeshResults(ctx context.Context, from string) []prereq.CheckResult {
+	if !o.isServiceMeshEnabled() {
+		return []prereq.CheckResult{{Name: "mesh", Description: "Service mesh", Status: prereq.CheckPassed, Details: "not enabled, skipped"}}
+	}
+	err := verifyMeshWithRoot(ctx, o.projectRoot, from, "", o.localOverride())
+	if err == nil {
+		return []prereq.CheckResult{{Name: "mesh", Description: "Mesh between homelab and nas", Status: prereq.CheckPassed, Details: "probed from " + from}}
+	}
+	problems := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		problems = joined.Unwrap()
+	}
+	results := make([]prereq.CheckResult, 0, len(problems))
+	for _, problem := range problems {
+		results = append(results, prereq.CheckResult{Name: "mesh", Description: "Mesh between homelab and nas", Status: prereq.CheckFailed, Error: problem,
+			Details: "Run 'bootstrap mesh status' for the state of each cluster"})
+	}
+	return results
+}
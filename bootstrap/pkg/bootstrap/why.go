@@ -0,0 +1,16 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+)
+
+// DiagnoseKustomization walks a Kustomization from its source down to the logs of its failing workload
+func (o *Orchestrator) DiagnoseKustomization(ctx context.Context, namespace, name string) (*flux.Diagnosis, error) {
+	fluxClient, err := o.newFluxClient()
+	if err != nil {
+		return nil, err
+	}
+	return fluxClient.Diagnose(ctx, namespace, name)
+}
@@ -0,0 +1,263 @@
+package ceph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// Namespace is where Rook runs the Ceph cluster and its toolbox
+	Namespace = "rook-ceph"
+	// ClusterName is the CephCluster the homelab deploys
+	ClusterName = "rook-ceph"
+
+	// Ceph health states
+	HealthOK   = "HEALTH_OK"
+	HealthWarn = "HEALTH_WARN"
+	HealthErr  = "HEALTH_ERR"
+
+	toolboxSelector  = "app=rook-ceph-tools"
+	toolboxContainer = "rook-ceph-tools"
+)
+
+var cephClusterGVR = schema.GroupVersionResource{Group: "ceph.rook.io", Version: "v1", Resource: "cephclusters"}
+
+// Status is the health of the Ceph cluster. Without a toolbox only the health and capacity Rook
+// reports on the CephCluster are known.
+type Status struct {
+	Health     string         `json:"health"`
+	Checks     []Check        `json:"checks,omitempty"`
+	OSDs       int            `json:"osds"`
+	OSDsUp     int            `json:"osdsUp"`
+	OSDsIn     int            `json:"osdsIn"`
+	PGs        int            `json:"pgs"`
+	PGStates   map[string]int `json:"pgStates,omitempty"`
+	BytesUsed  uint64         `json:"bytesUsed"`
+	BytesTotal uint64         `json:"bytesTotal"`
+	Flags      []string       `json:"flags,omitempty"`
+	// Source is toolbox when the status was read with the ceph CLI, cephcluster otherwise
+	Source string `json:"source"`
+}
+
+// Check is a health check Ceph raises
+type Check struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Healthy reports whether Ceph reports HEALTH_OK
+func (s *Status) Healthy() bool {
+	return s.Health == HealthOK
+}
+
+// UsedRatio is the share of the raw capacity in use
+func (s *Status) UsedRatio() float64 {
+	if s.BytesTotal == 0 {
+		return 0
+	}
+	return float64(s.BytesUsed) / float64(s.BytesTotal)
+}
+
+// CleanPGs returns how many placement groups are active+clean
+func (s *Status) CleanPGs() int {
+	return s.PGStates["active+clean"]
+}
+
+// Client reads and drives the Rook-Ceph cluster through its toolbox
+type Client struct {
+	client *k8s.Client
+}
+
+// NewClient creates a Ceph client for the cluster behind client
+func NewClient(client *k8s.Client) *Client {
+	return &Client{client: client}
+}
+
+// Status reads the cluster status with the toolbox, or from the CephCluster when no toolbox runs
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	pod, err := c.toolbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pod == "" {
+		log.Debug("Ceph toolbox not running, reading the CephCluster status")
+		return c.clusterStatus(ctx)
+	}
+	return c.toolboxStatus(ctx, pod)
+}
+
+// WaitForHealthy polls the status until Ceph reports HEALTH_OK and returns the last status read
+func (c *Client) WaitForHealthy(ctx context.Context, timeout time.Duration) (*Status, error) {
+	var status *Status
+	last := ""
+	err := k8s.Poll(ctx, 10*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		current, err := c.Status(ctx)
+		if err != nil {
+			log.Debug("Ceph status not readable yet", "error", err)
+			return false, nil
+		}
+		status = current
+		if status.Health != last {
+			log.Info("Ceph health", "status", status.Health, "osds_up", status.OSDsUp, "osds", status.OSDs)
+			last = status.Health
+		}
+		return status.Healthy(), nil
+	})
+	if status == nil {
+		return nil, fmt.Errorf("failed to read the Ceph status: %w", err)
+	}
+	return status, err
+}
+
+// toolbox returns a running toolbox pod, empty when none is deployed
+func (c *Client) toolbox(ctx context.Context) (string, error) {
+	pods, err := c.client.GetClientset().CoreV1().Pods(Namespace).List(ctx, metav1.ListOptions{LabelSelector: toolboxSelector})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the Ceph toolbox pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			return pod.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// ceph runs the ceph CLI in the toolbox and returns its output
+func (c *Client) ceph(ctx context.Context, pod string, args ...string) (string, error) {
+	return c.client.Exec(ctx, Namespace, pod, toolboxContainer, append([]string{"ceph"}, args...))
+}
+
+// cephStatus is the part of `ceph status --format json` the status is read from
+type cephStatus struct {
+	Health struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Severity string `json:"severity"`
+			Summary  struct {
+				Message string `json:"message"`
+			} `json:"summary"`
+		} `json:"checks"`
+	} `json:"health"`
+	OSDMap struct {
+		NumOSDs   int `json:"num_osds"`
+		NumUpOSDs int `json:"num_up_osds"`
+		NumInOSDs int `json:"num_in_osds"`
+	} `json:"osdmap"`
+	PGMap struct {
+		NumPGs     int `json:"num_pgs"`
+		PGsByState []struct {
+			StateName string `json:"state_name"`
+			Count     int    `json:"count"`
+		} `json:"pgs_by_state"`
+		BytesUsed  uint64 `json:"bytes_used"`
+		BytesTotal uint64 `json:"bytes_total"`
+	} `json:"pgmap"`
+}
+
+func (c *Client) toolboxStatus(ctx context.Context, pod string) (*Status, error) {
+	out, err := c.ceph(ctx, pod, "status", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+	var raw cephStatus
+	if err := json.Unmarshal([]byte(out), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ceph status: %w", err)
+	}
+
+	status := &Status{
+		Health:     raw.Health.Status,
+		OSDs:       raw.OSDMap.NumOSDs,
+		OSDsUp:     raw.OSDMap.NumUpOSDs,
+		OSDsIn:     raw.OSDMap.NumInOSDs,
+		PGs:        raw.PGMap.NumPGs,
+		PGStates:   map[string]int{},
+		BytesUsed:  raw.PGMap.BytesUsed,
+		BytesTotal: raw.PGMap.BytesTotal,
+		Source:     "toolbox",
+	}
+	for name, check := range raw.Health.Checks {
+		status.Checks = append(status.Checks, Check{Name: name, Severity: check.Severity, Message: check.Summary.Message})
+	}
+	sortChecks(status.Checks)
+	for _, state := range raw.PGMap.PGsByState {
+		status.PGStates[state.StateName] = state.Count
+	}
+
+	flags, err := c.osdFlags(ctx, pod)
+	if err != nil {
+		log.Warn("Failed to read the OSD flags", "error", err)
+	}
+	status.Flags = flags
+	return status, nil
+}
+
+// osdFlags returns the cluster-wide OSD flags, noout and norebalance among them during maintenance
+func (c *Client) osdFlags(ctx context.Context, pod string) ([]string, error) {
+	out, err := c.ceph(ctx, pod, "osd", "dump", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+	var dump struct {
+		Flags string `json:"flags"`
+	}
+	if err := json.Unmarshal([]byte(out), &dump); err != nil {
+		return nil, fmt.Errorf("failed to parse ceph osd dump: %w", err)
+	}
+	if dump.Flags == "" {
+		return nil, nil
+	}
+	return strings.Split(dump.Flags, ","), nil
+}
+
+// clusterStatus reads the health and capacity Rook copies onto the CephCluster status
+func (c *Client) clusterStatus(ctx context.Context) (*Status, error) {
+	cluster, err := c.client.GetDynamicClient().Resource(cephClusterGVR).Namespace(Namespace).Get(ctx, ClusterName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("CephCluster %s/%s not found", Namespace, ClusterName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the CephCluster: %w", err)
+	}
+
+	status := &Status{Source: "cephcluster"}
+	status.Health, _, _ = unstructured.NestedString(cluster.Object, "status", "ceph", "health")
+	if status.Health == "" {
+		return nil, fmt.Errorf("CephCluster %s/%s reports no health yet", Namespace, ClusterName)
+	}
+	details, _, _ := unstructured.NestedMap(cluster.Object, "status", "ceph", "details")
+	for name, detail := range details {
+		fields, _ := detail.(map[string]interface{})
+		severity, _ := fields["severity"].(string)
+		message, _ := fields["message"].(string)
+		status.Checks = append(status.Checks, Check{Name: name, Severity: severity, Message: message})
+	}
+	sortChecks(status.Checks)
+	used, _, _ := unstructured.NestedInt64(cluster.Object, "status", "ceph", "capacity", "bytesUsed")
+	total, _, _ := unstructured.NestedInt64(cluster.Object, "status", "ceph", "capacity", "bytesTotal")
+	status.BytesUsed, status.BytesTotal = uint64(used), uint64(total)
+	return status, nil
+}
+
+// sortChecks orders checks by severity, errors first, then by name
+func sortChecks(checks []Check) {
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].Severity != checks[j].Severity {
+			return checks[i].Severity == HealthErr
+		}
+		return checks[i].Name < checks[j].Name
+	})
+}
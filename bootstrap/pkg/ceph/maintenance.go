@@ -0,0 +1,56 @@
+package ceph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+)
+
+// MaintenanceFlags keep Ceph from marking stopped OSDs out and moving their data while nodes reboot
+var MaintenanceFlags = []string{"noout", "norebalance"}
+
+// SetMaintenance sets the maintenance flags so nodes can be drained or rebooted without a rebalance
+func (c *Client) SetMaintenance(ctx context.Context) error {
+	return c.maintenance(ctx, "set")
+}
+
+// UnsetMaintenance clears the maintenance flags, letting Ceph recover the OSDs that stayed down
+func (c *Client) UnsetMaintenance(ctx context.Context) error {
+	return c.maintenance(ctx, "unset")
+}
+
+func (c *Client) maintenance(ctx context.Context, verb string) error {
+	if err := k8s.GuardMutation(fmt.Sprintf("ceph osd %s maintenance flags", verb)); err != nil {
+		return err
+	}
+	pod, err := c.toolbox(ctx)
+	if err != nil {
+		return err
+	}
+	if pod == "" {
+		return fmt.Errorf("the Ceph toolbox is not running in %s, deploy rook-ceph-tools to change the OSD flags", Namespace)
+	}
+	for _, flag := range MaintenanceFlags {
+		if _, err := c.ceph(ctx, pod, "osd", verb, flag); err != nil {
+			return fmt.Errorf("failed to %s %s: %w", verb, flag, err)
+		}
+		log.Info("OSD flag changed", "flag", flag, "action", verb)
+	}
+	return nil
+}
+
+// InMaintenance reports whether every maintenance flag is set
+func (s *Status) InMaintenance() bool {
+	set := map[string]bool{}
+	for _, flag := range s.Flags {
+		set[flag] = true
+	}
+	for _, flag := range MaintenanceFlags {
+		if !set[flag] {
+			return false
+		}
+	}
+	return true
+}
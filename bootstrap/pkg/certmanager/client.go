@@ -0,0 +1,286 @@
+package certmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// Namespace is where platform-foundation deploys cert-manager
+	Namespace = "cert-manager"
+	// DefaultExpiryWindow is how close to expiry a certificate is reported
+	DefaultExpiryWindow = 14 * 24 * time.Hour
+
+	webhookName         = "cert-manager-webhook"
+	testCertificateName = "bootstrap-test-certificate"
+)
+
+// Issuer types, detected from the ClusterIssuer spec
+const (
+	IssuerVault      = "vault"
+	IssuerACME       = "acme"
+	IssuerCA         = "ca"
+	IssuerSelfSigned = "selfSigned"
+)
+
+var (
+	groupVersion     = schema.GroupVersion{Group: "cert-manager.io", Version: "v1"}
+	clusterIssuerGVR = groupVersion.WithResource("clusterissuers")
+	// CertificateGVR is exported for the status dashboard watch
+	CertificateGVR = groupVersion.WithResource("certificates")
+)
+
+// deployments must all be ready before cert-manager admits and issues certificates
+var deployments = []string{"cert-manager", "cert-manager-cainjector", webhookName}
+
+// Client waits for and validates cert-manager
+type Client struct {
+	k8sClient *k8s.Client
+}
+
+// NewClient creates a new cert-manager client
+func NewClient(k8sClient *k8s.Client) *Client {
+	return &Client{k8sClient: k8sClient}
+}
+
+// IssuerStatus is the readiness of a ClusterIssuer
+type IssuerStatus struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Server  string `json:"server,omitempty"` // Vault address or ACME directory
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+}
+
+// Certificate is the issuance state of a Certificate
+type Certificate struct {
+	Namespace string     `json:"namespace"`
+	Name      string     `json:"name"`
+	Issuer    string     `json:"issuer"`
+	Ready     bool       `json:"ready"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+// Installed reports whether the cluster serves the cert-manager v1 API
+func (c *Client) Installed(ctx context.Context) (bool, error) {
+	resources, err := c.k8sClient.GetClientset().Discovery().ServerResourcesForGroupVersion(groupVersion.String())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to discover %s: %w", groupVersion, err)
+	}
+	served := map[string]bool{}
+	for _, resource := range resources.APIResources {
+		served[resource.Name] = true
+	}
+	return served[clusterIssuerGVR.Resource] && served[CertificateGVR.Resource], nil
+}
+
+// WaitForWebhook waits for the controllers and until the cainjector has given the webhook its CA bundle,
+// before which every Certificate or Issuer apply is rejected
+func (c *Client) WaitForWebhook(ctx context.Context, timeout time.Duration) error {
+	for _, name := range deployments {
+		if err := c.k8sClient.WaitForDeployment(ctx, Namespace, name, timeout); err != nil {
+			return fmt.Errorf("%s not ready: %w", name, err)
+		}
+	}
+
+	admission := c.k8sClient.GetClientset().AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	err := k8s.Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		config, err := admission.Get(ctx, webhookName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, webhook := range config.Webhooks {
+			if len(webhook.ClientConfig.CABundle) == 0 {
+				return false, nil
+			}
+		}
+		return len(config.Webhooks) > 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("the cert-manager webhook has no CA bundle injected: %w", err)
+	}
+	log.Debug("cert-manager webhook ready")
+	return nil
+}
+
+// Issuers returns the readiness of every ClusterIssuer
+func (c *Client) Issuers(ctx context.Context) ([]IssuerStatus, error) {
+	list, err := c.k8sClient.GetDynamicClient().Resource(clusterIssuerGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ClusterIssuers: %w", err)
+	}
+
+	statuses := make([]IssuerStatus, 0, len(list.Items))
+	for _, issuer := range list.Items {
+		status := IssuerStatus{Name: issuer.GetName()}
+		spec, _, _ := unstructured.NestedMap(issuer.Object, "spec")
+		for _, kind := range []string{IssuerVault, IssuerACME, IssuerCA, IssuerSelfSigned} {
+			if _, ok := spec[kind]; ok {
+				status.Type = kind
+			}
+		}
+		switch status.Type {
+		case IssuerVault:
+			status.Server, _, _ = unstructured.NestedString(issuer.Object, "spec", "vault", "server")
+		case IssuerACME:
+			status.Server, _, _ = unstructured.NestedString(issuer.Object, "spec", "acme", "server")
+		}
+		status.Ready, status.Message = readyCondition(&issuer)
+		// An ACME issuer is only usable once its account is registered with the directory
+		if status.Type == IssuerACME && status.Ready {
+			if uri, _, _ := unstructured.NestedString(issuer.Object, "status", "acme", "uri"); uri == "" {
+				status.Ready, status.Message = false, "ACME account not registered"
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// ValidateIssuers returns the issuers and an error naming those not ready
+func (c *Client) ValidateIssuers(ctx context.Context) ([]IssuerStatus, error) {
+	statuses, err := c.Issuers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	for _, status := range statuses {
+		if !status.Ready {
+			failed = append(failed, fmt.Sprintf("%s (%s)", status.Name, status.Message))
+		}
+	}
+	if len(failed) > 0 {
+		return statuses, fmt.Errorf("ClusterIssuers not ready: %v", failed)
+	}
+	return statuses, nil
+}
+
+// TestCertificate requests a certificate for dnsName from issuer, waits until it is issued and removes it
+func (c *Client) TestCertificate(ctx context.Context, issuer, namespace, dnsName string, timeout time.Duration) error {
+	if err := k8s.GuardMutation("create test Certificate"); err != nil {
+		return err
+	}
+	certificates := c.k8sClient.GetDynamicClient().Resource(CertificateGVR).Namespace(namespace)
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": groupVersion.String(),
+			"kind":       "Certificate",
+			"metadata": map[string]interface{}{
+				"name":      testCertificateName,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"secretName": testCertificateName,
+				"dnsNames":   []interface{}{dnsName},
+				"duration":   "1h",
+				"issuerRef": map[string]interface{}{
+					"name": issuer,
+					"kind": "ClusterIssuer",
+				},
+			},
+		},
+	}
+	if _, err := certificates.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create test Certificate: %w", err)
+	}
+	defer func() {
+		if err := certificates.Delete(context.Background(), testCertificateName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Warn("Failed to delete the test Certificate", "error", err)
+		}
+		err := c.k8sClient.GetClientset().CoreV1().Secrets(namespace).Delete(context.Background(), testCertificateName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Warn("Failed to delete the test certificate secret", "error", err)
+		}
+	}()
+
+	log.Info("Waiting for the test certificate", "issuer", issuer, "dns_name", dnsName)
+	var message string
+	err := k8s.Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		cert, err := certificates.Get(ctx, testCertificateName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		var ready bool
+		ready, message = readyCondition(cert)
+		return ready, nil
+	})
+	if err != nil {
+		return fmt.Errorf("issuer %s did not issue the test certificate: %s", issuer, message)
+	}
+	return nil
+}
+
+// Certificates returns the issuance state of every Certificate
+func (c *Client) Certificates(ctx context.Context) ([]Certificate, error) {
+	list, err := c.k8sClient.GetDynamicClient().Resource(CertificateGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Certificates: %w", err)
+	}
+	certificates := make([]Certificate, 0, len(list.Items))
+	for i := range list.Items {
+		certificates = append(certificates, CertificateFrom(&list.Items[i]))
+	}
+	return certificates, nil
+}
+
+// CertificateFrom reads the issuance state of a Certificate object
+func CertificateFrom(obj *unstructured.Unstructured) Certificate {
+	cert := Certificate{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	cert.Issuer, _, _ = unstructured.NestedString(obj.Object, "spec", "issuerRef", "name")
+	cert.Ready, cert.Message = readyCondition(obj)
+	if notAfter, _, _ := unstructured.NestedString(obj.Object, "status", "notAfter"); notAfter != "" {
+		if t, err := time.Parse(time.RFC3339, notAfter); err == nil {
+			cert.NotAfter = &t
+		}
+	}
+	return cert
+}
+
+// Expiring returns the certificates not ready or expiring within window, soonest first
+func Expiring(certificates []Certificate, window time.Duration) []Certificate {
+	var expiring []Certificate
+	for _, cert := range certificates {
+		if !cert.Ready || (cert.NotAfter != nil && time.Until(*cert.NotAfter) < window) {
+			expiring = append(expiring, cert)
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		a, b := expiring[i].NotAfter, expiring[j].NotAfter
+		switch {
+		case a == nil:
+			return b != nil
+		case b == nil:
+			return false
+		}
+		return a.Before(*b)
+	})
+	return expiring
+}
+
+// readyCondition returns the Ready condition of obj and its message
+func readyCondition(obj *unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		message, _ := condition["message"].(string)
+		return condition["status"] == "True", message
+	}
+	return false, "no Ready condition yet"
+}
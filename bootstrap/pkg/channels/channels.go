@@ -0,0 +1,155 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// Release channels a component can track
+const (
+	// Stable follows the newest general availability release
+	Stable = "stable"
+	// Edge also follows release candidates and other pre-releases
+	Edge = "edge"
+)
+
+// Component is a platform component published as GitHub releases
+type Component struct {
+	Name       string
+	Repository string
+}
+
+// Components are the platform components whose versions follow a channel
+var Components = []Component{
+	{Name: "cilium", Repository: "cilium/cilium"},
+	{Name: "istio", Repository: "istio/istio"},
+	{Name: "flux", Repository: "fluxcd/flux2"},
+	{Name: "rook", Repository: "rook/rook"},
+}
+
+// Channel returns the channel a component tracks, from its override or the cluster default
+func Channel(cfg config.ChannelsConfig, component string) string {
+	if channel := cfg.Components[component]; channel != "" {
+		return channel
+	}
+	if cfg.Default != "" {
+		return cfg.Default
+	}
+	return Stable
+}
+
+// Validate rejects unknown channels and components
+func Validate(cfg config.ChannelsConfig) error {
+	if cfg.Default != "" && cfg.Default != Stable && cfg.Default != Edge {
+		return fmt.Errorf("unknown default channel %q, expected %s or %s", cfg.Default, Stable, Edge)
+	}
+	for component, channel := range cfg.Components {
+		if _, ok := lookup(component); !ok {
+			return fmt.Errorf("unknown component %q in channels", component)
+		}
+		if channel != Stable && channel != Edge {
+			return fmt.Errorf("unknown channel %q for %s, expected %s or %s", channel, component, Stable, Edge)
+		}
+	}
+	return nil
+}
+
+// Release is a published version of a component
+type Release struct {
+	Tag        string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// Latest returns the newest release of the channel, ignoring tags that are not semantic versions
+func Latest(releases []Release, channel string) (semver.Version, bool) {
+	var versions []semver.Version
+	for _, release := range releases {
+		if release.Draft {
+			continue
+		}
+		version, err := semver.ParseTolerant(release.Tag)
+		if err != nil {
+			continue
+		}
+		if channel != Edge && (release.Prerelease || len(version.Pre) > 0) {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return semver.Version{}, false
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].GT(versions[j]) })
+	return versions[0], true
+}
+
+// ReleaseIndex lists component releases, caching them for the lifetime of the index
+type ReleaseIndex struct {
+	token      string
+	httpClient *http.Client
+	cache      map[string][]Release
+}
+
+// NewReleaseIndex creates a release index, authenticating with token when set
+func NewReleaseIndex(token string) *ReleaseIndex {
+	return &ReleaseIndex{
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		cache:      make(map[string][]Release),
+	}
+}
+
+// Releases returns the recent releases of a GitHub repository
+func (r *ReleaseIndex) Releases(ctx context.Context, repository string) ([]Release, error) {
+	if releases, ok := r.cache[repository]; ok {
+		return releases, nil
+	}
+
+	if err := airgap.GuardNetwork("list the releases of " + repository); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=100", repository)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := strings.TrimSpace(r.token); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s releases: %w", repository, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list %s releases: GET %s: %s", repository, url, resp.Status)
+	}
+
+	var releases []Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to decode %s releases: %w", repository, err)
+	}
+	r.cache[repository] = releases
+	return releases, nil
+}
+
+func lookup(name string) (Component, bool) {
+	for _, component := range Components {
+		if component.Name == name {
+			return component, true
+		}
+	}
+	return Component{}, false
+}
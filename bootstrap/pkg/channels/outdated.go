@@ -0,0 +1,131 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Status compares the deployed version of a component with the newest release of its channel
+type Status struct {
+	Component string
+	Cluster   string
+	Channel   string
+	Current   string
+	Proposed  string
+	// Installed is false when the component does not run on the cluster
+	Installed bool
+	Outdated  bool
+	// Ahead marks clusters running a newer version than their channel offers, e.g. an edge build on stable
+	Ahead bool
+	Error string
+}
+
+// Checker reports outdated components per cluster according to their channels
+type Checker struct {
+	index *ReleaseIndex
+}
+
+// NewChecker creates a checker sharing a release index across clusters
+func NewChecker(index *ReleaseIndex) *Checker {
+	return &Checker{index: index}
+}
+
+// Check compares every component deployed on the cluster with its channel
+func (c *Checker) Check(ctx context.Context, cluster string, client *k8s.Client, cfg config.ChannelsConfig) []Status {
+	statuses := make([]Status, 0, len(Components))
+	for _, component := range Components {
+		status := Status{Component: component.Name, Cluster: cluster, Channel: Channel(cfg, component.Name)}
+
+		current, err := DeployedVersion(ctx, client, component.Name)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				status.Error = err.Error()
+			}
+			statuses = append(statuses, status)
+			continue
+		}
+		status.Installed = true
+		status.Current = current
+
+		releases, err := c.index.Releases(ctx, component.Repository)
+		if err != nil {
+			status.Error = err.Error()
+			statuses = append(statuses, status)
+			continue
+		}
+		latest, ok := Latest(releases, status.Channel)
+		if !ok {
+			status.Error = fmt.Sprintf("no %s release found for %s", status.Channel, component.Repository)
+			statuses = append(statuses, status)
+			continue
+		}
+		status.Proposed = latest.String()
+
+		deployed, err := semver.ParseTolerant(current)
+		if err != nil {
+			status.Error = fmt.Sprintf("deployed version %q is not a semantic version", current)
+			statuses = append(statuses, status)
+			continue
+		}
+		switch deployed.Compare(latest) {
+		case -1:
+			status.Outdated = true
+		case 1:
+			// Never propose a downgrade when the cluster left its channel
+			status.Ahead = true
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// DeployedVersion reads the running version of a component from the cluster, a NotFound error when it is not installed
+func DeployedVersion(ctx context.Context, client *k8s.Client, component string) (string, error) {
+	apps := client.GetClientset().AppsV1()
+	switch component {
+	case "cilium":
+		ds, err := apps.DaemonSets("kube-system").Get(ctx, "cilium", metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return containerTag(ds.Spec.Template.Spec.Containers)
+	case "istio":
+		deployment, err := apps.Deployments("istio-system").Get(ctx, "istiod", metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return containerTag(deployment.Spec.Template.Spec.Containers)
+	case "flux":
+		// Flux labels its install with the distribution version, controller images have their own
+		namespace, err := client.GetClientset().CoreV1().Namespaces().Get(ctx, "flux-system", metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		if version := namespace.Labels["app.kubernetes.io/version"]; version != "" {
+			return version, nil
+		}
+		return "", fmt.Errorf("flux-system namespace has no app.kubernetes.io/version label")
+	case "rook":
+		deployment, err := apps.Deployments("rook-ceph").Get(ctx, "rook-ceph-operator", metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return containerTag(deployment.Spec.Template.Spec.Containers)
+	}
+	return "", fmt.Errorf("unknown component %q", component)
+}
+
+// containerTag returns the image tag of the main container
+func containerTag(containers []corev1.Container) (string, error) {
+	if len(containers) == 0 {
+		return "", fmt.Errorf("workload has no container")
+	}
+	return k8s.ImageTag(containers[0].Image), nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/viper"
+)
+
+// Roles a cluster plays in the multi-cluster mesh
+const (
+	RolePrimary = "primary" // Talos cluster bootstrapped from the homelab config
+	RoleStorage = "storage" // NAS cluster bootstrapped from the nas config
+	RoleEdge    = "edge"    // Additional cluster joining the mesh
+)
+
+// MeshCluster is a named member of the multi-cluster mesh
+type MeshCluster struct {
+	Name        string `yaml:"name" validate:"required,hostname_rfc1123"`
+	Role        string `yaml:"role" validate:"required,oneof=primary storage edge"`
+	KubeConfig  string `yaml:"kubeconfig,omitempty"`
+	KubeContext string `yaml:"context,omitempty"`
+	// GatewayHosts are used for the east-west gateway address when its service publishes none
+	GatewayHosts []string `yaml:"gateway_hosts,omitempty"`
+}
+
+// defaultMeshClusters is the two-cluster topology used when no clusters are declared
+var defaultMeshClusters = []MeshCluster{
+	{Name: "homelab", Role: RolePrimary},
+	{Name: "nas", Role: RoleStorage},
+}
+
+// MeshClusters returns the declared mesh members, or the homelab and NAS pair
+func (c *Config) MeshClusters() []MeshCluster {
+	if len(c.Clusters) > 0 {
+		return c.Clusters
+	}
+	return append([]MeshCluster(nil), defaultMeshClusters...)
+}
+
+// MeshDeclared reports whether the mesh members come from the clusters list rather than the defaults
+func (c *Config) MeshDeclared() bool {
+	return len(c.Clusters) > 0
+}
+
+// MeshClusterNames returns the names of all mesh members
+func (c *Config) MeshClusterNames() []string {
+	clusters := c.MeshClusters()
+	names := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		names = append(names, cluster.Name)
+	}
+	return names
+}
+
+// KnownClusters returns the clusters a command may select: homelab, nas and the members declared under
+// clusters in their config files. A declared cluster is loaded from the config file named after it.
+func (l *Loader) KnownClusters() []string {
+	names := []string{"homelab", "nas"}
+	for _, configType := range []string{"homelab", "nas"} {
+		v := viper.New()
+		v.SetConfigName(configType)
+		v.SetConfigType("yaml")
+		for _, dir := range l.configDirs {
+			v.AddConfigPath(dir)
+		}
+		if err := v.ReadInConfig(); err != nil {
+			continue
+		}
+		var clusters []MeshCluster
+		if err := v.UnmarshalKey("clusters", &clusters); err != nil {
+			log.Debug("Ignoring unreadable clusters list", "config", v.ConfigFileUsed(), "error", err)
+			continue
+		}
+		for _, cluster := range clusters {
+			if cluster.Name != "" && !slices.Contains(names, cluster.Name) {
+				names = append(names, cluster.Name)
+			}
+		}
+	}
+	return names
+}
+
+// EnvKeyPrefix turns a cluster name into the prefix of its environment keys, edge-1 becoming EDGE_1
+func EnvKeyPrefix(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(name))
+}
+
+// validateMeshClusters checks the clusters list names each member once and includes the local cluster
+func validateMeshClusters(clusters []MeshCluster, local string) error {
+	if len(clusters) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		if cluster.Name == "" {
+			return fmt.Errorf("clusters entries require a name")
+		}
+		if seen[cluster.Name] {
+			return fmt.Errorf("cluster %s is declared twice in clusters", cluster.Name)
+		}
+		seen[cluster.Name] = true
+		switch cluster.Role {
+		case RolePrimary, RoleStorage, RoleEdge:
+		default:
+			return fmt.Errorf("cluster %s role %q is not supported, expected primary, storage or edge", cluster.Name, cluster.Role)
+		}
+	}
+	if local != "" && !seen[local] {
+		return fmt.Errorf("local cluster %s is missing from clusters", local)
+	}
+	return nil
+}
@@ -1,11 +1,13 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/charmbracelet/log"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +16,7 @@ import (
 type Loader struct {
 	configDirs []string
 	envPrefix  string
+	profile    string
 }
 
 // NewLoader creates a new configuration loader
@@ -24,6 +27,7 @@ func NewLoader() *Loader {
 	return &Loader{
 		configDirs: configDirs,
 		envPrefix:  "HOMELAB",
+		profile:    activeProfile,
 	}
 }
 
@@ -108,7 +112,21 @@ func (l *Loader) LoadConfig(configType string) (*Config, error) {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
+		if l.profile != "" {
+			return nil, fmt.Errorf("profile %q needs a %s config file to merge over", l.profile, configType)
+		}
 		// Config file not found, use defaults and env vars
+	} else if err := checkSchemaVersion(v.ConfigFileUsed()); err != nil {
+		return nil, err
+	} else if l.profile != "" {
+		rendered, err := l.Render(configType)
+		if err != nil {
+			return nil, err
+		}
+		if err := v.ReadConfig(bytes.NewReader(rendered.Data)); err != nil {
+			return nil, fmt.Errorf("failed to read profile %s: %w", l.profile, err)
+		}
+		log.Debug("Config profile merged", "profile", l.profile, "file", rendered.Profile)
 	}
 
 	// Unmarshal into struct
@@ -145,6 +163,41 @@ func (l *Loader) LoadConfig(configType string) (*Config, error) {
 	return &config, nil
 }
 
+// FindConfigFile returns the path of the homelab or nas config file the loader would read
+func (l *Loader) FindConfigFile(configType string) (string, error) {
+	for _, dir := range l.configDirs {
+		for _, ext := range []string{".yaml", ".yml"} {
+			path := filepath.Join(dir, configType+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no %s config file found in %s", configType, strings.Join(l.configDirs, ", "))
+}
+
+// checkSchemaVersion rejects configs from newer releases and points older ones at the migration
+func checkSchemaVersion(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	version, err := SchemaVersion(data)
+	if err != nil {
+		return err
+	}
+	if version > CurrentSchemaVersion {
+		return fmt.Errorf("config %s uses schema version %d but this release supports up to %d, run 'bootstrap self-update'",
+			path, version, CurrentSchemaVersion)
+	}
+	if version < CurrentSchemaVersion {
+		log.Warn("Config uses an outdated schema, some settings may be ignored",
+			"file", path, "version", version, "current", CurrentSchemaVersion,
+			"fix", "bootstrap config migrate")
+	}
+	return nil
+}
+
 // setDefaults sets default configuration values
 func (l *Loader) setDefaults(v *viper.Viper, configType string) {
 	// Common defaults
@@ -231,9 +284,80 @@ func (l *Loader) loadSecrets(config *Config) error {
 		}
 	}
 
+	// Load the MinIO credentials Velero writes backups with from environment
+	if accessKey, secretKey := os.Getenv("MINIO_ACCESS_KEY"), os.Getenv("MINIO_SECRET_KEY"); accessKey != "" && secretKey != "" {
+		if config.Homelab != nil {
+			config.Homelab.Backup.AccessKey = accessKey
+			config.Homelab.Backup.SecretKey = secretKey
+		}
+		if config.NAS != nil {
+			config.NAS.Backup.AccessKey = accessKey
+			config.NAS.Backup.SecretKey = secretKey
+		}
+	}
+
+	// Load the credentials of the registry mirror from environment
+	if username, password := os.Getenv("REGISTRY_USERNAME"), os.Getenv("REGISTRY_PASSWORD"); username != "" && password != "" {
+		if config.Homelab != nil {
+			config.Homelab.Registry.Username = username
+			config.Homelab.Registry.Password = password
+		}
+		if config.NAS != nil {
+			config.NAS.Registry.Username = username
+			config.NAS.Registry.Password = password
+		}
+	}
+
+	// Load the Proxmox API token from environment, PROXMOX_VE_API_TOKEN being the id=secret pair the Terraform provider reads
+	if config.Homelab != nil {
+		tokenID, tokenSecret := os.Getenv("PROXMOX_TOKEN_ID"), os.Getenv("PROXMOX_TOKEN_SECRET")
+		if tokenID == "" || tokenSecret == "" {
+			tokenID, tokenSecret, _ = strings.Cut(os.Getenv("PROXMOX_VE_API_TOKEN"), "=")
+		}
+		if tokenID != "" && tokenSecret != "" {
+			config.Homelab.Proxmox.TokenID = tokenID
+			config.Homelab.Proxmox.TokenSecret = tokenSecret
+		}
+	}
+
+	// Load the credentials of a bucket GitOps source, defaulting to the MinIO ones
+	var sources []*GitOpsConfig
+	if config.Homelab != nil {
+		sources = append(sources, &config.Homelab.GitOps)
+	}
+	if config.NAS != nil {
+		sources = append(sources, &config.NAS.GitOps)
+	}
+	for _, gitops := range sources {
+		if !gitops.IsBucket() {
+			continue
+		}
+		accessKey, secretKey := os.Getenv("FLUX_BUCKET_ACCESS_KEY"), os.Getenv("FLUX_BUCKET_SECRET_KEY")
+		if accessKey == "" || secretKey == "" {
+			accessKey, secretKey = os.Getenv("MINIO_ACCESS_KEY"), os.Getenv("MINIO_SECRET_KEY")
+		}
+		if accessKey != "" && secretKey != "" {
+			gitops.Bucket.AccessKey = accessKey
+			gitops.Bucket.SecretKey = secretKey
+		}
+	}
+
 	return nil
 }
 
+// ParseAndValidate decodes a config document and runs the loader validation on it, without defaults,
+// environment variables or secrets
+func ParseAndValidate(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := (&Loader{}).validateConfig(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
 // validateConfig validates the loaded configuration
 func (l *Loader) validateConfig(config *Config) error {
 	// Basic validation - in a real implementation, use a validation library
@@ -247,6 +371,14 @@ func (l *Loader) validateConfig(config *Config) error {
 		if config.Homelab.GitOps.Repository == "" {
 			return fmt.Errorf("homelab gitops repository is required")
 		}
+		if err := validateGitOpsSource("homelab", &config.Homelab.GitOps); err != nil {
+			return err
+		}
+		switch mode := config.Homelab.Networking.ServiceMesh.Mode; mode {
+		case "", MeshModeSidecar, MeshModeAmbient:
+		default:
+			return fmt.Errorf("homelab networking.service_mesh.mode %q must be %s or %s", mode, MeshModeSidecar, MeshModeAmbient)
+		}
 	}
 
 	if config.NAS != nil {
@@ -259,11 +391,84 @@ func (l *Loader) validateConfig(config *Config) error {
 		if config.NAS.GitOps.Repository == "" {
 			return fmt.Errorf("nas gitops repository is required")
 		}
+		if err := validateGitOpsSource("nas", &config.NAS.GitOps); err != nil {
+			return err
+		}
+	}
+
+	local := ""
+	if config.Homelab != nil {
+		local = config.Homelab.Cluster.Name
+	} else if config.NAS != nil {
+		local = config.NAS.Cluster.Name
+	}
+	if err := validateMeshClusters(config.Clusters, local); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// validateGitOpsSource checks the repository URL matches the configured source type, authentication and image automation settings
+func validateGitOpsSource(cluster string, gitops *GitOpsConfig) error {
+	switch gitops.SourceType {
+	case "", SourceTypeGit:
+		if strings.HasPrefix(gitops.Repository, "oci://") {
+			return fmt.Errorf("%s gitops repository %s is an OCI artifact, set source_type: oci", cluster, gitops.Repository)
+		}
+	case SourceTypeOCI:
+		if !strings.HasPrefix(gitops.Repository, "oci://") {
+			return fmt.Errorf("%s gitops repository must be oci://<registry>/<artifact> when source_type is oci", cluster)
+		}
+	case SourceTypeBucket:
+		if gitops.Bucket.Endpoint == "" || gitops.Bucket.Name == "" {
+			return fmt.Errorf("%s gitops bucket endpoint and name are required when source_type is bucket", cluster)
+		}
+		if strings.Contains(gitops.Bucket.Endpoint, "://") {
+			return fmt.Errorf("%s gitops bucket endpoint %s must be host[:port] without scheme, set insecure for plain HTTP", cluster, gitops.Bucket.Endpoint)
+		}
+	default:
+		return fmt.Errorf("%s gitops source_type %q is not supported, expected git, oci or bucket", cluster, gitops.SourceType)
+	}
+
+	switch gitops.Auth {
+	case "", AuthToken:
+	case AuthSSH:
+		if gitops.SourceType != "" && gitops.SourceType != SourceTypeGit {
+			return fmt.Errorf("%s gitops auth ssh applies to Git repositories only", cluster)
+		}
+		if !strings.HasPrefix(gitops.Repository, "https://github.com/") {
+			return fmt.Errorf("%s gitops auth ssh manages GitHub deploy keys, repository must be https://github.com/<owner>/<repo>", cluster)
+		}
+	default:
+		return fmt.Errorf("%s gitops auth %q is not supported, expected token or ssh", cluster, gitops.Auth)
+	}
+
+	names := map[string]bool{}
+	for _, image := range gitops.ImageAutomation.Images {
+		if image.Name == "" || image.Image == "" {
+			return fmt.Errorf("%s gitops image_automation images need a name and an image", cluster)
+		}
+		if names[image.Name] {
+			return fmt.Errorf("%s gitops image_automation image %s is listed twice", cluster, image.Name)
+		}
+		names[image.Name] = true
+		if image.Semver != "" && image.Pattern != "" {
+			return fmt.Errorf("%s gitops image_automation image %s sets both semver and pattern", cluster, image.Name)
+		}
+		if image.Pattern != "" && !strings.Contains(image.Pattern, "?P<ts>") {
+			return fmt.Errorf("%s gitops image_automation image %s pattern needs a (?P<ts>...) group to order tags", cluster, image.Name)
+		}
+	}
+	if len(gitops.ImageAutomation.Images) > 0 && gitops.SourceType != "" && gitops.SourceType != SourceTypeGit {
+		return fmt.Errorf("%s gitops image_automation commits to Git and requires source_type git", cluster)
+	}
+	if len(gitops.ImageAutomation.Images) > 0 && gitops.Auth == AuthSSH && !gitops.DeployKey.Write {
+		return fmt.Errorf("%s gitops image_automation pushes with the deploy key, set deploy_key.write", cluster)
+	}
+	return nil
+}
+
 // SaveConfig saves configuration to a file
 func (l *Loader) SaveConfig(config *Config, filename string) error {
 	data, err := yaml.Marshal(config)
@@ -308,6 +513,13 @@ func (l *Loader) resolveRelativePaths(config *Config) error {
 		}
 	}
 
+	// Resolve mesh member kubeconfig paths
+	for i := range config.Clusters {
+		if path := config.Clusters[i].KubeConfig; path != "" && !filepath.IsAbs(path) {
+			config.Clusters[i].KubeConfig = filepath.Join(projectRoot, path)
+		}
+	}
+
 	// Resolve Homelab cluster kubeconfig path
 	if config.Homelab != nil && config.Homelab.Cluster.KubeConfig != "" {
 		if !filepath.IsAbs(config.Homelab.Cluster.KubeConfig) {
@@ -315,6 +527,13 @@ func (l *Loader) resolveRelativePaths(config *Config) error {
 		}
 	}
 
+	// Resolve the Proxmox CA certificate path
+	if config.Homelab != nil && config.Homelab.Proxmox.CACert != "" {
+		if !filepath.IsAbs(config.Homelab.Proxmox.CACert) {
+			config.Homelab.Proxmox.CACert = filepath.Join(projectRoot, config.Homelab.Proxmox.CACert)
+		}
+	}
+
 	// Resolve NAS cert path
 	if config.NAS != nil && config.NAS.Cluster.CertPath != "" {
 		if !filepath.IsAbs(config.NAS.Cluster.CertPath) {
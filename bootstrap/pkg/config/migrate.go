@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentSchemaVersion is the config schema version understood by this release
+const CurrentSchemaVersion = 2
+
+// schemaVersionKey is the top-level key recording the schema version of a config file
+const schemaVersionKey = "schema_version"
+
+// clusterKeys are the top-level sections holding a cluster configuration
+var clusterKeys = []string{"homelab", "nas"}
+
+// Migration upgrades a config document to the next schema version
+type Migration struct {
+	To          int
+	Description string
+	edits       func(root *yaml.Node) ([]edit, error)
+}
+
+// edit replaces the token old found at a 1-based line and column of the original document
+type edit struct {
+	line   int
+	column int
+	old    string
+	new    string
+}
+
+// migrations are applied in order to documents older than their target version
+var migrations = []Migration{
+	{
+		To:          2,
+		Description: "rename monitoring.alerting.channels to receivers, channels now selects release channels",
+		edits:       renameAlertingChannels,
+	},
+}
+
+// MigrationResult describes the upgrade of one config document
+type MigrationResult struct {
+	From    int
+	To      int
+	Applied []Migration
+	Data    []byte
+}
+
+// Changed reports whether the migration rewrote the document
+func (r *MigrationResult) Changed() bool {
+	return r.From != r.To
+}
+
+// Diff renders a unified diff between the original and migrated document
+func (r *MigrationResult) Diff(original []byte, name string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(original)),
+		B:        difflib.SplitLines(string(r.Data)),
+		FromFile: name,
+		ToFile:   name + " (migrated)",
+		Context:  3,
+	})
+}
+
+// SchemaVersion returns the schema version of a config document, 1 for files predating versioning
+func SchemaVersion(data []byte) (int, error) {
+	var doc struct {
+		SchemaVersion int `yaml:"schema_version"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return 0, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc.SchemaVersion == 0 {
+		return 1, nil
+	}
+	return doc.SchemaVersion, nil
+}
+
+// Migrate upgrades a config document to CurrentSchemaVersion, keeping its comments and key order
+func Migrate(data []byte) (*MigrationResult, error) {
+	from, err := SchemaVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	if from > CurrentSchemaVersion {
+		return nil, fmt.Errorf("config schema version %d is newer than this release supports (%d), update the bootstrap binary", from, CurrentSchemaVersion)
+	}
+
+	result := &MigrationResult{From: from, To: from, Data: data}
+	if from == CurrentSchemaVersion {
+		return result, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config is not a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	// Edits are applied to the original text so comments and blank lines survive
+	var edits []edit
+	for _, migration := range migrations {
+		if migration.To <= from {
+			continue
+		}
+		migrationEdits, err := migration.edits(root)
+		if err != nil {
+			return nil, fmt.Errorf("migration to schema version %d failed: %w", migration.To, err)
+		}
+		if len(migrationEdits) > 0 {
+			result.Applied = append(result.Applied, migration)
+			edits = append(edits, migrationEdits...)
+		}
+	}
+
+	migrated, err := applyEdits(data, edits)
+	if err != nil {
+		return nil, err
+	}
+	migrated = setSchemaVersion(migrated, root, CurrentSchemaVersion)
+
+	if version, err := SchemaVersion(migrated); err != nil || version != CurrentSchemaVersion {
+		return nil, fmt.Errorf("migrated config does not parse back to schema version %d: %v", CurrentSchemaVersion, err)
+	}
+	result.To = CurrentSchemaVersion
+	result.Data = migrated
+	return result, nil
+}
+
+// renameAlertingChannels moves the alert notification channels out of the way of release channels
+func renameAlertingChannels(root *yaml.Node) ([]edit, error) {
+	var edits []edit
+	for _, cluster := range clusterKeys {
+		alerting := lookupPath(root, cluster, "monitoring", "alerting")
+		key := mappingKey(alerting, "channels")
+		if key == nil {
+			continue
+		}
+		if mappingKey(alerting, "receivers") != nil {
+			return nil, fmt.Errorf("%s.monitoring.alerting has both channels and receivers, merge them by hand", cluster)
+		}
+		edits = append(edits, edit{line: key.Line, column: key.Column, old: "channels", new: "receivers"})
+	}
+	return edits, nil
+}
+
+// applyEdits replaces each edited token in place, checking the original text still holds it
+func applyEdits(data []byte, edits []edit) ([]byte, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+	for _, e := range edits {
+		if e.line < 1 || e.line > len(lines) {
+			return nil, fmt.Errorf("edit outside of the document at line %d", e.line)
+		}
+		line := lines[e.line-1]
+		start := e.column - 1
+		if start < 0 || !strings.HasPrefix(line[start:], e.old) {
+			return nil, fmt.Errorf("unexpected content at line %d, expected %q", e.line, e.old)
+		}
+		lines[e.line-1] = line[:start] + e.new + line[start+len(e.old):]
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// setSchemaVersion updates the schema_version value, or adds it as the first line of the document
+func setSchemaVersion(data []byte, root *yaml.Node, version int) []byte {
+	value := strconv.Itoa(version)
+	if node := mappingValue(root, schemaVersionKey); node != nil {
+		if updated, err := applyEdits(data, []edit{{line: node.Line, column: node.Column, old: node.Value, new: value}}); err == nil {
+			return updated
+		}
+	}
+	return append([]byte(fmt.Sprintf("%s: %s\n\n", schemaVersionKey, value)), data...)
+}
+
+// lookupPath walks nested mappings and returns the mapping at path, nil when absent
+func lookupPath(node *yaml.Node, path ...string) *yaml.Node {
+	for _, key := range path {
+		node = mappingValue(node, key)
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+	}
+	return node
+}
+
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetNodes records the machines of a cluster in a config document: cluster.nodes lists their addresses and
+// talos.nodes their address, role, hostname and MAC. Listed talos nodes are matched by hostname then address
+// and keep their other keys, such as patches. Only those two lists are rewritten, the rest of the document
+// and its comments are kept, and the document is returned unchanged when it already matches.
+func SetNodes(data []byte, cluster string, nodes []TalosNode) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config is not a YAML mapping")
+	}
+	section := lookupPath(doc.Content[0], cluster)
+	if section == nil {
+		return nil, fmt.Errorf("config has no %s section", cluster)
+	}
+	clusterSection := lookupPath(section, "cluster")
+	if clusterSection == nil {
+		return nil, fmt.Errorf("config has no %s.cluster section", cluster)
+	}
+
+	var edits []spanEdit
+
+	addresses := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, node := range nodes {
+		addresses.Content = append(addresses.Content, quotedScalar(node.Address))
+	}
+	if !sameScalars(mappingValue(clusterSection, "nodes"), addresses) {
+		edit, err := setSequence(clusterSection, "nodes", addresses)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+
+	talos := lookupPath(section, "talos")
+	if talos == nil && mappingKey(section, "talos") != nil {
+		return nil, fmt.Errorf("%s.talos is not a mapping", cluster)
+	}
+	existing := mappingValue(talos, "nodes")
+	machines := &yaml.Node{Kind: yaml.SequenceNode}
+	changed := existing == nil || existing.Kind != yaml.SequenceNode || len(existing.Content) != len(nodes)
+	for i, node := range nodes {
+		entry := matchingNode(existing, node)
+		if entry == nil {
+			entry = &yaml.Node{Kind: yaml.MappingNode}
+			changed = true
+		} else if i >= len(existing.Content) || existing.Content[i] != entry {
+			changed = true
+		}
+		fields := [][2]string{{"address", node.Address}, {"role", node.Role}, {"hostname", node.Hostname}, {"mac", node.MAC}}
+		for _, field := range fields {
+			if field[1] != "" && setScalar(entry, field[0], field[1]) {
+				changed = true
+			}
+		}
+		machines.Content = append(machines.Content, entry)
+	}
+	if changed {
+		var edit spanEdit
+		var err error
+		if talos == nil {
+			// Add the talos section right after the cluster section
+			talosSection := &yaml.Node{Kind: yaml.MappingNode}
+			talosSection.Content = append(talosSection.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "nodes"}, machines)
+			edit, err = insertKey(section, "talos", talosSection, lastLine(clusterSection))
+			edit.text = "\n" + edit.text
+		} else {
+			edit, err = setSequence(talos, "nodes", machines)
+		}
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+
+	if len(edits) == 0 {
+		return data, nil
+	}
+	return applySpanEdits(data, edits), nil
+}
+
+// spanEdit replaces the 1-based lines start to end, inserting before start when end is start-1
+type spanEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+// setSequence rewrites the value of key in mapping with a block sequence, or appends the key to mapping
+func setSequence(mapping *yaml.Node, key string, value *yaml.Node) (spanEdit, error) {
+	keyNode := mappingKey(mapping, key)
+	if keyNode == nil {
+		return insertKey(mapping, key, value, lastLine(mapping))
+	}
+	indent := strings.Repeat(" ", keyNode.Column-1)
+	itemIndent := indent + "  "
+	if current := mappingValue(mapping, key); current.Kind == yaml.SequenceNode && current.Style&yaml.FlowStyle == 0 && len(current.Content) > 0 {
+		// Keep the indentation of the existing items, as the dash may sit under the key or be indented
+		itemIndent = strings.Repeat(" ", current.Content[0].Column-3)
+	}
+	items, err := encodeIndented(value, itemIndent)
+	if err != nil {
+		return spanEdit{}, err
+	}
+	return spanEdit{start: keyNode.Line, end: lastLine(mappingValue(mapping, key)), text: indent + key + ":\n" + items}, nil
+}
+
+// insertKey adds key to mapping after line, indented like the other keys of mapping
+func insertKey(mapping *yaml.Node, key string, value *yaml.Node, after int) (spanEdit, error) {
+	if len(mapping.Content) == 0 {
+		return spanEdit{}, fmt.Errorf("cannot add %s to an empty mapping", key)
+	}
+	indent := strings.Repeat(" ", mapping.Content[0].Column-1)
+	body, err := encodeIndented(value, indent+"  ")
+	if err != nil {
+		return spanEdit{}, err
+	}
+	return spanEdit{start: after + 1, end: after, text: indent + key + ":\n" + body}, nil
+}
+
+// encodeIndented renders node as block YAML with every line indented
+func encodeIndented(node *yaml.Node, indent string) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			out.WriteString(indent + line)
+		}
+	}
+	return out.String(), nil
+}
+
+// applySpanEdits applies edits from the bottom of the document up so line numbers stay valid
+func applySpanEdits(data []byte, edits []spanEdit) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		lines[len(lines)-1] += "\n"
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, e := range edits {
+		replaced := append([]string{e.text}, lines[e.end:]...)
+		lines = append(lines[:e.start-1], replaced...)
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// lastLine returns the last line a node spans
+func lastLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		line = max(line, lastLine(child))
+	}
+	return line
+}
+
+// matchingNode finds the talos.nodes entry describing node
+func matchingNode(list *yaml.Node, node TalosNode) *yaml.Node {
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, field := range [][2]string{{"hostname", node.Hostname}, {"address", node.Address}} {
+		if field[1] == "" {
+			continue
+		}
+		for _, entry := range list.Content {
+			if value := mappingValue(entry, field[0]); value != nil && value.Value == field[1] {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// setScalar sets key of mapping to value, reporting whether it changed
+func setScalar(mapping *yaml.Node, key, value string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			if mapping.Content[i+1].Kind == yaml.ScalarNode && mapping.Content[i+1].Value == value {
+				return false
+			}
+			mapping.Content[i+1] = quotedScalar(value)
+			return true
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, quotedScalar(value))
+	return true
+}
+
+// sameScalars reports whether two sequences hold the same scalar values
+func sameScalars(a, b *yaml.Node) bool {
+	if a == nil || a.Kind != yaml.SequenceNode || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if a.Content[i].Kind != yaml.ScalarNode || a.Content[i].Value != b.Content[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+func quotedScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value, Style: yaml.DoubleQuotedStyle}
+}
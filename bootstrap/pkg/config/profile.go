@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnv selects the config profile when --profile is not given
+const ProfileEnv = "HOMELAB_PROFILE"
+
+var (
+	activeProfile string
+	profileName   = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+)
+
+// SetProfile selects the profile every loader created afterwards merges over the base config
+func SetProfile(profile string) {
+	activeProfile = profile
+}
+
+// ActiveProfile returns the selected profile, empty when the base config is used as is
+func ActiveProfile() string {
+	return activeProfile
+}
+
+// WithProfile overrides the profile of this loader
+func (l *Loader) WithProfile(profile string) *Loader {
+	l.profile = profile
+	return l
+}
+
+// Rendered is a config file with its profile merged in
+type Rendered struct {
+	Base    string
+	Profile string
+	Data    []byte
+}
+
+// Render returns the config of configType as the loader sees it once the profile is merged, before
+// defaults, environment variables and secrets are applied
+func (l *Loader) Render(configType string) (*Rendered, error) {
+	base, err := l.FindConfigFile(configType)
+	if err != nil {
+		return nil, err
+	}
+	rendered := &Rendered{Base: base}
+	if rendered.Data, err = os.ReadFile(base); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if l.profile == "" {
+		return rendered, nil
+	}
+
+	if rendered.Profile, err = l.findProfileFile(base, configType); err != nil {
+		return nil, err
+	}
+	overlay, err := os.ReadFile(rendered.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile %s: %w", l.profile, err)
+	}
+	if rendered.Data, err = MergeProfile(rendered.Data, overlay); err != nil {
+		return nil, fmt.Errorf("failed to merge profile %s: %w", rendered.Profile, err)
+	}
+	return rendered, nil
+}
+
+// findProfileFile returns <configType>.<profile>.yaml next to the base config file
+func (l *Loader) findProfileFile(base, configType string) (string, error) {
+	if !profileName.MatchString(l.profile) {
+		return "", fmt.Errorf("invalid profile name %q", l.profile)
+	}
+	dir := filepath.Dir(base)
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(dir, configType+"."+l.profile+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("profile %q not found, expected %s", l.profile, filepath.Join(dir, configType+"."+l.profile+".yaml"))
+}
+
+// MergeProfile merges a profile over a base config document. Mappings are merged key by key, any other
+// value of the profile (lists included) replaces the base one and a null value removes the key. The base
+// keeps its key order and comments, keys only found in the profile are appended. The schema version of the
+// base applies, the profile must not declare a newer one.
+func MergeProfile(base, profile []byte) ([]byte, error) {
+	var baseDoc, profileDoc yaml.Node
+	if err := yaml.Unmarshal(base, &baseDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := yaml.Unmarshal(profile, &profileDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	if len(profileDoc.Content) == 0 {
+		return base, nil
+	}
+	if version, err := SchemaVersion(profile); err != nil {
+		return nil, err
+	} else if version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("profile uses schema version %d but this release supports up to %d", version, CurrentSchemaVersion)
+	}
+
+	overlay := profileDoc.Content[0]
+	if overlay.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("profile must be a mapping")
+	}
+	if len(baseDoc.Content) == 0 {
+		baseDoc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := baseDoc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config must be a mapping")
+	}
+	mergeMapping(root, overlay, true)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&baseDoc); err != nil {
+		return nil, fmt.Errorf("failed to encode merged config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func mergeMapping(base, overlay *yaml.Node, top bool) {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if top && key.Value == "schema_version" {
+			continue
+		}
+
+		index := -1
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			if base.Content[j].Value == key.Value {
+				index = j
+				break
+			}
+		}
+
+		switch {
+		case value.Tag == "!!null":
+			if index >= 0 {
+				base.Content = append(base.Content[:index], base.Content[index+2:]...)
+			}
+		case index < 0:
+			base.Content = append(base.Content, key, value)
+		case value.Kind == yaml.MappingNode && base.Content[index+1].Kind == yaml.MappingNode:
+			mergeMapping(base.Content[index+1], value, false)
+		default:
+			base.Content[index+1] = value
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// ScaffoldAnswers are the choices config init turns into config files
+type ScaffoldAnswers struct {
+	HomelabName     string
+	HomelabNodes    []string
+	Repository      string
+	Branch          string
+	HomelabPath     string
+	StorageProvider string
+	MeshEnabled     bool
+	NASEnabled      bool
+	NASName         string
+	NASHost         string
+	NASPath         string
+	VaultAddress    string
+}
+
+// DefaultScaffoldAnswers returns the answers matching the reference homelab
+func DefaultScaffoldAnswers() ScaffoldAnswers {
+	return ScaffoldAnswers{
+		HomelabName:     "homelab",
+		HomelabNodes:    []string{"192.168.1.67", "192.168.1.68", "192.168.1.69"},
+		Repository:      "https://github.com/fredericrous/homelab",
+		Branch:          "main",
+		HomelabPath:     "kubernetes/homelab",
+		StorageProvider: "ceph",
+		MeshEnabled:     true,
+		NASEnabled:      true,
+		NASName:         "nas",
+		NASHost:         "192.168.1.20",
+		NASPath:         "kubernetes/nas",
+		VaultAddress:    "http://192.168.1.20:61200",
+	}
+}
+
+// Scaffold holds the rendered files, NAS is empty when no NAS cluster was asked for
+type Scaffold struct {
+	Homelab []byte
+	NAS     []byte
+	Env     []byte
+}
+
+// Owner returns the GitHub owner of the repository, empty when it is not a GitHub URL
+func (a ScaffoldAnswers) Owner() string {
+	parsed, err := url.Parse(a.Repository)
+	if err != nil || parsed.Host != "github.com" {
+		return ""
+	}
+	owner, _, _ := strings.Cut(strings.Trim(parsed.Path, "/"), "/")
+	return owner
+}
+
+// Replicas is the storage replica count, one per node for Ceph up to three
+func (a ScaffoldAnswers) Replicas() int {
+	if a.StorageProvider != "ceph" {
+		return 1
+	}
+	return min(len(a.HomelabNodes), 3)
+}
+
+// Render writes the config files and .env template of the answers and validates the configs
+// the way the loader would
+func (a ScaffoldAnswers) Render() (*Scaffold, error) {
+	if len(a.HomelabNodes) == 0 {
+		return nil, fmt.Errorf("at least one homelab node is required")
+	}
+	for _, node := range a.HomelabNodes {
+		if net.ParseIP(node) == nil {
+			return nil, fmt.Errorf("node %q is not an IP address", node)
+		}
+	}
+	if a.NASEnabled && net.ParseIP(a.NASHost) == nil {
+		return nil, fmt.Errorf("NAS host %q is not an IP address", a.NASHost)
+	}
+
+	data := struct {
+		ScaffoldAnswers
+		SchemaVersion int
+		Owner         string
+		Replicas      int
+	}{a, CurrentSchemaVersion, a.Owner(), a.Replicas()}
+	if data.Owner == "" {
+		data.Owner = "owner"
+	}
+
+	scaffold := &Scaffold{}
+	var err error
+	if scaffold.Homelab, err = renderScaffold(homelabScaffold, data); err != nil {
+		return nil, err
+	}
+	if a.NASEnabled {
+		if scaffold.NAS, err = renderScaffold(nasScaffold, data); err != nil {
+			return nil, err
+		}
+	}
+	if scaffold.Env, err = renderScaffold(envScaffold, data); err != nil {
+		return nil, err
+	}
+
+	for name, file := range map[string][]byte{"homelab": scaffold.Homelab, "nas": scaffold.NAS} {
+		if len(file) == 0 {
+			continue
+		}
+		if _, err := ParseAndValidate(file); err != nil {
+			return nil, fmt.Errorf("generated %s config: %w", name, err)
+		}
+	}
+	return scaffold, nil
+}
+
+func renderScaffold(text string, data interface{}) ([]byte, error) {
+	tmpl, err := template.New("scaffold").Funcs(template.FuncMap{"quote": func(s string) string {
+		return fmt.Sprintf("%q", s)
+	}}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+const homelabScaffold = `schema_version: {{ .SchemaVersion }}
+
+# Generated by 'bootstrap config init', see the reference configs/homelab.yaml of the
+# upstream repository for every option (talos, backup, notifications, steps...).
+
+homelab:
+  cluster:
+    name: {{ quote .HomelabName }}
+    nodes:
+{{- range .HomelabNodes }}
+      - {{ quote . }}
+{{- end }}
+    distribution: "talos"
+    cni: "cilium"
+    version: "v1.29.0"
+    kubeconfig: "../infrastructure/homelab/kubeconfig.yaml"
+    networking:
+      pod_cidr: "10.244.0.0/16"
+      service_cidr: "10.96.0.0/12"
+      cluster_dns: "10.96.0.10"
+    timeouts:
+      bootstrap: "10m"
+      infrastructure: "15m"
+      application: "10m"
+      validation: "5m"
+
+  storage:
+    provider: {{ quote .StorageProvider }}
+    replicas: {{ .Replicas }}
+    size: "100Gi"
+
+  gitops:
+    provider: "fluxcd"
+    repository: {{ quote .Repository }}
+    branch: {{ quote .Branch }}
+    path: {{ quote .HomelabPath }}
+    owner: {{ quote .Owner }}
+
+  networking:
+    service_mesh:
+      enabled: {{ .MeshEnabled }}
+      provider: "istio"
+      version: "1.20.0"
+    ingress:
+      provider: "nginx"
+      class: "nginx"
+      tls: true
+    dns:
+      provider: "external-dns"
+      domains:
+        - "homelab.local"
+
+  security:
+    vault:
+      enabled: {{ .NASEnabled }}
+{{- if .NASEnabled }}
+      address: {{ quote .VaultAddress }}
+      transit_path: "transit"
+      pki_path: "pki"
+{{- end }}
+    cert_manager:
+      enabled: true
+    tls:
+      enabled: true
+    rbac:
+      enabled: true
+
+  integration:
+    vault:
+      enabled: {{ .NASEnabled }}
+{{- if .NASEnabled }}
+      address: {{ quote .VaultAddress }}
+      transit_path: "transit"
+{{- end }}
+`
+
+const nasScaffold = `schema_version: {{ .SchemaVersion }}
+
+# Generated by 'bootstrap config init', see the reference configs/nas.yaml of the
+# upstream repository for every option.
+
+nas:
+  cluster:
+    name: {{ quote .NASName }}
+    host: {{ quote .NASHost }}
+    port: 2376
+    docker_host: "tcp://{{ .NASHost }}:2376"
+    cert_path: "../infrastructure/nas/cert"
+    kubeconfig: "../infrastructure/nas/kubeconfig.yaml"
+    timeouts:
+      bootstrap: "5m"
+      infrastructure: "10m"
+      application: "5m"
+      validation: "3m"
+
+  storage:
+    provider: "local-path"
+    minio:
+      enabled: true
+      root_user: "admin"
+      buckets:
+        - "backups"
+
+  gitops:
+    provider: "fluxcd"
+    repository: {{ quote .Repository }}
+    branch: {{ quote .Branch }}
+    path: {{ quote .NASPath }}
+    owner: {{ quote .Owner }}
+
+  security:
+    vault:
+      enabled: true
+      address: {{ quote .VaultAddress }}
+      transit_path: "transit"
+    tls:
+      enabled: false
+    rbac:
+      enabled: true
+
+  integration:
+    vault:
+      enabled: true
+      address: {{ quote .VaultAddress }}
+      transit_path: "transit"
+`
+
+const envScaffold = `# Generated by 'bootstrap config init', fill in the secrets before bootstrapping.
+# Keep this file out of Git, 'bootstrap secrets encrypt' stores it SOPS-encrypted instead.
+
+# GitHub token Flux bootstraps {{ .Repository }} with (repo scope)
+GITHUB_TOKEN=
+
+CONTROL_PLANE_IP={{ index .HomelabNodes 0 }}
+# Public domain the ingress and certificates are served on
+EXTERNAL_DOMAIN=
+
+HOMELAB_KUBECONFIG_PATH=./infrastructure/homelab/kubeconfig.yaml
+{{- if .NASEnabled }}
+NAS_KUBECONFIG_PATH=./infrastructure/nas/kubeconfig.yaml
+NAS_IP={{ .NASHost }}
+
+# NAS Vault, VAULT_TOKEN signs the homelab PKI and mints the transit token
+NAS_VAULT_ADDR={{ .VaultAddress }}
+VAULT_TOKEN=
+# Transit token the homelab Vault auto-unseals with
+VAULT_TRANSIT_TOKEN=
+
+# NAS MinIO credentials used by 'bootstrap backup install'
+MINIO_ACCESS_KEY=
+MINIO_SECRET_KEY=
+{{- end }}
+{{- if .MeshEnabled }}
+
+CACERTS_DIR=./cacerts
+{{- end }}
+`
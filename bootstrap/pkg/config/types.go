@@ -2,8 +2,11 @@ package config
 
 // Config represents the main configuration structure
 type Config struct {
-	Homelab *HomelabConfig `yaml:"homelab,omitempty"`
-	NAS     *NASConfig     `yaml:"nas,omitempty"`
+	SchemaVersion int            `yaml:"schema_version,omitempty"`
+	Homelab       *HomelabConfig `yaml:"homelab,omitempty"`
+	NAS           *NASConfig     `yaml:"nas,omitempty"`
+	// Clusters lists every member of the mesh, homelab and nas are assumed when empty
+	Clusters []MeshCluster `yaml:"clusters,omitempty"`
 }
 
 // HomelabConfig represents homelab-specific configuration
@@ -16,14 +19,84 @@ type HomelabConfig struct {
 	Security       SecurityConfig        `yaml:"security"`
 	Monitoring     MonitoringConfig      `yaml:"monitoring"`
 	Integration    IntegrationConfig     `yaml:"integration"`
+	Channels       ChannelsConfig        `yaml:"channels,omitempty"`
+	Notifications  NotificationsConfig   `yaml:"notifications,omitempty"`
+	Schedules      []ScheduleConfig      `yaml:"schedules,omitempty" validate:"omitempty,dive"`
+	Cilium         CiliumConfig          `yaml:"cilium,omitempty"`
+	Talos          TalosConfig           `yaml:"talos,omitempty"`
+	Proxmox        ProxmoxConfig         `yaml:"proxmox,omitempty"`
+	Backup         BackupConfig          `yaml:"backup,omitempty"`
+	AirGap         AirGapConfig          `yaml:"airgap,omitempty"`
+	Registry       RegistryConfig        `yaml:"registry,omitempty"`
+	LoadBalancer   LoadBalancerConfig    `yaml:"load_balancer,omitempty"`
+	Steps          map[string]StepPolicy `yaml:"steps,omitempty" validate:"omitempty,dive"`
+}
+
+// TalosConfig describes how homelab up configures the Talos machines
+type TalosConfig struct {
+	Version           string `yaml:"version,omitempty"`            // Talos version the machine configs target
+	KubernetesVersion string `yaml:"kubernetes_version,omitempty"` // Defaults to cluster.version
+	Endpoint          string `yaml:"endpoint,omitempty"`           // https://<first control plane>:6443 when empty
+	InstallImage      string `yaml:"install_image,omitempty"`
+	// ConfigDir receives the secrets bundle, talosconfig and machine configs, infrastructure/homelab/talos by default
+	ConfigDir string `yaml:"config_dir,omitempty"`
+	// Patches are applied to every machine config, paths are relative to the project root
+	Patches []string    `yaml:"patches,omitempty"`
+	Nodes   []TalosNode `yaml:"nodes,omitempty"`
+}
+
+// TalosNode is a machine of the cluster, cluster.nodes are used when none are listed
+type TalosNode struct {
+	Address  string   `yaml:"address" validate:"required,ip"`
+	Role     string   `yaml:"role" validate:"required,oneof=controlplane worker"`
+	Hostname string   `yaml:"hostname,omitempty"`
+	MAC      string   `yaml:"mac,omitempty" validate:"omitempty,mac"` // Recorded from the Terraform outputs by homelab up
+	Patches  []string `yaml:"patches,omitempty"`
+}
+
+// ProxmoxConfig gives the CLI the Proxmox VE API hosting the cluster VMs
+type ProxmoxConfig struct {
+	URL         string `yaml:"url,omitempty" validate:"omitempty,url"` // https://pve.lan:8006
+	TokenID     string `yaml:"token_id,omitempty"`                     // Will be fetched from env
+	TokenSecret string `yaml:"token_secret,omitempty"`                 // Will be fetched from env
+	// CACert is a PEM file trusted for the API, which usually serves a self-signed certificate
+	CACert   string `yaml:"ca_cert,omitempty"`
+	Insecure bool   `yaml:"insecure,omitempty"` // Skip verifying the API certificate
+	// VMs are the VM names of the cluster nodes, the hostnames of talos.nodes when empty
+	VMs []string `yaml:"vms,omitempty"`
+	// AutoStart starts stopped VMs before verify-cluster and waits for the Kubernetes API
+	AutoStart bool `yaml:"auto_start,omitempty"`
+}
+
+// Enabled reports whether the Proxmox API is configured
+func (p ProxmoxConfig) Enabled() bool {
+	return p.URL != ""
+}
+
+// ProxmoxVMs returns the names of the cluster VMs on Proxmox
+func (h *HomelabConfig) ProxmoxVMs() []string {
+	if len(h.Proxmox.VMs) > 0 {
+		return h.Proxmox.VMs
+	}
+	var names []string
+	for _, node := range h.Talos.Nodes {
+		if node.Hostname != "" {
+			names = append(names, node.Hostname)
+		}
+	}
+	return names
 }
 
 // InfrastructureConfig represents infrastructure provisioning configuration
 type InfrastructureConfig struct {
+	// TerraformDir is the root module of the VMs, infrastructure/homelab by default
 	TerraformDir string `yaml:"terraform_dir,omitempty"`
 	PodCIDR      string `yaml:"pod_cidr,omitempty"`
 	ServiceCIDR  string `yaml:"service_cidr,omitempty"`
 	Provider     string `yaml:"provider,omitempty"` // proxmox, aws, etc
+
+	// Binary is tofu or terraform, whichever is installed when empty
+	Binary string `yaml:"binary,omitempty" validate:"omitempty,oneof=terraform tofu"`
 }
 
 // NASConfig represents NAS-specific configuration
@@ -33,7 +106,16 @@ type NASConfig struct {
 	Storage        NASStorageConfig         `yaml:"storage"`
 	GitOps         GitOpsConfig             `yaml:"gitops"`
 	Security       SecurityConfig           `yaml:"security"`
+	Monitoring     MonitoringConfig         `yaml:"monitoring,omitempty"`
 	Integration    IntegrationConfig        `yaml:"integration"`
+	Channels       ChannelsConfig           `yaml:"channels,omitempty"`
+	Notifications  NotificationsConfig      `yaml:"notifications,omitempty"`
+	Schedules      []ScheduleConfig         `yaml:"schedules,omitempty" validate:"omitempty,dive"`
+	Backup         BackupConfig             `yaml:"backup,omitempty"`
+	AirGap         AirGapConfig             `yaml:"airgap,omitempty"`
+	Registry       RegistryConfig           `yaml:"registry,omitempty"`
+	LoadBalancer   LoadBalancerConfig       `yaml:"load_balancer,omitempty"`
+	Steps          map[string]StepPolicy    `yaml:"steps,omitempty" validate:"omitempty,dive"`
 }
 
 // NASInfrastructureConfig represents NAS infrastructure configuration
@@ -49,6 +131,7 @@ type ClusterConfig struct {
 	Nodes        []string          `yaml:"nodes" validate:"required,min=1"`
 	CNI          string            `yaml:"cni" validate:"required,oneof=cilium calico flannel"`
 	KubeConfig   string            `yaml:"kubeconfig" validate:"required"`
+	KubeContext  string            `yaml:"context,omitempty"`
 	Distribution string            `yaml:"distribution" validate:"required,oneof=talos k3s"`
 	Version      string            `yaml:"version"`
 	Timeouts     TimeoutConfig     `yaml:"timeouts"`
@@ -57,13 +140,14 @@ type ClusterConfig struct {
 
 // NASClusterConfig represents NAS-specific cluster config
 type NASClusterConfig struct {
-	Name       string        `yaml:"name" validate:"required"`
-	Host       string        `yaml:"host" validate:"required,ip"`
-	Port       int           `yaml:"port" validate:"required,min=1,max=65535"`
-	DockerHost string        `yaml:"docker_host" validate:"required"`
-	CertPath   string        `yaml:"cert_path" validate:"required,dir"`
-	KubeConfig string        `yaml:"kubeconfig" validate:"required"`
-	Timeouts   TimeoutConfig `yaml:"timeouts"`
+	Name        string        `yaml:"name" validate:"required"`
+	Host        string        `yaml:"host" validate:"required,ip"`
+	Port        int           `yaml:"port" validate:"required,min=1,max=65535"`
+	DockerHost  string        `yaml:"docker_host" validate:"required"`
+	CertPath    string        `yaml:"cert_path" validate:"required,dir"`
+	KubeConfig  string        `yaml:"kubeconfig" validate:"required"`
+	KubeContext string        `yaml:"context,omitempty"`
+	Timeouts    TimeoutConfig `yaml:"timeouts"`
 }
 
 // StorageConfig represents storage configuration
@@ -72,12 +156,24 @@ type StorageConfig struct {
 	Replicas int               `yaml:"replicas" validate:"required,min=1"`
 	Size     string            `yaml:"size" validate:"required"`
 	Options  map[string]string `yaml:"options,omitempty"`
+	// SmokeTest provisions a volume and measures it during validate-deployment
+	SmokeTest StorageSmokeTestConfig `yaml:"smoke_test,omitempty"`
 }
 
 // NASStorageConfig represents NAS-specific storage
 type NASStorageConfig struct {
 	Provider string      `yaml:"provider" validate:"required,oneof=ceph local-path none"`
 	MinIO    MinIOConfig `yaml:"minio"`
+	// SmokeTest provisions a volume and measures it during validate-deployment
+	SmokeTest StorageSmokeTestConfig `yaml:"smoke_test,omitempty"`
+}
+
+// StorageSmokeTestConfig checks a volume binds and takes fsynced writes fast enough
+type StorageSmokeTestConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	StorageClass string `yaml:"storage_class,omitempty"`                       // The default class when empty
+	MinIOPS      int    `yaml:"min_iops,omitempty" validate:"omitempty,min=0"` // Synchronous 4k writes per second, only reported when 0
+	Timeout      string `yaml:"timeout,omitempty"`                             // 3m when empty
 }
 
 // MinIOConfig represents MinIO configuration
@@ -97,6 +193,128 @@ type GitOpsConfig struct {
 	Path       string `yaml:"path" validate:"required"`
 	Owner      string `yaml:"owner" validate:"required"`
 	Token      string `yaml:"token,omitempty"` // Will be fetched from env
+	// FluxVersion is the Flux release the bootstrap installs, e.g. v2.7.2, the latest when empty
+	FluxVersion string `yaml:"flux_version,omitempty"`
+	// Auth selects how Flux authenticates to the Git repository: token (default, the token is stored in
+	// flux-system) or ssh (a deploy key is generated and uploaded with the token, which stays local)
+	Auth      string          `yaml:"auth,omitempty" validate:"omitempty,oneof=token ssh"`
+	DeployKey DeployKeyConfig `yaml:"deploy_key,omitempty"`
+	// SourceType selects the Flux source syncing the repository, git (default), oci or bucket
+	SourceType string       `yaml:"source_type,omitempty" validate:"omitempty,oneof=git oci bucket"`
+	OCI        OCIConfig    `yaml:"oci,omitempty"`
+	Bucket     BucketConfig `yaml:"bucket,omitempty"`
+	SOPS       SOPSConfig   `yaml:"sops,omitempty"`
+	// ImageAutomation lets Flux commit new image tags back to the repository
+	ImageAutomation ImageAutomationConfig `yaml:"image_automation,omitempty"`
+}
+
+// GitOps source types
+const (
+	SourceTypeGit    = "git"
+	SourceTypeOCI    = "oci"
+	SourceTypeBucket = "bucket"
+)
+
+// IsOCI reports whether the repository is published as an OCI artifact
+func (g *GitOpsConfig) IsOCI() bool {
+	return g.SourceType == SourceTypeOCI
+}
+
+// UsesDeployKey reports whether Flux pulls the Git repository over SSH with a deploy key
+func (g *GitOpsConfig) UsesDeployKey() bool {
+	return g.Auth == AuthSSH && !g.IsOCI() && !g.IsBucket()
+}
+
+// GitOps authentication modes
+const (
+	AuthToken = "token"
+	AuthSSH   = "ssh"
+)
+
+// DeployKeyConfig describes the deploy key registered on the repository when auth is ssh
+type DeployKeyConfig struct {
+	Title string `yaml:"title,omitempty"` // Defaults to "flux <path>"
+	// Write grants push access, required by image automation
+	Write bool `yaml:"write,omitempty"`
+}
+
+// IsBucket reports whether the manifests are synced from an S3 compatible bucket
+func (g *GitOpsConfig) IsBucket() bool {
+	return g.SourceType == SourceTypeBucket
+}
+
+// BucketConfig selects the S3 compatible bucket Flux syncs when source_type is bucket, such as the NAS MinIO
+// when GitHub is unreachable. The repository stays the Git origin the bucket is mirrored from.
+type BucketConfig struct {
+	Endpoint string `yaml:"endpoint"` // host[:port], without scheme
+	Name     string `yaml:"name"`
+	Region   string `yaml:"region,omitempty"`
+	// Provider authenticates to the bucket: generic (access key secret, default), aws, azure or gcp
+	Provider  string `yaml:"provider,omitempty" validate:"omitempty,oneof=generic aws azure gcp"`
+	Insecure  bool   `yaml:"insecure,omitempty"`   // Plain HTTP, for MinIO on the NAS network
+	AccessKey string `yaml:"access_key,omitempty"` // Will be fetched from env
+	SecretKey string `yaml:"secret_key,omitempty"` // Will be fetched from env
+}
+
+// OCIConfig selects the OCI artifact Flux pulls when source_type is oci, repository being oci://<registry>/<name>
+type OCIConfig struct {
+	Tag    string `yaml:"tag,omitempty"`    // Defaults to latest
+	Semver string `yaml:"semver,omitempty"` // Takes precedence over tag
+	// Provider authenticates to the registry: generic (token pull secret, default), aws, azure or gcp
+	Provider string           `yaml:"provider,omitempty" validate:"omitempty,oneof=generic aws azure gcp"`
+	Verify   *OCIVerifyConfig `yaml:"verify,omitempty"`
+}
+
+// OCIVerifyConfig enables signature verification of the OCI artifact
+type OCIVerifyConfig struct {
+	Provider string `yaml:"provider,omitempty" validate:"omitempty,oneof=cosign notation"` // Defaults to cosign
+	// SecretRef names a secret in flux-system holding the public keys, keyless verification when empty
+	SecretRef string `yaml:"secret_ref,omitempty"`
+	// Issuer and Subject are regular expressions matching the OIDC identity of keyless signatures
+	Issuer  string `yaml:"issuer,omitempty"`
+	Subject string `yaml:"subject,omitempty"`
+}
+
+// SOPSConfig enables SOPS decryption of the manifests applied by the flux-system Kustomization
+type SOPSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AgeKeyFile is the age identity, defaults to $SOPS_AGE_KEY_FILE then ~/.config/sops/age/keys.txt
+	AgeKeyFile string `yaml:"age_key_file,omitempty"`
+	SecretName string `yaml:"secret_name,omitempty"` // Defaults to sops-age
+}
+
+// DefaultSOPSSecretName is the flux-system secret holding the age identity
+const DefaultSOPSSecretName = "sops-age"
+
+// Secret returns the name of the decryption secret
+func (s SOPSConfig) Secret() string {
+	if s.SecretName != "" {
+		return s.SecretName
+	}
+	return DefaultSOPSSecretName
+}
+
+// ImageAutomationConfig lists the images Flux scans and how the updates are committed
+type ImageAutomationConfig struct {
+	Images []ImagePolicyConfig `yaml:"images,omitempty"`
+	// Path is the directory whose image setters are updated, defaults to the gitops path
+	Path string `yaml:"path,omitempty"`
+	// PushBranch receives the commits, defaults to the gitops branch
+	PushBranch  string `yaml:"push_branch,omitempty"`
+	Interval    string `yaml:"interval,omitempty"` // Defaults to 30m
+	AuthorName  string `yaml:"author_name,omitempty"`
+	AuthorEmail string `yaml:"author_email,omitempty"`
+}
+
+// ImagePolicyConfig selects the tags of one image Flux may roll out
+type ImagePolicyConfig struct {
+	Name  string `yaml:"name"`  // Names the ImageRepository and ImagePolicy, referenced by the setter markers
+	Image string `yaml:"image"` // Registry and repository, without tag
+	// Semver is the range of versions allowed, defaults to >=0.0.0 unless Pattern is set
+	Semver string `yaml:"semver,omitempty"`
+	// Pattern filters tags with a regular expression whose named group ts is ordered numerically, e.g. ^main-[a-f0-9]+-(?P<ts>[0-9]+)
+	Pattern   string `yaml:"pattern,omitempty"`
+	SecretRef string `yaml:"secret_ref,omitempty"` // Docker config secret in flux-system for private registries
 }
 
 // NetworkingConfig represents networking configuration
@@ -113,11 +331,79 @@ type ClusterNetworking struct {
 	ClusterDNS  string `yaml:"cluster_dns" validate:"required,ip"`
 }
 
+// CiliumConfig overrides how the bootstrap installs Cilium
+type CiliumConfig struct {
+	Version string `yaml:"version,omitempty"` // Chart version, the tested default when empty
+	// Values is a YAML document deep-merged onto the default Helm values, null removes a default
+	Values string `yaml:"values,omitempty"`
+}
+
+// AirGapConfig makes the bootstrap work without internet access
+type AirGapConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"` // Same as --air-gapped
+	// BundleDir holds the Flux manifests and charts 'bootstrap airgap bundle' downloads, airgap by default
+	BundleDir string `yaml:"bundle_dir,omitempty"`
+}
+
+// RegistryConfig pulls the images the bootstrap installs from a private registry mirror
+type RegistryConfig struct {
+	// Mirror is the registry images are pulled from with their repository path kept, e.g. registry.lan:5000
+	Mirror   string `yaml:"mirror,omitempty"`
+	Insecure bool   `yaml:"insecure,omitempty"` // Mirror served over plain HTTP
+	Username string `yaml:"username,omitempty"` // Will be fetched from env
+	Password string `yaml:"password,omitempty"` // Will be fetched from env
+	// PullSecret names the secret created from the credentials, registry-credentials by default
+	PullSecret string `yaml:"pull_secret,omitempty"`
+	// Namespaces receive the pull secret besides flux-system and kube-system
+	Namespaces []string `yaml:"namespaces,omitempty"`
+	// VerifyImages are checked on the mirror along with the Flux and Cilium images
+	VerifyImages []string `yaml:"verify_images,omitempty"`
+}
+
+// PullSecretName returns the pull secret pods use, empty when the mirror needs no credentials
+func (r RegistryConfig) PullSecretName() string {
+	if r.Mirror == "" || r.Username == "" {
+		return ""
+	}
+	if r.PullSecret == "" {
+		return "registry-credentials"
+	}
+	return r.PullSecret
+}
+
+// LoadBalancerConfig gives LoadBalancer services an address from a LAN pool, announced over L2
+type LoadBalancerConfig struct {
+	// Provider is metallb, or cilium for its LB-IPAM and L2 announcements
+	Provider string `yaml:"provider,omitempty" validate:"omitempty,oneof=metallb cilium"`
+	// Addresses are the CIDRs or first-last IP ranges services get their address from
+	Addresses []string `yaml:"addresses,omitempty"`
+	// Interfaces restrict the L2 announcements to these node interfaces, all of them when empty
+	Interfaces []string `yaml:"interfaces,omitempty"`
+	Version    string   `yaml:"version,omitempty"` // MetalLB chart version, the tested default when empty
+}
+
+// Enabled reports whether the bootstrap sets up the load balancer
+func (l LoadBalancerConfig) Enabled() bool {
+	return l.Provider != "" && len(l.Addresses) > 0
+}
+
 // ServiceMeshConfig represents service mesh configuration
 type ServiceMeshConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	Provider string `yaml:"provider" validate:"oneof=istio linkerd consul"`
 	Version  string `yaml:"version"`
+	Mode     string `yaml:"mode,omitempty" validate:"omitempty,oneof=sidecar ambient"` // Istio data plane, sidecar when empty
+}
+
+// Istio data plane modes
+const (
+	MeshModeSidecar = "sidecar"
+	MeshModeAmbient = "ambient"
+)
+
+// IsAmbient reports whether workloads are captured by ztunnel and waypoints instead of injected sidecars
+func (s ServiceMeshConfig) IsAmbient() bool {
+	return s.Mode == MeshModeAmbient
 }
 
 // IngressConfig represents ingress configuration
@@ -125,6 +411,8 @@ type IngressConfig struct {
 	Provider string `yaml:"provider" validate:"oneof=nginx traefik istio"`
 	Class    string `yaml:"class"`
 	TLS      bool   `yaml:"tls"`
+	// CAFile is a PEM bundle trusted besides the system roots when validating the served certificate
+	CAFile string `yaml:"ca_file,omitempty"`
 }
 
 // DNSConfig represents DNS configuration
@@ -132,15 +420,93 @@ type DNSConfig struct {
 	Provider   string   `yaml:"provider" validate:"oneof=coredns external-dns"`
 	Domains    []string `yaml:"domains"`
 	Nameserver string   `yaml:"nameserver,omitempty"`
+	// TestHostname is resolved inside and outside the cluster and its TLS chain checked at the gateway
+	TestHostname string `yaml:"test_hostname,omitempty"`
 }
 
 // SecurityConfig represents security configuration
 type SecurityConfig struct {
-	TLS         TLSConfig         `yaml:"tls"`
-	RBAC        RBACConfig        `yaml:"rbac"`
-	Policies    bool              `yaml:"policies"`
-	Vault       VaultConfig       `yaml:"vault"`
-	CertManager CertManagerConfig `yaml:"cert_manager"`
+	TLS               TLSConfig               `yaml:"tls"`
+	RBAC              RBACConfig              `yaml:"rbac"`
+	Policies          bool                    `yaml:"policies"`
+	PolicyEngine      PolicyEngineConfig      `yaml:"policy_engine,omitempty"`
+	Falco             FalcoConfig             `yaml:"falco,omitempty"`
+	Benchmark         BenchmarkConfig         `yaml:"benchmark,omitempty"`
+	ImageScan         ImageScanConfig         `yaml:"image_scan,omitempty"`
+	NamespaceBaseline NamespaceBaselineConfig `yaml:"namespace_baseline,omitempty"`
+	Vault             VaultConfig             `yaml:"vault"`
+	CertManager       CertManagerConfig       `yaml:"cert_manager"`
+}
+
+// PolicyEngineConfig represents the admission policy engine and its baseline policies
+type PolicyEngineConfig struct {
+	Engine             string   `yaml:"engine,omitempty" validate:"omitempty,oneof=kyverno gatekeeper"`
+	Mode               string   `yaml:"mode,omitempty" validate:"omitempty,oneof=audit enforce"`
+	ExcludedNamespaces []string `yaml:"excluded_namespaces,omitempty"`
+	// Policies selects baseline policies by name (disallow-privileged, require-requests-limits,
+	// restrict-host-path, restrict-registries), all of them when empty
+	Policies []string `yaml:"policies,omitempty"`
+	// AllowedRegistries are the registry prefixes images must start with, enabling restrict-registries
+	AllowedRegistries []string `yaml:"allowed_registries,omitempty"`
+}
+
+// FalcoConfig represents Falco runtime security configuration
+type FalcoConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Driver   string `yaml:"driver,omitempty" validate:"omitempty,oneof=modern_ebpf ebpf kmod"`
+	Priority string `yaml:"priority,omitempty"`
+	Webhook  string `yaml:"webhook,omitempty"`
+}
+
+// BenchmarkConfig runs the kube-bench CIS benchmark with the security validation
+type BenchmarkConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Profile is the distribution benchmarked, detected from the nodes when empty
+	Profile string `yaml:"profile,omitempty" validate:"omitempty,oneof=talos k3s generic"`
+	Image   string `yaml:"image,omitempty"`
+	Timeout string `yaml:"timeout,omitempty"` // 5m when empty
+}
+
+// ImageScanConfig scans the images of the running pods for vulnerabilities with the security validation
+type ImageScanConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Namespace     string `yaml:"namespace,omitempty"` // All namespaces when empty
+	IgnoreUnfixed bool   `yaml:"ignore_unfixed,omitempty"`
+	// FailOn is the severity at or above which `security scan-images` exits with an error
+	FailOn  string `yaml:"fail_on,omitempty" validate:"omitempty,oneof=critical high medium low"`
+	Timeout string `yaml:"timeout,omitempty"` // Per image, 5m when empty
+}
+
+// NamespaceBaselineConfig declares the labels and default objects every application namespace must carry
+type NamespaceBaselineConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// PodSecurity is the enforced Pod Security Standard, PodSecurityWarn the stricter level only warned about
+	PodSecurity     string            `yaml:"pod_security,omitempty" validate:"omitempty,oneof=privileged baseline restricted"`
+	PodSecurityWarn string            `yaml:"pod_security_warn,omitempty" validate:"omitempty,oneof=privileged baseline restricted"`
+	MeshMode        string            `yaml:"mesh_mode,omitempty" validate:"omitempty,oneof=ambient sidecar none"`
+	Labels          map[string]string `yaml:"labels,omitempty"`
+	// NetworkPolicy is the default ingress policy, AllowFrom the namespaces it always admits
+	NetworkPolicy      string                       `yaml:"network_policy,omitempty" validate:"omitempty,oneof=none deny-ingress allow-same-namespace"`
+	AllowFrom          []string                     `yaml:"allow_from,omitempty"`
+	ResourceQuota      map[string]string            `yaml:"resource_quota,omitempty"`
+	LimitRange         LimitRangeConfig             `yaml:"limit_range,omitempty"`
+	ExcludedNamespaces []string                     `yaml:"excluded_namespaces,omitempty"`
+	Overrides          map[string]NamespaceOverride `yaml:"overrides,omitempty"`
+}
+
+// LimitRangeConfig holds the container defaults and ceilings of the baseline LimitRange
+type LimitRangeConfig struct {
+	Default        map[string]string `yaml:"default,omitempty"`
+	DefaultRequest map[string]string `yaml:"default_request,omitempty"`
+	Max            map[string]string `yaml:"max,omitempty"`
+}
+
+// NamespaceOverride relaxes the baseline for a single namespace
+type NamespaceOverride struct {
+	PodSecurity   string `yaml:"pod_security,omitempty" validate:"omitempty,oneof=privileged baseline restricted"`
+	MeshMode      string `yaml:"mesh_mode,omitempty" validate:"omitempty,oneof=ambient sidecar none"`
+	NetworkPolicy string `yaml:"network_policy,omitempty" validate:"omitempty,oneof=none deny-ingress allow-same-namespace"`
+	SkipQuota     bool   `yaml:"skip_quota,omitempty"`
 }
 
 // TLSConfig represents TLS configuration
@@ -164,6 +530,24 @@ type VaultConfig struct {
 	Token       string `yaml:"token,omitempty"`
 	TransitPath string `yaml:"transit_path" validate:"required_if=Enabled true"`
 	PKIPath     string `yaml:"pki_path,omitempty"`
+	// PKI configures the homelab intermediate CA signed by the root under pki_path of the NAS Vault
+	PKI VaultPKIConfig `yaml:"pki,omitempty"`
+}
+
+// VaultPKIConfig describes the intermediate CA of the homelab Vault and the roles cert-manager issues from
+type VaultPKIConfig struct {
+	Mount      string         `yaml:"mount,omitempty"`                           // Defaults to pki
+	CommonName string         `yaml:"common_name,omitempty"`                     // Defaults to Homelab Intermediate CA
+	TTL        string         `yaml:"ttl,omitempty"`                             // Defaults to 8760h
+	Roles      []VaultPKIRole `yaml:"roles,omitempty" validate:"omitempty,dive"` // Defaults to a cert-manager role for networking.dns.domains
+}
+
+// VaultPKIRole is a PKI role cert-manager signs certificates with
+type VaultPKIRole struct {
+	Name            string   `yaml:"name" validate:"required"`
+	AllowedDomains  []string `yaml:"allowed_domains" validate:"required,min=1"`
+	AllowSubdomains bool     `yaml:"allow_subdomains,omitempty"`
+	MaxTTL          string   `yaml:"max_ttl,omitempty"` // Defaults to 2160h
 }
 
 // CertManagerConfig represents cert-manager configuration
@@ -187,14 +571,18 @@ type MonitoringConfig struct {
 	Prometheus PrometheusConfig `yaml:"prometheus"`
 	Grafana    GrafanaConfig    `yaml:"grafana"`
 	Alerting   AlertingConfig   `yaml:"alerting"`
+	Logging    LoggingConfig    `yaml:"logging,omitempty"`
+	Federation FederationConfig `yaml:"federation,omitempty"`
+	SLOs       []SLOConfig      `yaml:"slos,omitempty"`
 }
 
 // PrometheusConfig represents Prometheus configuration
 type PrometheusConfig struct {
-	Enabled   bool              `yaml:"enabled"`
-	Retention string            `yaml:"retention" validate:"required_if=Enabled true"`
-	Storage   string            `yaml:"storage" validate:"required_if=Enabled true"`
-	Options   map[string]string `yaml:"options,omitempty"`
+	Enabled     bool              `yaml:"enabled"`
+	Retention   string            `yaml:"retention" validate:"required_if=Enabled true"`
+	Storage     string            `yaml:"storage" validate:"required_if=Enabled true"`
+	Pushgateway string            `yaml:"pushgateway,omitempty" validate:"omitempty,url"`
+	Options     map[string]string `yaml:"options,omitempty"`
 }
 
 // GrafanaConfig represents Grafana configuration
@@ -208,17 +596,192 @@ type GrafanaConfig struct {
 
 // AlertingConfig represents alerting configuration
 type AlertingConfig struct {
-	Enabled  bool              `yaml:"enabled"`
-	Webhook  string            `yaml:"webhook,omitempty"`
-	Channels []string          `yaml:"channels,omitempty"`
-	Options  map[string]string `yaml:"options,omitempty"`
+	Enabled   bool              `yaml:"enabled"`
+	Webhook   string            `yaml:"webhook,omitempty"`
+	Receivers []string          `yaml:"receivers,omitempty"`
+	Options   map[string]string `yaml:"options,omitempty"`
+}
+
+// LoggingConfig represents log shipping into the NAS Loki
+type LoggingConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Collector    string `yaml:"collector,omitempty" validate:"omitempty,oneof=alloy fluent-bit"`
+	LokiEndpoint string `yaml:"loki_endpoint,omitempty" validate:"omitempty,url"`
+}
+
+// FederationConfig represents homelab metrics remote-written into the NAS receiver
+type FederationConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Endpoint     string `yaml:"endpoint,omitempty" validate:"omitempty,url"`
+	Username     string `yaml:"username,omitempty"`
+	Retention    string `yaml:"retention,omitempty"`
+	StorageClass string `yaml:"storage_class,omitempty"`
+}
+
+// SLOConfig represents a service level objective evaluated against Prometheus
+type SLOConfig struct {
+	Name             string  `yaml:"name" validate:"required"`
+	Service          string  `yaml:"service,omitempty"`
+	Namespace        string  `yaml:"namespace,omitempty"`
+	Window           string  `yaml:"window,omitempty"`
+	Availability     float64 `yaml:"availability,omitempty" validate:"omitempty,gt=0,lt=100"`
+	LatencyThreshold string  `yaml:"latency_threshold,omitempty"`
+	LatencyTarget    float64 `yaml:"latency_target,omitempty" validate:"omitempty,gt=0,lt=100"`
+	ErrorQuery       string  `yaml:"error_query,omitempty"`
+	TotalQuery       string  `yaml:"total_query,omitempty"`
+	LatencyQuery     string  `yaml:"latency_query,omitempty"`
+}
+
+// ChannelsConfig maps platform components to the release channel the cluster tracks
+type ChannelsConfig struct {
+	// Default is the channel of components without an override (stable when empty)
+	Default    string            `yaml:"default,omitempty" validate:"omitempty,oneof=stable edge"`
+	Components map[string]string `yaml:"components,omitempty"`
+}
+
+// NotificationsConfig lists where bootstrap, destroy and drift events are sent
+type NotificationsConfig struct {
+	Sinks []NotificationSink `yaml:"sinks,omitempty"`
+}
+
+// NotificationsFor returns the notification settings of the homelab or NAS cluster
+func (c *Config) NotificationsFor(isNAS bool) NotificationsConfig {
+	if isNAS && c.NAS != nil {
+		return c.NAS.Notifications
+	}
+	if !isNAS && c.Homelab != nil {
+		return c.Homelab.Notifications
+	}
+	return NotificationsConfig{}
+}
+
+// ScheduleConfig is a recurring operation run by bootstrap schedule run or bootstrap serve --schedule
+type ScheduleConfig struct {
+	Name     string `yaml:"name" validate:"required"`
+	Schedule string `yaml:"schedule" validate:"required"` // Cron expression, @daily or @every 6h
+	Task     string `yaml:"task" validate:"required,oneof=verify security-scan gateway-cert etcd-snapshot health drift"`
+}
+
+// SchedulesFor returns the scheduled operations of the homelab or NAS cluster
+func (c *Config) SchedulesFor(isNAS bool) []ScheduleConfig {
+	if isNAS && c.NAS != nil {
+		return c.NAS.Schedules
+	}
+	if !isNAS && c.Homelab != nil {
+		return c.Homelab.Schedules
+	}
+	return nil
+}
+
+// BackupConfig configures Velero backups into the NAS MinIO
+type BackupConfig struct {
+	ChartVersion string `yaml:"chart_version,omitempty"` // Velero chart, the tested default when empty
+	// S3URL is the MinIO S3 endpoint, the NAS MinIO reached across the mesh by default
+	S3URL     string `yaml:"s3_url,omitempty" validate:"omitempty,url"`
+	Bucket    string `yaml:"bucket,omitempty"`     // Defaults to velero
+	Region    string `yaml:"region,omitempty"`     // Defaults to minio
+	AccessKey string `yaml:"access_key,omitempty"` // Will be fetched from env
+	SecretKey string `yaml:"secret_key,omitempty"` // Will be fetched from env
+	// Schedules are the recurring backups, a daily backup of every namespace when empty
+	Schedules []BackupSchedule `yaml:"schedules,omitempty"`
+	// EtcdRetention is how many etcd snapshots are kept in the bucket, 7 when unset
+	EtcdRetention int `yaml:"etcd_retention,omitempty" validate:"omitempty,min=1"`
+}
+
+// BackupSchedule is a recurring Velero backup
+type BackupSchedule struct {
+	Name       string   `yaml:"name" validate:"required"`
+	Schedule   string   `yaml:"schedule" validate:"required"` // Cron expression
+	TTL        string   `yaml:"ttl,omitempty"`                // How long backups are kept, e.g. 720h
+	Namespaces []string `yaml:"namespaces,omitempty"`         // Every namespace when empty
+}
+
+// BackupFor returns the backup settings of the homelab or NAS cluster
+func (c *Config) BackupFor(isNAS bool) BackupConfig {
+	if isNAS && c.NAS != nil {
+		return c.NAS.Backup
+	}
+	if !isNAS && c.Homelab != nil {
+		return c.Homelab.Backup
+	}
+	return BackupConfig{}
+}
+
+// AirGapFor returns the air-gap settings of the homelab or NAS cluster
+func (c *Config) AirGapFor(isNAS bool) AirGapConfig {
+	if isNAS && c.NAS != nil {
+		return c.NAS.AirGap
+	}
+	if !isNAS && c.Homelab != nil {
+		return c.Homelab.AirGap
+	}
+	return AirGapConfig{}
+}
+
+// RegistryFor returns the registry mirror settings of the homelab or NAS cluster
+func (c *Config) RegistryFor(isNAS bool) RegistryConfig {
+	if isNAS && c.NAS != nil {
+		return c.NAS.Registry
+	}
+	if !isNAS && c.Homelab != nil {
+		return c.Homelab.Registry
+	}
+	return RegistryConfig{}
+}
+
+// LoadBalancerFor returns the load balancer settings of the homelab or NAS cluster
+func (c *Config) LoadBalancerFor(isNAS bool) LoadBalancerConfig {
+	if isNAS && c.NAS != nil {
+		return c.NAS.LoadBalancer
+	}
+	if !isNAS && c.Homelab != nil {
+		return c.Homelab.LoadBalancer
+	}
+	return LoadBalancerConfig{}
+}
+
+// StepPolicy overrides how long a bootstrap step may run and how it is retried, keyed by step name
+type StepPolicy struct {
+	Timeout string `yaml:"timeout,omitempty"`                                   // Per attempt, e.g. 15m
+	Retries *int   `yaml:"retries,omitempty" validate:"omitempty,min=0,max=10"` // Unset keeps the step's own retries, 0 disables them
+	Backoff string `yaml:"backoff,omitempty"`                                   // First retry delay, doubled with jitter on each retry
+}
+
+// StepsFor returns the step policies of the homelab or NAS cluster
+func (c *Config) StepsFor(isNAS bool) map[string]StepPolicy {
+	if isNAS && c.NAS != nil {
+		return c.NAS.Steps
+	}
+	if !isNAS && c.Homelab != nil {
+		return c.Homelab.Steps
+	}
+	return nil
+}
+
+// NotificationSink is a single notification destination
+type NotificationSink struct {
+	Type string `yaml:"type" validate:"required,oneof=slack discord webhook ntfy"`
+	// URL is the webhook or ntfy topic URL, ${VAR} references are expanded from the environment
+	URL   string `yaml:"url" validate:"required"`
+	Token string `yaml:"token,omitempty"` // Bearer token for webhook and ntfy sinks
+	// Events limits the sink to these event types, failures and completions when empty
+	Events []string `yaml:"events,omitempty"`
 }
 
 // IntegrationConfig represents external integration configuration
 type IntegrationConfig struct {
-	Vault VaultConfig `yaml:"vault"`
-	AWS   AWSConfig   `yaml:"aws"`
-	OVH   OVHConfig   `yaml:"ovh"`
+	Vault           VaultConfig           `yaml:"vault"`
+	AWS             AWSConfig             `yaml:"aws"`
+	OVH             OVHConfig             `yaml:"ovh"`
+	ExternalSecrets ExternalSecretsConfig `yaml:"external_secrets,omitempty"`
+}
+
+// ExternalSecretsConfig wires the External Secrets Operator to the Vault configured under integration.vault
+type ExternalSecretsConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	ChartVersion string `yaml:"chart_version,omitempty"` // Only used when Flux has not installed ESO yet
+	StoreName    string `yaml:"store_name,omitempty"`    // Defaults to nas-vault
+	KVMount      string `yaml:"kv_mount,omitempty"`      // KV v2 mount, defaults to secret
 }
 
 // AWSConfig represents AWS integration configuration
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetVersion pins version at the key path under the cluster section of a config document, e.g.
+// cilium.version. Missing mappings are added at the end of their parent. Only the pinned line changes,
+// the rest of the document and its comments are kept, and the document is returned unchanged when it
+// already pins version.
+func SetVersion(data []byte, cluster string, path []string, version string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("no key to pin %s at", version)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config is not a YAML mapping")
+	}
+	parent := lookupPath(doc.Content[0], cluster)
+	if parent == nil {
+		return nil, fmt.Errorf("config has no %s section", cluster)
+	}
+
+	for i, key := range path[:len(path)-1] {
+		child := lookupPath(parent, key)
+		if child != nil {
+			parent = child
+			continue
+		}
+		if mappingKey(parent, key) != nil {
+			return nil, fmt.Errorf("%s.%s is not a mapping", cluster, strings.Join(path[:i+1], "."))
+		}
+		if len(parent.Content) == 0 {
+			return nil, fmt.Errorf("cannot add %s to an empty mapping", key)
+		}
+		// Add the missing mappings down to the pinned key in one block
+		value := quotedScalar(version)
+		for j := len(path) - 1; j > i; j-- {
+			value = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: path[j]}, value}}
+		}
+		edit, err := insertKey(parent, key, value, endLine(data, parent))
+		if err != nil {
+			return nil, err
+		}
+		return applySpanEdits(data, []spanEdit{edit}), nil
+	}
+
+	key := path[len(path)-1]
+	keyNode := mappingKey(parent, key)
+	if keyNode == nil {
+		if len(parent.Content) == 0 {
+			return nil, fmt.Errorf("cannot add %s to an empty mapping", key)
+		}
+		indent := strings.Repeat(" ", parent.Content[0].Column-1)
+		after := endLine(data, parent)
+		return applySpanEdits(data, []spanEdit{{start: after + 1, end: after, text: indent + key + ": " + quote(version) + "\n"}}), nil
+	}
+	value := mappingValue(parent, key)
+	if value.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("%s.%s is not a scalar", cluster, strings.Join(path, "."))
+	}
+	if value.Value == version {
+		return data, nil
+	}
+	text := strings.Repeat(" ", keyNode.Column-1) + key + ": " + quote(version)
+	if value.LineComment != "" {
+		text += " " + value.LineComment
+	}
+	return applySpanEdits(data, []spanEdit{{start: keyNode.Line, end: value.Line, text: text + "\n"}}), nil
+}
+
+// endLine returns the last line of mapping in data, past the block scalars lastLine stops at the start of.
+// Trailing blank lines and the comments indented like the keys of mapping are left out.
+func endLine(data []byte, mapping *yaml.Node) int {
+	lines := strings.Split(string(data), "\n")
+	keyIndent := mapping.Content[0].Column - 1
+	end := lastLine(mapping)
+	for next := end; next < len(lines); next++ {
+		line := lines[next]
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+		if len(line)-len(trimmed) <= keyIndent {
+			break
+		}
+		end = next + 1
+	}
+	return end
+}
+
+func quote(value string) string {
+	return `"` + value + `"`
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultReloadDebounce coalesces bursts of writes (editors, atomic renames) into one reload
+const defaultReloadDebounce = 500 * time.Millisecond
+
+// ReloadFunc receives the freshly loaded configuration after a change on disk
+type ReloadFunc func(cfg *Config)
+
+// Watcher reloads configuration when the config file or .env files change
+type Watcher struct {
+	loader     *Loader
+	configType string
+	files      map[string]struct{}
+	debounce   time.Duration
+
+	mu       sync.Mutex
+	current  *Config
+	handlers []ReloadFunc
+}
+
+// NewWatcher creates a new watcher for the given config type (homelab or nas)
+func NewWatcher(configType string) *Watcher {
+	loader := NewLoader()
+	w := &Watcher{
+		loader:     loader,
+		configType: configType,
+		files:      map[string]struct{}{},
+		debounce:   defaultReloadDebounce,
+	}
+
+	for _, dir := range loader.configDirs {
+		for _, ext := range []string{".yaml", ".yml"} {
+			w.addFile(filepath.Join(dir, configType+ext))
+			if loader.profile != "" {
+				w.addFile(filepath.Join(dir, configType+"."+loader.profile+ext))
+			}
+		}
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		if projectRoot := findProjectRoot(wd); projectRoot != "" {
+			w.addFile(filepath.Join(projectRoot, ".env"))
+			w.addFile(filepath.Join(projectRoot, ".env.generated"))
+		}
+	}
+
+	return w
+}
+
+func (w *Watcher) addFile(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		w.files[abs] = struct{}{}
+	}
+}
+
+// OnReload registers a handler invoked with every successfully reloaded configuration
+func (w *Watcher) OnReload(fn ReloadFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, fn)
+}
+
+// Current returns the last successfully loaded configuration
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Run watches the configuration files until the context is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer fsWatcher.Close()
+
+	// Watch parent directories so atomic saves (write + rename) are seen
+	dirs := map[string]struct{}{}
+	for file := range w.files {
+		dir := filepath.Dir(file)
+		if _, ok := dirs[dir]; ok {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if err := fsWatcher.Add(dir); err != nil {
+			log.Debug("Failed to watch config directory", "dir", dir, "error", err)
+			continue
+		}
+		dirs[dir] = struct{}{}
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no configuration directories to watch")
+	}
+
+	log.Debug("Watching configuration for changes", "type", w.configType, "dirs", len(dirs))
+
+	var timer *time.Timer
+	var pending <-chan time.Time
+	changed := ""
+
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		case event, ok := <-fsWatcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			path, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			if _, ok := w.files[path]; !ok {
+				continue
+			}
+			changed = path
+			if timer == nil {
+				timer = time.NewTimer(w.debounce)
+			} else {
+				timer.Reset(w.debounce)
+			}
+			pending = timer.C
+		case err, ok := <-fsWatcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Warn("Configuration watcher error", "error", err)
+		case <-pending:
+			pending = nil
+			w.reload(changed)
+		}
+	}
+}
+
+func (w *Watcher) reload(changed string) {
+	cfg, err := w.loader.LoadConfig(w.configType)
+	if err != nil {
+		log.Warn("Failed to reload configuration, keeping previous values", "file", changed, "error", err)
+		return
+	}
+
+	w.mu.Lock()
+	w.current = cfg
+	handlers := append([]ReloadFunc(nil), w.handlers...)
+	w.mu.Unlock()
+
+	log.Info("🔄 Configuration reloaded", "file", changed)
+	for _, handler := range handlers {
+		handler(cfg)
+	}
+}
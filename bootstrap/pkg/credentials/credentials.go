@@ -0,0 +1,288 @@
+package credentials
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// States of a credential relative to the warning window
+const (
+	StateValid    = "valid"
+	StateExpiring = "expiring"
+	StateExpired  = "expired"
+	StateNoExpiry = "no-expiry"
+	StateUnknown  = "unknown"
+)
+
+var certificateGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+// Credential is a token or certificate with its expiry
+type Credential struct {
+	Name      string
+	Kind      string
+	Cluster   string
+	ExpiresAt time.Time
+	// NoExpiry marks credentials verified to never expire
+	NoExpiry bool
+	Error    string
+	Hint     string
+	// Scopes are the OAuth scopes of a classic GitHub token, nil when the token does not report them
+	Scopes []string
+}
+
+// Remaining returns the time left before the credential expires
+func (c Credential) Remaining() time.Duration {
+	if c.ExpiresAt.IsZero() {
+		return 0
+	}
+	return time.Until(c.ExpiresAt)
+}
+
+// State classifies the credential, expiring once less than warn remains
+func (c Credential) State(warn time.Duration) string {
+	switch {
+	case c.Error != "":
+		return StateUnknown
+	case c.NoExpiry:
+		return StateNoExpiry
+	case c.ExpiresAt.IsZero():
+		return StateUnknown
+	case c.Remaining() <= 0:
+		return StateExpired
+	case c.Remaining() < warn:
+		return StateExpiring
+	default:
+		return StateValid
+	}
+}
+
+// Sort orders credentials by expiry, soonest first and unknown last
+func Sort(credentials []Credential) {
+	sort.SliceStable(credentials, func(i, j int) bool {
+		a, b := credentials[i], credentials[j]
+		if a.ExpiresAt.IsZero() != b.ExpiresAt.IsZero() {
+			return !a.ExpiresAt.IsZero()
+		}
+		return a.ExpiresAt.Before(b.ExpiresAt)
+	})
+}
+
+// GitHubToken validates token against the GitHub API and reads its expiration header
+func GitHubToken(ctx context.Context, token string) Credential {
+	credential := Credential{
+		Name: "GITHUB_TOKEN",
+		Kind: "github-token",
+		Hint: "create a new fine-grained token and update GITHUB_TOKEN, then re-run the Flux bootstrap",
+	}
+	if token == "" {
+		credential.Error = "GITHUB_TOKEN not set"
+		return credential
+	}
+
+	if err := airgap.GuardNetwork("validate GITHUB_TOKEN"); err != nil {
+		credential.Error = err.Error()
+		return credential
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user", nil)
+	if err != nil {
+		credential.Error = err.Error()
+		return credential
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		credential.Error = fmt.Sprintf("GitHub API not reachable: %v", err)
+		return credential
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		credential.ExpiresAt = time.Now()
+		credential.Error = "token rejected by GitHub (expired or revoked)"
+		return credential
+	}
+	if resp.StatusCode >= 300 {
+		credential.Error = fmt.Sprintf("GitHub API returned %s", resp.Status)
+		return credential
+	}
+
+	if header := resp.Header.Get("X-OAuth-Scopes"); header != "" {
+		for _, scope := range strings.Split(header, ",") {
+			credential.Scopes = append(credential.Scopes, strings.TrimSpace(scope))
+		}
+	}
+
+	expiration := resp.Header.Get("GitHub-Authentication-Token-Expiration")
+	if expiration == "" {
+		credential.NoExpiry = true
+		return credential
+	}
+	expiresAt, err := time.Parse("2006-01-02 15:04:05 MST", expiration)
+	if err != nil {
+		credential.Error = fmt.Sprintf("unparsable expiration %q", expiration)
+		return credential
+	}
+	credential.ExpiresAt = expiresAt
+	return credential
+}
+
+// VaultToken looks the token up against the Vault at address to read its TTL
+func VaultToken(ctx context.Context, name, address, token, hint string) Credential {
+	credential := Credential{Name: name, Kind: "vault-token", Hint: hint}
+	if address == "" || token == "" {
+		credential.Error = "Vault address or token not available"
+		return credential
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(address, "/")+"/v1/auth/token/lookup-self", nil)
+	if err != nil {
+		credential.Error = err.Error()
+		return credential
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		credential.Error = fmt.Sprintf("Vault not reachable: %v", err)
+		return credential
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden {
+		credential.ExpiresAt = time.Now()
+		credential.Error = "token rejected by Vault (expired or revoked)"
+		return credential
+	}
+	if resp.StatusCode >= 300 {
+		credential.Error = fmt.Sprintf("Vault returned %s", resp.Status)
+		return credential
+	}
+
+	var lookup struct {
+		Data struct {
+			TTL        int64  `json:"ttl"`
+			ExpireTime string `json:"expire_time"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&lookup); err != nil {
+		credential.Error = fmt.Sprintf("failed to decode token lookup: %v", err)
+		return credential
+	}
+	if lookup.Data.TTL == 0 && lookup.Data.ExpireTime == "" {
+		credential.NoExpiry = true
+		return credential
+	}
+	credential.ExpiresAt = time.Now().Add(time.Duration(lookup.Data.TTL) * time.Second)
+	return credential
+}
+
+// KubeconfigCertificates returns the client certificates embedded in or referenced by a kubeconfig
+func KubeconfigCertificates(path, cluster, hint string) []Credential {
+	kubeconfig, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return []Credential{{Name: path, Kind: "kubeconfig-cert", Cluster: cluster, Error: err.Error(), Hint: hint}}
+	}
+
+	var credentials []Credential
+	for name, auth := range kubeconfig.AuthInfos {
+		data := auth.ClientCertificateData
+		if len(data) == 0 && auth.ClientCertificate != "" {
+			if data, err = os.ReadFile(auth.ClientCertificate); err != nil {
+				credentials = append(credentials, Credential{Name: name, Kind: "kubeconfig-cert", Cluster: cluster, Error: err.Error(), Hint: hint})
+				continue
+			}
+		}
+		if len(data) == 0 {
+			// Token or exec based users have no certificate to expire
+			continue
+		}
+		credential := Certificate(data, name, "kubeconfig-cert", hint)
+		credential.Cluster = cluster
+		credentials = append(credentials, credential)
+	}
+	return credentials
+}
+
+// Certificate reads the expiry of the first certificate of a PEM bundle
+func Certificate(data []byte, name, kind, hint string) Credential {
+	credential := Credential{Name: name, Kind: kind, Hint: hint}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		credential.Error = "no PEM certificate found"
+		return credential
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		credential.Error = fmt.Sprintf("invalid certificate: %v", err)
+		return credential
+	}
+	credential.ExpiresAt = cert.NotAfter
+	return credential
+}
+
+// SecretCertificate reads the expiry of the certificate stored under key in a secret
+func SecretCertificate(ctx context.Context, client *k8s.Client, cluster, namespace, name, key, kind, hint string) Credential {
+	secret, err := client.GetSecret(ctx, namespace, name)
+	if err != nil {
+		message := err.Error()
+		if apierrors.IsNotFound(err) {
+			message = "secret not found"
+		}
+		return Credential{Name: namespace + "/" + name, Kind: kind, Cluster: cluster, Error: message, Hint: hint}
+	}
+	credential := Certificate(secret.Data[key], namespace+"/"+name, kind, hint)
+	credential.Cluster = cluster
+	return credential
+}
+
+// CertManagerCertificates returns the expiry of every cert-manager Certificate of the cluster
+func CertManagerCertificates(ctx context.Context, client *k8s.Client, cluster string) ([]Credential, error) {
+	list, err := client.GetDynamicClient().Resource(certificateGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list certificates on %s: %w", cluster, err)
+	}
+
+	credentials := make([]Credential, 0, len(list.Items))
+	for _, item := range list.Items {
+		issuer, _, _ := unstructured.NestedString(item.Object, "spec", "issuerRef", "name")
+		credential := Credential{
+			Name:    item.GetNamespace() + "/" + item.GetName(),
+			Kind:    "certificate",
+			Cluster: cluster,
+			Hint:    fmt.Sprintf("cert-manager renews it through issuer %s; check 'kubectl describe certificate -n %s %s' if it does not", issuer, item.GetNamespace(), item.GetName()),
+		}
+		if notAfter, _, _ := unstructured.NestedString(item.Object, "status", "notAfter"); notAfter != "" {
+			if parsed, err := time.Parse(time.RFC3339, notAfter); err == nil {
+				credential.ExpiresAt = parsed
+			}
+		}
+		if credential.ExpiresAt.IsZero() {
+			credential.Error = "not issued yet"
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
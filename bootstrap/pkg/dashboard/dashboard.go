@@ -0,0 +1,377 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/certmanager"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	fluxNamespace  = "flux-system"
+	istioNamespace = "istio-system"
+	cephNamespace  = "rook-ceph"
+	// maxEvents is how many of the most recent Flux events a snapshot keeps
+	maxEvents = 8
+	// settleDelay batches bursts of watch events into a single refresh
+	settleDelay = 300 * time.Millisecond
+)
+
+var (
+	kustomizationGVR = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	helmReleaseGVR   = schema.GroupVersionResource{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"}
+	cephClusterGVR   = schema.GroupVersionResource{Group: "ceph.rook.io", Version: "v1", Resource: "cephclusters"}
+)
+
+// Node is the readiness of a cluster node
+type Node struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// FluxObject is the Ready state of a Kustomization or HelmRelease
+type FluxObject struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Ready     bool   `json:"ready"`
+	Suspended bool   `json:"suspended,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Gateway is an address published by an Istio gateway service
+type Gateway struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// Event is a Flux event from the flux-system namespace
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Object  string    `json:"object"`
+	Message string    `json:"message"`
+}
+
+// ClusterState is a snapshot of what the dashboard shows for one cluster
+type ClusterState struct {
+	Cluster        string       `json:"cluster"`
+	Nodes          []Node       `json:"nodes"`
+	Kustomizations []FluxObject `json:"kustomizations"`
+	HelmReleases   []FluxObject `json:"helmReleases"`
+	Gateways       []Gateway    `json:"gateways"`
+	CephHealth     string       `json:"cephHealth,omitempty"`
+	// ExpiringCertificates are cert-manager Certificates not ready or expiring soon
+	ExpiringCertificates []certmanager.Certificate `json:"expiringCertificates,omitempty"`
+	Events               []Event                   `json:"events"`
+	Error                string                    `json:"error,omitempty"`
+	Updated              time.Time                 `json:"updated"`
+}
+
+// Watcher keeps informer caches of one cluster and publishes a snapshot whenever they change
+type Watcher struct {
+	cluster string
+	client  *k8s.Client
+
+	nodes    cache.GenericLister
+	services cache.GenericLister
+	events   cache.GenericLister
+	flux     map[schema.GroupVersionResource]cache.GenericLister
+
+	synced  []cache.InformerSynced
+	changed chan struct{}
+	once    sync.Once
+}
+
+// NewWatcher creates a watcher for the named cluster
+func NewWatcher(cluster string, client *k8s.Client) *Watcher {
+	return &Watcher{
+		cluster: cluster,
+		client:  client,
+		flux:    map[schema.GroupVersionResource]cache.GenericLister{},
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Cluster returns the name of the watched cluster
+func (w *Watcher) Cluster() string {
+	return w.cluster
+}
+
+// Run starts the informers and sends a snapshot on updates after every change, until ctx is done
+func (w *Watcher) Run(ctx context.Context, updates chan<- ClusterState) {
+	if err := w.start(ctx); err != nil {
+		w.publish(ctx, updates, ClusterState{Cluster: w.cluster, Error: err.Error(), Updated: time.Now()})
+		return
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), w.synced...) {
+		return
+	}
+	w.publish(ctx, updates, w.Snapshot())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.changed:
+		}
+		// Let the rest of a burst land before rebuilding the snapshot
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(settleDelay):
+		}
+		select {
+		case <-w.changed:
+		default:
+		}
+		w.publish(ctx, updates, w.Snapshot())
+	}
+}
+
+// Collect takes a single snapshot once the informer caches have synced
+func (w *Watcher) Collect(ctx context.Context) (ClusterState, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := w.start(ctx); err != nil {
+		return ClusterState{}, err
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), w.synced...) {
+		return ClusterState{}, fmt.Errorf("%s: informer caches did not sync: %w", w.cluster, ctx.Err())
+	}
+	return w.Snapshot(), nil
+}
+
+func (w *Watcher) publish(ctx context.Context, updates chan<- ClusterState, state ClusterState) {
+	select {
+	case updates <- state:
+	case <-ctx.Done():
+	}
+}
+
+// start registers the informers, custom resources are only watched when their API is served
+func (w *Watcher) start(ctx context.Context) error {
+	var err error
+	w.once.Do(func() {
+		if err = w.client.IsReady(ctx); err != nil {
+			return
+		}
+		clientset := w.client.GetClientset()
+		notify := cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { w.notify() },
+			UpdateFunc: func(interface{}, interface{}) { w.notify() },
+			DeleteFunc: func(interface{}) { w.notify() },
+		}
+
+		core := informers.NewSharedInformerFactory(clientset, 0)
+		istio := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(istioNamespace))
+		flux := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(fluxNamespace))
+		w.nodes = w.register(core.Core().V1().Nodes().Informer(), corev1.Resource("nodes"), notify)
+		w.services = w.register(istio.Core().V1().Services().Informer(), corev1.Resource("services"), notify)
+		w.events = w.register(flux.Core().V1().Events().Informer(), corev1.Resource("events"), notify)
+
+		dynamicFactory := dynamicinformer.NewDynamicSharedInformerFactory(w.client.GetDynamicClient(), 0)
+		for _, gvr := range []schema.GroupVersionResource{kustomizationGVR, helmReleaseGVR, cephClusterGVR, certmanager.CertificateGVR} {
+			if !w.served(gvr) {
+				log.Debug("Resource not served, skipping watch", "cluster", w.cluster, "resource", gvr.Resource)
+				continue
+			}
+			w.flux[gvr] = w.register(dynamicFactory.ForResource(gvr).Informer(), gvr.GroupResource(), notify)
+		}
+
+		for _, factory := range []interface{ Start(<-chan struct{}) }{core, istio, flux, dynamicFactory} {
+			factory.Start(ctx.Done())
+		}
+	})
+	return err
+}
+
+func (w *Watcher) register(informer cache.SharedIndexInformer, resource schema.GroupResource, handler cache.ResourceEventHandler) cache.GenericLister {
+	if _, err := informer.AddEventHandler(handler); err != nil {
+		log.Debug("Failed to add event handler", "cluster", w.cluster, "resource", resource, "error", err)
+	}
+	w.synced = append(w.synced, informer.HasSynced)
+	return cache.NewGenericLister(informer.GetIndexer(), resource)
+}
+
+func (w *Watcher) served(gvr schema.GroupVersionResource) bool {
+	resources, err := w.client.GetClientset().Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == gvr.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Snapshot builds the current state from the informer caches
+func (w *Watcher) Snapshot() ClusterState {
+	state := ClusterState{Cluster: w.cluster, Updated: time.Now()}
+
+	if objects, err := w.nodes.List(labels.Everything()); err == nil {
+		for _, obj := range objects {
+			if node, ok := obj.(*corev1.Node); ok {
+				state.Nodes = append(state.Nodes, Node{Name: node.Name, Ready: nodeReady(node)})
+			}
+		}
+		sort.Slice(state.Nodes, func(i, j int) bool { return state.Nodes[i].Name < state.Nodes[j].Name })
+	}
+
+	state.Kustomizations = w.fluxObjects(kustomizationGVR)
+	state.HelmReleases = w.fluxObjects(helmReleaseGVR)
+
+	if objects, err := w.services.List(labels.Everything()); err == nil {
+		for _, obj := range objects {
+			if svc, ok := obj.(*corev1.Service); ok && svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+				state.Gateways = append(state.Gateways, Gateway{Name: svc.Name, Address: gatewayAddress(svc)})
+			}
+		}
+		sort.Slice(state.Gateways, func(i, j int) bool { return state.Gateways[i].Name < state.Gateways[j].Name })
+	}
+
+	if lister, ok := w.flux[cephClusterGVR]; ok {
+		if obj, err := lister.ByNamespace(cephNamespace).Get(cephNamespace); err == nil {
+			if cluster, ok := obj.(*unstructured.Unstructured); ok {
+				state.CephHealth, _, _ = unstructured.NestedString(cluster.Object, "status", "ceph", "health")
+			}
+		}
+		if state.CephHealth == "" {
+			state.CephHealth = "unknown"
+		}
+	}
+
+	if lister, ok := w.flux[certmanager.CertificateGVR]; ok {
+		if objects, err := lister.List(labels.Everything()); err == nil {
+			certificates := make([]certmanager.Certificate, 0, len(objects))
+			for _, obj := range objects {
+				if item, ok := obj.(*unstructured.Unstructured); ok {
+					certificates = append(certificates, certmanager.CertificateFrom(item))
+				}
+			}
+			state.ExpiringCertificates = certmanager.Expiring(certificates, certmanager.DefaultExpiryWindow)
+		}
+	}
+
+	if objects, err := w.events.List(labels.Everything()); err == nil {
+		for _, obj := range objects {
+			if event, ok := obj.(*corev1.Event); ok {
+				state.Events = append(state.Events, Event{
+					Time:    eventTime(event),
+					Type:    event.Type,
+					Object:  event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name,
+					Message: event.Message,
+				})
+			}
+		}
+		sort.Slice(state.Events, func(i, j int) bool { return state.Events[i].Time.After(state.Events[j].Time) })
+		if len(state.Events) > maxEvents {
+			state.Events = state.Events[:maxEvents]
+		}
+	}
+
+	return state
+}
+
+func (w *Watcher) fluxObjects(gvr schema.GroupVersionResource) []FluxObject {
+	lister, ok := w.flux[gvr]
+	if !ok {
+		return nil
+	}
+	objects, err := lister.List(labels.Everything())
+	if err != nil {
+		return nil
+	}
+
+	result := make([]FluxObject, 0, len(objects))
+	for _, obj := range objects {
+		item, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		object := FluxObject{Namespace: item.GetNamespace(), Name: item.GetName()}
+		object.Suspended, _, _ = unstructured.NestedBool(item.Object, "spec", "suspend")
+		conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+		for _, raw := range conditions {
+			condition, ok := raw.(map[string]interface{})
+			if !ok || condition["type"] != "Ready" {
+				continue
+			}
+			object.Ready = condition["status"] == string(metav1.ConditionTrue)
+			object.Reason, _ = condition["reason"].(string)
+			object.Message, _ = condition["message"].(string)
+		}
+		result = append(result, object)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// gatewayAddress returns the load balancer address of svc, or its node ports while none is assigned
+func gatewayAddress(svc *corev1.Service) string {
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		host := ingress.IP
+		if host == "" {
+			host = ingress.Hostname
+		}
+		if host != "" && len(svc.Spec.Ports) > 0 {
+			return fmt.Sprintf("%s:%d", host, svc.Spec.Ports[0].Port)
+		}
+		if host != "" {
+			return host
+		}
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.NodePort != 0 {
+			return fmt.Sprintf("<pending> (nodePort %d)", port.NodePort)
+		}
+	}
+	return "<pending>"
+}
+
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
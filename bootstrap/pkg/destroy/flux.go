@@ -16,10 +16,17 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
 // FluxDestroyer handles FluxCD resource cleanup
 type FluxDestroyer struct {
 	client        kubernetes.Interface
 	dynamicClient dynamic.Interface
+	keepCRDs      bool
 }
 
 // NewFluxDestroyer creates a new FluxDestroyer
@@ -30,6 +37,11 @@ func NewFluxDestroyer(client kubernetes.Interface, dynamicClient dynamic.Interfa
 	}
 }
 
+// SetKeepCRDs leaves the CustomResourceDefinitions in place when destroying
+func (fd *FluxDestroyer) SetKeepCRDs(keep bool) {
+	fd.keepCRDs = keep
+}
+
 // Destroy performs complete FluxCD cleanup
 func (fd *FluxDestroyer) Destroy(ctx context.Context, namespace string) error {
 	log.Info("🗑️ Starting FluxCD destruction process", "namespace", namespace)
@@ -71,7 +83,9 @@ func (fd *FluxDestroyer) Destroy(ctx context.Context, namespace string) error {
 	}
 
 	// Step 6: Clean up CRDs
-	if err := fd.cleanupCRDs(ctx); err != nil {
+	if fd.keepCRDs {
+		log.Info("Keeping CRDs")
+	} else if err := fd.cleanupCRDs(ctx); err != nil {
 		log.Warn("Failed to cleanup CRDs", "error", err)
 		// Continue anyway
 	}
@@ -312,8 +326,6 @@ func (fd *FluxDestroyer) cleanupNamespaces(ctx context.Context) error {
 		return fmt.Errorf("failed to list namespaces: %w", err)
 	}
 
-	systemNamespaces := []string{"kube-system", "kube-public", "kube-node-lease", "default"}
-
 	for _, ns := range namespaces.Items {
 		nsName := ns.Name
 
@@ -435,39 +447,16 @@ func (fd *FluxDestroyer) cleanupPersistentVolumes(ctx context.Context) error {
 func (fd *FluxDestroyer) cleanupCRDs(ctx context.Context) error {
 	log.Info("🗑️ Cleaning up CRDs")
 
-	crdGVR := schema.GroupVersionResource{
-		Group:    "apiextensions.k8s.io",
-		Version:  "v1",
-		Resource: "customresourcedefinitions",
-	}
-
 	crds, err := fd.dynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to list CRDs: %w", err)
 	}
 
-	// Core Kubernetes CRDs to preserve
-	corePatterns := []string{
-		"k8s.io",
-		"kubernetes.io",
-		"metrics.k8s.io",
-		"apiregistration.k8s.io",
-		"admissionregistration.k8s.io",
-	}
-
 	for _, crd := range crds.Items {
 		crdName := crd.GetName()
 
 		// Skip core Kubernetes CRDs
-		isCore := false
-		for _, pattern := range corePatterns {
-			if strings.Contains(crdName, pattern) {
-				isCore = true
-				break
-			}
-		}
-
-		if isCore {
+		if isCoreCRD(crdName) {
 			continue
 		}
 
@@ -481,6 +470,23 @@ func (fd *FluxDestroyer) cleanupCRDs(ctx context.Context) error {
 	return nil
 }
 
+// isCoreCRD reports whether a CRD belongs to Kubernetes itself and survives a destroy
+func isCoreCRD(name string) bool {
+	corePatterns := []string{
+		"k8s.io",
+		"kubernetes.io",
+		"metrics.k8s.io",
+		"apiregistration.k8s.io",
+		"admissionregistration.k8s.io",
+	}
+	for _, pattern := range corePatterns {
+		if strings.Contains(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
 func (fd *FluxDestroyer) forceCleanupFluxNamespace(ctx context.Context, namespace string) error {
 	log.Info("🗑️ Final cleanup of flux-system namespace", "namespace", namespace)
 
@@ -3,10 +3,15 @@ package destroy
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/notify"
+	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
+	"github.com/fredericrous/homelab/bootstrap/pkg/snapshot"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -17,25 +22,31 @@ type Manager struct {
 	client        *k8s.Client
 	fluxDestroyer *FluxDestroyer
 	nsCleanup     *NamespaceCleanup
+	notifier      *notify.Notifier
+	snapshotDir   string
+	snapshotKey   *secrets.AgeKey
+	options       Options
 }
 
 // NewManager creates a new destroy manager
 func NewManager(cfg *config.Config, isNAS bool) (*Manager, error) {
-	var kubeconfig string
+	var kubeconfig, kubeContext string
 	if isNAS {
 		if cfg.NAS == nil {
 			return nil, fmt.Errorf("NAS configuration not found")
 		}
 		kubeconfig = cfg.NAS.Cluster.KubeConfig
+		kubeContext = cfg.NAS.Cluster.KubeContext
 	} else {
 		if cfg.Homelab == nil {
 			return nil, fmt.Errorf("homelab configuration not found")
 		}
 		kubeconfig = cfg.Homelab.Cluster.KubeConfig
+		kubeContext = cfg.Homelab.Cluster.KubeContext
 	}
 
 	// Connect to cluster
-	client, err := k8s.NewClient(kubeconfig)
+	client, err := k8s.NewClientWithContext(kubeconfig, kubeContext)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to cluster: %w", err)
 	}
@@ -44,17 +55,60 @@ func NewManager(cfg *config.Config, isNAS bool) (*Manager, error) {
 	fluxDestroyer := NewFluxDestroyer(client.GetClientset(), client.GetDynamicClient())
 	nsCleanup := NewNamespaceCleanup(client.GetClientset(), client.GetDynamicClient())
 
+	notifier, err := notify.NewNotifier(cfg.NotificationsFor(isNAS))
+	if err != nil {
+		log.Warn("Notifications disabled", "error", err)
+	}
+
 	return &Manager{
 		cfg:           cfg,
 		isNAS:         isNAS,
 		client:        client,
 		fluxDestroyer: fluxDestroyer,
 		nsCleanup:     nsCleanup,
+		notifier:      notifier,
 	}, nil
 }
 
-// DestroyCluster performs complete cluster destruction
+// SetSnapshot exports the cluster state to dir before destroying it, secrets are only kept when key encrypts them
+func (m *Manager) SetSnapshot(dir string, key *secrets.AgeKey) {
+	m.snapshotDir = dir
+	m.snapshotKey = key
+}
+
+// SetOptions limits the destroy to some stacks or namespaces, and whether CRDs are kept
+func (m *Manager) SetOptions(opts Options) {
+	m.options = opts
+	m.fluxDestroyer.SetKeepCRDs(opts.KeepCRDs)
+}
+
+// clusterName returns the name the cluster is confirmed, notified and snapshotted under
+func (m *Manager) clusterName() string {
+	if m.isNAS {
+		return "nas"
+	}
+	return "homelab"
+}
+
+// DestroyCluster performs complete cluster destruction and notifies the outcome
 func (m *Manager) DestroyCluster(ctx context.Context) error {
+	start := time.Now()
+	err := m.destroyCluster(ctx)
+
+	event := notify.Event{Type: notify.DestroyCompleted, Cluster: m.clusterName(), Message: "Cluster destroyed", Duration: time.Since(start)}
+	if m.options.Scoped() {
+		event.Message = fmt.Sprintf("Destroyed %s", m.scopeDescription())
+	}
+	if err != nil {
+		event.Type = notify.DestroyFailed
+		event.Message = "Cluster destruction failed"
+		event.Error = err.Error()
+	}
+	m.notifier.Notify(ctx, event)
+	return err
+}
+
+func (m *Manager) destroyCluster(ctx context.Context) error {
 	clusterType := "homelab"
 	if m.isNAS {
 		clusterType = "NAS"
@@ -62,6 +116,28 @@ func (m *Manager) DestroyCluster(ctx context.Context) error {
 
 	log.Info("🗑️ Starting cluster destruction", "type", clusterType)
 
+	snapshotPath := ""
+	if m.snapshotDir != "" {
+		log.Info("Exporting cluster state before destruction")
+		path, err := snapshot.NewExporter(m.client, m.snapshotKey).Export(ctx, m.snapshotDir, m.clusterName())
+		if err != nil {
+			return fmt.Errorf("failed to export cluster state, pass --skip-backup to destroy without it: %w", err)
+		}
+		snapshotPath = path
+	}
+
+	if m.options.Scoped() {
+		log.Info("Limiting destruction", "scope", m.scopeDescription(), "keep_crds", m.options.KeepCRDs)
+		if err := m.destroyScoped(ctx); err != nil {
+			return err
+		}
+		log.Info("✅ Scoped destruction completed", "type", clusterType, "scope", m.scopeDescription())
+		if snapshotPath != "" {
+			log.Info("ℹ️ Run 'bootstrap restore " + snapshotPath + "' to re-apply the exported state")
+		}
+		return nil
+	}
+
 	// Step 1: Destroy FluxCD and all deployed resources
 	log.Info("Step 1: Destroying FluxCD and deployed resources")
 	if err := m.fluxDestroyer.Destroy(ctx, "flux-system"); err != nil {
@@ -85,10 +161,25 @@ func (m *Manager) DestroyCluster(ctx context.Context) error {
 
 	log.Info("✅ Cluster destruction completed successfully", "type", clusterType)
 	log.Info("ℹ️ Run 'bootstrap deploy' to reinstall")
+	if snapshotPath != "" {
+		log.Info("ℹ️ Run 'bootstrap restore " + snapshotPath + "' to re-apply the exported state")
+	}
 
 	return nil
 }
 
+// scopeDescription lists the stacks and namespaces a scoped destroy removes
+func (m *Manager) scopeDescription() string {
+	var parts []string
+	for _, scope := range m.options.Only {
+		parts = append(parts, string(scope))
+	}
+	for _, namespace := range m.options.Namespaces {
+		parts = append(parts, "namespace "+namespace)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // ForceCleanupNamespaces only cleans up stuck namespaces (for standalone use)
 func (m *Manager) ForceCleanupNamespaces(ctx context.Context) error {
 	log.Info("🔧 Starting namespace force cleanup")
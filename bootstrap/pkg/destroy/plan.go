@@ -0,0 +1,89 @@
+package destroy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Plan lists what a destroy with the current options deletes
+type Plan struct {
+	Cluster           string   `json:"cluster"`
+	Scopes            []Scope  `json:"scopes,omitempty"`
+	Namespaces        []string `json:"namespaces"`
+	CRDs              []string `json:"crds"`
+	PersistentVolumes []string `json:"persistent_volumes"`
+}
+
+// Plan computes the namespaces, CRDs and PersistentVolumes the destroy would delete, without changing anything
+func (m *Manager) Plan(ctx context.Context) (*Plan, error) {
+	plan := &Plan{Cluster: m.clusterName(), Scopes: m.options.Only}
+
+	namespaces, err := m.client.GetClientset().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	deleted := map[string]bool{}
+	for _, ns := range namespaces.Items {
+		if m.deletesNamespace(ns.Name) {
+			deleted[ns.Name] = true
+			plan.Namespaces = append(plan.Namespaces, ns.Name)
+		}
+	}
+
+	if !m.options.KeepCRDs {
+		crds, err := m.client.GetDynamicClient().Resource(crdGVR).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list CRDs: %w", err)
+		}
+		groups := m.options.crdGroups()
+		for _, crd := range crds.Items {
+			name := crd.GetName()
+			if (!m.options.Scoped() && !isCoreCRD(name)) || (len(groups) > 0 && crdInGroups(name, groups)) {
+				plan.CRDs = append(plan.CRDs, name)
+			}
+		}
+	}
+
+	pvs, err := m.client.GetClientset().CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+	for _, pv := range pvs.Items {
+		claimed := pv.Spec.ClaimRef != nil && deleted[pv.Spec.ClaimRef.Namespace]
+		// Released volumes are only force removed when storage goes
+		released := pv.Status.Phase == corev1.VolumeReleased && (!m.options.Scoped() || m.options.has(ScopeStorage))
+		if claimed || released {
+			plan.PersistentVolumes = append(plan.PersistentVolumes, pv.Name)
+		}
+	}
+
+	sort.Strings(plan.Namespaces)
+	sort.Strings(plan.CRDs)
+	sort.Strings(plan.PersistentVolumes)
+	return plan, nil
+}
+
+// deletesNamespace reports whether the destroy removes the named namespace
+func (m *Manager) deletesNamespace(name string) bool {
+	if contains(systemNamespaces, name) {
+		return false
+	}
+	opts := m.options
+	if !opts.Scoped() || contains(opts.Namespaces, name) {
+		return true
+	}
+	switch {
+	case name == "flux-system":
+		return opts.has(ScopeFlux)
+	case contains(istioNamespaces, name):
+		return opts.has(ScopeIstio)
+	case contains(storageNamespaces, name):
+		return opts.has(ScopeStorage)
+	default:
+		return opts.has(ScopeApps)
+	}
+}
@@ -0,0 +1,331 @@
+package destroy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Scope selects one stack a destroy is limited to
+type Scope string
+
+const (
+	ScopeFlux    Scope = "flux"
+	ScopeIstio   Scope = "istio"
+	ScopeStorage Scope = "storage"
+	ScopeApps    Scope = "apps"
+)
+
+// AllScopes lists every stack a destroy can be limited to
+var AllScopes = []Scope{ScopeFlux, ScopeIstio, ScopeStorage, ScopeApps}
+
+// Namespaces torn down by the istio and storage scopes, apps covers every other non-system namespace
+var (
+	istioNamespaces   = []string{"istio-system", "istio-ingress"}
+	storageNamespaces = []string{"rook-ceph"}
+	systemNamespaces  = []string{"kube-system", "kube-public", "kube-node-lease", "default"}
+)
+
+// CRD groups removed with each scope unless CRDs are kept
+var scopeCRDGroups = map[Scope][]string{
+	ScopeFlux:    {"toolkit.fluxcd.io"},
+	ScopeIstio:   {"istio.io"},
+	ScopeStorage: {"ceph.rook.io", "objectbucket.io"},
+}
+
+// Options limits what a destroy removes, the zero value destroys everything
+type Options struct {
+	// Only restricts the destroy to these stacks
+	Only []Scope
+	// Namespaces are torn down on top of the selected stacks
+	Namespaces []string
+	// KeepCRDs leaves the CustomResourceDefinitions in place
+	KeepCRDs bool
+}
+
+// Scoped returns true when the destroy is limited to some stacks or namespaces
+func (o Options) Scoped() bool {
+	return len(o.Only) > 0 || len(o.Namespaces) > 0
+}
+
+func (o Options) has(scope Scope) bool {
+	for _, selected := range o.Only {
+		if selected == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// crdGroups lists the CRD groups of the selected stacks
+func (o Options) crdGroups() []string {
+	var groups []string
+	for _, scope := range o.Only {
+		groups = append(groups, scopeCRDGroups[scope]...)
+	}
+	return groups
+}
+
+// ParseScopes validates the --only values
+func ParseScopes(values []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(values))
+	for _, value := range values {
+		scope := Scope(strings.ToLower(strings.TrimSpace(value)))
+		valid := false
+		for _, known := range AllScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown destroy scope %q (expected flux, istio, storage or apps)", value)
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// destroyScoped tears down the selected stacks and namespaces only
+func (m *Manager) destroyScoped(ctx context.Context) error {
+	fd := m.fluxDestroyer
+	opts := m.options
+
+	if !opts.has(ScopeFlux) {
+		// Keep Flux from recreating what is about to be removed
+		if fd.namespaceExists(ctx, "flux-system") {
+			if err := fd.suspendReconciliations(ctx, "flux-system"); err != nil {
+				log.Warn("Failed to suspend reconciliations", "error", err)
+			}
+			log.Warn("Flux reconciliations are suspended, resume them once the removed stacks should come back")
+		}
+	}
+
+	// Apps go first so workloads do not hang on storage or the mesh
+	if opts.has(ScopeApps) {
+		log.Info("🗑️ Destroying application namespaces")
+		namespaces, err := m.appNamespaces(ctx)
+		if err != nil {
+			return err
+		}
+		for _, namespace := range namespaces {
+			fd.deleteNamespace(ctx, namespace)
+		}
+	}
+
+	for _, namespace := range opts.Namespaces {
+		log.Info("🗑️ Destroying namespace", "namespace", namespace)
+		fd.deleteNamespace(ctx, namespace)
+	}
+
+	if opts.has(ScopeIstio) {
+		log.Info("🗑️ Destroying Istio")
+		if err := fd.deleteIstioWebhooks(ctx); err != nil {
+			log.Warn("Failed to delete Istio webhooks", "error", err)
+		}
+		for _, namespace := range istioNamespaces {
+			fd.deleteNamespace(ctx, namespace)
+		}
+	}
+
+	if opts.has(ScopeStorage) {
+		log.Info("🗑️ Destroying Rook-Ceph storage")
+		if err := fd.cleanupRookCeph(ctx); err != nil {
+			log.Warn("Failed to cleanup Rook-Ceph", "error", err)
+		}
+		for _, namespace := range storageNamespaces {
+			fd.deleteNamespace(ctx, namespace)
+		}
+		if err := fd.cleanupPersistentVolumes(ctx); err != nil {
+			log.Warn("Failed to cleanup persistent volumes", "error", err)
+		}
+	}
+
+	if opts.has(ScopeFlux) {
+		log.Info("🗑️ Destroying FluxCD")
+		if fd.namespaceExists(ctx, "flux-system") {
+			if err := fd.suspendReconciliations(ctx, "flux-system"); err != nil {
+				log.Warn("Failed to suspend reconciliations", "error", err)
+			}
+			// Orphan what Flux applied, only the controllers and their objects go
+			if err := fd.orphanKustomizations(ctx, "flux-system"); err != nil {
+				log.Warn("Failed to delete kustomizations", "error", err)
+			}
+			if err := fd.forceCleanupFluxNamespace(ctx, "flux-system"); err != nil {
+				log.Warn("Failed to force cleanup flux namespace", "error", err)
+			}
+		}
+	}
+
+	if !opts.KeepCRDs {
+		if groups := opts.crdGroups(); len(groups) > 0 {
+			if err := fd.cleanupCRDGroups(ctx, groups); err != nil {
+				log.Warn("Failed to cleanup CRDs", "error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// appNamespaces lists the namespaces that belong to none of the other stacks
+func (m *Manager) appNamespaces(ctx context.Context) ([]string, error) {
+	namespaces, err := m.client.GetClientset().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	var apps []string
+	for _, ns := range namespaces.Items {
+		name := ns.Name
+		if name == "flux-system" || contains(systemNamespaces, name) || contains(istioNamespaces, name) || contains(storageNamespaces, name) {
+			continue
+		}
+		apps = append(apps, name)
+	}
+	return apps, nil
+}
+
+// deleteNamespace force deletes the pods of namespace, strips the finalizers of its objects and deletes it
+func (fd *FluxDestroyer) deleteNamespace(ctx context.Context, namespace string) {
+	if !fd.namespaceExists(ctx, namespace) {
+		log.Debug("Namespace not found, skipping", "namespace", namespace)
+		return
+	}
+	log.Info("Cleaning namespace", "namespace", namespace)
+
+	gracePeriod := int64(0)
+	deletePolicy := metav1.DeletePropagationForeground
+	err := fd.client.CoreV1().Pods(namespace).DeleteCollection(ctx, metav1.DeleteOptions{
+		PropagationPolicy:  &deletePolicy,
+		GracePeriodSeconds: &gracePeriod,
+	}, metav1.ListOptions{})
+	if err != nil {
+		log.Warn("Failed to delete pods", "namespace", namespace, "error", err)
+	}
+
+	if err := fd.removeAllFinalizersInNamespace(ctx, namespace); err != nil {
+		log.Warn("Failed to remove finalizers", "namespace", namespace, "error", err)
+	}
+
+	if err := fd.client.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{}); err != nil {
+		log.Warn("Failed to delete namespace", "namespace", namespace, "error", err)
+	}
+}
+
+// orphanKustomizations deletes the Kustomizations of namespace while leaving the objects they applied in place
+func (fd *FluxDestroyer) orphanKustomizations(ctx context.Context, namespace string) error {
+	gvr := schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	resource := fd.dynamicClient.Resource(gvr).Namespace(namespace)
+
+	list, err := resource.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		// Kustomizations with prune enabled garbage collect their inventory on deletion
+		patch := []byte(`{"spec":{"prune":false},"metadata":{"finalizers":null}}`)
+		if _, err := resource.Patch(ctx, item.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			log.Warn("Failed to disable pruning", "kustomization", item.GetName(), "error", err)
+		}
+		if err := resource.Delete(ctx, item.GetName(), metav1.DeleteOptions{}); err != nil {
+			log.Warn("Failed to delete kustomization", "name", item.GetName(), "error", err)
+		}
+	}
+	return nil
+}
+
+// deleteIstioWebhooks removes the Istio admission webhooks, which would otherwise block pod creation once istiod is gone
+func (fd *FluxDestroyer) deleteIstioWebhooks(ctx context.Context) error {
+	selector := metav1.ListOptions{LabelSelector: "app in (sidecar-injector,istiod)"}
+
+	mutating := fd.client.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	if err := mutating.DeleteCollection(ctx, metav1.DeleteOptions{}, selector); err != nil {
+		return fmt.Errorf("failed to delete mutating webhooks: %w", err)
+	}
+	validating := fd.client.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	if err := validating.DeleteCollection(ctx, metav1.DeleteOptions{}, selector); err != nil {
+		return fmt.Errorf("failed to delete validating webhooks: %w", err)
+	}
+	return nil
+}
+
+// cleanupCRDGroups deletes the CRDs whose group is or ends with one of groups
+func (fd *FluxDestroyer) cleanupCRDGroups(ctx context.Context, groups []string) error {
+	log.Info("🗑️ Cleaning up CRDs", "groups", groups)
+
+	crds, err := fd.dynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list CRDs: %w", err)
+	}
+
+	for _, crd := range crds.Items {
+		if !crdInGroups(crd.GetName(), groups) {
+			continue
+		}
+
+		// Objects left with finalizers would keep the CRD terminating once their controller is gone
+		fd.stripCustomResourceFinalizers(ctx, crd)
+
+		log.Info("Deleting CRD", "name", crd.GetName())
+		if err := fd.dynamicClient.Resource(crdGVR).Delete(ctx, crd.GetName(), metav1.DeleteOptions{}); err != nil {
+			log.Warn("Failed to delete CRD", "name", crd.GetName(), "error", err)
+		}
+	}
+	return nil
+}
+
+// crdInGroups reports whether the group of a CRD is or ends with one of groups
+func crdInGroups(name string, groups []string) bool {
+	// CRD names are <plural>.<group>
+	_, group, _ := strings.Cut(name, ".")
+	for _, wanted := range groups {
+		if group == wanted || strings.HasSuffix(group, "."+wanted) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripCustomResourceFinalizers removes the finalizers of every object of a CRD, in all namespaces
+func (fd *FluxDestroyer) stripCustomResourceFinalizers(ctx context.Context, crd unstructured.Unstructured) {
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	version := ""
+	for _, raw := range versions {
+		if v, ok := raw.(map[string]interface{}); ok && v["storage"] == true {
+			version, _ = v["name"].(string)
+		}
+	}
+	if version == "" || plural == "" {
+		return
+	}
+
+	gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: plural}
+	items, err := fd.dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return
+	}
+	patch := []byte(`{"metadata":{"finalizers":null}}`)
+	for _, item := range items.Items {
+		if len(item.GetFinalizers()) == 0 {
+			continue
+		}
+		var err error
+		if namespace := item.GetNamespace(); namespace != "" {
+			_, err = fd.dynamicClient.Resource(gvr).Namespace(namespace).Patch(ctx, item.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		} else {
+			_, err = fd.dynamicClient.Resource(gvr).Patch(ctx, item.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		}
+		if err != nil {
+			log.Debug("Failed to remove finalizers", "resource", plural, "name", item.GetName(), "error", err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Scope selects which aspect of the clusters is compared
+type Scope string
+
+const (
+	ScopeFlux     Scope = "flux"
+	ScopeVersions Scope = "versions"
+	ScopeSecrets  Scope = "secrets"
+	ScopeStorage  Scope = "storage"
+)
+
+// AllScopes lists every supported comparison scope
+var AllScopes = []Scope{ScopeFlux, ScopeVersions, ScopeSecrets, ScopeStorage}
+
+// missing marks a value that is absent on one side of the comparison
+const missing = "<missing>"
+
+// componentNamespaces are the namespaces whose workload images are compared
+var componentNamespaces = []string{
+	"flux-system",
+	"istio-system",
+	"cert-manager",
+	"vault",
+	"external-secrets",
+	"rook-ceph",
+	"minio",
+}
+
+var (
+	crdGVR            = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	gitRepositoryGVR  = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"}
+	ociRepositoryGVR  = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Resource: "ocirepositories"}
+	bucketGVR         = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "buckets"}
+	kustomizationGVR  = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	helmRepositoryGVR = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "helmrepositories"}
+)
+
+// Entry is a single difference between the two clusters
+type Entry struct {
+	Scope   Scope  `json:"scope"`
+	Key     string `json:"key"`
+	Homelab string `json:"homelab"`
+	NAS     string `json:"nas"`
+}
+
+// Report holds the differences found per scope
+type Report struct {
+	Scopes  []Scope `json:"scopes"`
+	Entries []Entry `json:"entries"`
+}
+
+// ClusterDiffer compares the homelab and NAS clusters
+type ClusterDiffer struct {
+	homelab *k8s.Client
+	nas     *k8s.Client
+}
+
+// NewClusterDiffer creates a new cluster differ
+func NewClusterDiffer(homelab, nas *k8s.Client) *ClusterDiffer {
+	return &ClusterDiffer{
+		homelab: homelab,
+		nas:     nas,
+	}
+}
+
+// ParseScopes validates scope names, returning all scopes when none are given
+func ParseScopes(values []string) ([]Scope, error) {
+	if len(values) == 0 {
+		return AllScopes, nil
+	}
+	var scopes []Scope
+	for _, value := range values {
+		scope := Scope(strings.ToLower(strings.TrimSpace(value)))
+		switch scope {
+		case ScopeFlux, ScopeVersions, ScopeSecrets, ScopeStorage:
+			scopes = append(scopes, scope)
+		default:
+			return nil, fmt.Errorf("unknown scope %q (expected flux, versions, secrets or storage)", value)
+		}
+	}
+	return scopes, nil
+}
+
+// Diff compares both clusters for the requested scopes
+func (d *ClusterDiffer) Diff(ctx context.Context, scopes []Scope) (*Report, error) {
+	report := &Report{Scopes: scopes}
+
+	for _, scope := range scopes {
+		var collect func(context.Context, *k8s.Client) (map[string]string, error)
+		switch scope {
+		case ScopeFlux:
+			collect = collectFlux
+		case ScopeVersions:
+			collect = collectVersions
+		case ScopeSecrets:
+			collect = collectSecrets
+		case ScopeStorage:
+			collect = collectStorage
+		default:
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+
+		homelabValues, err := collect(ctx, d.homelab)
+		if err != nil {
+			return nil, fmt.Errorf("homelab %s: %w", scope, err)
+		}
+		nasValues, err := collect(ctx, d.nas)
+		if err != nil {
+			return nil, fmt.Errorf("nas %s: %w", scope, err)
+		}
+
+		report.Entries = append(report.Entries, compare(scope, homelabValues, nasValues)...)
+	}
+
+	return report, nil
+}
+
+func compare(scope Scope, homelab, nas map[string]string) []Entry {
+	keys := make(map[string]struct{}, len(homelab)+len(nas))
+	for key := range homelab {
+		keys[key] = struct{}{}
+	}
+	for key := range nas {
+		keys[key] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var entries []Entry
+	for _, key := range sorted {
+		h, hok := homelab[key]
+		n, nok := nas[key]
+		if !hok {
+			h = missing
+		}
+		if !nok {
+			n = missing
+		}
+		if h == n {
+			continue
+		}
+		entries = append(entries, Entry{Scope: scope, Key: key, Homelab: h, NAS: n})
+	}
+	return entries
+}
+
+// collectFlux gathers Flux source and Kustomization revisions keyed by kind/name
+func collectFlux(ctx context.Context, client *k8s.Client) (map[string]string, error) {
+	values := map[string]string{}
+
+	sources := []struct {
+		kind  string
+		gvr   schema.GroupVersionResource
+		field []string
+	}{
+		{"GitRepository", gitRepositoryGVR, []string{"status", "artifact", "revision"}},
+		{"OCIRepository", ociRepositoryGVR, []string{"status", "artifact", "revision"}},
+		{"Bucket", bucketGVR, []string{"status", "artifact", "revision"}},
+		{"HelmRepository", helmRepositoryGVR, []string{"spec", "url"}},
+		{"Kustomization", kustomizationGVR, []string{"status", "lastAppliedRevision"}},
+	}
+
+	for _, source := range sources {
+		list, err := client.GetDynamicClient().Resource(source.gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			log.Debug("Failed to list Flux resources", "kind", source.kind, "error", err)
+			continue
+		}
+		for _, item := range list.Items {
+			value, _, _ := unstructured.NestedString(item.Object, source.field...)
+			if value == "" {
+				value = "<none>"
+			}
+			values[fmt.Sprintf("%s/%s", source.kind, item.GetName())] = value
+		}
+	}
+
+	return values, nil
+}
+
+// collectVersions gathers the Kubernetes version, component images and installed CRDs
+func collectVersions(ctx context.Context, client *k8s.Client) (map[string]string, error) {
+	values := map[string]string{}
+
+	if version, err := client.GetClientset().Discovery().ServerVersion(); err == nil {
+		values["kubernetes"] = version.GitVersion
+	}
+
+	for _, namespace := range componentNamespaces {
+		deployments, err := client.GetClientset().AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			for _, deployment := range deployments.Items {
+				for _, container := range deployment.Spec.Template.Spec.Containers {
+					values[fmt.Sprintf("%s/%s/%s", namespace, deployment.Name, container.Name)] = k8s.ImageTag(container.Image)
+				}
+			}
+		}
+		daemonSets, err := client.GetClientset().AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			for _, daemonSet := range daemonSets.Items {
+				for _, container := range daemonSet.Spec.Template.Spec.Containers {
+					values[fmt.Sprintf("%s/%s/%s", namespace, daemonSet.Name, container.Name)] = k8s.ImageTag(container.Image)
+				}
+			}
+		}
+	}
+
+	crds, err := client.GetDynamicClient().Resource(crdGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CRDs: %w", err)
+	}
+	for _, crd := range crds.Items {
+		values["crd/"+crd.GetName()] = crdStorageVersion(crd)
+	}
+
+	return values, nil
+}
+
+// collectSecrets gathers the key presence of the cluster-vars secret (never the values)
+func collectSecrets(ctx context.Context, client *k8s.Client) (map[string]string, error) {
+	values := map[string]string{}
+
+	secret, err := client.GetSecret(ctx, "flux-system", "cluster-vars")
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("failed to read cluster-vars: %w", err)
+	}
+
+	for key, value := range secret.Data {
+		state := "set"
+		if len(strings.TrimSpace(string(value))) == 0 {
+			state = "empty"
+		}
+		values["cluster-vars/"+key] = state
+	}
+
+	return values, nil
+}
+
+// collectStorage gathers storage classes, the default class and CSI drivers
+func collectStorage(ctx context.Context, client *k8s.Client) (map[string]string, error) {
+	values := map[string]string{}
+
+	classes, err := client.GetClientset().StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+	for _, class := range classes.Items {
+		values["storageclass/"+class.Name] = class.Provisioner
+		if class.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" {
+			values["default-storageclass"] = class.Name
+		}
+	}
+
+	drivers, err := client.GetClientset().StorageV1().CSIDrivers().List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for _, driver := range drivers.Items {
+			values["csidriver/"+driver.Name] = "installed"
+		}
+	}
+
+	return values, nil
+}
+
+func crdStorageVersion(crd unstructured.Unstructured) string {
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, raw := range versions {
+		version, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if storage, _ := version["storage"].(bool); storage {
+			if name, ok := version["name"].(string); ok {
+				return name
+			}
+		}
+	}
+	return "installed"
+}
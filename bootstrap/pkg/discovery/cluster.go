@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
 	"k8s.io/client-go/rest"
 	clientcmd "k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -297,6 +298,9 @@ func loadClusterConfig(path, cluster, context string) (*rest.Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to build config from %s: %w", path, err)
 	}
+	k8s.ConfigureClient(cfg)
+	k8s.GuardConfig(cfg)
+	k8s.AuditConfig(cfg, context)
 
 	return cfg, nil
 }
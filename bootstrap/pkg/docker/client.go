@@ -0,0 +1,315 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHost is the socket of a local Docker daemon
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// ErrNotFound is returned when the container or file asked for does not exist
+var ErrNotFound = errors.New("not found")
+
+// Client talks to the Docker Engine API of a Docker host, like the docker CLI with DOCKER_HOST and DOCKER_CERT_PATH
+type Client struct {
+	host    string
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for host, a tcp:// or unix:// address. A tcp host is reached over mutual TLS
+// with the ca.pem, cert.pem and key.pem of certPath, or in plain HTTP when certPath is empty.
+func NewClient(host, certPath string) (*Client, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = DefaultHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %s: %w", host, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	client := &Client{http: &http.Client{Transport: transport}}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		client.baseURL = "http://docker"
+	case "tcp":
+		client.host = u.Hostname()
+		if certPath == "" {
+			client.baseURL = "http://" + u.Host
+			break
+		}
+		tlsConfig, err := tlsConfig(certPath, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+		client.baseURL = "https://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host %s, use a tcp:// or unix:// address", host)
+	}
+	return client, nil
+}
+
+// tlsConfig loads the client certificate and CA of a DOCKER_CERT_PATH directory
+func tlsConfig(certPath, serverName string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load docker client certificate: %w", err)
+	}
+	pem, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", filepath.Join(certPath, "ca.pem"))
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool, ServerName: serverName}, nil
+}
+
+// Host returns the address of a tcp Docker host, empty for a local socket
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Ping checks the daemon answers
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/_ping", nil, nil)
+}
+
+// Inspect returns the container with the given name or ID, ErrNotFound when there is none
+func (c *Client) Inspect(ctx context.Context, name string) (*Container, error) {
+	var container Container
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, &container); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// Containers lists the running containers, and the stopped ones too with all
+func (c *Client) Containers(ctx context.Context, all bool) ([]ContainerSummary, error) {
+	var containers []ContainerSummary
+	if err := c.do(ctx, http.MethodGet, "/containers/json?all="+strconv.FormatBool(all), nil, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// Pull downloads image, reporting the layer progress to progress
+func (c *Client) Pull(ctx context.Context, image string, progress func(message string)) error {
+	query := url.Values{"fromImage": {image}}
+	resp, err := c.request(ctx, http.MethodPost, "/images/create?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Pull errors arrive in the stream once the request was accepted
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read pull progress of %s: %w", image, err)
+		}
+		if message.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", image, message.Error)
+		}
+		if progress == nil || strings.HasPrefix(message.Status, "Downloading") || strings.HasPrefix(message.Status, "Extracting") {
+			continue
+		}
+		if message.ID != "" {
+			progress(message.ID + ": " + message.Status)
+		} else {
+			progress(message.Status)
+		}
+	}
+}
+
+// Create creates a container named name, returning its ID
+func (c *Client) Create(ctx context.Context, name string, config ContainerConfig) (string, error) {
+	var created struct {
+		ID       string   `json:"Id"`
+		Warnings []string `json:"Warnings"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/containers/create?name="+url.QueryEscape(name), config, &created); err != nil {
+		return "", fmt.Errorf("failed to create container %s: %w", name, err)
+	}
+	return created.ID, nil
+}
+
+// Start starts a created or stopped container
+func (c *Client) Start(ctx context.Context, id string) error {
+	if err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/start", nil, nil); err != nil {
+		return fmt.Errorf("failed to start container %s: %w", id, err)
+	}
+	return nil
+}
+
+// Stop asks the container to exit, killing it once timeout passes
+func (c *Client) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	path := "/containers/" + url.PathEscape(id) + "/stop?t=" + strconv.Itoa(int(timeout.Seconds()))
+	if err := c.do(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", id, err)
+	}
+	return nil
+}
+
+// Remove deletes a stopped container with its anonymous volumes
+func (c *Client) Remove(ctx context.Context, id string) error {
+	if err := c.do(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id)+"?v=true", nil, nil); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", id, err)
+	}
+	return nil
+}
+
+// Logs copies the output of a container without a TTY to w, up to tail lines back ("all" for everything),
+// and keeps streaming with follow until ctx is done or the container stops
+func (c *Client) Logs(ctx context.Context, id string, tail string, follow bool, w io.Writer) error {
+	query := url.Values{
+		"stdout": {"true"},
+		"stderr": {"true"},
+		"tail":   {tail},
+		"follow": {strconv.FormatBool(follow)},
+	}
+	resp, err := c.request(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := demux(resp.Body, w); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read logs of %s: %w", id, err)
+	}
+	return nil
+}
+
+// ReadFile returns the content of the file at path inside a container, ErrNotFound when it does not exist
+func (c *Client) ReadFile(ctx context.Context, id, file string) ([]byte, error) {
+	query := url.Values{"path": {file}}
+	resp, err := c.request(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/archive?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	archive := tar.NewReader(resp.Body)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s: %w", file, ErrNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", file, id, err)
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == path.Base(file) {
+			return io.ReadAll(archive)
+		}
+	}
+}
+
+// demux splits the stdout and stderr frames Docker multiplexes the output of a container without a TTY in,
+// both are written to w
+func demux(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, reader, size); err != nil {
+			return err
+		}
+	}
+}
+
+// do calls the API with body encoded as JSON and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	resp, err := c.request(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse docker API response: %w", err)
+	}
+	return nil
+}
+
+// request sends a request and turns error statuses into errors, leaving a successful body to the caller
+func (c *Client) request(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker API request failed: %w", err)
+	}
+	// 304 answers a start or stop of a container already in that state
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var failure struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if json.Unmarshal(data, &failure) != nil || failure.Message == "" {
+		failure.Message = resp.Status
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", failure.Message, ErrNotFound)
+	}
+	return nil, fmt.Errorf("%s %s: %s", method, path, failure.Message)
+}
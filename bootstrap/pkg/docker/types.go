@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"strings"
+	"time"
+)
+
+// Container is the inspected state of a container
+type Container struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	State struct {
+		Status     string    `json:"Status"`
+		Running    bool      `json:"Running"`
+		Restarting bool      `json:"Restarting"`
+		ExitCode   int       `json:"ExitCode"`
+		Error      string    `json:"Error"`
+		StartedAt  time.Time `json:"StartedAt"`
+	} `json:"State"`
+	RestartCount    int `json:"RestartCount"`
+	NetworkSettings struct {
+		Ports    map[string][]PortBinding `json:"Ports"`
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// IPAddress returns the address of the container on its first network
+func (c *Container) IPAddress() string {
+	for _, network := range c.NetworkSettings.Networks {
+		if network.IPAddress != "" {
+			return network.IPAddress
+		}
+	}
+	return ""
+}
+
+// ContainerSummary is a container as listed by the daemon
+type ContainerSummary struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+// Name returns the container name without the leading slash the daemon lists
+func (c ContainerSummary) Name() string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// ContainerConfig is the body of a container create request
+type ContainerConfig struct {
+	Hostname     string              `json:"Hostname,omitempty"`
+	Image        string              `json:"Image"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   HostConfig          `json:"HostConfig"`
+}
+
+// HostConfig holds the host resources of a container
+type HostConfig struct {
+	Binds         []string                 `json:"Binds,omitempty"`
+	NetworkMode   string                   `json:"NetworkMode,omitempty"`
+	Privileged    bool                     `json:"Privileged,omitempty"`
+	PortBindings  map[string][]PortBinding `json:"PortBindings,omitempty"`
+	RestartPolicy RestartPolicy            `json:"RestartPolicy"`
+	LogConfig     LogConfig                `json:"LogConfig"`
+	Runtime       string                   `json:"Runtime,omitempty"`
+}
+
+// PortBinding publishes a container port on the host, HostPort empty picks a free one
+type PortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// RestartPolicy tells the daemon when to restart the container
+type RestartPolicy struct {
+	Name              string `json:"Name"`
+	MaximumRetryCount int    `json:"MaximumRetryCount,omitempty"`
+}
+
+// LogConfig selects the log driver of the container, the daemon default when Type is empty
+type LogConfig struct {
+	Type   string            `json:"Type"`
+	Config map[string]string `json:"Config,omitempty"`
+}
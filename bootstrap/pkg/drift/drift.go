@@ -0,0 +1,272 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v6/value"
+)
+
+// Field change types, from the point of view of the cluster
+const (
+	// FieldAdded is owned by the bootstrap in the cluster but no longer rendered
+	FieldAdded = "added"
+	// FieldRemoved is rendered but missing from the cluster
+	FieldRemoved = "removed"
+	// FieldModified is rendered with another value than the cluster holds
+	FieldModified = "modified"
+)
+
+// FieldChange is a field of a managed object that differs from its rendered state, values are never shown
+type FieldChange struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// ObjectDrift lists the changed fields of one rendered object
+type ObjectDrift struct {
+	Object
+	Source  string        `json:"source"`
+	Missing bool          `json:"missing,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// Report is the outcome of a drift detection
+type Report struct {
+	// Managed lists every object carrying the bootstrap field manager
+	Managed []Object `json:"managed"`
+	// Drifted lists the rendered objects that differ from the cluster
+	Drifted []ObjectDrift `json:"drifted"`
+	// Unrendered lists managed objects applied imperatively, without a rendered state to compare with
+	Unrendered []Object `json:"unrendered"`
+}
+
+// Clean reports whether every rendered object matches the cluster
+func (r *Report) Clean() bool {
+	return len(r.Drifted) == 0
+}
+
+// Source renders a group of objects the bootstrap applies
+type Source struct {
+	Name   string
+	Render func(ctx context.Context) ([]*unstructured.Unstructured, error)
+	// Reapply restores the source when server-side applying its objects is not enough, e.g. to drop stale keys written by updates
+	Reapply func(ctx context.Context) error
+}
+
+// Detector compares the objects the bootstrap manages with what its sources render now
+type Detector struct {
+	client  *k8s.Client
+	sources []Source
+}
+
+// NewDetector creates a detector for the cluster behind client
+func NewDetector(client *k8s.Client, sources ...Source) *Detector {
+	return &Detector{client: client, sources: sources}
+}
+
+// Detect lists the managed objects and diffs every rendered one against the cluster
+func (d *Detector) Detect(ctx context.Context) (*Report, error) {
+	managed, err := d.Inventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Managed: managed}
+
+	rendered := make(map[string]bool)
+	for _, source := range d.sources {
+		objects, err := source.Render(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", source.Name, err)
+		}
+		for _, obj := range objects {
+			rendered[objectOf(obj).key()] = true
+			drift, err := d.compare(ctx, obj)
+			if err != nil {
+				return nil, err
+			}
+			if drift != nil {
+				drift.Source = source.Name
+				report.Drifted = append(report.Drifted, *drift)
+			}
+		}
+	}
+
+	for _, obj := range managed {
+		if !rendered[obj.key()] {
+			report.Unrendered = append(report.Unrendered, obj)
+		}
+	}
+	return report, nil
+}
+
+// Fix re-applies the rendered state of every drifted object
+func (d *Detector) Fix(ctx context.Context, report *Report) error {
+	drifted := make(map[string]map[string]bool)
+	for _, drift := range report.Drifted {
+		if drifted[drift.Source] == nil {
+			drifted[drift.Source] = make(map[string]bool)
+		}
+		drifted[drift.Source][drift.key()] = true
+	}
+
+	for _, source := range d.sources {
+		keys := drifted[source.Name]
+		if len(keys) == 0 {
+			continue
+		}
+		if source.Reapply != nil {
+			if err := source.Reapply(ctx); err != nil {
+				return fmt.Errorf("failed to re-apply %s: %w", source.Name, err)
+			}
+			log.Info("Re-applied", "source", source.Name)
+			continue
+		}
+
+		objects, err := source.Render(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", source.Name, err)
+		}
+		for _, obj := range objects {
+			if !keys[objectOf(obj).key()] {
+				continue
+			}
+			if _, err := d.client.Apply(ctx, obj, false); err != nil {
+				return err
+			}
+			log.Info("Re-applied", "source", source.Name, "object", objectOf(obj))
+		}
+	}
+	return nil
+}
+
+// compare diffs obj with its live counterpart, nil when they match
+func (d *Detector) compare(ctx context.Context, obj *unstructured.Unstructured) (*ObjectDrift, error) {
+	resource, err := d.client.ResourceFor(ctx, obj.GroupVersionKind(), obj.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &ObjectDrift{Object: objectOf(obj), Missing: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", objectOf(obj), err)
+	}
+
+	// A dry-run apply yields the rendered state as the API server would store it, defaults included
+	expected, err := d.client.Apply(ctx, obj.DeepCopy(), true)
+	if err != nil {
+		return nil, err
+	}
+	applied := ownedFields(expected, metav1.ManagedFieldsOperationApply)
+	owned := ownedFields(live, "")
+
+	var changes []FieldChange
+	applied.Leaves().Iterate(func(path fieldpath.Path) {
+		want, _ := valueAt(expected.Object, path)
+		have, found := valueAt(live.Object, path)
+		switch {
+		case !found:
+			changes = append(changes, FieldChange{Path: path.String(), Type: FieldRemoved})
+		case !equality.Semantic.DeepEqual(want, have):
+			changes = append(changes, FieldChange{Path: path.String(), Type: FieldModified})
+		}
+	})
+	owned.Difference(applied).Leaves().Iterate(func(path fieldpath.Path) {
+		if _, found := valueAt(live.Object, path); found {
+			changes = append(changes, FieldChange{Path: path.String(), Type: FieldAdded})
+		}
+	})
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return &ObjectDrift{Object: objectOf(obj), Changes: changes}, nil
+}
+
+// ownedFields returns the fields the bootstrap owns in obj, restricted to one operation unless operation is empty
+func ownedFields(obj *unstructured.Unstructured, operation metav1.ManagedFieldsOperationType) *fieldpath.Set {
+	owned := &fieldpath.Set{}
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != k8s.FieldManager || entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+		if operation != "" && entry.Operation != operation {
+			continue
+		}
+		fields := &fieldpath.Set{}
+		if err := fields.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			log.Debug("Ignoring unreadable managed fields", "object", objectOf(obj), "error", err)
+			continue
+		}
+		owned = owned.Union(fields)
+	}
+	return owned
+}
+
+// valueAt resolves a field path in an unstructured object
+func valueAt(obj map[string]interface{}, path fieldpath.Path) (interface{}, bool) {
+	var current interface{} = obj
+	for _, element := range path {
+		switch {
+		case element.FieldName != nil:
+			fields, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = fields[*element.FieldName]; !ok {
+				return nil, false
+			}
+		case element.Index != nil:
+			items, ok := current.([]interface{})
+			if !ok || *element.Index >= len(items) {
+				return nil, false
+			}
+			current = items[*element.Index]
+		default:
+			items, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			found := false
+			for _, item := range items {
+				if matches(item, element) {
+					current, found = item, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		}
+	}
+	return current, true
+}
+
+// matches reports whether a list item is the one a key or set-value path element selects
+func matches(item interface{}, element fieldpath.PathElement) bool {
+	if element.Value != nil {
+		return value.Equals(value.NewValueInterface(item), *element.Value)
+	}
+	fields, ok := item.(map[string]interface{})
+	if !ok || element.Key == nil {
+		return false
+	}
+	for _, key := range *element.Key {
+		field, ok := fields[key.Name]
+		if !ok || !value.Equals(value.NewValueInterface(field), key.Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package drift
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+)
+
+// Object identifies a Kubernetes object
+type Object struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// String renders the object as Kind/namespace/name
+func (o Object) String() string {
+	if o.Namespace == "" {
+		return o.Kind + "/" + o.Name
+	}
+	return o.Kind + "/" + o.Namespace + "/" + o.Name
+}
+
+// key identifies the object whatever API version it was read with
+func (o Object) key() string {
+	gv, _ := schema.ParseGroupVersion(o.APIVersion)
+	return gv.Group + "/" + o.String()
+}
+
+func objectOf(obj *unstructured.Unstructured) Object {
+	return Object{APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+// skippedResources churn constantly and are never written by the bootstrap
+var skippedResources = []string{"events", "leases", "endpoints", "endpointslices"}
+
+// Inventory lists every object with fields owned by the bootstrap field manager, reading metadata only
+func (d *Detector) Inventory(ctx context.Context) ([]Object, error) {
+	metadataClient, err := metadata.NewForConfig(d.client.GetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata client: %w", err)
+	}
+	lists, err := d.client.GetClientset().Discovery().ServerPreferredResources()
+	if err != nil {
+		if len(lists) == 0 {
+			return nil, fmt.Errorf("failed to discover API resources: %w", err)
+		}
+		log.Debug("Some API groups are unavailable, skipping them", "error", err)
+	}
+
+	var objects []Object
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") || !slices.Contains(resource.Verbs, "list") || slices.Contains(skippedResources, resource.Name) {
+				continue
+			}
+			items, err := metadataClient.Resource(gv.WithResource(resource.Name)).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				log.Debug("Skipping resource that cannot be listed", "resource", resource.Name, "error", err)
+				continue
+			}
+			for i := range items.Items {
+				item := &items.Items[i]
+				if !managed(item.GetManagedFields()) {
+					continue
+				}
+				objects = append(objects, Object{APIVersion: list.GroupVersion, Kind: resource.Kind, Namespace: item.GetNamespace(), Name: item.GetName()})
+			}
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].key() < objects[j].key() })
+	return objects, nil
+}
+
+// managed reports whether the bootstrap owns fields of the main resource
+func managed(entries []metav1.ManagedFieldsEntry) bool {
+	for _, entry := range entries {
+		if entry.Manager == k8s.FieldManager && entry.Subresource == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+)
+
+// DefaultRetention is how many snapshots are kept in the bucket
+const DefaultRetention = 7
+
+// Source takes and restores the etcd snapshots of a cluster
+type Source interface {
+	// Save streams a snapshot, including the hash etcd appends to it
+	Save(ctx context.Context, w io.Writer) error
+	// Restore resets the cluster datastore to the snapshot at path
+	Restore(ctx context.Context, path string) error
+}
+
+// Manager takes snapshots with a source, verifies them and keeps them in a store
+type Manager struct {
+	cluster string
+	source  Source
+	store   *Store
+	workDir string
+}
+
+// NewManager creates a manager for cluster, snapshots transit through workDir
+func NewManager(cluster string, source Source, store *Store, workDir string) *Manager {
+	return &Manager{cluster: cluster, source: source, store: store, workDir: workDir}
+}
+
+// Snapshot takes a snapshot, verifies and uploads it, then prunes the bucket down to retention snapshots
+func (m *Manager) Snapshot(ctx context.Context, retention int) (*Snapshot, []string, error) {
+	if retention < 1 {
+		retention = DefaultRetention
+	}
+	file, err := m.tempFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(file.Name())
+
+	name := fmt.Sprintf("%s-%s.db", m.cluster, time.Now().UTC().Format("20060102-150405"))
+	log.Info("Taking etcd snapshot", "cluster", m.cluster, "name", name)
+	err = m.source.Save(ctx, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to take etcd snapshot: %w", err)
+	}
+
+	checksum, err := Verify(file.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := os.Stat(file.Name())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat snapshot: %w", err)
+	}
+	if err := m.store.Upload(ctx, name, file.Name(), checksum); err != nil {
+		return nil, nil, err
+	}
+	log.Info("Snapshot uploaded", "name", name, "size", info.Size(), "sha256", checksum)
+
+	removed, err := m.store.Prune(ctx, retention)
+	if err != nil {
+		log.Warn("Failed to prune old snapshots", "error", err)
+	}
+	return &Snapshot{Name: name, Size: info.Size(), Created: info.ModTime(), SHA256: checksum}, removed, nil
+}
+
+// List returns the stored snapshots, newest first
+func (m *Manager) List(ctx context.Context) ([]Snapshot, error) {
+	return m.store.List(ctx)
+}
+
+// Restore downloads a snapshot, checks it against its recorded checksum and the etcd hash, then restores it
+func (m *Manager) Restore(ctx context.Context, name string) error {
+	if err := k8s.GuardMutation("restore etcd snapshot"); err != nil {
+		return err
+	}
+	file, err := m.tempFile()
+	if err != nil {
+		return err
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	recorded, err := m.store.Download(ctx, name, file.Name())
+	if err != nil {
+		return err
+	}
+	checksum, err := Verify(file.Name())
+	if err != nil {
+		return err
+	}
+	if recorded != "" && recorded != checksum {
+		return fmt.Errorf("snapshot %s does not match the checksum recorded at upload", name)
+	}
+
+	log.Info("Restoring etcd snapshot", "cluster", m.cluster, "name", name)
+	if err := m.source.Restore(ctx, file.Name()); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+	return nil
+}
+
+// tempFile creates a private file in the work directory, snapshots hold every secret of the cluster
+func (m *Manager) tempFile() (*os.File, error) {
+	if err := os.MkdirAll(m.workDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", m.workDir, err)
+	}
+	file, err := os.CreateTemp(m.workDir, "etcd-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	return file, nil
+}
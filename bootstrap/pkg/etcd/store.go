@@ -0,0 +1,123 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// checksumMeta carries the sha256 of a snapshot as object metadata
+const checksumMeta = "Sha256"
+
+// StoreOptions locates the MinIO bucket holding the snapshots
+type StoreOptions struct {
+	Endpoint  string // S3 URL, e.g. http://127.0.0.1:9000
+	Bucket    string
+	Prefix    string // Snapshots are stored under <prefix>/
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// Store keeps etcd snapshots in a MinIO bucket
+type Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewStore connects to the bucket of opts
+func NewStore(opts StoreOptions) (*Store, error) {
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, fmt.Errorf("MinIO credentials are required, set MINIO_ACCESS_KEY and MINIO_SECRET_KEY")
+	}
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 URL %q", opts.Endpoint)
+	}
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: endpoint.Scheme == "https",
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+	}
+	return &Store{client: client, bucket: opts.Bucket, prefix: strings.Trim(opts.Prefix, "/")}, nil
+}
+
+// Upload stores the snapshot file at localPath as name, recording its checksum
+func (s *Store) Upload(ctx context.Context, name, localPath, checksum string) error {
+	_, err := s.client.FPutObject(ctx, s.bucket, s.key(name), localPath, minio.PutObjectOptions{
+		ContentType:  "application/octet-stream",
+		UserMetadata: map[string]string{checksumMeta: checksum},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to %s: %w", name, s.bucket, err)
+	}
+	return nil
+}
+
+// Download writes the snapshot name to localPath and returns the checksum recorded at upload
+func (s *Store) Download(ctx context.Context, name, localPath string) (string, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, s.key(name), minio.StatObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("snapshot %s not found in %s: %w", name, s.bucket, err)
+	}
+	if err := s.client.FGetObject(ctx, s.bucket, s.key(name), localPath, minio.GetObjectOptions{}); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	return info.UserMetadata[checksumMeta], nil
+}
+
+// List returns the stored snapshots, newest first
+func (s *Store) List(ctx context.Context) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix + "/"}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list snapshots in %s: %w", s.bucket, object.Err)
+		}
+		snapshot := Snapshot{Name: path.Base(object.Key), Size: object.Size, Created: object.LastModified}
+		if info, err := s.client.StatObject(ctx, s.bucket, object.Key, minio.StatObjectOptions{}); err == nil {
+			snapshot.SHA256 = info.UserMetadata[checksumMeta]
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Created.After(snapshots[j].Created) })
+	return snapshots, nil
+}
+
+// Prune removes all but the keep newest snapshots and returns the removed names
+func (s *Store) Prune(ctx context.Context, keep int) ([]string, error) {
+	snapshots, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for i := keep; i < len(snapshots); i++ {
+		if err := s.client.RemoveObject(ctx, s.bucket, s.key(snapshots[i].Name), minio.RemoveObjectOptions{}); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", snapshots[i].Name, err)
+		}
+		removed = append(removed, snapshots[i].Name)
+	}
+	return removed, nil
+}
+
+func (s *Store) key(name string) string {
+	return s.prefix + "/" + name
+}
+
+// Snapshot is an etcd snapshot stored in MinIO
+type Snapshot struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+	SHA256  string    `json:"sha256,omitempty"`
+}
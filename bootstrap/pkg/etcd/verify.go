@@ -0,0 +1,61 @@
+package etcd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// boltMagic opens the meta page of the bbolt database an etcd snapshot holds
+const boltMagic = 0xED0CDAED
+
+// boltMagicOffset skips the page header of the first meta page
+const boltMagicOffset = 16
+
+// Verify checks a snapshot is a bbolt database ending with the sha256 etcd appends to it, and returns the sha256 of the file
+func Verify(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat snapshot: %w", err)
+	}
+
+	// etcd itself only trusts the trailing hash when the database part is a multiple of 512 bytes
+	size := info.Size()
+	if size <= sha256.Size || size%512 != sha256.Size {
+		return "", fmt.Errorf("snapshot %s has no integrity hash (%d bytes)", path, size)
+	}
+
+	header := make([]byte, boltMagicOffset+4)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return "", fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+	if binary.LittleEndian.Uint32(header[boltMagicOffset:]) != boltMagic {
+		return "", fmt.Errorf("snapshot %s is not an etcd database", path)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	content, whole := sha256.New(), sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(content, whole), file, size-sha256.Size); err != nil {
+		return "", fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	appended := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(file, appended); err != nil {
+		return "", fmt.Errorf("failed to read snapshot hash: %w", err)
+	}
+	whole.Write(appended)
+	if !bytes.Equal(content.Sum(nil), appended) {
+		return "", fmt.Errorf("snapshot %s is corrupted, its content does not match the appended hash", path)
+	}
+	return hex.EncodeToString(whole.Sum(nil)), nil
+}
@@ -0,0 +1,260 @@
+package externalsecrets
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// Namespace is where the operator runs and the store token secret lives
+	Namespace = "external-secrets"
+	// DefaultStoreName is the ClusterSecretStore backed by the NAS Vault
+	DefaultStoreName = "nas-vault"
+	// DefaultKVMount is the KV v2 engine the store reads from
+	DefaultKVMount = "secret"
+
+	releaseName    = "external-secrets"
+	chartRepoURL   = "https://charts.external-secrets.io"
+	operatorImage  = "ghcr.io/external-secrets/external-secrets"
+	storeTokenKey  = "token"
+	fieldManager   = k8s.FieldManager
+	installTimeout = 5 * time.Minute
+)
+
+var (
+	groupVersion          = schema.GroupVersion{Group: "external-secrets.io", Version: "v1"}
+	clusterSecretStoreGVR = groupVersion.WithResource("clustersecretstores")
+	externalSecretGVR     = groupVersion.WithResource("externalsecrets")
+)
+
+// Client installs and configures the External Secrets Operator
+type Client struct {
+	k8sClient *k8s.Client
+}
+
+// NewClient creates a new External Secrets client
+func NewClient(k8sClient *k8s.Client) *Client {
+	return &Client{k8sClient: k8sClient}
+}
+
+// VaultStore describes a ClusterSecretStore reading a Vault KV v2 engine with a token
+type VaultStore struct {
+	Name    string
+	Server  string
+	KVMount string
+	Token   string
+}
+
+// Installed reports whether the cluster serves the External Secrets v1 API
+func (c *Client) Installed(ctx context.Context) (bool, error) {
+	resources, err := c.k8sClient.GetClientset().Discovery().ServerResourcesForGroupVersion(groupVersion.String())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to discover %s: %w", groupVersion, err)
+	}
+	served := map[string]bool{}
+	for _, resource := range resources.APIResources {
+		served[resource.Name] = true
+	}
+	return served[clusterSecretStoreGVR.Resource] && served[externalSecretGVR.Resource], nil
+}
+
+// Install deploys the operator chart with helm unless Flux already installed it
+func (c *Client) Install(ctx context.Context, version string) error {
+	installed, err := c.Installed(ctx)
+	if err != nil {
+		return err
+	}
+	if installed {
+		log.Info("External Secrets Operator already installed")
+		return nil
+	}
+
+	if _, err := exec.LookPath("helm"); err != nil {
+		return fmt.Errorf("helm CLI not found - install with: brew install helm")
+	}
+	if err := k8s.GuardMutation("helm install external-secrets"); err != nil {
+		return err
+	}
+
+	log.Info("Installing External Secrets Operator", "version", version)
+	chart := releaseName + "/external-secrets"
+	if airgap.Enabled() {
+		if chart, err = airgap.Chart("external-secrets", version); err != nil {
+			return err
+		}
+	} else {
+		addCmd := exec.CommandContext(ctx, "helm", "repo", "add", releaseName, chartRepoURL)
+		if output, err := addCmd.CombinedOutput(); err != nil && !strings.Contains(string(output), "already exists") {
+			return fmt.Errorf("failed to add helm repo: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		updateCmd := exec.CommandContext(ctx, "helm", "repo", "update", releaseName)
+		if output, err := updateCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to update helm repo: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	args := []string{"upgrade", "--install", releaseName, chart,
+		"--namespace", Namespace,
+		"--create-namespace",
+		"--set", "installCRDs=true",
+		"--wait",
+		"--timeout", installTimeout.String(),
+	}
+	if registry.Mirror() != "" {
+		// The controller, webhook and cert controller share one image
+		repository := registry.Rewrite(operatorImage)
+		for _, value := range []string{"image", "webhook.image", "certController.image"} {
+			args = append(args, "--set", value+".repository="+repository)
+		}
+	}
+	if version != "" {
+		args = append(args, "--version", version)
+	}
+	if output, err := exec.CommandContext(ctx, "helm", args...).CombinedOutput(); err != nil {
+		log.Error("External Secrets Helm install failed", "error", err, "output", string(output))
+		return fmt.Errorf("helm install failed: %w", err)
+	}
+
+	log.Info("External Secrets Operator installed")
+	return nil
+}
+
+// BundleChart is the operator chart at version, the latest when empty, for the air-gap bundle
+func BundleChart(version string) airgap.BundleChart {
+	return airgap.BundleChart{Name: "external-secrets", RepoURL: chartRepoURL, Version: version}
+}
+
+// CreateVaultStore stores the Vault token and applies a ClusterSecretStore using it
+func (c *Client) CreateVaultStore(ctx context.Context, store VaultStore) error {
+	if store.Server == "" || store.Token == "" {
+		return fmt.Errorf("vault address and token are required for ClusterSecretStore %s", store.Name)
+	}
+	if err := c.k8sClient.CreateNamespace(ctx, Namespace); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %w", Namespace, err)
+	}
+
+	tokenSecret := store.Name + "-token"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tokenSecret,
+			Namespace: Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			storeTokenKey: []byte(store.Token),
+		},
+	}
+	if err := c.k8sClient.CreateOrUpdateSecret(ctx, secret); err != nil {
+		return fmt.Errorf("failed to create %s secret: %w", tokenSecret, err)
+	}
+
+	log.Info("Applying ClusterSecretStore", "name", store.Name, "server", store.Server, "mount", store.KVMount)
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": groupVersion.String(),
+			"kind":       "ClusterSecretStore",
+			"metadata": map[string]interface{}{
+				"name": store.Name,
+			},
+			"spec": map[string]interface{}{
+				"provider": map[string]interface{}{
+					"vault": map[string]interface{}{
+						"server":  store.Server,
+						"path":    store.KVMount,
+						"version": "v2",
+						"auth": map[string]interface{}{
+							"tokenSecretRef": map[string]interface{}{
+								"name":      tokenSecret,
+								"namespace": Namespace,
+								"key":       storeTokenKey,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	_, err := c.k8sClient.GetDynamicClient().Resource(clusterSecretStoreGVR).Apply(ctx, store.Name, obj, metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply ClusterSecretStore %s: %w", store.Name, err)
+	}
+	return nil
+}
+
+// Validate checks the operator is running and the store can reach Vault
+func (c *Client) Validate(ctx context.Context, storeName string) error {
+	installed, err := c.Installed(ctx)
+	if err != nil {
+		return err
+	}
+	if !installed {
+		return fmt.Errorf("External Secrets CRDs are not installed")
+	}
+
+	deployment, err := c.k8sClient.GetClientset().AppsV1().Deployments(Namespace).Get(ctx, releaseName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get external-secrets deployment: %w", err)
+	}
+	if deployment.Status.ReadyReplicas == 0 {
+		return fmt.Errorf("external-secrets deployment has no ready replica")
+	}
+
+	return c.storeReady(ctx, storeName)
+}
+
+// WaitForStore waits until the ClusterSecretStore reports Ready
+func (c *Client) WaitForStore(ctx context.Context, storeName string, timeout time.Duration) error {
+	var lastErr error
+	err := k8s.Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		lastErr = c.storeReady(ctx, storeName)
+		return lastErr == nil, nil
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+// storeReady returns the reason a ClusterSecretStore is not Ready
+func (c *Client) storeReady(ctx context.Context, storeName string) error {
+	store, err := c.k8sClient.GetDynamicClient().Resource(clusterSecretStoreGVR).Get(ctx, storeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get ClusterSecretStore %s: %w", storeName, err)
+	}
+	return readyCondition(store, "ClusterSecretStore "+storeName)
+}
+
+// readyCondition returns an error unless obj has a Ready=True condition
+func readyCondition(obj *unstructured.Unstructured, description string) error {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		if condition["status"] == "True" {
+			return nil
+		}
+		return fmt.Errorf("%s not ready: %v", description, condition["message"])
+	}
+	return fmt.Errorf("%s has no Ready condition yet", description)
+}
@@ -0,0 +1,140 @@
+package externalsecrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Migration moves a Kubernetes secret into Vault and lets an ExternalSecret keep it in sync
+type Migration struct {
+	Namespace    string
+	Name         string
+	Store        VaultStore
+	RemoteKey    string // Path under the KV mount, e.g. clusters/homelab/cluster-vars
+	RefreshEvery time.Duration
+}
+
+// RemoteKeyFor returns the Vault path a cluster secret is migrated to
+func RemoteKeyFor(cluster, name string) string {
+	return fmt.Sprintf("clusters/%s/%s", cluster, name)
+}
+
+// MigrateSecret writes the secret data to Vault and applies the ExternalSecret that owns it from now on
+func (c *Client) MigrateSecret(ctx context.Context, m Migration) error {
+	secret, err := c.k8sClient.GetSecret(ctx, m.Namespace, m.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read secret %s/%s: %w", m.Namespace, m.Name, err)
+	}
+	if err := k8s.GuardMutation("write " + m.RemoteKey + " to Vault"); err != nil {
+		return err
+	}
+
+	data := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = string(value)
+	}
+	log.Info("Writing secret to Vault", "secret", m.Namespace+"/"+m.Name, "path", m.Store.KVMount+"/"+m.RemoteKey, "keys", len(data))
+	if err := writeKV(ctx, m.Store, m.RemoteKey, data); err != nil {
+		return err
+	}
+
+	refresh := m.RefreshEvery
+	if refresh == 0 {
+		refresh = time.Hour
+	}
+	// Merge keeps the secret in place, so workloads and Kustomizations substituting it never see it disappear
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": groupVersion.String(),
+			"kind":       "ExternalSecret",
+			"metadata": map[string]interface{}{
+				"name":      m.Name,
+				"namespace": m.Namespace,
+			},
+			"spec": map[string]interface{}{
+				"refreshInterval": refresh.String(),
+				"secretStoreRef": map[string]interface{}{
+					"kind": "ClusterSecretStore",
+					"name": m.Store.Name,
+				},
+				"target": map[string]interface{}{
+					"name":           m.Name,
+					"creationPolicy": "Merge",
+				},
+				"dataFrom": []interface{}{
+					map[string]interface{}{
+						"extract": map[string]interface{}{
+							"key": m.RemoteKey,
+						},
+					},
+				},
+			},
+		},
+	}
+	log.Info("Applying ExternalSecret", "namespace", m.Namespace, "name", m.Name, "store", m.Store.Name)
+	_, err = c.k8sClient.GetDynamicClient().Resource(externalSecretGVR).Namespace(m.Namespace).Apply(ctx, m.Name, obj, metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply ExternalSecret %s/%s: %w", m.Namespace, m.Name, err)
+	}
+	return nil
+}
+
+// WaitForExternalSecret waits until an ExternalSecret reports it synced its target
+func (c *Client) WaitForExternalSecret(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	var lastErr error
+	err := k8s.Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		obj, err := c.k8sClient.GetDynamicClient().Resource(externalSecretGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			lastErr = fmt.Errorf("failed to get ExternalSecret %s/%s: %w", namespace, name, err)
+			return false, nil
+		}
+		lastErr = readyCondition(obj, "ExternalSecret "+namespace+"/"+name)
+		return lastErr == nil, nil
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+// writeKV stores data as the latest version of a KV v2 secret
+func writeKV(ctx context.Context, store VaultStore, key string, data map[string]string) error {
+	body, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return fmt.Errorf("failed to encode Vault payload: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(store.Server, "/"), strings.Trim(store.KVMount, "/"), strings.Trim(key, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", store.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("Vault not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to write %s to Vault: %s: %s", key, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
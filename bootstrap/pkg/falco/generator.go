@@ -0,0 +1,191 @@
+package falco
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+)
+
+const (
+	// Namespace is where Falco and Falcosidekick run
+	Namespace = "falco"
+
+	// RoutingSecret holds the alert webhook consumed by the Falcosidekick HelmRelease
+	RoutingSecret = "falco-alert-routing"
+
+	// RoutingSecretKey is the key of the webhook address in RoutingSecret
+	RoutingSecretKey = "webhook"
+)
+
+// Supported driver kinds
+const (
+	DriverModernEBPF = "modern_ebpf"
+	DriverEBPF       = "ebpf"
+	DriverKmod       = "kmod"
+)
+
+var chart = gitops.HelmChart{
+	Repository: "falcosecurity",
+	URL:        "https://falcosecurity.github.io/charts",
+	Chart:      "falco",
+	Version:    "6.2.2",
+	Namespace:  Namespace,
+}
+
+// containerdSockets maps each cluster to the containerd socket of its distribution
+var containerdSockets = map[string]string{
+	"homelab": "/run/containerd/containerd.sock",
+	"nas":     "/run/k3s/containerd/containerd.sock",
+}
+
+// homelabRules tunes the default ruleset for components that legitimately need host access
+const homelabRules = `- list: homelab_infrastructure_namespaces
+  items: [kube-system, rook-ceph, istio-system, flux-system, falco, kyverno, gatekeeper-system, vault]
+
+- rule: Terminal shell in container
+  condition: and not k8s.ns.name in (homelab_infrastructure_namespaces)
+  override:
+    condition: append
+
+- rule: Launch Privileged Container
+  condition: and not k8s.ns.name in (homelab_infrastructure_namespaces)
+  override:
+    condition: append
+
+- rule: Homelab Vault token read outside Vault
+  desc: A process outside the vault namespace read a file named like a Vault token
+  condition: >
+    open_read and container and fd.name endswith "vault-token"
+    and not k8s.ns.name = vault
+  output: Vault token read outside Vault (file=%fd.name process=%proc.name pod=%k8s.pod.name ns=%k8s.ns.name)
+  priority: WARNING
+  tags: [homelab, credentials]
+`
+
+// Options configures the generated Falco deployment
+type Options struct {
+	Driver   string
+	Priority string
+}
+
+// Generator writes the Falco deployment into the GitOps repository
+type Generator struct {
+	projectRoot string
+	cluster     string
+	opts        Options
+}
+
+// NewGenerator creates a new generator for cluster under projectRoot
+func NewGenerator(projectRoot, cluster string, opts Options) *Generator {
+	if opts.Driver == "" {
+		opts.Driver = DriverModernEBPF
+	}
+	if opts.Priority == "" {
+		opts.Priority = "warning"
+	}
+	return &Generator{
+		projectRoot: projectRoot,
+		cluster:     cluster,
+		opts:        opts,
+	}
+}
+
+// Generate writes the Falco manifests and returns the paths it wrote, relative to the project root
+func (g *Generator) Generate() ([]string, error) {
+	switch g.opts.Driver {
+	case DriverModernEBPF, DriverEBPF, DriverKmod:
+	default:
+		return nil, fmt.Errorf("unknown Falco driver %q (expected modern_ebpf, ebpf or kmod)", g.opts.Driver)
+	}
+	if _, ok := priorityRank[strings.ToLower(g.opts.Priority)]; !ok {
+		return nil, fmt.Errorf("unknown Falco priority %q", g.opts.Priority)
+	}
+
+	clusterDir := filepath.Join(g.projectRoot, "kubernetes", g.cluster)
+	if _, err := os.Stat(clusterDir); err != nil {
+		return nil, fmt.Errorf("GitOps directory for %s not found: %w", g.cluster, err)
+	}
+
+	falcoDir := filepath.Join(clusterDir, "falco")
+	files := map[string]string{
+		filepath.Join(falcoDir, "helm-repository.yaml"): chart.RenderRepository(),
+		filepath.Join(falcoDir, "helm-release.yaml"):    chart.RenderRelease(g.releaseSpec()),
+		filepath.Join(falcoDir, "kustomization.yaml"):   gitops.RenderKustomization([]string{"helm-repository.yaml", "helm-release.yaml"}),
+		filepath.Join(clusterDir, "falco.yaml"): gitops.RenderKustomizations(g.cluster, []gitops.Kustomization{{
+			Name:      gitops.Name(g.cluster, "falco"),
+			Path:      "falco",
+			DependsOn: gitops.FoundationKustomization(g.cluster),
+		}}),
+	}
+
+	written, err := gitops.WriteFiles(g.projectRoot, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rootKustomization := filepath.Join(clusterDir, "kustomization.yaml")
+	added, err := gitops.AddResource(rootKustomization, "falco.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		written = append(written, gitops.Relative(g.projectRoot, rootKustomization))
+	}
+
+	log.Info("Generated Falco deployment",
+		"cluster", g.cluster,
+		"driver", g.opts.Driver,
+		"priority", g.opts.Priority,
+		"files", len(written))
+
+	return written, nil
+}
+
+// releaseSpec renders the values of the Falco HelmRelease
+func (g *Generator) releaseSpec() string {
+	priority := strings.ToLower(g.opts.Priority)
+	return fmt.Sprintf(`valuesFrom:
+  - kind: Secret
+    name: %s
+    valuesKey: %s
+    targetPath: falcosidekick.config.webhook.address
+    optional: true
+values:
+  driver:
+    kind: %s
+  tty: true
+  collectors:
+    containerd:
+      enabled: true
+      socket: %s
+  falco:
+    json_output: true
+    json_include_output_property: true
+    priority: %s
+  resources:
+    requests:
+      cpu: 100m
+      memory: 256Mi
+    limits:
+      memory: 1Gi
+  falcosidekick:
+    enabled: true
+    replicaCount: 1
+    config:
+      webhook:
+        minimumpriority: %s
+    resources:
+      requests:
+        cpu: 10m
+        memory: 32Mi
+      limits:
+        memory: 128Mi
+  customRules:
+    homelab-rules.yaml: |
+%s
+`, RoutingSecret, RoutingSecretKey, g.opts.Driver, containerdSockets[g.cluster], priority, priority, gitops.Indent(strings.TrimRight(homelabRules, "\n"), 6))
+}
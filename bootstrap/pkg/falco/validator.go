@@ -0,0 +1,241 @@
+package falco
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	podSelector   = "app.kubernetes.io/name=falco"
+	containerName = "falco"
+)
+
+// priorityRank orders Falco priorities from most to least severe
+var priorityRank = map[string]int{
+	"emergency":     0,
+	"alert":         1,
+	"critical":      2,
+	"error":         3,
+	"warning":       4,
+	"notice":        5,
+	"informational": 6,
+	"info":          6,
+	"debug":         7,
+}
+
+// driverErrors are log fragments printed by Falco when its driver cannot be loaded
+var driverErrors = []string{
+	"unable to load the driver",
+	"failed to load",
+	"error opening device",
+	"unable to open",
+	"bpf_probe",
+	"kernel module",
+	"scap_init",
+}
+
+// DriverStatus is the Falco driver state on a single node
+type DriverStatus struct {
+	Node    string
+	Kernel  string
+	OSImage string
+	Pod     string
+	Ready   bool
+	Error   string
+}
+
+// Alert is a Falco alert parsed from the JSON output
+type Alert struct {
+	Time     time.Time `json:"time"`
+	Priority string    `json:"priority"`
+	Rule     string    `json:"rule"`
+	Output   string    `json:"output"`
+	Source   string    `json:"source"`
+	Hostname string    `json:"hostname"`
+}
+
+// Validator checks the Falco deployment on a cluster
+type Validator struct {
+	client *k8s.Client
+}
+
+// NewValidator creates a new Falco validator
+func NewValidator(client *k8s.Client) *Validator {
+	return &Validator{
+		client: client,
+	}
+}
+
+// ValidateDriver checks that the Falco driver loaded on every node
+func (v *Validator) ValidateDriver(ctx context.Context) ([]DriverStatus, error) {
+	clientset := v.client.GetClientset()
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods(Namespace).List(ctx, metav1.ListOptions{LabelSelector: podSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Falco pods: %w", err)
+	}
+
+	podsByNode := make(map[string]corev1.Pod, len(pods.Items))
+	for _, pod := range pods.Items {
+		podsByNode[pod.Spec.NodeName] = pod
+	}
+
+	statuses := make([]DriverStatus, 0, len(nodes.Items))
+	var failed []string
+	for _, node := range nodes.Items {
+		status := DriverStatus{
+			Node:    node.Name,
+			Kernel:  node.Status.NodeInfo.KernelVersion,
+			OSImage: node.Status.NodeInfo.OSImage,
+		}
+
+		pod, ok := podsByNode[node.Name]
+		switch {
+		case !ok:
+			status.Error = "no Falco pod scheduled"
+		case containerReady(pod):
+			status.Pod = pod.Name
+			status.Ready = true
+		default:
+			status.Pod = pod.Name
+			status.Error = v.driverError(ctx, pod)
+		}
+
+		if !status.Ready {
+			failed = append(failed, fmt.Sprintf("%s (%s)", status.Node, status.Error))
+			log.Warn("Falco driver not loaded",
+				"node", status.Node,
+				"kernel", status.Kernel,
+				"os", status.OSImage,
+				"error", status.Error,
+				"hint", driverHint(status.OSImage))
+		}
+		statuses = append(statuses, status)
+	}
+
+	if len(failed) > 0 {
+		return statuses, fmt.Errorf("Falco driver not loaded on %d/%d nodes: %s", len(failed), len(statuses), strings.Join(failed, ", "))
+	}
+	log.Info("Falco driver loaded on all nodes", "nodes", len(statuses))
+	return statuses, nil
+}
+
+// RecentAlerts returns the alerts emitted since the given duration with at least minPriority
+func (v *Validator) RecentAlerts(ctx context.Context, since time.Duration, minPriority string) ([]Alert, error) {
+	threshold, ok := priorityRank[strings.ToLower(minPriority)]
+	if !ok {
+		return nil, fmt.Errorf("unknown Falco priority %q", minPriority)
+	}
+
+	clientset := v.client.GetClientset()
+	pods, err := clientset.CoreV1().Pods(Namespace).List(ctx, metav1.ListOptions{LabelSelector: podSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Falco pods: %w", err)
+	}
+
+	sinceSeconds := int64(since.Seconds())
+	var alerts []Alert
+	for _, pod := range pods.Items {
+		logs, err := clientset.CoreV1().Pods(Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container:    containerName,
+			SinceSeconds: &sinceSeconds,
+		}).DoRaw(ctx)
+		if err != nil {
+			log.Warn("Failed to read Falco logs", "pod", pod.Name, "error", err)
+			continue
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(logs))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 || line[0] != '{' {
+				continue
+			}
+			var alert Alert
+			if err := json.Unmarshal(line, &alert); err != nil || alert.Rule == "" {
+				continue
+			}
+			if rank, ok := priorityRank[strings.ToLower(alert.Priority)]; !ok || rank > threshold {
+				continue
+			}
+			if alert.Hostname == "" {
+				alert.Hostname = pod.Spec.NodeName
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Time.Before(alerts[j].Time)
+	})
+	return alerts, nil
+}
+
+// driverError extracts the driver failure from the logs of a Falco pod that is not ready
+func (v *Validator) driverError(ctx context.Context, pod corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName && status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			if status.State.Waiting.Reason != "CrashLoopBackOff" {
+				return status.State.Waiting.Reason
+			}
+		}
+	}
+
+	tail := int64(200)
+	logs, err := v.client.GetClientset().CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: containerName,
+		TailLines: &tail,
+	}).DoRaw(ctx)
+	if err != nil {
+		return fmt.Sprintf("pod %s not ready", pod.Name)
+	}
+
+	lines := strings.Split(string(logs), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		lower := strings.ToLower(lines[i])
+		for _, fragment := range driverErrors {
+			if strings.Contains(lower, fragment) {
+				return strings.TrimSpace(lines[i])
+			}
+		}
+	}
+	return fmt.Sprintf("pod %s not ready", pod.Name)
+}
+
+func containerReady(pod corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName {
+			return status.Ready
+		}
+	}
+	return false
+}
+
+// driverHint suggests a driver for the node operating system
+func driverHint(osImage string) string {
+	lower := strings.ToLower(osImage)
+	switch {
+	case strings.Contains(lower, "talos"):
+		return "Talos does not allow loading kernel modules; use the modern_ebpf driver"
+	case strings.Contains(lower, "k3s"):
+		return "kmod requires kernel headers on the host; prefer modern_ebpf on kernels >= 5.8"
+	default:
+		return "modern_ebpf requires kernel >= 5.8 with BTF; fall back to ebpf or kmod on older kernels"
+	}
+}
@@ -0,0 +1,59 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Adoption is what adopting an existing Flux installation found, by apply set
+type Adoption struct {
+	// Version is the installed Flux release, empty when Flux is not installed
+	Version string                      `json:"version,omitempty"`
+	Sets    map[string]*k8s.AdoptResult `json:"sets,omitempty"`
+}
+
+// Adopt takes over a Flux installation the bootstrap did not create: the controllers are rendered at the
+// installed release rather than the pinned one, and every rendered object that exists is recorded in its apply
+// set with its fields handed to the bootstrap field manager. Nothing is applied, the objects keep their values.
+func (c *Client) Adopt(ctx context.Context, namespace, clusterType string, dryRun bool) (*Adoption, error) {
+	ns, err := c.k8sClient.GetClientset().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &Adoption{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read namespace %s: %w", namespace, err)
+	}
+	adoption := &Adoption{Version: ns.Labels[versionLabel], Sets: map[string]*k8s.AdoptResult{}}
+	if adoption.Version == "" {
+		return nil, fmt.Errorf("namespace %s carries no %s label, the installed Flux release is unknown", namespace, versionLabel)
+	}
+
+	manifest, err := installManifest(namespace, adoption.Version)
+	if err != nil {
+		return nil, err
+	}
+	sets := []struct {
+		name     string
+		manifest string
+	}{
+		{setInstall, manifest},
+		{setSync, c.generateSyncManifests(namespace)},
+		{setPlatformFoundation, c.platformFoundationManifest(namespace, clusterType)},
+	}
+	for _, set := range sets {
+		objects, err := decodeObjects(set.manifest)
+		if err != nil {
+			return nil, err
+		}
+		result, err := c.k8sClient.AdoptSet(ctx, set.name, objects, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("failed to adopt %s: %w", set.name, err)
+		}
+		adoption.Sets[set.name] = result
+	}
+	return adoption, nil
+}
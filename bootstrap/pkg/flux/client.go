@@ -7,20 +7,15 @@ import (
 	"time"
 
 	"github.com/charmbracelet/log"
-	"github.com/fluxcd/flux2/v2/pkg/manifestgen/install"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
-	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/util/yaml"
-	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/restmapper"
 )
 
 // Client handles FluxCD operations
@@ -64,30 +59,14 @@ func (c *Client) Install(ctx context.Context, namespace string) error {
 
 	// Use Flux Go library for installation
 	log.Info("Generating FluxCD install manifests")
-
-	// Create install options with proper defaults
-	opts := install.MakeDefaultOptions()
-	opts.Namespace = namespace
-	opts.Components = []string{
-		"source-controller",
-		"kustomize-controller",
-		"helm-controller",
-		"notification-controller",
-	}
-	opts.ComponentsExtra = []string{
-		"image-reflector-controller",
-		"image-automation-controller",
-	}
-
-	// Generate manifests
-	manifest, err := install.Generate(opts, "")
+	manifest, err := installManifest(namespace, c.fluxVersion())
 	if err != nil {
-		return fmt.Errorf("failed to generate flux install manifests: %w", err)
+		return err
 	}
 
 	// Apply manifests using server-side apply
 	log.Info("Applying FluxCD manifests")
-	if err := c.applyManifests(ctx, []byte(manifest.Content)); err != nil {
+	if err := c.applyManifests(ctx, setInstall, []byte(manifest)); err != nil {
 		return fmt.Errorf("failed to apply flux manifests: %w", err)
 	}
 
@@ -95,7 +74,7 @@ func (c *Client) Install(ctx context.Context, namespace string) error {
 
 	// Wait for controllers to be ready
 	if err := c.WaitForInstallation(ctx, namespace, 5*time.Minute); err != nil {
-		return fmt.Errorf("flux controllers not ready: %w", err)
+		return fmt.Errorf("flux controllers not ready, run bootstrap preflight connectivity to rule out proxy or clock problems: %w", err)
 	}
 
 	log.Info("FluxCD installation completed successfully")
@@ -104,7 +83,7 @@ func (c *Client) Install(ctx context.Context, namespace string) error {
 
 // Bootstrap configures FluxCD to sync with a Git repository using Flux Go library
 func (c *Client) Bootstrap(ctx context.Context, namespace string) error {
-	log.Info("Bootstrapping FluxCD with GitOps repository", "repository", c.config.Repository, "branch", c.config.Branch, "path", c.config.Path)
+	log.Info("Bootstrapping FluxCD with GitOps repository", "source", c.sourceKind(), "repository", c.config.Repository, "branch", c.config.Branch, "path", c.config.Path)
 
 	// Ensure Flux is installed first
 	if err := c.WaitForInstallation(ctx, namespace, 5*time.Minute); err != nil {
@@ -119,18 +98,16 @@ func (c *Client) Bootstrap(ctx context.Context, namespace string) error {
 
 	// Apply sync manifests
 	log.Info("Applying GitOps sync manifests")
-	if err := c.applyManifests(ctx, []byte(manifestContent)); err != nil {
+	if err := c.applyManifests(ctx, setSync, []byte(manifestContent)); err != nil {
 		return fmt.Errorf("failed to apply sync manifests: %w", err)
 	}
 
 	log.Debug("Sync manifests applied successfully")
 
-	// Create GitHub token secret if provided
-	if c.config.Token != "" {
-		if err := c.createGitHubTokenSecret(ctx, namespace); err != nil {
-			log.Warn("Failed to create GitHub token secret", "error", err)
-			// Continue - the sync might work without the secret for public repos
-		}
+	// Create GitHub token, registry pull or bucket secret if credentials are provided
+	if err := c.createSourceSecret(ctx, namespace); err != nil {
+		log.Warn("Failed to create source credentials secret", "error", err)
+		// Continue - the sync might work without the secret for public repos
 	}
 
 	// Wait for initial sync
@@ -145,8 +122,12 @@ func (c *Client) Bootstrap(ctx context.Context, namespace string) error {
 // BootstrapPlatformFoundation creates the platform-foundation Kustomization
 func (c *Client) BootstrapPlatformFoundation(ctx context.Context, namespace string, clusterType string) error {
 	log.Info("Creating platform-foundation Kustomization", "cluster", clusterType)
+	return c.applyManifests(ctx, setPlatformFoundation, []byte(c.platformFoundationManifest(namespace, clusterType)))
+}
 
-	manifest := fmt.Sprintf(`---
+// platformFoundationManifest renders the Kustomization deploying the platform foundation of clusterType
+func (c *Client) platformFoundationManifest(namespace, clusterType string) string {
+	return fmt.Sprintf(`---
 apiVersion: kustomize.toolkit.fluxcd.io/v1
 kind: Kustomization
 metadata:
@@ -157,13 +138,11 @@ spec:
   path: ./kubernetes/%s/platform-foundation
   prune: true
   sourceRef:
-    kind: GitRepository
+    kind: %s
     name: flux-system
   timeout: 5m0s
   wait: true
-`, clusterType, namespace, clusterType)
-
-	return c.applyManifests(ctx, []byte(manifest))
+`, clusterType, namespace, clusterType, c.sourceKind())
 }
 
 // createGitHubTokenSecret creates a secret for GitHub authentication
@@ -214,148 +193,52 @@ func (c *Client) WaitForInstallation(ctx context.Context, namespace string, time
 	return nil
 }
 
-// WaitForSync waits for GitRepository to be ready and synced
+// WaitForSync waits for the GitRepository, OCIRepository or Bucket to be ready and synced
 func (c *Client) WaitForSync(ctx context.Context, namespace, name string, timeout time.Duration) error {
-	log.Info("Waiting for GitRepository sync", "namespace", namespace, "name", name, "timeout", timeout)
-
-	return wait.PollImmediate(10*time.Second, timeout, func() (bool, error) {
-		log.Debug("Polling GitRepository status", "namespace", namespace, "name", name)
+	log.Info("Waiting for source sync", "kind", c.sourceKind(), "namespace", namespace, "name", name, "timeout", timeout)
 
-		// Get the GitRepository resource
-		dynamicClient := c.k8sClient.GetDynamicClient()
-		gvr := schema.GroupVersionResource{
-			Group:    "source.toolkit.fluxcd.io",
-			Version:  "v1",
-			Resource: "gitrepositories",
-		}
-
-		log.Debug("Attempting to get GitRepository", "gvr", gvr)
-		gitRepo, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
-			log.Debug("GitRepository not found yet", "error", err, "namespace", namespace, "name", name)
-			return false, nil // Not ready yet, continue waiting
-		}
-
-		log.Debug("GitRepository found, checking status", "name", name)
-
-		// Check status conditions
-		status, found, err := unstructured.NestedMap(gitRepo.Object, "status")
-		if err != nil || !found {
-			log.Debug("GitRepository status not available yet", "found", found, "error", err)
+	var last condition
+	err := c.k8sClient.WaitForObject(ctx, SourceGVR(c.config), namespace, name, timeout, func(source *unstructured.Unstructured) (bool, error) {
+		ready, found := readCondition(source, "Ready")
+		if !found {
+			log.Debug("Source conditions not available yet", "name", name)
 			return false, nil
 		}
-
-		log.Debug("GitRepository status found", "status", status)
-
-		conditions, found, err := unstructured.NestedSlice(status, "conditions")
-		if err != nil || !found {
-			log.Debug("GitRepository conditions not available yet", "found", found, "error", err)
-			return false, nil
+		if ready.status == "True" {
+			log.Info("Source is ready and synced", "kind", c.sourceKind(), "name", name)
+			return true, nil
 		}
-
-		log.Debug("GitRepository conditions found", "count", len(conditions))
-
-		// Check for Ready condition
-		for i, conditionRaw := range conditions {
-			log.Debug("Checking condition", "index", i, "condition", conditionRaw)
-
-			condition, ok := conditionRaw.(map[string]interface{})
-			if !ok {
-				log.Debug("Condition is not a map", "index", i, "type", fmt.Sprintf("%T", conditionRaw))
-				continue
-			}
-
-			condType, found, err := unstructured.NestedString(condition, "type")
-			if err != nil || !found {
-				log.Debug("Condition type not found", "index", i, "error", err, "found", found)
-				continue
-			}
-
-			log.Debug("Found condition", "type", condType, "index", i)
-
-			if condType != "Ready" {
-				continue
-			}
-
-			condStatus, found, err := unstructured.NestedString(condition, "status")
-			if err != nil || !found {
-				log.Debug("Condition status not found", "error", err, "found", found)
-				continue
-			}
-
-			log.Debug("Ready condition found", "status", condStatus)
-
-			if condStatus == "True" {
-				log.Info("GitRepository is ready and synced")
-				return true, nil
-			}
-
-			// Log the reason if not ready
-			reason, _, _ := unstructured.NestedString(condition, "reason")
-			message, _, _ := unstructured.NestedString(condition, "message")
-			log.Debug("GitRepository not ready yet", "reason", reason, "message", message, "status", condStatus)
+		if ready != last {
+			log.Debug("Source not ready yet", "reason", ready.reason, "message", ready.message, "status", ready.status)
 		}
-
-		return false, nil // Not ready yet
+		last = ready
+		return false, nil
 	})
+	if err != nil && last.message != "" {
+		return fmt.Errorf("%w: %s", err, last.message)
+	}
+	return err
 }
 
 // WaitForKustomization waits for a Kustomization to be ready
 func (c *Client) WaitForKustomization(ctx context.Context, namespace, name string, timeout time.Duration) error {
 	log.Info("Waiting for Kustomization", "namespace", namespace, "name", name, "timeout", timeout)
 
-	return wait.PollImmediate(10*time.Second, timeout, func() (bool, error) {
-		dynamicClient := c.k8sClient.GetDynamicClient()
-		gvr := schema.GroupVersionResource{
-			Group:    "kustomize.toolkit.fluxcd.io",
-			Version:  "v1",
-			Resource: "kustomizations",
-		}
-
-		kustomization, err := dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
-			log.Debug("Kustomization not found yet", "error", err)
-			return false, nil
-		}
-
-		// Check status
-		status, found, _ := unstructured.NestedMap(kustomization.Object, "status")
+	return c.k8sClient.WaitForObject(ctx, kustomizationGVR, namespace, name, timeout, func(kustomization *unstructured.Unstructured) (bool, error) {
+		ready, found := readCondition(kustomization, "Ready")
 		if !found {
-			log.Debug("Kustomization status not available yet")
-			return false, nil
-		}
-
-		// Check conditions
-		conditions, found, _ := unstructured.NestedSlice(status, "conditions")
-		if !found || len(conditions) == 0 {
 			log.Debug("Kustomization conditions not available yet")
 			return false, nil
 		}
-
-		// Look for Ready condition
-		for _, conditionRaw := range conditions {
-			condition, ok := conditionRaw.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			if condType, _ := condition["type"].(string); condType == "Ready" {
-				condStatus, _ := condition["status"].(string)
-				reason, _ := condition["reason"].(string)
-				message, _ := condition["message"].(string)
-
-				if condStatus == "True" {
-					log.Info("Kustomization is ready")
-					return true, nil
-				} else if reason == "ReconciliationFailed" || reason == "BuildFailed" {
-					// Fail fast on known error conditions
-					return false, fmt.Errorf("kustomization failed: %s - %s", reason, message)
-				}
-
-				log.Debug("Kustomization not ready", "status", condStatus, "reason", reason, "message", message)
-			}
+		if ready.status == "True" {
+			log.Info("Kustomization is ready")
+			return true, nil
 		}
-
+		if ready.reason == "ReconciliationFailed" || ready.reason == "BuildFailed" {
+			// Fail fast on known error conditions
+			return false, fmt.Errorf("kustomization failed: %s - %s", ready.reason, ready.message)
+		}
+		log.Debug("Kustomization not ready", "status", ready.status, "reason", ready.reason, "message", ready.message)
 		return false, nil
 	})
 }
@@ -407,22 +290,54 @@ type SyncStatus struct {
 	Path     string     `json:"path,omitempty"`
 }
 
-// Reconcile forces a reconciliation of the GitRepository
-func (c *Client) Reconcile(ctx context.Context, namespace, name string) error {
-	// This would annotate the GitRepository to force reconciliation
-	log.Info("Reconciling GitRepository", "namespace", namespace, "name", name)
+// Reconcile requests an immediate reconciliation of a Flux resource
+func (c *Client) Reconcile(ctx context.Context, kind, namespace, name string) error {
+	resource, kind, err := c.resourceFor(kind, namespace)
+	if err != nil {
+		return err
+	}
+
+	obj, err := resource.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %s %s/%s: %w", kind, namespace, name, err)
+	}
+	if suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend"); suspended {
+		return fmt.Errorf("%s %s/%s is suspended, resume it first", kind, namespace, name)
+	}
+
+	log.Info("Reconciling resource", "kind", kind, "namespace", namespace, "name", name)
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{"reconcile.fluxcd.io/requestedAt":"%s"}}}`, time.Now().Format(time.RFC3339Nano))
+	if _, err := resource.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to reconcile %s %s/%s: %w", kind, namespace, name, err)
+	}
 	return nil
 }
 
-// Suspend suspends GitOps synchronization
-func (c *Client) Suspend(ctx context.Context, namespace, name string) error {
-	log.Info("Suspending GitRepository", "namespace", namespace, "name", name)
-	return nil
+// Suspend stops Flux from reconciling a resource, the workloads it manages keep running
+func (c *Client) Suspend(ctx context.Context, kind, namespace, name string) error {
+	log.Info("Suspending resource", "kind", kind, "namespace", namespace, "name", name)
+	return c.setSuspend(ctx, kind, namespace, name, true)
 }
 
-// Resume resumes GitOps synchronization
-func (c *Client) Resume(ctx context.Context, namespace, name string) error {
-	log.Info("Resuming GitRepository", "namespace", namespace, "name", name)
+// Resume lets Flux reconcile a suspended resource again and requests an immediate reconciliation
+func (c *Client) Resume(ctx context.Context, kind, namespace, name string) error {
+	log.Info("Resuming resource", "kind", kind, "namespace", namespace, "name", name)
+	if err := c.setSuspend(ctx, kind, namespace, name, false); err != nil {
+		return err
+	}
+	return c.Reconcile(ctx, kind, namespace, name)
+}
+
+func (c *Client) setSuspend(ctx context.Context, kind, namespace, name string, suspend bool) error {
+	resource, kind, err := c.resourceFor(kind, namespace)
+	if err != nil {
+		return err
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
+	if _, err := resource.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch %s %s/%s: %w", kind, namespace, name, err)
+	}
 	return nil
 }
 
@@ -510,19 +425,28 @@ func (c *Client) ResumeReconciliation(ctx context.Context, namespace string) err
 	return nil
 }
 
-// applyManifests applies YAML manifests to the cluster using server-side apply
-func (c *Client) applyManifests(ctx context.Context, manifestsContent []byte) error {
-	log.Debug("Applying manifests to cluster", "size", len(manifestsContent), "content", string(manifestsContent))
+// Apply sets of the manifests the bootstrap generates, each pruning what it no longer renders
+const (
+	setInstall            = "flux-install"
+	setSync               = "flux-sync"
+	setPlatformFoundation = "flux-platform-foundation"
+	setImageAutomation    = "flux-image-automation"
+)
+
+// applyManifests applies YAML manifests to the cluster using server-side apply as the named set,
+// pruning the objects the set applied before and the manifests no longer contain
+func (c *Client) applyManifests(ctx context.Context, set string, manifestsContent []byte) error {
+	log.Debug("Applying manifests to cluster", "set", set, "size", len(manifestsContent), "content", string(manifestsContent))
 
 	// Parse the YAML manifests
 	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(string(manifestsContent)), 4096)
 
-	objectCount := 0
+	var objs []*unstructured.Unstructured
 	for {
 		var obj unstructured.Unstructured
 		if err := decoder.Decode(&obj); err != nil {
 			if err.Error() == "EOF" {
-				log.Debug("Finished decoding manifests", "totalObjects", objectCount)
+				log.Debug("Finished decoding manifests", "totalObjects", len(objs))
 				break
 			}
 			log.Error("Failed to decode manifest", "error", err, "content", string(manifestsContent))
@@ -534,95 +458,32 @@ func (c *Client) applyManifests(ctx context.Context, manifestsContent []byte) er
 			continue // Skip empty objects
 		}
 
-		objectCount++
-		log.Debug("Applying object", "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace(), "count", objectCount)
-
-		// Apply the object using server-side apply
-		if err := c.applyObject(ctx, &obj); err != nil {
-			log.Error("Failed to apply object", "kind", obj.GetKind(), "name", obj.GetName(), "error", err)
-			return fmt.Errorf("failed to apply object %s/%s: %w", obj.GetKind(), obj.GetName(), err)
-		}
-
-		log.Debug("Successfully applied object", "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+		// Set managed fields for server-side apply
+		obj.SetManagedFields(nil)
+		objs = append(objs, &obj)
 	}
 
-	return nil
-}
-
-// applyObject applies a single unstructured object using server-side apply
-func (c *Client) applyObject(ctx context.Context, obj *unstructured.Unstructured) error {
-	// Get dynamic client
-	dynamicClient := c.k8sClient.GetDynamicClient()
-
-	// Determine the resource interface
-	gvk := obj.GroupVersionKind()
-	gvr, err := c.gvkToGVR(gvk)
-	if err != nil {
-		return fmt.Errorf("failed to get GVR for %s: %w", gvk, err)
-	}
-
-	namespacedResource := dynamicClient.Resource(gvr)
-
-	// Handle namespaced vs cluster-scoped resources
-	var resourceInterface dynamic.ResourceInterface
-	if obj.GetNamespace() != "" {
-		resourceInterface = namespacedResource.Namespace(obj.GetNamespace())
-	} else {
-		resourceInterface = namespacedResource
-	}
-
-	// Set managed fields for server-side apply
-	obj.SetManagedFields(nil)
-
 	// Apply with server-side apply
 	// Note: Force:true is used during bootstrap to take ownership of Flux resources
 	// before the Flux controllers start. Once Flux controllers are running, they
 	// will take ownership using their own field manager. This ensures bootstrap
 	// can install Flux even on existing clusters with partial Flux installations.
-	applyOptions := metav1.ApplyOptions{
-		FieldManager: "homelab-bootstrap",
-		Force:        true,
-	}
-
-	_, err = resourceInterface.Apply(ctx, obj.GetName(), obj, applyOptions)
-	return err
-}
-
-// gvkToGVR converts GroupVersionKind to GroupVersionResource with retry logic for CRD discovery
-func (c *Client) gvkToGVR(gvk schema.GroupVersionKind) (schema.GroupVersionResource, error) {
-	// Create discovery client
-	discoveryClient := c.k8sClient.GetClientset().Discovery()
-
-	// Create REST mapper with memory cache
-	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
-
-	// Retry logic for CRD discovery - newly applied CRDs may not be immediately available
-	var mapping *meta.RESTMapping
-	var err error
-
-	err = wait.PollImmediate(2*time.Second, 30*time.Second, func() (bool, error) {
-		// Convert GVK to GVR
-		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
-		if err != nil {
-			// Check if this is a "no matches" error that might resolve after CRD registration
-			if meta.IsNoMatchError(err) {
-				log.Debug("GVK not found in discovery, retrying after CRD registration", "gvk", gvk, "error", err)
-				// Reset the mapper cache to pick up newly registered CRDs
-				mapper.Reset()
-				return false, nil // Retry
-			}
-			// Other errors are permanent
-			return false, err
-		}
-		// Success
-		return true, nil
-	})
-
+	// The shared REST mapper of the k8s client resolves the kind and is invalidated
+	// after the Flux CRDs are applied, so their custom resources map right away.
+	result, err := c.k8sClient.ApplySet(ctx, set, objs)
 	if err != nil {
-		return schema.GroupVersionResource{}, fmt.Errorf("failed to discover GVR for %s after retries: %w", gvk, err)
+		log.Error("Failed to apply manifests", "set", set, "error", err)
+		return err
 	}
+	log.Debug("Manifests applied", "set", set, "applied", len(result.Applied), "pruned", len(result.Pruned))
+	return nil
+}
 
-	return mapping.Resource, nil
+// applyObject applies a single unstructured object using server-side apply, outside of any apply set
+func (c *Client) applyObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	obj.SetManagedFields(nil)
+	_, err := c.k8sClient.Apply(ctx, obj, false)
+	return err
 }
 
 // suspendResources suspends Flux resources in a specific namespace
@@ -876,15 +737,19 @@ func parseAPIVersion(apiVersion string) (group, version string, err error) {
 	return parts[0], parts[1], nil
 }
 
-// generateSyncManifests creates GitRepository and Kustomization manifests with v1 API version
+// generateSyncManifests creates the source and Kustomization manifests with v1 API version
 func (c *Client) generateSyncManifests(namespace string) string {
 	// Debug: log the config being used
 	log.Debug("Generating sync manifests", "repository", c.config.Repository, "branch", c.config.Branch, "path", c.config.Path, "namespace", namespace)
 
 	// Use v1 API version to avoid deprecation warnings
 	var gitRepo string
-	if c.config.Token != "" {
-		// GitRepository with secretRef for authentication
+	if c.config.IsOCI() {
+		gitRepo = c.generateOCISource(namespace)
+	} else if c.config.IsBucket() {
+		gitRepo = c.generateBucketSource(namespace)
+	} else if c.config.Token != "" {
+		// GitRepository with secretRef for authentication, a token or a deploy key
 		gitRepo = fmt.Sprintf(`---
 apiVersion: source.toolkit.fluxcd.io/v1
 kind: GitRepository
@@ -898,7 +763,7 @@ spec:
   secretRef:
     name: flux-system
   url: %s
-`, namespace, c.config.Branch, c.config.Repository)
+`, namespace, c.config.Branch, c.repositoryURL())
 	} else {
 		// GitRepository without authentication (public repo)
 		gitRepo = fmt.Sprintf(`---
@@ -926,9 +791,12 @@ spec:
   path: %s
   prune: true
   sourceRef:
-    kind: GitRepository
+    kind: %s
     name: flux-system
-`, namespace, c.config.Path)
+`, namespace, c.config.Path, c.sourceKind())
+	if c.config.SOPS.Enabled {
+		kustomization += fmt.Sprintf("  decryption:\n    provider: sops\n    secretRef:\n      name: %s\n", c.config.SOPS.Secret())
+	}
 
 	return gitRepo + kustomization
 }
@@ -998,6 +866,7 @@ func (c *Client) CleanupFlux(ctx context.Context, namespace string) error {
 		{"source.toolkit.fluxcd.io", "v1", "helmrepositories", "HelmRepository"},
 		{"source.toolkit.fluxcd.io", "v1", "helmcharts", "HelmChart"},
 		{"source.toolkit.fluxcd.io", "v1", "buckets", "Bucket"},
+		{"source.toolkit.fluxcd.io", "v1", "ocirepositories", "OCIRepository"},
 		{"kustomize.toolkit.fluxcd.io", "v1", "kustomizations", "Kustomization"},
 		{"helm.toolkit.fluxcd.io", "v2beta1", "helmreleases", "HelmRelease"},
 		{"helm.toolkit.fluxcd.io", "v2", "helmreleases", "HelmRelease"}, // Try both v2beta1 and v2
@@ -1085,13 +954,15 @@ func (c *Client) CleanupFlux(ctx context.Context, namespace string) error {
 
 		// Wait a bit for the namespace to be cleaned up
 		log.Info("Waiting for namespace cleanup to complete", "namespace", namespace)
-		wait.PollImmediate(2*time.Second, 30*time.Second, func() (bool, error) {
+		if err := k8s.Poll(ctx, 2*time.Second, 30*time.Second, func(ctx context.Context) (bool, error) {
 			exists, err := c.k8sClient.NamespaceExists(ctx, namespace)
 			if err != nil {
 				return false, nil
 			}
 			return !exists, nil
-		})
+		}); err != nil {
+			log.Warn("Namespace still present after cleanup", "namespace", namespace, "error", err)
+		}
 	}
 
 	log.Info("Flux cleanup completed", "namespace", namespace)
@@ -0,0 +1,232 @@
+package flux
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"golang.org/x/crypto/ssh"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const githubAPI = "https://api.github.com"
+
+// deployKey is a repository deploy key as returned by the GitHub API
+type deployKey struct {
+	ID       int64  `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Key      string `json:"key"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// githubRepository returns the <owner>/<repo> of an https://github.com URL
+func githubRepository(url string) (string, error) {
+	path := strings.TrimSuffix(strings.TrimPrefix(url, "https://github.com/"), ".git")
+	if path == url || strings.Count(path, "/") != 1 {
+		return "", fmt.Errorf("repository %s is not https://github.com/<owner>/<repo>", url)
+	}
+	return path, nil
+}
+
+// repositoryURL returns the URL the flux-system GitRepository pulls from
+func (c *Client) repositoryURL() string {
+	if !c.config.UsesDeployKey() {
+		return c.config.Repository
+	}
+	repo, err := githubRepository(c.config.Repository)
+	if err != nil {
+		return c.config.Repository
+	}
+	return "ssh://git@github.com/" + repo
+}
+
+// deployKeyTitle names the deploy key on GitHub, one per cluster path
+func (c *Client) deployKeyTitle() string {
+	if c.config.DeployKey.Title != "" {
+		return c.config.DeployKey.Title
+	}
+	return "flux " + strings.TrimPrefix(c.config.Path, "./")
+}
+
+// createDeployKeySecret stores an ed25519 identity in the flux-system secret and registers its public key on the repository.
+// The identity already in the secret is kept while GitHub still holds its key with the configured access.
+func (c *Client) createDeployKeySecret(ctx context.Context, namespace string) error {
+	if c.config.Token == "" {
+		return fmt.Errorf("registering a deploy key needs GITHUB_TOKEN with administration access to the repository")
+	}
+	repo, err := githubRepository(c.config.Repository)
+	if err != nil {
+		return err
+	}
+	title := c.deployKeyTitle()
+
+	existing, err := c.k8sClient.GetSecret(ctx, namespace, "flux-system")
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to read the flux-system secret: %w", err)
+	}
+	found := err == nil
+	var existingPublic string
+	if found {
+		existingPublic = string(existing.Data["identity.pub"])
+	}
+
+	keys, err := c.listDeployKeys(ctx, repo)
+	if err != nil {
+		return err
+	}
+	// A key left behind by an interrupted rotation shares the title, prefer the one the secret holds
+	var registered *deployKey
+	for i := range keys {
+		if keys[i].Title != title {
+			continue
+		}
+		if registered == nil || sameKey(existingPublic, keys[i].Key) {
+			registered = &keys[i]
+		}
+	}
+
+	if found && registered != nil && registered.ReadOnly == !c.config.DeployKey.Write &&
+		len(existing.Data["identity"]) > 0 && sameKey(existingPublic, registered.Key) {
+		log.Debug("Deploy key already registered", "repository", repo, "title", title)
+		return nil
+	}
+
+	log.Info("Generating deploy key", "repository", repo, "title", title, "write", c.config.DeployKey.Write)
+	public, private, err := generateDeployKey(title)
+	if err != nil {
+		return err
+	}
+	knownHosts, err := c.githubKnownHosts(ctx)
+	if err != nil {
+		return err
+	}
+	// The previous key is only removed once the new one is registered and stored, so Flux keeps a working key
+	// on the repository if anything fails on the way
+	key := deployKey{Title: title, Key: public, ReadOnly: !c.config.DeployKey.Write}
+	if err := c.githubRequest(ctx, http.MethodPost, "/repos/"+repo+"/keys", key, nil); err != nil {
+		return fmt.Errorf("failed to register the deploy key: %w", err)
+	}
+
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      "flux-system",
+				"namespace": namespace,
+			},
+			"type": "Opaque",
+			"data": map[string]interface{}{
+				"identity":     base64.StdEncoding.EncodeToString(private),
+				"identity.pub": base64.StdEncoding.EncodeToString([]byte(public)),
+				"known_hosts":  base64.StdEncoding.EncodeToString([]byte(knownHosts)),
+			},
+		},
+	}
+	if err := c.applyObject(ctx, secret); err != nil {
+		return err
+	}
+	if registered != nil {
+		if err := c.githubRequest(ctx, http.MethodDelete, fmt.Sprintf("/repos/%s/keys/%d", repo, registered.ID), nil, nil); err != nil {
+			return fmt.Errorf("failed to remove the previous deploy key %d: %w", registered.ID, err)
+		}
+	}
+	return nil
+}
+
+// generateDeployKey returns an authorized_keys public key and a PEM OpenSSH private key
+func generateDeployKey(comment string) (string, []byte, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate ed25519 key: %w", err)
+	}
+	sshPublic, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, comment)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic))), pem.EncodeToMemory(block), nil
+}
+
+// githubKnownHosts renders the known_hosts lines of github.com from the host keys GitHub publishes
+func (c *Client) githubKnownHosts(ctx context.Context) (string, error) {
+	var meta struct {
+		SSHKeys []string `json:"ssh_keys"`
+	}
+	if err := c.githubRequest(ctx, http.MethodGet, "/meta", nil, &meta); err != nil {
+		return "", fmt.Errorf("failed to fetch the GitHub host keys: %w", err)
+	}
+	if len(meta.SSHKeys) == 0 {
+		return "", fmt.Errorf("GitHub published no SSH host keys")
+	}
+	var b strings.Builder
+	for _, key := range meta.SSHKeys {
+		fmt.Fprintf(&b, "github.com %s\n", key)
+	}
+	return b.String(), nil
+}
+
+func (c *Client) listDeployKeys(ctx context.Context, repo string) ([]deployKey, error) {
+	var keys []deployKey
+	if err := c.githubRequest(ctx, http.MethodGet, "/repos/"+repo+"/keys?per_page=100", nil, &keys); err != nil {
+		return nil, fmt.Errorf("failed to list deploy keys of %s: %w", repo, err)
+	}
+	return keys, nil
+}
+
+// githubRequest calls the GitHub REST API, authenticating with the configured token when there is one
+func (c *Client) githubRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	if err := airgap.GuardNetwork("GitHub API " + path); err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, githubAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// sameKey compares two authorized_keys entries, ignoring their comments
+func sameKey(a, b string) bool {
+	fieldsA, fieldsB := strings.Fields(a), strings.Fields(b)
+	return len(fieldsA) >= 2 && len(fieldsB) >= 2 && fieldsA[0] == fieldsB[0] && fieldsA[1] == fieldsB[1]
+}
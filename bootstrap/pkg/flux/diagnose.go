@@ -0,0 +1,653 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Statuses of a diagnosis layer
+const (
+	LayerHealthy = "healthy"
+	LayerFailed  = "failed"
+	LayerPending = "pending"
+	LayerSkipped = "skipped"
+)
+
+// Labels helm-controller sets on every object of a release
+const (
+	helmReleaseNameLabel      = "helm.toolkit.fluxcd.io/name"
+	helmReleaseNamespaceLabel = "helm.toolkit.fluxcd.io/namespace"
+)
+
+const (
+	maxEvents  = 15
+	maxLogPods = 2
+	logTail    = int64(30)
+)
+
+var kustomizationGVR = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+
+// sourceVersions is the API version served for each Flux source kind
+var sourceVersions = map[string]string{
+	"GitRepository": "v1",
+	"OCIRepository": "v1",
+	"Bucket":        "v1beta2",
+}
+
+var workloadKinds = map[string]bool{"Deployment": true, "StatefulSet": true, "DaemonSet": true}
+
+// Layer is one link of the chain from the source down to the container logs
+type Layer struct {
+	Title   string   `json:"title"`
+	Status  string   `json:"status"`
+	Summary string   `json:"summary"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Diagnosis is the layered explanation of a Kustomization's state
+type Diagnosis struct {
+	Namespace     string `json:"namespace"`
+	Kustomization string `json:"kustomization"`
+	// Workload is the first unhealthy workload found behind the inventory
+	Workload string  `json:"workload,omitempty"`
+	Layers   []Layer `json:"layers"`
+}
+
+// RootCause returns the index of the first failed layer, or -1 when everything is healthy
+func (d *Diagnosis) RootCause() int {
+	for i, layer := range d.Layers {
+		if layer.Status == LayerFailed {
+			return i
+		}
+	}
+	return -1
+}
+
+// workload is a pod controller whose events and logs explain a failure
+type workload struct {
+	kind      string
+	namespace string
+	name      string
+	selector  labels.Selector
+	reason    string
+}
+
+func (w *workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.kind, w.namespace, w.name)
+}
+
+// condition is the subset of a status condition the diagnosis reports
+type condition struct {
+	status  string
+	reason  string
+	message string
+}
+
+// Diagnose walks a Kustomization through its source, build, inventory, workload events and container logs
+func (c *Client) Diagnose(ctx context.Context, namespace, name string) (*Diagnosis, error) {
+	ks, err := c.k8sClient.GetDynamicClient().Resource(kustomizationGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("kustomization %s/%s not found", namespace, name)
+		}
+		return nil, fmt.Errorf("failed to get kustomization %s/%s: %w", namespace, name, err)
+	}
+
+	diagnosis := &Diagnosis{Namespace: namespace, Kustomization: name}
+	diagnosis.Layers = append(diagnosis.Layers, c.sourceLayer(ctx, ks), c.buildLayer(ctx, ks))
+
+	inventory, offender := c.inventoryLayer(ctx, ks)
+	diagnosis.Layers = append(diagnosis.Layers, inventory)
+
+	if offender == nil {
+		skipped := "no unhealthy workload in the inventory"
+		diagnosis.Layers = append(diagnosis.Layers,
+			Layer{Title: "Workload events", Status: LayerSkipped, Summary: skipped},
+			Layer{Title: "Container logs", Status: LayerSkipped, Summary: skipped})
+		return diagnosis, nil
+	}
+
+	diagnosis.Workload = offender.String()
+	diagnosis.Layers = append(diagnosis.Layers, c.eventsLayer(ctx, offender), c.logsLayer(ctx, offender))
+	return diagnosis, nil
+}
+
+// sourceLayer reports the fetched revision and fetch errors of the Kustomization source
+func (c *Client) sourceLayer(ctx context.Context, ks *unstructured.Unstructured) Layer {
+	kind, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "kind")
+	name, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "name")
+	namespace, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "namespace")
+	if namespace == "" {
+		namespace = ks.GetNamespace()
+	}
+
+	layer := Layer{Title: fmt.Sprintf("Source %s %s/%s", kind, namespace, name)}
+	version, ok := sourceVersions[kind]
+	if !ok {
+		layer.Status = LayerPending
+		layer.Summary = fmt.Sprintf("unsupported source kind %q", kind)
+		return layer
+	}
+
+	gvr := schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: version, Resource: fluxKindToResource(kind)}
+	source, err := c.k8sClient.GetDynamicClient().Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		layer.Status = LayerFailed
+		layer.Summary = fmt.Sprintf("source not readable: %v", err)
+		return layer
+	}
+
+	if url, _, _ := unstructured.NestedString(source.Object, "spec", "url"); url != "" {
+		layer.Details = append(layer.Details, "URL: "+url)
+	}
+	if ref, _, _ := unstructured.NestedStringMap(source.Object, "spec", "ref"); len(ref) > 0 {
+		keys := make([]string, 0, len(ref))
+		for key, value := range ref {
+			keys = append(keys, key+"="+value)
+		}
+		sort.Strings(keys)
+		layer.Details = append(layer.Details, "Ref: "+strings.Join(keys, ", "))
+	}
+	revision, _, _ := unstructured.NestedString(source.Object, "status", "artifact", "revision")
+	if revision != "" {
+		layer.Details = append(layer.Details, "Artifact revision: "+revision)
+	}
+	if updated, _, _ := unstructured.NestedString(source.Object, "status", "artifact", "lastUpdateTime"); updated != "" {
+		layer.Details = append(layer.Details, "Artifact updated: "+age(updated)+" ago")
+	}
+	layer.Details = append(layer.Details, conditionDetails(source)...)
+
+	ready, found := readCondition(source, "Ready")
+	fetch, fetchFailed := readCondition(source, "FetchFailed")
+	switch {
+	case suspended(source):
+		layer.Status = LayerFailed
+		layer.Summary = "source is suspended"
+	case fetchFailed && fetch.status == "True":
+		layer.Status = LayerFailed
+		layer.Summary = fmt.Sprintf("fetch failed: %s", fetch.message)
+	case found && ready.status == "False":
+		layer.Status = LayerFailed
+		layer.Summary = fmt.Sprintf("%s: %s", ready.reason, ready.message)
+	case found && ready.status == "True":
+		layer.Status = LayerHealthy
+		layer.Summary = "fetched " + revision
+	default:
+		layer.Status = LayerPending
+		layer.Summary = "source not reconciled yet"
+	}
+	return layer
+}
+
+// buildLayer reports the build and apply state of the Kustomization and its dependencies
+func (c *Client) buildLayer(ctx context.Context, ks *unstructured.Unstructured) Layer {
+	layer := Layer{Title: fmt.Sprintf("Kustomization %s/%s", ks.GetNamespace(), ks.GetName())}
+
+	path, _, _ := unstructured.NestedString(ks.Object, "spec", "path")
+	layer.Details = append(layer.Details, "Path: "+path)
+	attempted, _, _ := unstructured.NestedString(ks.Object, "status", "lastAttemptedRevision")
+	applied, _, _ := unstructured.NestedString(ks.Object, "status", "lastAppliedRevision")
+	layer.Details = append(layer.Details, "Last attempted revision: "+attempted, "Last applied revision: "+applied)
+	if attempted != "" && attempted != applied {
+		layer.Details = append(layer.Details, "⚠ the attempted revision was never applied")
+	}
+
+	dependencies, _, _ := unstructured.NestedSlice(ks.Object, "spec", "dependsOn")
+	var blocking []string
+	for _, raw := range dependencies {
+		dependency, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := dependency["name"].(string)
+		namespace, _ := dependency["namespace"].(string)
+		if namespace == "" {
+			namespace = ks.GetNamespace()
+		}
+		state := "ready"
+		dep, err := c.k8sClient.GetDynamicClient().Resource(kustomizationGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			state = "not found"
+			blocking = append(blocking, name)
+		} else if cond, ok := readCondition(dep, "Ready"); !ok || cond.status != "True" {
+			state = "not ready: " + cond.message
+			blocking = append(blocking, name)
+		}
+		layer.Details = append(layer.Details, fmt.Sprintf("Depends on %s/%s (%s)", namespace, name, state))
+	}
+	layer.Details = append(layer.Details, conditionDetails(ks)...)
+
+	ready, found := readCondition(ks, "Ready")
+	switch {
+	case suspended(ks):
+		layer.Status = LayerFailed
+		layer.Summary = "kustomization is suspended"
+	case found && ready.status == "True":
+		layer.Status = LayerHealthy
+		layer.Summary = "applied " + applied
+	case found && ready.reason == "DependencyNotReady" && len(blocking) > 0:
+		layer.Status = LayerFailed
+		layer.Summary = fmt.Sprintf("waiting on %s, run 'bootstrap why %s'", strings.Join(blocking, ", "), blocking[0])
+	case found && ready.status == "False":
+		layer.Status = LayerFailed
+		layer.Summary = fmt.Sprintf("%s: %s", ready.reason, firstLine(ready.message))
+		// Build errors span many lines, keep them whole in the details
+		if strings.Contains(ready.message, "\n") {
+			layer.Details = append(layer.Details, "", "Full message:")
+			layer.Details = append(layer.Details, strings.Split(ready.message, "\n")...)
+		}
+	default:
+		layer.Status = LayerPending
+		layer.Summary = "reconciliation in progress"
+	}
+	return layer
+}
+
+// inventoryLayer checks every applied object and returns the first unhealthy workload
+func (c *Client) inventoryLayer(ctx context.Context, ks *unstructured.Unstructured) (Layer, *workload) {
+	layer := Layer{Title: "Inventory"}
+
+	entries, _, _ := unstructured.NestedSlice(ks.Object, "status", "inventory", "entries")
+	if len(entries) == 0 {
+		layer.Status = LayerPending
+		layer.Summary = "no inventory, nothing was applied yet"
+		return layer, nil
+	}
+
+	mapper := c.k8sClient.RESTMapper()
+
+	var offender *workload
+	unhealthy := 0
+	for _, raw := range entries {
+		entry, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := entry["id"].(string)
+		version, _ := entry["v"].(string)
+
+		// Inventory ids are <namespace>_<name>_<group>_<kind>
+		parts := strings.Split(id, "_")
+		if len(parts) != 4 {
+			continue
+		}
+		namespace, name := parts[0], parts[1]
+		gk := schema.GroupKind{Group: parts[2], Kind: parts[3]}
+		ref := gk.Kind + " " + name
+		if namespace != "" {
+			ref = gk.Kind + " " + namespace + "/" + name
+		}
+
+		mapping, err := mapper.RESTMapping(gk, version)
+		if err != nil {
+			unhealthy++
+			layer.Details = append(layer.Details, fmt.Sprintf("✗ %s: API not served (%v)", ref, err))
+			continue
+		}
+		resource := c.k8sClient.GetDynamicClient().Resource(mapping.Resource)
+		var obj *unstructured.Unstructured
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			obj, err = resource.Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		} else {
+			obj, err = resource.Get(ctx, name, metav1.GetOptions{})
+		}
+		if err != nil {
+			unhealthy++
+			if apierrors.IsNotFound(err) {
+				layer.Details = append(layer.Details, fmt.Sprintf("✗ %s: missing from the cluster (deleted out of band?)", ref))
+			} else {
+				layer.Details = append(layer.Details, fmt.Sprintf("✗ %s: %v", ref, err))
+			}
+			continue
+		}
+
+		healthy, reason := objectHealth(obj)
+		if healthy {
+			continue
+		}
+		unhealthy++
+		layer.Details = append(layer.Details, fmt.Sprintf("✗ %s: %s", ref, reason))
+
+		if offender != nil {
+			continue
+		}
+		switch {
+		case workloadKinds[gk.Kind]:
+			offender = newWorkload(obj, reason)
+		case gk.Kind == "HelmRelease":
+			offender = c.releaseWorkload(ctx, obj)
+		case gk.Kind == "Kustomization" && gk.Group == kustomizationGVR.Group:
+			layer.Details = append(layer.Details, fmt.Sprintf("  run 'bootstrap why %s --namespace %s' to follow it", name, namespace))
+		}
+	}
+
+	if unhealthy == 0 {
+		layer.Status = LayerHealthy
+		layer.Summary = fmt.Sprintf("all %d objects healthy", len(entries))
+		return layer, nil
+	}
+	layer.Status = LayerFailed
+	layer.Summary = fmt.Sprintf("%d of %d objects unhealthy", unhealthy, len(entries))
+	if offender != nil {
+		layer.Summary += ", offending workload " + offender.String()
+	}
+	return layer, offender
+}
+
+// releaseWorkload finds the first unhealthy workload deployed by a HelmRelease
+func (c *Client) releaseWorkload(ctx context.Context, release *unstructured.Unstructured) *workload {
+	selector := labels.SelectorFromSet(labels.Set{
+		helmReleaseNameLabel:      release.GetName(),
+		helmReleaseNamespaceLabel: release.GetNamespace(),
+	}).String()
+
+	for _, kind := range []string{"deployments", "statefulsets", "daemonsets"} {
+		gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: kind}
+		list, err := c.k8sClient.GetDynamicClient().Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			continue
+		}
+		for i := range list.Items {
+			if healthy, reason := objectHealth(&list.Items[i]); !healthy {
+				return newWorkload(&list.Items[i], reason)
+			}
+		}
+	}
+	return nil
+}
+
+// eventsLayer lists the recent events of the workload, its replica sets and pods
+func (c *Client) eventsLayer(ctx context.Context, w *workload) Layer {
+	layer := Layer{Title: "Events of " + w.String()}
+
+	events, err := c.k8sClient.GetClientset().CoreV1().Events(w.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		layer.Status = LayerPending
+		layer.Summary = fmt.Sprintf("events not readable: %v", err)
+		return layer
+	}
+
+	var related []corev1.Event
+	for _, event := range events.Items {
+		object := event.InvolvedObject
+		if object.Name == w.name || strings.HasPrefix(object.Name, w.name+"-") {
+			related = append(related, event)
+		}
+	}
+	sort.Slice(related, func(i, j int) bool {
+		return eventTime(related[i]).After(eventTime(related[j]))
+	})
+	if len(related) > maxEvents {
+		related = related[:maxEvents]
+	}
+
+	warnings := 0
+	for _, event := range related {
+		if event.Type == corev1.EventTypeWarning {
+			warnings++
+		}
+		layer.Details = append(layer.Details, fmt.Sprintf("%s ago  %s  %s  %s/%s: %s",
+			time.Since(eventTime(event)).Round(time.Second), event.Type, event.Reason,
+			event.InvolvedObject.Kind, event.InvolvedObject.Name, strings.TrimSpace(event.Message)))
+	}
+
+	switch {
+	case len(related) == 0:
+		layer.Status = LayerPending
+		layer.Summary = "no recent events (they expire after an hour)"
+	case warnings > 0:
+		layer.Status = LayerFailed
+		layer.Summary = fmt.Sprintf("%d warning(s), latest: %s", warnings, latestWarning(related))
+	default:
+		layer.Status = LayerHealthy
+		layer.Summary = fmt.Sprintf("%d normal event(s)", len(related))
+	}
+	return layer
+}
+
+// logsLayer reports the failing containers of the workload pods with the tail of their logs
+func (c *Client) logsLayer(ctx context.Context, w *workload) Layer {
+	layer := Layer{Title: "Containers of " + w.String()}
+
+	pods, err := c.k8sClient.GetClientset().CoreV1().Pods(w.namespace).List(ctx, metav1.ListOptions{LabelSelector: w.selector.String()})
+	if err != nil {
+		layer.Status = LayerPending
+		layer.Summary = fmt.Sprintf("pods not readable: %v", err)
+		return layer
+	}
+	if len(pods.Items) == 0 {
+		layer.Status = LayerFailed
+		layer.Summary = "no pod scheduled: " + w.reason
+		return layer
+	}
+
+	inspected := 0
+	var failures []string
+	for _, pod := range pods.Items {
+		if inspected == maxLogPods {
+			break
+		}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		var failing []corev1.ContainerStatus
+		for _, status := range statuses {
+			if !status.Ready && !(status.State.Terminated != nil && status.State.Terminated.ExitCode == 0) {
+				failing = append(failing, status)
+			}
+		}
+		if len(failing) == 0 {
+			if pod.Status.Phase == corev1.PodPending {
+				inspected++
+				failures = append(failures, fmt.Sprintf("pod %s pending", pod.Name))
+				layer.Details = append(layer.Details, fmt.Sprintf("── pod %s is %s: %s", pod.Name, pod.Status.Phase, podScheduling(pod)))
+			}
+			continue
+		}
+		inspected++
+
+		for _, status := range failing {
+			state := containerState(status)
+			failures = append(failures, fmt.Sprintf("%s/%s %s", pod.Name, status.Name, state))
+			layer.Details = append(layer.Details, fmt.Sprintf("── pod %s container %s: %s (restarts %d)", pod.Name, status.Name, state, status.RestartCount))
+
+			if status.State.Waiting != nil && strings.Contains(status.State.Waiting.Reason, "Image") {
+				// Nothing ran, the waiting message is all there is
+				layer.Details = append(layer.Details, "   "+status.State.Waiting.Message)
+				continue
+			}
+			layer.Details = append(layer.Details, c.containerLogs(ctx, pod, status)...)
+		}
+	}
+
+	if len(failures) == 0 {
+		layer.Status = LayerHealthy
+		layer.Summary = fmt.Sprintf("all containers of %d pod(s) ready", len(pods.Items))
+		return layer
+	}
+	layer.Status = LayerFailed
+	layer.Summary = failures[0]
+	if len(failures) > 1 {
+		layer.Summary += fmt.Sprintf(" (+%d more)", len(failures)-1)
+	}
+	return layer
+}
+
+// containerLogs returns the tail of the logs of a container, from its previous run when it restarted
+func (c *Client) containerLogs(ctx context.Context, pod corev1.Pod, status corev1.ContainerStatus) []string {
+	tail := logTail
+	previous := status.RestartCount > 0 && status.State.Running == nil
+	logs, err := c.k8sClient.GetClientset().CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: status.Name,
+		TailLines: &tail,
+		Previous:  previous,
+	}).DoRaw(ctx)
+	if err != nil {
+		return []string{fmt.Sprintf("   logs not available: %v", err)}
+	}
+
+	text := strings.TrimRight(string(logs), "\n")
+	if text == "" {
+		return []string{"   (no output)"}
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = "   " + line
+	}
+	return lines
+}
+
+// objectHealth tells whether an applied object is healthy and why not
+func objectHealth(obj *unstructured.Unstructured) (bool, string) {
+	switch obj.GetKind() {
+	case "Deployment", "StatefulSet":
+		desired, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			desired = 1
+		}
+		ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		if ready < desired {
+			reason := fmt.Sprintf("%d/%d replicas ready", ready, desired)
+			if progressing, ok := readCondition(obj, "Progressing"); ok && progressing.status == "False" {
+				reason += ": " + progressing.message
+			}
+			return false, reason
+		}
+		return true, ""
+	case "DaemonSet":
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+		if ready < desired {
+			return false, fmt.Sprintf("%d/%d pods ready", ready, desired)
+		}
+		return true, ""
+	case "Job":
+		if failed, ok := readCondition(obj, "Failed"); ok && failed.status == "True" {
+			return false, fmt.Sprintf("job failed: %s", failed.message)
+		}
+		return true, ""
+	}
+
+	if suspended(obj) {
+		return false, "suspended"
+	}
+	if ready, ok := readCondition(obj, "Ready"); ok && ready.status != "True" {
+		return false, fmt.Sprintf("%s: %s", ready.reason, firstLine(ready.message))
+	}
+	return true, ""
+}
+
+func newWorkload(obj *unstructured.Unstructured, reason string) *workload {
+	w := &workload{kind: obj.GetKind(), namespace: obj.GetNamespace(), name: obj.GetName(), reason: reason, selector: labels.Nothing()}
+	if raw, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector"); found {
+		var selector metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &selector); err == nil {
+			if parsed, err := metav1.LabelSelectorAsSelector(&selector); err == nil {
+				w.selector = parsed
+			}
+		}
+	}
+	return w
+}
+
+// readCondition returns the status condition of the given type
+func readCondition(obj *unstructured.Unstructured, conditionType string) (condition, bool) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, raw := range conditions {
+		cond, ok := raw.(map[string]interface{})
+		if !ok || cond["type"] != conditionType {
+			continue
+		}
+		status, _ := cond["status"].(string)
+		reason, _ := cond["reason"].(string)
+		message, _ := cond["message"].(string)
+		return condition{status: status, reason: reason, message: message}, true
+	}
+	return condition{}, false
+}
+
+// conditionDetails renders every status condition of an object
+func conditionDetails(obj *unstructured.Unstructured) []string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	details := make([]string, 0, len(conditions))
+	for _, raw := range conditions {
+		cond, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		details = append(details, fmt.Sprintf("Condition %v=%v (%v): %v", cond["type"], cond["status"], cond["reason"], firstLine(fmt.Sprint(cond["message"]))))
+	}
+	return details
+}
+
+func suspended(obj *unstructured.Unstructured) bool {
+	value, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend")
+	return value
+}
+
+func containerState(status corev1.ContainerStatus) string {
+	switch {
+	case status.State.Waiting != nil:
+		state := status.State.Waiting.Reason
+		if last := status.LastTerminationState.Terminated; last != nil {
+			state += fmt.Sprintf(", last exit %d (%s)", last.ExitCode, last.Reason)
+		}
+		return state
+	case status.State.Terminated != nil:
+		return fmt.Sprintf("terminated with exit %d (%s)", status.State.Terminated.ExitCode, status.State.Terminated.Reason)
+	default:
+		return "running but not ready"
+	}
+}
+
+func podScheduling(pod corev1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status != corev1.ConditionTrue {
+			return cond.Message
+		}
+	}
+	return "waiting for containers to start"
+}
+
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+func latestWarning(events []corev1.Event) string {
+	for _, event := range events {
+		if event.Type == corev1.EventTypeWarning {
+			return fmt.Sprintf("%s %s", event.Reason, firstLine(event.Message))
+		}
+	}
+	return ""
+}
+
+func age(timestamp string) string {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp
+	}
+	return time.Since(parsed).Round(time.Second).String()
+}
+
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
@@ -0,0 +1,116 @@
+package flux
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fluxcd/flux2/v2/pkg/manifestgen/install"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// installManifest generates the manifests of the Flux controllers Install deploys, from the release version
+// or the latest one when empty. Air-gapped, they are built from the bundled release instead of the GitHub
+// one. The controllers pull from the registry mirror when one is configured.
+func installManifest(namespace, version string) (string, error) {
+	opts := install.MakeDefaultOptions()
+	opts.Namespace = namespace
+	if version != "" {
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		opts.Version = version
+	}
+	opts.Components = []string{
+		"source-controller",
+		"kustomize-controller",
+		"helm-controller",
+		"notification-controller",
+	}
+	opts.ComponentsExtra = []string{
+		"image-reflector-controller",
+		"image-automation-controller",
+	}
+
+	opts.Registry = registry.Rewrite(opts.Registry)
+	opts.ImagePullSecret = registry.PullSecret()
+
+	manifestsBase := ""
+	if airgap.Enabled() {
+		dir, version, err := airgap.FluxManifests()
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(dir)
+		manifestsBase, opts.Version = dir, version
+	}
+
+	manifest, err := install.Generate(opts, manifestsBase)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate flux install manifests: %w", err)
+	}
+	return manifest.Content, nil
+}
+
+// fluxVersion returns the Flux release pinned in the GitOps config, empty for the latest
+func (c *Client) fluxVersion() string {
+	if c.config == nil {
+		return ""
+	}
+	return c.config.FluxVersion
+}
+
+// InstallObjects renders the objects Install applies
+func (c *Client) InstallObjects(namespace string) ([]*unstructured.Unstructured, error) {
+	manifest, err := installManifest(namespace, c.fluxVersion())
+	if err != nil {
+		return nil, err
+	}
+	return decodeObjects(manifest)
+}
+
+// InstallImages returns the images of the Flux controllers Install deploys
+func (c *Client) InstallImages(namespace string) ([]string, error) {
+	objects, err := c.InstallObjects(namespace)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, obj := range objects {
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		for _, container := range containers {
+			if image, ok := container.(map[string]interface{})["image"].(string); ok {
+				images = append(images, image)
+			}
+		}
+	}
+	return images, nil
+}
+
+// SyncObjects renders the flux-system source and the Kustomizations Bootstrap and BootstrapPlatformFoundation apply
+func (c *Client) SyncObjects(namespace, clusterType string) ([]*unstructured.Unstructured, error) {
+	return decodeObjects(c.generateSyncManifests(namespace) + c.platformFoundationManifest(namespace, clusterType))
+}
+
+// decodeObjects splits a multi-document manifest into objects, skipping empty documents
+func decodeObjects(manifest string) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	var objects []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		if obj.Object != nil {
+			objects = append(objects, obj)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	defaultImageAutomationInterval = "30m"
+	defaultImageAuthorName         = "fluxcdbot"
+	defaultImageAuthorEmail        = "fluxcdbot@users.noreply.github.com"
+)
+
+// ImageAutomationManifest renders an ImageRepository and ImagePolicy per configured image and the
+// ImageUpdateAutomation committing the selected tags to the flux-system GitRepository
+func (c *Client) ImageAutomationManifest(namespace string) (string, error) {
+	automation := c.config.ImageAutomation
+	if len(automation.Images) == 0 {
+		return "", fmt.Errorf("no images configured under gitops.image_automation.images")
+	}
+	if c.config.IsOCI() || c.config.IsBucket() {
+		return "", fmt.Errorf("image automation commits to Git, the %s source cannot be written back", c.sourceKind())
+	}
+
+	interval := orDefault(automation.Interval, defaultImageAutomationInterval)
+	var b strings.Builder
+	for _, image := range automation.Images {
+		fmt.Fprintf(&b, `---
+apiVersion: image.toolkit.fluxcd.io/v1
+kind: ImageRepository
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  image: %s
+  interval: %s
+`, image.Name, namespace, image.Image, interval)
+		if image.SecretRef != "" {
+			fmt.Fprintf(&b, "  secretRef:\n    name: %s\n", image.SecretRef)
+		}
+
+		fmt.Fprintf(&b, `---
+apiVersion: image.toolkit.fluxcd.io/v1
+kind: ImagePolicy
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  imageRepositoryRef:
+    name: %s
+`, image.Name, namespace, image.Name)
+		if image.Pattern != "" {
+			fmt.Fprintf(&b, "  filterTags:\n    pattern: %q\n    extract: \"$ts\"\n  policy:\n    numerical:\n      order: asc\n", image.Pattern)
+		} else {
+			fmt.Fprintf(&b, "  policy:\n    semver:\n      range: %q\n", orDefault(image.Semver, ">=0.0.0"))
+		}
+	}
+
+	fmt.Fprintf(&b, `---
+apiVersion: image.toolkit.fluxcd.io/v1
+kind: ImageUpdateAutomation
+metadata:
+  name: flux-system
+  namespace: %s
+spec:
+  interval: %s
+  sourceRef:
+    kind: GitRepository
+    name: flux-system
+  git:
+    checkout:
+      ref:
+        branch: %s
+    commit:
+      author:
+        name: %s
+        email: %s
+      messageTemplate: |
+        Update images
+
+        {{ range .Changed.Changes -}}
+        - {{ .OldValue }} -> {{ .NewValue }}
+        {{ end -}}
+    push:
+      branch: %s
+  update:
+    path: ./%s
+    strategy: Setters
+`, namespace, interval, c.config.Branch,
+		orDefault(automation.AuthorName, defaultImageAuthorName),
+		orDefault(automation.AuthorEmail, defaultImageAuthorEmail),
+		orDefault(automation.PushBranch, c.config.Branch),
+		strings.TrimPrefix(orDefault(automation.Path, c.config.Path), "./"))
+	return b.String(), nil
+}
+
+// InitImageAutomation wires the write-back credentials to the flux-system GitRepository, then applies the image automation objects
+func (c *Client) InitImageAutomation(ctx context.Context, namespace string) error {
+	manifest, err := c.ImageAutomationManifest(namespace)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.config.Token == "" && c.config.UsesDeployKey():
+		return fmt.Errorf("image automation pushes with a write deploy key, set GITHUB_TOKEN to register it")
+	case c.config.Token == "":
+		return fmt.Errorf("image automation pushes commits, set GITHUB_TOKEN to a token with contents write access")
+	}
+
+	// The automation pushes with the credentials of its GitRepository
+	log.Info("Wiring write-back credentials to the GitRepository", "repository", c.config.Repository)
+	if err := c.ApplySyncManifests(ctx, namespace); err != nil {
+		return err
+	}
+
+	log.Info("Applying image automation", "images", len(c.config.ImageAutomation.Images))
+	if err := c.applyManifests(ctx, setImageAutomation, []byte(manifest)); err != nil {
+		return fmt.Errorf("failed to apply image automation manifests: %w", err)
+	}
+	return nil
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package flux
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// fluxKindGroups maps the reconcilable Flux kinds to their API group
+var fluxKindGroups = map[string]string{
+	"GitRepository":         "source.toolkit.fluxcd.io",
+	"HelmRepository":        "source.toolkit.fluxcd.io",
+	"HelmChart":             "source.toolkit.fluxcd.io",
+	"Bucket":                "source.toolkit.fluxcd.io",
+	"OCIRepository":         "source.toolkit.fluxcd.io",
+	"Kustomization":         "kustomize.toolkit.fluxcd.io",
+	"HelmRelease":           "helm.toolkit.fluxcd.io",
+	"Receiver":              "notification.toolkit.fluxcd.io",
+	"ImageRepository":       "image.toolkit.fluxcd.io",
+	"ImageUpdateAutomation": "image.toolkit.fluxcd.io",
+}
+
+// fluxKindAliases are the short names accepted for the Flux kinds, matching the flux CLI
+var fluxKindAliases = map[string]string{
+	"ks":       "Kustomization",
+	"hr":       "HelmRelease",
+	"gitrepo":  "GitRepository",
+	"helmrepo": "HelmRepository",
+	"ocirepo":  "OCIRepository",
+}
+
+// ParseKind resolves a kind name, plural resource or alias to its Flux kind, case-insensitively
+func ParseKind(kind string) (string, error) {
+	lower := strings.ToLower(kind)
+	if alias, ok := fluxKindAliases[lower]; ok {
+		return alias, nil
+	}
+	for known := range fluxKindGroups {
+		if lower == strings.ToLower(known) || lower == fluxKindToResource(known) {
+			return known, nil
+		}
+	}
+
+	kinds := make([]string, 0, len(fluxKindGroups))
+	for known := range fluxKindGroups {
+		kinds = append(kinds, known)
+	}
+	sort.Strings(kinds)
+	return "", fmt.Errorf("unknown Flux kind %q, expected one of: %s", kind, strings.Join(kinds, ", "))
+}
+
+// resourceFor returns the dynamic client of a Flux kind at the version the cluster serves
+func (c *Client) resourceFor(kind, namespace string) (dynamic.ResourceInterface, string, error) {
+	kind, err := ParseKind(kind)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mapping, err := c.k8sClient.RESTMapper().RESTMapping(schema.GroupKind{Group: fluxKindGroups[kind], Kind: kind})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find %s in the cluster API: %w", kind, err)
+	}
+	return c.k8sClient.GetDynamicClient().Resource(mapping.Resource).Namespace(namespace), kind, nil
+}
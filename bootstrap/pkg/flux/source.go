@@ -0,0 +1,222 @@
+package flux
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	gitRepositoryGVR = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"}
+	ociRepositoryGVR = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "ocirepositories"}
+	bucketGVR        = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "buckets"}
+)
+
+// SourceGVR returns the resource of the flux-system source for a GitOps config
+func SourceGVR(cfg *config.GitOpsConfig) schema.GroupVersionResource {
+	switch {
+	case cfg != nil && cfg.IsOCI():
+		return ociRepositoryGVR
+	case cfg != nil && cfg.IsBucket():
+		return bucketGVR
+	}
+	return gitRepositoryGVR
+}
+
+// sourceKind returns the kind of the flux-system source
+func (c *Client) sourceKind() string {
+	switch {
+	case c.config.IsOCI():
+		return "OCIRepository"
+	case c.config.IsBucket():
+		return "Bucket"
+	}
+	return "GitRepository"
+}
+
+// ociTag returns the artifact tag Flux pulls when no semver range is set
+func (c *Client) ociTag() string {
+	if c.config.OCI.Tag != "" {
+		return c.config.OCI.Tag
+	}
+	return "latest"
+}
+
+// ociUsesPullSecret reports whether the registry is authenticated with the token rather than a cloud identity
+func (c *Client) ociUsesPullSecret() bool {
+	provider := c.config.OCI.Provider
+	return c.config.Token != "" && (provider == "" || provider == "generic")
+}
+
+// generateOCISource renders the OCIRepository pulling the GitOps artifact
+func (c *Client) generateOCISource(namespace string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `---
+apiVersion: source.toolkit.fluxcd.io/v1
+kind: OCIRepository
+metadata:
+  name: flux-system
+  namespace: %s
+spec:
+  interval: 1m0s
+  url: %s
+  ref:
+`, namespace, c.config.Repository)
+	if c.config.OCI.Semver != "" {
+		fmt.Fprintf(&b, "    semver: %q\n", c.config.OCI.Semver)
+	} else {
+		fmt.Fprintf(&b, "    tag: %q\n", c.ociTag())
+	}
+	if provider := c.config.OCI.Provider; provider != "" && provider != "generic" {
+		fmt.Fprintf(&b, "  provider: %s\n", provider)
+	}
+	if c.ociUsesPullSecret() {
+		b.WriteString("  secretRef:\n    name: flux-system\n")
+	}
+
+	if verify := c.config.OCI.Verify; verify != nil {
+		provider := verify.Provider
+		if provider == "" {
+			provider = "cosign"
+		}
+		fmt.Fprintf(&b, "  verify:\n    provider: %s\n", provider)
+		if verify.SecretRef != "" {
+			fmt.Fprintf(&b, "    secretRef:\n      name: %s\n", verify.SecretRef)
+		}
+		if verify.Issuer != "" || verify.Subject != "" {
+			fmt.Fprintf(&b, "    matchOIDCIdentity:\n      - issuer: %q\n        subject: %q\n", orMatchAll(verify.Issuer), orMatchAll(verify.Subject))
+		}
+	}
+	return b.String()
+}
+
+// bucketUsesSecret reports whether the bucket is authenticated with an access key rather than a cloud identity
+func (c *Client) bucketUsesSecret() bool {
+	provider := c.config.Bucket.Provider
+	return c.config.Bucket.AccessKey != "" && (provider == "" || provider == "generic")
+}
+
+// generateBucketSource renders the Bucket the manifests are synced from
+func (c *Client) generateBucketSource(namespace string) string {
+	bucket := c.config.Bucket
+	var b strings.Builder
+	fmt.Fprintf(&b, `---
+apiVersion: source.toolkit.fluxcd.io/v1
+kind: Bucket
+metadata:
+  name: flux-system
+  namespace: %s
+spec:
+  interval: 1m0s
+  endpoint: %s
+  bucketName: %s
+`, namespace, bucket.Endpoint, bucket.Name)
+	if bucket.Provider != "" {
+		fmt.Fprintf(&b, "  provider: %s\n", bucket.Provider)
+	}
+	if bucket.Region != "" {
+		fmt.Fprintf(&b, "  region: %s\n", bucket.Region)
+	}
+	if bucket.Insecure {
+		b.WriteString("  insecure: true\n")
+	}
+	if c.bucketUsesSecret() {
+		b.WriteString("  secretRef:\n    name: flux-system\n")
+	}
+	return b.String()
+}
+
+func orMatchAll(pattern string) string {
+	if pattern == "" {
+		return ".*"
+	}
+	return pattern
+}
+
+// createSourceSecret creates the credentials the flux-system source authenticates with
+func (c *Client) createSourceSecret(ctx context.Context, namespace string) error {
+	switch {
+	case c.config.IsOCI():
+		if !c.ociUsesPullSecret() {
+			return nil
+		}
+		return c.createOCIPullSecret(ctx, namespace)
+	case c.config.IsBucket():
+		if !c.bucketUsesSecret() {
+			return nil
+		}
+		return c.createBucketSecret(ctx, namespace)
+	case c.config.UsesDeployKey():
+		return c.createDeployKeySecret(ctx, namespace)
+	case c.config.Token == "":
+		return nil
+	}
+	return c.createGitHubTokenSecret(ctx, namespace)
+}
+
+// createBucketSecret creates the access key secret the Bucket source authenticates with
+func (c *Client) createBucketSecret(ctx context.Context, namespace string) error {
+	log.Info("Creating bucket credentials secret", "endpoint", c.config.Bucket.Endpoint)
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      "flux-system",
+				"namespace": namespace,
+			},
+			"type": "Opaque",
+			"data": map[string]interface{}{
+				"accesskey": base64.StdEncoding.EncodeToString([]byte(c.config.Bucket.AccessKey)),
+				"secretkey": base64.StdEncoding.EncodeToString([]byte(c.config.Bucket.SecretKey)),
+			},
+		},
+	}
+	return c.applyObject(ctx, secret)
+}
+
+// createOCIPullSecret creates a docker config secret granting access to the artifact registry
+func (c *Client) createOCIPullSecret(ctx context.Context, namespace string) error {
+	registry, _, _ := strings.Cut(strings.TrimPrefix(c.config.Repository, "oci://"), "/")
+	log.Info("Creating OCI registry pull secret", "registry", registry)
+
+	username := c.config.Owner
+	if username == "" {
+		username = "flux"
+	}
+	dockerConfig, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			registry: map[string]string{
+				"username": username,
+				"password": c.config.Token,
+				"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + c.config.Token)),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode registry credentials: %w", err)
+	}
+
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      "flux-system",
+				"namespace": namespace,
+			},
+			"type": "kubernetes.io/dockerconfigjson",
+			"data": map[string]interface{}{
+				".dockerconfigjson": base64.StdEncoding.EncodeToString(dockerConfig),
+			},
+		},
+	}
+	return c.applyObject(ctx, secret)
+}
@@ -0,0 +1,158 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SyncDrift lists the differences between the deployed flux-system sync objects and the GitOps config
+func (c *Client) SyncDrift(ctx context.Context, namespace string) ([]string, error) {
+	dynamicClient := c.k8sClient.GetDynamicClient()
+	var drift []string
+
+	kind := c.sourceKind()
+	repo, err := dynamicClient.Resource(SourceGVR(c.config)).Namespace(namespace).Get(ctx, "flux-system", metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		drift = append(drift, kind+" flux-system missing")
+	case err != nil:
+		return nil, fmt.Errorf("failed to get %s flux-system: %w", kind, err)
+	case c.config.IsOCI():
+		drift = append(drift, c.ociSourceDrift(repo)...)
+	case c.config.IsBucket():
+		drift = append(drift, c.bucketSourceDrift(repo)...)
+	default:
+		url, _, _ := unstructured.NestedString(repo.Object, "spec", "url")
+		branch, _, _ := unstructured.NestedString(repo.Object, "spec", "ref", "branch")
+		secret, _, _ := unstructured.NestedString(repo.Object, "spec", "secretRef", "name")
+		if url != c.repositoryURL() {
+			drift = append(drift, fmt.Sprintf("repository %s → %s", url, c.repositoryURL()))
+		}
+		if branch != c.config.Branch {
+			drift = append(drift, fmt.Sprintf("branch %s → %s", branch, c.config.Branch))
+		}
+		if (secret != "") != (c.config.Token != "") {
+			drift = append(drift, "repository authentication changed")
+		}
+	}
+
+	kustomization, err := dynamicClient.Resource(kustomizationGVR).Namespace(namespace).Get(ctx, "flux-system", metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		drift = append(drift, "Kustomization flux-system missing")
+	case err != nil:
+		return nil, fmt.Errorf("failed to get Kustomization flux-system: %w", err)
+	default:
+		sourceKind, _, _ := unstructured.NestedString(kustomization.Object, "spec", "sourceRef", "kind")
+		if sourceKind != kind {
+			drift = append(drift, fmt.Sprintf("source %s → %s", sourceKind, kind))
+		}
+		path, _, _ := unstructured.NestedString(kustomization.Object, "spec", "path")
+		if strings.TrimPrefix(path, "./") != strings.TrimPrefix(c.config.Path, "./") {
+			drift = append(drift, fmt.Sprintf("path %s → %s", path, c.config.Path))
+		}
+		decryption, _, _ := unstructured.NestedString(kustomization.Object, "spec", "decryption", "secretRef", "name")
+		switch {
+		case c.config.SOPS.Enabled && decryption != c.config.SOPS.Secret():
+			drift = append(drift, "SOPS decryption enabled")
+		case !c.config.SOPS.Enabled && decryption != "":
+			drift = append(drift, "SOPS decryption disabled")
+		}
+	}
+	return drift, nil
+}
+
+// ociSourceDrift compares a deployed OCIRepository with the OCI settings
+func (c *Client) ociSourceDrift(repo *unstructured.Unstructured) []string {
+	var drift []string
+	url, _, _ := unstructured.NestedString(repo.Object, "spec", "url")
+	tag, _, _ := unstructured.NestedString(repo.Object, "spec", "ref", "tag")
+	semver, _, _ := unstructured.NestedString(repo.Object, "spec", "ref", "semver")
+	verifier, _, _ := unstructured.NestedString(repo.Object, "spec", "verify", "provider")
+	if url != c.config.Repository {
+		drift = append(drift, fmt.Sprintf("repository %s → %s", url, c.config.Repository))
+	}
+	if semver != c.config.OCI.Semver {
+		drift = append(drift, fmt.Sprintf("semver %q → %q", semver, c.config.OCI.Semver))
+	} else if semver == "" && tag != c.ociTag() {
+		drift = append(drift, fmt.Sprintf("tag %s → %s", tag, c.ociTag()))
+	}
+	if (verifier != "") != (c.config.OCI.Verify != nil) {
+		drift = append(drift, "signature verification changed")
+	}
+	return drift
+}
+
+// bucketSourceDrift compares a deployed Bucket with the bucket settings
+func (c *Client) bucketSourceDrift(bucket *unstructured.Unstructured) []string {
+	var drift []string
+	endpoint, _, _ := unstructured.NestedString(bucket.Object, "spec", "endpoint")
+	name, _, _ := unstructured.NestedString(bucket.Object, "spec", "bucketName")
+	insecure, _, _ := unstructured.NestedBool(bucket.Object, "spec", "insecure")
+	secret, _, _ := unstructured.NestedString(bucket.Object, "spec", "secretRef", "name")
+	if endpoint != c.config.Bucket.Endpoint {
+		drift = append(drift, fmt.Sprintf("endpoint %s → %s", endpoint, c.config.Bucket.Endpoint))
+	}
+	if name != c.config.Bucket.Name {
+		drift = append(drift, fmt.Sprintf("bucket %s → %s", name, c.config.Bucket.Name))
+	}
+	if insecure != c.config.Bucket.Insecure {
+		drift = append(drift, fmt.Sprintf("insecure %t → %t", insecure, c.config.Bucket.Insecure))
+	}
+	if (secret != "") != c.bucketUsesSecret() {
+		drift = append(drift, "bucket authentication changed")
+	}
+	return drift
+}
+
+// ApplySyncManifests re-applies the flux-system source and Kustomization without waiting for a sync
+func (c *Client) ApplySyncManifests(ctx context.Context, namespace string) error {
+	if err := c.applyManifests(ctx, setSync, []byte(c.generateSyncManifests(namespace))); err != nil {
+		return fmt.Errorf("failed to apply sync manifests: %w", err)
+	}
+	if err := c.createSourceSecret(ctx, namespace); err != nil {
+		log.Warn("Failed to create source credentials secret", "error", err)
+	}
+	return nil
+}
+
+// SubstitutionConsumers returns the Kustomizations substituting variables from the named Secret or ConfigMap
+func (c *Client) SubstitutionConsumers(ctx context.Context, name string) ([]types.NamespacedName, error) {
+	list, err := c.k8sClient.GetDynamicClient().Resource(kustomizationGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kustomizations: %w", err)
+	}
+
+	var consumers []types.NamespacedName
+	for _, item := range list.Items {
+		sources, _, _ := unstructured.NestedSlice(item.Object, "spec", "postBuild", "substituteFrom")
+		for _, source := range sources {
+			ref, ok := source.(map[string]interface{})
+			if ok && ref["name"] == name {
+				consumers = append(consumers, types.NamespacedName{Namespace: item.GetNamespace(), Name: item.GetName()})
+				break
+			}
+		}
+	}
+	return consumers, nil
+}
+
+// ReconcileSource requests an immediate fetch of the flux-system source kind
+func (c *Client) ReconcileSource(ctx context.Context, namespace, name string) error {
+	log.Info("Triggering reconciliation", "namespace", namespace, "name", name)
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{"reconcile.fluxcd.io/requestedAt":"%s"}}}`, time.Now().Format(time.RFC3339))
+	_, err := c.k8sClient.GetDynamicClient().Resource(SourceGVR(c.config)).Namespace(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile %s %s/%s: %w", c.sourceKind(), namespace, name, err)
+	}
+	return nil
+}
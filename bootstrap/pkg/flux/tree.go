@@ -0,0 +1,247 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// treeSourceKinds are the sources a tree is rooted at
+var treeSourceKinds = []string{"GitRepository", "OCIRepository", "Bucket"}
+
+// TreeNode is one object of the reconciliation tree, from a source down to the workloads
+type TreeNode struct {
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Ready     bool        `json:"ready"`
+	Suspended bool        `json:"suspended,omitempty"`
+	Revision  string      `json:"revision,omitempty"`
+	Message   string      `json:"message,omitempty"`
+	Children  []*TreeNode `json:"children,omitempty"`
+}
+
+// Ref returns the node as <kind>/<namespace>/<name>
+func (n *TreeNode) Ref() string {
+	return n.Kind + "/" + n.Namespace + "/" + n.Name
+}
+
+// Find returns the node of the given kind, namespace and name in the subtree, or nil
+func (n *TreeNode) Find(kind, namespace, name string) *TreeNode {
+	if n.Kind == kind && n.Namespace == namespace && n.Name == name {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := child.Find(kind, namespace, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Tree walks sources to the Kustomizations applying them, their nested Kustomizations and HelmReleases,
+// and the workloads behind both. A Kustomization appears under the object that applied it, otherwise
+// under its source.
+func (c *Client) Tree(ctx context.Context) ([]*TreeNode, error) {
+	kustomizations, err := c.listFlux(ctx, "Kustomization")
+	if err != nil {
+		return nil, err
+	}
+	releases, err := c.listFlux(ctx, "HelmRelease")
+	if err != nil {
+		log.Debug("HelmReleases not listed", "error", err)
+	}
+	workloads := c.listWorkloads(ctx)
+
+	nodes := map[string]*TreeNode{}
+	for _, item := range append(kustomizations, releases...) {
+		node := fluxNode(item)
+		nodes[node.Ref()] = node
+	}
+
+	// Attach what each Kustomization applied, remembering what has a parent
+	applied := map[string]bool{}
+	for _, ks := range kustomizations {
+		self := treeRef("Kustomization", ks.GetNamespace(), ks.GetName())
+		parent := nodes[self]
+		entries, _, _ := unstructured.NestedSlice(ks.Object, "status", "inventory", "entries")
+		for _, raw := range entries {
+			entry, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := entry["id"].(string)
+			// Inventory ids are <namespace>_<name>_<group>_<kind>
+			parts := strings.Split(id, "_")
+			if len(parts) != 4 {
+				continue
+			}
+			ref := treeRef(parts[3], parts[0], parts[1])
+			switch {
+			case ref == self:
+				// flux-system applies itself, it stays under its source
+			case nodes[ref] != nil && (parts[2] == fluxKindGroups["Kustomization"] || parts[2] == fluxKindGroups["HelmRelease"]):
+				parent.Children = append(parent.Children, nodes[ref])
+				applied[ref] = true
+			case parts[2] == "apps" && workloadKinds[parts[3]]:
+				if workload, ok := workloads[ref]; ok {
+					parent.Children = append(parent.Children, workloadNode(workload))
+				}
+			}
+		}
+	}
+
+	// HelmReleases own the workloads carrying their labels
+	for _, hr := range releases {
+		parent := nodes[treeRef("HelmRelease", hr.GetNamespace(), hr.GetName())]
+		for _, workload := range workloads {
+			labels := workload.GetLabels()
+			if labels[helmReleaseNameLabel] == hr.GetName() && labels[helmReleaseNamespaceLabel] == hr.GetNamespace() {
+				parent.Children = append(parent.Children, workloadNode(workload))
+			}
+		}
+	}
+
+	sources := map[string]*TreeNode{}
+	var roots []*TreeNode
+	for _, kind := range treeSourceKinds {
+		items, err := c.listFlux(ctx, kind)
+		if err != nil {
+			log.Debug("Flux sources not listed", "kind", kind, "error", err)
+			continue
+		}
+		for _, item := range items {
+			node := fluxNode(item)
+			sources[node.Ref()] = node
+			roots = append(roots, node)
+		}
+	}
+
+	for _, ks := range kustomizations {
+		ref := treeRef("Kustomization", ks.GetNamespace(), ks.GetName())
+		if applied[ref] {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "kind")
+		name, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "name")
+		namespace, _, _ := unstructured.NestedString(ks.Object, "spec", "sourceRef", "namespace")
+		if namespace == "" {
+			namespace = ks.GetNamespace()
+		}
+		sourceRef := treeRef(kind, namespace, name)
+		source, ok := sources[sourceRef]
+		if !ok {
+			source = &TreeNode{Kind: kind, Namespace: namespace, Name: name, Message: "source not found"}
+			sources[sourceRef] = source
+			roots = append(roots, source)
+		}
+		source.Children = append(source.Children, nodes[ref])
+	}
+
+	sortTree(roots)
+	return roots, nil
+}
+
+// listFlux lists a Flux kind across all namespaces at the version the cluster serves
+func (c *Client) listFlux(ctx context.Context, kind string) ([]unstructured.Unstructured, error) {
+	resource, _, err := c.resourceFor(kind, metav1.NamespaceAll)
+	if err != nil {
+		return nil, err
+	}
+	list, err := resource.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+	return list.Items, nil
+}
+
+// listWorkloads lists every pod controller, keyed by tree reference
+func (c *Client) listWorkloads(ctx context.Context) map[string]*unstructured.Unstructured {
+	workloads := map[string]*unstructured.Unstructured{}
+	for _, resource := range []string{"deployments", "statefulsets", "daemonsets"} {
+		gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: resource}
+		list, err := c.k8sClient.GetDynamicClient().Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Debug("Workloads not listed", "resource", resource, "error", err)
+			continue
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			workloads[treeRef(item.GetKind(), item.GetNamespace(), item.GetName())] = item
+		}
+	}
+	return workloads
+}
+
+func fluxNode(obj unstructured.Unstructured) *TreeNode {
+	node := &TreeNode{
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Suspended: suspended(&obj),
+		Revision:  objectRevision(&obj),
+	}
+	ready, found := readCondition(&obj, "Ready")
+	node.Ready = found && ready.status == "True" && !node.Suspended
+	switch {
+	case node.Suspended:
+		node.Message = "suspended"
+	case !found:
+		node.Message = "not reconciled yet"
+	case !node.Ready:
+		node.Message = fmt.Sprintf("%s: %s", ready.reason, firstLine(ready.message))
+	}
+	return node
+}
+
+func workloadNode(obj *unstructured.Unstructured) *TreeNode {
+	healthy, reason := objectHealth(obj)
+	return &TreeNode{
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Ready:     healthy,
+		Message:   reason,
+	}
+}
+
+// objectRevision returns the revision last fetched by a source or applied by a Kustomization or HelmRelease
+func objectRevision(obj *unstructured.Unstructured) string {
+	if revision, _, _ := unstructured.NestedString(obj.Object, "status", "artifact", "revision"); revision != "" {
+		return revision
+	}
+	if revision, _, _ := unstructured.NestedString(obj.Object, "status", "lastAppliedRevision"); revision != "" {
+		return revision
+	}
+	// helm.toolkit.fluxcd.io/v2 records releases in a history, latest first
+	history, _, _ := unstructured.NestedSlice(obj.Object, "status", "history")
+	if len(history) > 0 {
+		if release, ok := history[0].(map[string]interface{}); ok {
+			chart, _ := release["chartName"].(string)
+			version, _ := release["chartVersion"].(string)
+			if chart != "" {
+				return chart + "@" + version
+			}
+		}
+	}
+	return ""
+}
+
+func sortTree(nodes []*TreeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Ref() < nodes[j].Ref()
+	})
+	for _, node := range nodes {
+		sortTree(node.Children)
+	}
+}
+
+func treeRef(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
@@ -0,0 +1,276 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// versionLabel is the label Flux puts the distribution version in on its namespace
+const versionLabel = "app.kubernetes.io/version"
+
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// CRDChange is how an upgrade changes one of the Flux CRDs
+type CRDChange struct {
+	Name string `json:"name"`
+	// New marks a CRD the installed release does not have
+	New             bool     `json:"new,omitempty"`
+	AddedVersions   []string `json:"added_versions,omitempty"`
+	RemovedVersions []string `json:"removed_versions,omitempty"`
+	StorageFrom     string   `json:"storage_from,omitempty"`
+	StorageTo       string   `json:"storage_to,omitempty"`
+	// SchemaChanged is set when a version served before and after the upgrade gets another schema
+	SchemaChanged bool `json:"schema_changed,omitempty"`
+	// Migrate lists the stored versions the new CRD drops, the objects are rewritten at StorageFrom first
+	Migrate []string `json:"migrate,omitempty"`
+}
+
+// Changed reports whether the upgrade changes the CRD
+func (c CRDChange) Changed() bool {
+	return c.New || len(c.AddedVersions) > 0 || len(c.RemovedVersions) > 0 || c.StorageFrom != c.StorageTo || c.SchemaChanged
+}
+
+// UpgradePlan is what upgrading the Flux controllers to another release changes
+type UpgradePlan struct {
+	From string      `json:"from,omitempty"`
+	To   string      `json:"to"`
+	CRDs []CRDChange `json:"crds,omitempty"`
+
+	manifest string
+	objects  []*unstructured.Unstructured
+}
+
+// UpToDate reports whether the cluster already runs the target release
+func (p *UpgradePlan) UpToDate() bool {
+	if p.From != p.To {
+		return false
+	}
+	for _, crd := range p.CRDs {
+		if crd.Changed() {
+			return false
+		}
+	}
+	return true
+}
+
+// PlanUpgrade renders the install manifests of version, the pinned release or the latest one when empty,
+// and compares their CRDs with the installed ones
+func (c *Client) PlanUpgrade(ctx context.Context, namespace, version string) (*UpgradePlan, error) {
+	if version == "" {
+		version = c.fluxVersion()
+	}
+	manifest, err := installManifest(namespace, version)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := decodeObjects(manifest)
+	if err != nil {
+		return nil, err
+	}
+	plan := &UpgradePlan{manifest: manifest, objects: objects}
+
+	for _, obj := range objects {
+		if obj.GetKind() == "Namespace" && obj.GetName() == namespace {
+			plan.To = obj.GetLabels()[versionLabel]
+		}
+	}
+	if plan.To == "" {
+		return nil, fmt.Errorf("the rendered Flux manifests carry no %s label on the %s namespace", versionLabel, namespace)
+	}
+	if version != "" && airgap.Enabled() && strings.TrimPrefix(plan.To, "v") != strings.TrimPrefix(version, "v") {
+		return nil, fmt.Errorf("the air-gap bundle holds Flux %s, rebuild it with bootstrap airgap bundle --flux-version %s", plan.To, version)
+	}
+
+	installed, err := c.k8sClient.GetClientset().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("flux is not installed in %s, run bootstrap homelab bootstrap first", namespace)
+		}
+		return nil, fmt.Errorf("failed to read the installed Flux version: %w", err)
+	}
+	plan.From = installed.Labels[versionLabel]
+
+	crds := c.k8sClient.GetDynamicClient().Resource(crdGVR)
+	for _, obj := range objects {
+		if obj.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		live, err := crds.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			plan.CRDs = append(plan.CRDs, CRDChange{Name: obj.GetName(), New: true, StorageTo: storageVersion(obj)})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CRD %s: %w", obj.GetName(), err)
+		}
+		plan.CRDs = append(plan.CRDs, compareCRDs(live, obj))
+	}
+	return plan, nil
+}
+
+// Upgrade migrates the objects stored at the versions the new CRDs drop, applies the manifests of the plan
+// with server-side apply, waits for every controller to roll out and verifies the GitOps sync again
+func (c *Client) Upgrade(ctx context.Context, namespace string, plan *UpgradePlan) error {
+	for _, crd := range plan.CRDs {
+		if len(crd.Migrate) == 0 {
+			continue
+		}
+		if err := c.migrateStoredVersions(ctx, crd); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Applying the Flux manifests", "from", plan.From, "to", plan.To)
+	if err := c.applyManifests(ctx, setInstall, []byte(plan.manifest)); err != nil {
+		return fmt.Errorf("failed to apply flux manifests: %w", err)
+	}
+
+	for _, obj := range plan.objects {
+		if obj.GetKind() != "Deployment" {
+			continue
+		}
+		log.Info("Waiting for controller rollout", "controller", obj.GetName())
+		if err := c.k8sClient.WaitForRollout(ctx, obj.GetNamespace(), obj.GetName(), 5*time.Minute); err != nil {
+			return fmt.Errorf("controller %s did not roll out: %w", obj.GetName(), err)
+		}
+	}
+
+	return c.verifySync(ctx, namespace)
+}
+
+// verifySync reconciles the flux-system source and Kustomization and waits for both to be ready, skipped
+// when the GitOps sync was never bootstrapped
+func (c *Client) verifySync(ctx context.Context, namespace string) error {
+	_, err := c.k8sClient.GetDynamicClient().Resource(SourceGVR(c.config)).Namespace(namespace).Get(ctx, "flux-system", metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		log.Info("No GitOps sync to verify", "kind", c.sourceKind(), "namespace", namespace)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the %s source: %w", c.sourceKind(), err)
+	}
+
+	if err := c.ReconcileSource(ctx, namespace, "flux-system"); err != nil {
+		return err
+	}
+	if err := c.WaitForSync(ctx, namespace, "flux-system", 5*time.Minute); err != nil {
+		return fmt.Errorf("repository sync failed after the upgrade: %w", err)
+	}
+	if err := c.TriggerReconcile(ctx, namespace, "flux-system"); err != nil {
+		return fmt.Errorf("failed to reconcile the flux-system Kustomization: %w", err)
+	}
+	if err := c.WaitForKustomization(ctx, namespace, "flux-system", 5*time.Minute); err != nil {
+		return fmt.Errorf("flux-system Kustomization not ready after the upgrade: %w", err)
+	}
+	return nil
+}
+
+// migrateStoredVersions rewrites every object of the CRD so the API server stores it at the current storage
+// version, then drops the other versions from the CRD status. The new CRD would be rejected while its
+// status lists a stored version it no longer defines.
+func (c *Client) migrateStoredVersions(ctx context.Context, crd CRDChange) error {
+	if slices.Contains(crd.RemovedVersions, crd.StorageFrom) {
+		return fmt.Errorf("CRD %s stores objects at %s, which the new release drops, upgrade through an intermediate Flux release first", crd.Name, crd.StorageFrom)
+	}
+	plural, group, _ := strings.Cut(crd.Name, ".")
+	resource := c.k8sClient.GetDynamicClient().Resource(schema.GroupVersionResource{Group: group, Version: crd.StorageFrom, Resource: plural})
+
+	list, err := resource.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", crd.Name, err)
+	}
+	log.Info("Migrating stored objects", "crd", crd.Name, "objects", len(list.Items), "from", strings.Join(crd.Migrate, ","), "to", crd.StorageFrom)
+	for _, item := range list.Items {
+		namespaced := resource.Namespace(item.GetNamespace())
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := namespaced.Get(ctx, item.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			// An unchanged update is enough for the API server to write the object at the storage version
+			_, err = namespaced.Update(ctx, current, metav1.UpdateOptions{FieldManager: k8s.FieldManager})
+			return err
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to migrate %s %s/%s: %w", crd.Name, item.GetNamespace(), item.GetName(), err)
+		}
+	}
+
+	patch := fmt.Sprintf(`{"status":{"storedVersions":[%q]}}`, crd.StorageFrom)
+	if _, err := c.k8sClient.GetDynamicClient().Resource(crdGVR).Patch(ctx, crd.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}, "status"); err != nil {
+		return fmt.Errorf("failed to update the stored versions of %s: %w", crd.Name, err)
+	}
+	return nil
+}
+
+// compareCRDs lists what replacing the live CRD with the rendered one changes
+func compareCRDs(live, rendered *unstructured.Unstructured) CRDChange {
+	change := CRDChange{Name: rendered.GetName(), StorageFrom: storageVersion(live), StorageTo: storageVersion(rendered)}
+	liveVersions := servedVersions(live)
+	renderedVersions := servedVersions(rendered)
+
+	for name, schema := range renderedVersions {
+		previous, ok := liveVersions[name]
+		if !ok {
+			change.AddedVersions = append(change.AddedVersions, name)
+		} else if !reflect.DeepEqual(previous, schema) {
+			change.SchemaChanged = true
+		}
+	}
+	for name := range liveVersions {
+		if _, ok := renderedVersions[name]; !ok {
+			change.RemovedVersions = append(change.RemovedVersions, name)
+		}
+	}
+	stored, _, _ := unstructured.NestedStringSlice(live.Object, "status", "storedVersions")
+	for _, version := range stored {
+		if _, ok := renderedVersions[version]; !ok {
+			change.Migrate = append(change.Migrate, version)
+		}
+	}
+	slices.Sort(change.AddedVersions)
+	slices.Sort(change.RemovedVersions)
+	slices.Sort(change.Migrate)
+	return change
+}
+
+// servedVersions maps the versions a CRD serves to their schema
+func servedVersions(crd *unstructured.Unstructured) map[string]interface{} {
+	served := map[string]interface{}{}
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, entry := range versions {
+		version, ok := entry.(map[string]interface{})
+		if !ok || version["served"] != true {
+			continue
+		}
+		name, _ := version["name"].(string)
+		served[name] = version["schema"]
+	}
+	return served
+}
+
+// storageVersion returns the version a CRD stores its objects at
+func storageVersion(crd *unstructured.Unstructured) string {
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, entry := range versions {
+		if version, ok := entry.(map[string]interface{}); ok && version["storage"] == true {
+			name, _ := version["name"].(string)
+			return name
+		}
+	}
+	return ""
+}
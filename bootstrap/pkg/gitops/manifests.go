@@ -0,0 +1,265 @@
+package gitops
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kustomization is a Flux Kustomization reconciling a directory of the GitOps repository
+type Kustomization struct {
+	Name      string
+	Path      string
+	DependsOn string
+}
+
+// Render renders the Flux Kustomization for cluster
+func (k Kustomization) Render(cluster string) string {
+	return fmt.Sprintf(`apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: %s
+  namespace: flux-system
+spec:
+  interval: 10m
+  sourceRef:
+    kind: GitRepository
+    name: flux-system
+  path: ./kubernetes/%s/%s
+  prune: true
+  wait: true
+  dependsOn:
+    - name: %s
+      namespace: flux-system
+`, k.Name, cluster, k.Path, k.DependsOn)
+}
+
+// RenderKustomizations renders several Flux Kustomizations as one multi-document file
+func RenderKustomizations(cluster string, kustomizations []Kustomization) string {
+	docs := make([]string, 0, len(kustomizations))
+	for _, kustomization := range kustomizations {
+		docs = append(docs, kustomization.Render(cluster))
+	}
+	return strings.Join(docs, "---\n")
+}
+
+// HelmChart describes a Helm chart installed by Flux
+type HelmChart struct {
+	Repository string
+	URL        string
+	Chart      string
+	Version    string
+	Namespace  string
+}
+
+// RenderRepository renders the Flux HelmRepository of the chart
+func (c HelmChart) RenderRepository() string {
+	return fmt.Sprintf(`apiVersion: source.toolkit.fluxcd.io/v1
+kind: HelmRepository
+metadata:
+  name: %s
+  namespace: flux-system
+spec:
+  interval: 24h
+  url: %s
+`, c.Repository, c.URL)
+}
+
+// RenderRelease renders the Flux HelmRelease of the chart, appending extra spec fields such as values
+func (c HelmChart) RenderRelease(extra string) string {
+	release := fmt.Sprintf(`apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: %s
+  namespace: flux-system
+spec:
+  targetNamespace: %s
+  interval: 30m
+  chart:
+    spec:
+      chart: %s
+      version: "%s"
+      sourceRef:
+        kind: HelmRepository
+        name: %s
+        namespace: flux-system
+      interval: 12h
+  install:
+    createNamespace: true
+    crds: CreateReplace
+    remediation:
+      retries: 3
+  upgrade:
+    cleanupOnFail: true
+    crds: CreateReplace
+    remediation:
+      retries: 3
+`, c.Chart, c.Namespace, c.Chart, c.Version, c.Repository)
+	if extra != "" {
+		release += Indent(strings.TrimRight(extra, "\n"), 2) + "\n"
+	}
+	return release
+}
+
+// ChartInstalled checks whether a HelmRelease for chart exists under clusterDir, ignoring skipDir
+func ChartInstalled(clusterDir, chart, skipDir string) bool {
+	found := false
+	_ = filepath.WalkDir(clusterDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || found {
+			return nil
+		}
+		if entry.IsDir() {
+			if skipDir != "" && path == skipDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		content := string(data)
+		if strings.Contains(content, "kind: HelmRelease") && strings.Contains(content, "chart: "+chart) {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+// FoundationKustomization returns the Flux Kustomization generated layers depend on
+func FoundationKustomization(cluster string) string {
+	return Name(cluster, "platform-foundation")
+}
+
+// Name prefixes base with the cluster name on the NAS, matching the existing Flux Kustomizations
+func Name(cluster, base string) string {
+	if cluster == "nas" {
+		return "nas-" + base
+	}
+	return base
+}
+
+// RenderKustomization renders a kustomize kustomization.yaml listing resources
+func RenderKustomization(resources []string) string {
+	return fmt.Sprintf(`apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+%s
+`, YAMLList(resources, 2))
+}
+
+// AddResource appends resource to the resources list of a kustomization.yaml if missing
+func AddResource(path, resource string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	inResources := false
+	insertAt := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "- "+resource {
+			return false, nil
+		}
+		switch {
+		case strings.HasPrefix(line, "resources:"):
+			inResources = true
+			insertAt = i + 1
+		case inResources && strings.HasPrefix(trimmed, "- "):
+			insertAt = i + 1
+		case inResources && trimmed != "" && !strings.HasPrefix(line, " "):
+			inResources = false
+		}
+	}
+	if insertAt < 0 {
+		return false, fmt.Errorf("no resources list in %s", path)
+	}
+
+	lines = append(lines[:insertAt], append([]string{"  - " + resource}, lines[insertAt:]...)...)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		return false, fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// WriteFiles writes files keyed by absolute path and returns their paths relative to projectRoot
+func WriteFiles(projectRoot string, files map[string]string) ([]string, error) {
+	written := make([]string, 0, len(files))
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		written = append(written, Relative(projectRoot, path))
+	}
+	sort.Strings(written)
+	return written, nil
+}
+
+// Relative returns path relative to projectRoot when possible
+func Relative(projectRoot, path string) string {
+	if rel, err := filepath.Rel(projectRoot, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// YAMLList renders values as a YAML sequence indented by spaces
+func YAMLList(values []string, spaces int) string {
+	pad := strings.Repeat(" ", spaces)
+	lines := make([]string, 0, len(values))
+	for _, value := range values {
+		lines = append(lines, fmt.Sprintf("%s- %s", pad, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Indent indents every non-empty line of text by spaces
+func Indent(text string, spaces int) string {
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ServiceFromURL extracts the Kubernetes service, namespace and port of a cluster-local URL
+func ServiceFromURL(endpoint string) (name, namespace, port string, err error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return "", "", "", fmt.Errorf("invalid service URL %q", endpoint)
+	}
+
+	host := parsed.Hostname()
+	if net.ParseIP(host) != nil || !strings.Contains(host, ".svc") {
+		return "", "", "", fmt.Errorf("%q is not a Kubernetes service URL", endpoint)
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) < 3 {
+		return "", "", "", fmt.Errorf("%q is not a Kubernetes service URL", endpoint)
+	}
+
+	port = parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return parts[0], parts[1], port, nil
+}
@@ -2,8 +2,11 @@ package health
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -12,9 +15,19 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// defaultConcurrency bounds how many component checks run at the same time
+	defaultConcurrency = 4
+	// defaultCheckTimeout bounds how long a single component check may take
+	defaultCheckTimeout = 10 * time.Second
+)
+
 // HealthChecker performs comprehensive cluster health validation
 type HealthChecker struct {
-	client *k8s.Client
+	client       *k8s.Client
+	concurrency  int
+	checkTimeout time.Duration
+	ingress      *IngressOptions
 }
 
 // HealthStatus represents the overall cluster health
@@ -22,6 +35,7 @@ type HealthStatus struct {
 	Overall    HealthState            `json:"overall"`
 	Components map[string]HealthState `json:"components"`
 	Details    map[string]string      `json:"details"`
+	Failures   []string               `json:"failures,omitempty"`
 	Timestamp  time.Time              `json:"timestamp"`
 }
 
@@ -35,10 +49,33 @@ const (
 	HealthStateUnknown   HealthState = "unknown"
 )
 
+// componentCheck is an independent health check reporting a single component
+type componentCheck struct {
+	component string
+	name      string
+	run       func(ctx context.Context, status *HealthStatus) error
+}
+
 // NewHealthChecker creates a new health checker
 func NewHealthChecker(client *k8s.Client) *HealthChecker {
 	return &HealthChecker{
-		client: client,
+		client:       client,
+		concurrency:  defaultConcurrency,
+		checkTimeout: defaultCheckTimeout,
+	}
+}
+
+// SetConcurrency sets how many component checks may run in parallel
+func (hc *HealthChecker) SetConcurrency(n int) {
+	if n > 0 {
+		hc.concurrency = n
+	}
+}
+
+// SetCheckTimeout sets the per-check timeout
+func (hc *HealthChecker) SetCheckTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		hc.checkTimeout = timeout
 	}
 }
 
@@ -46,56 +83,117 @@ func NewHealthChecker(client *k8s.Client) *HealthChecker {
 func (hc *HealthChecker) CheckClusterHealth(ctx context.Context) (*HealthStatus, error) {
 	log.Info("Performing comprehensive cluster health check")
 
-	status := &HealthStatus{
-		Components: make(map[string]HealthState),
-		Details:    make(map[string]string),
-		Timestamp:  time.Now(),
+	checks := []componentCheck{
+		{component: "api_server", name: "API Server", run: hc.checkAPIServer},
+		{component: "nodes", name: "Node", run: hc.checkNodeHealth},
+		{component: "cni", name: "CNI", run: hc.checkCNIHealth},
+		{component: "dns", name: "DNS", run: hc.checkDNSHealth},
+		{component: "storage", name: "Storage", run: hc.checkStorageHealth},
+		{component: "control_plane", name: "Control plane", run: hc.checkControlPlaneHealth},
+		{component: "network_connectivity", name: "Network connectivity", run: hc.checkNetworkConnectivity},
 	}
-
-	// Check API Server Health
-	if err := hc.checkAPIServer(ctx, status); err != nil {
-		log.Error("API Server health check failed", "error", err)
+	if hc.ingress != nil {
+		checks = append(checks, hc.ingressChecks()...)
 	}
+	status := hc.runChecks(ctx, checks)
 
-	// Check Node Health
-	if err := hc.checkNodeHealth(ctx, status); err != nil {
-		log.Error("Node health check failed", "error", err)
-	}
+	log.Info("Cluster health check completed",
+		"overall", status.Overall,
+		"healthy_components", hc.countHealthyComponents(status.Components),
+		"total_components", len(status.Components),
+		"failed_checks", len(status.Failures))
 
-	// Check CNI Health
-	if err := hc.checkCNIHealth(ctx, status); err != nil {
-		log.Error("CNI health check failed", "error", err)
-	}
+	return status, nil
+}
 
-	// Check DNS Resolution
-	if err := hc.checkDNSHealth(ctx, status); err != nil {
-		log.Error("DNS health check failed", "error", err)
+// runChecks runs checks concurrently, bounded by the concurrency, and merges what they report
+func (hc *HealthChecker) runChecks(ctx context.Context, checks []componentCheck) *HealthStatus {
+	status := &HealthStatus{
+		Components: make(map[string]HealthState),
+		Details:    make(map[string]string),
+		Timestamp:  time.Now(),
 	}
 
-	// Check Storage Health
-	if err := hc.checkStorageHealth(ctx, status); err != nil {
-		log.Error("Storage health check failed", "error", err)
-	}
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, hc.concurrency)
+	)
+
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check componentCheck) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				mu.Lock()
+				defer mu.Unlock()
+				status.Components[check.component] = HealthStateUnknown
+				status.Details[check.component] = fmt.Sprintf("Check not run: %v", ctx.Err())
+				status.Failures = append(status.Failures, check.component)
+				return
+			}
 
-	// Check Control Plane Health
-	if err := hc.checkControlPlaneHealth(ctx, status); err != nil {
-		log.Error("Control plane health check failed", "error", err)
-	}
+			result, err := hc.runCheck(ctx, check)
 
-	// Check Network Connectivity
-	if err := hc.checkNetworkConnectivity(ctx, status); err != nil {
-		log.Error("Network connectivity check failed", "error", err)
+			mu.Lock()
+			defer mu.Unlock()
+			for component, state := range result.Components {
+				status.Components[component] = state
+			}
+			for component, detail := range result.Details {
+				status.Details[component] = detail
+			}
+			if err != nil {
+				log.Error(check.name+" health check failed", "error", err)
+				status.Failures = append(status.Failures, check.component)
+			}
+		}(check)
 	}
+	wg.Wait()
+	sort.Strings(status.Failures)
 
 	// Determine overall health
 	status.Overall = hc.calculateOverallHealth(status.Components)
+	return status
+}
 
-	log.Info("Cluster health check completed",
-		"overall", status.Overall,
-		"healthy_components", hc.countHealthyComponents(status.Components),
-		"total_components", len(status.Components))
+// runCheck runs a single check with its own timeout, reporting the component as unhealthy if it hangs
+func (hc *HealthChecker) runCheck(ctx context.Context, check componentCheck) (*HealthStatus, error) {
+	checkCtx, cancel := context.WithTimeout(ctx, hc.checkTimeout)
+	defer cancel()
 
-	return status, nil
+	type outcome struct {
+		status *HealthStatus
+		err    error
+	}
+	done := make(chan outcome, 1)
+
+	go func() {
+		result := &HealthStatus{
+			Components: make(map[string]HealthState),
+			Details:    make(map[string]string),
+		}
+		err := check.run(checkCtx, result)
+		done <- outcome{status: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.status, out.err
+	case <-checkCtx.Done():
+		err := checkCtx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", hc.checkTimeout)
+		}
+		return &HealthStatus{
+			Components: map[string]HealthState{check.component: HealthStateUnhealthy},
+			Details:    map[string]string{check.component: fmt.Sprintf("%s check %v", check.name, err)},
+		}, err
+	}
 }
 
 // checkAPIServer validates Kubernetes API server health
@@ -0,0 +1,238 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// certificateExpiryWarning is how close to its expiry a served certificate turns the TLS check into a warning
+const certificateExpiryWarning = 14 * 24 * time.Hour
+
+// ingressSelectors find the controller deployments of each ingress provider, in any namespace
+var ingressSelectors = map[string]string{
+	"nginx":   "app.kubernetes.io/name=ingress-nginx",
+	"traefik": "app.kubernetes.io/name=traefik",
+	"istio":   "istio=ingressgateway",
+}
+
+// IngressOptions configures the end-to-end DNS and ingress checks
+type IngressOptions struct {
+	// Provider is the ingress controller, nginx, traefik or istio
+	Provider string
+	// ExternalDNS checks external-dns runs besides the controller
+	ExternalDNS bool
+	// Hostname is resolved inside and outside the cluster and its certificate verified at the gateway
+	Hostname string
+	// Nameserver resolves the hostname from outside the cluster, the system resolver when empty
+	Nameserver string
+	// CAFile is a PEM bundle trusted besides the system roots when verifying the served chain
+	CAFile string
+}
+
+// SetIngress adds the DNS and ingress checks to the cluster health
+func (hc *HealthChecker) SetIngress(opts IngressOptions) {
+	hc.ingress = &opts
+}
+
+// CheckIngress runs only the DNS and ingress checks
+func (hc *HealthChecker) CheckIngress(ctx context.Context) (*HealthStatus, error) {
+	if hc.ingress == nil {
+		return nil, fmt.Errorf("ingress checks not configured")
+	}
+	log.Info("Validating DNS and ingress", "hostname", hc.ingress.Hostname)
+	return hc.runChecks(ctx, hc.ingressChecks()), nil
+}
+
+func (hc *HealthChecker) ingressChecks() []componentCheck {
+	checks := []componentCheck{
+		{component: "ingress_controller", name: "Ingress controller", run: hc.checkIngressController},
+	}
+	if hc.ingress.ExternalDNS {
+		checks = append(checks, componentCheck{component: "external_dns", name: "external-dns", run: hc.checkExternalDNS})
+	}
+	if hc.ingress.Hostname != "" {
+		checks = append(checks,
+			componentCheck{component: "dns_cluster", name: "In-cluster DNS", run: hc.checkClusterResolution},
+			componentCheck{component: "dns_external", name: "External DNS", run: hc.checkExternalResolution},
+			componentCheck{component: "ingress_tls", name: "Ingress TLS", run: hc.checkIngressTLS},
+		)
+	}
+	return checks
+}
+
+// checkIngressController checks the deployments of the ingress controller are available
+func (hc *HealthChecker) checkIngressController(ctx context.Context, status *HealthStatus) error {
+	selector, ok := ingressSelectors[hc.ingress.Provider]
+	if !ok {
+		status.Components["ingress_controller"] = HealthStateUnknown
+		status.Details["ingress_controller"] = fmt.Sprintf("Unknown ingress provider %q", hc.ingress.Provider)
+		return nil
+	}
+	return hc.checkDeployments(ctx, status, "ingress_controller", hc.ingress.Provider+" ingress", selector)
+}
+
+// checkExternalDNS checks external-dns is available
+func (hc *HealthChecker) checkExternalDNS(ctx context.Context, status *HealthStatus) error {
+	return hc.checkDeployments(ctx, status, "external_dns", "external-dns", "app.kubernetes.io/name=external-dns")
+}
+
+// checkDeployments reports component unhealthy unless the deployments matching selector are all available
+func (hc *HealthChecker) checkDeployments(ctx context.Context, status *HealthStatus, component, name, selector string) error {
+	deployments, err := hc.client.GetClientset().AppsV1().Deployments("").List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		status.Components[component] = HealthStateUnknown
+		status.Details[component] = fmt.Sprintf("Failed to list %s deployments: %v", name, err)
+		return err
+	}
+	if len(deployments.Items) == 0 {
+		status.Components[component] = HealthStateUnhealthy
+		status.Details[component] = fmt.Sprintf("No %s deployment found (%s)", name, selector)
+		return fmt.Errorf("no %s deployment found", name)
+	}
+
+	var unavailable []string
+	for _, deployment := range deployments.Items {
+		if deployment.Status.AvailableReplicas == 0 || deployment.Status.AvailableReplicas < deployment.Status.Replicas {
+			unavailable = append(unavailable, fmt.Sprintf("%s/%s (%d/%d)", deployment.Namespace, deployment.Name,
+				deployment.Status.AvailableReplicas, deployment.Status.Replicas))
+		}
+	}
+	if len(unavailable) > 0 {
+		status.Components[component] = HealthStateUnhealthy
+		status.Details[component] = fmt.Sprintf("%s not available: %s", name, strings.Join(unavailable, ", "))
+		return fmt.Errorf("%s not available", name)
+	}
+	status.Components[component] = HealthStateHealthy
+	status.Details[component] = fmt.Sprintf("%s available (%d deployments)", name, len(deployments.Items))
+	return nil
+}
+
+// checkClusterResolution resolves the hostname through the cluster DNS, reached over a port-forward
+func (hc *HealthChecker) checkClusterResolution(ctx context.Context, status *HealthStatus) error {
+	port, stop, err := hc.client.PortForward(ctx, "kube-system", "kube-dns", 53)
+	if err != nil {
+		status.Components["dns_cluster"] = HealthStateUnknown
+		status.Details["dns_cluster"] = fmt.Sprintf("Cluster DNS unreachable: %v", err)
+		return err
+	}
+	defer stop()
+
+	// The port-forward only carries TCP, so the query goes over DNS-over-TCP
+	resolver := tcpResolver(net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	addrs, err := resolver.LookupHost(ctx, hc.ingress.Hostname)
+	if err != nil {
+		status.Components["dns_cluster"] = HealthStateUnhealthy
+		status.Details["dns_cluster"] = fmt.Sprintf("%s does not resolve in the cluster: %v", hc.ingress.Hostname, err)
+		return err
+	}
+	status.Components["dns_cluster"] = HealthStateHealthy
+	status.Details["dns_cluster"] = fmt.Sprintf("%s resolves to %s in the cluster", hc.ingress.Hostname, joinSorted(addrs))
+	return nil
+}
+
+// checkExternalResolution resolves the hostname like a LAN client would
+func (hc *HealthChecker) checkExternalResolution(ctx context.Context, status *HealthStatus) error {
+	addrs, err := hc.externalResolver().LookupHost(ctx, hc.ingress.Hostname)
+	if err != nil {
+		status.Components["dns_external"] = HealthStateUnhealthy
+		status.Details["dns_external"] = fmt.Sprintf("%s does not resolve outside the cluster: %v", hc.ingress.Hostname, err)
+		return err
+	}
+	status.Components["dns_external"] = HealthStateHealthy
+	status.Details["dns_external"] = fmt.Sprintf("%s resolves to %s", hc.ingress.Hostname, joinSorted(addrs))
+	return nil
+}
+
+// checkIngressTLS connects to the hostname on 443 and verifies the served chain and its expiry
+func (hc *HealthChecker) checkIngressTLS(ctx context.Context, status *HealthStatus) error {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if hc.ingress.CAFile != "" {
+		pem, err := os.ReadFile(hc.ingress.CAFile)
+		if err != nil {
+			status.Components["ingress_tls"] = HealthStateUnknown
+			status.Details["ingress_tls"] = fmt.Sprintf("Failed to read CA bundle: %v", err)
+			return err
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			status.Components["ingress_tls"] = HealthStateUnknown
+			status.Details["ingress_tls"] = fmt.Sprintf("No certificate found in %s", hc.ingress.CAFile)
+			return fmt.Errorf("no certificate found in %s", hc.ingress.CAFile)
+		}
+	}
+
+	addrs, err := hc.externalResolver().LookupHost(ctx, hc.ingress.Hostname)
+	if err != nil || len(addrs) == 0 {
+		status.Components["ingress_tls"] = HealthStateUnknown
+		status.Details["ingress_tls"] = fmt.Sprintf("%s does not resolve, TLS not checked", hc.ingress.Hostname)
+		return nil
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: hc.ingress.Hostname, RootCAs: roots}}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addrs[0], "443"))
+	if err != nil {
+		status.Components["ingress_tls"] = HealthStateUnhealthy
+		status.Details["ingress_tls"] = fmt.Sprintf("TLS handshake with %s failed: %v", hc.ingress.Hostname, err)
+		return err
+	}
+	defer conn.Close()
+
+	chain := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	leaf := chain[0]
+	remaining := time.Until(leaf.NotAfter)
+	detail := fmt.Sprintf("%s serves a valid chain of %d issued by %q, expires %s",
+		hc.ingress.Hostname, len(chain), leaf.Issuer.CommonName, leaf.NotAfter.UTC().Format(time.RFC3339))
+	if remaining < certificateExpiryWarning {
+		status.Components["ingress_tls"] = HealthStateWarning
+		status.Details["ingress_tls"] = detail + fmt.Sprintf(" (in %s)", remaining.Round(time.Hour))
+		return nil
+	}
+	status.Components["ingress_tls"] = HealthStateHealthy
+	status.Details["ingress_tls"] = detail
+	return nil
+}
+
+// externalResolver queries the configured nameserver, or the system resolver
+func (hc *HealthChecker) externalResolver() *net.Resolver {
+	if hc.ingress.Nameserver == "" {
+		return net.DefaultResolver
+	}
+	address := hc.ingress.Nameserver
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}
+}
+
+// tcpResolver queries the nameserver at address over TCP
+func tcpResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "tcp", address)
+		},
+	}
+}
+
+func joinSorted(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
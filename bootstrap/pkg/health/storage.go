@@ -0,0 +1,198 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// smokeNamespace always holds the registry pull secret the writer image may need
+	smokeNamespace = "kube-system"
+	smokeName      = "homelab-bootstrap-storage-smoke"
+	smokeImage     = "debian:bookworm-slim"
+	smokeMarker    = "homelab-storage-smoke"
+	// smokeWrites is how many 4k synchronous writes the IOPS and latency are measured over
+	smokeWrites         = 500
+	defaultSmokeTimeout = 3 * time.Minute
+)
+
+// smokeScript checks a write survives an fsync, then times synchronous 4k writes
+var smokeScript = fmt.Sprintf(`set -e
+echo %[1]s > /data/marker
+sync /data/marker
+test "$(cat /data/marker)" = %[1]s
+start=$(date +%%s%%N)
+dd if=/dev/zero of=/data/smoke bs=4k count=%[2]d oflag=dsync status=none
+end=$(date +%%s%%N)
+echo "writes=%[2]d elapsed_ns=$((end - start))"
+`, smokeMarker, smokeWrites)
+
+// StorageSmokeOptions configures the storage smoke test
+type StorageSmokeOptions struct {
+	// StorageClass is the class the volume is provisioned with, the default class when empty
+	StorageClass string
+	// MinIOPS fails the test below this many synchronous writes per second, 0 only reports them
+	MinIOPS int
+	Timeout time.Duration
+}
+
+// StorageSmokeResult reports how the provisioned volume performed
+type StorageSmokeResult struct {
+	StorageClass string        `json:"storageClass"`
+	BindTime     time.Duration `json:"bindTime"`
+	Writes       int           `json:"writes"`
+	IOPS         float64       `json:"iops"`
+	Latency      time.Duration `json:"latency"`
+}
+
+// StorageSmokeTest provisions a volume, has a pod write to it with fsync and measures synchronous write
+// IOPS and latency, then removes both. A volume that does not bind, a lost write or too few IOPS fail it.
+func StorageSmokeTest(ctx context.Context, client *k8s.Client, opts StorageSmokeOptions) (*StorageSmokeResult, error) {
+	if err := k8s.GuardMutation("run the storage smoke test"); err != nil {
+		return nil, err
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultSmokeTimeout
+	}
+	core := client.GetClientset().CoreV1()
+	cleanup := func(ctx context.Context) {
+		if err := core.Pods(smokeNamespace).Delete(ctx, smokeName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Warn("Failed to delete the storage smoke test pod", "error", err)
+		}
+		if err := core.PersistentVolumeClaims(smokeNamespace).Delete(ctx, smokeName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Warn("Failed to delete the storage smoke test volume", "error", err)
+		}
+	}
+	// A previous run may have been interrupted before cleaning up
+	cleanup(ctx)
+	if err := k8s.Poll(ctx, 2*time.Second, time.Minute, func(ctx context.Context) (bool, error) {
+		_, err := core.PersistentVolumeClaims(smokeNamespace).Get(ctx, smokeName, metav1.GetOptions{})
+		return apierrors.IsNotFound(err), nil
+	}); err != nil {
+		return nil, fmt.Errorf("previous storage smoke test volume still terminating: %w", err)
+	}
+	defer cleanup(context.WithoutCancel(ctx))
+
+	labels := map[string]string{"app.kubernetes.io/managed-by": k8s.FieldManager}
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: smokeName, Namespace: smokeNamespace, Labels: labels},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+	}
+	if opts.StorageClass != "" {
+		claim.Spec.StorageClassName = &opts.StorageClass
+	}
+	if _, err := core.PersistentVolumeClaims(smokeNamespace).Create(ctx, claim, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create the smoke test volume: %w", err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: smokeName, Namespace: smokeNamespace, Labels: labels},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:         "writer",
+				Image:        registry.Rewrite(smokeImage),
+				Command:      []string{"sh", "-c", smokeScript},
+				VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: smokeName},
+				},
+			}},
+		},
+	}
+	if secret := registry.PullSecret(); secret != "" {
+		pod.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: secret}}
+	}
+	start := time.Now()
+	if _, err := core.Pods(smokeNamespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create the smoke test pod: %w", err)
+	}
+
+	result := &StorageSmokeResult{StorageClass: opts.StorageClass}
+	var phase corev1.PodPhase
+	var claimPhase corev1.PersistentVolumeClaimPhase
+	err := k8s.Poll(ctx, 2*time.Second, opts.Timeout, func(ctx context.Context) (bool, error) {
+		current, err := core.PersistentVolumeClaims(smokeNamespace).Get(ctx, smokeName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		claimPhase = current.Status.Phase
+		if claimPhase == corev1.ClaimBound && result.BindTime == 0 {
+			result.BindTime = time.Since(start)
+			if current.Spec.StorageClassName != nil {
+				result.StorageClass = *current.Spec.StorageClassName
+			}
+		}
+		running, err := core.Pods(smokeNamespace).Get(ctx, smokeName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		phase = running.Status.Phase
+		return phase == corev1.PodSucceeded || phase == corev1.PodFailed, nil
+	})
+	if err != nil {
+		if claimPhase != corev1.ClaimBound {
+			return nil, fmt.Errorf("smoke test volume is %s, check the storage class and its provisioner: %w", claimPhase, err)
+		}
+		return nil, fmt.Errorf("smoke test pod is %s: %w", phase, err)
+	}
+
+	logs, err := core.Pods(smokeNamespace).GetLogs(smokeName, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the smoke test output: %w", err)
+	}
+	if phase == corev1.PodFailed {
+		return nil, fmt.Errorf("smoke test write failed: %s", strings.TrimSpace(string(logs)))
+	}
+	if err := parseSmokeOutput(string(logs), result); err != nil {
+		return nil, err
+	}
+
+	log.Info("Storage smoke test passed", "class", result.StorageClass, "bind", result.BindTime.Round(time.Millisecond),
+		"iops", fmt.Sprintf("%.0f", result.IOPS), "latency", result.Latency.Round(time.Microsecond))
+	if opts.MinIOPS > 0 && result.IOPS < float64(opts.MinIOPS) {
+		return result, fmt.Errorf("storage class %s sustains %.0f synchronous write IOPS, below the %d required", result.StorageClass, result.IOPS, opts.MinIOPS)
+	}
+	return result, nil
+}
+
+// parseSmokeOutput reads the writes=<n> elapsed_ns=<ns> line the smoke script ends with
+func parseSmokeOutput(output string, result *StorageSmokeResult) error {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	fields := map[string]int64{}
+	for _, field := range strings.Fields(lines[len(lines)-1]) {
+		key, value, _ := strings.Cut(field, "=")
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected smoke test output %q", output)
+		}
+		fields[key] = number
+	}
+	writes, elapsed := fields["writes"], fields["elapsed_ns"]
+	if writes == 0 || elapsed <= 0 {
+		return fmt.Errorf("unexpected smoke test output %q", output)
+	}
+	result.Writes = int(writes)
+	result.Latency = time.Duration(elapsed / writes)
+	result.IOPS = float64(writes) / time.Duration(elapsed).Seconds()
+	return nil
+}
@@ -0,0 +1,291 @@
+package history
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// registryDir is where run records are stored, relative to the project root
+const registryDir = ".bootstrap/history"
+
+// Status is the outcome of a recorded run
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Step is the outcome of a single step within a run
+type Step struct {
+	Name     string        `json:"name"`
+	Success  bool          `json:"success"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Alert is a runtime security alert observed during a run
+type Alert struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Priority string    `json:"priority"`
+	Rule     string    `json:"rule"`
+	Output   string    `json:"output"`
+	Node     string    `json:"node,omitempty"`
+}
+
+// Run is a recorded bootstrap, destroy or upgrade invocation
+type Run struct {
+	ID          string            `json:"id"`
+	Command     string            `json:"command"`
+	Cluster     string            `json:"cluster,omitempty"`
+	Args        []string          `json:"args,omitempty"`
+	Flags       map[string]string `json:"flags,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at,omitempty"`
+	Duration    time.Duration     `json:"duration"`
+	Status      Status            `json:"status"`
+	Error       string            `json:"error,omitempty"`
+	Steps       []Step            `json:"steps,omitempty"`
+	Versions    map[string]string `json:"versions,omitempty"`
+	Alerts      []Alert           `json:"alerts,omitempty"`
+	Endpoints   map[string]string `json:"endpoints,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"`
+	Logs        []string          `json:"logs,omitempty"`
+	GitRevision string            `json:"git_revision,omitempty"`
+
+	mu sync.Mutex
+}
+
+// NewRun creates a new run record for command against cluster
+func NewRun(command, cluster string) *Run {
+	now := time.Now()
+	return &Run{
+		ID:        newID(now),
+		Command:   command,
+		Cluster:   cluster,
+		StartedAt: now,
+		Status:    StatusRunning,
+		Flags:     map[string]string{},
+		Versions:  map[string]string{},
+		Endpoints: map[string]string{},
+	}
+}
+
+// AddStep records the outcome of a step
+func (r *Run) AddStep(name string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	step := Step{Name: name, Success: err == nil, Duration: duration}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	r.Steps = append(r.Steps, step)
+}
+
+// SetVersion records the version of a deployed component
+func (r *Run) SetVersion(component, version string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Versions[component] = version
+}
+
+// SetEndpoint records an endpoint discovered or configured during the run
+func (r *Run) SetEndpoint(name, address string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Endpoints == nil {
+		r.Endpoints = map[string]string{}
+	}
+	r.Endpoints[name] = address
+}
+
+// AddWarning records a problem the run worked around
+func (r *Run) AddWarning(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// AddLog records a log file written during the run
+func (r *Run) AddLog(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	r.Logs = append(r.Logs, path)
+}
+
+// AddAlert records a runtime security alert
+func (r *Run) AddAlert(alert Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Alerts = append(r.Alerts, alert)
+}
+
+// Finish marks the run as completed with the given error
+func (r *Run) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now()
+	r.Duration = r.FinishedAt.Sub(r.StartedAt)
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = err.Error()
+		return
+	}
+	r.Status = StatusSucceeded
+}
+
+type runKey struct{}
+
+// WithRun attaches a run record to ctx so deeper layers can report steps and versions
+func WithRun(ctx context.Context, run *Run) context.Context {
+	return context.WithValue(ctx, runKey{}, run)
+}
+
+// FromContext returns the run record attached to ctx, or nil
+func FromContext(ctx context.Context) *Run {
+	if ctx == nil {
+		return nil
+	}
+	run, _ := ctx.Value(runKey{}).(*Run)
+	return run
+}
+
+// Registry stores run records as JSON files under the project root
+type Registry struct {
+	dir string
+}
+
+// NewRegistry creates a new registry rooted at projectRoot
+func NewRegistry(projectRoot string) *Registry {
+	return &Registry{
+		dir: filepath.Join(projectRoot, registryDir),
+	}
+}
+
+// Save writes the run record, replacing any previous version
+func (r *Registry) Save(run *Run) error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	run.mu.Lock()
+	data, err := json.MarshalIndent(run, "", "  ")
+	run.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode run %s: %w", run.ID, err)
+	}
+
+	path := filepath.Join(r.dir, run.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write run %s: %w", run.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to store run %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+// List returns all recorded runs, newest first
+func (r *Registry) List() ([]*Run, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var runs []*Run
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		run, err := r.load(filepath.Join(r.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	return runs, nil
+}
+
+// Get returns the run with the given id or unique id prefix
+func (r *Registry) Get(id string) (*Run, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, fmt.Errorf("run id is required")
+	}
+
+	if run, err := r.load(filepath.Join(r.dir, id+".json")); err == nil {
+		return run, nil
+	}
+
+	runs, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	var match *Run
+	for _, run := range runs {
+		if strings.HasPrefix(run.ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("run id %q is ambiguous", id)
+			}
+			match = run
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("run %q not found", id)
+	}
+	return match, nil
+}
+
+func (r *Registry) load(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return &run, nil
+}
+
+// GitRevision returns the HEAD commit of the git repository at dir, or an empty string
+func GitRevision(ctx context.Context, dir string) string {
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func newID(now time.Time) string {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return now.Format("20060102-150405")
+	}
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
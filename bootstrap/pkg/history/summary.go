@@ -0,0 +1,170 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// summaryDir is where run summaries are written, relative to the project root
+const summaryDir = ".bootstrap/runs"
+
+// SummaryStep is the outcome of a step in a run summary
+type SummaryStep struct {
+	Name            string  `json:"name"`
+	Status          Status  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Summary is the machine-readable record of what a run did, written once the run finishes
+type Summary struct {
+	RunID           string            `json:"run_id"`
+	Command         string            `json:"command"`
+	Cluster         string            `json:"cluster,omitempty"`
+	Status          Status            `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      time.Time         `json:"finished_at"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Steps           []SummaryStep     `json:"steps"`
+	Versions        map[string]string `json:"versions,omitempty"`
+	Endpoints       map[string]string `json:"endpoints,omitempty"`
+	Warnings        []string          `json:"warnings,omitempty"`
+	Logs            []string          `json:"logs,omitempty"`
+	GitRevision     string            `json:"git_revision,omitempty"`
+	// History is the full run record, including flags and security alerts
+	History string `json:"history"`
+}
+
+// NewSummary builds the summary of a finished run
+func NewSummary(projectRoot string, run *Run) *Summary {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	summary := &Summary{
+		RunID:           run.ID,
+		Command:         run.Command,
+		Cluster:         run.Cluster,
+		Status:          run.Status,
+		Error:           run.Error,
+		StartedAt:       run.StartedAt,
+		FinishedAt:      run.FinishedAt,
+		DurationSeconds: run.Duration.Seconds(),
+		Steps:           make([]SummaryStep, 0, len(run.Steps)),
+		Versions:        run.Versions,
+		Endpoints:       run.Endpoints,
+		Warnings:        run.Warnings,
+		Logs:            run.Logs,
+		GitRevision:     run.GitRevision,
+		History:         filepath.Join(projectRoot, registryDir, run.ID+".json"),
+	}
+	for _, step := range run.Steps {
+		status := StatusSucceeded
+		if !step.Success {
+			status = StatusFailed
+		}
+		summary.Steps = append(summary.Steps, SummaryStep{
+			Name:            step.Name,
+			Status:          status,
+			DurationSeconds: step.Duration.Seconds(),
+			Error:           step.Error,
+		})
+	}
+	return summary
+}
+
+// WriteSummary stores the summary of a finished run as .bootstrap/runs/<timestamp>.json and returns its path
+func WriteSummary(projectRoot string, run *Run) (string, error) {
+	dir := filepath.Join(projectRoot, summaryDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(NewSummary(projectRoot, run), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode summary of run %s: %w", run.ID, err)
+	}
+
+	path := filepath.Join(dir, run.StartedAt.UTC().Format("20060102T150405Z")+".json")
+	if _, err := os.Stat(path); err == nil {
+		// Two runs started within the same second, keep both
+		path = filepath.Join(dir, run.StartedAt.UTC().Format("20060102T150405Z")+"-"+run.ID+".json")
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write summary of run %s: %w", run.ID, err)
+	}
+	return path, nil
+}
+
+// PrintSummary renders the compact human summary of a run
+func PrintSummary(w io.Writer, summary *Summary, path string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n%s %s\t%s\t%s\n", statusIcon(summary.Status), summary.Command, summary.Status, formatSeconds(summary.DurationSeconds))
+	for _, step := range summary.Steps {
+		fmt.Fprintf(tw, "  %s %s\t%s\t%s\n", statusIcon(step.Status), step.Name, formatSeconds(step.DurationSeconds), firstLine(step.Error))
+	}
+	tw.Flush()
+
+	printMap(w, "Versions", summary.Versions)
+	printMap(w, "Endpoints", summary.Endpoints)
+	if len(summary.Warnings) > 0 {
+		fmt.Fprintln(w, "Warnings")
+		for _, warning := range summary.Warnings {
+			fmt.Fprintf(w, "  ⚠️  %s\n", firstLine(warning))
+		}
+	}
+	if summary.Error != "" {
+		fmt.Fprintf(w, "Error: %s\n", firstLine(summary.Error))
+	}
+	for _, logFile := range summary.Logs {
+		fmt.Fprintf(w, "Log: %s\n", logFile)
+	}
+	if path != "" {
+		fmt.Fprintf(w, "Summary: %s\n", path)
+	}
+}
+
+func printMap(w io.Writer, title string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(w, title)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		fmt.Fprintf(tw, "  %s\t%s\n", key, values[key])
+	}
+	tw.Flush()
+}
+
+func statusIcon(status Status) string {
+	switch status {
+	case StatusSucceeded:
+		return "✅"
+	case StatusFailed:
+		return "❌"
+	default:
+		return "⏳"
+	}
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
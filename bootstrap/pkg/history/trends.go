@@ -0,0 +1,170 @@
+package history
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// TotalStep is the pseudo step the trends report the whole run duration under, once per command
+const TotalStep = "total"
+
+// StepTrend is how the duration and failure rate of a step evolve over the recorded runs of a cluster
+type StepTrend struct {
+	Cluster string `json:"cluster,omitempty"`
+	Step    string `json:"step"`
+	// Command is the command whose runs the TotalStep trend covers
+	Command     string  `json:"command,omitempty"`
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+	// Median, Min and Max cover the successful runs before the last one
+	Median time.Duration `json:"median"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	// Last is the step in the latest run, LastDelta how much slower (positive) or faster than Median it was, as a ratio
+	Last       time.Duration `json:"last"`
+	LastRunID  string        `json:"last_run_id"`
+	LastFailed bool          `json:"last_failed,omitempty"`
+	LastDelta  float64       `json:"last_delta"`
+	// Drift compares the median of the newer half of the successful runs with the older half, as a ratio
+	Drift float64 `json:"drift"`
+	// Durations are the successful durations, oldest first
+	Durations []time.Duration `json:"durations"`
+}
+
+type stepSample struct {
+	runID    string
+	duration time.Duration
+	success  bool
+}
+
+// Trends computes the trend of every step recorded in runs, or only of step when set. Steps are grouped by
+// cluster and listed in the order the latest run executed them, each cluster followed by its run totals.
+func Trends(runs []*Run, step string) []StepTrend {
+	ordered := slices.Clone(runs)
+	slices.SortFunc(ordered, func(a, b *Run) int { return a.StartedAt.Compare(b.StartedAt) })
+
+	type key struct{ cluster, step, command string }
+	samples := map[key][]stepSample{}
+	var order []key
+	add := func(k key, sample stepSample) {
+		if step != "" && k.step != step {
+			return
+		}
+		if _, ok := samples[k]; !ok {
+			order = append(order, k)
+		}
+		samples[k] = append(samples[k], sample)
+	}
+	for _, run := range ordered {
+		if len(run.Steps) == 0 || run.Status == StatusRunning {
+			continue
+		}
+		for _, s := range run.Steps {
+			add(key{run.Cluster, s.Name, ""}, stepSample{runID: run.ID, duration: s.Duration, success: s.Success})
+		}
+		add(key{run.Cluster, TotalStep, run.Command}, stepSample{runID: run.ID, duration: run.Duration, success: run.Status == StatusSucceeded})
+	}
+
+	// The latest run sets the order, steps it skipped come after the ones it ran
+	position := func(k key) int {
+		for i := len(ordered) - 1; i >= 0; i-- {
+			if ordered[i].Cluster != k.cluster {
+				continue
+			}
+			if k.step == TotalStep {
+				return len(ordered[i].Steps)
+			}
+			for j, s := range ordered[i].Steps {
+				if s.Name == k.step {
+					return j
+				}
+			}
+		}
+		return len(ordered)
+	}
+	slices.SortStableFunc(order, func(a, b key) int {
+		if c := strings.Compare(a.cluster, b.cluster); c != 0 {
+			return c
+		}
+		if c := position(a) - position(b); c != 0 {
+			return c
+		}
+		return strings.Compare(a.command, b.command)
+	})
+
+	trends := make([]StepTrend, 0, len(order))
+	for _, k := range order {
+		trend := stepTrend(k.cluster, k.step, samples[k])
+		trend.Command = k.command
+		trends = append(trends, trend)
+	}
+	return trends
+}
+
+// stepTrend summarizes the samples of a step, oldest first
+func stepTrend(cluster, step string, samples []stepSample) StepTrend {
+	trend := StepTrend{Cluster: cluster, Step: step, Runs: len(samples)}
+	last := samples[len(samples)-1]
+	trend.Last, trend.LastRunID, trend.LastFailed = last.duration, last.runID, !last.success
+
+	var previous []time.Duration
+	for i, sample := range samples {
+		if !sample.success {
+			trend.Failures++
+			continue
+		}
+		trend.Durations = append(trend.Durations, sample.duration)
+		if i < len(samples)-1 {
+			previous = append(previous, sample.duration)
+		}
+	}
+	trend.FailureRate = float64(trend.Failures) / float64(trend.Runs)
+
+	if len(previous) > 0 {
+		trend.Median = median(previous)
+		trend.Min, trend.Max = slices.Min(previous), slices.Max(previous)
+		if last.success && trend.Median > 0 {
+			trend.LastDelta = float64(last.duration-trend.Median) / float64(trend.Median)
+		}
+	}
+	if half := len(trend.Durations) / 2; half > 0 {
+		older, newer := median(trend.Durations[:half]), median(trend.Durations[len(trend.Durations)-half:])
+		if older > 0 {
+			trend.Drift = float64(newer-older) / float64(older)
+		}
+	}
+	return trend
+}
+
+// median returns the median of durations, the mean of the two middle ones for an even count
+func median(durations []time.Duration) time.Duration {
+	sorted := slices.Sorted(slices.Values(durations))
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// Sparkline draws the last width durations as block characters scaled between their minimum and maximum
+func Sparkline(durations []time.Duration, width int) string {
+	if width > 0 && len(durations) > width {
+		durations = durations[len(durations)-width:]
+	}
+	if len(durations) == 0 {
+		return ""
+	}
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	low, high := slices.Min(durations), slices.Max(durations)
+	var b strings.Builder
+	for _, duration := range durations {
+		level := len(blocks) / 2
+		if high > low {
+			level = int(float64(duration-low) / float64(high-low) * float64(len(blocks)-1))
+		}
+		b.WriteRune(blocks[level])
+	}
+	return b.String()
+}
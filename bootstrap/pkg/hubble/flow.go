@@ -0,0 +1,355 @@
+package hubble
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Flow kinds
+const (
+	KindDrop   = "drop"
+	KindPolicy = "policy"
+	KindDNS    = "dns"
+	KindFlow   = "flow"
+)
+
+// DefaultKinds are the flows worth looking at when debugging connectivity
+var DefaultKinds = []string{KindDrop, KindPolicy, KindDNS}
+
+// Flow is a network flow observed by Cilium, reduced to what the flow commands show
+type Flow struct {
+	Time        time.Time `json:"time"`
+	Node        string    `json:"node"`
+	Kind        string    `json:"kind"`
+	Verdict     string    `json:"verdict"`
+	DropReason  string    `json:"dropReason,omitempty"`
+	Direction   string    `json:"direction,omitempty"`
+	Protocol    string    `json:"protocol,omitempty"`
+	Source      Endpoint  `json:"source"`
+	Destination Endpoint  `json:"destination"`
+	Reply       bool      `json:"reply,omitempty"`
+	DNS         *DNS      `json:"dns,omitempty"`
+}
+
+// Endpoint is one side of a flow
+type Endpoint struct {
+	Namespace string   `json:"namespace,omitempty"`
+	Pod       string   `json:"pod,omitempty"`
+	IP        string   `json:"ip,omitempty"`
+	Port      uint32   `json:"port,omitempty"`
+	Names     []string `json:"names,omitempty"`
+}
+
+// DNS is the DNS query or answer a flow carries
+type DNS struct {
+	Query    string   `json:"query"`
+	Types    []string `json:"types,omitempty"`
+	IPs      []string `json:"ips,omitempty"`
+	RCode    string   `json:"rcode,omitempty"`
+	Response bool     `json:"response"`
+}
+
+// String names the endpoint by pod when known, by address otherwise
+func (e Endpoint) String() string {
+	name := e.IP
+	switch {
+	case e.Pod != "":
+		name = e.Namespace + "/" + e.Pod
+	case len(e.Names) > 0:
+		name = e.Names[0]
+	}
+	if e.Port != 0 {
+		name = fmt.Sprintf("%s:%d", name, e.Port)
+	}
+	return name
+}
+
+// Field numbers of the Hubble observer and flow protos (cilium/api/v1)
+const (
+	requestFollow    = 3
+	requestWhitelist = 6
+	requestSince     = 7
+
+	filterSourcePod      = 2
+	filterDestinationPod = 4
+
+	responseFlow     = 1
+	responseNodeName = 1000
+
+	flowTime             = 1
+	flowVerdict          = 2
+	flowIP               = 5
+	flowL4               = 6
+	flowSource           = 8
+	flowDestination      = 9
+	flowNodeName         = 11
+	flowSourceNames      = 13
+	flowDestinationNames = 14
+	flowL7               = 15
+	flowEventType        = 19
+	flowTrafficDirection = 22
+	flowDropReason       = 25
+	flowIsReply          = 26
+
+	// policyVerdictEvent is the Cilium monitor event type of policy verdict notifications
+	policyVerdictEvent = 5
+)
+
+var verdicts = map[uint64]string{
+	0: "UNKNOWN", 1: "FORWARDED", 2: "DROPPED", 3: "ERROR", 4: "AUDIT", 5: "REDIRECTED", 6: "TRACED", 7: "TRANSLATED",
+}
+
+var directions = map[uint64]string{1: "ingress", 2: "egress"}
+
+var protocols = map[protowire.Number]string{1: "TCP", 2: "UDP", 3: "ICMPv4", 4: "ICMPv6", 5: "SCTP"}
+
+var dropReasons = map[uint64]string{
+	130: "invalid source MAC",
+	131: "invalid destination MAC",
+	132: "invalid source IP",
+	133: "policy denied",
+	134: "invalid packet",
+	181: "denied by policy",
+}
+
+var dnsRCodes = map[uint64]string{0: "NOERROR", 1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED"}
+
+var nodeStates = map[uint64]string{0: "unknown", 1: "connected", 2: "unavailable", 3: "gone", 4: "error"}
+
+// flowsRequest encodes a GetFlowsRequest for the flows since since, from or to namespace when set
+func flowsRequest(since time.Time, namespace string, follow bool) frame {
+	var b []byte
+	if follow {
+		b = protowire.AppendTag(b, requestFollow, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if namespace != "" {
+		// Filters are ORed, so flows match either side; a pod filter ending in / matches the whole namespace
+		for _, side := range []protowire.Number{filterSourcePod, filterDestinationPod} {
+			var filter []byte
+			filter = protowire.AppendTag(filter, side, protowire.BytesType)
+			filter = protowire.AppendString(filter, namespace+"/")
+			b = protowire.AppendTag(b, requestWhitelist, protowire.BytesType)
+			b = protowire.AppendBytes(b, filter)
+		}
+	}
+	var timestamp []byte
+	timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, uint64(since.Unix()))
+	timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, uint64(since.Nanosecond()))
+	b = protowire.AppendTag(b, requestSince, protowire.BytesType)
+	return protowire.AppendBytes(b, timestamp)
+}
+
+// parseFlowsResponse decodes a GetFlowsResponse, reporting false for node status and lost event responses
+func parseFlowsResponse(data []byte) (Flow, bool, error) {
+	response, err := decode(data)
+	if err != nil {
+		return Flow{}, false, err
+	}
+	if !response.has(responseFlow) {
+		return Flow{}, false, nil
+	}
+	raw, err := decode(response.bytes(responseFlow))
+	if err != nil {
+		return Flow{}, false, err
+	}
+
+	flow := Flow{
+		Time:      raw.message(flowTime).timestamp(),
+		Node:      raw.str(flowNodeName),
+		Verdict:   verdicts[raw.uint(flowVerdict)],
+		Direction: directions[raw.uint(flowTrafficDirection)],
+		Reply:     raw.message(flowIsReply).uint(1) == 1,
+	}
+	if flow.Node == "" {
+		flow.Node = response.str(responseNodeName)
+	}
+	ip := raw.message(flowIP)
+	flow.Source = endpoint(raw.message(flowSource), ip.str(1), raw.strs(flowSourceNames))
+	flow.Destination = endpoint(raw.message(flowDestination), ip.str(2), raw.strs(flowDestinationNames))
+
+	l4 := raw.message(flowL4)
+	for number, name := range protocols {
+		if l4.has(number) {
+			ports := l4.message(number)
+			flow.Protocol = name
+			flow.Source.Port = uint32(ports.uint(1))
+			flow.Destination.Port = uint32(ports.uint(2))
+		}
+	}
+
+	if dns := raw.message(flowL7).message(100); len(dns) > 0 {
+		flow.DNS = &DNS{
+			Query:    dns.str(1),
+			IPs:      dns.strs(2),
+			Types:    dns.strs(7),
+			Response: raw.message(flowL7).uint(1) == 2,
+		}
+		if flow.DNS.Response {
+			flow.DNS.RCode = dnsRCodes[dns.uint(6)]
+			if flow.DNS.RCode == "" {
+				flow.DNS.RCode = fmt.Sprintf("RCODE%d", dns.uint(6))
+			}
+		}
+	}
+
+	if reason := raw.uint(flowDropReason); reason != 0 {
+		flow.DropReason = dropReasons[reason]
+		if flow.DropReason == "" {
+			flow.DropReason = fmt.Sprintf("drop reason %d", reason)
+		}
+	}
+
+	switch {
+	case flow.Verdict == "DROPPED":
+		flow.Kind = KindDrop
+	case raw.message(flowEventType).uint(1) == policyVerdictEvent:
+		flow.Kind = KindPolicy
+	case flow.DNS != nil:
+		flow.Kind = KindDNS
+	default:
+		flow.Kind = KindFlow
+	}
+	return flow, true, nil
+}
+
+func endpoint(raw fields, ip string, names []string) Endpoint {
+	return Endpoint{Namespace: raw.str(3), Pod: raw.str(5), IP: ip, Names: names}
+}
+
+// parseServerStatus decodes a ServerStatusResponse
+func parseServerStatus(data []byte) (*Status, error) {
+	raw, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the relay status: %w", err)
+	}
+	return &Status{
+		NumFlows:         raw.uint(1),
+		MaxFlows:         raw.uint(2),
+		SeenFlows:        raw.uint(3),
+		Uptime:           time.Duration(raw.uint(4)),
+		ConnectedNodes:   uint32(raw.message(5).uint(1)),
+		UnavailableNodes: raw.strs(7),
+		Version:          raw.str(8),
+		FlowsRate:        math.Float64frombits(raw.uint(9)),
+	}, nil
+}
+
+// parseNodes decodes a GetNodesResponse
+func parseNodes(data []byte) ([]Node, error) {
+	raw, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []Node
+	for _, entry := range raw[1] {
+		node, err := decode(entry.bytes)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, Node{
+			Name:      node.str(1),
+			Version:   node.str(2),
+			Address:   node.str(3),
+			State:     nodeStates[node.uint(4)],
+			Uptime:    time.Duration(node.uint(6)),
+			NumFlows:  node.uint(7),
+			MaxFlows:  node.uint(8),
+			SeenFlows: node.uint(9),
+		})
+	}
+	return nodes, nil
+}
+
+// fields is a decoded protobuf message, its values keyed by field number. Length-delimited values keep
+// their bytes, the others their integer encoding.
+type fields map[protowire.Number][]value
+
+type value struct {
+	bytes []byte
+	raw   uint64
+}
+
+func decode(b []byte) (fields, error) {
+	decoded := fields{}
+	for len(b) > 0 {
+		number, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v value
+		switch typ {
+		case protowire.VarintType:
+			v.raw, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v.raw, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var fixed uint32
+			fixed, n = protowire.ConsumeFixed32(b)
+			v.raw = uint64(fixed)
+		case protowire.BytesType:
+			v.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(number, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		decoded[number] = append(decoded[number], v)
+	}
+	return decoded, nil
+}
+
+func (f fields) has(number protowire.Number) bool {
+	return len(f[number]) > 0
+}
+
+func (f fields) uint(number protowire.Number) uint64 {
+	if values := f[number]; len(values) > 0 {
+		return values[len(values)-1].raw
+	}
+	return 0
+}
+
+func (f fields) bytes(number protowire.Number) []byte {
+	if values := f[number]; len(values) > 0 {
+		return values[len(values)-1].bytes
+	}
+	return nil
+}
+
+func (f fields) str(number protowire.Number) string {
+	return string(f.bytes(number))
+}
+
+func (f fields) strs(number protowire.Number) []string {
+	var values []string
+	for _, v := range f[number] {
+		values = append(values, string(v.bytes))
+	}
+	return values
+}
+
+// message decodes a nested message, empty when absent or malformed
+func (f fields) message(number protowire.Number) fields {
+	nested, err := decode(f.bytes(number))
+	if err != nil {
+		return fields{}
+	}
+	return nested
+}
+
+func (f fields) timestamp() time.Time {
+	if !f.has(1) && !f.has(2) {
+		return time.Time{}
+	}
+	return time.Unix(int64(f.uint(1)), int64(f.uint(2)))
+}
@@ -0,0 +1,197 @@
+package hubble
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// Namespace is where Cilium deploys the Hubble relay
+	Namespace = "kube-system"
+	// RelayService is the plaintext gRPC service of the relay
+	RelayService = "hubble-relay"
+	RelayPort    = 80
+
+	getFlowsMethod     = "/observer.Observer/GetFlows"
+	serverStatusMethod = "/observer.Observer/ServerStatus"
+	getNodesMethod     = "/observer.Observer/GetNodes"
+)
+
+// Client reads flows from the Hubble relay over a port-forward, without the hubble CLI
+type Client struct {
+	client *k8s.Client
+}
+
+// NewClient creates a Hubble client for the cluster behind client
+func NewClient(client *k8s.Client) *Client {
+	return &Client{client: client}
+}
+
+// FlowOptions selects the flows to read
+type FlowOptions struct {
+	// Namespace keeps the flows from or to pods of one namespace, all namespaces when empty
+	Namespace string
+	Since     time.Duration
+	// Kinds keeps the flows of these kinds, every flow when empty
+	Kinds  []string
+	Follow bool
+}
+
+// Status is the state of the Hubble relay and the nodes it reads flows from
+type Status struct {
+	Version          string        `json:"version"`
+	NumFlows         uint64        `json:"numFlows"`
+	MaxFlows         uint64        `json:"maxFlows"`
+	SeenFlows        uint64        `json:"seenFlows"`
+	FlowsRate        float64       `json:"flowsRate"`
+	Uptime           time.Duration `json:"uptime"`
+	ConnectedNodes   uint32        `json:"connectedNodes"`
+	UnavailableNodes []string      `json:"unavailableNodes,omitempty"`
+	Nodes            []Node        `json:"nodes,omitempty"`
+}
+
+// Node is a Cilium agent the relay connects to
+type Node struct {
+	Name      string        `json:"name"`
+	Version   string        `json:"version"`
+	Address   string        `json:"address"`
+	State     string        `json:"state"`
+	NumFlows  uint64        `json:"numFlows"`
+	MaxFlows  uint64        `json:"maxFlows"`
+	SeenFlows uint64        `json:"seenFlows"`
+	Uptime    time.Duration `json:"uptime"`
+}
+
+// Healthy reports whether every node the relay knows is connected
+func (s *Status) Healthy() bool {
+	return len(s.UnavailableNodes) == 0
+}
+
+// Flows streams the flows matching opts to fn, until the buffered flows are read or, when following, ctx is done
+func (c *Client) Flows(ctx context.Context, opts FlowOptions, fn func(Flow)) error {
+	conn, closeConn, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, getFlowsMethod)
+	if err != nil {
+		return fmt.Errorf("failed to open the flow stream: %w", err)
+	}
+	request := flowsRequest(time.Now().Add(-opts.Since), opts.Namespace, opts.Follow)
+	if err := stream.SendMsg(&request); err != nil {
+		return fmt.Errorf("failed to request flows: %w", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	kinds := map[string]bool{}
+	for _, kind := range opts.Kinds {
+		kinds[kind] = true
+	}
+	for {
+		var response frame
+		err := stream.RecvMsg(&response)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read flows: %w", err)
+		}
+		flow, ok, err := parseFlowsResponse(response)
+		if err != nil {
+			log.Debug("Skipping undecodable flow", "error", err)
+			continue
+		}
+		if ok && (len(kinds) == 0 || kinds[flow.Kind]) {
+			fn(flow)
+		}
+	}
+}
+
+// Status returns the relay status and, when the relay lists them, its nodes
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	conn, closeConn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	var response frame
+	if err := conn.Invoke(ctx, serverStatusMethod, &frame{}, &response); err != nil {
+		return nil, fmt.Errorf("failed to read the relay status: %w", err)
+	}
+	status, err := parseServerStatus(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes frame
+	if err := conn.Invoke(ctx, getNodesMethod, &frame{}, &nodes); err != nil {
+		log.Debug("Failed to list the Hubble nodes", "error", err)
+		return status, nil
+	}
+	if status.Nodes, err = parseNodes(nodes); err != nil {
+		log.Debug("Failed to decode the Hubble nodes", "error", err)
+	}
+	return status, nil
+}
+
+// connect port-forwards to the relay and dials it
+func (c *Client) connect(ctx context.Context) (*grpc.ClientConn, func(), error) {
+	port, stop, err := c.client.PortForward(ctx, Namespace, RelayService, RelayPort)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hubble relay unreachable, check hubble.relay is enabled in the Cilium values: %w", err)
+	}
+	conn, err := grpc.NewClient(fmt.Sprintf("127.0.0.1:%d", port),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{})))
+	if err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("failed to dial the hubble relay: %w", err)
+	}
+	return conn, func() {
+		conn.Close()
+		stop()
+	}, nil
+}
+
+// frame is an undecoded protobuf message
+type frame []byte
+
+// rawCodec passes frames through, the messages being encoded and decoded by hand in this package
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	f, ok := v.(*frame)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *f, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	f, ok := v.(*frame)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*f = append((*f)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
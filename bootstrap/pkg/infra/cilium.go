@@ -3,18 +3,25 @@ package infra
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/api/core/v1"
+	"helm.sh/helm/v3/pkg/action"
 )
 
-// CiliumInstaller handles Cilium CNI installation using Helm (matching original bash script)
+const (
+	ciliumChartVersion = "1.18.1"
+	ciliumRepoURL      = "https://helm.cilium.io"
+	ciliumHelmTimeout  = 5 * time.Minute
+)
+
+// CiliumInstaller handles Cilium CNI installation with the Helm SDK (matching original bash script)
 type CiliumInstaller struct {
 	client *k8s.Client
 }
@@ -32,34 +39,34 @@ type CiliumConfig struct {
 	ClusterPodCIDR string
 	NodeEncryption bool
 	Hubble         bool
-	LoadBalancer   bool
+	// LoadBalancer enables the L2 announcements of LB-IPAM addresses
+	LoadBalancer bool
+	// Version overrides the chart version
+	Version string
+	// Values is a YAML document merged onto the rendered values
+	Values string
+}
+
+// chartVersion returns the Cilium chart version to deploy
+func (c CiliumConfig) chartVersion() string {
+	if c.Version != "" {
+		return c.Version
+	}
+	return ciliumChartVersion
+}
+
+// BundleChart is the chart Install deploys, for the air-gap bundle
+func (c CiliumConfig) BundleChart() airgap.BundleChart {
+	return airgap.BundleChart{Name: "cilium", RepoURL: ciliumRepoURL, Version: c.chartVersion()}
 }
 
 // Install installs Cilium CNI using Helm (matching original bash script)
 func (c *CiliumInstaller) Install(ctx context.Context, config CiliumConfig) error {
 	log.Info("Installing Cilium CNI using Helm")
 
-	// Check if Helm is available
-	if !c.isHelmAvailable() {
-		return fmt.Errorf("helm CLI not found - install with: brew install helm")
-	}
-
-	// Get control plane IP if not provided
-	if config.ControlPlaneIP == "" {
-		ip, err := c.getControlPlaneIP(ctx)
-		if err != nil {
-			log.Warn("Could not detect control plane IP", "error", err)
-			return fmt.Errorf("control plane IP required: %w", err)
-		} else {
-			config.ControlPlaneIP = ip
-			log.Info("Using detected control plane IP", "ip", ip)
-		}
-	}
-
-	// Set default ClusterPodCIDR if not provided
-	if config.ClusterPodCIDR == "" {
-		config.ClusterPodCIDR = "10.244.0.0/16"
-		log.Info("Using default cluster pod CIDR", "cidr", config.ClusterPodCIDR)
+	config, err := c.resolveConfig(ctx, config)
+	if err != nil {
+		return err
 	}
 
 	// Check if Cilium is already installed
@@ -68,9 +75,8 @@ func (c *CiliumInstaller) Install(ctx context.Context, config CiliumConfig) erro
 		return c.waitForCilium(ctx)
 	}
 
-	// Add Cilium Helm repository
-	if err := c.addCiliumHelmRepo(ctx); err != nil {
-		return fmt.Errorf("failed to add Cilium Helm repo: %w", err)
+	if err := k8s.GuardMutation("helm install cilium"); err != nil {
+		return err
 	}
 
 	// Install Cilium using Helm
@@ -93,10 +99,25 @@ func (c *CiliumInstaller) Install(ctx context.Context, config CiliumConfig) erro
 	return nil
 }
 
-// isHelmAvailable checks if helm CLI is available
-func (c *CiliumInstaller) isHelmAvailable() bool {
-	_, err := exec.LookPath("helm")
-	return err == nil
+// resolveConfig fills in the control plane IP and pod CIDR when config leaves them empty
+func (c *CiliumInstaller) resolveConfig(ctx context.Context, config CiliumConfig) (CiliumConfig, error) {
+	// Get control plane IP if not provided
+	if config.ControlPlaneIP == "" {
+		ip, err := c.getControlPlaneIP(ctx)
+		if err != nil {
+			log.Warn("Could not detect control plane IP", "error", err)
+			return config, fmt.Errorf("control plane IP required: %w", err)
+		}
+		config.ControlPlaneIP = ip
+		log.Info("Using detected control plane IP", "ip", ip)
+	}
+
+	// Set default ClusterPodCIDR if not provided
+	if config.ClusterPodCIDR == "" {
+		config.ClusterPodCIDR = "10.244.0.0/16"
+		log.Info("Using default cluster pod CIDR", "cidr", config.ClusterPodCIDR)
+	}
+	return config, nil
 }
 
 // isCiliumInstalled checks if Cilium is already installed
@@ -130,65 +151,96 @@ func (c *CiliumInstaller) getControlPlaneIP(ctx context.Context) (string, error)
 	return "", fmt.Errorf("could not detect control plane IP")
 }
 
-// addCiliumHelmRepo adds the Cilium Helm repository
-func (c *CiliumInstaller) addCiliumHelmRepo(ctx context.Context) error {
-	log.Info("Adding Cilium Helm repository")
-
-	// Add repo
-	addCmd := exec.CommandContext(ctx, "helm", "repo", "add", "cilium", "https://helm.cilium.io")
-	if output, err := addCmd.CombinedOutput(); err != nil {
-		// Ignore error if repo already exists
-		if !strings.Contains(string(output), "already exists") {
-			log.Error("Failed to add Cilium Helm repo", "error", err, "output", string(output))
-			return fmt.Errorf("failed to add helm repo: %w", err)
-		}
-		log.Info("Cilium Helm repo already exists")
+// installCiliumWithHelm installs the Cilium chart with configuration matching the original bash script
+func (c *CiliumInstaller) installCiliumWithHelm(ctx context.Context, config CiliumConfig) error {
+	log.Info("Installing Cilium with Helm configuration", "version", config.chartVersion())
+
+	values, err := renderCiliumValues(config)
+	if err != nil {
+		return err
+	}
+	helmConfig, err := helmConfiguration(c.client.GetConfig(), "kube-system")
+	if err != nil {
+		return err
+	}
+
+	install := action.NewInstall(helmConfig)
+	install.ReleaseName = "cilium"
+	install.Namespace = "kube-system"
+	install.Version = config.chartVersion()
+	install.RepoURL = ciliumRepoURL
+	install.Timeout = ciliumHelmTimeout
+	install.PostRenderer = imagePostRenderer()
+	chart, err := loadChart(&install.ChartPathOptions, "cilium")
+	if err != nil {
+		return err
 	}
 
-	// Update repo
-	updateCmd := exec.CommandContext(ctx, "helm", "repo", "update")
-	if output, err := updateCmd.CombinedOutput(); err != nil {
-		log.Error("Failed to update Helm repos", "error", err, "output", string(output))
-		return fmt.Errorf("failed to update helm repos: %w", err)
+	if _, err := install.RunWithContext(ctx, chart, values); err != nil {
+		log.Error("Cilium Helm installation failed", "error", err)
+		return fmt.Errorf("helm install failed: %w", err)
 	}
 
-	log.Info("Cilium Helm repository added and updated")
+	log.Info("Cilium Helm release installed")
 	return nil
 }
 
-// installCiliumWithHelm installs Cilium using Helm with configuration matching the original bash script
-func (c *CiliumInstaller) installCiliumWithHelm(ctx context.Context, config CiliumConfig) error {
-	log.Info("Installing Cilium with Helm configuration")
-
-	// Create temporary values file (matching original bash script)
-	valuesFile, err := c.createCiliumValuesFile(config)
+// Images renders the chart for config and returns the images the release runs, pointed at the registry mirror
+func (c *CiliumInstaller) Images(ctx context.Context, config CiliumConfig) ([]string, error) {
+	config, err := c.resolveConfig(ctx, config)
 	if err != nil {
-		return fmt.Errorf("failed to create values file: %w", err)
+		return nil, err
 	}
-	defer os.Remove(valuesFile)
-
-	// Install Cilium with Helm
-	args := []string{
-		"install", "cilium", "cilium/cilium",
-		"--version", "1.18.1",
-		"--namespace", "kube-system",
-		"--values", valuesFile,
+	values, err := renderCiliumValues(config)
+	if err != nil {
+		return nil, err
 	}
 
-	cmd := exec.CommandContext(ctx, "helm", args...)
-	output, err := cmd.CombinedOutput()
+	install := action.NewInstall(&action.Configuration{})
+	install.DryRun = true
+	install.ClientOnly = true
+	install.ReleaseName = "cilium"
+	install.Namespace = "kube-system"
+	install.Version = config.chartVersion()
+	install.RepoURL = ciliumRepoURL
+	install.PostRenderer = imagePostRenderer()
+	chart, err := loadChart(&install.ChartPathOptions, "cilium")
 	if err != nil {
-		log.Error("Cilium Helm installation failed", "error", err, "output", string(output))
-		return fmt.Errorf("helm install failed: %w", err)
+		return nil, err
+	}
+	release, err := install.RunWithContext(ctx, chart, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the Cilium chart: %w", err)
 	}
+	return manifestImages(release.Manifest), nil
+}
 
-	log.Info("Cilium Helm installation command completed")
-	return nil
+// renderCiliumValues returns the default values for config with its overrides merged on top
+func renderCiliumValues(config CiliumConfig) (map[string]interface{}, error) {
+	values, err := parseValues(ciliumValues(config))
+	if err != nil {
+		return nil, err
+	}
+	if secret := registry.PullSecret(); secret != "" {
+		values["imagePullSecrets"] = []interface{}{map[string]interface{}{"name": secret}}
+	}
+	if config.LoadBalancer {
+		values["l2announcements"] = map[string]interface{}{"enabled": true}
+		values["externalIPs"] = map[string]interface{}{"enabled": true}
+	}
+	if strings.TrimSpace(config.Values) == "" {
+		return values, nil
+	}
+	overrides, err := parseValues(config.Values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cilium.values: %w", err)
+	}
+	return mergeValues(values, overrides), nil
 }
 
-// createCiliumValuesFile creates a values file matching the original bash script configuration
-func (c *CiliumInstaller) createCiliumValuesFile(config CiliumConfig) (string, error) {
-	valuesContent := fmt.Sprintf(`# Cilium bootstrap configuration for homelab (matching original bash script)
+// ciliumValues renders the default Helm values for config
+func ciliumValues(config CiliumConfig) string {
+	return fmt.Sprintf(`# Cilium bootstrap configuration for homelab (matching original bash script)
 routingMode: "native"
 ipv4NativeRoutingCIDR: "%s"
 autoDirectNodeRoutes: true
@@ -278,26 +330,6 @@ socketLB:
 cni:
   exclusive: false
 `, config.ClusterPodCIDR, config.ControlPlaneIP, config.ClusterPodCIDR, config.Hubble, config.Hubble, config.Hubble)
-
-	// Create temporary file
-	tmpFile, err := os.CreateTemp("", "cilium-bootstrap-values-*.yaml")
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
-	}
-
-	if _, err := tmpFile.WriteString(valuesContent); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return "", fmt.Errorf("failed to write values file: %w", err)
-	}
-
-	if err := tmpFile.Close(); err != nil {
-		os.Remove(tmpFile.Name())
-		return "", fmt.Errorf("failed to close values file: %w", err)
-	}
-
-	log.Info("Created Cilium values file", "path", tmpFile.Name())
-	return tmpFile.Name(), nil
 }
 
 // waitForCilium waits for Cilium to be ready (matching original bash script logic)
@@ -0,0 +1,146 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// ValuesDrift lists the Helm values of the deployed Cilium release that differ from the ones config renders
+func (c *CiliumInstaller) ValuesDrift(ctx context.Context, config CiliumConfig) ([]string, error) {
+	config, err := c.resolveConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	desired, err := renderCiliumValues(config)
+	if err != nil {
+		return nil, err
+	}
+	deployed, err := c.deployedValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[string]interface{}{}
+	have := map[string]interface{}{}
+	flattenValues("", desired, want)
+	flattenValues("", deployed, have)
+
+	var drift []string
+	for path, value := range want {
+		current, ok := have[path]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("%s added", path))
+		case !reflect.DeepEqual(current, value):
+			drift = append(drift, fmt.Sprintf("%s: %v → %v", path, current, value))
+		}
+	}
+	for path := range have {
+		if _, ok := want[path]; !ok {
+			drift = append(drift, fmt.Sprintf("%s removed", path))
+		}
+	}
+	sort.Strings(drift)
+	return drift, nil
+}
+
+// Upgrade re-applies the rendered values to the deployed Cilium release, rolling back when it does not become ready
+func (c *CiliumInstaller) Upgrade(ctx context.Context, config CiliumConfig) error {
+	config, err := c.resolveConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	if err := k8s.GuardMutation("helm upgrade cilium"); err != nil {
+		return err
+	}
+
+	values, err := renderCiliumValues(config)
+	if err != nil {
+		return err
+	}
+	helmConfig, err := helmConfiguration(c.client.GetConfig(), "kube-system")
+	if err != nil {
+		return err
+	}
+
+	upgrade := action.NewUpgrade(helmConfig)
+	upgrade.Namespace = "kube-system"
+	upgrade.Version = config.chartVersion()
+	upgrade.RepoURL = ciliumRepoURL
+	upgrade.Timeout = ciliumHelmTimeout
+	upgrade.Atomic = true
+	upgrade.CleanupOnFail = true
+	upgrade.PostRenderer = imagePostRenderer()
+	chart, err := loadChart(&upgrade.ChartPathOptions, "cilium")
+	if err != nil {
+		return err
+	}
+
+	log.Info("Upgrading Cilium release with updated values")
+	if _, err := upgrade.RunWithContext(ctx, "cilium", chart, values); err != nil {
+		log.Error("Cilium Helm upgrade failed, release rolled back", "error", err)
+		return fmt.Errorf("helm upgrade failed: %w", err)
+	}
+
+	if err := c.waitForCilium(ctx); err != nil {
+		return fmt.Errorf("Cilium not ready: %w", err)
+	}
+	return nil
+}
+
+// deployedValues reads the user-supplied values of the deployed Cilium release
+func (c *CiliumInstaller) deployedValues(ctx context.Context) (map[string]interface{}, error) {
+	helmConfig, err := helmConfiguration(c.client.GetConfig(), "kube-system")
+	if err != nil {
+		return nil, err
+	}
+	values, err := action.NewGetValues(helmConfig).Run("cilium")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Cilium release values: %w", err)
+	}
+	return values, nil
+}
+
+// Release returns the chart version of the deployed cilium Helm release, empty when Cilium was installed
+// without Helm
+func (c *CiliumInstaller) Release(ctx context.Context) (string, error) {
+	helmConfig, err := helmConfiguration(c.client.GetConfig(), "kube-system")
+	if err != nil {
+		return "", err
+	}
+	release, err := action.NewGet(helmConfig).Run("cilium")
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get Cilium release: %w", err)
+	}
+	if release.Chart == nil || release.Chart.Metadata == nil {
+		return "", nil
+	}
+	return release.Chart.Metadata.Version, nil
+}
+
+// flattenValues indexes nested Helm values by dotted path, lists are compared as a whole
+func flattenValues(prefix string, values map[string]interface{}, out map[string]interface{}) {
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenValues(path, nested, out)
+			continue
+		}
+		out[path] = value
+	}
+}
@@ -0,0 +1,154 @@
+package infra
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/postrender"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// restClientGetter hands Helm the REST config of an existing client instead of a kubeconfig file
+type restClientGetter struct {
+	config    *rest.Config
+	namespace string
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(g.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	return memory.NewMemCacheClient(client), nil
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	client, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	return restmapper.NewDeferredDiscoveryRESTMapper(client), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	overrides := &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: g.namespace}}
+	return clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), overrides)
+}
+
+// helmConfiguration initializes Helm actions against the cluster, releases are stored as secrets like the helm CLI does
+func helmConfiguration(config *rest.Config, namespace string) (*action.Configuration, error) {
+	cfg := &action.Configuration{}
+	getter := &restClientGetter{config: config, namespace: namespace}
+	if err := cfg.Init(getter, namespace, "secret", func(format string, args ...interface{}) {
+		log.Debug(fmt.Sprintf(format, args...))
+	}); err != nil {
+		return nil, fmt.Errorf("failed to initialize Helm: %w", err)
+	}
+	return cfg, nil
+}
+
+// loadChart downloads name at version from the repository into the Helm cache and loads it,
+// air-gapped it is read from the bundle instead
+func loadChart(options *action.ChartPathOptions, name string) (*chart.Chart, error) {
+	var path string
+	var err error
+	if airgap.Enabled() {
+		path, err = airgap.Chart(name, options.Version)
+	} else {
+		path, err = options.LocateChart(name, cli.New())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate chart %s %s: %w", name, options.Version, err)
+	}
+	loaded, err := loader.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s: %w", name, err)
+	}
+	return loaded, nil
+}
+
+var imageLine = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?image:\s*)(["']?)([^"'\s#]+)(["']?)`)
+
+// mirrorImages rewrites the images of the rendered manifests to the registry mirror
+type mirrorImages struct{}
+
+func (mirrorImages) Run(rendered *bytes.Buffer) (*bytes.Buffer, error) {
+	out := imageLine.ReplaceAllStringFunc(rendered.String(), func(line string) string {
+		m := imageLine.FindStringSubmatch(line)
+		return m[1] + m[2] + registry.Rewrite(m[3]) + m[4]
+	})
+	return bytes.NewBufferString(out), nil
+}
+
+// manifestImages returns the images a rendered manifest runs, once each
+func manifestImages(manifest string) []string {
+	seen := map[string]bool{}
+	var images []string
+	for _, m := range imageLine.FindAllStringSubmatch(manifest, -1) {
+		if !seen[m[3]] {
+			seen[m[3]] = true
+			images = append(images, m[3])
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// imagePostRenderer returns the post-renderer pointing releases at the mirror, nil without one
+func imagePostRenderer() postrender.PostRenderer {
+	if registry.Mirror() == "" {
+		return nil
+	}
+	return mirrorImages{}
+}
+
+// mergeValues deep-merges overrides onto base without modifying either, a null override removes the key
+func mergeValues(base, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		nested, ok := value.(map[string]interface{})
+		current, isMap := merged[key].(map[string]interface{})
+		if ok && isMap {
+			merged[key] = mergeValues(current, nested)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// parseValues turns rendered values YAML into Helm values
+func parseValues(values string) (map[string]interface{}, error) {
+	parsed, err := chartutil.ReadValues([]byte(values))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Helm values: %w", err)
+	}
+	return parsed.AsMap(), nil
+}
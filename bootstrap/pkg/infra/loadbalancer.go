@@ -0,0 +1,300 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	"helm.sh/helm/v3/pkg/action"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Load balancer providers
+const (
+	LoadBalancerMetalLB = "metallb"
+	LoadBalancerCilium  = "cilium"
+)
+
+const (
+	// MetalLBNamespace is where the MetalLB controller, speakers and address pools live
+	MetalLBNamespace = "metallb-system"
+
+	metallbChartVersion = "0.15.2"
+	metallbRepoURL      = "https://metallb.github.io/metallb"
+	metallbHelmTimeout  = 5 * time.Minute
+	// loadBalancerPool names the address pool and its L2 announcement
+	loadBalancerPool = "homelab"
+	// loadBalancerSet is the apply set of the pool, pruning the objects of a provider switched away from
+	loadBalancerSet = "load-balancer"
+	// probeService is the throwaway service checking addresses get assigned
+	probeService = "homelab-bootstrap-lb-probe"
+	probeTimeout = 2 * time.Minute
+)
+
+// LoadBalancerInstaller gives LoadBalancer services a LAN address with MetalLB or Cilium LB-IPAM
+type LoadBalancerInstaller struct {
+	client *k8s.Client
+}
+
+// NewLoadBalancerInstaller creates a new load balancer installer
+func NewLoadBalancerInstaller(client *k8s.Client) *LoadBalancerInstaller {
+	return &LoadBalancerInstaller{client: client}
+}
+
+// LoadBalancerConfig represents the load balancer installation configuration
+type LoadBalancerConfig struct {
+	Provider   string
+	Addresses  []string
+	Interfaces []string
+	// Version overrides the MetalLB chart version
+	Version string
+}
+
+// chartVersion returns the MetalLB chart version to deploy
+func (c LoadBalancerConfig) chartVersion() string {
+	if c.Version != "" {
+		return c.Version
+	}
+	return metallbChartVersion
+}
+
+// BundleChart is the MetalLB chart Install deploys, for the air-gap bundle
+func (c LoadBalancerConfig) BundleChart() airgap.BundleChart {
+	return airgap.BundleChart{Name: "metallb", RepoURL: metallbRepoURL, Version: c.chartVersion()}
+}
+
+// Install sets up the provider, applies the address pool with its L2 announcement and checks a
+// LoadBalancer service gets an address from it
+func (l *LoadBalancerInstaller) Install(ctx context.Context, config LoadBalancerConfig) error {
+	if len(config.Addresses) == 0 {
+		return fmt.Errorf("load_balancer.addresses is required")
+	}
+	if err := k8s.GuardMutation("install the load balancer"); err != nil {
+		return err
+	}
+
+	var objs []*unstructured.Unstructured
+	switch config.Provider {
+	case LoadBalancerMetalLB:
+		if err := l.installMetalLB(ctx, config); err != nil {
+			return err
+		}
+		objs = metallbPool(config)
+	case LoadBalancerCilium:
+		if err := l.checkCiliumL2(ctx); err != nil {
+			return err
+		}
+		objs = ciliumPool(config)
+	default:
+		return fmt.Errorf("unknown load balancer provider %q, use %s or %s", config.Provider, LoadBalancerMetalLB, LoadBalancerCilium)
+	}
+
+	// The MetalLB webhook validating the pool may still be starting right after its deployment turned ready
+	var result *k8s.ApplySetResult
+	err := k8s.Poll(ctx, 5*time.Second, time.Minute, func(ctx context.Context) (bool, error) {
+		var err error
+		result, err = l.client.ApplySet(ctx, loadBalancerSet, objs)
+		if err != nil {
+			log.Debug("Address pool not applied yet", "error", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply the address pool: %w", err)
+	}
+	log.Info("Load balancer address pool applied", "provider", config.Provider, "addresses", strings.Join(config.Addresses, ","), "objects", result)
+
+	address, err := l.Probe(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info("Load balancer assigns addresses", "provider", config.Provider, "probe", address)
+	return nil
+}
+
+// installMetalLB installs the MetalLB chart unless it runs already, then waits for the controller and speakers
+func (l *LoadBalancerInstaller) installMetalLB(ctx context.Context, config LoadBalancerConfig) error {
+	_, err := l.client.GetClientset().AppsV1().Deployments(MetalLBNamespace).Get(ctx, "metallb-controller", metav1.GetOptions{})
+	switch {
+	case err == nil:
+		log.Info("MetalLB is already installed")
+	case apierrors.IsNotFound(err):
+		if err := l.installMetalLBWithHelm(ctx, config); err != nil {
+			return fmt.Errorf("failed to install MetalLB with Helm: %w", err)
+		}
+	default:
+		return fmt.Errorf("failed to get the MetalLB controller: %w", err)
+	}
+
+	if err := l.client.WaitForDeployment(ctx, MetalLBNamespace, "metallb-controller", 5*time.Minute); err != nil {
+		return fmt.Errorf("metallb-controller not ready: %w", err)
+	}
+	if err := l.client.WaitForDaemonSet(ctx, MetalLBNamespace, "metallb-speaker", 5*time.Minute); err != nil {
+		return fmt.Errorf("metallb-speaker not ready: %w", err)
+	}
+	log.Info("MetalLB components are ready")
+	return nil
+}
+
+func (l *LoadBalancerInstaller) installMetalLBWithHelm(ctx context.Context, config LoadBalancerConfig) error {
+	log.Info("Installing MetalLB with Helm", "version", config.chartVersion())
+
+	// L2 announcements need no BGP, leave the FRR sidecars out
+	values := map[string]interface{}{
+		"speaker": map[string]interface{}{"frr": map[string]interface{}{"enabled": false}},
+	}
+	if secret := registry.PullSecret(); secret != "" {
+		values["imagePullSecrets"] = []interface{}{map[string]interface{}{"name": secret}}
+	}
+	helmConfig, err := helmConfiguration(l.client.GetConfig(), MetalLBNamespace)
+	if err != nil {
+		return err
+	}
+
+	install := action.NewInstall(helmConfig)
+	install.ReleaseName = "metallb"
+	install.Namespace = MetalLBNamespace
+	install.CreateNamespace = true
+	install.Version = config.chartVersion()
+	install.RepoURL = metallbRepoURL
+	install.Timeout = metallbHelmTimeout
+	install.PostRenderer = imagePostRenderer()
+	chart, err := loadChart(&install.ChartPathOptions, "metallb")
+	if err != nil {
+		return err
+	}
+	if _, err := install.RunWithContext(ctx, chart, values); err != nil {
+		return fmt.Errorf("helm install failed: %w", err)
+	}
+
+	// The chart brought the MetalLB CRDs the pool is applied with
+	l.client.InvalidateDiscovery()
+	log.Info("MetalLB Helm release installed")
+	return nil
+}
+
+// checkCiliumL2 waits for the operator allocating LB-IPAM addresses and checks the agents announce them
+func (l *LoadBalancerInstaller) checkCiliumL2(ctx context.Context) error {
+	cm, err := l.client.GetClientset().CoreV1().ConfigMaps("kube-system").Get(ctx, "cilium-config", metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("cilium is required for the cilium load balancer provider: %w", err)
+	}
+	if cm.Data["enable-l2-announcements"] != "true" {
+		return fmt.Errorf("cilium was installed without L2 announcements, run 'homelab sync' to upgrade the release with them")
+	}
+	if err := l.client.WaitForDeployment(ctx, "kube-system", "cilium-operator", 5*time.Minute); err != nil {
+		return fmt.Errorf("cilium-operator not ready: %w", err)
+	}
+	return nil
+}
+
+// metallbPool returns the MetalLB address pool and its L2 advertisement
+func metallbPool(config LoadBalancerConfig) []*unstructured.Unstructured {
+	pool := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metallb.io/v1beta1",
+		"kind":       "IPAddressPool",
+		"metadata":   map[string]interface{}{"name": loadBalancerPool, "namespace": MetalLBNamespace},
+		"spec":       map[string]interface{}{"addresses": toInterfaces(config.Addresses)},
+	}}
+	advertisement := map[string]interface{}{"ipAddressPools": []interface{}{loadBalancerPool}}
+	if len(config.Interfaces) > 0 {
+		advertisement["interfaces"] = toInterfaces(config.Interfaces)
+	}
+	return []*unstructured.Unstructured{pool, {Object: map[string]interface{}{
+		"apiVersion": "metallb.io/v1beta1",
+		"kind":       "L2Advertisement",
+		"metadata":   map[string]interface{}{"name": loadBalancerPool, "namespace": MetalLBNamespace},
+		"spec":       advertisement,
+	}}}
+}
+
+// ciliumPool returns the Cilium LB-IPAM pool and the policy announcing its addresses
+func ciliumPool(config LoadBalancerConfig) []*unstructured.Unstructured {
+	blocks := make([]interface{}, 0, len(config.Addresses))
+	for _, address := range config.Addresses {
+		if start, stop, ok := strings.Cut(address, "-"); ok {
+			blocks = append(blocks, map[string]interface{}{"start": strings.TrimSpace(start), "stop": strings.TrimSpace(stop)})
+			continue
+		}
+		blocks = append(blocks, map[string]interface{}{"cidr": address})
+	}
+	policy := map[string]interface{}{"loadBalancerIPs": true}
+	if len(config.Interfaces) > 0 {
+		interfaces := make([]interface{}, 0, len(config.Interfaces))
+		for _, name := range config.Interfaces {
+			// Cilium matches interfaces by regular expression
+			interfaces = append(interfaces, "^"+name+"$")
+		}
+		policy["interfaces"] = interfaces
+	}
+	return []*unstructured.Unstructured{{Object: map[string]interface{}{
+		"apiVersion": "cilium.io/v2alpha1",
+		"kind":       "CiliumLoadBalancerIPPool",
+		"metadata":   map[string]interface{}{"name": loadBalancerPool},
+		"spec":       map[string]interface{}{"blocks": blocks},
+	}}, {Object: map[string]interface{}{
+		"apiVersion": "cilium.io/v2alpha1",
+		"kind":       "CiliumL2AnnouncementPolicy",
+		"metadata":   map[string]interface{}{"name": loadBalancerPool},
+		"spec":       policy,
+	}}}
+}
+
+// Probe creates a throwaway LoadBalancer service and returns the address it was assigned
+func (l *LoadBalancerInstaller) Probe(ctx context.Context) (string, error) {
+	services := l.client.GetClientset().CoreV1().Services("kube-system")
+	if err := services.Delete(ctx, probeService, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to delete the previous probe service: %w", err)
+	}
+	probe := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      probeService,
+			Namespace: "kube-system",
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": k8s.FieldManager},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "probe", Port: 80}},
+		},
+	}
+	if _, err := services.Create(ctx, probe, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to create the probe service: %w", err)
+	}
+	defer func() {
+		if err := services.Delete(context.WithoutCancel(ctx), probeService, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Warn("Failed to delete the load balancer probe service", "error", err)
+		}
+	}()
+
+	var address string
+	err := l.client.WaitForService(ctx, "kube-system", probeService, probeTimeout, func(svc *corev1.Service) (bool, error) {
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				address = ingress.IP
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("probe service got no load balancer address, check the pool addresses are free on the LAN: %w", err)
+	}
+	return address, nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		out = append(out, value)
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// VMInfoOutput is the output of the homelab root module describing the VMs it created
+const VMInfoOutput = "vm_info"
+
+// Output is a root module output as printed by `output -json`
+type Output struct {
+	Sensitive bool            `json:"sensitive"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// Outputs are the root module outputs by name
+type Outputs map[string]Output
+
+// VM is a machine created by the VM layer
+type VM struct {
+	Key      string `json:"key"` // controlplane, worker1, worker2...
+	ID       int    `json:"id"`
+	Hostname string `json:"name"`
+	Role     string `json:"role"`
+	IP       string `json:"ip"`
+	MAC      string `json:"mac_address"`
+}
+
+// VMs decodes the vm_info output, control planes first then by VM ID
+func (o Outputs) VMs() ([]VM, error) {
+	output, ok := o[VMInfoOutput]
+	if !ok {
+		return nil, fmt.Errorf("output %s not found, has the VM layer been applied?", VMInfoOutput)
+	}
+	var byKey map[string]VM
+	if err := json.Unmarshal(output.Value, &byKey); err != nil {
+		return nil, fmt.Errorf("failed to parse output %s: %w", VMInfoOutput, err)
+	}
+
+	vms := make([]VM, 0, len(byKey))
+	for key, vm := range byKey {
+		vm.Key = key
+		if vm.Role == "" {
+			// States applied before vm_info carried the role only tell it by key
+			vm.Role = "worker"
+			if key == "controlplane" {
+				vm.Role = "controlplane"
+			}
+		}
+		if vm.IP == "" {
+			return nil, fmt.Errorf("VM %s has no IP address in output %s", key, VMInfoOutput)
+		}
+		vms = append(vms, vm)
+	}
+	sort.Slice(vms, func(i, j int) bool {
+		if (vms[i].Role == "controlplane") != (vms[j].Role == "controlplane") {
+			return vms[i].Role == "controlplane"
+		}
+		if vms[i].ID != vms[j].ID {
+			return vms[i].ID < vms[j].ID
+		}
+		return vms[i].Key < vms[j].Key
+	})
+	return vms, nil
+}
@@ -0,0 +1,99 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Actions a planned resource change takes, replace being a delete and a create
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionReplace = "replace"
+	ActionDelete  = "delete"
+)
+
+// Plan is a saved plan and the resource changes it makes
+type Plan struct {
+	File    string           `json:"file"`
+	Changes []ResourceChange `json:"changes"`
+}
+
+// ResourceChange is a resource the plan creates, updates, replaces or deletes
+type ResourceChange struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// parsePlan reads the changes out of `show -json`, leaving out no-ops and data source reads
+func parsePlan(data []byte) (*Plan, error) {
+	var document struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Change  struct {
+				Actions []string `json:"actions"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	plan := &Plan{}
+	for _, resource := range document.ResourceChanges {
+		action := ""
+		switch actions := strings.Join(resource.Change.Actions, ","); actions {
+		case "create", "update", "delete":
+			action = actions
+		case "delete,create", "create,delete":
+			action = ActionReplace
+		default:
+			continue
+		}
+		plan.Changes = append(plan.Changes, ResourceChange{Address: resource.Address, Action: action})
+	}
+	return plan, nil
+}
+
+// Empty reports whether the infrastructure already matches the configuration
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Counts returns the resources to add, change and destroy, a replacement counting as an add and a destroy
+func (p *Plan) Counts() (add, change, destroy int) {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			add++
+		case ActionUpdate:
+			change++
+		case ActionDelete:
+			destroy++
+		case ActionReplace:
+			add++
+			destroy++
+		}
+	}
+	return add, change, destroy
+}
+
+// Summary renders the counts the way terraform ends its plan output
+func (p *Plan) Summary() string {
+	if p.Empty() {
+		return "No changes, the infrastructure matches the configuration"
+	}
+	add, change, destroy := p.Counts()
+	return fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy", add, change, destroy)
+}
+
+// Lines renders each change with terraform's action symbol
+func (p *Plan) Lines() []string {
+	symbols := map[string]string{ActionCreate: "+", ActionUpdate: "~", ActionReplace: "-/+", ActionDelete: "-"}
+	lines := make([]string, 0, len(p.Changes))
+	for _, c := range p.Changes {
+		lines = append(lines, fmt.Sprintf("%-3s %s", symbols[c.Action], c.Address))
+	}
+	return lines
+}
@@ -0,0 +1,126 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// Binaries are the CLIs a runner drives, OpenTofu first when both are installed
+var Binaries = []string{"tofu", "terraform"}
+
+// PlanFile is where Plan saves the plan Apply applies, inside the ignored working directory of the root module
+const PlanFile = ".terraform/bootstrap.tfplan"
+
+// Runner runs terraform or OpenTofu against a root module
+type Runner struct {
+	Binary string
+	Dir    string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// New creates a runner for the root module in dir, binary is found on PATH when empty
+func New(dir, binary string) (*Runner, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("terraform root module not found: %w", err)
+	}
+	candidates := Binaries
+	if binary != "" {
+		candidates = []string{binary}
+	}
+	for _, candidate := range candidates {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return &Runner{Binary: path, Dir: dir, Stdout: os.Stdout, Stderr: os.Stderr}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found in PATH", strings.Join(candidates, " or "))
+}
+
+// Init installs the providers and modules of the root module
+func (r *Runner) Init(ctx context.Context) error {
+	return r.run(ctx, r.Stdout, "init", "-input=false")
+}
+
+// PlanOptions narrows a plan
+type PlanOptions struct {
+	Targets []string // Resource addresses, the whole module when empty
+}
+
+// Plan computes the changes the root module needs, saving them to PlanFile for Apply
+func (r *Runner) Plan(ctx context.Context, opts PlanOptions) (*Plan, error) {
+	args := []string{"plan", "-input=false", "-out=" + PlanFile}
+	for _, target := range opts.Targets {
+		args = append(args, "-target="+target)
+	}
+	if err := r.run(ctx, r.Stdout, args...); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := r.run(ctx, &out, "show", "-json", PlanFile); err != nil {
+		return nil, err
+	}
+	plan, err := parsePlan(out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	plan.File = filepath.Join(r.Dir, PlanFile)
+	return plan, nil
+}
+
+// Apply applies a saved plan, which fails when the infrastructure changed since it was computed
+func (r *Runner) Apply(ctx context.Context, plan *Plan) error {
+	return r.run(ctx, r.Stdout, "apply", "-input=false", plan.File)
+}
+
+// Outputs reads the root module outputs from the state
+func (r *Runner) Outputs(ctx context.Context) (Outputs, error) {
+	var out bytes.Buffer
+	if err := r.run(ctx, &out, "output", "-json"); err != nil {
+		return nil, err
+	}
+	var outputs Outputs
+	if err := json.Unmarshal(out.Bytes(), &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse outputs: %w", err)
+	}
+	return outputs, nil
+}
+
+func (r *Runner) run(ctx context.Context, stdout io.Writer, args ...string) error {
+	cmd := exec.CommandContext(ctx, r.Binary, args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1")
+	cmd.Stdout = stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(r.Stderr, &stderr)
+
+	log.Debug("Running "+filepath.Base(r.Binary), "args", args, "dir", r.Dir)
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%s %s failed: %w: %s", filepath.Base(r.Binary), args[0], err, errorLine(message))
+		}
+		return fmt.Errorf("%s %s failed: %w", filepath.Base(r.Binary), args[0], err)
+	}
+	return nil
+}
+
+// errorLine picks the first diagnostic out of terraform's boxed error output
+func errorLine(message string) string {
+	lines := strings.Split(message, "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "│"))
+		if strings.HasPrefix(line, "Error:") {
+			return line
+		}
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
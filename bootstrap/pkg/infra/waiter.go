@@ -2,15 +2,24 @@ package infra
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	kustomizationGVR = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	cephClusterGVR   = schema.GroupVersionResource{Group: "ceph.rook.io", Version: "v1", Resource: "cephclusters"}
 )
 
 // Waiter handles waiting for infrastructure components to be ready
@@ -69,21 +78,25 @@ func (w *Waiter) WaitForInfrastructure(ctx context.Context) error {
 		"ceph_timeout", w.timeouts.Ceph)
 
 	// Step 1: Wait for FluxCD to create kustomizations
+	progress.Report(ctx, 0, "Waiting for the Flux kustomizations")
 	if err := w.waitForKustomizations(ctx); err != nil {
 		return fmt.Errorf("kustomizations not ready: %w", err)
 	}
 
 	// Step 2: Wait for controllers layer (operators)
+	progress.Report(ctx, 25, "Waiting for the controllers layer")
 	if err := w.waitForControllers(ctx); err != nil {
 		return fmt.Errorf("controllers not ready: %w", err)
 	}
 
 	// Step 3: Wait for platform foundation
+	progress.Report(ctx, 50, "Waiting for the platform foundation")
 	if err := w.waitForPlatform(ctx); err != nil {
 		return fmt.Errorf("platform not ready: %w", err)
 	}
 
 	// Step 4: Wait for storage (provider specific)
+	progress.Report(ctx, 75, "Waiting for storage")
 	if err := w.waitForStorage(ctx); err != nil {
 		return fmt.Errorf("storage not ready: %w", err)
 	}
@@ -98,21 +111,9 @@ func (w *Waiter) waitForKustomizations(ctx context.Context) error {
 
 	target := w.platformKustomization
 
-	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, w.timeouts.Kustomization, true, func(ctx context.Context) (bool, error) {
-		// Check if platform-foundation kustomization exists
-		exists, err := w.kustomizationExists(ctx, target)
-		if err != nil {
-			log.Debug("Error checking kustomization", "error", err)
-			return false, nil // Continue polling
-		}
-
-		if exists {
-			log.Info("Kustomization found", "name", target)
-			return true, nil
-		}
-
-		log.Debug("Waiting for kustomization", "name", target)
-		return false, nil
+	err := w.client.WaitForObject(ctx, kustomizationGVR, "flux-system", target, w.timeouts.Kustomization, func(*unstructured.Unstructured) (bool, error) {
+		log.Info("Kustomization found", "name", target)
+		return true, nil
 	})
 
 	if err != nil {
@@ -145,18 +146,13 @@ func (w *Waiter) waitForControllers(ctx context.Context) error {
 	}
 
 	// Wait for controllers to be ready
-	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, w.timeouts.Controllers, true, func(ctx context.Context) (bool, error) {
-		ready, err := w.isKustomizationReady(ctx, w.controllersKustomization)
-		if err != nil {
-			log.Debug("Error checking controllers status", "error", err)
-			return false, nil
-		}
-		return ready, nil
-	})
-
+	reason, err := w.watchKustomizationReady(ctx, w.controllersKustomization, w.timeouts.Controllers)
 	if err != nil {
-		log.Error("Controllers layer not ready", "name", w.controllersKustomization, "timeout", w.timeouts.Controllers)
+		log.Error("Controllers layer not ready", "name", w.controllersKustomization, "timeout", w.timeouts.Controllers, "reason", reason)
 		w.diagnoseKustomization(ctx, w.controllersKustomization)
+		if reason != "" {
+			return fmt.Errorf("%s: %w", reason, err)
+		}
 		return err
 	}
 
@@ -168,17 +164,9 @@ func (w *Waiter) waitForControllers(ctx context.Context) error {
 func (w *Waiter) waitForPlatform(ctx context.Context) error {
 	log.Info("Waiting for platform foundation components", "name", w.platformKustomization)
 
-	err := wait.PollUntilContextTimeout(ctx, 5*time.Second, w.timeouts.Platform, true, func(ctx context.Context) (bool, error) {
-		ready, err := w.isKustomizationReady(ctx, w.platformKustomization)
-		if err != nil {
-			log.Debug("Error checking platform status", "error", err)
-			return false, nil
-		}
-		return ready, nil
-	})
-
+	reason, err := w.watchKustomizationReady(ctx, w.platformKustomization, w.timeouts.Platform)
 	if err != nil {
-		log.Warn("Platform foundation not ready yet", "name", w.platformKustomization, "timeout", w.timeouts.Platform)
+		log.Warn("Platform foundation not ready yet", "name", w.platformKustomization, "timeout", w.timeouts.Platform, "reason", reason)
 		w.diagnoseKustomization(ctx, w.platformKustomization)
 		// Don't fail here - platform might still be deploying
 	} else {
@@ -203,14 +191,62 @@ func (w *Waiter) waitForStorage(ctx context.Context) error {
 
 // Helper methods
 
-func (w *Waiter) kustomizationExists(ctx context.Context, name string) (bool, error) {
+// kustomizationStatus holds the fields of a Flux Kustomization that tell whether it is healthy
+type kustomizationStatus struct {
+	Metadata struct {
+		Generation int64 `json:"generation"`
+	} `json:"metadata"`
+	Spec struct {
+		Suspend bool `json:"suspend"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration int64              `json:"observedGeneration"`
+		Conditions         []metav1.Condition `json:"conditions"`
+	} `json:"status"`
+}
+
+// ready reports whether the Ready condition is True for the current generation, and why not otherwise
+func (k *kustomizationStatus) ready() (bool, string) {
+	if k.Spec.Suspend {
+		return false, "reconciliation is suspended"
+	}
+	if k.Status.ObservedGeneration < k.Metadata.Generation {
+		return false, fmt.Sprintf("generation %d not reconciled yet (observed %d)", k.Metadata.Generation, k.Status.ObservedGeneration)
+	}
+	for _, condition := range k.Status.Conditions {
+		if condition.Type != "Ready" {
+			continue
+		}
+		if condition.ObservedGeneration != 0 && condition.ObservedGeneration < k.Metadata.Generation {
+			return false, "Ready condition is stale: " + condition.Message
+		}
+		if condition.Status == metav1.ConditionTrue {
+			return true, ""
+		}
+		return false, fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+	}
+	return false, "no Ready condition reported yet"
+}
+
+func (w *Waiter) getKustomization(ctx context.Context, name string) (*kustomizationStatus, error) {
 	clientset := w.client.GetClientset()
-	_, err := clientset.CoreV1().RESTClient().
+	result, err := clientset.CoreV1().RESTClient().
 		Get().
 		AbsPath("/apis/kustomize.toolkit.fluxcd.io/v1/namespaces/flux-system/kustomizations/" + name).
 		DoRaw(ctx)
-
 	if err != nil {
+		return nil, err
+	}
+
+	var status kustomizationStatus
+	if err := json.Unmarshal(result, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse kustomization %s: %w", name, err)
+	}
+	return &status, nil
+}
+
+func (w *Waiter) kustomizationExists(ctx context.Context, name string) (bool, error) {
+	if _, err := w.getKustomization(ctx, name); err != nil {
 		if apierrors.IsNotFound(err) {
 			return false, nil
 		}
@@ -219,13 +255,24 @@ func (w *Waiter) kustomizationExists(ctx context.Context, name string) (bool, er
 	return true, nil
 }
 
-func (w *Waiter) isKustomizationReady(ctx context.Context, name string) (bool, error) {
-	// Simplified check - in production, parse the actual status conditions
-	exists, err := w.kustomizationExists(ctx, name)
-	if err != nil {
-		return false, err
-	}
-	return exists, nil
+// watchKustomizationReady waits for the Ready condition, logging each new reconcile message and returning the last one
+func (w *Waiter) watchKustomizationReady(ctx context.Context, name string, timeout time.Duration) (string, error) {
+	last := "kustomization not created yet"
+	err := w.client.WaitForObject(ctx, kustomizationGVR, "flux-system", name, timeout, func(obj *unstructured.Unstructured) (bool, error) {
+		var status kustomizationStatus
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &status); err != nil {
+			log.Debug("Error parsing kustomization status", "name", name, "error", err)
+			return false, nil
+		}
+		ready, message := status.ready()
+		if message != "" && message != last {
+			log.Info("Kustomization not ready", "name", name, "reason", message)
+			progress.Report(ctx, -1, fmt.Sprintf("%s: %s", name, message))
+		}
+		last = message
+		return ready, nil
+	})
+	return last, err
 }
 
 func (w *Waiter) waitForCephStorage(ctx context.Context) error {
@@ -246,18 +293,9 @@ func (w *Waiter) waitForCephStorage(ctx context.Context) error {
 		log.Info("Rook operator is ready")
 	}
 
-	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, w.timeouts.Ceph, true, func(ctx context.Context) (bool, error) {
-		exists, err := w.cephClusterExists(ctx)
-		if err != nil {
-			log.Debug("Error checking CephCluster", "error", err)
-			return false, nil
-		}
-		if exists {
-			log.Info("CephCluster resource found")
-			return true, nil
-		}
-		log.Debug("Waiting for CephCluster")
-		return false, nil
+	err = w.client.WaitForObject(ctx, cephClusterGVR, "rook-ceph", "rook-ceph", w.timeouts.Ceph, func(*unstructured.Unstructured) (bool, error) {
+		log.Info("CephCluster resource found")
+		return true, nil
 	})
 
 	if err != nil {
@@ -316,22 +354,6 @@ func (w *Waiter) hasDefaultStorageClass(ctx context.Context) bool {
 	return false
 }
 
-func (w *Waiter) cephClusterExists(ctx context.Context) (bool, error) {
-	clientset := w.client.GetClientset()
-	_, err := clientset.CoreV1().RESTClient().
-		Get().
-		AbsPath("/apis/ceph.rook.io/v1/namespaces/rook-ceph/cephclusters/rook-ceph").
-		DoRaw(ctx)
-
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return false, nil
-		}
-		return false, err
-	}
-	return true, nil
-}
-
 // Diagnostic methods
 
 func (w *Waiter) diagnoseFluxCD(ctx context.Context) {
@@ -381,29 +403,19 @@ func (w *Waiter) listKustomizations(ctx context.Context) {
 func (w *Waiter) diagnoseKustomization(ctx context.Context, name string) {
 	log.Info("Diagnosing kustomization", "name", name)
 
-	clientset := w.client.GetClientset()
-	result, err := clientset.CoreV1().RESTClient().
-		Get().
-		AbsPath(fmt.Sprintf("/apis/kustomize.toolkit.fluxcd.io/v1/namespaces/flux-system/kustomizations/%s", name)).
-		DoRaw(ctx)
-
+	status, err := w.getKustomization(ctx, name)
 	if err != nil {
 		log.Error("Failed to get kustomization details", "name", name, "error", err)
 		return
 	}
 
-	// Display basic status information
-	if string(result) != "" {
-		log.Debug("Kustomization details", "name", name, "data", string(result)[:min(800, len(result))])
-
-		// Look for common error indicators in the response
-		response := string(result)
-		if strings.Contains(response, "\"ready\": false") {
-			log.Warn("Kustomization is not ready", "name", name)
-		}
-		if strings.Contains(response, "error") {
-			log.Error("Kustomization has errors", "name", name)
+	log.Info("Kustomization generation", "name", name, "generation", status.Metadata.Generation, "observed", status.Status.ObservedGeneration, "suspended", status.Spec.Suspend)
+	for _, condition := range status.Status.Conditions {
+		if condition.Status == metav1.ConditionTrue {
+			log.Info("Kustomization condition", "name", name, "type", condition.Type, "reason", condition.Reason)
+			continue
 		}
+		log.Warn("Kustomization condition", "name", name, "type", condition.Type, "reason", condition.Reason, "message", condition.Message)
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -15,24 +16,59 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
 )
 
 const (
 	istioReaderPrefix = "istio-reader"
 	istioNamespace    = "istio-system"
+
+	// TokenIssuedAtAnnotation records when the embedded reader token was issued
+	TokenIssuedAtAnnotation = "homelab.bootstrap/token-issued-at"
+	// TokenExpiresAtAnnotation records when the embedded reader token expires
+	TokenExpiresAtAnnotation = "homelab.bootstrap/token-expires-at"
+	// TokenAnchorAnnotation names the secret the embedded reader token is bound to
+	TokenAnchorAnnotation = "homelab.bootstrap/token-anchor"
+
+	// DefaultTokenTTL is the lifetime requested for remote secret reader tokens
+	DefaultTokenTTL = 7 * 24 * time.Hour
 )
 
 // MultiClusterManager handles Istio multi-cluster configuration
 type MultiClusterManager struct {
-	client *k8s.Client
+	client   *k8s.Client
+	tokenTTL time.Duration
 }
 
 // NewMultiClusterManager creates a new multi-cluster manager
 func NewMultiClusterManager(client *k8s.Client) *MultiClusterManager {
 	return &MultiClusterManager{
-		client: client,
+		client:   client,
+		tokenTTL: DefaultTokenTTL,
+	}
+}
+
+// SetTokenTTL overrides the lifetime requested for reader tokens
+func (m *MultiClusterManager) SetTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		m.tokenTTL = ttl
+	}
+}
+
+// TokenExpiry returns the issue and expiry times recorded on a remote secret
+func TokenExpiry(secret *corev1.Secret) (time.Time, time.Time, bool) {
+	if secret == nil || secret.Annotations == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	issued, err := time.Parse(time.RFC3339, secret.Annotations[TokenIssuedAtAnnotation])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
 	}
+	expires, err := time.Parse(time.RFC3339, secret.Annotations[TokenExpiresAtAnnotation])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return issued, expires, true
 }
 
 // CreateRemoteSecret creates a remote secret for cross-cluster discovery
@@ -50,8 +86,15 @@ func (m *MultiClusterManager) CreateRemoteSecret(ctx context.Context, clusterNam
 		return nil, fmt.Errorf("failed to create RBAC: %w", err)
 	}
 
+	// Bind the token to an anchor secret of its own so it can be revoked by deleting the anchor
+	anchor, err := m.createTokenAnchor(ctx, sa.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token anchor: %w", err)
+	}
+
 	// Wait for service account token
-	token, ca, err := m.waitForServiceAccountToken(ctx, sa.Name, sa.Namespace)
+	issuedAt := time.Now().UTC()
+	token, ca, expiresAt, err := m.waitForServiceAccountToken(ctx, sa.Name, sa.Namespace, anchor)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service account token: %w", err)
 	}
@@ -76,6 +119,12 @@ func (m *MultiClusterManager) CreateRemoteSecret(ctx context.Context, clusterNam
 			Labels: map[string]string{
 				"istio/multiCluster": "true",
 			},
+			Annotations: map[string]string{
+				"networking.istio.io/cluster": clusterName,
+				TokenIssuedAtAnnotation:       issuedAt.Format(time.RFC3339),
+				TokenExpiresAtAnnotation:      expiresAt.UTC().Format(time.RFC3339),
+				TokenAnchorAnnotation:         anchor.Name,
+			},
 		},
 		Type: corev1.SecretTypeOpaque,
 		Data: map[string][]byte{
@@ -83,7 +132,7 @@ func (m *MultiClusterManager) CreateRemoteSecret(ctx context.Context, clusterNam
 		},
 	}
 
-	log.Info("Remote secret created", "cluster", clusterName)
+	log.Info("Remote secret created", "cluster", clusterName, "token_expires", expiresAt.UTC().Format(time.RFC3339))
 	return secret, nil
 }
 
@@ -209,22 +258,73 @@ func (m *MultiClusterManager) createRBAC(ctx context.Context, remoteCluster, saN
 	return nil
 }
 
-// waitForServiceAccountToken waits for and retrieves the service account token
-func (m *MultiClusterManager) waitForServiceAccountToken(ctx context.Context, saName, namespace string) (string, []byte, error) {
+// tokenAnchorPrefix returns the name prefix of the anchors reader tokens of a service account are bound to
+func tokenAnchorPrefix(saName string) string {
+	return fmt.Sprintf("%s-token-anchor", saName)
+}
+
+// createTokenAnchor creates a new secret for a reader token to be bound to
+func (m *MultiClusterManager) createTokenAnchor(ctx context.Context, saName string) (*corev1.Secret, error) {
+	anchor := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: tokenAnchorPrefix(saName) + "-",
+			Namespace:    istioNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "homelab-bootstrap",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+	created, err := m.client.GetClientset().CoreV1().Secrets(istioNamespace).Create(ctx, anchor, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create anchor secret for %s: %w", saName, err)
+	}
+	return created, nil
+}
+
+// RevokeTokens invalidates the reader tokens issued for a cluster by deleting their anchors, all but keep
+func (m *MultiClusterManager) RevokeTokens(ctx context.Context, clusterName, keep string) error {
+	prefix := tokenAnchorPrefix(fmt.Sprintf("%s-%s", istioReaderPrefix, clusterName))
+	secrets := m.client.GetClientset().CoreV1().Secrets(istioNamespace)
+	list, err := secrets.List(ctx, metav1.ListOptions{LabelSelector: "app.kubernetes.io/managed-by=homelab-bootstrap"})
+	if err != nil {
+		return fmt.Errorf("failed to list token anchors: %w", err)
+	}
+	for _, anchor := range list.Items {
+		if anchor.Name == keep || (anchor.Name != prefix && !strings.HasPrefix(anchor.Name, prefix+"-")) {
+			continue
+		}
+		if err := secrets.Delete(ctx, anchor.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete token anchor %s: %w", anchor.Name, err)
+		}
+		log.Info("Revoked previous reader tokens", "cluster", clusterName, "anchor", anchor.Name)
+	}
+	return nil
+}
+
+// waitForServiceAccountToken waits for and retrieves a bound, time-limited service account token
+func (m *MultiClusterManager) waitForServiceAccountToken(ctx context.Context, saName, namespace string, anchor *corev1.Secret) (string, []byte, time.Time, error) {
 	var token string
 	var ca []byte
+	var expiresAt time.Time
 
-	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, 30*time.Second, true, func(ctx context.Context) (bool, error) {
+	err := k8s.Poll(ctx, 2*time.Second, 30*time.Second, func(ctx context.Context) (bool, error) {
 		// Get the service account
 		sa, err := m.client.GetClientset().CoreV1().ServiceAccounts(namespace).Get(ctx, saName, metav1.GetOptions{})
 		if err != nil {
 			return false, nil
 		}
 
-		// In Kubernetes 1.24+, we need to create a token manually
+		// Request a token bound to the anchor secret that expires after the configured TTL
 		tokenRequest := &authv1.TokenRequest{
 			Spec: authv1.TokenRequestSpec{
-				ExpirationSeconds: int64Ptr(365 * 24 * 60 * 60), // 1 year
+				ExpirationSeconds: ptr.To(int64(m.tokenTTL.Seconds())),
+				BoundObjectRef: &authv1.BoundObjectReference{
+					Kind:       "Secret",
+					APIVersion: "v1",
+					Name:       anchor.Name,
+					UID:        anchor.UID,
+				},
 			},
 		}
 
@@ -235,6 +335,7 @@ func (m *MultiClusterManager) waitForServiceAccountToken(ctx context.Context, sa
 		}
 
 		token = tokenResponse.Status.Token
+		expiresAt = tokenResponse.Status.ExpirationTimestamp.Time
 
 		// Get CA certificate from the cluster
 		caSecret, err := m.client.GetClientset().CoreV1().Secrets("kube-system").Get(ctx, "kube-root-ca.crt", metav1.GetOptions{})
@@ -256,16 +357,14 @@ func (m *MultiClusterManager) waitForServiceAccountToken(ctx context.Context, sa
 			}
 		}
 
-		// Fallback: try to get from service account secret (pre-1.24 clusters)
+		// Fallback: read the CA from a legacy service account secret (pre-1.24 clusters).
+		// The long-lived token it carries is deliberately ignored.
 		for _, secretRef := range sa.Secrets {
 			secret, err := m.client.GetClientset().CoreV1().Secrets(namespace).Get(ctx, secretRef.Name, metav1.GetOptions{})
 			if err != nil {
 				continue
 			}
 			if secret.Type == corev1.SecretTypeServiceAccountToken {
-				if t, ok := secret.Data["token"]; ok {
-					token = string(t)
-				}
 				if c, ok := secret.Data["ca.crt"]; ok {
 					ca = c
 					return true, nil
@@ -277,10 +376,10 @@ func (m *MultiClusterManager) waitForServiceAccountToken(ctx context.Context, sa
 	})
 
 	if err != nil {
-		return "", nil, fmt.Errorf("timeout waiting for service account token: %w", err)
+		return "", nil, time.Time{}, fmt.Errorf("timeout waiting for service account token: %w", err)
 	}
 
-	return token, ca, nil
+	return token, ca, expiresAt, nil
 }
 
 // getAPIServerAddress gets the Kubernetes API server address
@@ -328,8 +427,3 @@ func (m *MultiClusterManager) createMinimalKubeconfig(clusterName, server string
 
 	return json.Marshal(kubeconfig)
 }
-
-// Helper function
-func int64Ptr(i int64) *int64 {
-	return &i
-}
@@ -0,0 +1,362 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Issue kinds raised by the audit
+const (
+	IssueStale          = "stale"
+	IssueNeverSucceeded = "never-succeeded"
+	IssueFailing        = "failing"
+	IssueStuck          = "stuck"
+	IssueSuspended      = "suspended"
+)
+
+var (
+	veleroScheduleGVR     = schema.GroupVersionResource{Group: "velero.io", Version: "v1", Resource: "schedules"}
+	replicationSourceGVR  = schema.GroupVersionResource{Group: "volsync.backube", Version: "v1alpha1", Resource: "replicationsources"}
+	minimumStuckThreshold = time.Hour
+)
+
+// Issue is a problem found on a schedule
+type Issue struct {
+	Kind     string
+	Severity string
+	Message  string
+}
+
+// Schedule is a CronJob or scheduled backup/replication resource
+type Schedule struct {
+	Cluster        string
+	Kind           string
+	Namespace      string
+	Name           string
+	Schedule       string
+	Suspended      bool
+	Interval       time.Duration
+	LastScheduled  *time.Time
+	LastSuccessful *time.Time
+	Active         int
+	Issues         []Issue
+}
+
+// ID identifies the schedule across clusters
+func (s Schedule) ID() string {
+	return fmt.Sprintf("%s:%s/%s/%s", s.Cluster, s.Kind, s.Namespace, s.Name)
+}
+
+// Healthy reports whether the schedule raised no issue
+func (s Schedule) Healthy() bool {
+	return len(s.Issues) == 0
+}
+
+// Options tunes the audit thresholds
+type Options struct {
+	// Tolerance is how many schedule intervals may pass without a success
+	Tolerance float64
+	// StuckAfter flags active runs older than this, defaulting to one interval
+	StuckAfter time.Duration
+}
+
+// Auditor audits the scheduled jobs of one cluster
+type Auditor struct {
+	cluster string
+	client  *k8s.Client
+	opts    Options
+	now     func() time.Time
+}
+
+// NewAuditor creates a new auditor for the cluster behind client
+func NewAuditor(cluster string, client *k8s.Client, opts Options) *Auditor {
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 2
+	}
+	return &Auditor{
+		cluster: cluster,
+		client:  client,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// Audit lists the CronJobs, Velero schedules and VolSync replications of the cluster with their issues
+func (a *Auditor) Audit(ctx context.Context) ([]Schedule, error) {
+	schedules, err := a.cronJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	velero, err := a.veleroSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedules = append(schedules, velero...)
+
+	replications, err := a.replicationSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedules = append(schedules, replications...)
+
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID() < schedules[j].ID() })
+	return schedules, nil
+}
+
+func (a *Auditor) cronJobs(ctx context.Context) ([]Schedule, error) {
+	clientset := a.client.GetClientset()
+	cronJobs, err := clientset.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs on %s: %w", a.cluster, err)
+	}
+	jobList, err := clientset.BatchV1().Jobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs on %s: %w", a.cluster, err)
+	}
+
+	owned := make(map[string][]batchv1.Job)
+	for _, job := range jobList.Items {
+		for _, owner := range job.OwnerReferences {
+			if owner.Kind == "CronJob" {
+				key := job.Namespace + "/" + owner.Name
+				owned[key] = append(owned[key], job)
+			}
+		}
+	}
+
+	schedules := make([]Schedule, 0, len(cronJobs.Items))
+	for _, cronJob := range cronJobs.Items {
+		expression := cronJob.Spec.Schedule
+		if cronJob.Spec.TimeZone != nil && !strings.HasPrefix(expression, "CRON_TZ=") {
+			expression = fmt.Sprintf("CRON_TZ=%s %s", *cronJob.Spec.TimeZone, expression)
+		}
+
+		schedule := Schedule{
+			Cluster:   a.cluster,
+			Kind:      "CronJob",
+			Namespace: cronJob.Namespace,
+			Name:      cronJob.Name,
+			Schedule:  cronJob.Spec.Schedule,
+			Suspended: cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+			Active:    len(cronJob.Status.Active),
+		}
+		if cronJob.Status.LastScheduleTime != nil {
+			schedule.LastScheduled = &cronJob.Status.LastScheduleTime.Time
+		}
+		if cronJob.Status.LastSuccessfulTime != nil {
+			schedule.LastSuccessful = &cronJob.Status.LastSuccessfulTime.Time
+		}
+
+		a.checkSchedule(&schedule, expression, cronJob.CreationTimestamp.Time)
+		a.checkJobs(&schedule, owned[cronJob.Namespace+"/"+cronJob.Name])
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func (a *Auditor) veleroSchedules(ctx context.Context) ([]Schedule, error) {
+	items, err := a.listOptional(ctx, veleroScheduleGVR)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]Schedule, 0, len(items))
+	for _, item := range items {
+		expression, _, _ := unstructured.NestedString(item.Object, "spec", "schedule")
+		schedule := Schedule{
+			Cluster:   a.cluster,
+			Kind:      "VeleroSchedule",
+			Namespace: item.GetNamespace(),
+			Name:      item.GetName(),
+			Schedule:  expression,
+			Suspended: nestedBoolValue(item.Object, "spec", "paused"),
+		}
+		// Velero only records the last backup it started, a failed backup still shows in Backup objects
+		if last := nestedTime(item.Object, "status", "lastBackup"); last != nil {
+			schedule.LastScheduled = last
+			schedule.LastSuccessful = last
+		}
+		if phase, _, _ := unstructured.NestedString(item.Object, "status", "phase"); phase == "FailedValidation" {
+			schedule.Issues = append(schedule.Issues, Issue{Kind: IssueFailing, Severity: "error", Message: "schedule failed validation"})
+		}
+
+		a.checkSchedule(&schedule, expression, item.GetCreationTimestamp().Time)
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func (a *Auditor) replicationSources(ctx context.Context) ([]Schedule, error) {
+	items, err := a.listOptional(ctx, replicationSourceGVR)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]Schedule, 0, len(items))
+	for _, item := range items {
+		expression, _, _ := unstructured.NestedString(item.Object, "spec", "trigger", "schedule")
+		if expression == "" {
+			// Manually triggered replications have no expected window
+			continue
+		}
+		schedule := Schedule{
+			Cluster:   a.cluster,
+			Kind:      "ReplicationSource",
+			Namespace: item.GetNamespace(),
+			Name:      item.GetName(),
+			Schedule:  expression,
+			Suspended: nestedBoolValue(item.Object, "spec", "paused"),
+		}
+		if last := nestedTime(item.Object, "status", "lastSyncTime"); last != nil {
+			schedule.LastScheduled = last
+			schedule.LastSuccessful = last
+		}
+		if started := nestedTime(item.Object, "status", "lastSyncStartTime"); started != nil {
+			schedule.LastScheduled = started
+		}
+		a.checkSchedule(&schedule, expression, item.GetCreationTimestamp().Time)
+
+		conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+		for _, raw := range conditions {
+			condition, ok := raw.(map[string]interface{})
+			if !ok || condition["type"] != "Synchronizing" {
+				continue
+			}
+			if condition["status"] == string(corev1.ConditionTrue) {
+				schedule.Active = 1
+				if started := nestedTime(condition, "lastTransitionTime"); started != nil {
+					a.checkStuck(&schedule, *started)
+				}
+			}
+			if reason, _ := condition["reason"].(string); reason == "Error" {
+				message, _ := condition["message"].(string)
+				schedule.Issues = append(schedule.Issues, Issue{Kind: IssueFailing, Severity: "error", Message: "last sync failed: " + message})
+			}
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// listOptional lists a custom resource, returning nothing when its CRD is not installed
+func (a *Auditor) listOptional(ctx context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	list, err := a.client.GetDynamicClient().Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list %s on %s: %w", gvr.Resource, a.cluster, err)
+	}
+	return list.Items, nil
+}
+
+// checkSchedule flags suspended schedules and ones without a success within their expected window
+func (a *Auditor) checkSchedule(schedule *Schedule, expression string, created time.Time) {
+	if schedule.Suspended {
+		schedule.Issues = append(schedule.Issues, Issue{Kind: IssueSuspended, Severity: "warning", Message: "schedule is suspended"})
+		return
+	}
+
+	parsed, err := cron.ParseStandard(expression)
+	if err != nil {
+		schedule.Issues = append(schedule.Issues, Issue{Kind: IssueFailing, Severity: "error", Message: fmt.Sprintf("invalid schedule %q: %v", expression, err)})
+		return
+	}
+	next := parsed.Next(a.now())
+	schedule.Interval = parsed.Next(next).Sub(next)
+
+	window := time.Duration(float64(schedule.Interval) * a.opts.Tolerance)
+	now := a.now()
+	switch {
+	case schedule.LastSuccessful == nil && now.Sub(created) > window:
+		schedule.Issues = append(schedule.Issues, Issue{
+			Kind:     IssueNeverSucceeded,
+			Severity: "error",
+			Message:  fmt.Sprintf("no successful run since creation %s ago", now.Sub(created).Round(time.Minute)),
+		})
+	case schedule.LastSuccessful != nil && now.Sub(*schedule.LastSuccessful) > window:
+		schedule.Issues = append(schedule.Issues, Issue{
+			Kind:     IssueStale,
+			Severity: "error",
+			Message: fmt.Sprintf("last success %s ago, expected every %s",
+				now.Sub(*schedule.LastSuccessful).Round(time.Minute), schedule.Interval),
+		})
+	}
+}
+
+// checkJobs flags CronJobs whose latest run failed or whose active runs are stuck
+func (a *Auditor) checkJobs(schedule *Schedule, jobs []batchv1.Job) {
+	if len(jobs) == 0 {
+		return
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreationTimestamp.After(jobs[j].CreationTimestamp.Time)
+	})
+
+	for _, condition := range jobs[0].Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			schedule.Issues = append(schedule.Issues, Issue{
+				Kind:     IssueFailing,
+				Severity: "error",
+				Message:  fmt.Sprintf("latest job %s failed: %s", jobs[0].Name, condition.Reason),
+			})
+		}
+	}
+
+	for _, job := range jobs {
+		if job.Status.Active > 0 && job.Status.StartTime != nil && a.checkStuck(schedule, job.Status.StartTime.Time) {
+			return
+		}
+	}
+}
+
+// checkStuck flags a run active for longer than the stuck threshold
+func (a *Auditor) checkStuck(schedule *Schedule, started time.Time) bool {
+	threshold := a.opts.StuckAfter
+	if threshold == 0 {
+		threshold = schedule.Interval
+		if threshold < minimumStuckThreshold {
+			threshold = minimumStuckThreshold
+		}
+	}
+	if running := a.now().Sub(started); running > threshold {
+		schedule.Issues = append(schedule.Issues, Issue{
+			Kind:     IssueStuck,
+			Severity: "error",
+			Message:  fmt.Sprintf("run active for %s", running.Round(time.Minute)),
+		})
+		return true
+	}
+	return false
+}
+
+func nestedTime(object map[string]interface{}, fields ...string) *time.Time {
+	value, found, err := unstructured.NestedString(object, fields...)
+	if err != nil || !found || value == "" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+func nestedBoolValue(object map[string]interface{}, fields ...string) bool {
+	value, _, _ := unstructured.NestedBool(object, fields...)
+	return value
+}
@@ -0,0 +1,464 @@
+package k3s
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/docker"
+	"gopkg.in/yaml.v3"
+)
+
+// Labels docker compose finds the containers of a project by, set so docker compose exec and ps keep working
+const (
+	composeProjectLabel   = "com.docker.compose.project"
+	composeServiceLabel   = "com.docker.compose.service"
+	composeNumberLabel    = "com.docker.compose.container-number"
+	composeOneoffLabel    = "com.docker.compose.oneoff"
+	configHashLabel       = "homelab.bootstrap/config-hash"
+	defaultComposeProject = "k3s"
+)
+
+// supportedServiceKeys are the compose service keys translated to the Docker API, others need docker compose
+var supportedServiceKeys = map[string]bool{
+	"container_name": true, "hostname": true, "image": true, "command": true, "environment": true,
+	"volumes": true, "ports": true, "network_mode": true, "privileged": true, "restart": true,
+	"labels": true, "logging": true, "runtime": true,
+}
+
+// variablePattern matches $$, $VAR, ${VAR}, ${VAR:-default} and ${VAR-default}
+var variablePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?-)([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// ComposeService is a service of a compose file, with its variables substituted, run through the Docker API
+type ComposeService struct {
+	Project       string
+	Name          string
+	ContainerName string
+	Hostname      string
+	Image         string
+	Command       []string
+	Environment   map[string]string
+	Volumes       []string
+	Ports         []string
+	NetworkMode   string
+	Privileged    bool
+	Restart       string
+	Labels        map[string]string
+	LogDriver     string
+	LogOptions    map[string]string
+	Runtime       string
+}
+
+// composeFile is the part of a compose file the bootstrap reads
+type composeFile struct {
+	Name     string               `yaml:"name"`
+	Services map[string]yaml.Node `yaml:"services"`
+}
+
+type composeServiceSpec struct {
+	ContainerName string       `yaml:"container_name"`
+	Hostname      string       `yaml:"hostname"`
+	Image         string       `yaml:"image"`
+	Command       stringOrList `yaml:"command"`
+	Environment   listOrMap    `yaml:"environment"`
+	Volumes       []string     `yaml:"volumes"`
+	Ports         []string     `yaml:"ports"`
+	NetworkMode   string       `yaml:"network_mode"`
+	Privileged    bool         `yaml:"privileged"`
+	Restart       string       `yaml:"restart"`
+	Labels        listOrMap    `yaml:"labels"`
+	Logging       struct {
+		Driver  string            `yaml:"driver"`
+		Options map[string]string `yaml:"options"`
+	} `yaml:"logging"`
+	Runtime string `yaml:"runtime"`
+}
+
+// stringOrList is a command given as a string, split like a shell would, or as a list
+type stringOrList []string
+
+func (s *stringOrList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		words, err := splitWords(node.Value)
+		if err != nil {
+			return err
+		}
+		*s = words
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// listOrMap is a mapping given either as KEY=VALUE items or as a mapping
+type listOrMap map[string]string
+
+func (l *listOrMap) UnmarshalYAML(node *yaml.Node) error {
+	values := map[string]string{}
+	if node.Kind == yaml.SequenceNode {
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			key, value, _ := strings.Cut(item, "=")
+			values[key] = value
+		}
+	} else if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// LoadComposeService reads service from a compose file. Variables come from the environment, then the .env
+// file next to the compose file, as with docker compose; an unset variable without default is an error
+// since it usually ends up in a host path.
+func LoadComposeService(path, service string) (*ComposeService, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var file composeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	node, ok := file.Services[service]
+	if !ok {
+		return nil, fmt.Errorf("no %s service in %s", service, path)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i].Value; !supportedServiceKeys[key] {
+			return nil, fmt.Errorf("%s: %s of the %s service is not supported, run it with docker compose", path, key, service)
+		}
+	}
+	var spec composeServiceSpec
+	if err := node.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s service of %s: %w", service, path, err)
+	}
+
+	vars, err := readDotEnv(filepath.Join(filepath.Dir(path), ".env"))
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string]bool{}
+	expand := func(value string) string {
+		return variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+			if match == "$$" {
+				return "$"
+			}
+			groups := variablePattern.FindStringSubmatch(match)
+			name := groups[1] + groups[4]
+			value, set := os.LookupEnv(name)
+			if !set {
+				value, set = vars[name]
+			}
+			switch {
+			case groups[2] == ":-" && value == "", groups[2] == "-" && !set:
+				return groups[3]
+			case !set:
+				missing[name] = true
+			}
+			return value
+		})
+	}
+	expandAll := func(values []string) []string {
+		expanded := make([]string, len(values))
+		for i, value := range values {
+			expanded[i] = expand(value)
+		}
+		return expanded
+	}
+	expandMap := func(values map[string]string) map[string]string {
+		expanded := make(map[string]string, len(values))
+		for key, value := range values {
+			expanded[key] = expand(value)
+		}
+		return expanded
+	}
+
+	project := file.Name
+	if project == "" {
+		project = defaultComposeProject
+	}
+	s := &ComposeService{
+		Project:       project,
+		Name:          service,
+		ContainerName: expand(spec.ContainerName),
+		Hostname:      expand(spec.Hostname),
+		Image:         expand(spec.Image),
+		Command:       expandAll(spec.Command),
+		Environment:   expandMap(spec.Environment),
+		Volumes:       expandAll(spec.Volumes),
+		Ports:         expandAll(spec.Ports),
+		NetworkMode:   spec.NetworkMode,
+		Privileged:    spec.Privileged,
+		Restart:       spec.Restart,
+		Labels:        expandMap(spec.Labels),
+		LogDriver:     spec.Logging.Driver,
+		LogOptions:    spec.Logging.Options,
+		Runtime:       spec.Runtime,
+	}
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%s uses unset variables %v, set them in %s", path, names, filepath.Join(filepath.Dir(path), ".env"))
+	}
+	if s.Image == "" {
+		return nil, fmt.Errorf("the %s service of %s has no image", service, path)
+	}
+	if s.ContainerName == "" {
+		// docker compose names a single container after the project and service
+		s.ContainerName = project + "-" + service + "-1"
+	}
+	return s, nil
+}
+
+// readDotEnv parses the KEY=VALUE lines of a .env file, a missing file has no variables
+func readDotEnv(path string) (map[string]string, error) {
+	vars := map[string]string{}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return vars, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// splitWords splits a command string on spaces, keeping quoted words together
+func splitWords(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command %q", command)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// HostPorts returns the host ports the service publishes explicitly, those Docker picks are left out
+func (s *ComposeService) HostPorts() ([]HostPort, error) {
+	mappings, err := s.portMappings()
+	if err != nil {
+		return nil, err
+	}
+	var ports []HostPort
+	for _, mapping := range mappings {
+		if mapping.hostPort != 0 {
+			ports = append(ports, HostPort{IP: mapping.hostIP, Port: mapping.hostPort, Protocol: mapping.protocol})
+		}
+	}
+	return ports, nil
+}
+
+// HostPort is a port the service binds on the Docker host
+type HostPort struct {
+	IP       string
+	Port     int
+	Protocol string
+}
+
+type portMapping struct {
+	hostIP        string
+	hostPort      int
+	containerPort int
+	protocol      string
+}
+
+// portMappings expands the short syntax [[ip:]host:]container[/protocol] of ports, ranges included
+func (s *ComposeService) portMappings() ([]portMapping, error) {
+	var mappings []portMapping
+	for _, entry := range s.Ports {
+		spec, protocol, _ := strings.Cut(entry, "/")
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		parts := strings.Split(spec, ":")
+		var hostIP, hostRange, containerRange string
+		switch len(parts) {
+		case 1:
+			containerRange = parts[0]
+		case 2:
+			hostRange, containerRange = parts[0], parts[1]
+		case 3:
+			hostIP, hostRange, containerRange = parts[0], parts[1], parts[2]
+		default:
+			return nil, fmt.Errorf("unsupported port %q of the %s service", entry, s.Name)
+		}
+		containerFirst, containerLast, err := portRange(containerRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q of the %s service: %w", entry, s.Name, err)
+		}
+		hostFirst, hostLast := 0, 0
+		if hostRange != "" {
+			if hostFirst, hostLast, err = portRange(hostRange); err != nil {
+				return nil, fmt.Errorf("invalid port %q of the %s service: %w", entry, s.Name, err)
+			}
+			if hostLast-hostFirst != containerLast-containerFirst {
+				return nil, fmt.Errorf("invalid port %q of the %s service: host and container ranges differ in size", entry, s.Name)
+			}
+		}
+		for offset := 0; containerFirst+offset <= containerLast; offset++ {
+			mapping := portMapping{hostIP: hostIP, containerPort: containerFirst + offset, protocol: protocol}
+			if hostFirst != 0 {
+				mapping.hostPort = hostFirst + offset
+			}
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings, nil
+}
+
+func portRange(value string) (int, int, error) {
+	first, last, isRange := strings.Cut(value, "-")
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, err
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(last); err != nil {
+			return 0, 0, err
+		}
+	}
+	if start < 1 || end > 65535 || end < start {
+		return 0, 0, fmt.Errorf("port range %s out of bounds", value)
+	}
+	return start, end, nil
+}
+
+// ContainerConfig translates the service into a Docker create request, labelled so docker compose
+// recognises the container as its own and with a hash of the definition to detect changes
+func (s *ComposeService) ContainerConfig() (docker.ContainerConfig, error) {
+	config := docker.ContainerConfig{
+		Hostname: s.Hostname,
+		Image:    s.Image,
+		Cmd:      s.Command,
+		Labels:   map[string]string{},
+		HostConfig: docker.HostConfig{
+			NetworkMode: s.NetworkMode,
+			Privileged:  s.Privileged,
+			Runtime:     s.Runtime,
+			LogConfig:   docker.LogConfig{Type: s.LogDriver, Config: s.LogOptions},
+		},
+	}
+	for key, value := range s.Environment {
+		config.Env = append(config.Env, key+"="+value)
+	}
+	sort.Strings(config.Env)
+	for key, value := range s.Labels {
+		config.Labels[key] = value
+	}
+
+	for _, volume := range s.Volumes {
+		if !strings.HasPrefix(volume, "/") {
+			return docker.ContainerConfig{}, fmt.Errorf("volume %q of the %s service is not an absolute host path, named volumes need docker compose", volume, s.Name)
+		}
+		config.HostConfig.Binds = append(config.HostConfig.Binds, volume)
+	}
+
+	mappings, err := s.portMappings()
+	if err != nil {
+		return docker.ContainerConfig{}, err
+	}
+	if len(mappings) > 0 {
+		config.ExposedPorts = map[string]struct{}{}
+		config.HostConfig.PortBindings = map[string][]docker.PortBinding{}
+	}
+	for _, mapping := range mappings {
+		port := strconv.Itoa(mapping.containerPort) + "/" + mapping.protocol
+		config.ExposedPorts[port] = struct{}{}
+		binding := docker.PortBinding{HostIP: mapping.hostIP}
+		if mapping.hostPort != 0 {
+			binding.HostPort = strconv.Itoa(mapping.hostPort)
+		}
+		config.HostConfig.PortBindings[port] = append(config.HostConfig.PortBindings[port], binding)
+	}
+
+	if s.Restart != "" && s.Restart != "no" {
+		name, retries, _ := strings.Cut(s.Restart, ":")
+		config.HostConfig.RestartPolicy.Name = name
+		if retries != "" {
+			count, err := strconv.Atoi(retries)
+			if err != nil {
+				return docker.ContainerConfig{}, fmt.Errorf("invalid restart policy %q of the %s service", s.Restart, s.Name)
+			}
+			config.HostConfig.RestartPolicy.MaximumRetryCount = count
+		}
+	}
+
+	// The hash covers the definition before the bootstrap labels, which are derived from it
+	data, err := json.Marshal(config)
+	if err != nil {
+		return docker.ContainerConfig{}, err
+	}
+	sum := sha256.Sum256(data)
+	config.Labels[configHashLabel] = hex.EncodeToString(sum[:])
+	config.Labels[composeProjectLabel] = s.Project
+	config.Labels[composeServiceLabel] = s.Name
+	config.Labels[composeNumberLabel] = "1"
+	config.Labels[composeOneoffLabel] = "False"
+	return config, nil
+}
@@ -0,0 +1,292 @@
+package k3s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/docker"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// kubeconfigPath is the admin kubeconfig K3s writes once its API server is up
+	kubeconfigPath = "/etc/rancher/k3s/k3s.yaml"
+	// stopTimeout gives K3s time to stop its pods before the container is killed
+	stopTimeout = 30 * time.Second
+	// dialTimeout bounds the probe of a host port before starting the container
+	dialTimeout = 2 * time.Second
+)
+
+// Container runs the K3s server of the NAS compose file through the Docker Engine API, without docker compose
+type Container struct {
+	docker   *docker.Client
+	service  *ComposeService
+	progress func(message string)
+}
+
+// ContainerStatus is the state of the K3s container on the Docker host
+type ContainerStatus struct {
+	Name         string    `json:"name"`
+	Exists       bool      `json:"exists"`
+	State        string    `json:"state,omitempty"`
+	Image        string    `json:"image,omitempty"`
+	StartedAt    time.Time `json:"started_at,omitempty"`
+	RestartCount int       `json:"restart_count,omitempty"`
+	ExitCode     int       `json:"exit_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	// UpToDate tells whether the container runs the current definition of the compose file
+	UpToDate bool `json:"up_to_date"`
+}
+
+// NewContainer manages the container of service through client
+func NewContainer(client *docker.Client, service *ComposeService) *Container {
+	return &Container{docker: client, service: service}
+}
+
+// SetProgressSink forwards progress messages, e.g. to the TUI log pane, instead of logging them
+func (c *Container) SetProgressSink(sink func(message string)) {
+	c.progress = sink
+}
+
+// Name returns the name of the container
+func (c *Container) Name() string {
+	return c.service.ContainerName
+}
+
+// Status inspects the container, which may not exist
+func (c *Container) Status(ctx context.Context) (*ContainerStatus, error) {
+	status := &ContainerStatus{Name: c.Name()}
+	current, err := c.inspect(ctx)
+	if err != nil || current == nil {
+		return status, err
+	}
+	config, err := c.service.ContainerConfig()
+	if err != nil {
+		return nil, err
+	}
+	status.Exists = true
+	status.State = current.State.Status
+	status.Image = current.Config.Image
+	status.StartedAt = current.State.StartedAt
+	status.RestartCount = current.RestartCount
+	status.ExitCode = current.State.ExitCode
+	status.Error = current.State.Error
+	status.IPAddress = current.IPAddress()
+	status.UpToDate = current.Config.Labels[configHashLabel] == config.Labels[configHashLabel]
+	return status, nil
+}
+
+// CheckPorts fails when a host port the service publishes is taken, by another container or by a process
+// of the Docker host, so the conflict is reported before Docker half-creates the container
+func (c *Container) CheckPorts(ctx context.Context) error {
+	ports, err := c.service.HostPorts()
+	if err != nil || len(ports) == 0 {
+		return err
+	}
+	current, err := c.inspect(ctx)
+	if err != nil {
+		return err
+	}
+	containers, err := c.docker.Containers(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []error
+	for _, other := range containers {
+		if current != nil && other.ID == current.ID {
+			continue
+		}
+		for _, published := range other.Ports {
+			for _, port := range ports {
+				if published.PublicPort == port.Port && published.Type == port.Protocol {
+					conflicts = append(conflicts, fmt.Errorf("port %d/%s is already published by container %s", port.Port, port.Protocol, other.Name()))
+				}
+			}
+		}
+	}
+	if current != nil && current.State.Running {
+		// The running container holds its own ports
+		return errors.Join(conflicts...)
+	}
+
+	host := c.docker.Host()
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	for _, port := range ports {
+		if port.Protocol != "tcp" {
+			continue
+		}
+		address := host
+		if port.IP != "" && port.IP != "0.0.0.0" {
+			address = port.IP
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(port.Port)))
+		if err == nil {
+			conn.Close()
+			conflicts = append(conflicts, fmt.Errorf("port %d on %s is already in use", port.Port, address))
+		}
+	}
+	return errors.Join(conflicts...)
+}
+
+// Up pulls the image and starts the container, recreating it when the compose definition changed
+func (c *Container) Up(ctx context.Context) error {
+	config, err := c.service.ContainerConfig()
+	if err != nil {
+		return err
+	}
+	c.report("Pulling " + config.Image)
+	if err := c.docker.Pull(ctx, config.Image, c.report); err != nil {
+		return err
+	}
+
+	current, err := c.inspect(ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Config.Labels[configHashLabel] != config.Labels[configHashLabel] {
+		c.report("Recreating " + c.Name() + ", its definition changed")
+		if err := c.remove(ctx, current); err != nil {
+			return err
+		}
+		current = nil
+	}
+
+	id := ""
+	if current == nil {
+		c.report("Creating " + c.Name())
+		if id, err = c.docker.Create(ctx, c.Name(), config); err != nil {
+			return err
+		}
+	} else if current.State.Running {
+		c.report(c.Name() + " is already running")
+		return nil
+	} else {
+		id = current.ID
+	}
+	c.report("Starting " + c.Name())
+	return c.docker.Start(ctx, id)
+}
+
+// WaitRunning waits for the container to run, failing once it exited for good
+func (c *Container) WaitRunning(ctx context.Context, timeout time.Duration) error {
+	return k8s.Poll(ctx, 2*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		current, err := c.inspect(ctx)
+		if err != nil {
+			return false, err
+		}
+		if current == nil {
+			return false, fmt.Errorf("container %s disappeared", c.Name())
+		}
+		switch current.State.Status {
+		case "exited", "dead":
+			return false, fmt.Errorf("container %s exited with code %d, see bootstrap nas logs", c.Name(), current.State.ExitCode)
+		}
+		return current.State.Running && !current.State.Restarting, nil
+	})
+}
+
+// Kubeconfig waits for K3s to write its admin kubeconfig and returns it pointed at server, with its context
+// renamed to contextName
+func (c *Container) Kubeconfig(ctx context.Context, server, contextName string, timeout time.Duration) ([]byte, error) {
+	var data []byte
+	err := k8s.Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		content, err := c.docker.ReadFile(ctx, c.Name(), kubeconfigPath)
+		if errors.Is(err, docker.ErrNotFound) {
+			log.Debug("Waiting for the K3s kubeconfig", "container", c.Name())
+			return false, nil
+		}
+		data = content
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from %s: %w", kubeconfigPath, c.Name(), err)
+	}
+
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the K3s kubeconfig: %w", err)
+	}
+	// K3s points the kubeconfig at its loopback address, unreachable from outside the container
+	for _, cluster := range kubeconfig.Clusters {
+		cluster.Server = server
+	}
+	if current, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]; ok && contextName != kubeconfig.CurrentContext {
+		delete(kubeconfig.Contexts, kubeconfig.CurrentContext)
+		kubeconfig.Contexts[contextName] = current
+		kubeconfig.CurrentContext = contextName
+	}
+	return clientcmd.Write(*kubeconfig)
+}
+
+// Logs copies the last tail lines of the container output to w, following new lines with follow
+func (c *Container) Logs(ctx context.Context, tail string, follow bool, w io.Writer) error {
+	return c.docker.Logs(ctx, c.Name(), tail, follow, w)
+}
+
+// FollowLogs sends the last tail lines of the container output then each new one to sink, until ctx is done
+func (c *Container) FollowLogs(ctx context.Context, tail string, sink func(line string)) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(c.Logs(ctx, tail, true, writer))
+	}()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		sink(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// Down stops and removes the container, the data of its host volumes stays on the NAS
+func (c *Container) Down(ctx context.Context) error {
+	current, err := c.inspect(ctx)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		c.report(c.Name() + " does not exist")
+		return nil
+	}
+	return c.remove(ctx, current)
+}
+
+func (c *Container) remove(ctx context.Context, current *docker.Container) error {
+	if current.State.Running {
+		c.report("Stopping " + c.Name())
+		if err := c.docker.Stop(ctx, current.ID, stopTimeout); err != nil {
+			return err
+		}
+	}
+	c.report("Removing " + c.Name())
+	return c.docker.Remove(ctx, current.ID)
+}
+
+// inspect returns the container, nil when it does not exist
+func (c *Container) inspect(ctx context.Context) (*docker.Container, error) {
+	current, err := c.docker.Inspect(ctx, c.Name())
+	if errors.Is(err, docker.ErrNotFound) {
+		return nil, nil
+	}
+	return current, err
+}
+
+func (c *Container) report(message string) {
+	if c.progress != nil {
+		c.progress(message)
+		return
+	}
+	log.Info(message)
+}
@@ -0,0 +1,156 @@
+package k3s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	controllerVersion   = "v0.14.2"
+	controllerNamespace = "system-upgrade"
+	controllerName      = "system-upgrade-controller"
+	fieldManager        = "homelab-bootstrap"
+	upgradeImage        = "rancher/k3s-upgrade"
+)
+
+// controllerManifests are the release assets installing the CRDs and the controller
+var controllerManifests = []string{"crd.yaml", "system-upgrade-controller.yaml"}
+
+// ensureController installs the system-upgrade-controller unless its deployment already exists
+func (u *Upgrader) ensureController(ctx context.Context) error {
+	_, err := u.k8sClient.GetClientset().AppsV1().Deployments(controllerNamespace).Get(ctx, controllerName, metav1.GetOptions{})
+	if err == nil {
+		log.Debug("system-upgrade-controller already installed")
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to check for the system-upgrade-controller: %w", err)
+	}
+
+	log.Info("Installing system-upgrade-controller", "version", controllerVersion)
+	for _, asset := range controllerManifests {
+		manifest, err := download(ctx, fmt.Sprintf("https://github.com/rancher/system-upgrade-controller/releases/download/%s/%s", controllerVersion, asset))
+		if err != nil {
+			return err
+		}
+		if err := u.apply(ctx, manifest); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", asset, err)
+		}
+	}
+	return u.k8sClient.WaitForDeployment(ctx, controllerNamespace, controllerName, 5*time.Minute)
+}
+
+// applyPlans hands the upgrade to the controller, servers first then agents once the servers are done
+func (u *Upgrader) applyPlans(ctx context.Context, version string) error {
+	upgrade := map[string]interface{}{"image": upgradeImage}
+	server := planSpec("k3s-server", "Exists", upgrade, version)
+	agent := planSpec("k3s-agent", "DoesNotExist", upgrade, version)
+	agent.Object["spec"].(map[string]interface{})["prepare"] = map[string]interface{}{
+		"image": upgradeImage,
+		"args":  []interface{}{"prepare", "k3s-server"},
+	}
+
+	for _, plan := range []*unstructured.Unstructured{server, agent} {
+		if err := u.applyObject(ctx, plan); err != nil {
+			return fmt.Errorf("failed to apply plan %s: %w", plan.GetName(), err)
+		}
+		log.Info("Applied upgrade plan", "plan", plan.GetName())
+	}
+	return nil
+}
+
+// planSpec builds a Plan for the nodes with or without the control-plane role
+func planSpec(name, controlPlane string, upgrade map[string]interface{}, version string) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"concurrency":        int64(1),
+		"cordon":             true,
+		"serviceAccountName": "system-upgrade",
+		"upgrade":            upgrade,
+		"version":            version,
+		"nodeSelector": map[string]interface{}{
+			"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "node-role.kubernetes.io/control-plane", "operator": controlPlane},
+			},
+		},
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "upgrade.cattle.io/v1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": name, "namespace": controllerNamespace},
+		"spec":       spec,
+	}}
+}
+
+// apply server-side applies every object of a multi-document manifest
+func (u *Upgrader) apply(ctx context.Context, manifest []byte) error {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	for {
+		var obj unstructured.Unstructured
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		if obj.Object == nil {
+			continue
+		}
+		if err := u.applyObject(ctx, &obj); err != nil {
+			return fmt.Errorf("failed to apply %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+}
+
+// applyObject server-side applies obj, retrying while freshly applied CRDs register
+func (u *Upgrader) applyObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	mapping, err := u.k8sClient.RESTMapping(ctx, obj.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+
+	resource := u.k8sClient.GetDynamicClient().Resource(mapping.Resource)
+	options := metav1.ApplyOptions{FieldManager: fieldManager, Force: true}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		_, err = resource.Namespace(obj.GetNamespace()).Apply(ctx, obj.GetName(), obj, options)
+	} else {
+		_, err = resource.Apply(ctx, obj.GetName(), obj, options)
+	}
+	if err == nil && k8s.IsCRD(obj) {
+		u.k8sClient.InvalidateDiscovery()
+	}
+	return err
+}
+
+// download fetches a release asset
+func download(ctx context.Context, url string) ([]byte, error) {
+	if err := airgap.GuardNetwork("download " + url); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,80 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/output"
+)
+
+// imagePattern matches the K3s image of the compose service, the tag is the running version
+var imagePattern = regexp.MustCompile(`(?m)^(\s*image:\s*["']?rancher/k3s:)([^\s"']+)`)
+
+// ComposeVersion returns the K3s image tag pinned in a compose file
+func ComposeVersion(composeFile string) (string, error) {
+	data, err := os.ReadFile(composeFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", composeFile, err)
+	}
+	match := imagePattern.FindSubmatch(data)
+	if match == nil {
+		return "", fmt.Errorf("no rancher/k3s image in %s", composeFile)
+	}
+	return string(match[2]), nil
+}
+
+// upgradeCompose pins the new image in the compose file and recreates the container on the Docker host
+func (u *Upgrader) upgradeCompose(ctx context.Context, opts UpgradeOptions, version string) error {
+	data, err := os.ReadFile(opts.ComposeFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", opts.ComposeFile, err)
+	}
+	updated := imagePattern.ReplaceAll(data, []byte("${1}"+ImageTag(version)))
+	if err := os.WriteFile(opts.ComposeFile, updated, 0o644); err != nil {
+		return fmt.Errorf("failed to update %s: %w", opts.ComposeFile, err)
+	}
+	log.Info("Pinned K3s image", "file", opts.ComposeFile, "tag", ImageTag(version))
+
+	for _, args := range [][]string{{"pull", "k3s"}, {"up", "-d", "k3s"}} {
+		if err := u.compose(ctx, opts, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compose runs docker compose against the NAS Docker host, like the infrastructure Taskfile
+func (u *Upgrader) compose(ctx context.Context, opts UpgradeOptions, args ...string) error {
+	return runCompose(composeCommand(ctx, opts.ComposeFile, opts.DockerHost, opts.CertPath, args...), args)
+}
+
+// composeCommand prepares docker compose for the compose file, pointed at the NAS Docker host
+func composeCommand(ctx context.Context, composeFile, dockerHost, certPath string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"compose", "-f", composeFile}, args...)...)
+	cmd.Dir = filepath.Dir(composeFile)
+	cmd.Env = os.Environ()
+	if dockerHost != "" {
+		cmd.Env = append(cmd.Env, "DOCKER_HOST="+dockerHost, "DOCKER_TLS_VERIFY=1")
+	}
+	if certPath != "" {
+		cmd.Env = append(cmd.Env, "DOCKER_CERT_PATH="+certPath)
+	}
+
+	outputMgr := output.GetManager()
+	cmd.Stdout = outputMgr.GetStdout()
+	cmd.Stderr = outputMgr.GetStderr()
+	return cmd
+}
+
+func runCompose(cmd *exec.Cmd, args []string) error {
+	log.Debug("Running docker compose", "args", args)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker compose %s failed: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// composeService runs the K3s server in the NAS compose file
+	composeService = "k3s"
+	// snapshotDir is inside the volume holding the K3s data, so restores survive the container
+	snapshotDir = "/var/lib/rancher/k3s/server/db/snapshots"
+	// serverConfig is the config file the compose service starts the server with
+	serverConfig = "/etc/rancher/config.yaml"
+)
+
+// saveScript snapshots into a scratch directory and streams the file, K3s names it after the node and time
+const saveScript = `set -e
+dir=$(mktemp -d)
+trap 'rm -rf "$dir"' EXIT
+k3s etcd-snapshot save --name bootstrap --dir "$dir" >&2
+cat "$dir"/bootstrap-*`
+
+// EtcdSnapshots takes and restores etcd snapshots of the K3s server run by the NAS compose file
+type EtcdSnapshots struct {
+	composeFile string
+	dockerHost  string
+	certPath    string
+}
+
+// NewEtcdSnapshots creates a snapshot source for the K3s server of composeFile on dockerHost
+func NewEtcdSnapshots(composeFile, dockerHost, certPath string) *EtcdSnapshots {
+	return &EtcdSnapshots{composeFile: composeFile, dockerHost: dockerHost, certPath: certPath}
+}
+
+// Save streams a k3s etcd-snapshot, which requires the server to run with the embedded etcd
+func (e *EtcdSnapshots) Save(ctx context.Context, w io.Writer) error {
+	args := []string{"exec", "-T", composeService, "sh", "-c", saveScript}
+	cmd := e.compose(ctx, args...)
+	cmd.Stdout = w
+	return runCompose(cmd, args)
+}
+
+// Restore copies the snapshot into the K3s data volume, stops the server, resets the cluster from the snapshot and starts it again
+func (e *EtcdSnapshots) Restore(ctx context.Context, snapshotPath string) error {
+	target := path.Join(snapshotDir, "restore-"+filepath.Base(snapshotPath))
+	steps := [][]string{
+		{"cp", snapshotPath, composeService + ":" + target},
+		{"stop", composeService},
+		{"run", "--rm", "--no-deps", composeService, "server", "--config", serverConfig,
+			"--cluster-reset", "--cluster-reset-restore-path=" + target},
+		{"up", "-d", composeService},
+	}
+	for _, args := range steps {
+		log.Info("Running docker compose "+args[0], "service", composeService)
+		if err := runCompose(e.compose(ctx, args...), args); err != nil {
+			if args[0] == "run" {
+				return fmt.Errorf("%w, the server is stopped: start it with docker compose up -d %s once fixed", err, composeService)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *EtcdSnapshots) compose(ctx context.Context, args ...string) *exec.Cmd {
+	return composeCommand(ctx, e.composeFile, e.dockerHost, e.certPath, args...)
+}
@@ -0,0 +1,140 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Upgrade methods
+const (
+	// MethodController hands the upgrade to the system-upgrade-controller running in the cluster
+	MethodController = "controller"
+	// MethodDocker recreates the K3s container of the NAS Docker host with the new image
+	MethodDocker = "docker"
+)
+
+const rejoinTimeout = 20 * time.Minute
+
+// UpgradeOptions selects the target K3s version and how to roll it out
+type UpgradeOptions struct {
+	Channel     string // Release channel resolved through update.k3s.io, e.g. stable or v1.34
+	Version     string // Exact version, e.g. v1.34.2+k3s1, takes precedence over Channel
+	Method      string // MethodController or MethodDocker
+	ComposeFile string // Compose file running the K3s container, for MethodDocker
+	DockerHost  string
+	CertPath    string
+}
+
+// Upgrader upgrades K3s and waits for every node to report the new version
+type Upgrader struct {
+	k8sClient *k8s.Client
+}
+
+// NewUpgrader creates an upgrader for the cluster behind k8sClient
+func NewUpgrader(k8sClient *k8s.Client) *Upgrader {
+	return &Upgrader{k8sClient: k8sClient}
+}
+
+// TargetVersion returns the version opts asks for, resolving the channel when no version is pinned
+func TargetVersion(ctx context.Context, opts UpgradeOptions) (string, error) {
+	if opts.Version != "" {
+		return opts.Version, nil
+	}
+	channel := opts.Channel
+	if channel == "" {
+		channel = "stable"
+	}
+	return ResolveChannel(ctx, channel)
+}
+
+// Upgrade rolls K3s out to version and returns once all nodes run it Ready, it refuses downgrades
+func (u *Upgrader) Upgrade(ctx context.Context, opts UpgradeOptions, version string) error {
+	current, err := u.currentVersion(ctx)
+	if err != nil {
+		return err
+	}
+	cmp, err := compareVersions(version, current)
+	if err != nil {
+		return err
+	}
+	if cmp == 0 {
+		log.Info("K3s already at the target version", "version", current)
+		return nil
+	}
+	if cmp < 0 {
+		return fmt.Errorf("K3s %s is older than the running %s, downgrades are not supported", version, current)
+	}
+	if err := k8s.GuardMutation("upgrade K3s"); err != nil {
+		return err
+	}
+
+	log.Info("Upgrading K3s", "from", current, "to", version, "method", opts.Method)
+	switch opts.Method {
+	case MethodController:
+		if err := u.ensureController(ctx); err != nil {
+			return err
+		}
+		// A pinned version keeps the plan on what was validated, even if the channel moves meanwhile
+		if err := u.applyPlans(ctx, version); err != nil {
+			return err
+		}
+	case MethodDocker:
+		if err := u.upgradeCompose(ctx, opts, version); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown upgrade method %q, expected %s or %s", opts.Method, MethodController, MethodDocker)
+	}
+	return u.waitForVersion(ctx, version)
+}
+
+// currentVersion returns the oldest kubelet version among the nodes
+func (u *Upgrader) currentVersion(ctx context.Context) (string, error) {
+	nodes, err := u.k8sClient.GetClientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(nodes.Items) == 0 {
+		return "", fmt.Errorf("no node in the cluster")
+	}
+	oldest := nodes.Items[0].Status.NodeInfo.KubeletVersion
+	for _, node := range nodes.Items[1:] {
+		version := node.Status.NodeInfo.KubeletVersion
+		if cmp, err := compareVersions(version, oldest); err == nil && cmp < 0 {
+			oldest = version
+		}
+	}
+	return oldest, nil
+}
+
+// waitForVersion polls through the API server restart until every node is Ready on version
+func (u *Upgrader) waitForVersion(ctx context.Context, version string) error {
+	log.Info("Waiting for nodes to run the new version", "version", version)
+	var pending []string
+	err := k8s.PollAfter(ctx, 10*time.Second, rejoinTimeout, func(ctx context.Context) (bool, error) {
+		nodes, err := u.k8sClient.GetClientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Debug("API server unavailable during the upgrade", "error", err)
+			return false, nil
+		}
+		pending = pending[:0]
+		for i := range nodes.Items {
+			node := &nodes.Items[i]
+			cmp, err := compareVersions(node.Status.NodeInfo.KubeletVersion, version)
+			if err != nil || cmp != 0 || !k8s.NodeReady(node) {
+				pending = append(pending, node.Name)
+			}
+		}
+		return len(pending) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("nodes %v did not come back Ready on %s: %w", pending, version, err)
+	}
+	log.Info("✅ All nodes run K3s", "version", version)
+	return nil
+}
@@ -0,0 +1,83 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// channelServer redirects each channel to the GitHub release it currently points to
+const channelServer = "https://update.k3s.io/v1-release/channels"
+
+// ChannelURL returns the channel server URL of a release channel such as stable, latest or v1.34
+func ChannelURL(channel string) string {
+	return channelServer + "/" + channel
+}
+
+// ResolveChannel returns the K3s version a release channel currently points to
+func ResolveChannel(ctx context.Context, channel string) (string, error) {
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ChannelURL(channel), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve K3s channel %s: %w", channel, err)
+	}
+	defer resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+		return "", fmt.Errorf("failed to resolve K3s channel %s: GET %s: %s", channel, ChannelURL(channel), resp.Status)
+	}
+	target, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect for K3s channel %s: %w", channel, err)
+	}
+	version, err := url.PathUnescape(path.Base(target.Path))
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect for K3s channel %s: %w", channel, err)
+	}
+	if _, err := semver.ParseTolerant(version); err != nil {
+		return "", fmt.Errorf("K3s channel %s points to %s, which is not a version", channel, version)
+	}
+	return version, nil
+}
+
+// ImageTag converts a K3s version to its rancher/k3s image tag, which cannot hold a '+'
+func ImageTag(version string) string {
+	return strings.ReplaceAll(version, "+", "-")
+}
+
+// compareVersions orders two K3s versions, including their k3sN build suffix
+func compareVersions(a, b string) (int, error) {
+	left, err := semver.ParseTolerant(strings.ReplaceAll(a, "-k3s", "+k3s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid K3s version %s: %w", a, err)
+	}
+	right, err := semver.ParseTolerant(strings.ReplaceAll(b, "-k3s", "+k3s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid K3s version %s: %w", b, err)
+	}
+	if cmp := left.Compare(right); cmp != 0 {
+		return cmp, nil
+	}
+	// semver ignores build metadata, k3s2 still supersedes k3s1
+	leftBuild, rightBuild := strings.Join(left.Build, "."), strings.Join(right.Build, ".")
+	if len(leftBuild) != len(rightBuild) {
+		return len(leftBuild) - len(rightBuild), nil
+	}
+	return strings.Compare(leftBuild, rightBuild), nil
+}
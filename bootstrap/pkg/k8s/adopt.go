@@ -0,0 +1,142 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/csaupgrade"
+)
+
+// AdoptManagers are the field managers of the tools an existing installation was applied with: kubectl
+// client-side and server-side apply, kubectl create, the flux CLI, and objects older than managed fields
+var AdoptManagers = []string{"kubectl-client-side-apply", "kubectl", "kubectl-create", "flux", "before-first-apply"}
+
+// AdoptResult lists what adopting a set found
+type AdoptResult struct {
+	// Adopted objects exist and are recorded in the set, Transferred ones had fields of AdoptManagers moved to FieldManager
+	Adopted     []ObjectRef `json:"adopted,omitempty"`
+	Transferred []ObjectRef `json:"transferred,omitempty"`
+	// Missing objects are rendered but do not exist, the next apply of the set creates them
+	Missing []ObjectRef `json:"missing,omitempty"`
+}
+
+// AdoptSet records the existing objects of objs in the named set without applying them, and hands the fields
+// AdoptManagers own to FieldManager so the next apply of the set updates and prunes them like its own.
+// Live values stay untouched. A dry run only reports what it would adopt.
+func (c *Client) AdoptSet(ctx context.Context, set string, objs []*unstructured.Unstructured, dryRun bool) (*AdoptResult, error) {
+	result := &AdoptResult{}
+	for _, obj := range objs {
+		ref := ObjectRefOf(obj)
+		mapping, err := c.RESTMapper().RESTMapping(obj.GroupVersionKind().GroupKind(), obj.GroupVersionKind().Version)
+		if meta.IsNoMatchError(err) {
+			// The kind is not served, so neither is the object
+			result.Missing = append(result.Missing, ref)
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to map %s to a resource: %w", ref, err)
+		}
+		var resource dynamic.ResourceInterface = c.dynamicClient.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resource = c.dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+		}
+		live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			result.Missing = append(result.Missing, ref)
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read %s: %w", ref, err)
+		}
+		result.Adopted = append(result.Adopted, ref)
+
+		patch, err := adoptManagedFieldsPatch(live)
+		if err != nil {
+			return result, fmt.Errorf("failed to transfer the fields of %s: %w", ref, err)
+		}
+		if patch == nil {
+			continue
+		}
+		result.Transferred = append(result.Transferred, ref)
+		if dryRun {
+			continue
+		}
+		if _, err := resource.Patch(ctx, obj.GetName(), types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+			return result, fmt.Errorf("failed to transfer the fields of %s: %w", ref, err)
+		}
+		log.Debug("Transferred field ownership", "object", ref, "manager", FieldManager)
+	}
+
+	if !dryRun && len(result.Adopted) > 0 {
+		previous, err := c.Inventory(ctx, set)
+		if err != nil {
+			return result, fmt.Errorf("failed to read the apply inventory: %w", err)
+		}
+		if err := c.saveInventory(ctx, set, mergeRefs(previous, result.Adopted)); err != nil {
+			return result, fmt.Errorf("failed to record the apply inventory: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// adoptManagedFieldsPatch returns the JSON patch handing the fields of AdoptManagers to FieldManager, nil when
+// there is none. The first server-side apply manager among them is renamed, then the client-side ones are
+// merged into it.
+func adoptManagedFieldsPatch(live *unstructured.Unstructured) ([]byte, error) {
+	// Every call decodes the entries anew, so editing managed leaves original intact
+	original := live.GetManagedFields()
+	managed := live.GetManagedFields()
+
+	owned := slices.ContainsFunc(managed, func(entry metav1.ManagedFieldsEntry) bool {
+		return entry.Manager == FieldManager && entry.Operation == metav1.ManagedFieldsOperationApply && entry.Subresource == ""
+	})
+	for i, entry := range managed {
+		if owned {
+			break
+		}
+		if entry.Operation == metav1.ManagedFieldsOperationApply && entry.Subresource == "" && slices.Contains(AdoptManagers, entry.Manager) {
+			managed[i].Manager = FieldManager
+			owned = true
+		}
+	}
+
+	adopted := live.DeepCopy()
+	adopted.SetManagedFields(managed)
+	if err := csaupgrade.UpgradeManagedFields(adopted, sets.New(AdoptManagers...), FieldManager); err != nil {
+		return nil, err
+	}
+	if sameManagedFields(original, adopted.GetManagedFields()) {
+		return nil, nil
+	}
+
+	// Replacing the resource version makes the patch fail on a concurrent change instead of losing it
+	return json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/metadata/managedFields", "value": adopted.GetManagedFields()},
+		{"op": "replace", "path": "/metadata/resourceVersion", "value": live.GetResourceVersion()},
+	})
+}
+
+// sameManagedFields compares two managed fields lists by manager, operation and owned fields
+func sameManagedFields(a, b []metav1.ManagedFieldsEntry) bool {
+	return slices.EqualFunc(a, b, func(x, y metav1.ManagedFieldsEntry) bool {
+		return x.Manager == y.Manager && x.Operation == y.Operation && x.APIVersion == y.APIVersion &&
+			x.Subresource == y.Subresource && string(fieldsRaw(x.FieldsV1)) == string(fieldsRaw(y.FieldsV1))
+	})
+}
+
+func fieldsRaw(fields *metav1.FieldsV1) []byte {
+	if fields == nil {
+		return nil
+	}
+	return fields.Raw
+}
@@ -0,0 +1,93 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// FieldManager owns every field the bootstrap writes, through server-side apply and plain updates alike
+const FieldManager = "homelab-bootstrap"
+
+// RESTMapper returns the discovery-backed mapper shared by every caller of this client, so applying dozens
+// of manifests queries discovery once. It is refreshed by InvalidateDiscovery or when a kind is missing.
+func (c *Client) RESTMapper() meta.ResettableRESTMapper {
+	c.mapperOnce.Do(func() {
+		c.mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(c.clientset.Discovery()))
+	})
+	return c.mapper
+}
+
+// InvalidateDiscovery drops the cached API discovery, to call once CRDs were installed or removed
+func (c *Client) InvalidateDiscovery() {
+	c.RESTMapper().Reset()
+}
+
+// RESTMapping maps gvk with the shared mapper, refreshing discovery while freshly applied CRDs register
+func (c *Client) RESTMapping(ctx context.Context, gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapper := c.RESTMapper()
+	var mapping *meta.RESTMapping
+	err := Poll(ctx, 2*time.Second, 30*time.Second, func(ctx context.Context) (bool, error) {
+		var err error
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			log.Debug("Kind not served yet, refreshing discovery", "gvk", gvk)
+			mapper.Reset()
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to map %s to a resource: %w", gvk, err)
+	}
+	return mapping, nil
+}
+
+// ResourceFor returns the dynamic client of a kind, retrying while freshly applied CRDs register
+func (c *Client) ResourceFor(ctx context.Context, gvk schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error) {
+	mapping, err := c.RESTMapping(ctx, gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := c.dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return resource.Namespace(namespace), nil
+	}
+	return resource, nil
+}
+
+// Apply server-side applies obj as FieldManager, taking over conflicting fields; a dry run returns the merged object without persisting it
+func (c *Client) Apply(ctx context.Context, obj *unstructured.Unstructured, dryRun bool) (*unstructured.Unstructured, error) {
+	resource, err := c.ResourceFor(ctx, obj.GroupVersionKind(), obj.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	options := metav1.ApplyOptions{FieldManager: FieldManager, Force: true}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	applied, err := resource.Apply(ctx, obj.GetName(), obj, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	if !dryRun && IsCRD(obj) {
+		c.InvalidateDiscovery()
+	}
+	return applied, nil
+}
+
+// IsCRD reports whether obj is a CustomResourceDefinition, whose apply changes what discovery serves
+func IsCRD(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group == "apiextensions.k8s.io" && gvk.Kind == "CustomResourceDefinition"
+}
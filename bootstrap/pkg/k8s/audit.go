@@ -0,0 +1,205 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/audit"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// AuditConfig records every mutating request of clients built from cfg to the audit trail, tagged with cluster.
+// Call it after GuardConfig so requests refused in read-only mode are recorded as blocked.
+func AuditConfig(cfg *rest.Config, cluster string) {
+	if cluster == "" {
+		cluster = cfg.Host
+	}
+	// The mirror writes the trail itself, so it goes through the read-only guard but is not audited
+	mirror := rest.CopyConfig(cfg)
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &auditTransport{next: rt, cluster: cluster, mirror: mirror}
+	})
+}
+
+type auditTransport struct {
+	next    http.RoundTripper
+	cluster string
+	mirror  *rest.Config
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if allowedReadOnly(req) || isStreaming(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	entry := requestEntry(req)
+	entry.Cluster = t.cluster
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case errors.Is(err, ErrReadOnly):
+		entry.Outcome = audit.OutcomeBlocked
+		entry.Error = err.Error()
+	case err != nil:
+		entry.Outcome = audit.OutcomeFailed
+		entry.Error = err.Error()
+	case resp.StatusCode >= http.StatusBadRequest:
+		entry.Outcome = audit.OutcomeFailed
+		entry.Code = resp.StatusCode
+	default:
+		entry.Outcome = audit.OutcomeSucceeded
+		entry.Code = resp.StatusCode
+	}
+
+	audit.RegisterSink(t.cluster, func(ctx context.Context, entries []audit.Entry) error {
+		clientset, err := kubernetes.NewForConfig(t.mirror)
+		if err != nil {
+			return err
+		}
+		return audit.WriteConfigMap(ctx, clientset, entries)
+	})
+	audit.Record(req.Context(), entry)
+	return resp, err
+}
+
+// isStreaming reports whether req runs a command in or tunnels into a pod, which the trail leaves out
+func isStreaming(req *http.Request) bool {
+	for _, suffix := range streamingSubresources {
+		if strings.HasSuffix(req.URL.Path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestEntry describes the object a mutating request changes, from its path and body
+func requestEntry(req *http.Request) audit.Entry {
+	entry := audit.Entry{FieldManager: req.URL.Query().Get("fieldManager")}
+	if entry.FieldManager == "" {
+		entry.FieldManager = managerFromUserAgent(req.Header.Get("User-Agent"))
+	}
+	entry.As = req.Header.Get("Impersonate-User")
+
+	// /api/v1/... or /apis/<group>/<version>/..., then [namespaces/<ns>/]<resource>[/<name>[/<subresource>]]
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		entry.Version, parts = parts[1], parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		entry.Group, entry.Version, parts = parts[1], parts[2], parts[3:]
+	default:
+		entry.Resource = req.URL.Path
+		parts = nil
+	}
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		entry.Namespace, parts = parts[1], parts[2:]
+	}
+	if len(parts) > 0 {
+		entry.Resource = parts[0]
+	}
+	if len(parts) > 1 {
+		entry.Name = parts[1]
+	}
+	if len(parts) > 2 {
+		entry.Subresource = strings.Join(parts[2:], "/")
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		entry.Verb = audit.VerbCreate
+	case http.MethodPut:
+		entry.Verb = audit.VerbUpdate
+	case http.MethodPatch:
+		entry.Verb = audit.VerbPatch
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/apply-patch") {
+			entry.Verb = audit.VerbApply
+		}
+	case http.MethodDelete:
+		entry.Verb = audit.VerbDelete
+		if entry.Name == "" {
+			entry.Verb = audit.VerbDeleteCollection
+		}
+	default:
+		entry.Verb = strings.ToLower(req.Method)
+	}
+
+	body := requestBody(req)
+	if len(body) == 0 {
+		return entry
+	}
+	sum := sha256.Sum256(body)
+	entry.DiffHash = hex.EncodeToString(sum[:])[:12]
+
+	name, generateName := "", ""
+	if object, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(body, nil, nil); err == nil {
+		// Built-in types are sent as protobuf, custom resources and apply patches as JSON
+		entry.Kind = gvk.Kind
+		if accessor, err := meta.Accessor(object); err == nil {
+			name, generateName = accessor.GetName(), accessor.GetGenerateName()
+		}
+	} else {
+		var object struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name         string `json:"name"`
+				GenerateName string `json:"generateName"`
+			} `json:"metadata"`
+		}
+		if json.Unmarshal(body, &object) == nil {
+			entry.Kind = object.Kind
+			name, generateName = object.Metadata.Name, object.Metadata.GenerateName
+		}
+	}
+	if entry.Kind == "DeleteOptions" {
+		entry.Kind = ""
+	}
+	if entry.Name == "" && entry.Subresource == "" {
+		entry.Name = name
+		if name == "" && generateName != "" {
+			entry.Name = generateName + "*"
+		}
+	}
+	return entry
+}
+
+// requestBody returns the body of req without consuming it
+func requestBody(req *http.Request) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		return data
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// recordExternal records a mutation made outside the API clients
+func recordExternal(action string, err error) {
+	entry := audit.Entry{Verb: audit.VerbExternal, Name: action, Outcome: audit.OutcomeStarted}
+	if err != nil {
+		entry.Outcome = audit.OutcomeBlocked
+		entry.Error = err.Error()
+	}
+	audit.Record(context.Background(), entry)
+}
@@ -2,17 +2,21 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
@@ -24,6 +28,8 @@ type Client struct {
 	config        *rest.Config
 	kubeconfig    string
     contextName  string
+	mapperOnce    sync.Once
+	mapper        *restmapper.DeferredDiscoveryRESTMapper
 }
 
 // NewClient creates a new Kubernetes client
@@ -58,6 +64,9 @@ func NewClientWithContext(kubeconfig, context string) (*Client, error) {
 			return nil, fmt.Errorf("failed to build kubeconfig: %w", err)
 		}
 	}
+	ConfigureClient(config)
+	GuardConfig(config)
+	AuditConfig(config, context)
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -105,7 +114,7 @@ func (c *Client) IsReady(ctx context.Context) error {
 
 // WaitForReady waits for the Kubernetes API server to be ready
 func (c *Client) WaitForReady(ctx context.Context, timeout time.Duration) error {
-	return wait.PollImmediate(5*time.Second, timeout, func() (bool, error) {
+	return Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
 		if err := c.IsReady(ctx); err != nil {
 			return false, nil // Keep trying
 		}
@@ -151,12 +160,9 @@ func (c *Client) CreateNamespace(ctx context.Context, name string) error {
 
 // WaitForNamespace waits for a namespace to exist and be ready
 func (c *Client) WaitForNamespace(ctx context.Context, name string, timeout time.Duration) error {
-	return wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
-		exists, err := c.NamespaceExists(ctx, name)
-		if err != nil {
-			return false, err
-		}
-		return exists, nil
+	gvr := corev1.SchemeGroupVersion.WithResource("namespaces")
+	return c.WaitForObject(ctx, gvr, "", name, timeout, func(*unstructured.Unstructured) (bool, error) {
+		return true, nil
 	})
 }
 
@@ -175,57 +181,74 @@ func (c *Client) GetNodes(ctx context.Context) ([]string, error) {
 	return nodeNames, nil
 }
 
+// FilesystemStats is the usage of a node filesystem as reported by the kubelet
+type FilesystemStats struct {
+	AvailableBytes uint64 `json:"availableBytes"`
+	CapacityBytes  uint64 `json:"capacityBytes"`
+}
+
+// NodeFilesystems returns the root and image filesystem usage of node from the kubelet summary API
+func (c *Client) NodeFilesystems(ctx context.Context, node string) (root, images FilesystemStats, err error) {
+	data, err := c.clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").
+		DoRaw(ctx)
+	if err != nil {
+		return root, images, fmt.Errorf("failed to read kubelet stats of %s: %w", node, err)
+	}
+	var summary struct {
+		Node struct {
+			Fs      FilesystemStats `json:"fs"`
+			Runtime struct {
+				ImageFs FilesystemStats `json:"imageFs"`
+			} `json:"runtime"`
+		} `json:"node"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return root, images, fmt.Errorf("failed to decode kubelet stats of %s: %w", node, err)
+	}
+	return summary.Node.Fs, summary.Node.Runtime.ImageFs, nil
+}
+
 // WaitForNodes waits for the specified number of nodes to be ready
 func (c *Client) WaitForNodes(ctx context.Context, expectedCount int, timeout time.Duration) error {
-	return wait.PollImmediate(10*time.Second, timeout, func() (bool, error) {
-		nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return false, nil // Keep trying
-		}
-
-		readyNodes := 0
-		for _, node := range nodes.Items {
-			for _, condition := range node.Status.Conditions {
-				if condition.Type == "Ready" && condition.Status == "True" {
-					readyNodes++
-					break
-				}
+	return c.watchNodes(ctx, expectedCount, timeout, func(node *corev1.Node) bool {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady {
+				return condition.Status == corev1.ConditionTrue
 			}
 		}
-
-		return readyNodes >= expectedCount, nil
+		return false
 	})
 }
 
 // WaitForDeployment waits for a deployment to be ready
 func (c *Client) WaitForDeployment(ctx context.Context, namespace, name string, timeout time.Duration) error {
-	return wait.PollImmediate(5*time.Second, timeout, func() (bool, error) {
-		deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				return false, nil // Keep waiting
-			}
-			return false, err
-		}
-
+	return c.watchDeployment(ctx, namespace, name, timeout, func(deployment *appsv1.Deployment) bool {
 		return deployment.Status.ReadyReplicas == deployment.Status.Replicas &&
-			deployment.Status.ReadyReplicas > 0, nil
+			deployment.Status.ReadyReplicas > 0
 	})
 }
 
-// WaitForDaemonSet waits for a daemonset to be ready
-func (c *Client) WaitForDaemonSet(ctx context.Context, namespace, name string, timeout time.Duration) error {
-	return wait.PollImmediate(5*time.Second, timeout, func() (bool, error) {
-		daemonset, err := c.clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				return false, nil // Keep waiting
-			}
-			return false, err
+// WaitForRollout waits for the latest spec of a deployment to be rolled out, like kubectl rollout status:
+// every replica updated and available, and no pod of an older revision left
+func (c *Client) WaitForRollout(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	return c.watchDeployment(ctx, namespace, name, timeout, func(deployment *appsv1.Deployment) bool {
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
 		}
+		return deployment.Status.ObservedGeneration >= deployment.Generation &&
+			deployment.Status.UpdatedReplicas == replicas &&
+			deployment.Status.Replicas == replicas &&
+			deployment.Status.AvailableReplicas == replicas
+	})
+}
 
+// WaitForDaemonSet waits for a daemonset to be ready
+func (c *Client) WaitForDaemonSet(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	return c.watchDaemonSet(ctx, namespace, name, timeout, func(daemonset *appsv1.DaemonSet) bool {
 		return daemonset.Status.NumberReady == daemonset.Status.DesiredNumberScheduled &&
-			daemonset.Status.NumberReady > 0, nil
+			daemonset.Status.NumberReady > 0
 	})
 }
 
@@ -248,31 +271,16 @@ func (c *Client) GetPods(ctx context.Context, namespace string, labelSelector st
 
 // WaitForPods waits for pods matching a label selector to be ready
 func (c *Client) WaitForPods(ctx context.Context, namespace, labelSelector string, expectedCount int, timeout time.Duration) error {
-	return wait.PollImmediate(5*time.Second, timeout, func() (bool, error) {
-		pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-			LabelSelector: labelSelector,
-		})
-		if err != nil {
-			return false, nil // Keep trying
+	return c.watchPods(ctx, namespace, labelSelector, expectedCount, timeout, func(pod *corev1.Pod) bool {
+		if pod.Status.Phase != corev1.PodRunning {
+			return false
 		}
-
-		readyPods := 0
-		for _, pod := range pods.Items {
-			if pod.Status.Phase == "Running" {
-				allContainersReady := true
-				for _, condition := range pod.Status.Conditions {
-					if condition.Type == "Ready" && condition.Status != "True" {
-						allContainersReady = false
-						break
-					}
-				}
-				if allContainersReady {
-					readyPods++
-				}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
+				return false
 			}
 		}
-
-		return readyPods >= expectedCount, nil
+		return true
 	})
 }
 
@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NodeByAddress returns the node publishing address as one of its addresses
+func (c *Client) NodeByAddress(ctx context.Context, address string) (*corev1.Node, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for i := range nodes.Items {
+		for _, addr := range nodes.Items[i].Status.Addresses {
+			if addr.Address == address {
+				return &nodes.Items[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no node with address %s", address)
+}
+
+// NodeReady reports whether the node has a Ready=True condition
+func NodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// SetUnschedulable cordons or uncordons a node
+func (c *Client) SetUnschedulable(ctx context.Context, name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	if _, err := c.clientset.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to set unschedulable=%t on node %s: %w", unschedulable, name, err)
+	}
+	return nil
+}
+
+// DrainNode evicts every pod of the node except DaemonSet and static pods, honoring PodDisruptionBudgets
+func (c *Client) DrainNode(ctx context.Context, name string, timeout time.Duration) error {
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+
+	var evicted []corev1.Pod
+	for _, pod := range pods.Items {
+		if !evictable(pod) {
+			continue
+		}
+		evicted = append(evicted, pod)
+	}
+	log.Info("Draining node", "node", name, "pods", len(evicted))
+
+	return Poll(ctx, 5*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		remaining := 0
+		for _, pod := range evicted {
+			current, err := c.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+				continue
+			}
+			remaining++
+			eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+			if err := c.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction); err != nil && !apierrors.IsNotFound(err) {
+				// 429 means a PodDisruptionBudget blocks the eviction for now
+				if !apierrors.IsTooManyRequests(err) {
+					return false, fmt.Errorf("failed to evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+				}
+				log.Debug("Eviction blocked by disruption budget", "pod", pod.Namespace+"/"+pod.Name)
+			}
+		}
+		return remaining == 0, nil
+	})
+}
+
+// evictable skips pods a drain leaves in place
+func evictable(pod corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, mirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; mirror {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// eventRewatchDelay is the pause before reopening a watch the API server closed
+const eventRewatchDelay = 2 * time.Second
+
+// WatchWarningEvents calls handler for each Warning event raised in namespaces after since, until ctx is done.
+// An empty namespace watches all namespaces. Repeats of an event are reported once per new occurrence.
+func (c *Client) WatchWarningEvents(ctx context.Context, namespaces []string, since time.Time, handler func(*corev1.Event)) {
+	seen := &eventDeduper{counts: map[string]int32{}}
+	for _, namespace := range namespaces {
+		go c.watchWarningEvents(ctx, namespace, since, seen, handler)
+	}
+}
+
+func (c *Client) watchWarningEvents(ctx context.Context, namespace string, since time.Time, seen *eventDeduper, handler func(*corev1.Event)) {
+	events := c.clientset.CoreV1().Events(namespace)
+	for ctx.Err() == nil {
+		watcher, err := events.Watch(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+		if err != nil {
+			log.Debug("Failed to watch events", "namespace", namespace, "error", err)
+		} else {
+			for result := range watcher.ResultChan() {
+				if result.Type != watch.Added && result.Type != watch.Modified {
+					continue
+				}
+				event, ok := result.Object.(*corev1.Event)
+				if !ok || eventTime(event).Before(since) || !seen.first(event) {
+					continue
+				}
+				handler(event)
+			}
+			watcher.Stop()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventRewatchDelay):
+		}
+	}
+}
+
+// FormatEvent renders an event as a single log line
+func FormatEvent(event *corev1.Event) string {
+	object := strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name
+	message := strings.Join(strings.Fields(event.Message), " ")
+	if event.Count > 1 {
+		return fmt.Sprintf("%s %s/%s: %s (x%d)", event.Reason, event.InvolvedObject.Namespace, object, message, event.Count)
+	}
+	return fmt.Sprintf("%s %s/%s: %s", event.Reason, event.InvolvedObject.Namespace, object, message)
+}
+
+// eventTime returns when the event last occurred
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// eventDeduper drops the watch replays of occurrences already reported
+type eventDeduper struct {
+	mu     sync.Mutex
+	counts map[string]int32
+}
+
+func (d *eventDeduper) first(event *corev1.Event) bool {
+	count := event.Count
+	if event.Series != nil {
+		count = event.Series.Count
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := string(event.UID)
+	if previous, ok := d.counts[key]; ok && previous >= count {
+		return false
+	}
+	d.counts[key] = count
+	return true
+}
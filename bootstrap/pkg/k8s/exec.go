@@ -0,0 +1,38 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Exec runs command in container of pod and returns its standard output, a failed command returns
+// an error holding its standard error
+func (c *Client) Exec(ctx context.Context, namespace, pod, container string, command []string) (string, error) {
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
+	if err != nil {
+		return "", fmt.Errorf("failed to exec into %s/%s: %w", namespace, pod, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return stdout.String(), fmt.Errorf("%s failed in %s/%s: %w: %s", command[0], namespace, pod, err, message)
+		}
+		return stdout.String(), fmt.Errorf("%s failed in %s/%s: %w", command[0], namespace, pod, err)
+	}
+	return stdout.String(), nil
+}
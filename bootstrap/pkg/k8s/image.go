@@ -0,0 +1,12 @@
+package k8s
+
+import "strings"
+
+// ImageTag returns the tag of a container image reference, ignoring any digest, "latest" when it has none
+func ImageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if idx := strings.LastIndex(image, ":"); idx >= 0 && !strings.Contains(image[idx:], "/") {
+		return image[idx+1:]
+	}
+	return "latest"
+}
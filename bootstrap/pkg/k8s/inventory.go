@@ -0,0 +1,229 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// InventoryNamespace and InventoryName locate the ConfigMap recording what every apply set created
+	InventoryNamespace = "kube-system"
+	InventoryName      = "homelab-bootstrap-inventory"
+)
+
+// ObjectRef identifies an applied object
+type ObjectRef struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+}
+
+// ObjectRefOf returns the reference of obj
+func ObjectRefOf(obj *unstructured.Unstructured) ObjectRef {
+	gvk := obj.GroupVersionKind()
+	return ObjectRef{Namespace: obj.GetNamespace(), Name: obj.GetName(), Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}
+}
+
+// ID is the version-less identity of the object, <namespace>_<name>_<group>_<kind> like a Flux inventory entry
+func (r ObjectRef) ID() string {
+	return strings.Join([]string{r.Namespace, r.Name, r.Group, r.Kind}, "_")
+}
+
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Kind + " " + r.Name
+	}
+	return r.Kind + " " + r.Namespace + "/" + r.Name
+}
+
+// parseObjectRef reads an inventory line, "<id> <version>"
+func parseObjectRef(line string) (ObjectRef, bool) {
+	id, version, ok := strings.Cut(strings.TrimSpace(line), " ")
+	parts := strings.Split(id, "_")
+	if !ok || len(parts) != 4 {
+		return ObjectRef{}, false
+	}
+	return ObjectRef{Namespace: parts[0], Name: parts[1], Group: parts[2], Kind: parts[3], Version: version}, true
+}
+
+// ApplySetResult lists what an apply set changed
+type ApplySetResult struct {
+	Applied []ObjectRef `json:"applied"`
+	Pruned  []ObjectRef `json:"pruned,omitempty"`
+}
+
+// ApplySet server-side applies objs as the named set and records them in the inventory. Objects the set
+// applied on a previous run but no longer contains are pruned, as long as homelab-bootstrap still manages
+// them. CRDs and namespaces are left in place since deleting them takes everything they hold along.
+func (c *Client) ApplySet(ctx context.Context, set string, objs []*unstructured.Unstructured) (*ApplySetResult, error) {
+	previous, err := c.Inventory(ctx, set)
+	if err != nil {
+		log.Warn("Failed to read the apply inventory, nothing will be pruned", "set", set, "error", err)
+	}
+
+	result := &ApplySetResult{}
+	applied := map[string]bool{}
+	for _, obj := range objs {
+		if _, err := c.Apply(ctx, obj, false); err != nil {
+			// Remember everything this set may own so the next run can still prune it
+			c.recordInventory(ctx, set, mergeRefs(previous, result.Applied))
+			return result, err
+		}
+		ref := ObjectRefOf(obj)
+		result.Applied = append(result.Applied, ref)
+		applied[ref.ID()] = true
+	}
+
+	kept := append([]ObjectRef(nil), result.Applied...)
+	for _, ref := range previous {
+		if applied[ref.ID()] {
+			continue
+		}
+		pruned, err := c.prune(ctx, ref)
+		switch {
+		case err != nil:
+			log.Warn("Failed to prune object removed from the manifests", "set", set, "object", ref, "error", err)
+			kept = append(kept, ref)
+		case pruned:
+			log.Info("Pruned object removed from the manifests", "set", set, "object", ref)
+			result.Pruned = append(result.Pruned, ref)
+		}
+	}
+	c.recordInventory(ctx, set, kept)
+	return result, nil
+}
+
+// prune deletes an object dropped from its set, reporting false when it is gone already or was left alone
+func (c *Client) prune(ctx context.Context, ref ObjectRef) (bool, error) {
+	if ref.Kind == "Namespace" || (ref.Group == "apiextensions.k8s.io" && ref.Kind == "CustomResourceDefinition") {
+		log.Warn("Leaving object removed from the manifests in place, delete it by hand once unused", "object", ref)
+		return false, nil
+	}
+
+	mapping, err := c.RESTMapper().RESTMapping(schema.GroupKind{Group: ref.Group, Kind: ref.Kind}, ref.Version)
+	if meta.IsNoMatchError(err) {
+		// The kind is not served anymore, neither is the object
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var resource dynamic.ResourceInterface = c.dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = c.dynamicClient.Resource(mapping.Resource).Namespace(ref.Namespace)
+	}
+
+	obj, err := resource.Get(ctx, ref.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !managedByBootstrap(obj) {
+		log.Info("Object removed from the manifests is managed by someone else now, leaving it", "object", ref)
+		return false, nil
+	}
+
+	background := metav1.DeletePropagationBackground
+	options := metav1.DeleteOptions{PropagationPolicy: &background}
+	if err := resource.Delete(ctx, ref.Name, options); err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+func managedByBootstrap(obj *unstructured.Unstructured) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == FieldManager {
+			return true
+		}
+	}
+	return false
+}
+
+// Inventory returns the objects the named set applied last
+func (c *Client) Inventory(ctx context.Context, set string) ([]ObjectRef, error) {
+	cm, err := c.clientset.CoreV1().ConfigMaps(InventoryNamespace).Get(ctx, InventoryName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var refs []ObjectRef
+	for _, line := range strings.Split(cm.Data[set], "\n") {
+		if ref, ok := parseObjectRef(line); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// recordInventory stores refs as the content of the named set, logging failures since the apply succeeded
+func (c *Client) recordInventory(ctx context.Context, set string, refs []ObjectRef) {
+	if err := c.saveInventory(ctx, set, refs); err != nil {
+		log.Warn("Failed to record the apply inventory, removed objects will not be pruned", "set", set, "error", err)
+	}
+}
+
+func (c *Client) saveInventory(ctx context.Context, set string, refs []ObjectRef) error {
+	lines := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		lines = append(lines, ref.ID()+" "+ref.Version)
+	}
+	sort.Strings(lines)
+
+	configMaps := c.clientset.CoreV1().ConfigMaps(InventoryNamespace)
+	cm, err := configMaps.Get(ctx, InventoryName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      InventoryName,
+			Namespace: InventoryNamespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": FieldManager},
+		}}
+		cm.Data = map[string]string{set: strings.Join(lines, "\n")}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[set] = strings.Join(lines, "\n")
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// mergeRefs returns the refs of both lists, once each
+func mergeRefs(a, b []ObjectRef) []ObjectRef {
+	seen := map[string]bool{}
+	var merged []ObjectRef
+	for _, ref := range append(append([]ObjectRef(nil), b...), a...) {
+		if !seen[ref.ID()] {
+			seen[ref.ID()] = true
+			merged = append(merged, ref)
+		}
+	}
+	return merged
+}
+
+// String describes the result for logs
+func (r *ApplySetResult) String() string {
+	return fmt.Sprintf("%d applied, %d pruned", len(r.Applied), len(r.Pruned))
+}
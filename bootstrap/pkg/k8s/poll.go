@@ -0,0 +1,41 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Poll checks condition right away, then every interval until it is done or fails. Like the watch-based
+// waits, running out of time returns ErrWaitTimeout and a cancelled ctx returns its own error.
+func Poll(ctx context.Context, interval, timeout time.Duration, condition wait.ConditionWithContextFunc) error {
+	return poll(ctx, interval, timeout, true, condition)
+}
+
+// PollAfter is Poll waiting one interval before the first check, for conditions only meaningful once
+// the action they follow had time to start, such as a node going down for a reboot
+func PollAfter(ctx context.Context, interval, timeout time.Duration, condition wait.ConditionWithContextFunc) error {
+	return poll(ctx, interval, timeout, false, condition)
+}
+
+func poll(ctx context.Context, interval, timeout time.Duration, immediate bool, condition wait.ConditionWithContextFunc) error {
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if err := wait.PollUntilContextCancel(waitCtx, interval, immediate, condition); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if waitCtx.Err() != nil && wait.Interrupted(err) {
+			return fmt.Errorf("%w after %s", ErrWaitTimeout, timeout)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForward tunnels a free local port to port of a ready pod behind service, until stop is called
+func (c *Client) PortForward(ctx context.Context, namespace, service string, port int) (int, func(), error) {
+	pod, targetPort, err := c.serviceBackend(ctx, namespace, service, port)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create port-forward transport: %w", err)
+	}
+	url := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	forwarder, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", targetPort)}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to forward to %s/%s: %w", namespace, pod, err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- forwarder.ForwardPorts() }()
+
+	stop := func() { close(stopCh) }
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return 0, nil, fmt.Errorf("failed to forward to %s/%s: %w", namespace, pod, err)
+	case <-ctx.Done():
+		stop()
+		return 0, nil, ctx.Err()
+	}
+
+	ports, err := forwarder.GetPorts()
+	if err != nil || len(ports) == 0 {
+		stop()
+		return 0, nil, fmt.Errorf("failed to read forwarded port: %w", err)
+	}
+	return int(ports[0].Local), stop, nil
+}
+
+// serviceBackend picks a ready pod selected by service and the container port its port targets
+func (c *Client) serviceBackend(ctx context.Context, namespace, service string, port int) (string, int, error) {
+	svc, err := c.clientset.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get service %s/%s: %w", namespace, service, err)
+	}
+	var servicePort *corev1.ServicePort
+	for i := range svc.Spec.Ports {
+		if int(svc.Spec.Ports[i].Port) == port {
+			servicePort = &svc.Spec.Ports[i]
+		}
+	}
+	if servicePort == nil {
+		return "", 0, fmt.Errorf("service %s/%s has no port %d", namespace, service, port)
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to list pods of service %s/%s: %w", namespace, service, err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || !podReady(&pod) {
+			continue
+		}
+		if servicePort.TargetPort.IntValue() != 0 {
+			return pod.Name, servicePort.TargetPort.IntValue(), nil
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, containerPort := range container.Ports {
+				if containerPort.Name == servicePort.TargetPort.String() {
+					return pod.Name, int(containerPort.ContainerPort), nil
+				}
+			}
+		}
+		return pod.Name, port, nil
+	}
+	return "", 0, fmt.Errorf("no ready pod behind service %s/%s", namespace, service)
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"k8s.io/client-go/rest"
+)
+
+// ErrReadOnly is returned for any mutation attempted while read-only mode is enabled
+var ErrReadOnly = errors.New("refusing to modify cluster state in read-only mode")
+
+var readOnly atomic.Bool
+
+// SetReadOnly enables or disables read-only mode for every client built afterwards and every guarded action
+func SetReadOnly(enabled bool) {
+	readOnly.Store(enabled)
+}
+
+// ReadOnly reports whether read-only mode is enabled
+func ReadOnly() bool {
+	return readOnly.Load()
+}
+
+// GuardMutation fails when read-only mode is enabled, for mutations made outside the API clients (helm, kubectl, task).
+// The action is recorded to the audit trail either way.
+func GuardMutation(action string) error {
+	var err error
+	if ReadOnly() {
+		err = fmt.Errorf("%s: %w", action, ErrReadOnly)
+	}
+	recordExternal(action, err)
+	return err
+}
+
+// GuardConfig makes clients built from cfg reject mutating requests while read-only mode is enabled
+func GuardConfig(cfg *rest.Config) {
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &readOnlyTransport{next: rt}
+	})
+}
+
+// reviewResources are created by read-only callers to ask the API server a question, they persist nothing
+var reviewResources = []string{
+	"/selfsubjectaccessreviews",
+	"/selfsubjectrulesreviews",
+	"/subjectaccessreviews",
+	"/tokenreviews",
+}
+
+// streamingSubresources run commands in or tunnel into pods whatever the HTTP verb
+var streamingSubresources = []string{"/exec", "/attach", "/portforward"}
+
+type readOnlyTransport struct {
+	next http.RoundTripper
+}
+
+func (t *readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if ReadOnly() && !allowedReadOnly(req) {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrReadOnly)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// allowedReadOnly reports whether a request cannot change cluster state
+func allowedReadOnly(req *http.Request) bool {
+	path := req.URL.Path
+	for _, suffix := range streamingSubresources {
+		if strings.HasSuffix(path, suffix) {
+			return false
+		}
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	// Server-side dry-run validates a mutation without persisting it
+	for _, value := range req.URL.Query()["dryRun"] {
+		if value == "All" {
+			return true
+		}
+	}
+	if req.Method == http.MethodPost {
+		for _, suffix := range reviewResources {
+			if strings.HasSuffix(path, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
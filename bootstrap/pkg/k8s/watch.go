@@ -0,0 +1,200 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// ErrWaitTimeout is returned when a watch-based wait runs out of time
+var ErrWaitTimeout = errors.New("timed out waiting for the condition")
+
+// WaitForObject watches a single object until check accepts it, a missing object keeps the wait going
+func (c *Client) WaitForObject(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string, timeout time.Duration, check func(*unstructured.Unstructured) (bool, error)) error {
+	resource := c.dynamicClient.Resource(gvr).Namespace(namespace)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector(name)
+			return resource.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector(name)
+			return resource.Watch(ctx, options)
+		},
+	}
+	return watchUntil(ctx, timeout, lw, &unstructured.Unstructured{}, func(event watch.Event) (bool, error) {
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok || event.Type == watch.Deleted {
+			return false, nil
+		}
+		return check(obj)
+	})
+}
+
+// WaitForService watches a service until check accepts it, a missing service keeps the wait going
+func (c *Client) WaitForService(ctx context.Context, namespace, name string, timeout time.Duration, check func(*corev1.Service) (bool, error)) error {
+	services := c.clientset.CoreV1().Services(namespace)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector(name)
+			return services.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector(name)
+			return services.Watch(ctx, options)
+		},
+	}
+	return watchUntil(ctx, timeout, lw, &corev1.Service{}, func(event watch.Event) (bool, error) {
+		svc, ok := event.Object.(*corev1.Service)
+		if !ok || event.Type == watch.Deleted {
+			return false, nil
+		}
+		return check(svc)
+	})
+}
+
+// watchDeployment watches a deployment until check accepts it
+func (c *Client) watchDeployment(ctx context.Context, namespace, name string, timeout time.Duration, check func(*appsv1.Deployment) bool) error {
+	deployments := c.clientset.AppsV1().Deployments(namespace)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector(name)
+			return deployments.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector(name)
+			return deployments.Watch(ctx, options)
+		},
+	}
+	return watchUntil(ctx, timeout, lw, &appsv1.Deployment{}, func(event watch.Event) (bool, error) {
+		deployment, ok := event.Object.(*appsv1.Deployment)
+		return ok && event.Type != watch.Deleted && check(deployment), nil
+	})
+}
+
+// watchDaemonSet watches a daemonset until check accepts it
+func (c *Client) watchDaemonSet(ctx context.Context, namespace, name string, timeout time.Duration, check func(*appsv1.DaemonSet) bool) error {
+	daemonsets := c.clientset.AppsV1().DaemonSets(namespace)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = nameSelector(name)
+			return daemonsets.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = nameSelector(name)
+			return daemonsets.Watch(ctx, options)
+		},
+	}
+	return watchUntil(ctx, timeout, lw, &appsv1.DaemonSet{}, func(event watch.Event) (bool, error) {
+		daemonset, ok := event.Object.(*appsv1.DaemonSet)
+		return ok && event.Type != watch.Deleted && check(daemonset), nil
+	})
+}
+
+// watchSet tracks which of the watched objects satisfy a predicate, for waits on a count of objects
+type watchSet map[string]bool
+
+// update records the object of event and returns how many objects satisfy the predicate
+func (s watchSet) update(event watch.Event, predicate func(runtime.Object) bool) int {
+	if meta, ok := event.Object.(metav1.Object); ok {
+		key := meta.GetNamespace() + "/" + meta.GetName()
+		if event.Type == watch.Deleted {
+			delete(s, key)
+		} else {
+			s[key] = predicate(event.Object)
+		}
+	}
+	count := 0
+	for _, matched := range s {
+		if matched {
+			count++
+		}
+	}
+	return count
+}
+
+// watchNodes watches every node until count of them satisfy ready
+func (c *Client) watchNodes(ctx context.Context, count int, timeout time.Duration, ready func(*corev1.Node) bool) error {
+	if count <= 0 {
+		return nil
+	}
+	nodes := c.clientset.CoreV1().Nodes()
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			return nodes.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return nodes.Watch(ctx, options)
+		},
+	}
+	set := watchSet{}
+	return watchUntil(ctx, timeout, lw, &corev1.Node{}, func(event watch.Event) (bool, error) {
+		return set.update(event, func(obj runtime.Object) bool {
+			node, ok := obj.(*corev1.Node)
+			return ok && ready(node)
+		}) >= count, nil
+	})
+}
+
+// watchPods watches the pods matching labelSelector until count of them satisfy ready
+func (c *Client) watchPods(ctx context.Context, namespace, labelSelector string, count int, timeout time.Duration, ready func(*corev1.Pod) bool) error {
+	if count <= 0 {
+		return nil
+	}
+	pods := c.clientset.CoreV1().Pods(namespace)
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			return pods.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			return pods.Watch(ctx, options)
+		},
+	}
+	set := watchSet{}
+	return watchUntil(ctx, timeout, lw, &corev1.Pod{}, func(event watch.Event) (bool, error) {
+		return set.update(event, func(obj runtime.Object) bool {
+			pod, ok := obj.(*corev1.Pod)
+			return ok && ready(pod)
+		}) >= count, nil
+	})
+}
+
+// watchUntil runs an informer over lw until condition holds, the timeout elapses or ctx is cancelled.
+// The informer lists first, so objects already in the awaited state return immediately
+func watchUntil(ctx context.Context, timeout time.Duration, lw cache.ListerWatcher, objType runtime.Object, condition watchtools.ConditionFunc) error {
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if _, err := watchtools.UntilWithSync(waitCtx, lw, objType, nil, condition); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if waitCtx.Err() != nil {
+			return fmt.Errorf("%w after %s", ErrWaitTimeout, timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+func nameSelector(name string) string {
+	return fields.OneTermEqualSelector("metadata.name", name).String()
+}
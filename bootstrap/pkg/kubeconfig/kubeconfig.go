@@ -0,0 +1,140 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// FileName is the kubeconfig each infrastructure/<cluster> directory gets once its cluster is up
+const FileName = "kubeconfig.yaml"
+
+// Source is the kubeconfig file of one cluster of the project
+type Source struct {
+	Cluster string `json:"cluster"`
+	Path    string `json:"path"`
+}
+
+// MergeResult tells what a merge changed in the target kubeconfig
+type MergeResult struct {
+	Path     string   `json:"path"`
+	Backup   string   `json:"backup,omitempty"`
+	Added    []string `json:"added,omitempty"`
+	Replaced []string `json:"replaced,omitempty"`
+}
+
+// Discover lists the infrastructure/<cluster>/kubeconfig.yaml files of the project, by cluster name
+func Discover(projectRoot string) ([]Source, error) {
+	matches, err := filepath.Glob(filepath.Join(projectRoot, "infrastructure", "*", FileName))
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]Source, 0, len(matches))
+	for _, match := range matches {
+		sources = append(sources, Source{Cluster: filepath.Base(filepath.Dir(match)), Path: match})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Cluster < sources[j].Cluster })
+	return sources, nil
+}
+
+// Export combines the current context of each source into one kubeconfig. Every cluster gets a context,
+// cluster and user named after it, so the K3s and Talos defaults never collide, and its certificate files
+// are embedded so the result works from any directory. The first source is the current context.
+func Export(sources []Source) (*clientcmdapi.Config, error) {
+	exported := clientcmdapi.NewConfig()
+	for _, source := range sources {
+		config, err := clientcmd.LoadFromFile(source.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig of %s: %w", source.Cluster, err)
+		}
+		if err := clientcmdapi.FlattenConfig(config); err != nil {
+			return nil, fmt.Errorf("failed to embed the certificates of %s: %w", source.Cluster, err)
+		}
+
+		contextName := config.CurrentContext
+		if contextName == "" && len(config.Contexts) == 1 {
+			for name := range config.Contexts {
+				contextName = name
+			}
+		}
+		current, ok := config.Contexts[contextName]
+		if !ok {
+			return nil, fmt.Errorf("kubeconfig of %s has no current context", source.Cluster)
+		}
+		cluster, ok := config.Clusters[current.Cluster]
+		if !ok {
+			return nil, fmt.Errorf("kubeconfig of %s has no cluster %s", source.Cluster, current.Cluster)
+		}
+		user, ok := config.AuthInfos[current.AuthInfo]
+		if !ok {
+			return nil, fmt.Errorf("kubeconfig of %s has no user %s", source.Cluster, current.AuthInfo)
+		}
+
+		exported.Clusters[source.Cluster] = cluster
+		exported.AuthInfos[source.Cluster] = user
+		exported.Contexts[source.Cluster] = &clientcmdapi.Context{
+			Cluster:   source.Cluster,
+			AuthInfo:  source.Cluster,
+			Namespace: current.Namespace,
+		}
+		if exported.CurrentContext == "" {
+			exported.CurrentContext = source.Cluster
+		}
+	}
+	return exported, nil
+}
+
+// Merge writes the contexts, clusters and users of exported into the kubeconfig at path, replacing the
+// entries of the same name. An existing file is copied to a timestamped backup first and keeps its
+// current context.
+func Merge(path string, exported *clientcmdapi.Config) (*MergeResult, error) {
+	result := &MergeResult{Path: path}
+	target := clientcmdapi.NewConfig()
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	default:
+		if target, err = clientcmd.Load(data); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		result.Backup = path + ".bak." + time.Now().Format("20060102-150405")
+		if err := os.WriteFile(result.Backup, data, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+
+	names := make([]string, 0, len(exported.Contexts))
+	for name := range exported.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		context := exported.Contexts[name]
+		if _, exists := target.Contexts[name]; exists {
+			result.Replaced = append(result.Replaced, name)
+		} else {
+			result.Added = append(result.Added, name)
+		}
+		target.Contexts[name] = context
+		target.Clusters[context.Cluster] = exported.Clusters[context.Cluster]
+		target.AuthInfos[context.AuthInfo] = exported.AuthInfos[context.AuthInfo]
+	}
+	if target.CurrentContext == "" {
+		target.CurrentContext = exported.CurrentContext
+	}
+
+	if err := clientcmd.WriteToFile(*target, path); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return result, nil
+}
@@ -9,12 +9,8 @@ import (
 
 // SetupLogger configures a beautiful logger for the application
 func SetupLogger() {
-	// Create a styled logger
-	logger := log.NewWithOptions(os.Stderr, log.Options{
-		ReportCaller:    false,
-		ReportTimestamp: true,
-		Level:           log.InfoLevel,
-	})
+	// Create a styled logger, secrets are redacted before they reach the terminal
+	logger := newRedactingLogger(os.Stderr, log.InfoLevel)
 
 	// Set as default logger
 	log.SetDefault(logger)
@@ -49,6 +45,11 @@ func SetupTUILogger(logFile io.Writer) {
 		Level:           level,
 	})
 
-	// Set as default logger
-	log.SetDefault(logger)
+	// A run log keeps recording the run, only its human output moves to the file
+	if setConsole(logger) {
+		return
+	}
+
+	// Set as default logger, redacting like the terminal one
+	log.SetDefault(newRedactingLogger(logFile, level))
 }
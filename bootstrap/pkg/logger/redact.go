@@ -0,0 +1,212 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/log"
+)
+
+// Redacted replaces the value of a sensitive key
+const Redacted = "[REDACTED]"
+
+var (
+	// sensitiveSegments end the key names whose values are secrets. key and secret alone name things
+	// ("key", GITHUB_TOKEN) so they only count after another segment, as in access_key or client_secret.
+	sensitiveSegments = map[string]bool{"token": true, "password": true, "passwd": true, "b64": true, "credentials": true}
+	qualifiedSegments = map[string]bool{"key": true, "secret": true}
+
+	yamlLine   = regexp.MustCompile(`^(\s*(?:-\s+)?)([A-Za-z0-9_.-]+):(\s+)(\S.*)$`)
+	envLine    = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z0-9_]+)=(.+)$`)
+	jsonPair   = regexp.MustCompile(`"([A-Za-z0-9_.-]+)"(\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+	dataBlock  = regexp.MustCompile(`^(\s*)(data|stringData):\s*$`)
+	secretKind = regexp.MustCompile(`(?m)^kind:\s*["']?Secret["']?\s*$`)
+	hashed     = regexp.MustCompile(`^\[sha256:[0-9a-f]{12}\]$`)
+)
+
+// SensitiveKey reports whether values logged or written under name are secrets
+func SensitiveKey(name string) bool {
+	segments := keySegments(name)
+	if len(segments) == 0 {
+		return false
+	}
+	last := segments[len(segments)-1]
+	return sensitiveSegments[last] || (qualifiedSegments[last] && len(segments) > 1)
+}
+
+// keySegments splits a key name on separators and camel case, lowercased
+func keySegments(name string) []string {
+	var segments []string
+	var current []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			if len(current) > 0 {
+				segments = append(segments, string(current))
+			}
+			current = nil
+			continue
+		case unicode.IsUpper(r) && len(current) > 0 && i > 0 && unicode.IsLower(runes[i-1]):
+			segments = append(segments, string(current))
+			current = nil
+		}
+		current = append(current, unicode.ToLower(r))
+	}
+	if len(current) > 0 {
+		segments = append(segments, string(current))
+	}
+	return segments
+}
+
+// Redact masks value when key is sensitive and scrubs the secrets embedded in it otherwise
+func Redact(key string, value interface{}) interface{} {
+	if SensitiveKey(key) && !emptyValue(value) {
+		return Redacted
+	}
+	switch v := value.(type) {
+	case string:
+		return RedactText(v)
+	case map[string]interface{}:
+		for k, inner := range v {
+			v[k] = Redact(k, inner)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = Redact("", inner)
+		}
+	}
+	return value
+}
+
+func emptyValue(value interface{}) bool {
+	s, ok := value.(string)
+	return value == nil || (ok && s == "")
+}
+
+// RedactText masks the sensitive values of YAML, env and JSON lines in text, and replaces the data of
+// Secret manifests with hashes so changes stay visible without exposing values
+func RedactText(text string) string {
+	if !strings.ContainsAny(text, ":=") {
+		return text
+	}
+	docs := strings.Split(text, "\n---")
+	for i, doc := range docs {
+		docs[i] = redactDocument(doc, secretKind.MatchString(doc))
+	}
+	return strings.Join(docs, "\n---")
+}
+
+func redactDocument(doc string, secret bool) string {
+	lines := strings.Split(doc, "\n")
+	blockIndent := -1
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if blockIndent >= 0 && strings.TrimSpace(line) != "" && indent <= blockIndent {
+			blockIndent = -1
+		}
+		if secret {
+			if m := dataBlock.FindStringSubmatch(line); m != nil {
+				blockIndent = len(m[1])
+				continue
+			}
+		}
+
+		if m := yamlLine.FindStringSubmatch(line); m != nil {
+			value := m[4]
+			switch {
+			case blockIndent >= 0:
+				lines[i] = m[1] + m[2] + ":" + m[3] + hashValue(value)
+			case SensitiveKey(m[2]) && value != Redacted && !hashed.MatchString(value):
+				lines[i] = m[1] + m[2] + ":" + m[3] + Redacted
+			}
+			continue
+		}
+		if m := envLine.FindStringSubmatch(line); m != nil && SensitiveKey(m[2]) {
+			lines[i] = m[1] + m[2] + "=" + Redacted
+			continue
+		}
+		lines[i] = jsonPair.ReplaceAllStringFunc(line, func(pair string) string {
+			m := jsonPair.FindStringSubmatch(pair)
+			if !SensitiveKey(m[1]) || m[3] == "" {
+				return pair
+			}
+			return `"` + m[1] + `"` + m[2] + `"` + Redacted + `"`
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hashValue replaces a secret value with a short digest, leaving values already hashed alone
+func hashValue(value string) string {
+	trimmed := strings.Trim(value, `"'`)
+	if hashed.MatchString(value) || value == Redacted || trimmed == "" {
+		return value
+	}
+	sum := sha256.Sum256([]byte(trimmed))
+	return "[sha256:" + hex.EncodeToString(sum[:])[:12] + "]"
+}
+
+// redactEntry scrubs the message and field values of a parsed log line
+func redactEntry(entry *Entry) {
+	entry.Message = RedactText(entry.Message)
+	for i := 0; i+1 < len(entry.Fields); i += 2 {
+		key, _ := entry.Fields[i].(string)
+		entry.Fields[i+1] = Redact(key, entry.Fields[i+1])
+	}
+}
+
+// redactor renders the JSON lines of the default logger on a console logger once scrubbed,
+// so no sink sees a secret value
+type redactor struct {
+	mu      sync.Mutex
+	console *log.Logger
+	partial []byte
+}
+
+// newRedactingLogger returns a logger filtering everything it logs before out sees it. The returned
+// logger holds the level, the console renders whatever reaches it.
+func newRedactingLogger(out io.Writer, level log.Level) *log.Logger {
+	console := log.NewWithOptions(out, log.Options{
+		ReportTimestamp: true,
+		Level:           log.DebugLevel,
+	})
+	return log.NewWithOptions(&redactor{console: console}, log.Options{
+		Formatter:       log.JSONFormatter,
+		ReportTimestamp: true,
+		TimeFormat:      time.RFC3339Nano,
+		Level:           level,
+	})
+}
+
+// Write parses the JSON lines of the logger, redacts them and renders them on the console
+func (r *redactor) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.partial = append(r.partial, p...)
+	for {
+		idx := bytes.IndexByte(r.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		line := r.partial[:idx]
+		r.partial = r.partial[idx+1:]
+
+		entry, err := ParseEntry(line)
+		if err != nil {
+			r.console.Print(RedactText(string(line)))
+			continue
+		}
+		redactEntry(&entry)
+		r.console.Log(entry.Level, entry.Message, entry.Fields...)
+	}
+	return len(p), nil
+}
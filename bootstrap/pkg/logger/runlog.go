@@ -0,0 +1,381 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// RunLogDir is where per-run JSON-lines logs are written, relative to the project root
+	RunLogDir = ".bootstrap/logs"
+	// MaxRunLogs is how many run logs are kept, older ones are pruned when a run starts
+	MaxRunLogs = 20
+
+	runLogExt = ".jsonl"
+)
+
+// Entry is one line of a run log
+type Entry struct {
+	Time    time.Time
+	Level   log.Level
+	Message string
+	// Fields are the key/value pairs in the order they were logged, after the run, cluster and step tags
+	Fields []interface{}
+}
+
+// RunLog mirrors every log line of a run into a JSON-lines file, redacted and tagged with the run, cluster and current step
+type RunLog struct {
+	path    string
+	run     string
+	cluster string
+
+	mu      sync.Mutex
+	file    *os.File
+	console *log.Logger
+	step    string
+	partial []byte
+}
+
+var (
+	activeMu sync.Mutex
+	active   *RunLog
+)
+
+// StartRunLog opens .bootstrap/logs/<timestamp>-<run>.jsonl under projectRoot and routes the default logger through it.
+// Console output keeps its format and level, the file records every level down to debug.
+func StartRunLog(projectRoot, run, cluster string) (*RunLog, error) {
+	dir := filepath.Join(projectRoot, RunLogDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := PruneRunLogs(dir, MaxRunLogs-1); err != nil {
+		log.Debug("Failed to prune run logs", "error", err)
+	}
+
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102T150405Z")+"-"+run+runLogExt)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run log: %w", err)
+	}
+
+	r := &RunLog{path: path, run: run, cluster: cluster, file: file, console: log.Default()}
+	log.SetDefault(log.NewWithOptions(r, log.Options{
+		Formatter:       log.JSONFormatter,
+		ReportTimestamp: true,
+		TimeFormat:      time.RFC3339Nano,
+		Level:           log.DebugLevel,
+	}))
+
+	activeMu.Lock()
+	active = r
+	activeMu.Unlock()
+	return r, nil
+}
+
+// Path returns the file the run log is written to
+func (r *RunLog) Path() string {
+	return r.path
+}
+
+// Close restores the console logger and closes the file
+func (r *RunLog) Close() error {
+	activeMu.Lock()
+	if active == r {
+		active = nil
+	}
+	activeMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log.SetDefault(r.console)
+	return r.file.Close()
+}
+
+// SetStep tags the following lines of the active run log with step, empty once the step is over
+func SetStep(step string) {
+	activeMu.Lock()
+	r := active
+	activeMu.Unlock()
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.step = step
+	r.mu.Unlock()
+}
+
+// setConsole swaps where the active run log renders lines for humans, reporting whether a run log is active
+func setConsole(logger *log.Logger) bool {
+	activeMu.Lock()
+	r := active
+	activeMu.Unlock()
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	r.console = logger
+	r.mu.Unlock()
+	return true
+}
+
+// Write receives JSON lines from the default logger, stores them tagged and renders them on the console
+func (r *RunLog) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.partial = append(r.partial, p...)
+	for {
+		idx := bytes.IndexByte(r.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		line := r.partial[:idx]
+		r.partial = r.partial[idx+1:]
+
+		entry, err := ParseEntry(line)
+		if err != nil {
+			// Not something the JSON formatter produced, keep it scrubbed but otherwise verbatim
+			_, _ = r.file.Write(append([]byte(RedactText(string(line))), '\n'))
+			continue
+		}
+		redactEntry(&entry)
+		console := entry.Fields
+		// Lines logging their own run, cluster or step keep them, the tags only fill the gaps
+		var tags []interface{}
+		for _, tag := range [][2]string{{"run", r.run}, {"cluster", r.cluster}, {"step", r.step}} {
+			if _, ok := entry.Value(tag[0]); tag[1] != "" && !ok {
+				tags = append(tags, tag[0], tag[1])
+			}
+		}
+		if _, ok := entry.Value("step"); r.step != "" && !ok {
+			console = append([]interface{}{"step", r.step}, console...)
+		}
+		entry.Fields = append(tags, entry.Fields...)
+		if data, err := entry.MarshalJSON(); err == nil {
+			_, _ = r.file.Write(append(data, '\n'))
+		}
+		r.console.Log(entry.Level, entry.Message, console...)
+	}
+	return len(p), nil
+}
+
+// Value returns the value logged under key
+func (e Entry) Value(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		if e.Fields[i] == key {
+			return e.Fields[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// Render writes the entry to logger the way it was shown on the console, without the run tag
+func (e Entry) Render(logger *log.Logger) {
+	fields := make([]interface{}, 0, len(e.Fields))
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		if e.Fields[i] != "run" {
+			fields = append(fields, e.Fields[i], e.Fields[i+1])
+		}
+	}
+	logger.Log(e.Level, e.Message, fields...)
+}
+
+// MarshalJSON encodes the entry as one object, time, level and msg first then the fields in their order
+func (e Entry) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	write := func(key string, value interface{}) error {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(value))
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+		return nil
+	}
+
+	standard := []struct {
+		key   string
+		value interface{}
+		skip  bool
+	}{
+		{"time", e.Time.Format(time.RFC3339Nano), e.Time.IsZero()},
+		{"level", e.Level.String(), false},
+		{"msg", e.Message, false},
+	}
+	for _, field := range standard {
+		if field.skip {
+			continue
+		}
+		if err := write(field.key, field.value); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		if err := write(fmt.Sprint(e.Fields[i]), e.Fields[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// ParseEntry decodes a run log line, keeping the order of its fields
+func ParseEntry(line []byte) (Entry, error) {
+	entry := Entry{Level: log.InfoLevel}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return entry, fmt.Errorf("not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return entry, err
+		}
+		key, _ := tok.(string)
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return entry, err
+		}
+		text, _ := value.(string)
+		switch key {
+		case "time":
+			entry.Time, _ = time.Parse(time.RFC3339Nano, text)
+		case "level":
+			if level, err := log.ParseLevel(text); err == nil {
+				entry.Level = level
+			}
+		case "msg":
+			entry.Message = text
+		default:
+			entry.Fields = append(entry.Fields, key, value)
+		}
+	}
+	return entry, nil
+}
+
+// RunLogFile is a run log on disk
+type RunLogFile struct {
+	Path    string
+	Run     string
+	Started time.Time
+}
+
+// ListRunLogs returns the run logs of dir, oldest first
+func ListRunLogs(dir string) ([]RunLogFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+runLogExt))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]RunLogFile, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), runLogExt)
+		stamp, run, _ := strings.Cut(name, "-")
+		started, err := time.Parse("20060102T150405Z", stamp)
+		if err != nil {
+			continue
+		}
+		files = append(files, RunLogFile{Path: path, Run: run, Started: started})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].Started.Equal(files[j].Started) {
+			return files[i].Started.Before(files[j].Started)
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// FindRunLog returns the log of run, "last" or empty meaning the most recent one
+func FindRunLog(dir, run string) (RunLogFile, error) {
+	files, err := ListRunLogs(dir)
+	if err != nil {
+		return RunLogFile{}, err
+	}
+	if len(files) == 0 {
+		return RunLogFile{}, fmt.Errorf("no run logs in %s", dir)
+	}
+	if run == "" || run == "last" {
+		return files[len(files)-1], nil
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		if files[i].Run == run || strings.HasPrefix(files[i].Run, run) || filepath.Base(files[i].Path) == run {
+			return files[i], nil
+		}
+	}
+	return RunLogFile{}, fmt.Errorf("no log for run %s", run)
+}
+
+// PruneRunLogs deletes the oldest run logs of dir until at most keep remain
+func PruneRunLogs(dir string, keep int) error {
+	files, err := ListRunLogs(dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > max(keep, 0) {
+		if err := os.Remove(files[0].Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// ReadRunLog calls fn for every entry of the log at path. With follow it keeps waiting for new lines until ctx ends.
+func ReadRunLog(ctx context.Context, path string, follow bool, fn func(Entry, []byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var pending []byte
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		pending = append(pending, chunk...)
+		if err == nil {
+			line := bytes.TrimSpace(pending)
+			pending = pending[:0]
+			if len(line) == 0 {
+				continue
+			}
+			if entry, parseErr := ParseEntry(line); parseErr == nil {
+				fn(entry, line)
+			}
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		if !follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package logging
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+)
+
+const (
+	// Namespace is where the log collector runs
+	Namespace = "logging"
+
+	// DefaultLokiEndpoint is the push API of the NAS Loki, reached across the mesh
+	DefaultLokiEndpoint = "http://loki-gateway.loki.svc.cluster.local/loki/api/v1/push"
+)
+
+// Supported log collectors
+const (
+	CollectorAlloy     = "alloy"
+	CollectorFluentBit = "fluent-bit"
+)
+
+var collectorCharts = map[string]gitops.HelmChart{
+	CollectorAlloy: {
+		Repository: "grafana-alloy",
+		URL:        "https://grafana.github.io/helm-charts",
+		Chart:      "alloy",
+		Version:    "1.2.1",
+		Namespace:  Namespace,
+	},
+	CollectorFluentBit: {
+		Repository: "fluent",
+		URL:        "https://fluent.github.io/helm-charts",
+		Chart:      "fluent-bit",
+		Version:    "0.50.0",
+		Namespace:  Namespace,
+	},
+}
+
+// Options configures the generated log collector
+type Options struct {
+	Collector    string
+	LokiEndpoint string
+}
+
+// Generator writes the log collector into the GitOps repository
+type Generator struct {
+	projectRoot string
+	cluster     string
+	opts        Options
+}
+
+// NewGenerator creates a new generator for cluster under projectRoot
+func NewGenerator(projectRoot, cluster string, opts Options) *Generator {
+	if opts.Collector == "" {
+		opts.Collector = CollectorAlloy
+	}
+	if opts.LokiEndpoint == "" {
+		opts.LokiEndpoint = DefaultLokiEndpoint
+	}
+	return &Generator{
+		projectRoot: projectRoot,
+		cluster:     cluster,
+		opts:        opts,
+	}
+}
+
+// Generate writes the collector manifests and returns the paths it wrote, relative to the project root
+func (g *Generator) Generate() ([]string, error) {
+	chart, ok := collectorCharts[g.opts.Collector]
+	if !ok {
+		return nil, fmt.Errorf("unknown log collector %q (expected alloy or fluent-bit)", g.opts.Collector)
+	}
+	endpoint, err := url.Parse(g.opts.LokiEndpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid Loki endpoint %q", g.opts.LokiEndpoint)
+	}
+
+	clusterDir := filepath.Join(g.projectRoot, "kubernetes", g.cluster)
+	if _, err := os.Stat(clusterDir); err != nil {
+		return nil, fmt.Errorf("GitOps directory for %s not found: %w", g.cluster, err)
+	}
+
+	loggingDir := filepath.Join(clusterDir, "logging")
+	var values string
+	switch g.opts.Collector {
+	case CollectorFluentBit:
+		values = g.fluentBitValues(endpoint)
+	default:
+		values = g.alloyValues(endpoint)
+	}
+
+	files := map[string]string{
+		filepath.Join(loggingDir, "namespace.yaml"):       renderNamespace(),
+		filepath.Join(loggingDir, "helm-repository.yaml"): chart.RenderRepository(),
+		filepath.Join(loggingDir, "helm-release.yaml"):    chart.RenderRelease(values),
+		filepath.Join(loggingDir, "kustomization.yaml"):   gitops.RenderKustomization([]string{"namespace.yaml", "helm-repository.yaml", "helm-release.yaml"}),
+		filepath.Join(clusterDir, "logging.yaml"): gitops.RenderKustomizations(g.cluster, []gitops.Kustomization{{
+			Name:      gitops.Name(g.cluster, "logging"),
+			Path:      "logging",
+			DependsOn: gitops.FoundationKustomization(g.cluster),
+		}}),
+	}
+
+	// Switching collectors leaves no stale manifests behind
+	if err := os.RemoveAll(loggingDir); err != nil {
+		return nil, fmt.Errorf("failed to clean %s: %w", loggingDir, err)
+	}
+
+	written, err := gitops.WriteFiles(g.projectRoot, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rootKustomization := filepath.Join(clusterDir, "kustomization.yaml")
+	added, err := gitops.AddResource(rootKustomization, "logging.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		written = append(written, gitops.Relative(g.projectRoot, rootKustomization))
+	}
+
+	for _, legacy := range []string{"promtail", "fluent-bit", "alloy"} {
+		if legacy != chart.Chart && gitops.ChartInstalled(clusterDir, legacy, loggingDir) {
+			log.Warn("Hand-maintained log collector still deployed, remove it to avoid shipping logs twice",
+				"chart", legacy,
+				"cluster", g.cluster)
+		}
+	}
+
+	log.Info("Generated log shipping",
+		"cluster", g.cluster,
+		"collector", g.opts.Collector,
+		"loki", g.opts.LokiEndpoint,
+		"files", len(written))
+
+	return written, nil
+}
+
+// alloyValues configures Alloy to tail the pods of its node and push them to Loki
+func (g *Generator) alloyValues(endpoint *url.URL) string {
+	config := fmt.Sprintf(`discovery.kubernetes "pods" {
+  role = "pod"
+  selectors {
+    role  = "pod"
+    field = "spec.nodeName=" + sys.env("HOSTNAME")
+  }
+}
+
+discovery.relabel "pods" {
+  targets = discovery.kubernetes.pods.targets
+  rule {
+    source_labels = ["__meta_kubernetes_namespace"]
+    target_label  = "namespace"
+  }
+  rule {
+    source_labels = ["__meta_kubernetes_pod_name"]
+    target_label  = "pod"
+  }
+  rule {
+    source_labels = ["__meta_kubernetes_pod_container_name"]
+    target_label  = "container"
+  }
+  rule {
+    source_labels = ["__meta_kubernetes_pod_label_app_kubernetes_io_name", "__meta_kubernetes_pod_label_app"]
+    regex         = "^;*([^;]+)(;.*)?$"
+    target_label  = "app"
+  }
+  rule {
+    source_labels = ["__meta_kubernetes_pod_node_name"]
+    target_label  = "node_name"
+  }
+}
+
+loki.source.kubernetes "pods" {
+  targets    = discovery.relabel.pods.output
+  forward_to = [loki.write.nas.receiver]
+}
+
+loki.write "nas" {
+  endpoint {
+    url = "%s"
+  }
+  external_labels = {
+    cluster = "%s",
+  }
+}`, endpoint.String(), g.cluster)
+
+	return fmt.Sprintf(`values:
+  controller:
+    type: daemonset
+  alloy:
+    extraEnv:
+      - name: HOSTNAME
+        valueFrom:
+          fieldRef:
+            fieldPath: spec.nodeName
+    resources:
+      requests:
+        cpu: 50m
+        memory: 128Mi
+      limits:
+        memory: 512Mi
+    configMap:
+      create: true
+      content: |
+%s
+`, gitops.Indent(config, 8))
+}
+
+// fluentBitValues configures Fluent Bit to push container logs to Loki
+func (g *Generator) fluentBitValues(endpoint *url.URL) string {
+	host, port := endpoint.Hostname(), endpoint.Port()
+	if port == "" {
+		port = "80"
+		if endpoint.Scheme == "https" {
+			port = "443"
+		}
+	}
+	tls := "off"
+	if endpoint.Scheme == "https" {
+		tls = "on"
+	}
+
+	output := fmt.Sprintf(`[OUTPUT]
+    Name                   loki
+    Match                  kube.*
+    Host                   %s
+    Port                   %s
+    Uri                    %s
+    tls                    %s
+    Labels                 cluster=%s, job=fluent-bit
+    auto_kubernetes_labels on`, host, port, endpoint.EscapedPath(), tls, g.cluster)
+
+	return fmt.Sprintf(`values:
+  resources:
+    requests:
+      cpu: 50m
+      memory: 64Mi
+    limits:
+      memory: 256Mi
+  config:
+    outputs: |
+%s
+`, gitops.Indent(output, 6))
+}
+
+func renderNamespace() string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+  labels:
+    istio.io/dataplane-mode: ambient
+`, Namespace)
+}
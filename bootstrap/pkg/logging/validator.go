@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Status describes the log shipping state of a cluster
+type Status struct {
+	CollectorReady  bool
+	DesiredPods     int32
+	ReadyPods       int32
+	LokiReachable   bool
+	ClustersInLoki  []string
+	ClusterShipping bool
+}
+
+// Validator checks the log collector and the NAS Loki receiving its logs
+type Validator struct {
+	client     *k8s.Client
+	lokiClient *k8s.Client
+}
+
+// NewValidator creates a new validator; lokiClient targets the cluster running Loki
+func NewValidator(client, lokiClient *k8s.Client) *Validator {
+	return &Validator{
+		client:     client,
+		lokiClient: lokiClient,
+	}
+}
+
+// Validate checks that the collector runs on every node and that Loki receives logs labelled with cluster
+func (v *Validator) Validate(ctx context.Context, cluster, collector, endpoint string) (*Status, error) {
+	status := &Status{}
+
+	daemonSets, err := v.client.GetClientset().AppsV1().DaemonSets(Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=" + collector,
+	})
+	if err != nil {
+		return status, fmt.Errorf("failed to list %s daemonsets: %w", collector, err)
+	}
+	if len(daemonSets.Items) == 0 {
+		return status, fmt.Errorf("%s is not deployed in namespace %s", collector, Namespace)
+	}
+	for _, ds := range daemonSets.Items {
+		status.DesiredPods += ds.Status.DesiredNumberScheduled
+		status.ReadyPods += ds.Status.NumberReady
+	}
+	status.CollectorReady = status.DesiredPods > 0 && status.ReadyPods == status.DesiredPods
+	if !status.CollectorReady {
+		return status, fmt.Errorf("%s not ready: %d/%d pods", collector, status.ReadyPods, status.DesiredPods)
+	}
+
+	if v.lokiClient == nil {
+		log.Warn("No client for the Loki cluster, skipping delivery check")
+		return status, nil
+	}
+
+	name, namespace, port, err := gitops.ServiceFromURL(endpoint)
+	if err != nil {
+		log.Warn("Cannot verify delivery to Loki", "error", err)
+		return status, nil
+	}
+
+	data, err := v.lokiClient.GetClientset().CoreV1().Services(namespace).
+		ProxyGet("http", name, port, "/loki/api/v1/label/cluster/values", nil).
+		DoRaw(ctx)
+	if err != nil {
+		return status, fmt.Errorf("Loki not reachable at %s/%s: %w", namespace, name, err)
+	}
+	status.LokiReachable = true
+
+	var response struct {
+		Data []string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return status, fmt.Errorf("failed to decode Loki labels: %w", err)
+	}
+	status.ClustersInLoki = response.Data
+	for _, value := range response.Data {
+		if value == cluster {
+			status.ClusterShipping = true
+		}
+	}
+	if !status.ClusterShipping {
+		return status, fmt.Errorf("Loki has not received logs from %s yet (clusters seen: %v)", cluster, response.Data)
+	}
+
+	log.Info("Log shipping verified", "cluster", cluster, "collector", collector, "clusters_in_loki", response.Data)
+	return status, nil
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Credentials authenticate the homelab agent against the NAS receiver
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// authorization returns the basic auth header value of the credentials
+func (c Credentials) authorization() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+}
+
+// Status describes the result of a federation validation query
+type Status struct {
+	ReceiverReachable bool
+	Series            int
+	DatasourceReady   bool
+}
+
+// Federation configures and validates metrics federation between the homelab and the NAS
+type Federation struct {
+	homelab  *k8s.Client
+	nas      *k8s.Client
+	endpoint string
+}
+
+// NewFederation creates a new federation between the homelab and NAS clusters
+func NewFederation(homelab, nas *k8s.Client, endpoint string) *Federation {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &Federation{
+		homelab:  homelab,
+		nas:      nas,
+		endpoint: endpoint,
+	}
+}
+
+// ConfigureAuth creates the credentials on both clusters, reusing the existing password when present
+func (f *Federation) ConfigureAuth(ctx context.Context, username string) error {
+	if username == "" {
+		username = DefaultUsername
+	}
+
+	creds, err := f.credentials(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash federation password: %w", err)
+	}
+
+	if err := f.homelab.CreateNamespace(ctx, AgentNamespace); err != nil {
+		return err
+	}
+	if err := f.homelab.CreateOrUpdateSecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: RemoteWriteSecret, Namespace: AgentNamespace},
+		StringData: map[string]string{"username": creds.Username, "password": creds.Password},
+		Type:       corev1.SecretTypeOpaque,
+	}); err != nil {
+		return fmt.Errorf("failed to store remote-write credentials: %w", err)
+	}
+
+	if err := f.nas.CreateNamespace(ctx, ReceiverNamespace); err != nil {
+		return err
+	}
+	webConfig := fmt.Sprintf("basic_auth_users:\n  %s: %s\n", creds.Username, hash)
+	if err := f.nas.CreateOrUpdateSecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: WebConfigSecret, Namespace: ReceiverNamespace},
+		StringData: map[string]string{"web.yml": webConfig},
+		Type:       corev1.SecretTypeOpaque,
+	}); err != nil {
+		return fmt.Errorf("failed to store receiver web config: %w", err)
+	}
+	if err := f.nas.CreateOrUpdateSecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ProbeSecret, Namespace: "flux-system"},
+		StringData: map[string]string{"authorization": creds.authorization()},
+		Type:       corev1.SecretTypeOpaque,
+	}); err != nil {
+		return fmt.Errorf("failed to store receiver probe credentials: %w", err)
+	}
+
+	datasource, err := f.datasource(creds)
+	if err != nil {
+		return err
+	}
+	if err := f.nas.CreateOrUpdateSecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      DatasourceSecret,
+			Namespace: ReceiverNamespace,
+			Labels:    map[string]string{"grafana_datasource": "1"},
+		},
+		StringData: map[string]string{"federation-datasource.yaml": datasource},
+		Type:       corev1.SecretTypeOpaque,
+	}); err != nil {
+		return fmt.Errorf("failed to store Grafana datasource: %w", err)
+	}
+
+	log.Info("Metrics federation credentials configured", "user", creds.Username)
+	return nil
+}
+
+// Validate queries the NAS receiver, as the NAS Grafana does, for metrics labelled with cluster
+func (f *Federation) Validate(ctx context.Context, cluster string) (*Status, error) {
+	status := &Status{}
+
+	creds, err := f.existingCredentials(ctx)
+	if err != nil {
+		return status, err
+	}
+	if creds == nil {
+		return status, fmt.Errorf("federation credentials not configured, run the federation setup first")
+	}
+
+	if _, err := f.nas.GetClientset().CoreV1().Secrets(ReceiverNamespace).Get(ctx, DatasourceSecret, metav1.GetOptions{}); err == nil {
+		status.DatasourceReady = true
+	}
+
+	query := fmt.Sprintf(`count(up{cluster=%q})`, cluster)
+	series, _, err := QueryScalar(ctx, f.nas, f.endpoint, query, creds.authorization())
+	if err != nil {
+		return status, fmt.Errorf("metrics receiver not queryable: %w", err)
+	}
+	status.ReceiverReachable = true
+	status.Series = int(series)
+	if status.Series == 0 {
+		return status, fmt.Errorf("no %s metrics in the NAS receiver yet", cluster)
+	}
+
+	log.Info("Metrics federation verified", "cluster", cluster, "targets", status.Series, "grafana_datasource", status.DatasourceReady)
+	return status, nil
+}
+
+// credentials returns the existing credentials or generates new ones
+func (f *Federation) credentials(ctx context.Context, username string) (*Credentials, error) {
+	existing, err := f.existingCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Username == username {
+		return existing, nil
+	}
+
+	password := make([]byte, 24)
+	if _, err := rand.Read(password); err != nil {
+		return nil, fmt.Errorf("failed to generate federation password: %w", err)
+	}
+	return &Credentials{
+		Username: username,
+		Password: base64.RawURLEncoding.EncodeToString(password),
+	}, nil
+}
+
+func (f *Federation) existingCredentials(ctx context.Context) (*Credentials, error) {
+	secret, err := f.homelab.GetSecret(ctx, AgentNamespace, RemoteWriteSecret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read remote-write credentials: %w", err)
+	}
+	username, password := string(secret.Data["username"]), string(secret.Data["password"])
+	if username == "" || password == "" {
+		return nil, nil
+	}
+	return &Credentials{Username: username, Password: password}, nil
+}
+
+// datasource renders the Grafana provisioning file pointing at the receiver
+func (f *Federation) datasource(creds *Credentials) (string, error) {
+	name, namespace, port, err := gitops.ServiceFromURL(f.endpoint)
+	if err != nil {
+		return "", err
+	}
+	password, err := json.Marshal(creds.Password)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`apiVersion: 1
+datasources:
+  - name: Homelab (federated)
+    type: prometheus
+    uid: metrics-federation
+    access: proxy
+    url: http://%s.%s.svc.cluster.local:%s
+    basicAuth: true
+    basicAuthUser: %s
+    secureJsonData:
+      basicAuthPassword: %s
+`, name, namespace, port, creds.Username, password), nil
+}
@@ -0,0 +1,272 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+)
+
+const (
+	// ReceiverNamespace is where the NAS metrics receiver runs
+	ReceiverNamespace = "metrics"
+
+	// ReceiverService is the service of the NAS metrics receiver
+	ReceiverService = "metrics-receiver"
+
+	// AgentNamespace is where the homelab Prometheus agent runs
+	AgentNamespace = "monitoring"
+
+	// DefaultEndpoint is the remote-write URL of the NAS receiver, reached across the mesh
+	DefaultEndpoint = "http://metrics-receiver.metrics.svc.cluster.local/api/v1/write"
+
+	// DefaultUsername is the basic auth user the homelab remote-writes as
+	DefaultUsername = "homelab"
+
+	// DefaultRetention is how long the NAS receiver keeps federated metrics
+	DefaultRetention = "15d"
+)
+
+// Secrets holding the federation credentials
+const (
+	// RemoteWriteSecret holds the credentials of the homelab Prometheus agent
+	RemoteWriteSecret = "metrics-remote-write"
+
+	// WebConfigSecret holds the Prometheus web config enabling basic auth on the receiver
+	WebConfigSecret = "metrics-receiver-web-config"
+
+	// ProbeSecret holds the Authorization header used by the receiver probes, consumed by its HelmRelease
+	ProbeSecret = "metrics-receiver-probe"
+
+	// DatasourceSecret provisions the receiver as a datasource of the NAS Grafana
+	DatasourceSecret = "metrics-federation-datasource"
+)
+
+var receiverChart = gitops.HelmChart{
+	Repository: "prometheus-community",
+	URL:        "https://prometheus-community.github.io/helm-charts",
+	Chart:      "prometheus",
+	Version:    "27.39.0",
+	Namespace:  ReceiverNamespace,
+}
+
+// Options configures the generated federation
+type Options struct {
+	Endpoint     string
+	Retention    string
+	StorageClass string
+}
+
+// Generator writes the metrics federation into the GitOps repository
+type Generator struct {
+	projectRoot string
+	cluster     string
+	opts        Options
+}
+
+// NewGenerator creates a new generator for cluster under projectRoot
+func NewGenerator(projectRoot, cluster string, opts Options) *Generator {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
+	if opts.Retention == "" {
+		opts.Retention = DefaultRetention
+	}
+	return &Generator{
+		projectRoot: projectRoot,
+		cluster:     cluster,
+		opts:        opts,
+	}
+}
+
+// Generate writes the receiver on the NAS or the remote-write agent on the homelab
+func (g *Generator) Generate() ([]string, error) {
+	clusterDir := filepath.Join(g.projectRoot, "kubernetes", g.cluster)
+	if _, err := os.Stat(clusterDir); err != nil {
+		return nil, fmt.Errorf("GitOps directory for %s not found: %w", g.cluster, err)
+	}
+
+	federationDir := filepath.Join(clusterDir, "metrics-federation")
+	var files map[string]string
+	if g.cluster == "nas" {
+		files = g.receiverFiles(federationDir)
+	} else {
+		files = g.agentFiles(federationDir)
+	}
+
+	dependsOn := gitops.FoundationKustomization(g.cluster)
+	if g.cluster != "nas" {
+		// The agent needs the Prometheus operator CRDs deployed by the monitoring layer
+		dependsOn = "monitoring"
+	}
+	files[filepath.Join(clusterDir, "metrics-federation.yaml")] = gitops.RenderKustomizations(g.cluster, []gitops.Kustomization{{
+		Name:      gitops.Name(g.cluster, "metrics-federation"),
+		Path:      "metrics-federation",
+		DependsOn: dependsOn,
+	}})
+
+	if err := os.RemoveAll(federationDir); err != nil {
+		return nil, fmt.Errorf("failed to clean %s: %w", federationDir, err)
+	}
+
+	written, err := gitops.WriteFiles(g.projectRoot, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rootKustomization := filepath.Join(clusterDir, "kustomization.yaml")
+	added, err := gitops.AddResource(rootKustomization, "metrics-federation.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		written = append(written, gitops.Relative(g.projectRoot, rootKustomization))
+	}
+
+	log.Info("Generated metrics federation", "cluster", g.cluster, "endpoint", g.opts.Endpoint, "files", len(written))
+	return written, nil
+}
+
+func (g *Generator) receiverFiles(dir string) map[string]string {
+	persistence := `persistentVolume:
+      enabled: false`
+	if g.opts.StorageClass != "" {
+		persistence = fmt.Sprintf(`persistentVolume:
+      enabled: true
+      size: 20Gi
+      storageClass: %s`, g.opts.StorageClass)
+	}
+
+	values := fmt.Sprintf(`valuesFrom:
+  - kind: Secret
+    name: %s
+    valuesKey: authorization
+    targetPath: server.probeHeaders[0].value
+values:
+  alertmanager:
+    enabled: false
+  kube-state-metrics:
+    enabled: false
+  prometheus-node-exporter:
+    enabled: false
+  prometheus-pushgateway:
+    enabled: false
+  configmapReload:
+    prometheus:
+      enabled: false
+  serverFiles:
+    prometheus.yml:
+      scrape_configs: []
+  server:
+    fullnameOverride: %s
+    retention: %s
+    extraFlags:
+      - web.enable-lifecycle
+      - web.enable-remote-write-receiver
+      - web.config.file=/etc/prometheus-web/web.yml
+    extraSecretMounts:
+      - name: web-config
+        mountPath: /etc/prometheus-web
+        secretName: %s
+        readOnly: true
+    probeHeaders:
+      - name: Authorization
+    %s
+    resources:
+      requests:
+        cpu: 100m
+        memory: 512Mi
+      limits:
+        memory: 2Gi
+`, ProbeSecret, ReceiverService, g.opts.Retention, WebConfigSecret, persistence)
+
+	return map[string]string{
+		filepath.Join(dir, "namespace.yaml"):       renderNamespace(ReceiverNamespace),
+		filepath.Join(dir, "helm-repository.yaml"): receiverChart.RenderRepository(),
+		filepath.Join(dir, "helm-release.yaml"):    receiverChart.RenderRelease(values),
+		filepath.Join(dir, "kustomization.yaml"):   gitops.RenderKustomization([]string{"namespace.yaml", "helm-repository.yaml", "helm-release.yaml"}),
+	}
+}
+
+func (g *Generator) agentFiles(dir string) map[string]string {
+	agent := fmt.Sprintf(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: federation-agent
+  namespace: %[1]s
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: federation-agent
+rules:
+  - apiGroups: [""]
+    resources: ["nodes", "nodes/metrics", "services", "endpoints", "pods"]
+    verbs: ["get", "list", "watch"]
+  - apiGroups: ["discovery.k8s.io"]
+    resources: ["endpointslices"]
+    verbs: ["get", "list", "watch"]
+  - nonResourceURLs: ["/metrics"]
+    verbs: ["get"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: federation-agent
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: federation-agent
+subjects:
+  - kind: ServiceAccount
+    name: federation-agent
+    namespace: %[1]s
+---
+apiVersion: monitoring.coreos.com/v1alpha1
+kind: PrometheusAgent
+metadata:
+  name: federation
+  namespace: %[1]s
+spec:
+  serviceAccountName: federation-agent
+  replicas: 1
+  externalLabels:
+    cluster: %[2]s
+  serviceMonitorSelector: {}
+  serviceMonitorNamespaceSelector: {}
+  podMonitorSelector: {}
+  podMonitorNamespaceSelector: {}
+  remoteWrite:
+    - url: %[3]s
+      basicAuth:
+        username:
+          name: %[4]s
+          key: username
+        password:
+          name: %[4]s
+          key: password
+  resources:
+    requests:
+      cpu: 100m
+      memory: 256Mi
+    limits:
+      memory: 1Gi
+`, AgentNamespace, g.cluster, g.opts.Endpoint, RemoteWriteSecret)
+
+	return map[string]string{
+		filepath.Join(dir, "prometheus-agent.yaml"): agent,
+		filepath.Join(dir, "kustomization.yaml"):    gitops.RenderKustomization([]string{"prometheus-agent.yaml"}),
+	}
+}
+
+func renderNamespace(name string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+  labels:
+    istio.io/dataplane-mode: ambient
+`, name)
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+)
+
+// Sample is one series of an instant query result
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Query runs an instant PromQL query against the Prometheus behind serviceURL through the API server proxy
+func Query(ctx context.Context, client *k8s.Client, serviceURL, query, authorization string) ([]Sample, error) {
+	name, namespace, port, err := gitops.ServiceFromURL(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	request := client.GetClientset().CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/namespaces", namespace, "services", fmt.Sprintf("http:%s:%s", name, port), "proxy", "api", "v1", "query").
+		Param("query", query)
+	if authorization != "" {
+		request = request.SetHeader("Authorization", authorization)
+	}
+	data, err := request.DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Prometheus not reachable at %s/%s: %w", namespace, name, err)
+	}
+
+	var response struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode query response: %w", err)
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("query %s failed: %s", query, response.Error)
+	}
+
+	samples := make([]Sample, 0, len(response.Data.Result))
+	for _, result := range response.Data.Result {
+		if len(result.Value) != 2 {
+			continue
+		}
+		raw, ok := result.Value[1].(string)
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, Sample{Labels: result.Metric, Value: value})
+	}
+	return samples, nil
+}
+
+// QueryScalar runs query and returns the value of its first series, reporting false when the result is empty
+func QueryScalar(ctx context.Context, client *k8s.Client, serviceURL, query, authorization string) (float64, bool, error) {
+	samples, err := Query(ctx, client, serviceURL, query, authorization)
+	if err != nil || len(samples) == 0 {
+		return 0, false, err
+	}
+	return samples[0].Value, true, nil
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// EventType identifies what happened
+type EventType string
+
+// Events emitted by bootstrap, destroy, sync and scheduled tasks
+const (
+	StepStarted        EventType = "step_started"
+	StepSucceeded      EventType = "step_succeeded"
+	StepFailed         EventType = "step_failed"
+	BootstrapSucceeded EventType = "bootstrap_succeeded"
+	BootstrapFailed    EventType = "bootstrap_failed"
+	DestroyCompleted   EventType = "destroy_completed"
+	DestroyFailed      EventType = "destroy_failed"
+	DriftDetected      EventType = "drift_detected"
+	ScheduleSucceeded  EventType = "schedule_succeeded"
+	ScheduleFailed     EventType = "schedule_failed"
+)
+
+// defaultEvents are sent to sinks without an events filter, step progress is opt-in
+var defaultEvents = []EventType{
+	StepFailed,
+	BootstrapSucceeded,
+	BootstrapFailed,
+	DestroyCompleted,
+	DestroyFailed,
+	DriftDetected,
+	ScheduleFailed,
+}
+
+// sendTimeout bounds each delivery so an unreachable sink never stalls a bootstrap
+const sendTimeout = 10 * time.Second
+
+// Event is a single notification
+type Event struct {
+	Type     EventType     `json:"type"`
+	Cluster  string        `json:"cluster"`
+	Step     string        `json:"step,omitempty"`
+	Message  string        `json:"message"`
+	Error    string        `json:"error,omitempty"`
+	Details  []string      `json:"details,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Time     time.Time     `json:"time"`
+}
+
+// Failed reports whether the event is a failure
+func (e Event) Failed() bool {
+	return e.Type == StepFailed || e.Type == BootstrapFailed || e.Type == DestroyFailed || e.Type == ScheduleFailed
+}
+
+// Title returns a one-line summary of the event
+func (e Event) Title() string {
+	title := fmt.Sprintf("[%s] %s", e.Cluster, e.Message)
+	if e.Step != "" {
+		title = fmt.Sprintf("[%s] %s: %s", e.Cluster, e.Message, e.Step)
+	}
+	return title
+}
+
+// Text renders the event as plain text for chat sinks
+func (e Event) Text() string {
+	lines := []string{e.Title()}
+	if e.Duration > 0 {
+		lines = append(lines, "Duration: "+e.Duration.Round(time.Second).String())
+	}
+	if e.Error != "" {
+		lines = append(lines, "Error: "+e.Error)
+	}
+	for _, detail := range e.Details {
+		lines = append(lines, "- "+detail)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Sink delivers events to one destination
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+type routedSink struct {
+	sink   Sink
+	events map[EventType]bool
+}
+
+// Notifier fans events out to the configured sinks
+type Notifier struct {
+	sinks []routedSink
+}
+
+// NewNotifier creates a notifier for the configured sinks, a notifier without sinks drops every event
+func NewNotifier(cfg config.NotificationsConfig) (*Notifier, error) {
+	n := &Notifier{}
+	for i, sinkCfg := range cfg.Sinks {
+		sink, err := newSink(sinkCfg)
+		if err != nil {
+			return nil, fmt.Errorf("notification sink %d: %w", i+1, err)
+		}
+		events := map[EventType]bool{}
+		filter := sinkCfg.Events
+		if len(filter) == 0 {
+			for _, event := range defaultEvents {
+				events[event] = true
+			}
+		}
+		for _, event := range filter {
+			events[EventType(strings.TrimSpace(event))] = true
+		}
+		n.sinks = append(n.sinks, routedSink{sink: sink, events: events})
+	}
+	return n, nil
+}
+
+// Enabled reports whether any sink is configured
+func (n *Notifier) Enabled() bool {
+	return n != nil && len(n.sinks) > 0
+}
+
+// Notify sends the event to every sink subscribed to it, delivery failures are only logged
+func (n *Notifier) Notify(ctx context.Context, event Event) {
+	if !n.Enabled() {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, routed := range n.sinks {
+		if !routed.events[event.Type] {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+		if err := routed.sink.Send(sendCtx, event); err != nil {
+			log.Warn("Failed to send notification", "sink", routed.sink.Name(), "event", event.Type, "error", err)
+		}
+		cancel()
+	}
+}
+
+// newSink builds the sink for a configured destination
+func newSink(cfg config.NotificationSink) (Sink, error) {
+	url := os.ExpandEnv(cfg.URL)
+	if url == "" {
+		return nil, fmt.Errorf("%s sink requires a url", cfg.Type)
+	}
+	token := os.ExpandEnv(cfg.Token)
+	switch cfg.Type {
+	case "slack":
+		return &slackSink{url: url}, nil
+	case "discord":
+		return &discordSink{url: url}, nil
+	case "webhook":
+		return &webhookSink{url: url, token: token}, nil
+	case "ntfy":
+		return &ntfySink{url: url, token: token}, nil
+	}
+	return nil, fmt.Errorf("unsupported sink type %q, expected slack, discord, webhook or ntfy", cfg.Type)
+}
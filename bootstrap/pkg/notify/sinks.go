@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// slackSink posts to a Slack incoming webhook
+type slackSink struct {
+	url string
+}
+
+func (s *slackSink) Name() string { return "slack" }
+
+func (s *slackSink) Send(ctx context.Context, event Event) error {
+	return postJSON(ctx, s.url, "", map[string]string{"text": icon(event) + " " + event.Text()})
+}
+
+// discordSink posts to a Discord channel webhook
+type discordSink struct {
+	url string
+}
+
+func (s *discordSink) Name() string { return "discord" }
+
+func (s *discordSink) Send(ctx context.Context, event Event) error {
+	content := icon(event) + " " + event.Text()
+	// Discord rejects messages over 2000 characters
+	if runes := []rune(content); len(runes) > 2000 {
+		content = string(runes[:1997]) + "..."
+	}
+	return postJSON(ctx, s.url, "", map[string]string{"content": content})
+}
+
+// webhookSink posts the raw event as JSON
+type webhookSink struct {
+	url   string
+	token string
+}
+
+func (s *webhookSink) Name() string { return "webhook" }
+
+func (s *webhookSink) Send(ctx context.Context, event Event) error {
+	return postJSON(ctx, s.url, s.token, event)
+}
+
+// ntfySink publishes to an ntfy topic URL
+type ntfySink struct {
+	url   string
+	token string
+}
+
+func (s *ntfySink) Name() string { return "ntfy" }
+
+func (s *ntfySink) Send(ctx context.Context, event Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(event.Text()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", event.Title())
+	if event.Failed() {
+		req.Header.Set("Priority", "high")
+		req.Header.Set("Tags", "rotating_light")
+	} else {
+		req.Header.Set("Tags", "white_check_mark")
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return send(req)
+}
+
+// icon prefixes chat messages so failures stand out
+func icon(event Event) string {
+	switch {
+	case event.Failed():
+		return "❌"
+	case event.Type == DriftDetected:
+		return "⚠️"
+	case event.Type == StepStarted:
+		return "▶️"
+	}
+	return "✅"
+}
+
+func postJSON(ctx context.Context, url, token string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return send(req)
+}
+
+func send(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package observability
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+)
+
+const (
+	// DashboardNamespace is where the dashboard ConfigMaps are created for the Grafana sidecar
+	DashboardNamespace = "monitoring"
+
+	// DashboardFolder is the Grafana folder holding the curated dashboards
+	DashboardFolder = "Bootstrap"
+)
+
+// Panel is a single Grafana panel backed by one Prometheus query
+type Panel struct {
+	Title  string
+	Type   string
+	Expr   string
+	Legend string
+	Unit   string
+}
+
+// Dashboard is a curated Grafana dashboard shipped with the bootstrap tool
+type Dashboard struct {
+	UID         string
+	Title       string
+	Description string
+	Panels      []Panel
+}
+
+// Dashboards are the curated dashboards, versioned alongside the tool
+var Dashboards = []Dashboard{
+	{
+		UID:         "bootstrap-steps",
+		Title:       "Bootstrap Steps",
+		Description: "Bootstrap step durations and outcomes pushed to the Pushgateway",
+		Panels: []Panel{
+			{Title: "Last bootstrap", Type: "stat", Expr: "max by (cluster) (" + MetricLastRun + ") * 1000", Legend: "{{cluster}}", Unit: "dateTimeFromNow"},
+			{Title: "Total duration", Type: "stat", Expr: "max by (cluster) (" + MetricRunDuration + ")", Legend: "{{cluster}}", Unit: "s"},
+			{Title: "Failed steps", Type: "stat", Expr: "count by (cluster) (" + MetricStepSuccess + " == 0) or vector(0)", Legend: "{{cluster}}", Unit: "none"},
+			{Title: "Step durations", Type: "barchart", Expr: "max by (cluster, step) (" + MetricStepDuration + ")", Legend: "{{cluster}} {{step}}", Unit: "s"},
+			{Title: "Step duration history", Type: "timeseries", Expr: "max by (cluster, step) (" + MetricStepDuration + ")", Legend: "{{cluster}} {{step}}", Unit: "s"},
+		},
+	},
+	{
+		UID:         "bootstrap-flux",
+		Title:       "Flux Reconciliation Health",
+		Description: "Readiness and reconcile durations of Flux Kustomizations and HelmReleases",
+		Panels: []Panel{
+			{Title: "Not ready", Type: "stat", Expr: `count(gotk_reconcile_condition{type="Ready",status="False"} == 1) or vector(0)`, Unit: "none"},
+			{Title: "Suspended", Type: "stat", Expr: `count(gotk_suspend_status == 1) or vector(0)`, Unit: "none"},
+			{Title: "Not ready resources", Type: "table", Expr: `gotk_reconcile_condition{type="Ready",status="False"} == 1`, Legend: "{{kind}} {{exported_namespace}}/{{name}}", Unit: "none"},
+			{Title: "Reconcile duration p95", Type: "timeseries", Expr: `histogram_quantile(0.95, sum by (kind, name, le) (rate(gotk_reconcile_duration_seconds_bucket[5m])))`, Legend: "{{kind}} {{name}}", Unit: "s"},
+		},
+	},
+	{
+		UID:         "bootstrap-mesh-gateways",
+		Title:       "Mesh Gateway Traffic",
+		Description: "Cross-cluster traffic through the Istio east-west gateways",
+		Panels: []Panel{
+			{Title: "Requests per second", Type: "timeseries", Expr: `sum by (source_cluster, destination_cluster) (rate(istio_requests_total{destination_workload=~"istio-eastwestgateway.*"}[5m]))`, Legend: "{{source_cluster}} → {{destination_cluster}}", Unit: "reqps"},
+			{Title: "Error rate", Type: "timeseries", Expr: `sum by (destination_service) (rate(istio_requests_total{destination_workload=~"istio-eastwestgateway.*",response_code=~"5.."}[5m]))`, Legend: "{{destination_service}}", Unit: "reqps"},
+			{Title: "Bytes sent", Type: "timeseries", Expr: `sum by (source_cluster, destination_cluster) (rate(istio_tcp_sent_bytes_total{destination_workload=~"istio-eastwestgateway.*"}[5m]))`, Legend: "{{source_cluster}} → {{destination_cluster}}", Unit: "Bps"},
+			{Title: "Open connections", Type: "timeseries", Expr: `sum by (destination_cluster) (istio_tcp_connections_opened_total{destination_workload=~"istio-eastwestgateway.*"} - istio_tcp_connections_closed_total{destination_workload=~"istio-eastwestgateway.*"})`, Legend: "{{destination_cluster}}", Unit: "none"},
+		},
+	},
+	{
+		UID:         "bootstrap-ceph",
+		Title:       "Ceph Health",
+		Description: "Rook-Ceph cluster health, capacity and OSD state",
+		Panels: []Panel{
+			{Title: "Health (0 OK, 1 WARN, 2 ERR)", Type: "stat", Expr: "max(ceph_health_status)", Unit: "none"},
+			{Title: "OSDs up", Type: "stat", Expr: "sum(ceph_osd_up)", Unit: "none"},
+			{Title: "Capacity used", Type: "stat", Expr: "sum(ceph_cluster_total_used_bytes) / sum(ceph_cluster_total_bytes)", Unit: "percentunit"},
+			{Title: "Degraded placement groups", Type: "timeseries", Expr: "sum(ceph_pg_degraded)", Unit: "none"},
+			{Title: "Client throughput", Type: "timeseries", Expr: "sum(rate(ceph_pool_rd_bytes[5m])) + sum(rate(ceph_pool_wr_bytes[5m]))", Legend: "read + write", Unit: "Bps"},
+		},
+	},
+}
+
+// DashboardNames returns the identifiers of the curated dashboards
+func DashboardNames() []string {
+	names := make([]string, 0, len(Dashboards))
+	for _, dashboard := range Dashboards {
+		names = append(names, dashboard.UID)
+	}
+	return names
+}
+
+// JSON renders the dashboard model understood by Grafana, tagged with the tool version
+func (d Dashboard) JSON(version string) ([]byte, error) {
+	panels := make([]map[string]interface{}, 0, len(d.Panels))
+	x, y := 0, 0
+	for i, panel := range d.Panels {
+		width, height := 12, 8
+		if panel.Type == "stat" {
+			width, height = 6, 4
+		}
+		if x+width > 24 {
+			x, y = 0, y+8
+		}
+		legend := panel.Legend
+		if legend == "" {
+			legend = "__auto"
+		}
+		panels = append(panels, map[string]interface{}{
+			"id":         i + 1,
+			"title":      panel.Title,
+			"type":       panel.Type,
+			"datasource": map[string]string{"type": "prometheus", "uid": "${datasource}"},
+			"gridPos":    map[string]int{"x": x, "y": y, "w": width, "h": height},
+			"fieldConfig": map[string]interface{}{
+				"defaults":  map[string]string{"unit": panel.Unit},
+				"overrides": []interface{}{},
+			},
+			"targets": []map[string]string{{
+				"refId":        "A",
+				"expr":         panel.Expr,
+				"legendFormat": legend,
+			}},
+		})
+		x += width
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"uid":           d.UID,
+		"title":         d.Title,
+		"description":   d.Description,
+		"tags":          []string{"homelab", "bootstrap", "bootstrap-" + version},
+		"editable":      false,
+		"schemaVersion": 39,
+		"time":          map[string]string{"from": "now-24h", "to": "now"},
+		"refresh":       "1m",
+		"templating": map[string]interface{}{
+			"list": []map[string]interface{}{{
+				"name":  "datasource",
+				"label": "Datasource",
+				"type":  "datasource",
+				"query": "prometheus",
+			}},
+		},
+		"panels": panels,
+	}, "", "  ")
+}
+
+// DashboardGenerator writes the curated dashboards as Grafana sidecar ConfigMaps into the GitOps repository
+type DashboardGenerator struct {
+	projectRoot string
+	cluster     string
+	version     string
+	selected    []string
+}
+
+// NewDashboardGenerator creates a generator for cluster; an empty selection provisions every dashboard
+func NewDashboardGenerator(projectRoot, cluster, version string, selected []string) *DashboardGenerator {
+	return &DashboardGenerator{
+		projectRoot: projectRoot,
+		cluster:     cluster,
+		version:     version,
+		selected:    selected,
+	}
+}
+
+// Generate writes the dashboards and returns the paths it wrote, relative to the project root
+func (g *DashboardGenerator) Generate() ([]string, error) {
+	dashboards, err := g.dashboards()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterDir := filepath.Join(g.projectRoot, "kubernetes", g.cluster)
+	if _, err := os.Stat(clusterDir); err != nil {
+		return nil, fmt.Errorf("GitOps directory for %s not found: %w", g.cluster, err)
+	}
+
+	dashboardsDir := filepath.Join(clusterDir, "dashboards")
+	files := make(map[string]string, len(dashboards)+2)
+	resources := make([]string, 0, len(dashboards))
+	for _, dashboard := range dashboards {
+		data, err := dashboard.JSON(g.version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render dashboard %s: %w", dashboard.UID, err)
+		}
+		file := dashboard.UID + ".yaml"
+		files[filepath.Join(dashboardsDir, file)] = g.renderConfigMap(dashboard, data)
+		resources = append(resources, file)
+	}
+	sort.Strings(resources)
+	files[filepath.Join(dashboardsDir, "kustomization.yaml")] = gitops.RenderKustomization(resources)
+
+	dependsOn := gitops.FoundationKustomization(g.cluster)
+	if g.cluster != "nas" {
+		// The monitoring layer creates the namespace and the Grafana sidecar
+		dependsOn = "monitoring"
+	}
+	files[filepath.Join(clusterDir, "dashboards.yaml")] = gitops.RenderKustomizations(g.cluster, []gitops.Kustomization{{
+		Name:      gitops.Name(g.cluster, "dashboards"),
+		Path:      "dashboards",
+		DependsOn: dependsOn,
+	}})
+
+	// Dashboards dropped from the selection are pruned by Flux
+	if err := os.RemoveAll(dashboardsDir); err != nil {
+		return nil, fmt.Errorf("failed to clean %s: %w", dashboardsDir, err)
+	}
+
+	written, err := gitops.WriteFiles(g.projectRoot, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rootKustomization := filepath.Join(clusterDir, "kustomization.yaml")
+	added, err := gitops.AddResource(rootKustomization, "dashboards.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		written = append(written, gitops.Relative(g.projectRoot, rootKustomization))
+	}
+
+	log.Info("Generated Grafana dashboards", "cluster", g.cluster, "dashboards", len(dashboards), "version", g.version)
+	return written, nil
+}
+
+func (g *DashboardGenerator) dashboards() ([]Dashboard, error) {
+	if len(g.selected) == 0 {
+		return Dashboards, nil
+	}
+
+	byUID := make(map[string]Dashboard, len(Dashboards))
+	for _, dashboard := range Dashboards {
+		byUID[dashboard.UID] = dashboard
+	}
+	selected := make([]Dashboard, 0, len(g.selected))
+	for _, name := range g.selected {
+		dashboard, ok := byUID[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown dashboard %q (available: %v)", name, DashboardNames())
+		}
+		selected = append(selected, dashboard)
+	}
+	return selected, nil
+}
+
+// renderConfigMap wraps a dashboard into a ConfigMap picked up by the Grafana dashboard sidecar
+func (g *DashboardGenerator) renderConfigMap(dashboard Dashboard, data []byte) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: dashboard-%s
+  namespace: %s
+  labels:
+    grafana_dashboard: "1"
+    app.kubernetes.io/managed-by: homelab-bootstrap
+    app.kubernetes.io/version: "%s"
+  annotations:
+    k8s-sidecar-target-directory: /tmp/dashboards/%s
+data:
+  %s.json: |
+%s
+`, dashboard.UID, DashboardNamespace, g.version, DashboardFolder, dashboard.UID, gitops.Indent(string(data), 4))
+}
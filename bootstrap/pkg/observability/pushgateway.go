@@ -0,0 +1,79 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Metrics pushed by bootstrap runs and charted by the bootstrap-steps dashboard
+const (
+	MetricStepDuration = "homelab_bootstrap_step_duration_seconds"
+	MetricStepSuccess  = "homelab_bootstrap_step_success"
+	MetricRunDuration  = "homelab_bootstrap_duration_seconds"
+	MetricLastRun      = "homelab_bootstrap_last_run_timestamp_seconds"
+
+	pushJob = "homelab_bootstrap"
+)
+
+// StepMetric is the outcome of one bootstrap step
+type StepMetric struct {
+	Step     string
+	Duration time.Duration
+	Success  bool
+}
+
+// PushStepMetrics replaces the cluster's metric group on the Pushgateway with the steps of the last run
+func PushStepMetrics(ctx context.Context, pushgateway, cluster string, steps []StepMetric) error {
+	base, err := url.Parse(strings.TrimRight(pushgateway, "/"))
+	if err != nil || base.Host == "" {
+		return fmt.Errorf("invalid Pushgateway URL %q", pushgateway)
+	}
+	target := fmt.Sprintf("%s/metrics/job/%s/cluster/%s", base.String(), pushJob, url.PathEscape(cluster))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewBufferString(renderStepMetrics(steps, time.Now())))
+	if err != nil {
+		return fmt.Errorf("failed to build Pushgateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics to %s: %w", base.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Pushgateway rejected metrics: %s", resp.Status)
+	}
+	return nil
+}
+
+// renderStepMetrics renders steps in the Prometheus text exposition format
+func renderStepMetrics(steps []StepMetric, now time.Time) string {
+	var b strings.Builder
+	var total time.Duration
+
+	fmt.Fprintf(&b, "# TYPE %s gauge\n", MetricStepDuration)
+	for _, step := range steps {
+		fmt.Fprintf(&b, "%s{step=%q} %g\n", MetricStepDuration, step.Step, step.Duration.Seconds())
+		total += step.Duration
+	}
+
+	fmt.Fprintf(&b, "# TYPE %s gauge\n", MetricStepSuccess)
+	for _, step := range steps {
+		success := 0
+		if step.Success {
+			success = 1
+		}
+		fmt.Fprintf(&b, "%s{step=%q} %d\n", MetricStepSuccess, step.Step, success)
+	}
+
+	fmt.Fprintf(&b, "# TYPE %s gauge\n%s %g\n", MetricRunDuration, MetricRunDuration, total.Seconds())
+	fmt.Fprintf(&b, "# TYPE %s gauge\n%s %d\n", MetricLastRun, MetricLastRun, now.Unix())
+	return b.String()
+}
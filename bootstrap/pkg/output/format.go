@@ -0,0 +1,78 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Format selects how commands print their results
+type Format string
+
+const (
+	// FormatTable prints human readable logs, the default
+	FormatTable Format = "table"
+	// FormatJSON prints the results as a single JSON document on stdout
+	FormatJSON Format = "json"
+	// FormatYAML prints the results as a single YAML document on stdout
+	FormatYAML Format = "yaml"
+)
+
+var format atomic.Value
+
+// ParseFormat validates an --output value
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case FormatTable, FormatJSON, FormatYAML:
+		return Format(value), nil
+	case "":
+		return FormatTable, nil
+	}
+	return "", fmt.Errorf("unsupported output format %q, expected table, json or yaml", value)
+}
+
+// SetFormat sets the output format of the running command
+func SetFormat(f Format) {
+	format.Store(f)
+}
+
+// CurrentFormat returns the output format of the running command
+func CurrentFormat() Format {
+	if f, ok := format.Load().(Format); ok {
+		return f
+	}
+	return FormatTable
+}
+
+// Structured reports whether results are printed as JSON or YAML instead of logs
+func Structured() bool {
+	return CurrentFormat() != FormatTable
+}
+
+// Print writes v to stdout in the current structured format
+func Print(v interface{}) error {
+	return Write(GetManager().GetStdout(), CurrentFormat(), v)
+}
+
+// Write encodes v to w as JSON or YAML, using the json tags of v for both
+func Write(w io.Writer, f Format, v interface{}) error {
+	var data []byte
+	var err error
+	switch f {
+	case FormatJSON:
+		data, err = json.MarshalIndent(v, "", "  ")
+		data = append(data, '\n')
+	case FormatYAML:
+		data, err = yaml.Marshal(v)
+	default:
+		return fmt.Errorf("output format %q is not structured", f)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode %s output: %w", f, err)
+	}
+	_, err = w.Write(data)
+	return err
+}
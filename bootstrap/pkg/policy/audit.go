@@ -0,0 +1,277 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	policyReportGVR        = schema.GroupVersionResource{Group: "wgpolicyk8s.io", Version: "v1alpha2", Resource: "policyreports"}
+	clusterPolicyReportGVR = schema.GroupVersionResource{Group: "wgpolicyk8s.io", Version: "v1alpha2", Resource: "clusterpolicyreports"}
+	clusterPolicyGVR       = schema.GroupVersionResource{Group: "kyverno.io", Version: "v1", Resource: "clusterpolicies"}
+	constraintTemplateGVR  = schema.GroupVersionResource{Group: "templates.gatekeeper.sh", Version: "v1", Resource: "constrainttemplates"}
+)
+
+// Violation is a resource failing a baseline policy
+type Violation struct {
+	Policy    string `json:"policy"`
+	Rule      string `json:"rule,omitempty"`
+	Severity  string `json:"severity"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+}
+
+// Resource returns the namespaced name of the violating resource
+func (v Violation) Resource() string {
+	if v.Namespace == "" {
+		return fmt.Sprintf("%s/%s", v.Kind, v.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", v.Kind, v.Namespace, v.Name)
+}
+
+// Report is the audit-mode result of the baseline policies
+type Report struct {
+	Engine     Engine      `json:"engine"`
+	Installed  bool        `json:"installed"`
+	Policies   int         `json:"policies"`
+	Baselines  []string    `json:"baselines"`
+	Violations []Violation `json:"violations"`
+}
+
+// ByPolicy groups the violations by policy name
+func (r *Report) ByPolicy() map[string][]Violation {
+	grouped := map[string][]Violation{}
+	for _, violation := range r.Violations {
+		grouped[violation.Policy] = append(grouped[violation.Policy], violation)
+	}
+	return grouped
+}
+
+// HasBaseline reports whether the baseline policy name is installed
+func (r *Report) HasBaseline(name string) bool {
+	for _, installed := range r.Baselines {
+		if installed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Compliance maps the compliance checks of the installed baseline policies to whether no resource violates them
+func (r *Report) Compliance() map[string]bool {
+	grouped := r.ByPolicy()
+	checks := map[string]bool{}
+	for _, baseline := range BaselinePolicies {
+		if baseline.Compliance == "" || !r.HasBaseline(baseline.Name) {
+			continue
+		}
+		compliant, seen := checks[baseline.Compliance]
+		checks[baseline.Compliance] = (compliant || !seen) && len(grouped[baseline.Name]) == 0
+	}
+	return checks
+}
+
+// Auditor collects baseline policy violations from the policy engine
+type Auditor struct {
+	client *k8s.Client
+}
+
+// NewAuditor creates a new auditor
+func NewAuditor(client *k8s.Client) *Auditor {
+	return &Auditor{
+		client: client,
+	}
+}
+
+// Detect returns the policy engine running the baseline policies, or an empty engine when none is found
+func (a *Auditor) Detect(ctx context.Context) Engine {
+	dynamicClient := a.client.GetDynamicClient()
+	if _, err := dynamicClient.Resource(clusterPolicyGVR).List(ctx, metav1.ListOptions{Limit: 1}); err == nil {
+		return EngineKyverno
+	}
+	if _, err := dynamicClient.Resource(constraintTemplateGVR).List(ctx, metav1.ListOptions{Limit: 1}); err == nil {
+		return EngineGatekeeper
+	}
+	return ""
+}
+
+// Audit reports the baseline policy violations found by engine, detecting the engine when empty
+func (a *Auditor) Audit(ctx context.Context, engine Engine) (*Report, error) {
+	if engine == "" {
+		engine = a.Detect(ctx)
+	}
+	report := &Report{Engine: engine, Baselines: []string{}, Violations: []Violation{}}
+
+	var err error
+	switch engine {
+	case EngineKyverno:
+		err = a.auditKyverno(ctx, report)
+	case EngineGatekeeper:
+		err = a.auditGatekeeper(ctx, report)
+	default:
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Violations, func(i, j int) bool {
+		if report.Violations[i].Policy != report.Violations[j].Policy {
+			return report.Violations[i].Policy < report.Violations[j].Policy
+		}
+		return report.Violations[i].Resource() < report.Violations[j].Resource()
+	})
+
+	log.Debug("Policy audit completed",
+		"engine", report.Engine,
+		"policies", report.Policies,
+		"violations", len(report.Violations))
+
+	return report, nil
+}
+
+// auditKyverno reads failing baseline results from Kyverno policy reports
+func (a *Auditor) auditKyverno(ctx context.Context, report *Report) error {
+	dynamicClient := a.client.GetDynamicClient()
+
+	policies, err := dynamicClient.Resource(clusterPolicyGVR).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", ManagedByLabel, managedBy),
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list Kyverno policies: %w", err)
+	}
+	report.Installed = true
+	report.Policies = len(policies.Items)
+	for _, item := range policies.Items {
+		if baseline, ok := IsBaseline(item.GetName()); ok {
+			report.Baselines = append(report.Baselines, baseline.Name)
+		}
+	}
+
+	for _, gvr := range []schema.GroupVersionResource{policyReportGVR, clusterPolicyReportGVR} {
+		reports, err := dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+		for _, item := range reports.Items {
+			report.Violations = append(report.Violations, kyvernoViolations(item)...)
+		}
+	}
+	return nil
+}
+
+// kyvernoViolations extracts failing baseline results from a policy report
+func kyvernoViolations(item unstructured.Unstructured) []Violation {
+	// Reports created by Kyverno 1.13+ describe a single resource in their scope
+	scopeKind, _, _ := unstructured.NestedString(item.Object, "scope", "kind")
+	scopeName, _, _ := unstructured.NestedString(item.Object, "scope", "name")
+	scopeNamespace, _, _ := unstructured.NestedString(item.Object, "scope", "namespace")
+
+	results, _, _ := unstructured.NestedSlice(item.Object, "results")
+	var violations []Violation
+	for _, raw := range results {
+		result, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if outcome, _ := result["result"].(string); outcome != "fail" {
+			continue
+		}
+		name, _ := result["policy"].(string)
+		baseline, ok := IsBaseline(name)
+		if !ok {
+			continue
+		}
+		rule, _ := result["rule"].(string)
+		message, _ := result["message"].(string)
+
+		resources, _ := result["resources"].([]interface{})
+		if len(resources) == 0 && scopeName != "" {
+			resources = []interface{}{map[string]interface{}{"kind": scopeKind, "name": scopeName, "namespace": scopeNamespace}}
+		}
+		for _, rawResource := range resources {
+			resource, ok := rawResource.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			kind, _ := resource["kind"].(string)
+			resourceName, _ := resource["name"].(string)
+			namespace, _ := resource["namespace"].(string)
+			violations = append(violations, Violation{
+				Policy:    baseline.Name,
+				Rule:      rule,
+				Severity:  baseline.Severity,
+				Kind:      kind,
+				Namespace: namespace,
+				Name:      resourceName,
+				Message:   message,
+			})
+		}
+	}
+	return violations
+}
+
+// auditGatekeeper reads audit violations from the status of the baseline constraints
+func (a *Auditor) auditGatekeeper(ctx context.Context, report *Report) error {
+	dynamicClient := a.client.GetDynamicClient()
+
+	for _, baseline := range BaselinePolicies {
+		gvr := schema.GroupVersionResource{
+			Group:    "constraints.gatekeeper.sh",
+			Version:  "v1beta1",
+			Resource: strings.ToLower(baseline.Kind),
+		}
+		constraints, err := dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to list %s constraints: %w", baseline.Kind, err)
+		}
+		report.Installed = true
+		report.Policies += len(constraints.Items)
+		if len(constraints.Items) > 0 {
+			report.Baselines = append(report.Baselines, baseline.Name)
+		}
+
+		for _, constraint := range constraints.Items {
+			violations, _, _ := unstructured.NestedSlice(constraint.Object, "status", "violations")
+			for _, raw := range violations {
+				violation, ok := raw.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				kind, _ := violation["kind"].(string)
+				name, _ := violation["name"].(string)
+				namespace, _ := violation["namespace"].(string)
+				message, _ := violation["message"].(string)
+				report.Violations = append(report.Violations, Violation{
+					Policy:    baseline.Name,
+					Severity:  baseline.Severity,
+					Kind:      kind,
+					Namespace: namespace,
+					Name:      name,
+					Message:   message,
+				})
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+)
+
+// Engine is the admission policy engine enforcing the baseline
+type Engine string
+
+const (
+	EngineKyverno    Engine = "kyverno"
+	EngineGatekeeper Engine = "gatekeeper"
+)
+
+// Mode controls whether violations are only reported or rejected
+type Mode string
+
+const (
+	ModeAudit   Mode = "audit"
+	ModeEnforce Mode = "enforce"
+)
+
+// ManagedByLabel marks resources generated by the bootstrap tool
+const ManagedByLabel = "app.kubernetes.io/managed-by"
+
+const managedBy = "homelab-bootstrap"
+
+// DefaultExcludedNamespaces are never evaluated by the baseline policies
+var DefaultExcludedNamespaces = []string{
+	"kube-system",
+	"kube-public",
+	"kube-node-lease",
+	"flux-system",
+	"kyverno",
+	"gatekeeper-system",
+	"istio-system",
+	"rook-ceph",
+	"falco",
+}
+
+// Baseline describes one curated policy of the baseline set
+type Baseline struct {
+	Name        string
+	Kind        string
+	Title       string
+	Severity    string
+	Message     string
+	Remediation string
+	// Compliance is the compliance check the policy reports on, met when no resource violates it
+	Compliance  string
+	kyvernoRule string
+	rego        string
+}
+
+// BaselinePolicies is the curated baseline policy set
+var BaselinePolicies = []Baseline{
+	{
+		Name:        "baseline-disallow-privileged",
+		Kind:        "K8sBaselinePrivileged",
+		Title:       "Disallow Privileged Containers",
+		Severity:    "high",
+		Message:     "Privileged mode is not allowed.",
+		Remediation: "Remove securityContext.privileged or set it to false",
+		Compliance:  "cis_pod_security",
+		kyvernoRule: `        pattern:
+          spec:
+            =(ephemeralContainers):
+              - =(securityContext):
+                  =(privileged): "false"
+            =(initContainers):
+              - =(securityContext):
+                  =(privileged): "false"
+            containers:
+              - =(securityContext):
+                  =(privileged): "false"`,
+		rego: `package k8sbaselineprivileged
+
+violation[{"msg": msg}] {
+  c := input_containers[_]
+  c.securityContext.privileged
+  msg := sprintf("Privileged container is not allowed: %v", [c.name])
+}
+
+input_containers[c] { c := input.review.object.spec.containers[_] }
+input_containers[c] { c := input.review.object.spec.initContainers[_] }
+input_containers[c] { c := input.review.object.spec.ephemeralContainers[_] }`,
+	},
+	{
+		Name:        "baseline-require-requests-limits",
+		Kind:        "K8sBaselineResources",
+		Title:       "Require Requests and Limits",
+		Severity:    "medium",
+		Message:     "CPU and memory requests and a memory limit are required.",
+		Remediation: "Set resources.requests.cpu, resources.requests.memory and resources.limits.memory on every container",
+		Compliance:  "cis_resource_limits",
+		kyvernoRule: `        pattern:
+          spec:
+            containers:
+              - resources:
+                  requests:
+                    memory: "?*"
+                    cpu: "?*"
+                  limits:
+                    memory: "?*"`,
+		rego: `package k8sbaselineresources
+
+violation[{"msg": msg}] {
+  c := input.review.object.spec.containers[_]
+  missing(c)
+  msg := sprintf("Container %v must set cpu/memory requests and a memory limit", [c.name])
+}
+
+missing(c) { not c.resources.requests.cpu }
+missing(c) { not c.resources.requests.memory }
+missing(c) { not c.resources.limits.memory }`,
+	},
+	{
+		Name:        "baseline-restrict-host-path",
+		Kind:        "K8sBaselineHostPath",
+		Title:       "Restrict hostPath Volumes",
+		Severity:    "high",
+		Message:     "hostPath volumes are not allowed.",
+		Remediation: "Replace hostPath volumes with PersistentVolumeClaims or emptyDir",
+		Compliance:  "cis_pod_security",
+		kyvernoRule: `        pattern:
+          spec:
+            =(volumes):
+              - X(hostPath): "null"`,
+		rego: `package k8sbaselinehostpath
+
+violation[{"msg": msg}] {
+  v := input.review.object.spec.volumes[_]
+  v.hostPath
+  msg := sprintf("hostPath volume %v is not allowed", [v.name])
+}`,
+	},
+	{
+		// The allowed registries come from the configuration, see withRegistries
+		Name:        RestrictRegistries,
+		Kind:        "K8sBaselineRegistries",
+		Title:       "Restrict Image Registries",
+		Severity:    "medium",
+		Message:     "Images must come from an allowed registry.",
+		Remediation: "Pull the image through an allowed registry or add it to security.policy_engine.allowed_registries",
+		Compliance:  "cis_image_provenance",
+	},
+}
+
+// RestrictRegistries is the baseline policy only generated when allowed registries are configured
+const RestrictRegistries = "baseline-restrict-registries"
+
+// withRegistries returns the registry policy admitting only images under registries
+func (b Baseline) withRegistries(registries []string) Baseline {
+	patterns := make([]string, 0, len(registries))
+	prefixes := make([]string, 0, len(registries))
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(registry, "/") + "/"
+		patterns = append(patterns, registry+"*")
+		prefixes = append(prefixes, fmt.Sprintf("%q", registry))
+	}
+	pattern := strings.Join(patterns, " | ")
+
+	b.kyvernoRule = fmt.Sprintf(`        pattern:
+          spec:
+            =(ephemeralContainers):
+              - image: %[1]q
+            =(initContainers):
+              - image: %[1]q
+            containers:
+              - image: %[1]q`, pattern)
+	b.rego = fmt.Sprintf(`package k8sbaselineregistries
+
+registries := [%s]
+
+violation[{"msg": msg}] {
+  c := input_containers[_]
+  not allowed(c.image)
+  msg := sprintf("Image %%v of container %%v is not from an allowed registry", [c.image, c.name])
+}
+
+allowed(image) { startswith(image, registries[_]) }
+
+input_containers[c] { c := input.review.object.spec.containers[_] }
+input_containers[c] { c := input.review.object.spec.initContainers[_] }
+input_containers[c] { c := input.review.object.spec.ephemeralContainers[_] }`, strings.Join(prefixes, ", "))
+	return b
+}
+
+// SelectBaselines returns the baseline policies named in names, all of them when empty. Names may omit
+// the baseline- prefix. The registry policy is left out unless registries are allowed.
+func SelectBaselines(names, registries []string) ([]Baseline, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		baseline, ok := IsBaseline(name)
+		if !ok {
+			baseline, ok = IsBaseline("baseline-" + name)
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown baseline policy %q", name)
+		}
+		wanted[baseline.Name] = true
+	}
+
+	var selected []Baseline
+	for _, baseline := range BaselinePolicies {
+		if len(wanted) > 0 && !wanted[baseline.Name] {
+			continue
+		}
+		if baseline.Name == RestrictRegistries {
+			if len(registries) == 0 {
+				if wanted[baseline.Name] {
+					return nil, fmt.Errorf("%s needs security.policy_engine.allowed_registries", baseline.Name)
+				}
+				continue
+			}
+			baseline = baseline.withRegistries(registries)
+		}
+		selected = append(selected, baseline)
+	}
+	return selected, nil
+}
+
+// ParseEngine validates an engine name, defaulting to Kyverno
+func ParseEngine(value string) (Engine, error) {
+	switch Engine(strings.ToLower(strings.TrimSpace(value))) {
+	case "", EngineKyverno:
+		return EngineKyverno, nil
+	case EngineGatekeeper:
+		return EngineGatekeeper, nil
+	default:
+		return "", fmt.Errorf("unknown policy engine %q (expected kyverno or gatekeeper)", value)
+	}
+}
+
+// ParseMode validates a mode name, defaulting to audit
+func ParseMode(value string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(value))) {
+	case "", ModeAudit:
+		return ModeAudit, nil
+	case ModeEnforce:
+		return ModeEnforce, nil
+	default:
+		return "", fmt.Errorf("unknown policy mode %q (expected audit or enforce)", value)
+	}
+}
+
+// IsBaseline reports whether name is one of the baseline policies
+func IsBaseline(name string) (Baseline, bool) {
+	for _, policy := range BaselinePolicies {
+		if policy.Name == name || strings.EqualFold(policy.Kind, name) {
+			return policy, true
+		}
+	}
+	return Baseline{}, false
+}
+
+// renderKyverno renders the baseline policy as a Kyverno ClusterPolicy
+func (b Baseline) renderKyverno(mode Mode, excluded []string) string {
+	action := "Audit"
+	if mode == ModeEnforce {
+		action = "Enforce"
+	}
+	return fmt.Sprintf(`apiVersion: kyverno.io/v1
+kind: ClusterPolicy
+metadata:
+  name: %s
+  labels:
+    %s: %s
+  annotations:
+    policies.kyverno.io/title: %s
+    policies.kyverno.io/severity: %s
+spec:
+  validationFailureAction: %s
+  background: true
+  rules:
+    - name: %s
+      match:
+        any:
+          - resources:
+              kinds:
+                - Pod
+      exclude:
+        any:
+          - resources:
+              namespaces:
+%s
+      validate:
+        message: %q
+%s
+`, b.Name, ManagedByLabel, managedBy, b.Title, b.Severity, action, strings.TrimPrefix(b.Name, "baseline-"), gitops.YAMLList(excluded, 16), b.Message, b.kyvernoRule)
+}
+
+// renderGatekeeperTemplate renders the baseline policy as a Gatekeeper ConstraintTemplate
+func (b Baseline) renderGatekeeperTemplate() string {
+	return fmt.Sprintf(`apiVersion: templates.gatekeeper.sh/v1
+kind: ConstraintTemplate
+metadata:
+  name: %s
+  labels:
+    %s: %s
+  annotations:
+    metadata.gatekeeper.sh/title: %s
+spec:
+  crd:
+    spec:
+      names:
+        kind: %s
+  targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+%s
+`, strings.ToLower(b.Kind), ManagedByLabel, managedBy, b.Title, b.Kind, gitops.Indent(b.rego, 8))
+}
+
+// renderGatekeeperConstraint renders the constraint instantiating the baseline template
+func (b Baseline) renderGatekeeperConstraint(mode Mode, excluded []string) string {
+	action := "dryrun"
+	if mode == ModeEnforce {
+		action = "deny"
+	}
+	return fmt.Sprintf(`apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: %s
+metadata:
+  name: %s
+  labels:
+    %s: %s
+  annotations:
+    policies.homelab/severity: %s
+spec:
+  enforcementAction: %s
+  match:
+    kinds:
+      - apiGroups: [""]
+        kinds: ["Pod"]
+    excludedNamespaces:
+%s
+`, b.Kind, b.Name, ManagedByLabel, managedBy, b.Severity, action, gitops.YAMLList(excluded, 6))
+}
@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+)
+
+// Options configures the generated baseline policy set
+type Options struct {
+	Engine             Engine
+	Mode               Mode
+	ExcludedNamespaces []string
+	// Policies selects baseline policies by name, all of them when empty
+	Policies []string
+	// AllowedRegistries are the registry prefixes images must start with, none restricts nothing
+	AllowedRegistries []string
+}
+
+// engineCharts are the Helm charts installing each policy engine
+var engineCharts = map[Engine]gitops.HelmChart{
+	EngineKyverno: {
+		Repository: "kyverno",
+		URL:        "https://kyverno.github.io/kyverno/",
+		Chart:      "kyverno",
+		Version:    "3.5.2",
+		Namespace:  "kyverno",
+	},
+	EngineGatekeeper: {
+		Repository: "gatekeeper",
+		URL:        "https://open-policy-agent.github.io/gatekeeper/charts",
+		Chart:      "gatekeeper",
+		Version:    "3.20.1",
+		Namespace:  "gatekeeper-system",
+	},
+}
+
+// Generator writes the policy engine and baseline policies into the GitOps repository
+type Generator struct {
+	projectRoot string
+	cluster     string
+	opts        Options
+}
+
+// NewGenerator creates a new generator for cluster under projectRoot
+func NewGenerator(projectRoot, cluster string, opts Options) *Generator {
+	if opts.Engine == "" {
+		opts.Engine = EngineKyverno
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeAudit
+	}
+	if len(opts.ExcludedNamespaces) == 0 {
+		opts.ExcludedNamespaces = DefaultExcludedNamespaces
+	}
+	return &Generator{
+		projectRoot: projectRoot,
+		cluster:     cluster,
+		opts:        opts,
+	}
+}
+
+// Generate writes the policy manifests and returns the paths it wrote, relative to the project root
+func (g *Generator) Generate() ([]string, error) {
+	clusterDir := filepath.Join(g.projectRoot, "kubernetes", g.cluster)
+	if _, err := os.Stat(clusterDir); err != nil {
+		return nil, fmt.Errorf("GitOps directory for %s not found: %w", g.cluster, err)
+	}
+
+	baselines, err := SelectBaselines(g.opts.Policies, g.opts.AllowedRegistries)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]string{}
+	policiesDir := filepath.Join(clusterDir, "policies")
+
+	chart := engineCharts[g.opts.Engine]
+	installEngine := !gitops.ChartInstalled(clusterDir, chart.Chart, policiesDir)
+	if installEngine {
+		files[filepath.Join(policiesDir, "engine", "helm-repository.yaml")] = chart.RenderRepository()
+		files[filepath.Join(policiesDir, "engine", "helm-release.yaml")] = chart.RenderRelease("")
+		files[filepath.Join(policiesDir, "engine", "kustomization.yaml")] = gitops.RenderKustomization([]string{"helm-repository.yaml", "helm-release.yaml"})
+	} else {
+		log.Info("Policy engine already managed by the GitOps repository", "engine", g.opts.Engine, "cluster", g.cluster)
+	}
+
+	var kustomizations []gitops.Kustomization
+	dependsOn := gitops.FoundationKustomization(g.cluster)
+	if installEngine {
+		kustomizations = append(kustomizations, gitops.Kustomization{
+			Name:      gitops.Name(g.cluster, "policy-engine"),
+			Path:      "policies/engine",
+			DependsOn: dependsOn,
+		})
+		dependsOn = gitops.Name(g.cluster, "policy-engine")
+	}
+
+	switch g.opts.Engine {
+	case EngineGatekeeper:
+		var templates, constraints []string
+		for _, policy := range baselines {
+			templateFile := policy.Name + "-template.yaml"
+			constraintFile := policy.Name + ".yaml"
+			files[filepath.Join(policiesDir, "baseline", "templates", templateFile)] = policy.renderGatekeeperTemplate()
+			files[filepath.Join(policiesDir, "baseline", "constraints", constraintFile)] = policy.renderGatekeeperConstraint(g.opts.Mode, g.opts.ExcludedNamespaces)
+			templates = append(templates, templateFile)
+			constraints = append(constraints, constraintFile)
+		}
+		files[filepath.Join(policiesDir, "baseline", "templates", "kustomization.yaml")] = gitops.RenderKustomization(templates)
+		files[filepath.Join(policiesDir, "baseline", "constraints", "kustomization.yaml")] = gitops.RenderKustomization(constraints)
+
+		// Constraints can only be applied once Gatekeeper has created the CRDs for their templates
+		templatesName := gitops.Name(g.cluster, "baseline-policy-templates")
+		kustomizations = append(kustomizations,
+			gitops.Kustomization{Name: templatesName, Path: "policies/baseline/templates", DependsOn: dependsOn},
+			gitops.Kustomization{Name: gitops.Name(g.cluster, "baseline-policies"), Path: "policies/baseline/constraints", DependsOn: templatesName},
+		)
+	default:
+		var resources []string
+		for _, policy := range baselines {
+			file := policy.Name + ".yaml"
+			files[filepath.Join(policiesDir, "baseline", file)] = policy.renderKyverno(g.opts.Mode, g.opts.ExcludedNamespaces)
+			resources = append(resources, file)
+		}
+		files[filepath.Join(policiesDir, "baseline", "kustomization.yaml")] = gitops.RenderKustomization(resources)
+
+		kustomizations = append(kustomizations, gitops.Kustomization{
+			Name:      gitops.Name(g.cluster, "baseline-policies"),
+			Path:      "policies/baseline",
+			DependsOn: dependsOn,
+		})
+	}
+
+	files[filepath.Join(clusterDir, "policies.yaml")] = gitops.RenderKustomizations(g.cluster, kustomizations)
+
+	// The policies directory is owned by the generator, so stale files from a previous engine are dropped
+	if err := os.RemoveAll(policiesDir); err != nil {
+		return nil, fmt.Errorf("failed to clean %s: %w", policiesDir, err)
+	}
+
+	written, err := gitops.WriteFiles(g.projectRoot, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rootKustomization := filepath.Join(clusterDir, "kustomization.yaml")
+	added, err := gitops.AddResource(rootKustomization, "policies.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		written = append(written, gitops.Relative(g.projectRoot, rootKustomization))
+	}
+
+	log.Info("Generated baseline policies",
+		"engine", g.opts.Engine,
+		"mode", g.opts.Mode,
+		"cluster", g.cluster,
+		"policies", len(baselines),
+		"files", len(written))
+
+	return written, nil
+}
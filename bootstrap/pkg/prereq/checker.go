@@ -2,6 +2,7 @@ package prereq
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -57,6 +58,34 @@ func (s CheckStatus) String() string {
 	}
 }
 
+// MarshalText encodes the status as passed, failed or warning for structured output
+func (s CheckStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case CheckPassed:
+		return []byte("passed"), nil
+	case CheckFailed:
+		return []byte("failed"), nil
+	case CheckWarning:
+		return []byte("warning"), nil
+	}
+	return nil, fmt.Errorf("unknown check status %d", int(s))
+}
+
+// MarshalJSON encodes the result with its error as a message
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	var message string
+	if r.Error != nil {
+		message = r.Error.Error()
+	}
+	return json.Marshal(struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description"`
+		Status      CheckStatus `json:"status"`
+		Error       string      `json:"error,omitempty"`
+		Details     string      `json:"details,omitempty"`
+	}{r.Name, r.Description, r.Status, message, r.Details})
+}
+
 // CheckAll performs all prerequisite checks
 func (c *Checker) CheckAll(ctx context.Context) ([]CheckResult, error) {
 	var results []CheckResult
@@ -84,7 +113,11 @@ func (c *Checker) CheckAll(ctx context.Context) ([]CheckResult, error) {
 	results = append(results, c.checkVaultConfig())
 
 	// Cluster connectivity
-	results = append(results, c.checkClusterConnectivity(ctx))
+	connectivity, client := c.checkClusterConnectivity(ctx)
+	results = append(results, connectivity)
+	if client != nil {
+		results = append(results, NewConnectivityChecker(client.GetConfig()).CheckAll(ctx)...)
+	}
 
 	return results, nil
 }
@@ -290,21 +323,23 @@ func (c *Checker) checkVaultConfig() CheckResult {
 	}
 }
 
-// checkClusterConnectivity verifies cluster is accessible
-func (c *Checker) checkClusterConnectivity(ctx context.Context) CheckResult {
-	var kubeconfig string
+// checkClusterConnectivity verifies cluster is accessible, returning the client once one could be built
+func (c *Checker) checkClusterConnectivity(ctx context.Context) (CheckResult, *k8s.Client) {
+	var kubeconfig, kubeContext string
 
 	if c.config.Homelab != nil {
 		kubeconfig = c.config.Homelab.Cluster.KubeConfig
+		kubeContext = c.config.Homelab.Cluster.KubeContext
 	} else if c.config.NAS != nil {
 		kubeconfig = c.config.NAS.Cluster.KubeConfig
+		kubeContext = c.config.NAS.Cluster.KubeContext
 	} else {
 		return CheckResult{
 			Name:        "cluster-connectivity",
 			Description: "Kubernetes cluster connectivity",
 			Status:      CheckFailed,
 			Error:       fmt.Errorf("no cluster configuration found"),
-		}
+		}, nil
 	}
 
 	// Check if kubeconfig file exists
@@ -315,11 +350,11 @@ func (c *Checker) checkClusterConnectivity(ctx context.Context) CheckResult {
 			Status:      CheckWarning,
 			Error:       fmt.Errorf("kubeconfig not found at %s", kubeconfig),
 			Details:     "Cluster may not be created yet",
-		}
+		}, nil
 	}
 
 	// Try to connect to cluster
-	client, err := k8s.NewClient(kubeconfig)
+	client, err := k8s.NewClientWithContext(kubeconfig, kubeContext)
 	if err != nil {
 		return CheckResult{
 			Name:        "cluster-connectivity",
@@ -327,7 +362,7 @@ func (c *Checker) checkClusterConnectivity(ctx context.Context) CheckResult {
 			Status:      CheckWarning,
 			Error:       fmt.Errorf("failed to create k8s client: %w", err),
 			Details:     fmt.Sprintf("Kubeconfig: %s", kubeconfig),
-		}
+		}, nil
 	}
 
 	if err := client.IsReady(ctx); err != nil {
@@ -337,7 +372,7 @@ func (c *Checker) checkClusterConnectivity(ctx context.Context) CheckResult {
 			Status:      CheckWarning,
 			Error:       fmt.Errorf("cluster not ready: %w", err),
 			Details:     "Cluster may be starting up",
-		}
+		}, client
 	}
 
 	// Get node count
@@ -348,7 +383,7 @@ func (c *Checker) checkClusterConnectivity(ctx context.Context) CheckResult {
 			Description: "Kubernetes cluster connectivity",
 			Status:      CheckPassed,
 			Details:     "Cluster accessible (node count unavailable)",
-		}
+		}, client
 	}
 
 	return CheckResult{
@@ -356,5 +391,5 @@ func (c *Checker) checkClusterConnectivity(ctx context.Context) CheckResult {
 		Description: "Kubernetes cluster connectivity",
 		Status:      CheckPassed,
 		Details:     fmt.Sprintf("Cluster accessible with %d nodes", len(nodes)),
-	}
+	}, client
 }
@@ -0,0 +1,370 @@
+package prereq
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	latencySamples = 5
+	// latencyWarning is the round-trip time above which waits and watches start timing out
+	latencyWarning = 300 * time.Millisecond
+	// clockSkewWarning is the offset from the API server clock worth fixing before certificates are issued
+	clockSkewWarning = 30 * time.Second
+	probeTimeout     = 10 * time.Second
+)
+
+// proxyVariables are the environment variables Go and kubectl honour for outgoing requests
+var proxyVariables = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"}
+
+// ConnectivityChecker inspects the network path between this workstation and the API server
+type ConnectivityChecker struct {
+	config *rest.Config
+	now    func() time.Time
+}
+
+// NewConnectivityChecker creates a checker for the API server of cfg
+func NewConnectivityChecker(cfg *rest.Config) *ConnectivityChecker {
+	return &ConnectivityChecker{config: cfg, now: time.Now}
+}
+
+// CheckAll runs the proxy, TLS and clock, latency and streaming checks
+func (c *ConnectivityChecker) CheckAll(ctx context.Context) []CheckResult {
+	server, err := url.Parse(c.config.Host)
+	if err != nil || server.Host == "" {
+		return []CheckResult{{
+			Name:        "api-server",
+			Description: "API server address",
+			Status:      CheckFailed,
+			Error:       fmt.Errorf("invalid API server address %q", c.config.Host),
+		}}
+	}
+	if server.Scheme == "" {
+		server.Scheme = "https"
+	}
+
+	results := []CheckResult{c.checkProxy(server)}
+	tlsResult := c.checkTLSAndClock(ctx, server)
+	results = append(results, tlsResult)
+	if tlsResult.Status == CheckFailed {
+		// Every later request would fail the same handshake
+		return results
+	}
+	results = append(results, c.checkLatency(ctx, server))
+	results = append(results, c.checkStreaming(ctx, server))
+	return results
+}
+
+// checkProxy reports proxies that requests to the API server go through
+func (c *ConnectivityChecker) checkProxy(server *url.URL) CheckResult {
+	result := CheckResult{Name: "api-proxy", Description: "HTTP(S) proxy on the path to the API server", Status: CheckPassed}
+
+	var set []string
+	for _, name := range proxyVariables {
+		if value := os.Getenv(name); value != "" {
+			set = append(set, name+"="+redactProxy(value))
+		}
+	}
+
+	proxy, err := c.proxyFor(server)
+	switch {
+	case err != nil:
+		result.Status = CheckWarning
+		result.Error = fmt.Errorf("failed to resolve proxy: %w", err)
+	case proxy != nil:
+		result.Status = CheckWarning
+		result.Error = fmt.Errorf("requests to %s go through proxy %s", server.Host, redactProxy(proxy.String()))
+		result.Details = fmt.Sprintf("Proxies often buffer watches and drop exec upgrades, add %s to NO_PROXY unless the proxy is required", server.Hostname())
+	case len(set) > 0:
+		result.Details = fmt.Sprintf("API server bypasses the proxy (%s)", strings.Join(set, ", "))
+	default:
+		result.Details = "No proxy configured"
+	}
+	return result
+}
+
+// proxyFor returns the proxy used for the API server, preferring the kubeconfig proxy-url over the environment
+func (c *ConnectivityChecker) proxyFor(server *url.URL) (*url.URL, error) {
+	if c.config.Proxy != nil {
+		return c.config.Proxy(&http.Request{URL: server})
+	}
+	return httpproxy.FromEnvironment().ProxyFunc()(server)
+}
+
+// checkTLSAndClock performs a handshake, telling clock skew apart from genuinely expired certificates
+func (c *ConnectivityChecker) checkTLSAndClock(ctx context.Context, server *url.URL) CheckResult {
+	result := CheckResult{Name: "api-tls", Description: "API server TLS and clock skew", Status: CheckPassed}
+
+	client, err := c.httpClient(false)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Error = err
+		return result
+	}
+	sent := c.now()
+	resp, err := c.get(ctx, client, server, "/version")
+	if err != nil {
+		var invalid x509.CertificateInvalidError
+		if !errors.As(err, &invalid) || invalid.Reason != x509.Expired {
+			result.Status = CheckFailed
+			result.Error = fmt.Errorf("failed to reach API server: %w", err)
+			return result
+		}
+
+		result.Status = CheckFailed
+		skew, skewErr := c.unverifiedSkew(ctx, server)
+		validity := fmt.Sprintf("certificate %q is valid from %s to %s", invalid.Cert.Subject.CommonName,
+			invalid.Cert.NotBefore.UTC().Format(time.RFC3339), invalid.Cert.NotAfter.UTC().Format(time.RFC3339))
+		switch {
+		case skewErr != nil:
+			result.Error = fmt.Errorf("API server certificate rejected as expired or not yet valid, clock skew unknown: %w", skewErr)
+		case absDuration(skew) > clockSkewWarning:
+			result.Error = fmt.Errorf("local clock is %s off the API server, which invalidates its certificate", formatSkew(skew))
+			result.Details = "Sync the workstation clock (NTP), " + validity
+			return result
+		default:
+			result.Error = fmt.Errorf("API server certificate is expired or not yet valid and clocks agree")
+		}
+		result.Details = validity + ", rotate the API server certificates"
+		return result
+	}
+	resp.Body.Close()
+
+	skew, ok := serverSkew(resp, sent, c.now())
+	switch {
+	case !ok:
+		result.Details = "TLS handshake succeeded, API server sent no Date header"
+	case absDuration(skew) > clockSkewWarning:
+		result.Status = CheckWarning
+		result.Error = fmt.Errorf("local clock is %s off the API server", formatSkew(skew))
+		result.Details = "Certificates issued during bootstrap may be rejected as not yet valid, sync the workstation clock (NTP)"
+	default:
+		result.Details = fmt.Sprintf("TLS handshake succeeded, clock skew %s", formatSkew(skew))
+	}
+	return result
+}
+
+// ClockSkew returns how far the local clock is ahead of the API server, negative when it is behind
+func (c *ConnectivityChecker) ClockSkew(ctx context.Context) (time.Duration, error) {
+	server, err := url.Parse(c.config.Host)
+	if err != nil || server.Host == "" {
+		return 0, fmt.Errorf("invalid API server address %q", c.config.Host)
+	}
+	if server.Scheme == "" {
+		server.Scheme = "https"
+	}
+	return c.unverifiedSkew(ctx, server)
+}
+
+// unverifiedSkew reads the API server clock without verifying its certificate, sending no credentials
+func (c *ConnectivityChecker) unverifiedSkew(ctx context.Context, server *url.URL) (time.Duration, error) {
+	transport := &http.Transport{
+		Proxy:           c.config.Proxy,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	if transport.Proxy == nil {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	client := &http.Client{Transport: transport, Timeout: probeTimeout}
+
+	sent := c.now()
+	resp, err := c.get(ctx, client, server, "/version")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	skew, ok := serverSkew(resp, sent, c.now())
+	if !ok {
+		return 0, fmt.Errorf("API server sent no Date header")
+	}
+	return skew, nil
+}
+
+// checkLatency measures API round trips against the readiness endpoint, which also reports API health
+func (c *ConnectivityChecker) checkLatency(ctx context.Context, server *url.URL) CheckResult {
+	result := CheckResult{Name: "api-latency", Description: "API server round-trip latency and health", Status: CheckPassed}
+
+	client, err := c.httpClient(false)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Error = err
+		return result
+	}
+
+	var samples []time.Duration
+	for i := 0; i < latencySamples; i++ {
+		start := c.now()
+		resp, err := c.get(ctx, client, server, "/readyz")
+		if err != nil {
+			result.Status = CheckFailed
+			result.Error = fmt.Errorf("failed to reach API server: %w", err)
+			return result
+		}
+		resp.Body.Close()
+		samples = append(samples, c.now().Sub(start))
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusUnauthorized, http.StatusForbidden:
+			// Health endpoints can be restricted, latency stays meaningful
+		default:
+			result.Status = CheckFailed
+			result.Error = fmt.Errorf("API server not ready: /readyz returned %s", resp.Status)
+			result.Details = "Run kubectl get --raw='/readyz?verbose' for the failing checks"
+			return result
+		}
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	median := samples[len(samples)/2]
+	result.Details = fmt.Sprintf("min %s, median %s, max %s over %d requests",
+		samples[0].Round(time.Millisecond), median.Round(time.Millisecond), samples[len(samples)-1].Round(time.Millisecond), len(samples))
+	if median > latencyWarning {
+		result.Status = CheckWarning
+		result.Error = fmt.Errorf("median API latency %s exceeds %s", median.Round(time.Millisecond), latencyWarning)
+	}
+	return result
+}
+
+// checkStreaming upgrades a watch to a websocket, which exec, logs -f and port-forward rely on
+func (c *ConnectivityChecker) checkStreaming(ctx context.Context, server *url.URL) CheckResult {
+	result := CheckResult{Name: "api-streaming", Description: "Websocket upgrades for exec and port-forward", Status: CheckPassed}
+
+	// Upgrades need HTTP/1.1, the default transport negotiates HTTP/2
+	client, err := c.httpClient(true)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Error = err
+		return result
+	}
+
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		result.Status = CheckWarning
+		result.Error = fmt.Errorf("failed to generate websocket key: %w", err)
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	target := *server
+	target.Path = strings.TrimSuffix(server.Path, "/") + "/api/v1/namespaces"
+	target.RawQuery = "watch=true&timeoutSeconds=1&limit=1"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		result.Status = CheckWarning
+		result.Error = err
+		return result
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Error = fmt.Errorf("websocket upgrade failed: %w", err)
+		return result
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusSwitchingProtocols:
+		result.Details = "Websocket upgrade accepted"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		result.Status = CheckWarning
+		result.Error = fmt.Errorf("cannot watch namespaces to test upgrades: %s", resp.Status)
+	default:
+		result.Status = CheckFailed
+		result.Error = fmt.Errorf("websocket upgrade refused with %s", resp.Status)
+		result.Details = "An intermediate proxy strips Upgrade headers, kubectl exec and port-forward will hang or fail"
+	}
+	return result
+}
+
+// httpClient builds an authenticated client for the API server, restricted to HTTP/1.1 when upgrades are needed
+func (c *ConnectivityChecker) httpClient(http1 bool) (*http.Client, error) {
+	tlsConfig, err := rest.TLSConfigFor(c.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build TLS config: %w", err)
+	}
+	transport := &http.Transport{
+		Proxy:             c.config.Proxy,
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: !http1,
+	}
+	if transport.Proxy == nil {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	if http1 {
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if tlsConfig != nil {
+			tlsConfig.NextProtos = []string{"http/1.1"}
+		}
+	}
+	// Wrappers add the kubeconfig credentials and the read-only guard
+	rt, err := rest.HTTPWrappersForConfig(c.config, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build API transport: %w", err)
+	}
+	return &http.Client{Transport: rt, Timeout: probeTimeout}, nil
+}
+
+func (c *ConnectivityChecker) get(ctx context.Context, client *http.Client, server *url.URL, path string) (*http.Response, error) {
+	target := *server
+	target.Path = strings.TrimSuffix(server.Path, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// serverSkew returns how far the local clock is ahead of the server, taking the request midpoint as reference
+func serverSkew(resp *http.Response, sent, received time.Time) (time.Duration, bool) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+	midpoint := sent.Add(received.Sub(sent) / 2)
+	return midpoint.Sub(date).Truncate(time.Second), true
+}
+
+func formatSkew(skew time.Duration) string {
+	if skew < 0 {
+		return absDuration(skew).String() + " behind"
+	}
+	return skew.String() + " ahead"
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// redactProxy hides the credentials of a proxy URL
+func redactProxy(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.User == nil {
+		return value
+	}
+	parsed.User = url.User("***")
+	return parsed.String()
+}
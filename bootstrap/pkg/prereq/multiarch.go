@@ -0,0 +1,494 @@
+package prereq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	archLabel        = "kubernetes.io/arch"
+	registryWorkers  = 8
+	fallbackArch     = "amd64"
+	sourceCluster    = "cluster"
+	sourceRepository = "repository"
+)
+
+// ArchWorkload is a resource running container images, live or declared in the GitOps repository
+type ArchWorkload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Source    string
+	Images    []string
+	// PinnedArch is the architecture the workload is constrained to, empty when it may land anywhere
+	PinnedArch string
+}
+
+// ID identifies the workload across sources
+func (w ArchWorkload) ID() string {
+	return fmt.Sprintf("%s/%s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// ArchFinding is a workload with images lacking the target architecture
+type ArchFinding struct {
+	Workload ArchWorkload
+	Missing  map[string][]string
+	Unknown  map[string]string
+	// Blocked is true when the workload is not pinned and would be scheduled onto target nodes
+	Blocked bool
+	Patch   string
+}
+
+// ArchReport summarizes the multi-arch readiness of the workloads for one architecture
+type ArchReport struct {
+	Arch        string
+	TargetNodes []string
+	Workloads   int
+	Images      int
+	Findings    []ArchFinding
+}
+
+// Blocked returns the findings that would fail to schedule onto target nodes
+func (r *ArchReport) Blocked() []ArchFinding {
+	var blocked []ArchFinding
+	for _, finding := range r.Findings {
+		if finding.Blocked {
+			blocked = append(blocked, finding)
+		}
+	}
+	return blocked
+}
+
+// ArchChecker inspects workload images for architectures missing from their manifest lists
+type ArchChecker struct {
+	client      *k8s.Client
+	registry    *registry.Client
+	projectRoot string
+}
+
+// NewArchChecker creates a new checker; client may be nil to inspect the repository only
+func NewArchChecker(client *k8s.Client, projectRoot string) *ArchChecker {
+	return &ArchChecker{
+		client:      client,
+		registry:    registry.NewClient(),
+		projectRoot: projectRoot,
+	}
+}
+
+// Check reports which workloads run images unavailable for arch
+func (a *ArchChecker) Check(ctx context.Context, arch string) (*ArchReport, error) {
+	report := &ArchReport{Arch: arch}
+	workloads := make(map[string]ArchWorkload)
+
+	if a.client != nil {
+		nodes, err := a.client.GetClientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes: %w", err)
+		}
+		for _, node := range nodes.Items {
+			if node.Status.NodeInfo.Architecture == arch {
+				report.TargetNodes = append(report.TargetNodes, node.Name)
+			}
+		}
+
+		live, err := a.clusterWorkloads(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, workload := range live {
+			workloads[workload.ID()] = workload
+		}
+	}
+
+	if a.projectRoot != "" {
+		declared, err := a.repositoryWorkloads()
+		if err != nil {
+			return nil, err
+		}
+		for _, workload := range declared {
+			if _, exists := workloads[workload.ID()]; !exists {
+				workloads[workload.ID()] = workload
+			}
+		}
+	}
+
+	images := make(map[string]bool)
+	for _, workload := range workloads {
+		for _, image := range workload.Images {
+			images[image] = true
+		}
+	}
+	report.Workloads = len(workloads)
+	report.Images = len(images)
+
+	platforms, failures := a.resolvePlatforms(ctx, images)
+
+	ids := make([]string, 0, len(workloads))
+	for id := range workloads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		workload := workloads[id]
+		finding := ArchFinding{Workload: workload, Missing: make(map[string][]string), Unknown: make(map[string]string)}
+		for _, image := range workload.Images {
+			if err, failed := failures[image]; failed {
+				finding.Unknown[image] = err.Error()
+				continue
+			}
+			if !registry.Supports(platforms[image], arch) {
+				finding.Missing[image] = architectures(platforms[image])
+			}
+		}
+		if len(finding.Missing) == 0 && len(finding.Unknown) == 0 {
+			continue
+		}
+		if len(finding.Missing) > 0 && workload.PinnedArch != "" && workload.PinnedArch != arch {
+			// Already kept off the target nodes
+			continue
+		}
+
+		finding.Blocked = len(finding.Missing) > 0
+		if finding.Blocked {
+			finding.Patch = nodeSelectorPatch(workload, pinTarget(finding.Missing))
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	return report, nil
+}
+
+// resolvePlatforms queries the registries for every image with a bounded number of workers
+func (a *ArchChecker) resolvePlatforms(ctx context.Context, images map[string]bool) (map[string][]registry.Platform, map[string]error) {
+	platforms := make(map[string][]registry.Platform, len(images))
+	failures := make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < registryWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range queue {
+				result, err := a.registry.Platforms(ctx, image)
+				mu.Lock()
+				if err != nil {
+					failures[image] = err
+				} else {
+					platforms[image] = result
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for image := range images {
+		queue <- image
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(failures) > 0 {
+		log.Debug("Some image manifests could not be inspected", "count", len(failures))
+	}
+	return platforms, failures
+}
+
+// clusterWorkloads lists the pod-owning resources of the cluster
+func (a *ArchChecker) clusterWorkloads(ctx context.Context) ([]ArchWorkload, error) {
+	clientset := a.client.GetClientset()
+	var workloads []ArchWorkload
+
+	deployments, err := clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, item := range deployments.Items {
+		workloads = append(workloads, podWorkload("Deployment", item.Namespace, item.Name, sourceCluster, &item.Spec.Template.Spec))
+	}
+
+	statefulSets, err := clientset.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, item := range statefulSets.Items {
+		workloads = append(workloads, podWorkload("StatefulSet", item.Namespace, item.Name, sourceCluster, &item.Spec.Template.Spec))
+	}
+
+	daemonSets, err := clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, item := range daemonSets.Items {
+		workloads = append(workloads, podWorkload("DaemonSet", item.Namespace, item.Name, sourceCluster, &item.Spec.Template.Spec))
+	}
+
+	cronJobs, err := clientset.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	for _, item := range cronJobs.Items {
+		workloads = append(workloads, podWorkload("CronJob", item.Namespace, item.Name, sourceCluster, &item.Spec.JobTemplate.Spec.Template.Spec))
+	}
+
+	return workloads, nil
+}
+
+// repositoryWorkloads parses the workloads and HelmReleases declared under kubernetes/
+func (a *ArchChecker) repositoryWorkloads() ([]ArchWorkload, error) {
+	root := filepath.Join(a.projectRoot, "kubernetes")
+	if _, err := os.Stat(root); err != nil {
+		return nil, nil
+	}
+
+	var workloads []ArchWorkload
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+
+		decoder := yaml.NewDecoder(file)
+		for {
+			var doc map[string]interface{}
+			if err := decoder.Decode(&doc); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				// Templated or non-Kubernetes YAML is not inspected
+				log.Debug("Skipping unparsable manifest", "path", path, "error", err)
+				break
+			}
+			if workload, ok := manifestWorkload(doc); ok {
+				workloads = append(workloads, workload)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workloads, nil
+}
+
+// manifestWorkload extracts the images of a declared workload or HelmRelease
+func manifestWorkload(doc map[string]interface{}) (ArchWorkload, bool) {
+	kind, _ := doc["kind"].(string)
+	metadata, _ := doc["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	if name == "" {
+		return ArchWorkload{}, false
+	}
+
+	var path []string
+	switch kind {
+	case "Deployment", "StatefulSet", "DaemonSet", "Job":
+		path = []string{"spec", "template", "spec"}
+	case "CronJob":
+		path = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	case "HelmRelease":
+		spec, _ := doc["spec"].(map[string]interface{})
+		if target, ok := spec["targetNamespace"].(string); ok && target != "" {
+			namespace = target
+		}
+		workload := ArchWorkload{Kind: kind, Namespace: namespace, Name: name, Source: sourceRepository}
+		images := make(map[string]bool)
+		collectValueImages(spec["values"], images, &workload.PinnedArch)
+		for image := range images {
+			workload.Images = append(workload.Images, image)
+		}
+		sort.Strings(workload.Images)
+		return workload, len(workload.Images) > 0
+	default:
+		return ArchWorkload{}, false
+	}
+
+	var node interface{} = doc
+	for _, key := range path {
+		parent, ok := node.(map[string]interface{})
+		if !ok {
+			return ArchWorkload{}, false
+		}
+		node = parent[key]
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return ArchWorkload{}, false
+	}
+	var spec corev1.PodSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return ArchWorkload{}, false
+	}
+	workload := podWorkload(kind, namespace, name, sourceRepository, &spec)
+	return workload, len(workload.Images) > 0
+}
+
+// collectValueImages finds image references and architecture pins in Helm values
+func collectValueImages(node interface{}, images map[string]bool, pinned *string) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if repository, ok := value["repository"].(string); ok && repository != "" {
+			image := repository
+			if registryHost, ok := value["registry"].(string); ok && registryHost != "" {
+				image = registryHost + "/" + repository
+			}
+			if tag, ok := value["tag"].(string); ok && tag != "" {
+				image += ":" + tag
+			}
+			images[image] = true
+		}
+		if image, ok := value["image"].(string); ok && strings.ContainsAny(image, "/:") {
+			images[image] = true
+		}
+		if selector, ok := value["nodeSelector"].(map[string]interface{}); ok {
+			if arch, ok := selector[archLabel].(string); ok {
+				*pinned = arch
+			}
+		}
+		for _, child := range value {
+			collectValueImages(child, images, pinned)
+		}
+	case []interface{}:
+		for _, child := range value {
+			collectValueImages(child, images, pinned)
+		}
+	}
+}
+
+// podWorkload builds a workload from a pod spec
+func podWorkload(kind, namespace, name, source string, spec *corev1.PodSpec) ArchWorkload {
+	workload := ArchWorkload{Kind: kind, Namespace: namespace, Name: name, Source: source, PinnedArch: pinnedArch(spec)}
+	seen := make(map[string]bool)
+	for _, container := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		if container.Image != "" && !seen[container.Image] {
+			seen[container.Image] = true
+			workload.Images = append(workload.Images, container.Image)
+		}
+	}
+	return workload
+}
+
+// pinnedArch returns the architecture a pod spec is restricted to by node selector or required affinity
+func pinnedArch(spec *corev1.PodSpec) string {
+	if arch, ok := spec.NodeSelector[archLabel]; ok {
+		return arch
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil || spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expression := range term.MatchExpressions {
+			if expression.Key == archLabel && expression.Operator == corev1.NodeSelectorOpIn && len(expression.Values) == 1 {
+				return expression.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// pinTarget picks the architecture every missing image supports, preferring amd64
+func pinTarget(missing map[string][]string) string {
+	counts := make(map[string]int)
+	for _, arches := range missing {
+		for _, arch := range arches {
+			counts[arch]++
+		}
+	}
+	if counts[fallbackArch] == len(missing) {
+		return fallbackArch
+	}
+	var candidates []string
+	for arch, count := range counts {
+		if count == len(missing) {
+			candidates = append(candidates, arch)
+		}
+	}
+	if len(candidates) == 0 {
+		return fallbackArch
+	}
+	sort.Strings(candidates)
+	return candidates[0]
+}
+
+// nodeSelectorPatch renders a patch keeping the workload on arch nodes
+func nodeSelectorPatch(workload ArchWorkload, arch string) string {
+	switch workload.Kind {
+	case "HelmRelease":
+		return fmt.Sprintf(`apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: %s
+spec:
+  values:
+    nodeSelector:
+      %s: %s`, workload.Name, archLabel, arch)
+	case "CronJob":
+		return fmt.Sprintf(`apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          nodeSelector:
+            %s: %s`, workload.Name, workload.Namespace, archLabel, arch)
+	default:
+		apiVersion := "apps/v1"
+		if workload.Kind == "Job" {
+			apiVersion = "batch/v1"
+		}
+		return fmt.Sprintf(`apiVersion: %s
+kind: %s
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  template:
+    spec:
+      nodeSelector:
+        %s: %s`, apiVersion, workload.Kind, workload.Name, workload.Namespace, archLabel, arch)
+	}
+}
+
+func architectures(platforms []registry.Platform) []string {
+	seen := make(map[string]bool)
+	var arches []string
+	for _, platform := range platforms {
+		if platform.OS != "" && platform.OS != "linux" {
+			continue
+		}
+		if !seen[platform.Architecture] {
+			seen[platform.Architecture] = true
+			arches = append(arches, platform.Architecture)
+		}
+	}
+	sort.Strings(arches)
+	return arches
+}
@@ -0,0 +1,135 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Type identifies what an event reports
+type Type string
+
+// Events the orchestrator publishes while a bootstrap runs
+const (
+	StepStarted   Type = "step_started"
+	StepProgress  Type = "step_progress"
+	StepCompleted Type = "step_completed"
+	Warning       Type = "warning"
+)
+
+// subscriberBuffer is how many events a subscriber can lag behind before new ones are dropped for it
+const subscriberBuffer = 1024
+
+// Event is a single progress update
+type Event struct {
+	Type Type
+	Step string
+	// Index is the 1-based position of Step among Total steps, zero outside a bootstrap step
+	Index int
+	Total int
+	// Percent is how far Step got, from 0 to 100, or -1 when the update carries only a message
+	Percent int
+	Message string
+	// Err is set on the StepCompleted event of a failed step
+	Err      error
+	Duration time.Duration
+	Time     time.Time
+}
+
+// Bus fans the published events out to every subscriber. Publishing never blocks: a subscriber that falls
+// too far behind misses events rather than stalling the bootstrap.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers []chan Event
+	closed      bool
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe returns a channel receiving the events published from now on, closed by Close
+func (b *Bus) Subscribe() <-chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subscribers = append(b.subscribers, ch)
+	return ch
+}
+
+// Publish sends event to the subscribers, reporting whether there was any
+func (b *Bus) Publish(event Event) bool {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed || len(b.subscribers) == 0 {
+		return false
+	}
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Debug("Dropping progress event of a lagging subscriber", "type", event.Type, "step", event.Step)
+		}
+	}
+	return true
+}
+
+// Close closes every subscriber channel, later events are discarded
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, ch := range b.subscribers {
+		close(ch)
+	}
+	b.subscribers = nil
+}
+
+type reporterKey struct{}
+
+// WithReporter attaches the function StepProgress updates of the running step go to
+func WithReporter(ctx context.Context, report func(percent int, message string)) context.Context {
+	return context.WithValue(ctx, reporterKey{}, report)
+}
+
+// Report tells how far the running step got, percent being -1 when unknown. Outside a step it does nothing.
+func Report(ctx context.Context, percent int, message string) {
+	if report, ok := ctx.Value(reporterKey{}).(func(percent int, message string)); ok {
+		report(percent, message)
+	}
+}
+
+// Print renders the progress updates and warnings of events as log lines until events is closed, for runs
+// without the TUI. Steps starting and completing are logged by the orchestrator already.
+func Print(events <-chan Event) {
+	for event := range events {
+		switch event.Type {
+		case StepProgress:
+			if event.Percent < 0 {
+				log.Info("⏳ "+event.Message, "step", event.Step)
+				continue
+			}
+			log.Info("⏳ "+event.Message, "step", event.Step, "progress", fmt.Sprintf("%d%%", event.Percent))
+		case Warning:
+			log.Warn("⚠️ Kubernetes event", "step", event.Step, "event", event.Message)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// VM statuses reported by Proxmox
+const (
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+	StatusPaused  = "paused"
+)
+
+const taskPollInterval = 2 * time.Second
+
+// Options locates and authenticates against the Proxmox VE API
+type Options struct {
+	URL         string // https://pve.lan:8006, with or without the /api2/json suffix
+	TokenID     string // user@realm!name
+	TokenSecret string
+	CACert      string // PEM file trusted for the API
+	Insecure    bool
+}
+
+// Client manages QEMU VMs through the Proxmox VE API with an API token
+type Client struct {
+	baseURL string
+	auth    string
+	http    *http.Client
+}
+
+// VM is a QEMU guest of the Proxmox cluster
+type VM struct {
+	ID     int     `json:"vmid"`
+	Name   string  `json:"name"`
+	Node   string  `json:"node"`
+	Status string  `json:"status"`
+	Uptime int64   `json:"uptime"`
+	CPU    float64 `json:"cpu"`
+	MaxCPU int     `json:"maxcpu"`
+	Mem    int64   `json:"mem"`
+	MaxMem int64   `json:"maxmem"`
+}
+
+// Snapshot is a saved state of a VM
+type Snapshot struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Parent      string    `json:"parent,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// NewClient creates a client for the API at opts.URL
+func NewClient(opts Options) (*Client, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("proxmox API URL not configured, set homelab.proxmox.url")
+	}
+	if opts.TokenID == "" || opts.TokenSecret == "" {
+		return nil, fmt.Errorf("proxmox API token not set, export PROXMOX_TOKEN_ID and PROXMOX_TOKEN_SECRET")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read proxmox CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", opts.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	base := strings.TrimSuffix(strings.TrimSuffix(opts.URL, "/"), "/api2/json")
+	return &Client{
+		baseURL: base + "/api2/json",
+		auth:    "PVEAPIToken=" + opts.TokenID + "=" + opts.TokenSecret,
+		http:    &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}, nil
+}
+
+// VMs lists the QEMU VMs of every node of the Proxmox cluster, templates excluded
+func (c *Client) VMs(ctx context.Context) ([]VM, error) {
+	var resources []struct {
+		VM
+		Type     string `json:"type"`
+		Template int    `json:"template"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/cluster/resources?type=vm", nil, &resources); err != nil {
+		return nil, err
+	}
+	var vms []VM
+	for _, resource := range resources {
+		if resource.Type == "qemu" && resource.Template == 0 {
+			vms = append(vms, resource.VM)
+		}
+	}
+	return vms, nil
+}
+
+// Start boots vm and waits for Proxmox to report the task done
+func (c *Client) Start(ctx context.Context, vm VM) error {
+	return c.runTask(ctx, vm, "/status/start", nil)
+}
+
+// StartStopped starts the stopped VMs and resumes the paused ones, returning those it brought up
+func (c *Client) StartStopped(ctx context.Context, vms []VM) ([]VM, error) {
+	var started []VM
+	for _, vm := range vms {
+		switch vm.Status {
+		case StatusRunning:
+			continue
+		case StatusPaused:
+			log.Info("▶️ Resuming paused VM "+vm.Name, "vmid", vm.ID, "node", vm.Node)
+			if err := c.runTask(ctx, vm, "/status/resume", nil); err != nil {
+				return started, err
+			}
+		default:
+			log.Info("▶️ Starting stopped VM "+vm.Name, "vmid", vm.ID, "node", vm.Node)
+			if err := c.Start(ctx, vm); err != nil {
+				return started, err
+			}
+		}
+		started = append(started, vm)
+	}
+	return started, nil
+}
+
+// Shutdown asks the guest to power off, stopping it hard once timeout passes
+func (c *Client) Shutdown(ctx context.Context, vm VM, timeout time.Duration) error {
+	form := url.Values{"forceStop": {"1"}, "timeout": {strconv.Itoa(int(timeout.Seconds()))}}
+	return c.runTask(ctx, vm, "/status/shutdown", form)
+}
+
+// Stop powers vm off immediately
+func (c *Client) Stop(ctx context.Context, vm VM) error {
+	return c.runTask(ctx, vm, "/status/stop", nil)
+}
+
+// Snapshots lists the snapshots of vm, oldest first
+func (c *Client) Snapshots(ctx context.Context, vm VM) ([]Snapshot, error) {
+	var entries []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Parent      string `json:"parent"`
+		SnapTime    int64  `json:"snaptime"`
+	}
+	if err := c.do(ctx, http.MethodGet, vmPath(vm)+"/snapshot", nil, &entries); err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		// "current" is the running state, not a snapshot
+		if entry.Name == "current" {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Name:        entry.Name,
+			Description: strings.TrimSpace(entry.Description),
+			Parent:      entry.Parent,
+			Time:        time.Unix(entry.SnapTime, 0),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// Snapshot saves the disks of vm as name
+func (c *Client) Snapshot(ctx context.Context, vm VM, name, description string) error {
+	form := url.Values{"snapname": {name}}
+	if description != "" {
+		form.Set("description", description)
+	}
+	return c.runTask(ctx, vm, "/snapshot", form)
+}
+
+// Rollback restores vm to the snapshot name, which leaves it stopped unless the snapshot saved the RAM
+func (c *Client) Rollback(ctx context.Context, vm VM, name string) error {
+	return c.runTask(ctx, vm, "/snapshot/"+url.PathEscape(name)+"/rollback", nil)
+}
+
+// runTask posts an asynchronous VM action and waits for the task it starts
+func (c *Client) runTask(ctx context.Context, vm VM, path string, form url.Values) error {
+	var upid string
+	if err := c.do(ctx, http.MethodPost, vmPath(vm)+path, form, &upid); err != nil {
+		return fmt.Errorf("%s: %w", vm.Name, err)
+	}
+	log.Debug("Waiting for Proxmox task", "vm", vm.Name, "upid", upid)
+
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+	for {
+		var status struct {
+			Status     string `json:"status"`
+			ExitStatus string `json:"exitstatus"`
+		}
+		taskPath := "/nodes/" + url.PathEscape(vm.Node) + "/tasks/" + url.PathEscape(upid) + "/status"
+		if err := c.do(ctx, http.MethodGet, taskPath, nil, &status); err != nil {
+			return fmt.Errorf("%s: %w", vm.Name, err)
+		}
+		if status.Status == "stopped" {
+			if status.ExitStatus != "OK" {
+				return fmt.Errorf("%s: proxmox task failed: %s", vm.Name, status.ExitStatus)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// do calls the API and decodes the data field of its response into out
+func (c *Client) do(ctx context.Context, method, path string, form url.Values, out any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", c.auth)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("proxmox API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read proxmox API response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Proxmox puts the reason in the status line, parameter errors in the body
+		var failure struct {
+			Errors map[string]string `json:"errors"`
+		}
+		if json.Unmarshal(data, &failure) == nil && len(failure.Errors) > 0 {
+			return fmt.Errorf("%s %s: %s %v", method, path, resp.Status, failure.Errors)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("failed to parse proxmox API response: %w", err)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to parse proxmox API response: %w", err)
+	}
+	return nil
+}
+
+func vmPath(vm VM) string {
+	return "/nodes/" + url.PathEscape(vm.Node) + "/qemu/" + strconv.Itoa(vm.ID)
+}
+
+// Find returns the VMs named names in that order, failing on a name matching no VM or several
+func Find(vms []VM, names []string) ([]VM, error) {
+	found := make([]VM, 0, len(names))
+	for _, name := range names {
+		var matches []VM
+		for _, vm := range vms {
+			if vm.Name == name || strconv.Itoa(vm.ID) == name {
+				matches = append(matches, vm)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("no proxmox VM named %s", name)
+		case 1:
+			found = append(found, matches[0])
+		default:
+			return nil, fmt.Errorf("several proxmox VMs are named %s, use the VM ID", name)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,44 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// NewClientFromConfig creates a client for the Proxmox API of the homelab config
+func NewClientFromConfig(cfg config.ProxmoxConfig) (*Client, error) {
+	return NewClient(Options{
+		URL:         cfg.URL,
+		TokenID:     cfg.TokenID,
+		TokenSecret: cfg.TokenSecret,
+		CACert:      cfg.CACert,
+		Insecure:    cfg.Insecure,
+	})
+}
+
+// ClusterVMs returns the VMs of the cluster nodes, only those named in names when given
+func (c *Client) ClusterVMs(ctx context.Context, cfg *config.HomelabConfig, names []string) ([]VM, error) {
+	nodes := cfg.ProxmoxVMs()
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no cluster VMs configured, list them in homelab.proxmox.vms or set hostnames in homelab.talos.nodes")
+	}
+	all, err := c.VMs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vms, err := Find(all, nodes)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return vms, nil
+	}
+	// Named VMs must belong to the cluster, so a typo never reaches another guest of the host
+	selected, err := Find(vms, names)
+	if err != nil {
+		return nil, fmt.Errorf("%w among the cluster VMs %v", err, nodes)
+	}
+	return selected, nil
+}
@@ -12,10 +12,15 @@ import (
 
 // DiagnosticResult represents the result of a diagnostic check
 type DiagnosticResult struct {
-	Component   string
-	Status      string // "healthy", "warning", "error"
-	Message     string
-	Recoverable bool
+	Component   string `json:"component"`
+	Status      string `json:"status"` // "healthy", "warning", "error"
+	Message     string `json:"message"`
+	Recoverable bool   `json:"recoverable"`
+
+	// Known problems carry what a remediation needs to act on them
+	Cluster string  `json:"cluster,omitempty"`
+	Finding Finding `json:"finding,omitempty"`
+	Target  string  `json:"target,omitempty"`
 }
 
 // DiagnosticManager performs system diagnostics for recovery
@@ -35,7 +40,7 @@ func NewDiagnosticManager(cfg *config.Config, isNAS bool) (*DiagnosticManager, e
 
 	// Connect to homelab cluster if configuration exists
 	if cfg.Homelab != nil {
-		client, err := k8s.NewClient(cfg.Homelab.Cluster.KubeConfig)
+		client, err := k8s.NewClientWithContext(cfg.Homelab.Cluster.KubeConfig, cfg.Homelab.Cluster.KubeContext)
 		if err != nil {
 			log.Warn("Failed to connect to homelab cluster", "error", err)
 		} else {
@@ -45,7 +50,7 @@ func NewDiagnosticManager(cfg *config.Config, isNAS bool) (*DiagnosticManager, e
 
 	// Connect to NAS cluster if configuration exists
 	if cfg.NAS != nil {
-		client, err := k8s.NewClient(cfg.NAS.Cluster.KubeConfig)
+		client, err := k8s.NewClientWithContext(cfg.NAS.Cluster.KubeConfig, cfg.NAS.Cluster.KubeContext)
 		if err != nil {
 			log.Warn("Failed to connect to NAS cluster", "error", err)
 		} else {
@@ -148,6 +153,9 @@ func (dm *DiagnosticManager) diagnoseCluster(ctx context.Context, client *k8s.Cl
 		results = append(results, istioResults...)
 	}
 
+	// Check for the problems a remediation knows how to fix
+	results = append(results, dm.diagnoseFindings(ctx, client, clusterType)...)
+
 	return results, nil
 }
 
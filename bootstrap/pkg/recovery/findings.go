@@ -0,0 +1,235 @@
+package recovery
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Finding identifies a known problem with a remediation
+type Finding string
+
+const (
+	FindingCrashLoopingController Finding = "crashlooping-controller"
+	FindingStuckNamespace         Finding = "stuck-namespace"
+	FindingMissingCACerts         Finding = "missing-cacerts"
+	FindingSuspendedKustomization Finding = "suspended-kustomization"
+	FindingExpiredWebhookCert     Finding = "expired-webhook-cert"
+)
+
+// Findings lists every known finding, in the order remediations run
+var Findings = []Finding{
+	FindingStuckNamespace,
+	FindingCrashLoopingController,
+	FindingMissingCACerts,
+	FindingExpiredWebhookCert,
+	FindingSuspendedKustomization,
+}
+
+// namespaceStuckAfter is how long a namespace terminates before it counts as stuck
+const namespaceStuckAfter = 5 * time.Minute
+
+var kustomizationGVR = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+
+// diagnoseFindings looks for the problems a remediation knows how to fix
+func (dm *DiagnosticManager) diagnoseFindings(ctx context.Context, client *k8s.Client, clusterType string) []*DiagnosticResult {
+	var results []*DiagnosticResult
+	results = append(results, dm.diagnoseCrashLoops(ctx, client, clusterType)...)
+	results = append(results, dm.diagnoseStuckNamespaces(ctx, client, clusterType)...)
+	results = append(results, dm.diagnoseCACerts(ctx, client, clusterType)...)
+	results = append(results, dm.diagnoseSuspendedKustomizations(ctx, client, clusterType)...)
+	results = append(results, dm.diagnoseWebhookCerts(ctx, client, clusterType)...)
+	return results
+}
+
+// diagnoseCrashLoops reports the Flux controller deployments with pods in CrashLoopBackOff
+func (dm *DiagnosticManager) diagnoseCrashLoops(ctx context.Context, client *k8s.Client, clusterType string) []*DiagnosticResult {
+	pods, err := client.GetClientset().CoreV1().Pods("flux-system").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+
+	var results []*DiagnosticResult
+	reported := map[string]bool{}
+	for _, pod := range pods.Items {
+		deployment := podDeployment(&pod)
+		if deployment == "" || reported[deployment] {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting == nil || status.State.Waiting.Reason != "CrashLoopBackOff" {
+				continue
+			}
+			reported[deployment] = true
+			results = append(results, &DiagnosticResult{
+				Component:   fmt.Sprintf("%s-flux-%s", clusterType, deployment),
+				Status:      "error",
+				Message:     fmt.Sprintf("%s is crash looping (%d restarts)", deployment, status.RestartCount),
+				Recoverable: true,
+				Cluster:     clusterType,
+				Finding:     FindingCrashLoopingController,
+				Target:      "flux-system/" + deployment,
+			})
+			break
+		}
+	}
+	return results
+}
+
+// diagnoseStuckNamespaces reports namespaces terminating for longer than namespaceStuckAfter
+func (dm *DiagnosticManager) diagnoseStuckNamespaces(ctx context.Context, client *k8s.Client, clusterType string) []*DiagnosticResult {
+	namespaces, err := client.GetClientset().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+
+	var results []*DiagnosticResult
+	for _, ns := range namespaces.Items {
+		if ns.Status.Phase != corev1.NamespaceTerminating || ns.DeletionTimestamp == nil {
+			continue
+		}
+		age := time.Since(ns.DeletionTimestamp.Time)
+		if age < namespaceStuckAfter {
+			continue
+		}
+		results = append(results, &DiagnosticResult{
+			Component:   fmt.Sprintf("%s-namespace-%s", clusterType, ns.Name),
+			Status:      "error",
+			Message:     fmt.Sprintf("Namespace %s has been terminating for %s", ns.Name, age.Round(time.Minute)),
+			Recoverable: true,
+			Cluster:     clusterType,
+			Finding:     FindingStuckNamespace,
+			Target:      ns.Name,
+		})
+	}
+	return results
+}
+
+// diagnoseCACerts reports a mesh cluster without the shared Istio root CA
+func (dm *DiagnosticManager) diagnoseCACerts(ctx context.Context, client *k8s.Client, clusterType string) []*DiagnosticResult {
+	if exists, err := client.NamespaceExists(ctx, istioNamespace); err != nil || !exists {
+		return nil
+	}
+	_, err := client.GetSecret(ctx, istioNamespace, cacertsSecret)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return nil
+	}
+	return []*DiagnosticResult{{
+		Component:   fmt.Sprintf("%s-istio-cacerts", clusterType),
+		Status:      "error",
+		Message:     "The cacerts secret is missing, istiod signs with a self-generated root and cross-cluster mTLS fails",
+		Recoverable: true,
+		Cluster:     clusterType,
+		Finding:     FindingMissingCACerts,
+		Target:      istioNamespace + "/" + cacertsSecret,
+	}}
+}
+
+// diagnoseSuspendedKustomizations reports Kustomizations left suspended
+func (dm *DiagnosticManager) diagnoseSuspendedKustomizations(ctx context.Context, client *k8s.Client, clusterType string) []*DiagnosticResult {
+	list, err := client.GetDynamicClient().Resource(kustomizationGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+
+	var results []*DiagnosticResult
+	for _, ks := range list.Items {
+		if suspended, _, _ := unstructured.NestedBool(ks.Object, "spec", "suspend"); !suspended {
+			continue
+		}
+		ref := ks.GetNamespace() + "/" + ks.GetName()
+		results = append(results, &DiagnosticResult{
+			Component:   fmt.Sprintf("%s-kustomization-%s", clusterType, ks.GetName()),
+			Status:      "warning",
+			Message:     fmt.Sprintf("Kustomization %s is suspended", ref),
+			Recoverable: true,
+			Cluster:     clusterType,
+			Finding:     FindingSuspendedKustomization,
+			Target:      ref,
+		})
+	}
+	return results
+}
+
+// diagnoseWebhookCerts reports admission webhooks whose CA bundle has expired
+func (dm *DiagnosticManager) diagnoseWebhookCerts(ctx context.Context, client *k8s.Client, clusterType string) []*DiagnosticResult {
+	admission := client.GetClientset().AdmissionregistrationV1()
+	var results []*DiagnosticResult
+	report := func(kind, name string, bundle []byte) {
+		expired, notAfter := bundleExpired(bundle)
+		if !expired {
+			return
+		}
+		results = append(results, &DiagnosticResult{
+			Component:   fmt.Sprintf("%s-webhook-%s", clusterType, name),
+			Status:      "error",
+			Message:     fmt.Sprintf("%s webhook %s trusts a CA that expired on %s", kind, name, notAfter.Format(time.DateOnly)),
+			Recoverable: true,
+			Cluster:     clusterType,
+			Finding:     FindingExpiredWebhookCert,
+			Target:      kind + "/" + name,
+		})
+	}
+
+	if list, err := admission.MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, config := range list.Items {
+			for _, webhook := range config.Webhooks {
+				if webhook.ClientConfig.Service != nil {
+					report(webhookMutating, config.Name, webhook.ClientConfig.CABundle)
+					break
+				}
+			}
+		}
+	}
+	if list, err := admission.ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, config := range list.Items {
+			for _, webhook := range config.Webhooks {
+				if webhook.ClientConfig.Service != nil {
+					report(webhookValidating, config.Name, webhook.ClientConfig.CABundle)
+					break
+				}
+			}
+		}
+	}
+	return results
+}
+
+// podDeployment derives the deployment of a pod from its ReplicaSet owner
+func podDeployment(pod *corev1.Pod) string {
+	hash := pod.Labels["pod-template-hash"]
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "ReplicaSet" && hash != "" {
+			return strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return ""
+}
+
+// bundleExpired reports whether any certificate of a PEM bundle has expired
+func bundleExpired(bundle []byte) (bool, time.Time) {
+	for len(bundle) > 0 {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if time.Now().After(cert.NotAfter) {
+			return true, cert.NotAfter
+		}
+	}
+	return false, time.Time{}
+}
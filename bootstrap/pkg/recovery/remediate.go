@@ -0,0 +1,256 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	istioNamespace    = "istio-system"
+	cacertsSecret     = "cacerts"
+	webhookMutating   = "mutating"
+	webhookValidating = "validating"
+)
+
+// cacertsFiles are read from CACERTS_DIR when no peer cluster holds the root CA
+var cacertsFiles = []string{"root-cert.pem", "cert-chain.pem", "key.pem"}
+
+// Remediation is the fix of one finding and its outcome
+type Remediation struct {
+	Component string  `json:"component"`
+	Cluster   string  `json:"cluster"`
+	Finding   Finding `json:"finding"`
+	Target    string  `json:"target"`
+	Action    string  `json:"action"`
+	Status    string  `json:"status"` // "pending", "applied", "dry-run", "skipped", "failed"
+	Error     string  `json:"error,omitempty"`
+}
+
+// Remediations maps the known findings of results to their fixes, in the order of Findings
+func (dm *DiagnosticManager) Remediations(results []*DiagnosticResult) []*Remediation {
+	var remediations []*Remediation
+	for _, finding := range Findings {
+		for _, result := range results {
+			if result.Finding != finding {
+				continue
+			}
+			remediations = append(remediations, &Remediation{
+				Component: result.Component,
+				Cluster:   result.Cluster,
+				Finding:   result.Finding,
+				Target:    result.Target,
+				Action:    remediationAction(result.Finding, result.Target),
+				Status:    "pending",
+			})
+		}
+	}
+	return remediations
+}
+
+// Remediate applies r and records the outcome on it. A dry run sends every change with
+// server-side dry run, so admission and validation still run without anything persisting.
+func (dm *DiagnosticManager) Remediate(ctx context.Context, r *Remediation, dryRun bool) error {
+	err := dm.remediate(ctx, r, dryRun)
+	switch {
+	case err != nil:
+		r.Status = "failed"
+		r.Error = err.Error()
+	case dryRun:
+		r.Status = "dry-run"
+	default:
+		r.Status = "applied"
+	}
+	return err
+}
+
+func (dm *DiagnosticManager) remediate(ctx context.Context, r *Remediation, dryRun bool) error {
+	client := dm.clientFor(r.Cluster)
+	if client == nil {
+		return fmt.Errorf("not connected to the %s cluster", r.Cluster)
+	}
+	log.Info("🔧 "+r.Action, "cluster", r.Cluster, "dry_run", dryRun)
+
+	switch r.Finding {
+	case FindingCrashLoopingController:
+		namespace, name, _ := strings.Cut(r.Target, "/")
+		return restartDeployment(ctx, client, namespace, name, dryRun)
+	case FindingStuckNamespace:
+		unsticker := NewUnsticker(client)
+		unsticker.SetDryRun(dryRun)
+		namespaces, err := unsticker.StuckNamespaces(ctx, r.Target)
+		if err != nil {
+			return err
+		}
+		for _, ns := range namespaces {
+			if err := unsticker.StripNamespace(ctx, ns); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FindingMissingCACerts:
+		return dm.restoreCACerts(ctx, client, r.Cluster, dryRun)
+	case FindingSuspendedKustomization:
+		namespace, name, _ := strings.Cut(r.Target, "/")
+		patch := fmt.Sprintf(`{"spec":{"suspend":false},"metadata":{"annotations":{"reconcile.fluxcd.io/requestedAt":"%s"}}}`, time.Now().Format(time.RFC3339Nano))
+		_, err := client.GetDynamicClient().Resource(kustomizationGVR).Namespace(namespace).Patch(
+			ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{DryRun: dryRunOption(dryRun)})
+		if err != nil {
+			return fmt.Errorf("failed to resume Kustomization %s: %w", r.Target, err)
+		}
+		return nil
+	case FindingExpiredWebhookCert:
+		kind, name, _ := strings.Cut(r.Target, "/")
+		return restartWebhookBackends(ctx, client, kind, name, dryRun)
+	}
+	return fmt.Errorf("no remediation for %s", r.Finding)
+}
+
+// restoreCACerts copies the root CA of the peer cluster, or reads it from CACERTS_DIR, then restarts istiod to load it
+func (dm *DiagnosticManager) restoreCACerts(ctx context.Context, client *k8s.Client, cluster string, dryRun bool) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: cacertsSecret, Namespace: istioNamespace},
+		Type:       corev1.SecretTypeOpaque,
+	}
+
+	peer := "nas"
+	if cluster == "nas" {
+		peer = "homelab"
+	}
+	if peerClient := dm.clientFor(peer); peerClient != nil {
+		if peerSecret, err := peerClient.GetSecret(ctx, istioNamespace, cacertsSecret); err == nil {
+			log.Info("Copying the root CA of the peer cluster", "peer", peer)
+			secret.Data = peerSecret.Data
+		}
+	}
+	if secret.Data == nil {
+		dir := os.Getenv("CACERTS_DIR")
+		if dir == "" {
+			return fmt.Errorf("no peer cluster holds %s and CACERTS_DIR is not set", cacertsSecret)
+		}
+		secret.Data = map[string][]byte{}
+		for _, name := range cacertsFiles {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			secret.Data[name] = data
+		}
+		log.Info("Reading the root CA from disk", "dir", dir)
+	}
+
+	_, err := client.GetClientset().CoreV1().Secrets(istioNamespace).Create(ctx, secret, metav1.CreateOptions{DryRun: dryRunOption(dryRun)})
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", cacertsSecret, err)
+	}
+	return restartDeployment(ctx, client, istioNamespace, "istiod", dryRun)
+}
+
+// restartWebhookBackends restarts the deployments behind a webhook configuration so they reissue their serving certificates
+func restartWebhookBackends(ctx context.Context, client *k8s.Client, kind, name string, dryRun bool) error {
+	admission := client.GetClientset().AdmissionregistrationV1()
+	var services []*admissionv1.ServiceReference
+	switch kind {
+	case webhookMutating:
+		config, err := admission.MutatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get mutating webhook %s: %w", name, err)
+		}
+		for _, webhook := range config.Webhooks {
+			services = append(services, webhook.ClientConfig.Service)
+		}
+	case webhookValidating:
+		config, err := admission.ValidatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get validating webhook %s: %w", name, err)
+		}
+		for _, webhook := range config.Webhooks {
+			services = append(services, webhook.ClientConfig.Service)
+		}
+	default:
+		return fmt.Errorf("unknown webhook kind %q", kind)
+	}
+
+	restarted := map[string]bool{}
+	for _, ref := range services {
+		if ref == nil {
+			continue
+		}
+		service, err := client.GetService(ctx, ref.Namespace, ref.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get webhook service %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		selector := labels.SelectorFromSet(service.Spec.Selector)
+		deployments, err := client.GetClientset().AppsV1().Deployments(ref.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list deployments in %s: %w", ref.Namespace, err)
+		}
+		for _, deployment := range deployments.Items {
+			key := deployment.Namespace + "/" + deployment.Name
+			if restarted[key] || selector.Empty() || !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+				continue
+			}
+			if err := restartDeployment(ctx, client, deployment.Namespace, deployment.Name, dryRun); err != nil {
+				return err
+			}
+			restarted[key] = true
+		}
+	}
+	if len(restarted) == 0 {
+		return fmt.Errorf("no deployment serves the %s webhook %s", kind, name)
+	}
+	return nil
+}
+
+// restartDeployment rolls the pods of a deployment the way kubectl rollout restart does
+func restartDeployment(ctx context.Context, client *k8s.Client, namespace, name string, dryRun bool) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`, time.Now().Format(time.RFC3339))
+	_, err := client.GetClientset().AppsV1().Deployments(namespace).Patch(
+		ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{DryRun: dryRunOption(dryRun)})
+	if err != nil {
+		return fmt.Errorf("failed to restart deployment %s/%s: %w", namespace, name, err)
+	}
+	log.Info("Deployment restarted", "namespace", namespace, "name", name, "dry_run", dryRun)
+	return nil
+}
+
+func (dm *DiagnosticManager) clientFor(cluster string) *k8s.Client {
+	if cluster == "nas" {
+		return dm.nasClient
+	}
+	return dm.homelabClient
+}
+
+func remediationAction(finding Finding, target string) string {
+	switch finding {
+	case FindingCrashLoopingController:
+		return "Restart deployment " + target
+	case FindingStuckNamespace:
+		return "Strip the finalizers holding namespace " + target
+	case FindingMissingCACerts:
+		return "Restore " + target + " from the peer cluster or CACERTS_DIR and restart istiod"
+	case FindingSuspendedKustomization:
+		return "Resume Kustomization " + target
+	case FindingExpiredWebhookCert:
+		return "Restart the deployments serving the " + strings.Replace(target, "/", " webhook ", 1)
+	}
+	return string(finding)
+}
+
+func dryRunOption(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/restmapper"
+)
+
+// builtinController is reported for finalizers removed by kube-controller-manager
+const builtinController = "kube-controller-manager"
+
+// groupControllers maps API groups to the deployment reconciling them
+var groupControllers = map[string]string{
+	"kustomize.toolkit.fluxcd.io":    "flux-system/kustomize-controller",
+	"helm.toolkit.fluxcd.io":         "flux-system/helm-controller",
+	"source.toolkit.fluxcd.io":       "flux-system/source-controller",
+	"notification.toolkit.fluxcd.io": "flux-system/notification-controller",
+	"ceph.rook.io":                   "rook-ceph/rook-ceph-operator",
+	"objectbucket.io":                "rook-ceph/rook-ceph-operator",
+	"cert-manager.io":                "cert-manager/cert-manager",
+	"acme.cert-manager.io":           "cert-manager/cert-manager",
+	"networking.istio.io":            "istio-system/istiod",
+	"security.istio.io":              "istio-system/istiod",
+}
+
+// Controller is the workload expected to remove a finalizer
+type Controller struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "running", "unavailable", "not found", "built-in", "unknown"
+}
+
+// StuckObject is an object whose deletion waits on its finalizers
+type StuckObject struct {
+	Kind       string     `json:"kind"`
+	Namespace  string     `json:"namespace,omitempty"`
+	Name       string     `json:"name"`
+	Deleting   *time.Time `json:"deleting_since,omitempty"`
+	Finalizers []string   `json:"finalizers"`
+	Controller Controller `json:"controller"`
+
+	resource schema.GroupVersionResource
+}
+
+// StuckNamespace is a terminating namespace and the objects holding it
+type StuckNamespace struct {
+	Name       string         `json:"name"`
+	Deleting   *time.Time     `json:"deleting_since,omitempty"`
+	Finalizers []string       `json:"finalizers,omitempty"`
+	Conditions []string       `json:"conditions,omitempty"`
+	Blocking   []*StuckObject `json:"blocking"`
+}
+
+// Unsticker finds objects stuck on finalizers, names the controller that should release them and strips them on request
+type Unsticker struct {
+	client      *k8s.Client
+	controllers map[string]Controller
+	dryRun      bool
+}
+
+// NewUnsticker creates an unsticker for the cluster behind client
+func NewUnsticker(client *k8s.Client) *Unsticker {
+	return &Unsticker{client: client, controllers: map[string]Controller{}}
+}
+
+// SetDryRun makes Strip and StripNamespace validate their changes server-side without persisting them
+func (u *Unsticker) SetDryRun(dryRun bool) {
+	u.dryRun = dryRun
+}
+
+// StuckNamespaces inspects the named namespaces, or every terminating one when none is given
+func (u *Unsticker) StuckNamespaces(ctx context.Context, names ...string) ([]*StuckNamespace, error) {
+	var namespaces []corev1.Namespace
+	if len(names) == 0 {
+		list, err := u.client.GetClientset().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		for _, ns := range list.Items {
+			if ns.Status.Phase == corev1.NamespaceTerminating {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+	for _, name := range names {
+		ns, err := u.client.GetClientset().CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+		}
+		namespaces = append(namespaces, *ns)
+	}
+	if len(namespaces) == 0 {
+		return nil, nil
+	}
+
+	resources, err := u.namespacedResources()
+	if err != nil {
+		return nil, err
+	}
+
+	var stuck []*StuckNamespace
+	for _, ns := range namespaces {
+		result := &StuckNamespace{Name: ns.Name, Deleting: deletionTime(ns.DeletionTimestamp)}
+		for _, finalizer := range ns.Spec.Finalizers {
+			result.Finalizers = append(result.Finalizers, string(finalizer))
+		}
+		for _, condition := range ns.Status.Conditions {
+			if condition.Status == corev1.ConditionTrue {
+				result.Conditions = append(result.Conditions, condition.Message)
+			}
+		}
+		for _, gvr := range resources {
+			list, err := u.client.GetDynamicClient().Resource(gvr).Namespace(ns.Name).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				log.Debug("Resource not listed", "resource", gvr.Resource, "namespace", ns.Name, "error", err)
+				continue
+			}
+			for i := range list.Items {
+				if obj := u.stuckObject(ctx, &list.Items[i], gvr); obj != nil {
+					result.Blocking = append(result.Blocking, obj)
+				}
+			}
+		}
+		stuck = append(stuck, result)
+	}
+	return stuck, nil
+}
+
+// StuckObjects inspects the objects of resource, a kind, plural or short name, optionally narrowed to a namespace and name.
+// The namespace is ignored for cluster-scoped resources.
+func (u *Unsticker) StuckObjects(ctx context.Context, resource, namespace, name string) ([]*StuckObject, error) {
+	gvr, namespaced, err := u.resolve(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	client := u.client.GetDynamicClient().Resource(gvr)
+	var items []unstructured.Unstructured
+	switch {
+	case name != "" && namespaced:
+		obj, err := client.Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s %s/%s: %w", gvr.Resource, namespace, name, err)
+		}
+		items = append(items, *obj)
+	case name != "":
+		obj, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s %s: %w", gvr.Resource, name, err)
+		}
+		items = append(items, *obj)
+	default:
+		var list *unstructured.UnstructuredList
+		if namespaced {
+			list, err = client.Namespace(namespace).List(ctx, metav1.ListOptions{})
+		} else {
+			list, err = client.List(ctx, metav1.ListOptions{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+		items = list.Items
+	}
+
+	var stuck []*StuckObject
+	for i := range items {
+		if obj := u.stuckObject(ctx, &items[i], gvr); obj != nil {
+			stuck = append(stuck, obj)
+		}
+	}
+	return stuck, nil
+}
+
+// Strip removes the finalizers of obj so its deletion completes
+func (u *Unsticker) Strip(ctx context.Context, obj *StuckObject) error {
+	patch := []byte(`{"metadata":{"finalizers":null}}`)
+	opts := metav1.PatchOptions{DryRun: dryRunOption(u.dryRun)}
+	resource := u.client.GetDynamicClient().Resource(obj.resource)
+	var err error
+	if obj.Namespace != "" {
+		_, err = resource.Namespace(obj.Namespace).Patch(ctx, obj.Name, types.MergePatchType, patch, opts)
+	} else {
+		_, err = resource.Patch(ctx, obj.Name, types.MergePatchType, patch, opts)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to strip finalizers of %s %s: %w", obj.Kind, obj.ref(), err)
+	}
+	log.Info("Finalizers stripped", "kind", obj.Kind, "object", obj.ref(), "finalizers", obj.Finalizers, "dry_run", u.dryRun)
+	return nil
+}
+
+// StripNamespace strips the objects blocking ns, then finalizes the namespace itself when it is terminating
+func (u *Unsticker) StripNamespace(ctx context.Context, ns *StuckNamespace) error {
+	for _, obj := range ns.Blocking {
+		if err := u.Strip(ctx, obj); err != nil {
+			return err
+		}
+	}
+	if ns.Deleting == nil || len(ns.Finalizers) == 0 {
+		return nil
+	}
+
+	namespaces := u.client.GetClientset().CoreV1().Namespaces()
+	current, err := namespaces.Get(ctx, ns.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get namespace %s: %w", ns.Name, err)
+	}
+	current.Spec.Finalizers = nil
+	if _, err := namespaces.Finalize(ctx, current, metav1.UpdateOptions{DryRun: dryRunOption(u.dryRun)}); err != nil {
+		return fmt.Errorf("failed to finalize namespace %s: %w", ns.Name, err)
+	}
+	log.Info("Namespace finalized", "namespace", ns.Name, "dry_run", u.dryRun)
+	return nil
+}
+
+// stuckObject returns obj when it is being deleted with finalizers left, nil otherwise
+func (u *Unsticker) stuckObject(ctx context.Context, obj *unstructured.Unstructured, gvr schema.GroupVersionResource) *StuckObject {
+	if obj.GetDeletionTimestamp() == nil || len(obj.GetFinalizers()) == 0 {
+		return nil
+	}
+	return &StuckObject{
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Deleting:   deletionTime(obj.GetDeletionTimestamp()),
+		Finalizers: obj.GetFinalizers(),
+		Controller: u.controllerFor(ctx, obj, gvr.Group),
+		resource:   gvr,
+	}
+}
+
+// controllerFor names the controller expected to release obj: the one reconciling its API group,
+// a built-in controller for kubernetes finalizers, otherwise the object's own controller owner
+func (u *Unsticker) controllerFor(ctx context.Context, obj *unstructured.Unstructured, group string) Controller {
+	if name, ok := groupControllers[group]; ok {
+		return u.deploymentController(ctx, name)
+	}
+	for _, finalizer := range obj.GetFinalizers() {
+		if finalizer == "foregroundDeletion" || finalizer == "orphan" || strings.HasPrefix(finalizer, "kubernetes.io/") || strings.HasPrefix(finalizer, "batch.kubernetes.io/") {
+			return Controller{Name: builtinController, Status: "built-in"}
+		}
+	}
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.Controller != nil && *owner.Controller {
+			return Controller{Name: owner.Kind + "/" + owner.Name, Status: "unknown"}
+		}
+	}
+	for _, finalizer := range obj.GetFinalizers() {
+		if domain, _, found := strings.Cut(finalizer, "/"); found {
+			return Controller{Name: "controller of " + domain, Status: "unknown"}
+		}
+	}
+	return Controller{Name: "unknown", Status: "unknown"}
+}
+
+// deploymentController checks whether the <namespace>/<name> deployment runs, once per scan
+func (u *Unsticker) deploymentController(ctx context.Context, ref string) Controller {
+	if controller, ok := u.controllers[ref]; ok {
+		return controller
+	}
+	controller := Controller{Name: ref, Status: "running"}
+	namespace, name, _ := strings.Cut(ref, "/")
+	deployment, err := u.client.GetClientset().AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		controller.Status = "not found"
+	case err != nil:
+		controller.Status = "unknown"
+	case deployment.Status.AvailableReplicas == 0:
+		controller.Status = "unavailable"
+	}
+	u.controllers[ref] = controller
+	return controller
+}
+
+// resolve maps a kind, plural, short name or <plural>.<group> to its resource
+func (u *Unsticker) resolve(resource string) (schema.GroupVersionResource, bool, error) {
+	discovery := u.client.GetClientset().Discovery()
+	mapper := restmapper.NewShortcutExpander(u.client.RESTMapper(), discovery, nil)
+
+	gvr, err := mapper.ResourceFor(schema.ParseGroupResource(strings.ToLower(resource)).WithVersion(""))
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unknown resource %q: %w", resource, err)
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("failed to find the kind of %s: %w", gvr.Resource, err)
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("failed to map %s: %w", gvk.Kind, err)
+	}
+	return gvr, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// namespacedResources lists the preferred version of every namespaced resource that can be listed and patched
+func (u *Unsticker) namespacedResources() ([]schema.GroupVersionResource, error) {
+	lists, err := u.client.GetClientset().Discovery().ServerPreferredNamespacedResources()
+	if err != nil && len(lists) == 0 {
+		return nil, fmt.Errorf("failed to discover namespaced resources: %w", err)
+	}
+	var resources []schema.GroupVersionResource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") || !hasVerbs(resource.Verbs, "list", "patch") {
+				continue
+			}
+			resources = append(resources, gv.WithResource(resource.Name))
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].String() < resources[j].String()
+	})
+	return resources, nil
+}
+
+func (o *StuckObject) ref() string {
+	if o.Namespace == "" {
+		return o.Name
+	}
+	return o.Namespace + "/" + o.Name
+}
+
+func hasVerbs(verbs metav1.Verbs, wanted ...string) bool {
+	for _, verb := range wanted {
+		if !slices.Contains(verbs, verb) {
+			return false
+		}
+	}
+	return true
+}
+
+func deletionTime(timestamp *metav1.Time) *time.Time {
+	if timestamp == nil {
+		return nil
+	}
+	return &timestamp.Time
+}
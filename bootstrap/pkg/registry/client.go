@@ -0,0 +1,361 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Media types of multi-platform indexes and single-platform manifests
+const (
+	mediaTypeOCIIndex        = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerList      = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest     = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest  = "application/vnd.docker.distribution.manifest.v2+json"
+	defaultRegistry          = "docker.io"
+	defaultRegistryEndpoint  = "registry-1.docker.io"
+	defaultTag               = "latest"
+	officialImageNamespace   = "library"
+	maxManifestResponseBytes = 4 << 20
+)
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// String returns the reference in its canonical form
+func (r Reference) String() string {
+	ref := r.Registry + "/" + r.Repository
+	if r.Digest != "" {
+		return ref + "@" + r.Digest
+	}
+	return ref + ":" + r.Tag
+}
+
+// manifestRef returns the tag or digest addressing the manifest
+func (r Reference) manifestRef() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// endpoint returns the host serving the registry API
+func (r Reference) endpoint() string {
+	if r.Registry == defaultRegistry {
+		return defaultRegistryEndpoint
+	}
+	return r.Registry
+}
+
+// ParseReference parses an image such as nginx, ghcr.io/org/app:v1 or quay.io/app@sha256:...
+func ParseReference(image string) (Reference, error) {
+	image = strings.TrimSpace(image)
+	if image == "" || strings.ContainsAny(image, " \t\"'{}$") {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+
+	ref := Reference{Registry: defaultRegistry}
+	name := image
+	if at := strings.Index(name, "@"); at >= 0 {
+		ref.Digest = name[at+1:]
+		name = name[:at]
+	}
+	if slash, colon := strings.LastIndex(name, "/"), strings.LastIndex(name, ":"); colon > slash {
+		ref.Tag = name[colon+1:]
+		name = name[:colon]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	if first, rest, found := strings.Cut(name, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry = first
+		name = rest
+	}
+	if ref.Registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = officialImageNamespace + "/" + name
+	}
+	if name == "" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// Platform is an os/architecture pair an image is published for
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// String returns the platform as os/arch[/variant]
+func (p Platform) String() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Architecture + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Architecture
+}
+
+// Client queries OCI registries for image metadata, anonymously unless credentials were given for the registry
+type Client struct {
+	http        *http.Client
+	mu          sync.Mutex
+	cache       map[string][]Platform
+	credentials map[string][2]string
+	insecure    map[string]bool
+}
+
+// NewClient creates a new registry client
+func NewClient() *Client {
+	return &Client{
+		http:        &http.Client{Timeout: 20 * time.Second},
+		cache:       make(map[string][]Platform),
+		credentials: make(map[string][2]string),
+		insecure:    make(map[string]bool),
+	}
+}
+
+// WithCredentials authenticates the requests to host with a username and password
+func (c *Client) WithCredentials(host, username, password string) *Client {
+	c.credentials[host] = [2]string{username, password}
+	return c
+}
+
+// WithInsecure reaches host over plain HTTP
+func (c *Client) WithInsecure(host string) *Client {
+	c.insecure[host] = true
+	return c
+}
+
+// Check fails when the registry does not serve the manifest of image
+func (c *Client) Check(ctx context.Context, image string) error {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return err
+	}
+	accept := strings.Join([]string{mediaTypeOCIIndex, mediaTypeDockerList, mediaTypeOCIManifest, mediaTypeDockerManifest}, ", ")
+	_, _, _, err = c.get(ctx, ref, "manifests/"+ref.manifestRef(), accept, "")
+	return err
+}
+
+// Platforms returns the platforms an image is published for, from its manifest list when it has one
+func (c *Client) Platforms(ctx context.Context, image string) ([]Platform, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ref.String()
+	c.mu.Lock()
+	cached, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	platforms, err := c.platforms(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = platforms
+	c.mu.Unlock()
+	return platforms, nil
+}
+
+func (c *Client) platforms(ctx context.Context, ref Reference) ([]Platform, error) {
+	accept := strings.Join([]string{mediaTypeOCIIndex, mediaTypeDockerList, mediaTypeOCIManifest, mediaTypeDockerManifest}, ", ")
+	body, mediaType, authorization, err := c.get(ctx, ref, "manifests/"+ref.manifestRef(), accept, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		MediaType string `json:"mediaType"`
+		Manifests []struct {
+			Platform *struct {
+				OS           string `json:"os"`
+				Architecture string `json:"architecture"`
+				Variant      string `json:"variant"`
+			} `json:"platform"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest of %s: %w", ref, err)
+	}
+	if mediaType == "" || strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" {
+		mediaType = manifest.MediaType
+	}
+
+	if mediaType == mediaTypeOCIIndex || mediaType == mediaTypeDockerList || len(manifest.Manifests) > 0 {
+		platforms := make([]Platform, 0, len(manifest.Manifests))
+		for _, entry := range manifest.Manifests {
+			// Attestation manifests are published with an unknown platform
+			if entry.Platform == nil || entry.Platform.Architecture == "unknown" {
+				continue
+			}
+			platforms = append(platforms, Platform{OS: entry.Platform.OS, Architecture: entry.Platform.Architecture, Variant: entry.Platform.Variant})
+		}
+		return platforms, nil
+	}
+
+	if manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("manifest of %s has no config", ref)
+	}
+	blob, _, _, err := c.get(ctx, ref, "blobs/"+manifest.Config.Digest, "*/*", authorization)
+	if err != nil {
+		return nil, err
+	}
+	var imageConfig struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	}
+	if err := json.Unmarshal(blob, &imageConfig); err != nil {
+		return nil, fmt.Errorf("failed to decode image config of %s: %w", ref, err)
+	}
+	return []Platform{{OS: imageConfig.OS, Architecture: imageConfig.Architecture, Variant: imageConfig.Variant}}, nil
+}
+
+// get fetches a registry API path, negotiating the authorization when challenged. The authorization
+// is returned so following requests reuse it.
+func (c *Client) get(ctx context.Context, ref Reference, path, accept, authorization string) ([]byte, string, string, error) {
+	scheme := "https"
+	if c.insecure[ref.Registry] {
+		scheme = "http"
+	}
+	target := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.endpoint(), ref.Repository, path)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, "", "", err
+		}
+		req.Header.Set("Accept", accept)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to query %s: %w", ref.Registry, err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestResponseBytes))
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to read response from %s: %w", ref.Registry, err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && authorization == "":
+			authorization, err = c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), ref)
+			if err != nil {
+				return nil, "", "", err
+			}
+			continue
+		case resp.StatusCode >= 300:
+			return nil, "", "", fmt.Errorf("%s returned %s for %s", ref.Registry, resp.Status, ref)
+		}
+		return body, resp.Header.Get("Content-Type"), authorization, nil
+	}
+	return nil, "", "", fmt.Errorf("%s requires credentials for %s", ref.Registry, ref)
+}
+
+// authorize answers a Basic challenge with the credentials of the registry and a Bearer one with a pull token
+func (c *Client) authorize(ctx context.Context, challenge string, ref Reference) (string, error) {
+	credentials, authenticated := c.credentials[ref.Registry]
+	scheme, _, _ := strings.Cut(challenge, " ")
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		token, err := c.token(ctx, challenge, ref)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case strings.EqualFold(scheme, "Basic") && authenticated:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials[0]+":"+credentials[1])), nil
+	}
+	return "", fmt.Errorf("%s requires credentials for %s", ref.Registry, ref)
+}
+
+// token requests a pull token from the realm of a Bearer challenge, anonymously unless the registry has credentials
+func (c *Client) token(ctx context.Context, challenge string, ref Reference) (string, error) {
+	_, params, _ := strings.Cut(challenge, " ")
+
+	fields := make(map[string]string)
+	for _, param := range strings.Split(params, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if found {
+			fields[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+	realm, err := url.Parse(fields["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid auth challenge from %s", ref.Registry)
+	}
+	query := realm.Query()
+	if service := fields["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := fields["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if credentials, ok := c.credentials[ref.Registry]; ok {
+		req.SetBasicAuth(credentials[0], credentials[1])
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get token from %s: %w", realm.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s refused access to %s: %s", realm.Host, ref, resp.Status)
+	}
+
+	var response struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode token from %s: %w", realm.Host, err)
+	}
+	if response.Token != "" {
+		return response.Token, nil
+	}
+	return response.AccessToken, nil
+}
+
+// Supports reports whether platforms include arch on linux
+func Supports(platforms []Platform, arch string) bool {
+	for _, platform := range platforms {
+		if platform.Architecture == arch && (platform.OS == "" || platform.OS == "linux") {
+			return true
+		}
+	}
+	return false
+}
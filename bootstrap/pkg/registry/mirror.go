@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+var (
+	mirrorMu   sync.RWMutex
+	mirror     string
+	pullSecret string
+)
+
+// SetMirror makes Rewrite point images at the mirror registry, pods pulling them with the pull secret
+// when it is not empty
+func SetMirror(endpoint, secret string) {
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	mirror = strings.TrimSuffix(endpoint, "/")
+	pullSecret = secret
+}
+
+// Mirror returns the registry images are pulled from, empty when they are pulled from their own registry
+func Mirror() string {
+	mirrorMu.RLock()
+	defer mirrorMu.RUnlock()
+	return mirror
+}
+
+// PullSecret returns the name of the secret pods pull mirrored images with, empty without credentials
+func PullSecret() string {
+	mirrorMu.RLock()
+	defer mirrorMu.RUnlock()
+	return pullSecret
+}
+
+// Rewrite points image at the mirror registry, keeping its repository path, tag and digest.
+// Docker Hub images get their implicit library/ prefix.
+func Rewrite(image string) string {
+	target := Mirror()
+	if target == "" || image == "" || strings.HasPrefix(image, target+"/") {
+		return image
+	}
+	host, path, found := strings.Cut(image, "/")
+	switch {
+	case !found:
+		path = officialImageNamespace + "/" + image
+	case !strings.ContainsAny(host, ".:") && host != "localhost":
+		path = image
+	}
+	return target + "/" + path
+}
+
+// DockerConfigJSON renders the .dockerconfigjson of a pull secret for host
+func DockerConfigJSON(host, username, password string) ([]byte, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			host: map[string]string{"username": username, "password": password, "auth": auth},
+		},
+	})
+}
@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/robfig/cron/v3"
+)
+
+// Tasks a schedule can run
+const (
+	TaskVerify       = "verify"
+	TaskSecurityScan = "security-scan"
+	TaskGatewayCert  = "gateway-cert"
+	TaskEtcdSnapshot = "etcd-snapshot"
+	TaskHealth       = "health"
+	TaskDrift        = "drift"
+)
+
+// Tasks lists every task a schedule can name
+var Tasks = []string{TaskVerify, TaskSecurityScan, TaskGatewayCert, TaskEtcdSnapshot, TaskHealth, TaskDrift}
+
+// Job is a task run on a cluster at the times of a cron expression
+type Job struct {
+	Name     string `json:"name"`
+	Cluster  string `json:"cluster"`
+	Task     string `json:"task"`
+	Schedule string `json:"schedule"`
+
+	spec cron.Schedule
+	next time.Time
+}
+
+// ID returns the cluster-qualified job name
+func (j *Job) ID() string {
+	return j.Cluster + "/" + j.Name
+}
+
+// Next returns the first time the job runs after now
+func (j *Job) Next(now time.Time) time.Time {
+	return j.spec.Next(now)
+}
+
+// RunFunc runs a job, the scheduler only logs its error
+type RunFunc func(ctx context.Context, job *Job) error
+
+// Scheduler runs jobs at their scheduled times, one at a time so runs never overlap
+type Scheduler struct {
+	jobs []*Job
+	run  RunFunc
+}
+
+// NewJob validates a scheduled task of cluster
+func NewJob(cluster, name, task, expression string) (*Job, error) {
+	known := false
+	for _, t := range Tasks {
+		known = known || t == task
+	}
+	if !known {
+		return nil, fmt.Errorf("schedule %s: unknown task %q, expected one of %v", name, task, Tasks)
+	}
+	// Standard five-field cron, plus @daily, @weekly, @monthly and @every <duration>
+	spec, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: invalid schedule %q: %w", name, expression, err)
+	}
+	return &Job{Name: name, Cluster: cluster, Task: task, Schedule: expression, spec: spec}, nil
+}
+
+// New creates a scheduler running jobs with run
+func New(jobs []*Job, run RunFunc) (*Scheduler, error) {
+	seen := map[string]bool{}
+	for _, job := range jobs {
+		if seen[job.ID()] {
+			return nil, fmt.Errorf("schedule %s is defined twice", job.ID())
+		}
+		seen[job.ID()] = true
+	}
+	return &Scheduler{jobs: jobs, run: run}, nil
+}
+
+// Jobs returns the scheduled jobs ordered by their next run after now
+func (s *Scheduler) Jobs(now time.Time) []*Job {
+	jobs := append([]*Job(nil), s.jobs...)
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Next(now).Before(jobs[j].Next(now))
+	})
+	return jobs
+}
+
+// Run runs the jobs as they come due until ctx is done. A job due while another runs starts after it, and
+// fire times missed meanwhile collapse into that single run.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.jobs) == 0 {
+		return fmt.Errorf("no schedules configured")
+	}
+	now := time.Now()
+	for _, job := range s.jobs {
+		job.next = job.Next(now)
+		log.Info("⏰ Scheduled "+job.ID(), "task", job.Task, "schedule", job.Schedule, "next", job.next.Format(time.RFC3339))
+	}
+
+	for {
+		due := s.jobs[0].next
+		for _, job := range s.jobs[1:] {
+			if job.next.Before(due) {
+				due = job.next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		for _, job := range s.jobs {
+			if job.next.After(time.Now()) {
+				continue
+			}
+			log.Info("▶️ Running scheduled "+job.ID(), "task", job.Task)
+			if err := s.run(ctx, job); err != nil {
+				log.Error("❌ Scheduled "+job.ID()+" failed", "task", job.Task, "error", err)
+			} else {
+				log.Info("✅ Scheduled "+job.ID()+" succeeded", "task", job.Task)
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			job.next = job.Next(time.Now())
+			log.Debug("Next run", "job", job.ID(), "at", job.next.Format(time.RFC3339))
+		}
+	}
+}
+
+// Trigger runs the job named id, its cluster-qualified name or its name alone when unambiguous, right away
+func (s *Scheduler) Trigger(ctx context.Context, id string) error {
+	var matches []*Job
+	for _, job := range s.jobs {
+		if job.ID() == id || job.Name == id {
+			matches = append(matches, job)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("no schedule named %s", id)
+	case 1:
+		return s.run(ctx, matches[0])
+	}
+	return fmt.Errorf("schedule %s exists on several clusters, use <cluster>/%s", id, id)
+}
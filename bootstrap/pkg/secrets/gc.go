@@ -0,0 +1,235 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	pendingStoredAtAnnotation = "homelab.bootstrap/stored-at"
+	pendingRemoteSecretPrefix = "istio-remote-secret-"
+	pendingRemoteSecretSuffix = "-pending"
+
+	// DefaultPendingMaxAge is how long a pending remote secret is kept before it is considered expired.
+	DefaultPendingMaxAge = 7 * 24 * time.Hour
+)
+
+// clusterGeneratedEnvPatterns are .env.generated key templates written once per mesh cluster. Keys matching
+// one for a cluster no longer configured are obsolete; any other key is left alone.
+var clusterGeneratedEnvPatterns = []string{
+	"ISTIO_REMOTE_SECRET_%s_B64",
+	"%s_EW_GATEWAY_ADDR",
+	"%s_EW_GATEWAY_PORT",
+}
+
+// AppliedFunc reports whether a pending payload for a cluster has already been applied there.
+type AppliedFunc func(ctx context.Context, cluster, payloadB64 string) (bool, error)
+
+// GCOptions controls the garbage collection of pending secrets and generated env keys.
+type GCOptions struct {
+	KnownClusters []string
+	MaxPendingAge time.Duration
+	IsApplied     AppliedFunc
+	DryRun        bool
+}
+
+// GCReport summarises what garbage collection removed (or would remove in dry-run mode).
+type GCReport struct {
+	DeletedPendingSecrets []string `json:"deleted_pending_secrets"`
+	PrunedEnvKeys         []string `json:"pruned_env_keys"`
+	DryRun                bool     `json:"dry_run"`
+}
+
+// Empty reports whether nothing was collected.
+func (r *GCReport) Empty() bool {
+	return len(r.DeletedPendingSecrets) == 0 && len(r.PrunedEnvKeys) == 0
+}
+
+// obsoleteGeneratedEnvKey reports whether key was written for a cluster that is not among known
+func obsoleteGeneratedEnvKey(key string, known map[string]struct{}) bool {
+	for _, pattern := range clusterGeneratedEnvPatterns {
+		prefix, suffix, _ := strings.Cut(pattern, "%s")
+		if len(key) <= len(prefix)+len(suffix) || !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+			continue
+		}
+		cluster := strings.TrimSuffix(strings.TrimPrefix(key, prefix), suffix)
+		if _, ok := known[cluster]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GarbageCollect removes applied or expired pending remote secrets and prunes the .env.generated keys of
+// clusters no longer configured.
+func (m *Manager) GarbageCollect(ctx context.Context, opts GCOptions) (*GCReport, error) {
+	if opts.MaxPendingAge <= 0 {
+		opts.MaxPendingAge = DefaultPendingMaxAge
+	}
+
+	report := &GCReport{DryRun: opts.DryRun}
+
+	deleted, err := m.collectPendingRemoteSecrets(ctx, opts)
+	if err != nil {
+		return report, err
+	}
+	report.DeletedPendingSecrets = deleted
+
+	pruned, err := m.pruneGeneratedEnv(opts.KnownClusters, opts.DryRun)
+	if err != nil {
+		return report, err
+	}
+	report.PrunedEnvKeys = pruned
+
+	return report, nil
+}
+
+func (m *Manager) collectPendingRemoteSecrets(ctx context.Context, opts GCOptions) ([]string, error) {
+	list, err := m.client.GetClientset().CoreV1().Secrets(istioNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list secrets in %s: %w", istioNamespace, err)
+	}
+
+	known := make(map[string]struct{}, len(opts.KnownClusters))
+	for _, cluster := range opts.KnownClusters {
+		known[strings.ToLower(strings.TrimSpace(cluster))] = struct{}{}
+	}
+
+	var deleted []string
+	for i := range list.Items {
+		secret := &list.Items[i]
+		cluster, ok := pendingClusterFromName(secret.Name)
+		if !ok {
+			continue
+		}
+
+		reason := m.pendingCollectReason(ctx, secret, cluster, known, opts)
+		if reason == "" {
+			continue
+		}
+
+		if opts.DryRun {
+			log.Info("Would delete pending remote secret", "secret", secret.Name, "reason", reason)
+		} else {
+			if err := m.client.GetClientset().CoreV1().Secrets(istioNamespace).Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				log.Warn("Failed to delete pending remote secret", "secret", secret.Name, "error", err)
+				continue
+			}
+			log.Info("Deleted pending remote secret", "secret", secret.Name, "reason", reason)
+		}
+		deleted = append(deleted, secret.Name)
+	}
+
+	return deleted, nil
+}
+
+// pendingCollectReason returns why a pending secret should be collected, or an empty string to keep it.
+func (m *Manager) pendingCollectReason(ctx context.Context, secret *corev1.Secret, cluster string, known map[string]struct{}, opts GCOptions) string {
+	if len(known) > 0 {
+		if _, ok := known[cluster]; !ok {
+			return "unknown cluster"
+		}
+	}
+
+	payload := ""
+	if secret.Data != nil {
+		payload = string(secret.Data[pendingRemoteSecretKey])
+	}
+	if strings.TrimSpace(payload) == "" {
+		return "empty payload"
+	}
+
+	if opts.IsApplied != nil {
+		applied, err := opts.IsApplied(ctx, cluster, payload)
+		if err != nil {
+			log.Debug("Could not determine whether pending secret was applied", "cluster", cluster, "error", err)
+		} else if applied {
+			return "applied"
+		}
+	}
+
+	if age := time.Since(pendingStoredAt(secret)); age > opts.MaxPendingAge {
+		return fmt.Sprintf("expired (%s old)", age.Round(time.Minute))
+	}
+
+	return ""
+}
+
+func (m *Manager) pruneGeneratedEnv(clusters []string, dryRun bool) ([]string, error) {
+	known := make(map[string]struct{}, len(clusters))
+	for _, cluster := range clusters {
+		if prefix := config.EnvKeyPrefix(cluster); prefix != "" {
+			known[prefix] = struct{}{}
+		}
+	}
+	if len(known) == 0 {
+		// Without the configured clusters there is no telling which keys are obsolete
+		return nil, nil
+	}
+
+	path := filepath.Join(m.projectRoot, generatedEnvFilename)
+	env, err := NewEnvFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", generatedEnvFilename, err)
+	}
+
+	var pruned []string
+	for key := range env.All() {
+		if obsoleteGeneratedEnvKey(key, known) {
+			pruned = append(pruned, key)
+		}
+	}
+	sort.Strings(pruned)
+
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+
+	if dryRun {
+		log.Info("Would prune obsolete generated env keys", "keys", pruned)
+		return pruned, nil
+	}
+
+	for _, key := range pruned {
+		env.Set(key, "")
+	}
+	if err := env.Write(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", generatedEnvFilename, err)
+	}
+	log.Info("Pruned obsolete generated env keys", "keys", pruned)
+
+	return pruned, nil
+}
+
+func pendingClusterFromName(name string) (string, bool) {
+	if !strings.HasPrefix(name, pendingRemoteSecretPrefix) || !strings.HasSuffix(name, pendingRemoteSecretSuffix) {
+		return "", false
+	}
+	cluster := strings.TrimSuffix(strings.TrimPrefix(name, pendingRemoteSecretPrefix), pendingRemoteSecretSuffix)
+	if cluster == "" {
+		return "", false
+	}
+	return cluster, true
+}
+
+func pendingStoredAt(secret *corev1.Secret) time.Time {
+	if value, ok := secret.Annotations[pendingStoredAtAnnotation]; ok {
+		if ts, err := time.Parse(time.RFC3339, value); err == nil {
+			return ts
+		}
+	}
+	return secret.CreationTimestamp.Time
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -19,6 +21,8 @@ import (
 type Manager struct {
 	client      *k8s.Client
 	projectRoot string
+	// meshClusters get a NETWORK_<NAME> default in cluster-vars
+	meshClusters []string
 }
 
 const (
@@ -37,21 +41,46 @@ func NewManager(client *k8s.Client, projectRoot string) *Manager {
 	}
 }
 
+// SetMeshClusters sets the mesh members whose network names default in cluster-vars
+func (m *Manager) SetMeshClusters(clusters []string) {
+	m.meshClusters = clusters
+}
+
 // CreateClusterVarsSecret creates cluster-vars secret from .env file
 func (m *Manager) CreateClusterVarsSecret(ctx context.Context, namespace string) error {
 	log.Info("Creating cluster-vars secret from environment variables", "namespace", namespace)
 
-	vars, err := m.loadMergedEnvVars()
+	secret, err := m.ClusterVarsSecret(namespace)
 	if err != nil {
-		return fmt.Errorf("failed to load environment variables: %w", err)
+		return err
 	}
 
-	if len(vars) == 0 {
+	if secret == nil {
 		log.Warn("No environment variables found in .env or .env.generated")
 		return nil
 	}
 
-	log.Info("Found variables in .env file", "count", len(vars))
+	log.Info("Found variables in .env file", "count", len(secret.Data))
+
+	err = m.client.CreateOrUpdateSecret(ctx, secret)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster-vars secret: %w", err)
+	}
+
+	log.Info("Cluster-vars secret created successfully", "variables", getSecretKeys(secret.Data))
+	return nil
+}
+
+// ClusterVarsSecret renders the cluster-vars secret from .env files, nil when there is no variable
+func (m *Manager) ClusterVarsSecret(namespace string) (*corev1.Secret, error) {
+	vars, err := m.loadMergedEnvVars()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment variables: %w", err)
+	}
+
+	if len(vars) == 0 {
+		return nil, nil
+	}
 
 	// Create secret data
 	data := make(map[string][]byte)
@@ -59,8 +88,8 @@ func (m *Manager) CreateClusterVarsSecret(ctx context.Context, namespace string)
 		data[key] = []byte(value)
 	}
 
-	// Create the secret
-	secret := &corev1.Secret{
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "cluster-vars",
 			Namespace: namespace,
@@ -71,15 +100,28 @@ func (m *Manager) CreateClusterVarsSecret(ctx context.Context, namespace string)
 		},
 		Type: corev1.SecretTypeOpaque,
 		Data: data,
-	}
+	}, nil
+}
 
-	err = m.client.CreateOrUpdateSecret(ctx, secret)
+// DesiredClusterVars returns the cluster-vars content built from .env, .env.generated and defaults
+func (m *Manager) DesiredClusterVars() (map[string]string, error) {
+	return m.loadMergedEnvVars()
+}
+
+// ClusterVars returns the cluster-vars content deployed in namespace, nil when the secret does not exist
+func (m *Manager) ClusterVars(ctx context.Context, namespace string) (map[string]string, error) {
+	secret, err := m.client.GetClientset().CoreV1().Secrets(namespace).Get(ctx, "cluster-vars", metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create cluster-vars secret: %w", err)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get cluster-vars secret: %w", err)
 	}
-
-	log.Info("Cluster-vars secret created successfully", "variables", getSecretKeys(data))
-	return nil
+	vars := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		vars[key] = string(value)
+	}
+	return vars, nil
 }
 
 func (m *Manager) loadMergedEnvVars() (map[string]string, error) {
@@ -107,8 +149,13 @@ func (m *Manager) loadMergedEnvVars() (map[string]string, error) {
 	defaults := map[string]string{
 		"ISTIO_HELM_REPO": "https://istio-release.storage.googleapis.com/charts",
 		"ISTIO_VERSION":   "1.27.2",
-		"NETWORK_NAS":     "nas-network",
-		"NETWORK_HOMELAB": "homelab-network",
+	}
+	meshClusters := m.meshClusters
+	if len(meshClusters) == 0 {
+		meshClusters = []string{"homelab", "nas"}
+	}
+	for _, cluster := range meshClusters {
+		defaults["NETWORK_"+config.EnvKeyPrefix(cluster)] = cluster + "-network"
 	}
 	for key, value := range defaults {
 		if existing, ok := merged[key]; !ok || strings.TrimSpace(existing) == "" {
@@ -252,6 +299,21 @@ func (m *Manager) GetGeneratedEnvValue(key string) (string, error) {
 	return env.Get(key), nil
 }
 
+// GeneratedEnvKeys returns the sorted keys of .env.generated, never their values.
+func (m *Manager) GeneratedEnvKeys() ([]string, error) {
+	env, err := NewEnvFile(filepath.Join(m.projectRoot, generatedEnvFilename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", generatedEnvFilename, err)
+	}
+	vars := env.All()
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
 // GetEnvValue returns the value for a key from the merged .env and .env.generated content.
 func (m *Manager) GetEnvValue(key string) (string, error) {
 	if strings.TrimSpace(key) == "" {
@@ -284,6 +346,9 @@ func (m *Manager) StorePendingRemoteSecret(ctx context.Context, cluster string,
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: istioNamespace,
+			Annotations: map[string]string{
+				pendingStoredAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+			},
 		},
 		Type: corev1.SecretTypeOpaque,
 		Data: map[string][]byte{
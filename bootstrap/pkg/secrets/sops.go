@@ -0,0 +1,227 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// sopsAgeSecretKey is the data key Flux reads age identities from, it must end in .agekey
+	sopsAgeSecretKey = "age.agekey"
+	// EncryptedEnvSuffix is appended to .env files encrypted with SOPS
+	EncryptedEnvSuffix = ".enc"
+)
+
+// AgeKey holds the age identities SOPS decrypts with
+type AgeKey struct {
+	Path       string   // Empty when read from $SOPS_AGE_KEY
+	Identities []string // AGE-SECRET-KEY-1... lines
+	Recipient  string   // Public key of the first identity
+}
+
+// DefaultAgeKeyFile returns the identity file sops itself would use
+func DefaultAgeKeyFile() string {
+	if path := os.Getenv("SOPS_AGE_KEY_FILE"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sops", "age", "keys.txt")
+}
+
+// LoadAgeKey reads the age identity from path, $SOPS_AGE_KEY or the default sops key file
+func LoadAgeKey(ctx context.Context, path string) (*AgeKey, error) {
+	var data []byte
+	switch {
+	case path != "":
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read age key %s: %w", path, err)
+		}
+		data = content
+	case os.Getenv("SOPS_AGE_KEY") != "":
+		data = []byte(os.Getenv("SOPS_AGE_KEY"))
+	default:
+		path = DefaultAgeKeyFile()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read age key %s, set age_key_file or SOPS_AGE_KEY_FILE: %w", path, err)
+		}
+		data = content
+	}
+
+	key := &AgeKey{Path: path}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "AGE-SECRET-KEY-"):
+			key.Identities = append(key.Identities, line)
+		case strings.HasPrefix(line, "# public key:") && key.Recipient == "":
+			key.Recipient = strings.TrimSpace(strings.TrimPrefix(line, "# public key:"))
+		}
+	}
+	if len(key.Identities) == 0 {
+		return nil, fmt.Errorf("no age identity found in %s", describeAgeKeySource(path))
+	}
+
+	// Keys generated without age-keygen have no public key comment, derive it
+	if key.Recipient == "" {
+		recipient, err := ageRecipient(ctx, key.Identities[0])
+		if err != nil {
+			return nil, err
+		}
+		key.Recipient = recipient
+	}
+	return key, nil
+}
+
+func describeAgeKeySource(path string) string {
+	if path == "" {
+		return "SOPS_AGE_KEY"
+	}
+	return path
+}
+
+// ageRecipient derives the public key of an identity with age-keygen
+func ageRecipient(ctx context.Context, identity string) (string, error) {
+	if _, err := exec.LookPath("age-keygen"); err != nil {
+		return "", fmt.Errorf("age key has no public key comment and age-keygen was not found in PATH")
+	}
+	cmd := exec.CommandContext(ctx, "age-keygen", "-y")
+	cmd.Stdin = strings.NewReader(identity + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to derive age recipient: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CreateSOPSAgeSecret stores the age identities in the secret the Kustomization decrypts with
+func (m *Manager) CreateSOPSAgeSecret(ctx context.Context, namespace, name string, key *AgeKey) error {
+	log.Info("Creating SOPS age decryption secret", "namespace", namespace, "name", name, "recipient", key.Recipient)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			sopsAgeSecretKey: []byte(strings.Join(key.Identities, "\n") + "\n"),
+		},
+	}
+	if err := m.client.CreateOrUpdateSecret(ctx, secret); err != nil {
+		return fmt.Errorf("failed to create %s secret: %w", name, err)
+	}
+	return nil
+}
+
+// EncryptEnvFile encrypts a dotenv file for the age recipients with the sops CLI
+func EncryptEnvFile(ctx context.Context, path, output string, recipients []string) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("no age recipient to encrypt %s for", path)
+	}
+	return runSOPS(ctx, nil,
+		"--encrypt",
+		"--input-type", "dotenv", "--output-type", "dotenv",
+		"--age", strings.Join(recipients, ","),
+		"--output", output, path)
+}
+
+// DecryptEnvFile decrypts a SOPS encrypted dotenv file with key, the plaintext is only readable by the owner
+func DecryptEnvFile(ctx context.Context, path, output string, key *AgeKey) error {
+	env := []string{"SOPS_AGE_KEY=" + strings.Join(key.Identities, "\n")}
+	if err := runSOPS(ctx, env,
+		"--decrypt",
+		"--input-type", "dotenv", "--output-type", "dotenv",
+		"--output", output, path); err != nil {
+		return err
+	}
+	if err := os.Chmod(output, 0o600); err != nil {
+		return fmt.Errorf("failed to restrict permissions of %s: %w", output, err)
+	}
+	return nil
+}
+
+// EncryptedEnvPath returns where the encrypted copy of an env file is stored
+func EncryptedEnvPath(path string) string {
+	return path + EncryptedEnvSuffix
+}
+
+// DecryptedEnvPath returns the plaintext env file of an encrypted one
+func DecryptedEnvPath(path string) string {
+	if trimmed := strings.TrimSuffix(path, EncryptedEnvSuffix); trimmed != path {
+		return trimmed
+	}
+	return path + ".dec"
+}
+
+// EncryptManifest encrypts the data of a Secret manifest for the age recipients, its metadata stays readable
+func EncryptManifest(ctx context.Context, manifest []byte, recipients []string) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no age recipient to encrypt the manifest for")
+	}
+	return sopsManifest(ctx, manifest, nil,
+		"--encrypt",
+		"--input-type", "yaml", "--output-type", "yaml",
+		"--encrypted-regex", "^(data|stringData)$",
+		"--age", strings.Join(recipients, ","))
+}
+
+// DecryptManifest decrypts a manifest encrypted by EncryptManifest with key
+func DecryptManifest(ctx context.Context, manifest []byte, key *AgeKey) ([]byte, error) {
+	env := []string{"SOPS_AGE_KEY=" + strings.Join(key.Identities, "\n")}
+	return sopsManifest(ctx, manifest, env,
+		"--decrypt",
+		"--input-type", "yaml", "--output-type", "yaml")
+}
+
+// sopsManifest runs sops on manifest through a file only readable by the owner and returns its output
+func sopsManifest(ctx context.Context, manifest []byte, env []string, args ...string) ([]byte, error) {
+	file, err := os.CreateTemp("", "bootstrap-sops-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(manifest)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return sopsOutput(ctx, env, append(args, file.Name())...)
+}
+
+func runSOPS(ctx context.Context, env []string, args ...string) error {
+	_, err := sopsOutput(ctx, env, args...)
+	return err
+}
+
+func sopsOutput(ctx context.Context, env []string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		return nil, fmt.Errorf("sops not found in PATH")
+	}
+	cmd := exec.CommandContext(ctx, "sops", args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sops: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
@@ -0,0 +1,315 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/registry"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BenchmarkCIS runs the CIS Kubernetes Benchmark with kube-bench
+const BenchmarkCIS = "cis"
+
+// Benchmark profiles, picking the kube-bench benchmark and host paths of the distribution
+const (
+	ProfileTalos   = "talos"
+	ProfileK3s     = "k3s"
+	ProfileGeneric = "generic"
+)
+
+const (
+	kubeBenchImage      = "aquasec/kube-bench:v0.10.1"
+	kubeBenchJob        = "homelab-bootstrap-kube-bench"
+	kubeBenchNamespace  = "kube-system"
+	defaultBenchTimeout = 5 * time.Minute
+)
+
+// benchmarkProfile is how kube-bench runs on a distribution
+type benchmarkProfile struct {
+	benchmark string
+	targets   string
+	hostPaths []string
+	// controlPlane schedules the job on a control plane node, where the master checks can read their files
+	controlPlane bool
+}
+
+// Talos keeps the control plane files out of reach of pods, only the kubelet and policy checks apply.
+// K3s embeds etcd and the control plane in its server, checked with the k3s benchmark.
+var benchmarkProfiles = map[string]benchmarkProfile{
+	ProfileTalos: {
+		benchmark: "cis-1.9",
+		targets:   "node,policies",
+		hostPaths: []string{"/var/lib/kubelet", "/etc/kubernetes"},
+	},
+	ProfileK3s: {
+		benchmark:    "k3s-cis-1.8",
+		targets:      "master,node,etcd,policies",
+		hostPaths:    []string{"/var/lib/rancher", "/etc/rancher", "/var/lib/kubelet"},
+		controlPlane: true,
+	},
+	ProfileGeneric: {
+		benchmark:    "cis-1.9",
+		targets:      "master,node,etcd,policies",
+		hostPaths:    []string{"/var/lib/kubelet", "/etc/kubernetes", "/var/lib/etcd", "/etc/systemd"},
+		controlPlane: true,
+	},
+}
+
+// BenchmarkOptions configures a kube-bench run
+type BenchmarkOptions struct {
+	// Profile is talos, k3s or generic, detected from the nodes when empty
+	Profile string
+	// Image overrides the kube-bench image
+	Image   string
+	Timeout time.Duration
+}
+
+// BenchmarkResult sums up a kube-bench run
+type BenchmarkResult struct {
+	Profile   string            `json:"profile"`
+	Benchmark string            `json:"benchmark"`
+	Node      string            `json:"node"`
+	Pass      int               `json:"pass"`
+	Fail      int               `json:"fail"`
+	Warn      int               `json:"warn"`
+	Info      int               `json:"info"`
+	Findings  []SecurityFinding `json:"findings"`
+}
+
+// kubeBenchOutput is the --json report of kube-bench
+type kubeBenchOutput struct {
+	Controls []kubeBenchControls `json:"Controls"`
+}
+
+type kubeBenchControls struct {
+	ID       string `json:"id"`
+	Version  string `json:"version"`
+	Text     string `json:"text"`
+	NodeType string `json:"node_type"`
+	Tests    []struct {
+		Section string `json:"section"`
+		Desc    string `json:"desc"`
+		Results []struct {
+			TestNumber  string `json:"test_number"`
+			TestDesc    string `json:"test_desc"`
+			Remediation string `json:"remediation"`
+			Status      string `json:"status"`
+			Scored      bool   `json:"scored"`
+		} `json:"results"`
+	} `json:"tests"`
+}
+
+// RunBenchmark runs kube-bench as a Job and maps its failed and warning controls into findings
+func (sv *SecurityValidator) RunBenchmark(ctx context.Context, opts BenchmarkOptions) (*BenchmarkResult, error) {
+	if err := k8s.GuardMutation("run the kube-bench job"); err != nil {
+		return nil, err
+	}
+	if opts.Profile == "" {
+		detected, err := sv.detectProfile(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts.Profile = detected
+	}
+	profile, ok := benchmarkProfiles[opts.Profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown benchmark profile %q, use %s, %s or %s", opts.Profile, ProfileTalos, ProfileK3s, ProfileGeneric)
+	}
+	if opts.Image == "" {
+		opts.Image = kubeBenchImage
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultBenchTimeout
+	}
+
+	jobs := sv.client.GetClientset().BatchV1().Jobs(kubeBenchNamespace)
+	background := metav1.DeletePropagationBackground
+	remove := func(ctx context.Context) {
+		if err := jobs.Delete(ctx, kubeBenchJob, metav1.DeleteOptions{PropagationPolicy: &background}); err != nil && !apierrors.IsNotFound(err) {
+			log.Warn("Failed to delete the kube-bench job", "error", err)
+		}
+	}
+	remove(ctx)
+	if err := k8s.Poll(ctx, 2*time.Second, time.Minute, func(ctx context.Context) (bool, error) {
+		_, err := jobs.Get(ctx, kubeBenchJob, metav1.GetOptions{})
+		return apierrors.IsNotFound(err), nil
+	}); err != nil {
+		return nil, fmt.Errorf("previous kube-bench job still terminating: %w", err)
+	}
+
+	log.Info("Running kube-bench", "profile", opts.Profile, "benchmark", profile.benchmark, "targets", profile.targets)
+	if _, err := jobs.Create(ctx, kubeBenchJobSpec(profile, opts.Image), metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create the kube-bench job: %w", err)
+	}
+	defer remove(context.WithoutCancel(ctx))
+
+	pod, err := sv.waitForBenchmark(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := sv.client.GetClientset().CoreV1().Pods(kubeBenchNamespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the kube-bench output: %w", err)
+	}
+
+	result, err := parseKubeBench(logs)
+	if err != nil {
+		return nil, err
+	}
+	result.Profile = opts.Profile
+	result.Benchmark = profile.benchmark
+	result.Node = pod.Spec.NodeName
+	log.Info("kube-bench completed", "node", result.Node, "pass", result.Pass, "fail", result.Fail, "warn", result.Warn)
+	return result, nil
+}
+
+// detectProfile picks the profile from the operating system and kubelet of the nodes
+func (sv *SecurityValidator) detectProfile(ctx context.Context) (string, error) {
+	nodes, err := sv.client.GetClientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		switch {
+		case strings.Contains(node.Status.NodeInfo.OSImage, "Talos"):
+			return ProfileTalos, nil
+		case strings.Contains(node.Status.NodeInfo.KubeletVersion, "+k3s"):
+			return ProfileK3s, nil
+		}
+	}
+	return ProfileGeneric, nil
+}
+
+// kubeBenchJobSpec runs kube-bench once in the host PID namespace with the host paths of the profile
+func kubeBenchJobSpec(profile benchmarkProfile, image string) *batchv1.Job {
+	backoff := int32(0)
+	ttl := int32(600)
+	spec := corev1.PodSpec{
+		HostPID:       true,
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers: []corev1.Container{{
+			Name:    "kube-bench",
+			Image:   registry.Rewrite(image),
+			Command: []string{"kube-bench", "run", "--benchmark", profile.benchmark, "--targets", profile.targets, "--json"},
+		}},
+	}
+	for i, path := range profile.hostPaths {
+		name := fmt.Sprintf("host-%d", i)
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name:         name,
+			VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: path}},
+		})
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name: name, MountPath: path, ReadOnly: true,
+		})
+	}
+	if profile.controlPlane {
+		spec.Tolerations = []corev1.Toleration{{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}}
+		spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.NodeSelectorOpExists}},
+			}}},
+		}}
+	}
+	if secret := registry.PullSecret(); secret != "" {
+		spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: secret}}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeBenchJob,
+			Namespace: kubeBenchNamespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": k8s.FieldManager},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoff,
+			TTLSecondsAfterFinished: &ttl,
+			Template:                corev1.PodTemplateSpec{Spec: spec},
+		},
+	}
+}
+
+// waitForBenchmark waits for the kube-bench pod to exit and returns it
+func (sv *SecurityValidator) waitForBenchmark(ctx context.Context, timeout time.Duration) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	err := k8s.Poll(ctx, 3*time.Second, timeout, func(ctx context.Context) (bool, error) {
+		pods, err := sv.client.GetClientset().CoreV1().Pods(kubeBenchNamespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + kubeBenchJob})
+		if err != nil || len(pods.Items) == 0 {
+			return false, nil
+		}
+		pod = &pods.Items[0]
+		return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed, nil
+	})
+	if err != nil {
+		if pod != nil {
+			return nil, fmt.Errorf("kube-bench pod is %s: %w", pod.Status.Phase, err)
+		}
+		return nil, fmt.Errorf("kube-bench pod not created: %w", err)
+	}
+	if pod.Status.Phase == corev1.PodFailed {
+		return nil, fmt.Errorf("kube-bench failed on %s, check the benchmark profile", pod.Spec.NodeName)
+	}
+	return pod, nil
+}
+
+// parseKubeBench maps the failed and warning controls of a kube-bench report into findings, failed
+// scored controls being High
+func parseKubeBench(data []byte) (*BenchmarkResult, error) {
+	var output kubeBenchOutput
+	if err := json.Unmarshal(data, &output); err != nil || output.Controls == nil {
+		// Older kube-bench releases print the controls as a bare array
+		if arrErr := json.Unmarshal(data, &output.Controls); arrErr != nil {
+			return nil, fmt.Errorf("failed to parse the kube-bench report: %w", err)
+		}
+	}
+
+	result := &BenchmarkResult{Findings: []SecurityFinding{}}
+	for _, controls := range output.Controls {
+		for _, test := range controls.Tests {
+			for _, check := range test.Results {
+				switch check.Status {
+				case "PASS":
+					result.Pass++
+					continue
+				case "INFO":
+					result.Info++
+					continue
+				case "FAIL":
+					result.Fail++
+				case "WARN":
+					result.Warn++
+				}
+				severity := "Low"
+				if check.Status == "FAIL" {
+					severity = "Medium"
+					if check.Scored {
+						severity = "High"
+					}
+				}
+				result.Findings = append(result.Findings, SecurityFinding{
+					Severity:    severity,
+					Component:   fmt.Sprintf("CIS %s %s", controls.Version, controls.NodeType),
+					Control:     check.TestNumber,
+					Description: check.TestDesc,
+					Remediation: strings.TrimSpace(check.Remediation),
+				})
+			}
+		}
+	}
+	sort.SliceStable(result.Findings, func(i, j int) bool {
+		return severityRank[result.Findings[i].Severity] < severityRank[result.Findings[j].Severity]
+	})
+	return result, nil
+}
+
+var severityRank = map[string]int{"Critical": 0, "High": 1, "Medium": 2, "Low": 3}
@@ -0,0 +1,333 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Image scan sources
+const (
+	ScanSourceOperator = "trivy-operator"
+	ScanSourceCLI      = "trivy"
+)
+
+// Severities ordered from the most severe, as Trivy reports them
+var trivySeverities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+var vulnerabilityReportGVR = schema.GroupVersionResource{Group: "aquasecurity.github.io", Version: "v1alpha1", Resource: "vulnerabilityreports"}
+
+const defaultImageScanTimeout = 5 * time.Minute
+
+// ImageScanOptions configures the image vulnerability scan
+type ImageScanOptions struct {
+	// Namespace limits the scan to the pods of one namespace, all namespaces when empty
+	Namespace string
+	// IgnoreUnfixed leaves out vulnerabilities without a fixed version
+	IgnoreUnfixed bool
+	// Timeout bounds the scan of each image by the trivy CLI
+	Timeout time.Duration
+}
+
+// ImageScanResult aggregates the vulnerabilities of the images running in the cluster
+type ImageScanResult struct {
+	Images []ImageReport `json:"images"`
+	// Severities counts the vulnerabilities of every scanned image by severity
+	Severities map[string]int `json:"severities"`
+	// Unscanned lists the images neither the operator nor the trivy CLI reported on
+	Unscanned []string `json:"unscanned,omitempty"`
+}
+
+// ImageReport is the vulnerabilities of one image and the pods running it
+type ImageReport struct {
+	Image           string          `json:"image"`
+	Pods            []string        `json:"pods"`
+	Source          string          `json:"source"`
+	Severities      map[string]int  `json:"severities"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// Vulnerability is a CVE found in a package of an image
+type Vulnerability struct {
+	ID               string `json:"id"`
+	Severity         string `json:"severity"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installedVersion"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	Title            string `json:"title,omitempty"`
+}
+
+// AtOrAbove counts the vulnerabilities of threshold severity or worse
+func (r *ImageScanResult) AtOrAbove(threshold string) int {
+	count := 0
+	for _, severity := range trivySeverities {
+		count += r.Severities[severity]
+		if severity == strings.ToUpper(threshold) {
+			return count
+		}
+	}
+	return 0
+}
+
+// ValidSeverity reports whether severity is a Trivy severity
+func ValidSeverity(severity string) bool {
+	for _, known := range trivySeverities {
+		if strings.ToUpper(severity) == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanImages enumerates the images of the running pods and collects their vulnerabilities, from the
+// VulnerabilityReports of trivy-operator when it runs, with the trivy CLI for the images it has not scanned
+func (sv *SecurityValidator) ScanImages(ctx context.Context, opts ImageScanOptions) (*ImageScanResult, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultImageScanTimeout
+	}
+	images, err := sv.runningImages(ctx, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Scanning images", "images", len(images), "namespace", opts.Namespace)
+
+	reports, err := sv.operatorReports(ctx, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	trivy, lookErr := exec.LookPath("trivy")
+
+	result := &ImageScanResult{Images: []ImageReport{}, Severities: map[string]int{}}
+	for _, image := range sortedImages(images) {
+		report := ImageReport{Image: image, Pods: images[image]}
+		if vulnerabilities, ok := reports[normalizeImage(image)]; ok {
+			report.Source = ScanSourceOperator
+			report.Vulnerabilities = vulnerabilities
+		} else if lookErr == nil {
+			vulnerabilities, err := scanWithTrivy(ctx, trivy, image, opts)
+			if err != nil {
+				log.Warn("Image scan failed", "image", image, "error", err)
+				result.Unscanned = append(result.Unscanned, image)
+				continue
+			}
+			report.Source = ScanSourceCLI
+			report.Vulnerabilities = vulnerabilities
+		} else {
+			result.Unscanned = append(result.Unscanned, image)
+			continue
+		}
+
+		if opts.IgnoreUnfixed {
+			fixed := report.Vulnerabilities[:0]
+			for _, vulnerability := range report.Vulnerabilities {
+				if vulnerability.FixedVersion != "" {
+					fixed = append(fixed, vulnerability)
+				}
+			}
+			report.Vulnerabilities = fixed
+		}
+		report.Severities = map[string]int{}
+		for _, vulnerability := range report.Vulnerabilities {
+			report.Severities[vulnerability.Severity]++
+			result.Severities[vulnerability.Severity]++
+		}
+		result.Images = append(result.Images, report)
+	}
+
+	if len(result.Unscanned) > 0 && lookErr != nil {
+		log.Warn("Images without a trivy-operator report were not scanned, install trivy to scan them", "images", len(result.Unscanned))
+	}
+	log.Info("Image scan completed", "images", len(result.Images), "critical", result.Severities["CRITICAL"], "high", result.Severities["HIGH"])
+	return result, nil
+}
+
+// runningImages maps the images of the running pods to the pods using them
+func (sv *SecurityValidator) runningImages(ctx context.Context, namespace string) (map[string][]string, error) {
+	pods, err := sv.client.GetClientset().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	images := map[string][]string{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		seen := map[string]bool{}
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, container := range containers {
+			if seen[container.Image] {
+				continue
+			}
+			seen[container.Image] = true
+			images[container.Image] = append(images[container.Image], pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return images, nil
+}
+
+// operatorReports reads the VulnerabilityReports of trivy-operator keyed by image, none when it is not installed
+func (sv *SecurityValidator) operatorReports(ctx context.Context, namespace string) (map[string][]Vulnerability, error) {
+	list, err := sv.client.GetDynamicClient().Resource(vulnerabilityReportGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		log.Debug("trivy-operator not installed, scanning with the trivy CLI")
+		return map[string][]Vulnerability{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vulnerability reports: %w", err)
+	}
+
+	reports := map[string][]Vulnerability{}
+	for _, item := range list.Items {
+		image := reportImage(item.Object)
+		if image == "" {
+			continue
+		}
+		entries, _, _ := unstructured.NestedSlice(item.Object, "report", "vulnerabilities")
+		vulnerabilities := make([]Vulnerability, 0, len(entries))
+		for _, entry := range entries {
+			fields, _ := entry.(map[string]interface{})
+			vulnerability := Vulnerability{}
+			vulnerability.ID, _ = fields["vulnerabilityID"].(string)
+			vulnerability.Severity, _ = fields["severity"].(string)
+			vulnerability.Package, _ = fields["resource"].(string)
+			vulnerability.InstalledVersion, _ = fields["installedVersion"].(string)
+			vulnerability.FixedVersion, _ = fields["fixedVersion"].(string)
+			vulnerability.Title, _ = fields["title"].(string)
+			vulnerabilities = append(vulnerabilities, vulnerability)
+		}
+		// Several workloads running the same image each get a report, they hold the same vulnerabilities
+		reports[image] = vulnerabilities
+	}
+	return reports, nil
+}
+
+// reportImage rebuilds the normalized image reference a VulnerabilityReport was made for
+func reportImage(object map[string]interface{}) string {
+	server, _, _ := unstructured.NestedString(object, "report", "registry", "server")
+	repository, _, _ := unstructured.NestedString(object, "report", "artifact", "repository")
+	tag, _, _ := unstructured.NestedString(object, "report", "artifact", "tag")
+	digest, _, _ := unstructured.NestedString(object, "report", "artifact", "digest")
+	if repository == "" {
+		return ""
+	}
+	image := repository
+	if server != "" {
+		image = server + "/" + repository
+	}
+	switch {
+	case tag != "":
+		image += ":" + tag
+	case digest != "":
+		image += "@" + digest
+	}
+	return normalizeImage(image)
+}
+
+// normalizeImage drops the implicit Docker Hub registry and library namespace, so nginx:1.27 and
+// docker.io/library/nginx:1.27 compare equal
+func normalizeImage(image string) string {
+	for _, prefix := range []string{"index.docker.io/", "docker.io/"} {
+		image = strings.TrimPrefix(image, prefix)
+	}
+	return strings.TrimPrefix(image, "library/")
+}
+
+// trivyReport is the part of `trivy image --format json` the vulnerabilities are read from
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// scanWithTrivy scans image with the local trivy CLI
+func scanWithTrivy(ctx context.Context, trivy, image string, opts ImageScanOptions) ([]Vulnerability, error) {
+	scanCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	args := []string{"image", "--quiet", "--format", "json", "--scanners", "vuln"}
+	if opts.IgnoreUnfixed {
+		args = append(args, "--ignore-unfixed")
+	}
+	cmd := exec.CommandContext(scanCtx, trivy, append(args, image)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var report trivyReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse the trivy report: %w", err)
+	}
+	vulnerabilities := []Vulnerability{}
+	for _, target := range report.Results {
+		for _, found := range target.Vulnerabilities {
+			vulnerabilities = append(vulnerabilities, Vulnerability{
+				ID:               found.VulnerabilityID,
+				Severity:         found.Severity,
+				Package:          found.PkgName,
+				InstalledVersion: found.InstalledVersion,
+				FixedVersion:     found.FixedVersion,
+				Title:            found.Title,
+			})
+		}
+	}
+	return vulnerabilities, nil
+}
+
+// imageFindings sums up each image with critical or high vulnerabilities as one finding
+func imageFindings(result *ImageScanResult) []SecurityFinding {
+	findings := []SecurityFinding{}
+	for _, report := range result.Images {
+		critical, high := report.Severities["CRITICAL"], report.Severities["HIGH"]
+		if critical == 0 && high == 0 {
+			continue
+		}
+		severity := "High"
+		if critical > 0 {
+			severity = "Critical"
+		}
+		fixable := 0
+		for _, vulnerability := range report.Vulnerabilities {
+			if vulnerability.FixedVersion != "" && (vulnerability.Severity == "CRITICAL" || vulnerability.Severity == "HIGH") {
+				fixable++
+			}
+		}
+		findings = append(findings, SecurityFinding{
+			Severity:    severity,
+			Component:   "Image: " + report.Image,
+			Description: fmt.Sprintf("%d critical and %d high vulnerabilities, used by %s", critical, high, strings.Join(report.Pods, ", ")),
+			Remediation: fmt.Sprintf("Update the image, %d of these vulnerabilities have a fixed version", fixable),
+		})
+	}
+	return findings
+}
+
+func sortedImages(images map[string][]string) []string {
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -3,16 +3,20 @@ package security
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/log"
 	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/policy"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // SecurityValidator validates cluster security posture
 type SecurityValidator struct {
-	client *k8s.Client
+	client    *k8s.Client
+	benchmark *BenchmarkOptions
+	images    *ImageScanOptions
 }
 
 // SecurityStatus represents the security posture of the cluster
@@ -24,14 +28,20 @@ type SecurityStatus struct {
 	SecretsEncryption      bool              `json:"secrets_encryption"`
 	AdmissionControllers   []string          `json:"admission_controllers"`
 	SecurityScanning       bool              `json:"security_scanning"`
+	PolicyEngine           string            `json:"policy_engine,omitempty"`
+	PolicyViolations       int               `json:"policy_violations"`
 	ComplianceChecks       map[string]bool   `json:"compliance_checks"`
 	Vulnerabilities        []SecurityFinding `json:"vulnerabilities"`
+	Benchmark              *BenchmarkResult  `json:"benchmark,omitempty"`
+	ImageScan              *ImageScanResult  `json:"image_scan,omitempty"`
+	PolicyReport           *policy.Report    `json:"policy_report,omitempty"`
 }
 
 // SecurityFinding represents a security issue or vulnerability
 type SecurityFinding struct {
 	Severity    string `json:"severity"`
 	Component   string `json:"component"`
+	Control     string `json:"control,omitempty"`
 	Description string `json:"description"`
 	Remediation string `json:"remediation"`
 }
@@ -43,6 +53,17 @@ func NewSecurityValidator(client *k8s.Client) *SecurityValidator {
 	}
 }
 
+// SetBenchmark runs kube-bench as part of the validation, adding its failed controls to the vulnerabilities
+func (sv *SecurityValidator) SetBenchmark(opts BenchmarkOptions) {
+	sv.benchmark = &opts
+}
+
+// SetImageScan scans the images of the running pods as part of the validation, adding the images with
+// critical or high vulnerabilities to the vulnerabilities
+func (sv *SecurityValidator) SetImageScan(opts ImageScanOptions) {
+	sv.images = &opts
+}
+
 // ValidateClusterSecurity performs comprehensive security validation
 func (sv *SecurityValidator) ValidateClusterSecurity(ctx context.Context) (*SecurityStatus, error) {
 	log.Info("Performing comprehensive security validation")
@@ -82,6 +103,34 @@ func (sv *SecurityValidator) ValidateClusterSecurity(ctx context.Context) (*Secu
 		log.Warn("Admission controller validation failed", "error", err)
 	}
 
+	// Check baseline policy audit results
+	if err := sv.checkBaselinePolicies(ctx, status); err != nil {
+		log.Warn("Baseline policy audit failed", "error", err)
+	}
+
+	// Run the CIS benchmark
+	if sv.benchmark != nil {
+		result, err := sv.RunBenchmark(ctx, *sv.benchmark)
+		if err != nil {
+			log.Warn("CIS benchmark failed", "error", err)
+		} else {
+			status.Benchmark = result
+			status.Vulnerabilities = append(status.Vulnerabilities, result.Findings...)
+		}
+	}
+
+	// Scan the running images
+	if sv.images != nil {
+		result, err := sv.ScanImages(ctx, *sv.images)
+		if err != nil {
+			log.Warn("Image scan failed", "error", err)
+		} else {
+			status.ImageScan = result
+			status.SecurityScanning = true
+			status.Vulnerabilities = append(status.Vulnerabilities, imageFindings(result)...)
+		}
+	}
+
 	// Perform compliance checks
 	sv.performComplianceChecks(ctx, status)
 
@@ -119,7 +168,7 @@ func (sv *SecurityValidator) checkPodSecurityPolicies(ctx context.Context, statu
 				Severity:    "Medium",
 				Component:   "Pod Security",
 				Description: "No Pod Security Standards configured",
-				Remediation: "Configure Pod Security Standards on namespaces",
+				Remediation: "Declare security.namespace_baseline.pod_security and run 'bootstrap namespaces baseline'",
 			})
 		}
 	} else {
@@ -165,7 +214,7 @@ func (sv *SecurityValidator) checkNetworkPolicies(ctx context.Context, status *S
 					Severity:    "High",
 					Component:   "Network Security",
 					Description: fmt.Sprintf("Critical namespace %s lacks network policies", ns),
-					Remediation: fmt.Sprintf("Configure network policies for namespace %s", ns),
+					Remediation: fmt.Sprintf("Configure network policies for namespace %s, e.g. with security.namespace_baseline.network_policy", ns),
 				})
 			}
 		}
@@ -175,7 +224,7 @@ func (sv *SecurityValidator) checkNetworkPolicies(ctx context.Context, status *S
 			Severity:    "High",
 			Component:   "Network Security",
 			Description: "No Network Policies configured - all pod communication allowed",
-			Remediation: "Configure Network Policies to restrict pod-to-pod communication",
+			Remediation: "Declare security.namespace_baseline.network_policy and run 'bootstrap namespaces baseline'",
 		})
 	}
 
@@ -346,6 +395,63 @@ func (sv *SecurityValidator) checkAdmissionControllers(ctx context.Context, stat
 	return nil
 }
 
+// checkBaselinePolicies feeds the policy engine audit results into the security status
+func (sv *SecurityValidator) checkBaselinePolicies(ctx context.Context, status *SecurityStatus) error {
+	report, err := policy.NewAuditor(sv.client).Audit(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	if !report.Installed {
+		status.Vulnerabilities = append(status.Vulnerabilities, SecurityFinding{
+			Severity:    "Low",
+			Component:   "Policy Engine",
+			Description: "No baseline admission policies installed",
+			Remediation: "Enable security.policies and run 'bootstrap policy generate' to install Kyverno or Gatekeeper baseline policies",
+		})
+		return nil
+	}
+
+	status.PolicyEngine = string(report.Engine)
+	status.PolicyViolations = len(report.Violations)
+	status.PolicyReport = report
+
+	grouped := report.ByPolicy()
+	names := make([]string, 0, len(grouped))
+	for name := range grouped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		violations := grouped[name]
+		baseline, _ := policy.IsBaseline(name)
+
+		examples := make([]string, 0, 3)
+		for i := 0; i < len(violations) && i < 3; i++ {
+			examples = append(examples, violations[i].Resource())
+		}
+		description := fmt.Sprintf("%d resources violate %s (%s)", len(violations), baseline.Title, strings.Join(examples, ", "))
+		if len(violations) > len(examples) {
+			description = fmt.Sprintf("%d resources violate %s (%s, ...)", len(violations), baseline.Title, strings.Join(examples, ", "))
+		}
+
+		status.Vulnerabilities = append(status.Vulnerabilities, SecurityFinding{
+			Severity:    titleCase(baseline.Severity),
+			Component:   "Policy: " + name,
+			Description: description,
+			Remediation: baseline.Remediation,
+		})
+	}
+
+	log.Info("Baseline policy audit completed",
+		"engine", report.Engine,
+		"policies", report.Policies,
+		"violations", len(report.Violations))
+
+	return nil
+}
+
 // performComplianceChecks runs various compliance validations
 func (sv *SecurityValidator) performComplianceChecks(ctx context.Context, status *SecurityStatus) {
 	log.Info("Performing compliance checks")
@@ -354,6 +460,16 @@ func (sv *SecurityValidator) performComplianceChecks(ctx context.Context, status
 	status.ComplianceChecks["cis_rbac_enabled"] = status.RBACEnabled
 	status.ComplianceChecks["cis_network_policies"] = status.NetworkPolicies
 	status.ComplianceChecks["cis_pod_security"] = status.PodSecurityPolicies
+	status.ComplianceChecks["cis_baseline_policies"] = status.PolicyEngine != "" && status.PolicyViolations == 0
+	if status.Benchmark != nil {
+		status.ComplianceChecks["cis_benchmark"] = status.Benchmark.Fail == 0
+	}
+	// The policy reports tell which resources fail each control, overriding the configuration heuristics
+	if status.PolicyReport != nil {
+		for check, compliant := range status.PolicyReport.Compliance() {
+			status.ComplianceChecks[check] = compliant
+		}
+	}
 
 	// NIST checks
 	status.ComplianceChecks["nist_access_control"] = status.RBACEnabled && status.ServiceAccountSecurity
@@ -374,3 +490,10 @@ func (sv *SecurityValidator) performComplianceChecks(ctx context.Context, status
 		"compliant", compliantChecks,
 		"total", len(status.ComplianceChecks))
 }
+
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
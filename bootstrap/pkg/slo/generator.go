@@ -0,0 +1,203 @@
+package slo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/gitops"
+)
+
+// RuleNamespace is where the PrometheusRule is created for kube-prometheus-stack to load
+const RuleNamespace = "monitoring"
+
+// recordingWindows are the short ranges the burn rate alerts are evaluated over
+var recordingWindows = []string{"5m", "30m", "1h", "6h"}
+
+// burnAlert is a multi-window burn rate alert consuming budgetShare of the budget within long
+type burnAlert struct {
+	name        string
+	long, short string
+	budgetShare float64
+	duration    string
+	severity    string
+}
+
+var burnAlerts = []burnAlert{
+	{name: "SLOErrorBudgetBurnFast", long: "1h", short: "5m", budgetShare: 0.02, duration: "2m", severity: "critical"},
+	{name: "SLOErrorBudgetBurnSlow", long: "6h", short: "30m", budgetShare: 0.05, duration: "15m", severity: "warning"},
+}
+
+// Generator writes the SLO recording and alerting rules into the GitOps repository
+type Generator struct {
+	projectRoot string
+	cluster     string
+	objectives  []Objective
+}
+
+// NewGenerator creates a new generator for cluster under projectRoot
+func NewGenerator(projectRoot, cluster string, objectives []Objective) *Generator {
+	return &Generator{
+		projectRoot: projectRoot,
+		cluster:     cluster,
+		objectives:  objectives,
+	}
+}
+
+// Generate writes the PrometheusRule and returns the paths it wrote, relative to the project root
+func (g *Generator) Generate() ([]string, error) {
+	if len(g.objectives) == 0 {
+		return nil, fmt.Errorf("no SLOs declared in monitoring.slos")
+	}
+
+	clusterDir := filepath.Join(g.projectRoot, "kubernetes", g.cluster)
+	if _, err := os.Stat(clusterDir); err != nil {
+		return nil, fmt.Errorf("GitOps directory for %s not found: %w", g.cluster, err)
+	}
+
+	rules, err := g.renderRules()
+	if err != nil {
+		return nil, err
+	}
+
+	sloDir := filepath.Join(clusterDir, "slos")
+	dependsOn := gitops.FoundationKustomization(g.cluster)
+	if g.cluster != "nas" {
+		// PrometheusRule needs the Prometheus operator CRDs deployed by the monitoring layer
+		dependsOn = "monitoring"
+	}
+	files := map[string]string{
+		filepath.Join(sloDir, "slo-rules.yaml"):     rules,
+		filepath.Join(sloDir, "kustomization.yaml"): gitops.RenderKustomization([]string{"slo-rules.yaml"}),
+		filepath.Join(clusterDir, "slos.yaml"): gitops.RenderKustomizations(g.cluster, []gitops.Kustomization{{
+			Name:      gitops.Name(g.cluster, "slos"),
+			Path:      "slos",
+			DependsOn: dependsOn,
+		}}),
+	}
+
+	if err := os.RemoveAll(sloDir); err != nil {
+		return nil, fmt.Errorf("failed to clean %s: %w", sloDir, err)
+	}
+
+	written, err := gitops.WriteFiles(g.projectRoot, files)
+	if err != nil {
+		return nil, err
+	}
+
+	rootKustomization := filepath.Join(clusterDir, "kustomization.yaml")
+	added, err := gitops.AddResource(rootKustomization, "slos.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		written = append(written, gitops.Relative(g.projectRoot, rootKustomization))
+	}
+
+	log.Info("Generated SLO rules", "cluster", g.cluster, "objectives", len(g.objectives), "files", len(written))
+	return written, nil
+}
+
+// renderRules renders one recording group and one alerting group per objective
+func (g *Generator) renderRules() (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, `apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: homelab-slos
+  namespace: %s
+  labels:
+    release: kube-prometheus-stack
+    app.kubernetes.io/managed-by: homelab-bootstrap
+spec:
+  groups:
+`, RuleNamespace)
+
+	for _, objective := range g.objectives {
+		windowHours, err := hours(objective.Window)
+		if err != nil {
+			return "", fmt.Errorf("SLO %s: %w", objective.SLO, err)
+		}
+		labels := fmt.Sprintf("slo: %s\n            kind: %s", objective.SLO, objective.Kind)
+
+		fmt.Fprintf(&b, "    - name: slo-%s-recording\n      rules:\n", objective.Name())
+		windows := recordingWindows
+		if !contains(windows, objective.Window) {
+			windows = append(windows[:len(windows):len(windows)], objective.Window)
+		}
+		for _, window := range windows {
+			fmt.Fprintf(&b, `        - record: slo:error_ratio:rate%s
+          expr: %s
+          labels:
+            slo: %s
+            kind: %s
+`, window, strconv.Quote(objective.ErrorRatio(window)), objective.SLO, objective.Kind)
+		}
+		fmt.Fprintf(&b, `        - record: slo:objective:ratio
+          expr: vector(%s)
+          labels:
+            slo: %s
+            kind: %s
+`, formatFloat(objective.Target/100), objective.SLO, objective.Kind)
+
+		fmt.Fprintf(&b, "    - name: slo-%s-alerts\n      rules:\n", objective.Name())
+		for _, alert := range burnAlerts {
+			alertHours, _ := hours(alert.long)
+			factor := alert.budgetShare * windowHours / alertHours
+			threshold := formatFloat(factor * objective.Budget())
+			match := fmt.Sprintf(`{slo="%s",kind="%s"}`, objective.SLO, objective.Kind)
+			fmt.Fprintf(&b, `        - alert: %s
+          expr: slo:error_ratio:rate%s%s > %s and slo:error_ratio:rate%s%s > %s
+          for: %s
+          labels:
+            severity: %s
+            %s
+          annotations:
+            summary: %s
+            description: %s
+`, alert.name,
+				alert.long, match, threshold, alert.short, match, threshold,
+				alert.duration, alert.severity, labels,
+				strconv.Quote(fmt.Sprintf("SLO %s (%s) is burning its error budget %.1fx too fast", objective.SLO, objective.Kind, factor)),
+				strconv.Quote(fmt.Sprintf("%.0f%% of the %s error budget would be spent within %s at the current rate.", alert.budgetShare*100, objective.Window, alert.long)))
+		}
+	}
+
+	return b.String(), nil
+}
+
+// hours converts a Prometheus duration such as 30d or 6h to hours
+func hours(window string) (float64, error) {
+	if !windowPattern.MatchString(window) {
+		return 0, fmt.Errorf("invalid window %q", window)
+	}
+	value, _ := strconv.ParseFloat(window[:len(window)-1], 64)
+	switch window[len(window)-1] {
+	case 'w':
+		return value * 24 * 7, nil
+	case 'd':
+		return value * 24, nil
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q: %w", window, err)
+	}
+	return duration.Hours(), nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', 6, 64)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
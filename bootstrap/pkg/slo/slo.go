@@ -0,0 +1,145 @@
+package slo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// DefaultWindow is the compliance window of an SLO when none is configured
+const DefaultWindow = "30d"
+
+// Kinds of indicators an SLO can track
+const (
+	KindAvailability = "availability"
+	KindLatency      = "latency"
+)
+
+// windowPlaceholder is replaced by the evaluation range in custom queries
+const windowPlaceholder = "$window"
+
+var windowPattern = regexp.MustCompile(`^[0-9]+[smhdw]$`)
+
+// Objective is one indicator of a declared SLO with its PromQL ratio queries
+type Objective struct {
+	SLO    string
+	Kind   string
+	Target float64
+	Window string
+
+	// bad and total are rate expressions containing windowPlaceholder
+	bad   string
+	total string
+}
+
+// Name identifies the objective in rules and reports
+func (o Objective) Name() string {
+	return o.SLO + "-" + o.Kind
+}
+
+// Budget returns the fraction of requests allowed to be bad
+func (o Objective) Budget() float64 {
+	return 1 - o.Target/100
+}
+
+// ErrorRatio returns the PromQL ratio of bad requests over window
+func (o Objective) ErrorRatio(window string) string {
+	bad := strings.ReplaceAll(o.bad, windowPlaceholder, window)
+	total := strings.ReplaceAll(o.total, windowPlaceholder, window)
+	return fmt.Sprintf("(%s) / (%s)", bad, total)
+}
+
+// Parse turns the configured SLOs into objectives, one per declared indicator
+func Parse(slos []config.SLOConfig) ([]Objective, error) {
+	objectives := make([]Objective, 0, len(slos))
+	seen := make(map[string]bool, len(slos))
+
+	for _, slo := range slos {
+		if slo.Name == "" {
+			return nil, fmt.Errorf("SLO without a name")
+		}
+		if seen[slo.Name] {
+			return nil, fmt.Errorf("duplicate SLO %q", slo.Name)
+		}
+		seen[slo.Name] = true
+
+		window := slo.Window
+		if window == "" {
+			window = DefaultWindow
+		}
+		if !windowPattern.MatchString(window) {
+			return nil, fmt.Errorf("SLO %s: invalid window %q", slo.Name, window)
+		}
+
+		total := slo.TotalQuery
+		if total == "" {
+			if slo.Service == "" || slo.Namespace == "" {
+				return nil, fmt.Errorf("SLO %s: service and namespace are required without total_query", slo.Name)
+			}
+			total = fmt.Sprintf(`sum(rate(istio_requests_total{%s}[$window]))`, selector(slo))
+		}
+
+		if slo.Availability > 0 {
+			if slo.Availability >= 100 {
+				return nil, fmt.Errorf("SLO %s: availability must be below 100", slo.Name)
+			}
+			bad := slo.ErrorQuery
+			if bad == "" {
+				if slo.Service == "" {
+					return nil, fmt.Errorf("SLO %s: error_query is required without service", slo.Name)
+				}
+				bad = fmt.Sprintf(`sum(rate(istio_requests_total{%s,response_code=~"5.."}[$window]))`, selector(slo))
+			}
+			objectives = append(objectives, Objective{
+				SLO:    slo.Name,
+				Kind:   KindAvailability,
+				Target: slo.Availability,
+				Window: window,
+				bad:    bad,
+				total:  total,
+			})
+		}
+
+		if slo.LatencyThreshold != "" || slo.LatencyQuery != "" {
+			if slo.LatencyTarget <= 0 || slo.LatencyTarget >= 100 {
+				return nil, fmt.Errorf("SLO %s: latency_target must be between 0 and 100", slo.Name)
+			}
+			fast := slo.LatencyQuery
+			if fast == "" {
+				threshold, err := time.ParseDuration(slo.LatencyThreshold)
+				if err != nil {
+					return nil, fmt.Errorf("SLO %s: invalid latency_threshold %q: %w", slo.Name, slo.LatencyThreshold, err)
+				}
+				if slo.Service == "" {
+					return nil, fmt.Errorf("SLO %s: latency_query is required without service", slo.Name)
+				}
+				// The threshold must match a bucket boundary of the Istio duration histogram
+				fast = fmt.Sprintf(`sum(rate(istio_request_duration_milliseconds_bucket{%s,le=%q}[$window]))`,
+					selector(slo), strconv.FormatInt(threshold.Milliseconds(), 10))
+			}
+			objectives = append(objectives, Objective{
+				SLO:    slo.Name,
+				Kind:   KindLatency,
+				Target: slo.LatencyTarget,
+				Window: window,
+				bad:    fmt.Sprintf("(%s) - (%s)", total, fast),
+				total:  total,
+			})
+		}
+
+		if slo.Availability == 0 && slo.LatencyThreshold == "" && slo.LatencyQuery == "" {
+			return nil, fmt.Errorf("SLO %s declares neither availability nor latency", slo.Name)
+		}
+	}
+
+	return objectives, nil
+}
+
+// selector matches the server-side Istio metrics of the SLO service
+func selector(slo config.SLOConfig) string {
+	return fmt.Sprintf(`reporter="destination",destination_service_name=%q,destination_service_namespace=%q`, slo.Service, slo.Namespace)
+}
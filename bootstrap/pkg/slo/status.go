@@ -0,0 +1,76 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/metrics"
+)
+
+// DefaultPrometheus is the homelab Prometheus the error budgets are computed from
+const DefaultPrometheus = "http://kube-prometheus-stack-prometheus.monitoring.svc.cluster.local:9090"
+
+// Budget is the error budget state of one objective
+type Budget struct {
+	Objective Objective
+	HasData   bool
+	// SLI is the percentage of good requests over the objective window
+	SLI float64
+	// Remaining is the fraction of the error budget left, negative once exhausted
+	Remaining float64
+	// BurnRate is how many times faster than sustainable the budget burned over the last hour
+	BurnRate float64
+}
+
+// Exhausted reports whether the objective has spent its whole budget
+func (b Budget) Exhausted() bool {
+	return b.HasData && b.Remaining <= 0
+}
+
+// Reporter computes error budgets from Prometheus
+type Reporter struct {
+	client     *k8s.Client
+	prometheus string
+}
+
+// NewReporter creates a new reporter querying the Prometheus behind the prometheus service URL
+func NewReporter(client *k8s.Client, prometheus string) *Reporter {
+	if prometheus == "" {
+		prometheus = DefaultPrometheus
+	}
+	return &Reporter{
+		client:     client,
+		prometheus: prometheus,
+	}
+}
+
+// Report computes the error budget of every objective
+func (r *Reporter) Report(ctx context.Context, objectives []Objective) ([]Budget, error) {
+	budgets := make([]Budget, 0, len(objectives))
+	for _, objective := range objectives {
+		budget := Budget{Objective: objective}
+
+		ratio, found, err := metrics.QueryScalar(ctx, r.client, r.prometheus, objective.ErrorRatio(objective.Window), "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute SLO %s: %w", objective.Name(), err)
+		}
+		if found && !math.IsNaN(ratio) {
+			budget.HasData = true
+			budget.SLI = (1 - ratio) * 100
+			budget.Remaining = 1 - ratio/objective.Budget()
+		}
+
+		recent, found, err := metrics.QueryScalar(ctx, r.client, r.prometheus, objective.ErrorRatio("1h"), "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute burn rate of SLO %s: %w", objective.Name(), err)
+		}
+		if found && !math.IsNaN(recent) {
+			budget.BurnRate = recent / objective.Budget()
+		}
+
+		budgets = append(budgets, budget)
+	}
+	return budgets, nil
+}
@@ -0,0 +1,230 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// snapshotDir keeps the pre-destroy snapshots next to the run history
+const snapshotDir = ".bootstrap/snapshots"
+
+// manifestFile describes the content of a snapshot
+const manifestFile = "manifest.json"
+
+// Sections of a snapshot, restored in this order
+const (
+	sectionCRDs    = "crds"
+	sectionStorage = "storage"
+	sectionSecrets = "secrets"
+	sectionFlux    = "flux"
+)
+
+var (
+	crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	pvGVR  = schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumes"}
+	pvcGVR = schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+
+	secretGVR = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	// fluxGroups hold the sources, Kustomizations and HelmReleases, sources first so restores resolve their references
+	fluxGroups = []string{"source.toolkit.fluxcd.io", "kustomize.toolkit.fluxcd.io", "helm.toolkit.fluxcd.io"}
+	// skippedSecretTypes are recreated by Helm and the token controller rather than restored
+	skippedSecretTypes = []string{"helm.sh/release.v1", "kubernetes.io/service-account-token"}
+)
+
+// Manifest describes a snapshot
+type Manifest struct {
+	Cluster   string         `json:"cluster"`
+	CreatedAt time.Time      `json:"created_at"`
+	Counts    map[string]int `json:"counts"`
+	// Recipient is the age public key secrets are encrypted for, empty when they were skipped
+	Recipient string `json:"recipient,omitempty"`
+}
+
+// Dir returns where snapshots of the project are stored
+func Dir(projectRoot string) string {
+	return filepath.Join(projectRoot, snapshotDir)
+}
+
+// Exporter dumps the cluster state worth keeping across a destroy into a tarball
+type Exporter struct {
+	client *k8s.Client
+	key    *secrets.AgeKey
+}
+
+// NewExporter creates an exporter, secrets are only exported when an age key encrypts them
+func NewExporter(client *k8s.Client, key *secrets.AgeKey) *Exporter {
+	return &Exporter{client: client, key: key}
+}
+
+// Export writes <dir>/<cluster>-<timestamp>.tar.gz and returns its path
+func (e *Exporter) Export(ctx context.Context, dir, cluster string) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	manifest := &Manifest{Cluster: cluster, CreatedAt: time.Now().UTC(), Counts: map[string]int{}}
+	target := filepath.Join(dir, fmt.Sprintf("%s-%s.tar.gz", cluster, manifest.CreatedAt.Format("20060102-150405")))
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+
+	err = e.export(ctx, archive, manifest)
+	if err == nil {
+		err = writeJSON(archive, manifestFile, manifest)
+	}
+	for _, closer := range []interface{ Close() error }{archive, gz, file} {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		os.Remove(target)
+		return "", err
+	}
+
+	log.Info("📦 Cluster state exported", "path", target, "objects", manifest.Counts)
+	return target, nil
+}
+
+func (e *Exporter) export(ctx context.Context, archive *tar.Writer, manifest *Manifest) error {
+	if err := e.exportResource(ctx, archive, manifest, sectionCRDs, crdGVR); err != nil {
+		return err
+	}
+	for _, gvr := range []schema.GroupVersionResource{pvGVR, pvcGVR} {
+		if err := e.exportResource(ctx, archive, manifest, sectionStorage, gvr); err != nil {
+			return err
+		}
+	}
+
+	if e.key == nil {
+		log.Warn("No age key to encrypt secrets with, the snapshot leaves them out")
+	} else {
+		manifest.Recipient = e.key.Recipient
+		if err := e.exportResource(ctx, archive, manifest, sectionSecrets, secretGVR); err != nil {
+			return err
+		}
+	}
+
+	fluxResources, err := e.fluxResources()
+	if err != nil {
+		return err
+	}
+	for _, gvr := range fluxResources {
+		if err := e.exportResource(ctx, archive, manifest, sectionFlux, gvr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fluxResources lists the served Flux resources in restore order
+func (e *Exporter) fluxResources() ([]schema.GroupVersionResource, error) {
+	lists, err := e.client.GetClientset().Discovery().ServerPreferredResources()
+	if err != nil && len(lists) == 0 {
+		return nil, fmt.Errorf("failed to discover Flux resources: %w", err)
+	}
+	var resources []schema.GroupVersionResource
+	for _, group := range fluxGroups {
+		for _, list := range lists {
+			gv, err := schema.ParseGroupVersion(list.GroupVersion)
+			if err != nil || gv.Group != group {
+				continue
+			}
+			for _, resource := range list.APIResources {
+				if strings.Contains(resource.Name, "/") || !slices.Contains(resource.Verbs, "list") {
+					continue
+				}
+				resources = append(resources, gv.WithResource(resource.Name))
+			}
+		}
+	}
+	return resources, nil
+}
+
+// exportResource writes every object of gvr under section/<resource>/[<namespace>/]<name>.yaml
+func (e *Exporter) exportResource(ctx context.Context, archive *tar.Writer, manifest *Manifest, section string, gvr schema.GroupVersionResource) error {
+	list, err := e.client.GetDynamicClient().Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if gvr == secretGVR {
+			secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+			if slices.Contains(skippedSecretTypes, secretType) {
+				continue
+			}
+		}
+		sanitize(obj)
+
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		if gvr == secretGVR {
+			if data, err = secrets.EncryptManifest(ctx, data, []string{e.key.Recipient}); err != nil {
+				return fmt.Errorf("failed to encrypt secret %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+
+		name := path.Join(section, gvr.Resource, obj.GetNamespace(), obj.GetName()+".yaml")
+		if err := writeFile(archive, name, data); err != nil {
+			return err
+		}
+		manifest.Counts[gvr.Resource]++
+	}
+	return nil
+}
+
+// sanitize drops the server-populated fields so the object can be applied to a new cluster
+func sanitize(obj *unstructured.Unstructured) {
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	if obj.GetKind() == "PersistentVolume" {
+		// The claim is recreated with a new uid, a stale reference would keep the volume Released
+		unstructured.RemoveNestedField(obj.Object, "spec", "claimRef", "uid")
+		unstructured.RemoveNestedField(obj.Object, "spec", "claimRef", "resourceVersion")
+	}
+}
+
+func writeJSON(archive *tar.Writer, name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return writeFile(archive, name, data)
+}
+
+func writeFile(archive *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: time.Now()}
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := archive.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
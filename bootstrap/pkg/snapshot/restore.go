@@ -0,0 +1,119 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/fredericrous/homelab/bootstrap/pkg/secrets"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// RestoreResult summarizes a restore
+type RestoreResult struct {
+	Manifest *Manifest `json:"manifest"`
+	Applied  int       `json:"applied"`
+	Skipped  int       `json:"skipped"`
+	Failed   []string  `json:"failed,omitempty"`
+}
+
+// Restorer re-applies a snapshot to a cluster
+type Restorer struct {
+	client *k8s.Client
+	key    *secrets.AgeKey
+}
+
+// NewRestorer creates a restorer, secrets are skipped without the age key they were encrypted for
+func NewRestorer(client *k8s.Client, key *secrets.AgeKey) *Restorer {
+	return &Restorer{client: client, key: key}
+}
+
+// Restore applies the objects in the order they were exported: CRDs, volumes, claims, secrets then Flux objects
+func (r *Restorer) Restore(ctx context.Context, path string) (*RestoreResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	defer gz.Close()
+
+	result := &RestoreResult{}
+	namespaces := make(map[string]bool)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+
+		if header.Name == manifestFile {
+			result.Manifest = &Manifest{}
+			if err := json.Unmarshal(data, result.Manifest); err != nil {
+				return nil, fmt.Errorf("invalid snapshot manifest: %w", err)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(header.Name, sectionSecrets+"/") {
+			if r.key == nil {
+				result.Skipped++
+				continue
+			}
+			if data, err = secrets.DecryptManifest(ctx, data, r.key); err != nil {
+				result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", header.Name, err))
+				continue
+			}
+		}
+
+		if err := r.apply(ctx, data, namespaces); err != nil {
+			log.Warn("Failed to restore object", "entry", header.Name, "error", err)
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", header.Name, err))
+			continue
+		}
+		result.Applied++
+	}
+
+	if result.Skipped > 0 {
+		log.Warn("Secrets skipped, no age key to decrypt them", "count", result.Skipped)
+	}
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%d object(s) failed to restore", len(result.Failed))
+	}
+	return result, nil
+}
+
+// apply creates the namespace of the object on first use, then server-side applies it
+func (r *Restorer) apply(ctx context.Context, data []byte, namespaces map[string]bool) error {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(data, &obj.Object); err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	if namespace := obj.GetNamespace(); namespace != "" && !namespaces[namespace] {
+		if err := r.client.CreateNamespace(ctx, namespace); err != nil {
+			return err
+		}
+		namespaces[namespace] = true
+	}
+	_, err := r.client.Apply(ctx, obj, false)
+	return err
+}
@@ -0,0 +1,142 @@
+package talos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/siderolabs/talos/pkg/machinery/config"
+	"github.com/siderolabs/talos/pkg/machinery/config/configpatcher"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate"
+	"github.com/siderolabs/talos/pkg/machinery/config/generate/secrets"
+	"github.com/siderolabs/talos/pkg/machinery/config/machine"
+	"gopkg.in/yaml.v3"
+)
+
+// secretsFile holds the cluster CA and tokens, regenerating it would lock the bootstrap out of existing nodes
+const secretsFile = "secrets.yaml"
+
+// loadOrCreateBundle reuses the secrets bundle of a previous run so machine configs stay stable
+func loadOrCreateBundle(dir string, contract *config.VersionContract) (*secrets.Bundle, error) {
+	path := filepath.Join(dir, secretsFile)
+	bundle, err := secrets.LoadBundle(path)
+	if err == nil {
+		return bundle, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load Talos secrets %s: %w", path, err)
+	}
+
+	bundle, err = secrets.NewBundle(secrets.NewFixedClock(time.Now()), contract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Talos secrets: %w", err)
+	}
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Talos secrets: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write Talos secrets: %w", err)
+	}
+	log.Info("Generated Talos secrets bundle", "path", path)
+	return bundle, nil
+}
+
+// generateConfigs renders the machine config of every node and the admin talosconfig
+func generateConfigs(opts Options) (map[string][]byte, error) {
+	if err := os.MkdirAll(opts.ConfigDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", opts.ConfigDir, err)
+	}
+
+	var contract *config.VersionContract
+	if opts.TalosVersion != "" {
+		parsed, err := config.ParseContractFromVersion(opts.TalosVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Talos version %s: %w", opts.TalosVersion, err)
+		}
+		contract = parsed
+	}
+	bundle, err := loadOrCreateBundle(opts.ConfigDir, contract)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []string
+	for _, node := range opts.ControlPlanes() {
+		endpoints = append(endpoints, node.Address)
+	}
+	generateOpts := []generate.Option{
+		generate.WithSecretsBundle(bundle),
+		generate.WithVersionContract(contract),
+		generate.WithEndpointList(endpoints),
+		generate.WithAdditionalSubjectAltNames(endpoints),
+	}
+	if opts.InstallImage != "" {
+		generateOpts = append(generateOpts, generate.WithInstallImage(opts.InstallImage))
+	}
+	input, err := generate.NewInput(opts.ClusterName, opts.Endpoint, opts.KubernetesVersion, generateOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare Talos config generation: %w", err)
+	}
+
+	configs := make(map[string][]byte, len(opts.Nodes))
+	for _, node := range opts.Nodes {
+		data, err := renderMachineConfig(input, opts.Patches, node)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(opts.ConfigDir, node.Name()+".yaml")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write machine config %s: %w", path, err)
+		}
+		configs[node.Address] = data
+	}
+
+	talosconfig, err := input.Talosconfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate talosconfig: %w", err)
+	}
+	if talosContext := talosconfig.Contexts[talosconfig.Context]; talosContext != nil {
+		talosContext.Nodes = endpoints[:1]
+	}
+	if err := talosconfig.Save(opts.TalosconfigPath()); err != nil {
+		return nil, fmt.Errorf("failed to write talosconfig: %w", err)
+	}
+	return configs, nil
+}
+
+// renderMachineConfig generates the config of node and applies the common, node and hostname patches
+func renderMachineConfig(input *generate.Input, common []string, node Node) ([]byte, error) {
+	machineType := machine.TypeWorker
+	if node.Role == RoleControlPlane {
+		machineType = machine.TypeControlPlane
+	}
+	provider, err := input.Config(machineType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s config for %s: %w", node.Role, node.Name(), err)
+	}
+
+	var patchArgs []string
+	for _, path := range append(append([]string{}, common...), node.Patches...) {
+		patchArgs = append(patchArgs, "@"+path)
+	}
+	if node.Hostname != "" {
+		patchArgs = append(patchArgs, fmt.Sprintf("machine:\n  network:\n    hostname: %s\n", node.Hostname))
+	}
+	if len(patchArgs) == 0 {
+		return provider.Bytes()
+	}
+
+	patches, err := configpatcher.LoadPatches(patchArgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load patches for %s: %w", node.Name(), err)
+	}
+	patched, err := configpatcher.Apply(configpatcher.WithConfig(provider), patches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch config for %s: %w", node.Name(), err)
+	}
+	return patched.Bytes()
+}
@@ -0,0 +1,82 @@
+package talos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/log"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+)
+
+// EtcdSnapshots takes and recovers etcd snapshots through the Talos API of a control plane node
+type EtcdSnapshots struct {
+	talosconfig string
+	node        string
+}
+
+// NewEtcdSnapshots creates a snapshot source for the control plane at node
+func NewEtcdSnapshots(talosconfigPath, node string) *EtcdSnapshots {
+	return &EtcdSnapshots{talosconfig: talosconfigPath, node: node}
+}
+
+// Save streams an etcd snapshot of the node
+func (e *EtcdSnapshots) Save(ctx context.Context, w io.Writer) error {
+	c, err := e.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	reader, err := c.EtcdSnapshot(client.WithNode(ctx, e.node), &machineapi.EtcdSnapshotRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to start snapshot on %s: %w", e.node, err)
+	}
+	defer reader.Close()
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to read snapshot from %s: %w", e.node, err)
+	}
+	return nil
+}
+
+// Restore uploads the snapshot and bootstraps etcd from it, like talosctl bootstrap --recover-from.
+// etcd must not be running: the control plane nodes are reset or waiting to be bootstrapped
+func (e *EtcdSnapshots) Restore(ctx context.Context, path string) error {
+	snapshot, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer snapshot.Close()
+
+	c, err := e.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	nodeCtx := client.WithNode(ctx, e.node)
+	log.Info("Uploading snapshot", "node", e.node)
+	if _, err := c.EtcdRecover(nodeCtx, snapshot); err != nil {
+		return fmt.Errorf("failed to upload snapshot to %s: %w", e.node, err)
+	}
+	log.Info("Bootstrapping etcd from the snapshot", "node", e.node)
+	if err := c.Bootstrap(nodeCtx, &machineapi.BootstrapRequest{RecoverEtcd: true}); err != nil {
+		return fmt.Errorf("failed to bootstrap etcd from the snapshot on %s, etcd must be stopped on every control plane: %w", e.node, err)
+	}
+	return nil
+}
+
+func (e *EtcdSnapshots) client(ctx context.Context) (*client.Client, error) {
+	cfg, err := clientconfig.Open(e.talosconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read talosconfig: %w", err)
+	}
+	c, err := client.New(ctx, client.WithConfig(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Talos client: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,243 @@
+package talos
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	machineapi "github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiTimeout        = 5 * time.Minute
+	kubeconfigTimeout = 10 * time.Minute
+	pollInterval      = 10 * time.Second
+)
+
+// Step is a phase of bringing the cluster up
+type Step struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context) error
+}
+
+// Lifecycle brings a Talos cluster from booted machines to a reachable Kubernetes API
+type Lifecycle struct {
+	opts     Options
+	configs  map[string][]byte
+	progress func(message string)
+}
+
+// NewLifecycle creates a lifecycle for the cluster described by opts
+func NewLifecycle(opts Options) *Lifecycle {
+	return &Lifecycle{opts: opts}
+}
+
+// SetOptions replaces the options before the steps run, once provisioning knows the node addresses
+func (l *Lifecycle) SetOptions(opts Options) {
+	l.opts = opts
+}
+
+// SetProgressSink forwards per-node progress, e.g. to the TUI log pane
+func (l *Lifecycle) SetProgressSink(sink func(message string)) {
+	l.progress = sink
+}
+
+// Steps returns the phases of Up in order
+func (l *Lifecycle) Steps() []Step {
+	return []Step{
+		{Name: "generate-configs", Description: "Generate Talos machine configs", Run: l.GenerateConfigs},
+		{Name: "apply-configs", Description: "Apply machine configs to nodes", Run: l.ApplyConfigs},
+		{Name: "bootstrap-etcd", Description: "Bootstrap etcd on the first control plane", Run: l.BootstrapEtcd},
+		{Name: "fetch-kubeconfig", Description: "Fetch the cluster kubeconfig", Run: l.FetchKubeconfig},
+		{Name: "wait-kubernetes", Description: "Wait for the Kubernetes API", Run: l.WaitForKubernetes},
+	}
+}
+
+// Up runs every step, stopping at the first failure
+func (l *Lifecycle) Up(ctx context.Context) error {
+	for i, step := range l.Steps() {
+		log.Info("Talos lifecycle step", "step", i+1, "name", step.Name, "description", step.Description)
+		if err := step.Run(ctx); err != nil {
+			return fmt.Errorf("%s failed: %w", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// GenerateConfigs writes the machine configs and talosconfig, reusing the secrets of previous runs
+func (l *Lifecycle) GenerateConfigs(ctx context.Context) error {
+	configs, err := generateConfigs(l.opts)
+	if err != nil {
+		return err
+	}
+	l.configs = configs
+	l.report("Generated machine configs for %d nodes in %s", len(configs), l.opts.ConfigDir)
+	return nil
+}
+
+// ApplyConfigs sends each node its machine config, through the maintenance API on first install
+func (l *Lifecycle) ApplyConfigs(ctx context.Context) error {
+	if l.configs == nil {
+		if err := l.GenerateConfigs(ctx); err != nil {
+			return err
+		}
+	}
+	if err := k8s.GuardMutation("apply Talos machine configs"); err != nil {
+		return err
+	}
+
+	for _, node := range l.opts.Nodes {
+		if err := l.applyConfig(ctx, node); err != nil {
+			return err
+		}
+		l.report("Applied %s config to %s", node.Role, node.Name())
+	}
+	return nil
+}
+
+func (l *Lifecycle) applyConfig(ctx context.Context, node Node) error {
+	request := &machineapi.ApplyConfigurationRequest{
+		Data: l.configs[node.Address],
+		Mode: machineapi.ApplyConfigurationRequest_AUTO,
+	}
+
+	// Unconfigured machines only serve the maintenance API, with a self-signed certificate
+	insecure, err := client.New(ctx,
+		client.WithTLSConfig(&tls.Config{InsecureSkipVerify: true}),
+		client.WithEndpoints(node.Address))
+	if err == nil {
+		_, err = insecure.ApplyConfiguration(ctx, request)
+		insecure.Close()
+		if err == nil {
+			return nil
+		}
+	}
+	log.Debug("Maintenance API rejected the config, retrying with talosconfig", "node", node.Name(), "error", err)
+
+	c, err := l.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if _, err := c.ApplyConfiguration(client.WithNode(ctx, node.Address), request); err != nil {
+		return fmt.Errorf("failed to apply config to %s: %w", node.Name(), err)
+	}
+	return nil
+}
+
+// BootstrapEtcd bootstraps etcd once the first control plane API answers, an already bootstrapped cluster is left alone
+func (l *Lifecycle) BootstrapEtcd(ctx context.Context) error {
+	if err := k8s.GuardMutation("bootstrap Talos etcd"); err != nil {
+		return err
+	}
+	c, err := l.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	node := l.opts.ControlPlanes()[0]
+	nodeCtx := client.WithNode(ctx, node.Address)
+	l.report("Waiting for the Talos API on %s", node.Name())
+	var lastErr error
+	err = k8s.Poll(nodeCtx, pollInterval, apiTimeout, func(ctx context.Context) (bool, error) {
+		_, lastErr = c.Version(ctx)
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("Talos API on %s not ready: %w", node.Name(), firstErr(lastErr, err))
+	}
+
+	if err := c.Bootstrap(nodeCtx, &machineapi.BootstrapRequest{}); err != nil {
+		if status.Code(err) == codes.AlreadyExists || strings.Contains(err.Error(), "etcd data directory is not empty") {
+			l.report("Cluster already bootstrapped on %s", node.Name())
+			return nil
+		}
+		return fmt.Errorf("failed to bootstrap etcd on %s: %w", node.Name(), err)
+	}
+	l.report("Bootstrapped etcd on %s", node.Name())
+	return nil
+}
+
+// FetchKubeconfig writes the admin kubeconfig once the control plane issues it
+func (l *Lifecycle) FetchKubeconfig(ctx context.Context) error {
+	c, err := l.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	node := l.opts.ControlPlanes()[0]
+	var kubeconfig []byte
+	var lastErr error
+	err = k8s.Poll(client.WithNode(ctx, node.Address), pollInterval, kubeconfigTimeout, func(ctx context.Context) (bool, error) {
+		kubeconfig, lastErr = c.Kubeconfig(ctx)
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch kubeconfig from %s: %w", node.Name(), firstErr(lastErr, err))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.opts.KubeconfigPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+	if err := os.WriteFile(l.opts.KubeconfigPath, kubeconfig, 0o600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	l.report("Wrote kubeconfig to %s", l.opts.KubeconfigPath)
+	return nil
+}
+
+// WaitForKubernetes waits until the API server answers, nodes stay NotReady until the CNI is installed
+func (l *Lifecycle) WaitForKubernetes(ctx context.Context) error {
+	k8sClient, err := k8s.NewClient(l.opts.KubeconfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	if err := k8sClient.WaitForReady(ctx, kubeconfigTimeout); err != nil {
+		return fmt.Errorf("Kubernetes API not ready: %w", err)
+	}
+	l.report("Kubernetes API is ready at %s", l.opts.Endpoint)
+	return nil
+}
+
+// client connects with the generated talosconfig
+func (l *Lifecycle) client(ctx context.Context) (*client.Client, error) {
+	cfg, err := clientconfig.Open(l.opts.TalosconfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read talosconfig: %w", err)
+	}
+	c, err := client.New(ctx, client.WithConfig(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Talos client: %w", err)
+	}
+	return c, nil
+}
+
+func (l *Lifecycle) report(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Info(message)
+	if l.progress != nil {
+		l.progress(message)
+	}
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package talos
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// Machine roles
+const (
+	RoleControlPlane = "controlplane"
+	RoleWorker       = "worker"
+)
+
+// Node is a Talos machine the cluster is built from
+type Node struct {
+	Address  string
+	Role     string
+	Hostname string
+	Patches  []string // Absolute patch file paths applied after the common patches
+}
+
+// Name identifies the node in logs and file names
+func (n Node) Name() string {
+	if n.Hostname != "" {
+		return n.Hostname
+	}
+	return n.Address
+}
+
+// Options describes the cluster the lifecycle brings up
+type Options struct {
+	ClusterName       string
+	Endpoint          string
+	TalosVersion      string
+	KubernetesVersion string
+	InstallImage      string
+	ConfigDir         string
+	Patches           []string // Absolute patch file paths applied to every node
+	Nodes             []Node
+	KubeconfigPath    string
+}
+
+// OptionsFromConfig resolves the lifecycle options of the homelab cluster
+func OptionsFromConfig(cfg *config.HomelabConfig, projectRoot string) (Options, error) {
+	talos := cfg.Talos
+	opts := Options{
+		ClusterName:       cfg.Cluster.Name,
+		Endpoint:          talos.Endpoint,
+		TalosVersion:      talos.Version,
+		KubernetesVersion: strings.TrimPrefix(talos.KubernetesVersion, "v"),
+		InstallImage:      talos.InstallImage,
+		ConfigDir:         resolvePath(projectRoot, talos.ConfigDir),
+		Patches:           resolvePaths(projectRoot, talos.Patches),
+		KubeconfigPath:    cfg.Cluster.KubeConfig,
+	}
+	if opts.KubernetesVersion == "" {
+		opts.KubernetesVersion = strings.TrimPrefix(cfg.Cluster.Version, "v")
+	}
+	if opts.ConfigDir == "" {
+		opts.ConfigDir = filepath.Join(projectRoot, "infrastructure", "homelab", "talos")
+	}
+
+	for _, node := range talos.Nodes {
+		opts.Nodes = append(opts.Nodes, Node{
+			Address:  node.Address,
+			Role:     node.Role,
+			Hostname: node.Hostname,
+			Patches:  resolvePaths(projectRoot, node.Patches),
+		})
+	}
+	if len(opts.Nodes) == 0 {
+		// The first of cluster.nodes runs the control plane, as the Terraform stage did
+		for i, address := range cfg.Cluster.Nodes {
+			role := RoleWorker
+			if i == 0 {
+				role = RoleControlPlane
+			}
+			opts.Nodes = append(opts.Nodes, Node{Address: address, Role: role})
+		}
+	}
+
+	controlPlanes := opts.ControlPlanes()
+	if len(controlPlanes) == 0 {
+		return opts, fmt.Errorf("talos needs at least one controlplane node")
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = fmt.Sprintf("https://%s:6443", controlPlanes[0].Address)
+	}
+	if opts.KubeconfigPath == "" {
+		return opts, fmt.Errorf("homelab kubeconfig path not configured")
+	}
+	return opts, nil
+}
+
+// ControlPlanes returns the control plane nodes
+func (o Options) ControlPlanes() []Node {
+	var nodes []Node
+	for _, node := range o.Nodes {
+		if node.Role == RoleControlPlane {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// TalosconfigPath is where the admin talosconfig is written
+func (o Options) TalosconfigPath() string {
+	return filepath.Join(o.ConfigDir, "talosconfig")
+}
+
+func resolvePath(root, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+func resolvePaths(root string, paths []string) []string {
+	resolved := make([]string, 0, len(paths))
+	for _, path := range paths {
+		resolved = append(resolved, resolvePath(root, path))
+	}
+	return resolved
+}
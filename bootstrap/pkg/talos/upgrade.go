@@ -0,0 +1,126 @@
+package talos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	clientconfig "github.com/siderolabs/talos/pkg/machinery/client/config"
+)
+
+const (
+	drainTimeout  = 5 * time.Minute
+	rejoinTimeout = 15 * time.Minute
+)
+
+// UpgradeOptions selects what a rolling upgrade installs and where
+type UpgradeOptions struct {
+	Image  string   // Talos installer image, e.g. factory.talos.dev/installer/<schematic>:v1.11.0
+	Nodes  []string // Node addresses, upgraded in this order
+	Serial int      // Nodes upgraded at the same time
+}
+
+// Upgrader upgrades Talos nodes one batch at a time, draining them from Kubernetes first
+type Upgrader struct {
+	k8sClient   *k8s.Client
+	talosconfig string
+}
+
+// NewUpgrader creates an upgrader using the admin talosconfig
+func NewUpgrader(k8sClient *k8s.Client, talosconfigPath string) *Upgrader {
+	return &Upgrader{k8sClient: k8sClient, talosconfig: talosconfigPath}
+}
+
+// Upgrade rolls opts.Image out to the nodes, stopping after the first batch with a failure
+func (u *Upgrader) Upgrade(ctx context.Context, opts UpgradeOptions) error {
+	if opts.Image == "" {
+		return fmt.Errorf("an installer image is required")
+	}
+	if len(opts.Nodes) == 0 {
+		return fmt.Errorf("no node selected")
+	}
+	if err := k8s.GuardMutation("upgrade Talos nodes"); err != nil {
+		return err
+	}
+	serial := opts.Serial
+	if serial < 1 {
+		serial = 1
+	}
+
+	for start := 0; start < len(opts.Nodes); start += serial {
+		batch := opts.Nodes[start:min(start+serial, len(opts.Nodes))]
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i, address := range batch {
+			wg.Add(1)
+			go func(i int, address string) {
+				defer wg.Done()
+				errs[i] = u.upgradeNode(ctx, address, opts.Image)
+			}(i, address)
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgradeNode cordons and drains the node, installs the image, waits for it to reboot Ready and uncordons it
+func (u *Upgrader) upgradeNode(ctx context.Context, address, image string) error {
+	node, err := u.k8sClient.NodeByAddress(ctx, address)
+	if err != nil {
+		return err
+	}
+	name := node.Name
+	bootID := node.Status.NodeInfo.BootID
+
+	log.Info("Cordoning node", "node", name, "address", address)
+	if err := u.k8sClient.SetUnschedulable(ctx, name, true); err != nil {
+		return err
+	}
+	if err := u.k8sClient.DrainNode(ctx, name, drainTimeout); err != nil {
+		return fmt.Errorf("failed to drain node %s, it stays cordoned: %w", name, err)
+	}
+
+	cfg, err := clientconfig.Open(u.talosconfig)
+	if err != nil {
+		return fmt.Errorf("failed to read talosconfig: %w", err)
+	}
+	c, err := client.New(ctx, client.WithConfig(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to create Talos client: %w", err)
+	}
+	defer c.Close()
+
+	log.Info("Upgrading Talos", "node", name, "image", image)
+	if _, err := c.UpgradeWithOptions(client.WithNode(ctx, address),
+		client.WithUpgradeImage(image),
+		client.WithUpgradePreserve(true)); err != nil {
+		return fmt.Errorf("failed to start upgrade of node %s, it stays cordoned: %w", name, err)
+	}
+
+	// A new boot ID tells the node went through the reboot rather than not having left yet
+	log.Info("Waiting for node to reboot and rejoin", "node", name)
+	err = k8s.PollAfter(ctx, 10*time.Second, rejoinTimeout, func(ctx context.Context) (bool, error) {
+		current, err := u.k8sClient.NodeByAddress(ctx, address)
+		if err != nil {
+			return false, nil
+		}
+		return current.Status.NodeInfo.BootID != bootID && k8s.NodeReady(current), nil
+	})
+	if err != nil {
+		return fmt.Errorf("node %s did not rejoin Ready after the upgrade, it stays cordoned: %w", name, err)
+	}
+
+	if err := u.k8sClient.SetUnschedulable(ctx, name, false); err != nil {
+		return err
+	}
+	log.Info("✅ Node upgraded", "node", name)
+	return nil
+}
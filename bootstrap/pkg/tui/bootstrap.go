@@ -3,6 +3,7 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -12,30 +13,51 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/fredericrous/homelab/bootstrap/pkg/bootstrap"
 	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/history"
 	"github.com/fredericrous/homelab/bootstrap/pkg/logger"
+	"github.com/fredericrous/homelab/bootstrap/pkg/progress"
 )
 
-// BootstrapModel represents the TUI model for bootstrap process
+// BootstrapModel represents the TUI model for bootstrap process. It drives the orchestrator one step at a
+// time, so a failed step can be retried or, when optional, skipped, and the run paused between steps.
 type BootstrapModel struct {
 	config       *config.Config
 	orchestrator *bootstrap.Orchestrator
+	driver       *bootstrap.StepDriver
 	steps        []BootstrapStep
 	currentStep  int
 	status       string
 	logs         []string
 	err          error
 	done         bool
+	running      bool
+	failed       bool
+	paused       bool
+	quitting     bool
 	ctx          context.Context
+
+	// The full-log viewport shows the log file, following its end unless scrolled up
+	logFile   string
+	showLogs  bool
+	logLines  []string
+	logOffset int
+	logFollow bool
+	logRead   time.Time
+	height    int
 }
 
 // BootstrapStep represents a single bootstrap step
 type BootstrapStep struct {
 	Name        string
 	Description string
-	Status      StepStatus
-	Error       error
-	StartTime   time.Time
-	EndTime     time.Time
+	// Optional steps can fail or be skipped without stopping the bootstrap
+	Optional bool
+	// Progress is the last progress update of the running step
+	Progress  string
+	Status    StepStatus
+	Error     error
+	StartTime time.Time
+	EndTime   time.Time
 }
 
 // StepStatus represents the status of a bootstrap step
@@ -46,6 +68,7 @@ const (
 	StepRunning
 	StepCompleted
 	StepFailed
+	StepSkipped
 )
 
 func (s StepStatus) String() string {
@@ -58,6 +81,8 @@ func (s StepStatus) String() string {
 		return "✅"
 	case StepFailed:
 		return "❌"
+	case StepSkipped:
+		return "⏭️"
 	default:
 		return "?"
 	}
@@ -92,7 +117,7 @@ func defaultOrchestratorOptions(isNAS bool) *bootstrap.OrchestratorOptions {
 }
 
 // NewBootstrapModel creates a new bootstrap TUI model
-func NewBootstrapModel(ctx context.Context, cfg *config.Config, isNAS bool) *BootstrapModel {
+func NewBootstrapModel(ctx context.Context, cfg *config.Config, isNAS bool, opts ...*bootstrap.OrchestratorOptions) *BootstrapModel {
 	// Set up comprehensive file logging for TUI mode
 	// Infrastructure tools should always provide detailed logs for troubleshooting
 	logFileName := "bootstrap.log"
@@ -100,130 +125,324 @@ func NewBootstrapModel(ctx context.Context, cfg *config.Config, isNAS bool) *Boo
 	if f, err := tea.LogToFile(logFileName, "tui"); err == nil {
 		// Redirect application logs to the same file with debug level
 		logger.SetupTUILogger(f)
+		if run := history.FromContext(ctx); run != nil {
+			run.AddLog(logFileName)
+		}
 		// Don't defer close here - the file needs to stay open for the entire TUI session
 	}
 
 	// Create orchestrator for actual bootstrap operations
-	orchestrator, orchErr := bootstrap.NewOrchestrator(cfg, isNAS, defaultOrchestratorOptions(isNAS))
-	if orchErr != nil {
-		log.Error("Failed to create orchestrator for TUI", "error", orchErr)
-	}
-	steps := []BootstrapStep{
-		{
-			Name:        "check-prereq",
-			Description: "Check prerequisites and validate configuration",
-			Status:      StepPending,
-		},
-		{
-			Name:        "connect-cluster",
-			Description: "Connect to Kubernetes cluster",
-			Status:      StepPending,
-		},
-		{
-			Name:        "install-flux",
-			Description: "Install FluxCD GitOps controller",
-			Status:      StepPending,
-		},
-		{
-			Name:        "bootstrap-gitops",
-			Description: "Bootstrap GitOps repository synchronization",
-			Status:      StepPending,
-		},
-		{
-			Name:        "wait-controllers",
-			Description: "Wait for controllers to be ready",
-			Status:      StepPending,
-		},
-		{
-			Name:        "validate",
-			Description: "Validate infrastructure deployment",
-			Status:      StepPending,
-		},
-	}
-
-	if !isNAS {
-		// Add homelab-specific steps
-		steps = append(steps[:2], append([]BootstrapStep{
-			{
-				Name:        "install-cilium",
-				Description: "Install Cilium CNI",
-				Status:      StepPending,
-			},
-			{
-				Name:        "setup-vault",
-				Description: "Setup Vault integration",
-				Status:      StepPending,
-			},
-		}, steps[2:]...)...)
-	}
-
-	return &BootstrapModel{
-		config:       cfg,
-		orchestrator: orchestrator,
-		steps:        steps,
-		currentStep:  0,
-		logs:         []string{},
-		ctx:          ctx,
+	options := defaultOrchestratorOptions(isNAS)
+	if len(opts) > 0 && opts[0] != nil {
+		options = opts[0]
+	}
+	model := &BootstrapModel{
+		config:    cfg,
+		logs:      []string{},
+		ctx:       ctx,
+		logFile:   logFileName,
+		logFollow: true,
+		height:    24,
+	}
+	orchestrator, err := bootstrap.NewOrchestrator(cfg, isNAS, options)
+	if err != nil {
+		log.Error("Failed to create orchestrator for TUI", "error", err)
+		model.err = fmt.Errorf("failed to create orchestrator: %w", err)
+		return model
+	}
+	model.orchestrator = orchestrator
+
+	driver, err := orchestrator.NewStepDriver(ctx)
+	if err != nil {
+		model.err = err
+		return model
 	}
+	model.driver = driver
+	model.currentStep = driver.Next()
+	for i, step := range driver.Steps() {
+		status := StepPending
+		if i < driver.Next() {
+			// Completed in the previous run the bootstrap resumes
+			status = StepCompleted
+		}
+		model.steps = append(model.steps, BootstrapStep{Name: step.Name, Description: step.Description, Optional: !step.Required, Status: status})
+	}
+	return model
+}
+
+// Err returns why the bootstrap did not complete, nil once every step completed or was skipped
+func (m *BootstrapModel) Err() error {
+	if m.err == nil && !m.done {
+		return fmt.Errorf("interrupted before all steps completed")
+	}
+	return m.err
 }
 
 // Init initializes the TUI model
 func (m *BootstrapModel) Init() tea.Cmd {
-	return tea.Batch(
-		m.startBootstrap(),
-		tea.Tick(time.Millisecond*100, func(t time.Time) tea.Msg {
-			return TickMsg(t)
-		}),
-	)
+	return tea.Batch(m.advance(), tick())
 }
 
 // TickMsg represents a tick message for updating the UI
 type TickMsg time.Time
 
+// bootstrapDoneMsg carries the result of finishing the bootstrap, or of giving up on a failed step
+type bootstrapDoneMsg struct{ err error }
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Millisecond*100, func(t time.Time) tea.Msg {
+		return TickMsg(t)
+	})
+}
+
 // Update handles TUI messages
 func (m *BootstrapModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
 	case tea.KeyMsg:
+		if m.showLogs && m.scrollLogs(msg.String()) {
+			return m, nil
+		}
 		switch msg.String() {
-		case "ctrl+c", "q":
+		case "ctrl+c":
 			return m, tea.Quit
-		}
-	case StepCompleteMsg:
-		if m.currentStep < len(m.steps) {
-			m.steps[m.currentStep].Status = StepCompleted
-			m.steps[m.currentStep].EndTime = time.Now()
-			m.currentStep++
-
-			if m.currentStep < len(m.steps) {
-				return m, m.runStep(m.currentStep)
-			} else {
-				m.done = true
-				m.status = "🎉 Bootstrap completed successfully!"
+		case "q":
+			return m, m.quit()
+		case "r":
+			if m.failed && !m.running && !m.quitting {
+				m.failed = false
+				m.steps[m.currentStep].Error = nil
+				return m, m.runStep()
+			}
+		case "s":
+			return m, m.skip()
+		case "p":
+			return m, m.togglePause()
+		case "l":
+			m.showLogs = !m.showLogs
+			if m.showLogs {
+				m.logFollow = true
+				m.readLogs()
 			}
 		}
+	case StepCompleteMsg:
+		m.running = false
+		m.steps[m.currentStep].Status = StepCompleted
+		m.steps[m.currentStep].EndTime = time.Now()
+		m.currentStep = m.driver.Next()
+		return m, m.advance()
 	case StepErrorMsg:
-		if m.currentStep < len(m.steps) {
-			m.steps[m.currentStep].Status = StepFailed
-			m.steps[m.currentStep].Error = msg.Error
-			m.steps[m.currentStep].EndTime = time.Now()
-			m.err = msg.Error
-			m.status = fmt.Sprintf("❌ Bootstrap failed: %v", msg.Error)
+		m.running = false
+		m.failed = true
+		m.steps[m.currentStep].Status = StepFailed
+		m.steps[m.currentStep].Error = msg.Error
+		m.steps[m.currentStep].EndTime = time.Now()
+		m.status = m.failedStatus()
+	case bootstrapDoneMsg:
+		if m.quitting {
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		m.done = true
+		m.err = msg.err
+		if msg.err == nil {
+			m.status = "🎉 Bootstrap completed successfully!"
+		} else {
+			m.status = fmt.Sprintf("❌ Bootstrap failed: %v", msg.err)
 		}
 	case LogMsg:
-		m.logs = append(m.logs, msg.Message)
-		// Keep only last 10 log messages
-		if len(m.logs) > 10 {
-			m.logs = m.logs[1:]
+		m.addLog(msg.Message)
+	case ProgressMsg:
+		switch msg.Type {
+		case progress.Warning:
+			m.addLog("⚠️ " + msg.Message)
+		case progress.StepProgress:
+			if m.currentStep < len(m.steps) && m.steps[m.currentStep].Name == msg.Step {
+				m.steps[m.currentStep].Progress = msg.Message
+				if msg.Percent >= 0 {
+					m.steps[m.currentStep].Progress = fmt.Sprintf("%d%% %s", msg.Percent, msg.Message)
+				}
+			}
 		}
 	case TickMsg:
-		return m, tea.Tick(time.Millisecond*100, func(t time.Time) tea.Msg {
-			return TickMsg(t)
-		})
+		if m.showLogs && time.Since(m.logRead) > time.Second {
+			m.readLogs()
+		}
+		return m, tick()
 	}
 
 	return m, nil
 }
 
+func (m *BootstrapModel) addLog(message string) {
+	m.logs = append(m.logs, message)
+	// Keep only last 10 log messages
+	if len(m.logs) > 10 {
+		m.logs = m.logs[1:]
+	}
+}
+
+// advance starts the next step, or finishes the bootstrap once every step is done, unless paused
+func (m *BootstrapModel) advance() tea.Cmd {
+	if m.driver == nil {
+		return nil
+	}
+	if m.driver.Done() {
+		m.status = "Finishing bootstrap..."
+		return func() tea.Msg {
+			return bootstrapDoneMsg{err: m.driver.Finish(m.ctx)}
+		}
+	}
+	if m.paused {
+		m.status = fmt.Sprintf("⏸️  Paused before %s, press 'p' to continue", m.steps[m.currentStep].Name)
+		return nil
+	}
+	return m.runStep()
+}
+
+// runStep runs the current step through the driver, which records it in the run history
+func (m *BootstrapModel) runStep() tea.Cmd {
+	step := &m.steps[m.currentStep]
+	step.Status = StepRunning
+	step.StartTime = time.Now()
+	step.EndTime = time.Time{}
+	step.Progress = ""
+	m.running = true
+	m.status = ""
+	if m.paused {
+		m.status = "⏸️  Pausing after this step"
+	}
+	return func() tea.Msg {
+		if err := m.driver.Run(m.ctx); err != nil {
+			return StepErrorMsg{Error: err}
+		}
+		return StepCompleteMsg{}
+	}
+}
+
+// skip moves past the current optional step, failed or waiting on a pause
+func (m *BootstrapModel) skip() tea.Cmd {
+	if m.driver == nil || m.driver.Done() || m.running || m.done || m.quitting || !(m.failed || m.paused) {
+		return nil
+	}
+	if err := m.driver.Skip(); err != nil {
+		m.status = fmt.Sprintf("❌ %v", err)
+		return nil
+	}
+	m.failed = false
+	m.steps[m.currentStep].Status = StepSkipped
+	m.currentStep = m.driver.Next()
+	return m.advance()
+}
+
+// togglePause holds the bootstrap before the next step, or resumes it
+func (m *BootstrapModel) togglePause() tea.Cmd {
+	if m.driver == nil || m.done || m.quitting {
+		return nil
+	}
+	m.paused = !m.paused
+	if m.running || m.failed || m.driver.Done() {
+		switch {
+		case m.paused:
+			m.status = "⏸️  Pausing after this step"
+		case m.failed:
+			m.status = m.failedStatus()
+		default:
+			m.status = ""
+		}
+		return nil
+	}
+	// Waiting before the next step, which advance runs unless paused again
+	return m.advance()
+}
+
+// quit exits, giving up on a failed step first: its checkpoint is saved for --resume and the completed
+// steps are rolled back, as a non-interactive bootstrap does
+func (m *BootstrapModel) quit() tea.Cmd {
+	if !m.failed || m.running || m.quitting {
+		return tea.Quit
+	}
+	if !m.driver.Current().Required {
+		return tea.Quit
+	}
+	m.quitting = true
+	m.status = "Rolling back the completed steps..."
+	return func() tea.Msg {
+		return bootstrapDoneMsg{err: m.driver.Fail(m.ctx)}
+	}
+}
+
+func (m *BootstrapModel) failedStatus() string {
+	step := m.driver.Current()
+	if step.Required {
+		return fmt.Sprintf("❌ Step %s failed, press 'r' to retry or 'q' to give up", step.Name)
+	}
+	return fmt.Sprintf("❌ Optional step %s failed, press 'r' to retry or 's' to skip it", step.Name)
+}
+
+// readLogs loads the end of the log file into the viewport
+func (m *BootstrapModel) readLogs() {
+	m.logRead = time.Now()
+	data, err := os.ReadFile(m.logFile)
+	if err != nil {
+		m.logLines = []string{fmt.Sprintf("Failed to read %s: %v", m.logFile, err)}
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	m.logLines = lines[max(0, len(lines)-maxLogLines):]
+	if m.logFollow {
+		m.logOffset = max(0, len(m.logLines)-m.logHeight())
+	}
+}
+
+// maxLogLines bounds the lines the viewport keeps from the end of the log file
+const maxLogLines = 5000
+
+// scrollLogs moves the log viewport, reporting whether key was a scroll or close key
+func (m *BootstrapModel) scrollLogs(key string) bool {
+	bottom := max(0, len(m.logLines)-m.logHeight())
+	switch key {
+	case "up", "k":
+		m.logOffset = max(0, m.logOffset-1)
+	case "down", "j":
+		m.logOffset = min(bottom, m.logOffset+1)
+	case "pgup":
+		m.logOffset = max(0, m.logOffset-m.logHeight())
+	case "pgdown", " ":
+		m.logOffset = min(bottom, m.logOffset+m.logHeight())
+	case "home", "g":
+		m.logOffset = 0
+	case "end", "G":
+		m.logOffset = bottom
+	case "esc":
+		m.showLogs = false
+	default:
+		return false
+	}
+	m.logFollow = m.logOffset >= bottom
+	return true
+}
+
+// logHeight is the number of log lines fitting between the header and the status lines
+func (m *BootstrapModel) logHeight() int {
+	return max(1, m.height-6)
+}
+
+// viewLogs renders the full-log viewport
+func (m *BootstrapModel) viewLogs() string {
+	var s strings.Builder
+	end := min(len(m.logLines), m.logOffset+m.logHeight())
+	s.WriteString(strings.Join(m.logLines[min(m.logOffset, end):end], "\n"))
+	s.WriteString("\n\n")
+	s.WriteString(fmt.Sprintf("%s lines %d-%d of %d", m.logFile, min(m.logOffset+1, end), end, len(m.logLines)))
+	if m.status != "" {
+		s.WriteString(" • " + m.status)
+	}
+	s.WriteString("\n↑/↓ pgup/pgdown scroll • g/G top/bottom • l/esc close")
+	return s.String()
+}
+
 // View renders the TUI
 func (m *BootstrapModel) View() string {
 	var s strings.Builder
@@ -238,41 +457,13 @@ func (m *BootstrapModel) View() string {
 	s.WriteString(headerStyle.Render("🚀 Homelab Bootstrap"))
 	s.WriteString("\n\n")
 
-	// Steps
-	for i, step := range m.steps {
-		var style lipgloss.Style
-
-		switch step.Status {
-		case StepRunning:
-			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00"))
-		case StepCompleted:
-			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
-		case StepFailed:
-			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
-		default:
-			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
-		}
-
-		if i == m.currentStep {
-			style = style.Bold(true)
-		}
-
-		duration := ""
-		if !step.StartTime.IsZero() && !step.EndTime.IsZero() {
-			duration = fmt.Sprintf(" (%v)", step.EndTime.Sub(step.StartTime).Round(time.Second))
-		}
-
-		line := fmt.Sprintf("%s %s%s", step.Status.String(), step.Description, duration)
-		s.WriteString(style.Render(line))
-		s.WriteString("\n")
-
-		if step.Error != nil {
-			errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Margin(0, 2)
-			s.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", step.Error)))
-			s.WriteString("\n")
-		}
+	if m.showLogs {
+		s.WriteString(m.viewLogs())
+		return s.String()
 	}
 
+	// Steps
+	s.WriteString(renderSteps(m.steps, m.currentStep))
 	s.WriteString("\n")
 
 	// Status
@@ -297,173 +488,95 @@ func (m *BootstrapModel) View() string {
 	}
 
 	// Instructions
-	if !m.done && m.err == nil {
-		s.WriteString("Press 'q' or Ctrl+C to quit")
-	} else if m.done {
+	switch {
+	case m.done:
 		s.WriteString("✨ Press 'q' or Ctrl+C to exit")
-	} else {
-		s.WriteString("❌ Press 'q' or Ctrl+C to exit")
+	case m.driver == nil:
+		s.WriteString(fmt.Sprintf("❌ %v\nPress 'q' or Ctrl+C to exit", m.err))
+	case m.failed && m.driver.Current().Required:
+		s.WriteString("r retry • l logs • q give up and roll back")
+	case m.failed:
+		s.WriteString("r retry • s skip • l logs • q quit")
+	case m.paused && !m.running:
+		s.WriteString("p continue • s skip (optional steps) • l logs • q quit")
+	default:
+		s.WriteString("p pause after this step • l logs • q quit")
 	}
 
 	return s.String()
 }
 
-// Messages
-type StepCompleteMsg struct{}
-type StepErrorMsg struct{ Error error }
-type LogMsg struct{ Message string }
-
-// Commands
-func (m *BootstrapModel) startBootstrap() tea.Cmd {
-	return func() tea.Msg {
-		return m.runStep(0)()
-	}
-}
-
-func (m *BootstrapModel) runStep(stepIndex int) tea.Cmd {
-	return func() tea.Msg {
-		if stepIndex >= len(m.steps) {
-			return StepCompleteMsg{}
-		}
+// renderSteps draws one line per step, colored by status, with the error of failed steps
+func renderSteps(steps []BootstrapStep, current int) string {
+	var s strings.Builder
+	for i, step := range steps {
+		var style lipgloss.Style
 
-		step := &m.steps[stepIndex]
-		step.Status = StepRunning
-		step.StartTime = time.Now()
-
-		switch step.Name {
-		case "check-prereq":
-			return m.checkPrerequisites()
-		case "connect-cluster":
-			return m.connectCluster()
-		case "install-cilium":
-			return m.installCilium()
-		case "setup-vault":
-			return m.setupVault()
-		case "install-flux":
-			return m.installFlux()
-		case "bootstrap-gitops":
-			return m.bootstrapGitOps()
-		case "wait-controllers":
-			return m.waitControllers()
-		case "validate":
-			return m.validate()
+		switch step.Status {
+		case StepRunning:
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00"))
+		case StepCompleted:
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+		case StepFailed:
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
 		default:
-			return StepErrorMsg{Error: fmt.Errorf("unknown step: %s", step.Name)}
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
 		}
-	}
-}
-
-func (m *BootstrapModel) checkPrerequisites() tea.Msg {
-	// This would ideally run prerequisite checks
-	// For now, simulate the check
-	time.Sleep(1 * time.Second)
-	return StepCompleteMsg{}
-}
 
-func (m *BootstrapModel) connectCluster() tea.Msg {
-	// Use orchestrator to verify cluster connectivity
-	if m.orchestrator != nil {
-		// Run cluster verification step
-		err := m.orchestrator.VerifyCluster(m.ctx)
-		if err != nil {
-			return StepErrorMsg{Error: err}
+		if i == current {
+			style = style.Bold(true)
 		}
-		return StepCompleteMsg{}
-	}
-
-	// Fallback simulation
-	time.Sleep(1 * time.Second)
-	return StepCompleteMsg{}
-}
 
-func (m *BootstrapModel) installCilium() tea.Msg {
-	// Use orchestrator to install Cilium
-	if m.orchestrator != nil {
-		err := m.orchestrator.InstallCilium(m.ctx)
-		if err != nil {
-			return StepErrorMsg{Error: err}
+		duration := ""
+		if !step.StartTime.IsZero() && !step.EndTime.IsZero() {
+			duration = fmt.Sprintf(" (%v)", step.EndTime.Sub(step.StartTime).Round(time.Second))
 		}
-		return StepCompleteMsg{}
-	}
-
-	// Fallback simulation
-	time.Sleep(2 * time.Second)
-	return StepCompleteMsg{}
-}
 
-func (m *BootstrapModel) setupVault() tea.Msg {
-	// Use orchestrator to setup secrets (includes vault setup)
-	if m.orchestrator != nil {
-		err := m.orchestrator.SetupSecrets(m.ctx)
-		if err != nil {
-			return StepErrorMsg{Error: err}
+		optional := ""
+		if step.Optional {
+			optional = " (optional)"
 		}
-		return StepCompleteMsg{}
-	}
 
-	// Fallback simulation
-	time.Sleep(1 * time.Second)
-	return StepCompleteMsg{}
-}
+		line := fmt.Sprintf("%s %s%s%s", step.Status.String(), step.Description, optional, duration)
+		s.WriteString(style.Render(line))
+		s.WriteString("\n")
 
-func (m *BootstrapModel) installFlux() tea.Msg {
-	// Use orchestrator to install FluxCD
-	if m.orchestrator != nil {
-		err := m.orchestrator.InstallFluxCD(m.ctx)
-		if err != nil {
-			return StepErrorMsg{Error: err}
+		if step.Status == StepRunning && step.Progress != "" {
+			progressStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Margin(0, 2)
+			s.WriteString(progressStyle.Render(step.Progress))
+			s.WriteString("\n")
 		}
-		return StepCompleteMsg{}
-	}
 
-	// Fallback simulation
-	time.Sleep(2 * time.Second)
-	return StepCompleteMsg{}
-}
-
-func (m *BootstrapModel) bootstrapGitOps() tea.Msg {
-	// Use orchestrator to bootstrap GitOps
-	if m.orchestrator != nil {
-		err := m.orchestrator.BootstrapGitOps(m.ctx)
-		if err != nil {
-			return StepErrorMsg{Error: err}
+		if step.Error != nil {
+			errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Margin(0, 2)
+			s.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", step.Error)))
+			s.WriteString("\n")
 		}
-		return StepCompleteMsg{}
 	}
 
-	// Fallback simulation
-	time.Sleep(1 * time.Second)
-	return StepCompleteMsg{}
+	return s.String()
 }
 
-func (m *BootstrapModel) waitControllers() tea.Msg {
-	// Use orchestrator to wait for infrastructure
-	if m.orchestrator != nil {
-		err := m.orchestrator.WaitForInfrastructure(m.ctx)
-		if err != nil {
-			return StepErrorMsg{Error: err}
-		}
-		return StepCompleteMsg{}
-	}
+// Messages
+type StepCompleteMsg struct{}
+type StepErrorMsg struct{ Error error }
+type LogMsg struct{ Message string }
 
-	// Fallback simulation
-	time.Sleep(3 * time.Second)
-	return StepCompleteMsg{}
-}
+// ProgressMsg carries an event of the orchestrator progress bus
+type ProgressMsg progress.Event
 
-func (m *BootstrapModel) validate() tea.Msg {
-	// Use orchestrator to validate deployment
-	if m.orchestrator != nil {
-		err := m.orchestrator.ValidateDeployment(m.ctx)
-		if err != nil {
-			return StepErrorMsg{Error: err}
-		}
-		return StepCompleteMsg{}
+// StreamEventsTo forwards the progress of the running step and the Warning events raised while steps wait
+// to program
+func (m *BootstrapModel) StreamEventsTo(program *tea.Program) {
+	if m.orchestrator == nil {
+		return
 	}
-
-	// Fallback simulation
-	time.Sleep(1 * time.Second)
-	return StepCompleteMsg{}
+	events := m.orchestrator.Progress().Subscribe()
+	go func() {
+		for event := range events {
+			program.Send(ProgressMsg(event))
+		}
+	}()
 }
 
 func max(a, b int) int {
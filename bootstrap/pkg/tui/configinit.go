@@ -0,0 +1,306 @@
+package tui
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+)
+
+// wizardQuestion is one step of the config init wizard. Free text questions have no choices,
+// choice questions cycle through theirs.
+type wizardQuestion struct {
+	prompt  string
+	choices []string
+	value   func(a *config.ScaffoldAnswers) string
+	set     func(a *config.ScaffoldAnswers, value string) error
+	skip    func(a *config.ScaffoldAnswers) bool
+}
+
+// ConfigWizardModel asks for the settings of a new homelab and fills the scaffold answers
+type ConfigWizardModel struct {
+	answers   config.ScaffoldAnswers
+	questions []wizardQuestion
+	current   int
+	input     string
+	choice    int
+	err       error
+	done      bool
+	cancelled bool
+}
+
+// NewConfigWizardModel creates the wizard, proposing the given answers as defaults
+func NewConfigWizardModel(defaults config.ScaffoldAnswers) *ConfigWizardModel {
+	m := &ConfigWizardModel{answers: defaults, questions: wizardQuestions()}
+	m.load()
+	return m
+}
+
+// Answers returns the answers once the wizard is done, false when it was cancelled
+func (m *ConfigWizardModel) Answers() (config.ScaffoldAnswers, bool) {
+	return m.answers, m.done && !m.cancelled
+}
+
+func wizardQuestions() []wizardQuestion {
+	noNAS := func(a *config.ScaffoldAnswers) bool { return !a.NASEnabled }
+	text := func(prompt string, field func(a *config.ScaffoldAnswers) *string) wizardQuestion {
+		return wizardQuestion{
+			prompt: prompt,
+			value:  func(a *config.ScaffoldAnswers) string { return *field(a) },
+			set: func(a *config.ScaffoldAnswers, value string) error {
+				if value == "" {
+					return fmt.Errorf("a value is required")
+				}
+				*field(a) = value
+				return nil
+			},
+		}
+	}
+	yesNo := func(prompt string, field func(a *config.ScaffoldAnswers) *bool) wizardQuestion {
+		return wizardQuestion{
+			prompt:  prompt,
+			choices: []string{"yes", "no"},
+			value: func(a *config.ScaffoldAnswers) string {
+				if *field(a) {
+					return "yes"
+				}
+				return "no"
+			},
+			set: func(a *config.ScaffoldAnswers, value string) error {
+				*field(a) = value == "yes"
+				return nil
+			},
+		}
+	}
+
+	repository := text("GitOps repository URL", func(a *config.ScaffoldAnswers) *string { return &a.Repository })
+	repository.set = func(a *config.ScaffoldAnswers, value string) error {
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "ssh") {
+			return fmt.Errorf("enter an https:// or ssh:// repository URL")
+		}
+		a.Repository = value
+		return nil
+	}
+
+	nasName := text("NAS cluster name", func(a *config.ScaffoldAnswers) *string { return &a.NASName })
+	nasName.skip = noNAS
+	nasHost := text("NAS host IP", func(a *config.ScaffoldAnswers) *string { return &a.NASHost })
+	nasHost.skip = noNAS
+	nasHost.set = func(a *config.ScaffoldAnswers, value string) error {
+		if net.ParseIP(value) == nil {
+			return fmt.Errorf("%q is not an IP address", value)
+		}
+		// The Vault address follows the host unless it was pointed elsewhere
+		if a.VaultAddress == "" || strings.Contains(a.VaultAddress, "//"+a.NASHost+":") {
+			a.VaultAddress = "http://" + value + ":61200"
+		}
+		a.NASHost = value
+		return nil
+	}
+	nasPath := text("NAS GitOps path", func(a *config.ScaffoldAnswers) *string { return &a.NASPath })
+	nasPath.skip = noNAS
+	vaultAddress := text("NAS Vault address", func(a *config.ScaffoldAnswers) *string { return &a.VaultAddress })
+	vaultAddress.skip = noNAS
+
+	return []wizardQuestion{
+		text("Homelab cluster name", func(a *config.ScaffoldAnswers) *string { return &a.HomelabName }),
+		{
+			prompt: "Homelab node IPs (comma separated)",
+			value:  func(a *config.ScaffoldAnswers) string { return strings.Join(a.HomelabNodes, ",") },
+			set: func(a *config.ScaffoldAnswers, value string) error {
+				var nodes []string
+				for _, node := range strings.Split(value, ",") {
+					node = strings.TrimSpace(node)
+					if node == "" {
+						continue
+					}
+					if net.ParseIP(node) == nil {
+						return fmt.Errorf("%q is not an IP address", node)
+					}
+					nodes = append(nodes, node)
+				}
+				if len(nodes) == 0 {
+					return fmt.Errorf("at least one node is required")
+				}
+				a.HomelabNodes = nodes
+				return nil
+			},
+		},
+		repository,
+		text("GitOps branch", func(a *config.ScaffoldAnswers) *string { return &a.Branch }),
+		text("Homelab GitOps path", func(a *config.ScaffoldAnswers) *string { return &a.HomelabPath }),
+		{
+			prompt:  "Storage provider",
+			choices: []string{"ceph", "local-path", "none"},
+			value:   func(a *config.ScaffoldAnswers) string { return a.StorageProvider },
+			set: func(a *config.ScaffoldAnswers, value string) error {
+				a.StorageProvider = value
+				return nil
+			},
+		},
+		yesNo("Enable the Istio service mesh", func(a *config.ScaffoldAnswers) *bool { return &a.MeshEnabled }),
+		yesNo("Configure a NAS cluster (Vault, MinIO)", func(a *config.ScaffoldAnswers) *bool { return &a.NASEnabled }),
+		nasName,
+		nasHost,
+		vaultAddress,
+		nasPath,
+	}
+}
+
+// load prepares the input of the current question from the answers so far
+func (m *ConfigWizardModel) load() {
+	for m.current < len(m.questions) && m.question().skip != nil && m.question().skip(&m.answers) {
+		m.current++
+	}
+	if m.current >= len(m.questions) {
+		m.done = true
+		return
+	}
+	q := m.question()
+	m.input, m.choice = "", 0
+	for i, choice := range q.choices {
+		if choice == q.value(&m.answers) {
+			m.choice = i
+		}
+	}
+}
+
+func (m *ConfigWizardModel) question() wizardQuestion {
+	return m.questions[m.current]
+}
+
+// Init has nothing to start, the wizard only reacts to keys
+func (m *ConfigWizardModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update edits the answer of the current question and moves on when it is accepted
+func (m *ConfigWizardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok || m.done {
+		return m, nil
+	}
+	q := m.question()
+
+	switch key.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.cancelled, m.done = true, true
+		return m, tea.Quit
+	case tea.KeyEnter:
+		value := strings.TrimSpace(m.input)
+		if q.choices != nil {
+			value = q.choices[m.choice]
+		} else if value == "" {
+			value = q.value(&m.answers)
+		}
+		if err := q.set(&m.answers, value); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.err = nil
+		m.current++
+		m.load()
+		if m.done {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	if q.choices != nil {
+		switch key.String() {
+		case "left", "up", "shift+tab", "h", "k":
+			m.choice = (m.choice + len(q.choices) - 1) % len(q.choices)
+		case "right", "down", "tab", "l", "j", " ":
+			m.choice = (m.choice + 1) % len(q.choices)
+		case "y":
+			m.selectChoice("yes")
+		case "n":
+			m.selectChoice("no")
+		}
+		return m, nil
+	}
+
+	switch key.Type {
+	case tea.KeyBackspace:
+		if runes := []rune(m.input); len(runes) > 0 {
+			m.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeyCtrlU:
+		m.input = ""
+	case tea.KeyRunes, tea.KeySpace:
+		m.input += string(key.Runes)
+		if key.Type == tea.KeySpace {
+			m.input += " "
+		}
+	}
+	return m, nil
+}
+
+func (m *ConfigWizardModel) selectChoice(value string) {
+	for i, choice := range m.question().choices {
+		if choice == value {
+			m.choice = i
+		}
+	}
+}
+
+// View renders the answered questions and the current prompt
+func (m *ConfigWizardModel) View() string {
+	var s strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#7D56F4")).
+		Padding(0, 1)
+	s.WriteString(headerStyle.Render("🧰 New homelab configuration"))
+	s.WriteString("\n\n")
+
+	answered := lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
+	for i := 0; i < m.current && i < len(m.questions); i++ {
+		q := m.questions[i]
+		if q.skip != nil && q.skip(&m.answers) {
+			continue
+		}
+		s.WriteString(answered.Render(fmt.Sprintf("✓ %s: %s", q.prompt, q.value(&m.answers))))
+		s.WriteString("\n")
+	}
+	if m.done {
+		return s.String()
+	}
+
+	q := m.question()
+	s.WriteString(lipgloss.NewStyle().Bold(true).Render("? " + q.prompt + ": "))
+	if q.choices != nil {
+		for i, choice := range q.choices {
+			if i == m.choice {
+				s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00")).Bold(true).Render("[" + choice + "]"))
+			} else {
+				s.WriteString(" " + choice + " ")
+			}
+			s.WriteString(" ")
+		}
+	} else if m.input == "" {
+		s.WriteString(answered.Render(q.value(&m.answers)))
+	} else {
+		s.WriteString(m.input + "█")
+	}
+	s.WriteString("\n")
+
+	if m.err != nil {
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render("  " + m.err.Error()))
+		s.WriteString("\n")
+	}
+
+	help := "enter accept the default • type to replace it • esc cancel"
+	if q.choices != nil {
+		help = "←/→ choose • enter confirm • esc cancel"
+	}
+	s.WriteString("\n" + fmt.Sprintf("%d/%d • %s", m.current+1, len(m.questions), help))
+	return s.String()
+}
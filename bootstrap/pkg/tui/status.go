@@ -0,0 +1,242 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fredericrous/homelab/bootstrap/pkg/dashboard"
+)
+
+// maxUnready is how many not-ready Flux objects a panel lists before summarising the rest
+const maxUnready = 6
+
+// StatusModel renders the live state of several clusters side by side
+type StatusModel struct {
+	updates <-chan dashboard.ClusterState
+	order   []string
+	states  map[string]dashboard.ClusterState
+	width   int
+}
+
+// clusterStateMsg carries a new snapshot of one cluster
+type clusterStateMsg dashboard.ClusterState
+
+// NewStatusModel creates the dashboard for clusters, fed by the snapshots sent on updates
+func NewStatusModel(clusters []string, updates <-chan dashboard.ClusterState) *StatusModel {
+	return &StatusModel{
+		updates: updates,
+		order:   clusters,
+		states:  map[string]dashboard.ClusterState{},
+		width:   120,
+	}
+}
+
+// Init waits for the first snapshot
+func (m *StatusModel) Init() tea.Cmd {
+	return m.next()
+}
+
+func (m *StatusModel) next() tea.Cmd {
+	return func() tea.Msg {
+		state, ok := <-m.updates
+		if !ok {
+			return nil
+		}
+		return clusterStateMsg(state)
+	}
+}
+
+// Update stores incoming snapshots and handles resizing and quitting
+func (m *StatusModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case clusterStateMsg:
+		m.states[msg.Cluster] = dashboard.ClusterState(msg)
+		return m, m.next()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// View renders one panel per cluster
+func (m *StatusModel) View() string {
+	var s strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#7D56F4")).
+		Padding(0, 1)
+	s.WriteString(headerStyle.Render("📊 Cluster Status"))
+	s.WriteString("\n\n")
+
+	panelWidth := max(30, m.width/max(1, len(m.order))-4)
+	panels := make([]string, 0, len(m.order))
+	for _, cluster := range m.order {
+		state, ok := m.states[cluster]
+		body := "Connecting..."
+		if ok {
+			body = renderClusterState(state, panelWidth-4)
+		}
+		panel := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(clusterColor(state, ok)).
+			Padding(0, 1).
+			Width(panelWidth).
+			Render(lipgloss.NewStyle().Bold(true).Render(cluster) + "\n" + body)
+		panels = append(panels, panel)
+	}
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, panels...))
+	s.WriteString("\n")
+	s.WriteString("Updates live from cluster watches • q quit")
+	return s.String()
+}
+
+func renderClusterState(state dashboard.ClusterState, width int) string {
+	if state.Error != "" {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render("Error: " + state.Error)
+	}
+
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#808080"))
+	var s strings.Builder
+	section := func(title string) {
+		s.WriteString("\n")
+		s.WriteString(lipgloss.NewStyle().Underline(true).Render(title))
+		s.WriteString("\n")
+	}
+	line := func(icon, text string) {
+		s.WriteString(truncate(icon+" "+text, width))
+		s.WriteString("\n")
+	}
+
+	readyNodes := 0
+	for _, node := range state.Nodes {
+		if node.Ready {
+			readyNodes++
+		}
+	}
+	section(fmt.Sprintf("Nodes %d/%d ready", readyNodes, len(state.Nodes)))
+	for _, node := range state.Nodes {
+		line(readyIcon(node.Ready), node.Name)
+	}
+
+	for _, group := range []struct {
+		title   string
+		objects []dashboard.FluxObject
+	}{
+		{"Kustomizations", state.Kustomizations},
+		{"HelmReleases", state.HelmReleases},
+	} {
+		var unready []dashboard.FluxObject
+		for _, object := range group.objects {
+			if !object.Ready {
+				unready = append(unready, object)
+			}
+		}
+		section(fmt.Sprintf("%s %d/%d ready", group.title, len(group.objects)-len(unready), len(group.objects)))
+		for i, object := range unready {
+			if i == maxUnready {
+				s.WriteString(dim.Render(fmt.Sprintf("  … %d more not ready", len(unready)-maxUnready)))
+				s.WriteString("\n")
+				break
+			}
+			icon := readyIcon(false)
+			if object.Suspended {
+				icon = "⏸"
+			}
+			line(icon, fmt.Sprintf("%s/%s %s", object.Namespace, object.Name, object.Reason))
+		}
+	}
+
+	section("Istio gateways")
+	if len(state.Gateways) == 0 {
+		s.WriteString(dim.Render("  none"))
+		s.WriteString("\n")
+	}
+	for _, gateway := range state.Gateways {
+		line(readyIcon(!strings.HasPrefix(gateway.Address, "<pending>")), gateway.Name+" "+gateway.Address)
+	}
+
+	if state.CephHealth != "" {
+		section("Ceph")
+		line(cephIcon(state.CephHealth), state.CephHealth)
+	}
+
+	section("Recent Flux events")
+	if len(state.Events) == 0 {
+		s.WriteString(dim.Render("  none"))
+		s.WriteString("\n")
+	}
+	for _, event := range state.Events {
+		icon := "ℹ️"
+		if event.Type == "Warning" {
+			icon = "⚠️"
+		}
+		message := strings.Join(strings.Fields(event.Message), " ")
+		line(icon, fmt.Sprintf("%s %s %s", event.Time.Format("15:04:05"), event.Object, message))
+	}
+
+	s.WriteString("\n")
+	s.WriteString(dim.Render("updated " + state.Updated.Format(time.TimeOnly)))
+	return s.String()
+}
+
+func readyIcon(ready bool) string {
+	if ready {
+		return "✅"
+	}
+	return "❌"
+}
+
+func cephIcon(health string) string {
+	switch health {
+	case "HEALTH_OK":
+		return "✅"
+	case "HEALTH_WARN":
+		return "⚠️"
+	default:
+		return "❌"
+	}
+}
+
+func clusterColor(state dashboard.ClusterState, received bool) lipgloss.Color {
+	switch {
+	case !received:
+		return lipgloss.Color("#808080")
+	case state.Error != "":
+		return lipgloss.Color("#FF0000")
+	}
+	for _, node := range state.Nodes {
+		if !node.Ready {
+			return lipgloss.Color("#FFFF00")
+		}
+	}
+	for _, objects := range [][]dashboard.FluxObject{state.Kustomizations, state.HelmReleases} {
+		for _, object := range objects {
+			if !object.Ready && !object.Suspended {
+				return lipgloss.Color("#FFFF00")
+			}
+		}
+	}
+	return lipgloss.Color("#00FF00")
+}
+
+// truncate shortens text to width cells, marking the cut with an ellipsis
+func truncate(text string, width int) string {
+	if width <= 1 || lipgloss.Width(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && lipgloss.Width(string(runes)) > width-1 {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fredericrous/homelab/bootstrap/pkg/talos"
+)
+
+// UpModel shows the progress of homelab up, from VMs to a reachable Kubernetes API
+type UpModel struct {
+	ctx         context.Context
+	runs        []talos.Step
+	steps       []BootstrapStep
+	currentStep int
+	logs        []string
+	plan        *PlanMsg
+	title       string
+	doneMessage string
+	err         error
+	done        bool
+}
+
+// PlanMsg shows the summary and resource changes of the VM plan
+type PlanMsg struct {
+	Summary string
+	Changes []string
+}
+
+// NewUpModel creates a model running steps in order
+func NewUpModel(ctx context.Context, steps []talos.Step) *UpModel {
+	model := &UpModel{ctx: ctx, runs: steps, title: "🚀 Homelab Up", doneMessage: "✅ Cluster ready for the CNI"}
+	for _, step := range steps {
+		model.steps = append(model.steps, BootstrapStep{
+			Name:        step.Name,
+			Description: step.Description,
+			Status:      StepPending,
+		})
+	}
+	return model
+}
+
+// WithTitle replaces the header and the message shown once every step completed
+func (m *UpModel) WithTitle(title, doneMessage string) *UpModel {
+	m.title = title
+	m.doneMessage = doneMessage
+	return m
+}
+
+// Err returns the error of the failed step, or why the steps did not all run
+func (m *UpModel) Err() error {
+	if m.err == nil && !m.done {
+		return fmt.Errorf("interrupted before all steps completed")
+	}
+	return m.err
+}
+
+// Init starts the first step
+func (m *UpModel) Init() tea.Cmd {
+	return m.runStep(0)
+}
+
+// Update handles TUI messages
+func (m *UpModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		}
+	case StepCompleteMsg:
+		m.steps[m.currentStep].Status = StepCompleted
+		m.steps[m.currentStep].EndTime = time.Now()
+		m.currentStep++
+		if m.currentStep < len(m.steps) {
+			return m, m.runStep(m.currentStep)
+		}
+		m.done = true
+		return m, tea.Quit
+	case StepErrorMsg:
+		m.steps[m.currentStep].Status = StepFailed
+		m.steps[m.currentStep].Error = msg.Error
+		m.steps[m.currentStep].EndTime = time.Now()
+		m.err = msg.Error
+	case PlanMsg:
+		m.plan = &msg
+	case LogMsg:
+		m.logs = append(m.logs, msg.Message)
+		if len(m.logs) > 10 {
+			m.logs = m.logs[1:]
+		}
+	}
+	return m, nil
+}
+
+func (m *UpModel) runStep(index int) tea.Cmd {
+	m.steps[index].Status = StepRunning
+	m.steps[index].StartTime = time.Now()
+	run := m.runs[index].Run
+	return func() tea.Msg {
+		if err := run(m.ctx); err != nil {
+			return StepErrorMsg{Error: err}
+		}
+		return StepCompleteMsg{}
+	}
+}
+
+// View renders the TUI
+func (m *UpModel) View() string {
+	var s strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#7D56F4")).
+		Padding(0, 1)
+	s.WriteString(headerStyle.Render(m.title))
+	s.WriteString("\n\n")
+	s.WriteString(renderSteps(m.steps, m.currentStep))
+	s.WriteString("\n")
+
+	if m.plan != nil {
+		s.WriteString("📋 " + m.plan.Summary + "\n")
+		changeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+		for i, change := range m.plan.Changes {
+			if i == 8 {
+				s.WriteString(fmt.Sprintf("  ... and %d more\n", len(m.plan.Changes)-i))
+				break
+			}
+			s.WriteString(changeStyle.Render("  "+change) + "\n")
+		}
+		s.WriteString("\n")
+	}
+
+	if len(m.logs) > 0 {
+		logStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Italic(true)
+		s.WriteString("Recent activity:\n")
+		for _, line := range m.logs[max(0, len(m.logs)-5):] {
+			s.WriteString(logStyle.Render("  " + line))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
+	switch {
+	case m.done:
+		s.WriteString(m.doneMessage + "\n")
+	case m.err != nil:
+		s.WriteString(fmt.Sprintf("❌ %v\nPress 'q' or Ctrl+C to exit", m.err))
+	default:
+		s.WriteString("Press 'q' or Ctrl+C to quit")
+	}
+	return s.String()
+}
@@ -0,0 +1,200 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fredericrous/homelab/bootstrap/pkg/flux"
+)
+
+// DiagnoseFunc produces a fresh diagnosis of the Kustomization
+type DiagnoseFunc func(ctx context.Context) (*flux.Diagnosis, error)
+
+// WhyModel lets the user walk the layers of a Kustomization diagnosis
+type WhyModel struct {
+	ctx       context.Context
+	name      string
+	diagnose  DiagnoseFunc
+	diagnosis *flux.Diagnosis
+	err       error
+	loading   bool
+	cursor    int
+	offset    int
+	height    int
+}
+
+// diagnosisMsg carries the result of a diagnosis run
+type diagnosisMsg struct {
+	diagnosis *flux.Diagnosis
+	err       error
+}
+
+// NewWhyModel creates the troubleshooting TUI for the named Kustomization
+func NewWhyModel(ctx context.Context, name string, diagnose DiagnoseFunc) *WhyModel {
+	return &WhyModel{
+		ctx:      ctx,
+		name:     name,
+		diagnose: diagnose,
+		loading:  true,
+		height:   24,
+	}
+}
+
+// Init starts the first diagnosis
+func (m *WhyModel) Init() tea.Cmd {
+	return m.run()
+}
+
+func (m *WhyModel) run() tea.Cmd {
+	return func() tea.Msg {
+		diagnosis, err := m.diagnose(m.ctx)
+		return diagnosisMsg{diagnosis: diagnosis, err: err}
+	}
+}
+
+// Update handles navigation between layers and detail scrolling
+func (m *WhyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case diagnosisMsg:
+		m.loading = false
+		m.diagnosis, m.err = msg.diagnosis, msg.err
+		m.offset = 0
+		if m.diagnosis != nil {
+			// Open on the layer that breaks the chain
+			m.cursor = max(0, m.diagnosis.RootCause())
+		}
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "r":
+			if !m.loading {
+				m.loading = true
+				return m, m.run()
+			}
+		case "up", "k", "shift+tab":
+			if m.cursor > 0 {
+				m.cursor--
+				m.offset = 0
+			}
+		case "down", "j", "tab", "enter":
+			if m.diagnosis != nil && m.cursor < len(m.diagnosis.Layers)-1 {
+				m.cursor++
+				m.offset = 0
+			}
+		case "pgdown", " ":
+			m.offset = min(m.offset+m.detailHeight(), max(0, m.detailCount()-m.detailHeight()))
+		case "pgup":
+			m.offset = max(0, m.offset-m.detailHeight())
+		}
+	}
+
+	return m, nil
+}
+
+// View renders the layer list and the details of the selected layer
+func (m *WhyModel) View() string {
+	var s strings.Builder
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#7D56F4")).
+		Padding(0, 1)
+	s.WriteString(headerStyle.Render("🔍 Why is " + m.name + " not ready?"))
+	s.WriteString("\n\n")
+
+	if m.err != nil {
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render(fmt.Sprintf("Error: %v", m.err)))
+		s.WriteString("\n\nPress 'r' to retry or 'q' to quit")
+		return s.String()
+	}
+	if m.diagnosis == nil {
+		s.WriteString("Walking the reconciliation chain...")
+		return s.String()
+	}
+
+	for i, layer := range m.diagnosis.Layers {
+		style := lipgloss.NewStyle().Foreground(layerColor(layer.Status))
+		pointer := "  "
+		if i == m.cursor {
+			style = style.Bold(true)
+			pointer = "▸ "
+		}
+		s.WriteString(style.Render(fmt.Sprintf("%s%s %s", pointer, LayerIcon(layer.Status), layer.Title)))
+		s.WriteString("\n")
+		s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#808080")).Render("     " + layer.Summary))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	details := m.diagnosis.Layers[m.cursor].Details
+	if len(details) == 0 {
+		details = []string{"(no details)"}
+	}
+	end := min(len(details), m.offset+m.detailHeight())
+	detailStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(layerColor(m.diagnosis.Layers[m.cursor].Status)).
+		Padding(0, 1)
+	s.WriteString(detailStyle.Render(strings.Join(details[m.offset:end], "\n")))
+	s.WriteString("\n")
+	if len(details) > m.detailHeight() {
+		s.WriteString(fmt.Sprintf("lines %d-%d of %d\n", m.offset+1, end, len(details)))
+	}
+
+	status := "↑/↓ select layer • pgup/pgdown scroll • r refresh • q quit"
+	if m.loading {
+		status = "Refreshing..."
+	}
+	s.WriteString(status)
+	return s.String()
+}
+
+// detailHeight is the number of detail lines fitting below the layer list
+func (m *WhyModel) detailHeight() int {
+	used := 6
+	if m.diagnosis != nil {
+		used += 2 * len(m.diagnosis.Layers)
+	}
+	return max(5, m.height-used-3)
+}
+
+func (m *WhyModel) detailCount() int {
+	if m.diagnosis == nil {
+		return 0
+	}
+	return len(m.diagnosis.Layers[m.cursor].Details)
+}
+
+// LayerIcon returns the status icon of a diagnosis layer
+func LayerIcon(status string) string {
+	switch status {
+	case flux.LayerHealthy:
+		return "✅"
+	case flux.LayerFailed:
+		return "❌"
+	case flux.LayerPending:
+		return "⏳"
+	default:
+		return "➖"
+	}
+}
+
+func layerColor(status string) lipgloss.Color {
+	switch status {
+	case flux.LayerHealthy:
+		return lipgloss.Color("#00FF00")
+	case flux.LayerFailed:
+		return lipgloss.Color("#FF0000")
+	case flux.LayerPending:
+		return lipgloss.Color("#FFFF00")
+	default:
+		return lipgloss.Color("#808080")
+	}
+}
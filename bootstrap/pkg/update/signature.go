@@ -0,0 +1,144 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	minisignLegacyAlg    = "Ed"
+	minisignPrehashedAlg = "ED"
+	trustedCommentPrefix = "trusted comment: "
+)
+
+// verifyMinisign checks a minisign signature of message against the public key.
+// Legacy (Ed) signatures are verified natively; prehashed (ED) ones require the minisign CLI.
+func verifyMinisign(ctx context.Context, publicKey string, message, signature []byte) error {
+	keyBytes, err := base64.StdEncoding.DecodeString(lastLine(publicKey))
+	if err != nil || len(keyBytes) != 42 || string(keyBytes[:2]) != minisignLegacyAlg {
+		return fmt.Errorf("invalid minisign public key")
+	}
+	keyID := keyBytes[2:10]
+	key := ed25519.PublicKey(keyBytes[10:])
+
+	lines := nonEmptyLines(string(signature))
+	if len(lines) < 4 {
+		return fmt.Errorf("malformed minisign signature")
+	}
+
+	sigBytes, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sigBytes) != 74 {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	alg := string(sigBytes[:2])
+	if !bytes.Equal(sigBytes[2:10], keyID) {
+		return fmt.Errorf("signature was made with a different key")
+	}
+	sig := sigBytes[10:]
+
+	switch alg {
+	case minisignLegacyAlg:
+		if !ed25519.Verify(key, message, sig) {
+			return fmt.Errorf("signature does not match")
+		}
+	case minisignPrehashedAlg:
+		return verifyWithCLI(ctx, "minisign", message, signature, func(messagePath, signaturePath string) []string {
+			return []string{"-V", "-q", "-P", lastLine(publicKey), "-m", messagePath, "-x", signaturePath}
+		})
+	default:
+		return fmt.Errorf("unsupported minisign algorithm %q", alg)
+	}
+
+	if !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return fmt.Errorf("malformed minisign trusted comment")
+	}
+	trusted := strings.TrimPrefix(lines[2], trustedCommentPrefix)
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed minisign global signature")
+	}
+	if !ed25519.Verify(key, append(append([]byte{}, sig...), trusted...), globalSig) {
+		return fmt.Errorf("trusted comment signature does not match")
+	}
+	return nil
+}
+
+// verifyCosign checks a cosign blob signature with the cosign CLI
+func verifyCosign(ctx context.Context, publicKey string, message, signature []byte) error {
+	keyPath := publicKey
+	if strings.Contains(publicKey, "-----BEGIN") {
+		file, err := writeTemp("cosign-*.pub", []byte(publicKey))
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file)
+		keyPath = file
+	}
+
+	return verifyWithCLI(ctx, "cosign", message, signature, func(messagePath, signaturePath string) []string {
+		return []string{"verify-blob", "--key", keyPath, "--signature", signaturePath, messagePath}
+	})
+}
+
+func verifyWithCLI(ctx context.Context, tool string, message, signature []byte, args func(messagePath, signaturePath string) []string) error {
+	if _, err := exec.LookPath(tool); err != nil {
+		return fmt.Errorf("%s not found in PATH", tool)
+	}
+
+	messagePath, err := writeTemp("release-*", message)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(messagePath)
+
+	signaturePath, err := writeTemp("release-*.sig", signature)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(signaturePath)
+
+	output, err := exec.CommandContext(ctx, tool, args(messagePath, signaturePath)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", tool, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func writeTemp(pattern string, data []byte) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lines = append(lines, trimmed)
+		}
+	}
+	return lines
+}
+
+// lastLine returns the key material of a minisign public key, with or without its comment line
+func lastLine(s string) string {
+	lines := nonEmptyLines(s)
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,336 @@
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+)
+
+const (
+	// DefaultRepository is the GitHub repository releases are published to
+	DefaultRepository = "fredericrous/homelab"
+
+	checksumsAsset = "checksums.txt"
+	minisignSuffix = ".minisig"
+	cosignSuffix   = ".sig"
+)
+
+// Release signing keys, injected at build time with -ldflags "-X ..."
+var (
+	MinisignPublicKey = ""
+	CosignPublicKey   = ""
+)
+
+// Release describes a published release
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Name   string  `json:"name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a downloadable file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Options configures a self-update
+type Options struct {
+	Version string
+	DryRun  bool
+}
+
+// Result describes the outcome of a self-update
+type Result struct {
+	Previous string
+	Current  string
+	Path     string
+	Verifier string
+	Updated  bool
+}
+
+// Updater downloads, verifies and installs release binaries
+type Updater struct {
+	repository     string
+	currentVersion string
+	httpClient     *http.Client
+}
+
+// NewUpdater creates a new updater for the running binary version
+func NewUpdater(currentVersion string) *Updater {
+	repository := strings.TrimSpace(os.Getenv("BOOTSTRAP_RELEASE_REPOSITORY"))
+	if repository == "" {
+		repository = DefaultRepository
+	}
+	return &Updater{
+		repository:     repository,
+		currentVersion: currentVersion,
+		httpClient:     &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// AssetName returns the binary asset name for the running platform
+func AssetName() string {
+	name := fmt.Sprintf("bootstrap_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Latest returns the latest release, or the release tagged version when set
+func (u *Updater) Latest(ctx context.Context, version string) (*Release, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", u.repository)
+	if version != "" {
+		url = fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", u.repository, version)
+	}
+
+	data, err := u.fetch(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query release: %w", err)
+	}
+
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+	return &release, nil
+}
+
+// Update replaces the running binary with the verified release binary
+func (u *Updater) Update(ctx context.Context, opts Options) (*Result, error) {
+	release, err := u.Latest(ctx, opts.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Previous: u.currentVersion, Current: release.Tag}
+	if opts.Version == "" && sameVersion(release.Tag, u.currentVersion) {
+		log.Info("Already running the latest release", "version", u.currentVersion)
+		return result, nil
+	}
+
+	binaryAsset, ok := release.asset(AssetName())
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s", release.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	checksums, ok := release.asset(checksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s", release.Tag, checksumsAsset)
+	}
+
+	checksumData, err := u.fetch(ctx, checksums.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download checksums: %w", err)
+	}
+
+	verifier, err := u.verifyChecksums(ctx, release, checksumData)
+	if err != nil {
+		return nil, err
+	}
+	result.Verifier = verifier
+
+	expected, err := checksumFor(checksumData, binaryAsset.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := executablePath()
+	if err != nil {
+		return nil, err
+	}
+	result.Path = target
+
+	if opts.DryRun {
+		log.Info("Dry run: verified release, not replacing binary", "version", release.Tag, "path", target)
+		return result, nil
+	}
+
+	log.Info("Downloading release binary", "asset", binaryAsset.Name, "version", release.Tag)
+	binary, err := u.fetch(ctx, binaryAsset.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", binaryAsset.Name, err)
+	}
+
+	sum := sha256.Sum256(binary)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", binaryAsset.Name, expected, actual)
+	}
+
+	if err := replaceBinary(target, binary); err != nil {
+		return nil, err
+	}
+
+	result.Updated = true
+	return result, nil
+}
+
+// signingKeys returns the minisign and cosign keys releases are verified with. The keys built into the binary
+// always win, so the environment cannot swap in a key of its own; the environment only provides them to builds
+// without any.
+func signingKeys() (string, string) {
+	minisignKey, cosignKey := strings.TrimSpace(MinisignPublicKey), strings.TrimSpace(CosignPublicKey)
+	envMinisign, envCosign := strings.TrimSpace(os.Getenv("BOOTSTRAP_MINISIGN_PUBKEY")), strings.TrimSpace(os.Getenv("BOOTSTRAP_COSIGN_PUBKEY"))
+	if minisignKey != "" || cosignKey != "" {
+		if envMinisign != "" || envCosign != "" {
+			log.Warn("Ignoring BOOTSTRAP_MINISIGN_PUBKEY and BOOTSTRAP_COSIGN_PUBKEY, this binary embeds its release signing key")
+		}
+		return minisignKey, cosignKey
+	}
+	if envMinisign != "" || envCosign != "" {
+		log.Warn("Verifying the release with a signing key from the environment, this binary embeds none")
+	}
+	return envMinisign, envCosign
+}
+
+// verifyChecksums checks the signature of the checksum file with the configured key
+func (u *Updater) verifyChecksums(ctx context.Context, release *Release, checksums []byte) (string, error) {
+	minisignKey, cosignKey := signingKeys()
+
+	if minisignKey != "" {
+		if sigAsset, ok := release.asset(checksumsAsset + minisignSuffix); ok {
+			sig, err := u.fetch(ctx, sigAsset.URL)
+			if err != nil {
+				return "", fmt.Errorf("failed to download minisign signature: %w", err)
+			}
+			if err := verifyMinisign(ctx, minisignKey, checksums, sig); err != nil {
+				return "", fmt.Errorf("minisign verification failed: %w", err)
+			}
+			return "minisign", nil
+		}
+	}
+
+	if cosignKey != "" {
+		if sigAsset, ok := release.asset(checksumsAsset + cosignSuffix); ok {
+			sig, err := u.fetch(ctx, sigAsset.URL)
+			if err != nil {
+				return "", fmt.Errorf("failed to download cosign signature: %w", err)
+			}
+			if err := verifyCosign(ctx, cosignKey, checksums, sig); err != nil {
+				return "", fmt.Errorf("cosign verification failed: %w", err)
+			}
+			return "cosign", nil
+		}
+	}
+
+	if minisignKey == "" && cosignKey == "" {
+		return "", fmt.Errorf("this binary embeds no release signing key; set BOOTSTRAP_MINISIGN_PUBKEY or BOOTSTRAP_COSIGN_PUBKEY")
+	}
+	return "", fmt.Errorf("release %s has no signature matching the configured key", release.Tag)
+}
+
+func (u *Updater) fetch(ctx context.Context, url string) ([]byte, error) {
+	if err := airgap.GuardNetwork("download " + url); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "homelab-bootstrap/"+u.currentVersion)
+	if token := strings.TrimSpace(os.Getenv("GITHUB_TOKEN")); token != "" && strings.HasPrefix(url, "https://api.github.com/") {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// checksumFor finds the sha256 of name in a sha256sum-style checksum file
+func checksumFor(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s", name)
+}
+
+// executablePath resolves the real path of the running binary
+func executablePath() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate running binary: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve binary path: %w", err)
+	}
+	return resolved, nil
+}
+
+// replaceBinary writes the new binary next to target and renames it into place
+func replaceBinary(target string, binary []byte) error {
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", target, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".new-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary binary: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary binary: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to flush temporary binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("failed to make binary executable: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, target); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	return nil
+}
+
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
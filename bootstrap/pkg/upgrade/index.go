@@ -0,0 +1,196 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/fredericrous/homelab/bootstrap/pkg/airgap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// CacheDir holds the release lists under the project root, so repeated checks stay within the GitHub rate limit
+	CacheDir = ".bootstrap/cache/releases"
+	// DefaultCacheTTL is how long a cached release list is used before it is fetched again
+	DefaultCacheTTL = 6 * time.Hour
+)
+
+// breakingPattern spots the release notes announcing changes that need an action during the upgrade
+var breakingPattern = regexp.MustCompile(`(?i)breaking[ -]change|action required|\bbreaking\b`)
+
+// Release is a published version of a component
+type Release struct {
+	Tag        string `json:"tag"`
+	Prerelease bool   `json:"prerelease,omitempty"`
+	URL        string `json:"url,omitempty"`
+	// Breaking is set when the release notes announce breaking changes
+	Breaking bool `json:"breaking,omitempty"`
+}
+
+// cacheEntry is a release list stored on disk
+type cacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Releases  []Release `json:"releases"`
+}
+
+// Index lists the releases of GitHub repositories and the versions of Helm charts, through a disk cache
+type Index struct {
+	dir        string
+	token      string
+	ttl        time.Duration
+	refresh    bool
+	httpClient *http.Client
+}
+
+// NewIndex creates an index caching into dir, authenticating to GitHub with token when set
+func NewIndex(dir, token string) *Index {
+	return &Index{
+		dir:        dir,
+		token:      token,
+		ttl:        DefaultCacheTTL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetRefresh ignores the cached lists younger than the cache TTL, they are still used when a fetch fails
+func (i *Index) SetRefresh(refresh bool) {
+	i.refresh = refresh
+}
+
+// GitHubReleases returns the recent releases of a GitHub repository, drafts left out
+func (i *Index) GitHubReleases(ctx context.Context, repository string) ([]Release, error) {
+	return i.cached("github-"+repository, func() ([]Release, error) {
+		url := fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=100", repository)
+		body, err := i.get(ctx, url, "list the releases of "+repository, "application/vnd.github+json")
+		if err != nil {
+			return nil, err
+		}
+		var published []struct {
+			Tag        string `json:"tag_name"`
+			Draft      bool   `json:"draft"`
+			Prerelease bool   `json:"prerelease"`
+			URL        string `json:"html_url"`
+			Body       string `json:"body"`
+		}
+		if err := json.Unmarshal(body, &published); err != nil {
+			return nil, fmt.Errorf("failed to decode %s releases: %w", repository, err)
+		}
+		releases := make([]Release, 0, len(published))
+		for _, release := range published {
+			if release.Draft {
+				continue
+			}
+			releases = append(releases, Release{
+				Tag:        release.Tag,
+				Prerelease: release.Prerelease,
+				URL:        release.URL,
+				Breaking:   breakingPattern.MatchString(release.Body),
+			})
+		}
+		return releases, nil
+	})
+}
+
+// ChartVersions returns the versions of chart published in the Helm repository at repoURL
+func (i *Index) ChartVersions(ctx context.Context, repoURL, chart string) ([]Release, error) {
+	return i.cached("helm-"+repoURL+"-"+chart, func() ([]Release, error) {
+		url := strings.TrimSuffix(repoURL, "/") + "/index.yaml"
+		body, err := i.get(ctx, url, "read the Helm index of "+repoURL, "")
+		if err != nil {
+			return nil, err
+		}
+		var index struct {
+			Entries map[string][]struct {
+				Version string `yaml:"version"`
+			} `yaml:"entries"`
+		}
+		if err := yaml.Unmarshal(body, &index); err != nil {
+			return nil, fmt.Errorf("failed to decode the Helm index of %s: %w", repoURL, err)
+		}
+		entries, ok := index.Entries[chart]
+		if !ok {
+			return nil, fmt.Errorf("Helm repository %s has no %s chart", repoURL, chart)
+		}
+		releases := make([]Release, 0, len(entries))
+		for _, entry := range entries {
+			releases = append(releases, Release{Tag: entry.Version, Prerelease: strings.Contains(entry.Version, "-")})
+		}
+		return releases, nil
+	})
+}
+
+// cached returns the release list stored under key while it is fresh, fetching it otherwise. A stale list
+// is still returned when the fetch fails, e.g. air-gapped or rate limited.
+func (i *Index) cached(key string, fetch func() ([]Release, error)) ([]Release, error) {
+	path := filepath.Join(i.dir, cacheFileName(key))
+	var entry cacheEntry
+	stale := false
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &entry) == nil {
+		if !i.refresh && time.Since(entry.FetchedAt) < i.ttl {
+			return entry.Releases, nil
+		}
+		stale = true
+	}
+
+	releases, err := fetch()
+	if err != nil {
+		if stale {
+			log.Warn("Using cached releases", "source", key, "fetched_at", entry.FetchedAt.Format(time.RFC3339), "error", err)
+			return entry.Releases, nil
+		}
+		return nil, err
+	}
+
+	data, err := json.Marshal(cacheEntry{FetchedAt: time.Now().UTC(), Releases: releases})
+	if err == nil {
+		if err = os.MkdirAll(i.dir, 0o755); err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+	}
+	if err != nil {
+		log.Debug("Failed to cache releases", "source", key, "error", err)
+	}
+	return releases, nil
+}
+
+// get downloads url, refused air-gapped
+func (i *Index) get(ctx context.Context, url, action, accept string) ([]byte, error) {
+	if err := airgap.GuardNetwork(action); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token := strings.TrimSpace(i.token); token != "" && strings.HasPrefix(url, "https://api.github.com/") {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to %s: GET %s: %s", action, url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// cacheFileName turns a cache key into a file name
+func cacheFileName(key string) string {
+	replacer := strings.NewReplacer("https://", "", "http://", "", "/", "_", ":", "_")
+	return replacer.Replace(key) + ".json"
+}
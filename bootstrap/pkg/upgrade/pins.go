@@ -0,0 +1,35 @@
+package upgrade
+
+import "github.com/fredericrous/homelab/bootstrap/pkg/config"
+
+// PinPath returns the config keys pinning the version of component under the cluster section, nil when the
+// config of the cluster cannot pin it
+func PinPath(component string, isNAS bool) []string {
+	switch component {
+	case "flux":
+		return []string{"gitops", "flux_version"}
+	case "cilium":
+		if !isNAS {
+			return []string{"cilium", "version"}
+		}
+	case "istio":
+		if !isNAS {
+			return []string{"networking", "service_mesh", "version"}
+		}
+	}
+	return nil
+}
+
+// Pinned returns the versions the config of the cluster pins, by component
+func Pinned(cfg *config.Config, isNAS bool) map[string]string {
+	pinned := map[string]string{}
+	switch {
+	case isNAS && cfg.NAS != nil:
+		pinned["flux"] = cfg.NAS.GitOps.FluxVersion
+	case !isNAS && cfg.Homelab != nil:
+		pinned["flux"] = cfg.Homelab.GitOps.FluxVersion
+		pinned["cilium"] = cfg.Homelab.Cilium.Version
+		pinned["istio"] = cfg.Homelab.Networking.ServiceMesh.Version
+	}
+	return pinned
+}
@@ -0,0 +1,188 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/fredericrous/homelab/bootstrap/pkg/channels"
+	"github.com/fredericrous/homelab/bootstrap/pkg/config"
+	"github.com/fredericrous/homelab/bootstrap/pkg/k8s"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Component is a platform component the advisor proposes upgrades for
+type Component struct {
+	Name string
+	// Repository publishes the releases on GitHub, their notes are scanned for breaking changes
+	Repository string
+	// ChartRepo and Chart propose the chart versions the bootstrap installs instead of the GitHub releases
+	ChartRepo string
+	Chart     string
+	// UpgradeGuide documents the upgrade procedure
+	UpgradeGuide string
+	// MaxMinorSkip is how many minor versions one upgrade may cross, zero for no limit
+	MaxMinorSkip uint64
+}
+
+// Components are the components whose upgrades the advisor checks
+var Components = []Component{
+	{Name: "flux", Repository: "fluxcd/flux2", UpgradeGuide: "https://fluxcd.io/flux/installation/upgrade/"},
+	{
+		Name:         "cilium",
+		Repository:   "cilium/cilium",
+		ChartRepo:    "https://helm.cilium.io",
+		Chart:        "cilium",
+		UpgradeGuide: "https://docs.cilium.io/en/stable/operations/upgrade/",
+		MaxMinorSkip: 1,
+	},
+	{Name: "istio", Repository: "istio/istio", UpgradeGuide: "https://istio.io/latest/docs/setup/upgrade/", MaxMinorSkip: 2},
+}
+
+// Advice compares the installed version of a component with its newest release
+type Advice struct {
+	Component string `json:"component"`
+	Cluster   string `json:"cluster"`
+	Channel   string `json:"channel"`
+	// Installed is empty when the component does not run on the cluster
+	Installed string `json:"installed,omitempty"`
+	// Pinned is the version the config pins, empty when it follows the bootstrap default
+	Pinned string `json:"pinned,omitempty"`
+	// Latest is the newest release of the channel, as tagged upstream
+	Latest           string   `json:"latest,omitempty"`
+	UpgradeAvailable bool     `json:"upgrade_available"`
+	Changelog        string   `json:"changelog,omitempty"`
+	Warnings         []string `json:"warnings,omitempty"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// Advisor proposes component upgrades from the releases of an index
+type Advisor struct {
+	index *Index
+}
+
+// NewAdvisor creates an advisor reading releases from index
+func NewAdvisor(index *Index) *Advisor {
+	return &Advisor{index: index}
+}
+
+// Check compares every component of the cluster with the newest release of its channel. pinned holds the
+// versions the config pins, by component.
+func (a *Advisor) Check(ctx context.Context, cluster string, client *k8s.Client, cfg config.ChannelsConfig, pinned map[string]string) []Advice {
+	advice := make([]Advice, 0, len(Components))
+	for _, component := range Components {
+		advice = append(advice, a.check(ctx, component, cluster, client, channels.Channel(cfg, component.Name), pinned[component.Name]))
+	}
+	return advice
+}
+
+func (a *Advisor) check(ctx context.Context, component Component, cluster string, client *k8s.Client, channel, pinned string) Advice {
+	advice := Advice{Component: component.Name, Cluster: cluster, Channel: channel, Pinned: pinned}
+
+	installed, err := channels.DeployedVersion(ctx, client, component.Name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		advice.Error = err.Error()
+		return advice
+	}
+	advice.Installed = installed
+
+	notes, notesErr := a.index.GitHubReleases(ctx, component.Repository)
+	candidates := notes
+	if component.ChartRepo != "" {
+		if candidates, err = a.index.ChartVersions(ctx, component.ChartRepo, component.Chart); err != nil {
+			advice.Error = err.Error()
+			return advice
+		}
+	} else if notesErr != nil {
+		advice.Error = notesErr.Error()
+		return advice
+	}
+
+	latest, version, ok := newest(candidates, channel)
+	if !ok {
+		advice.Error = fmt.Sprintf("no %s release found for %s", channel, component.Name)
+		return advice
+	}
+	advice.Latest = latest.Tag
+	advice.Changelog = changelog(component, notes, version)
+
+	if pinned != "" && installed != "" && !sameVersion(pinned, installed) {
+		advice.Warnings = append(advice.Warnings, fmt.Sprintf("config pins %s but the cluster runs %s", pinned, installed))
+	}
+	if installed == "" {
+		return advice
+	}
+	current, err := semver.ParseTolerant(installed)
+	if err != nil {
+		advice.Error = fmt.Sprintf("installed version %q is not a semantic version", installed)
+		return advice
+	}
+	// Never propose a downgrade when the cluster runs a newer build than its channel
+	if current.GTE(version) {
+		return advice
+	}
+	advice.UpgradeAvailable = true
+	advice.Warnings = append(advice.Warnings, warnings(component, notes, current, version, channel)...)
+	return advice
+}
+
+// newest returns the newest release of the channel, ignoring tags that are not semantic versions
+func newest(releases []Release, channel string) (Release, semver.Version, bool) {
+	published := make([]channels.Release, 0, len(releases))
+	for _, release := range releases {
+		published = append(published, channels.Release{Tag: release.Tag, Prerelease: release.Prerelease})
+	}
+	version, ok := channels.Latest(published, channel)
+	if !ok {
+		return Release{}, semver.Version{}, false
+	}
+	for _, release := range releases {
+		if parsed, err := semver.ParseTolerant(release.Tag); err == nil && parsed.EQ(version) {
+			return release, version, true
+		}
+	}
+	return Release{}, semver.Version{}, false
+}
+
+// warnings lists what to look out for when upgrading from current to target
+func warnings(component Component, notes []Release, current, target semver.Version, channel string) []string {
+	var warnings []string
+	switch {
+	case target.Major > current.Major:
+		warnings = append(warnings, fmt.Sprintf("major upgrade from %s to %s, follow %s", current, target, component.UpgradeGuide))
+	case component.MaxMinorSkip > 0 && target.Minor-current.Minor > component.MaxMinorSkip:
+		warnings = append(warnings, fmt.Sprintf("crosses %d minor versions while %s supports %d per upgrade, step through them: %s",
+			target.Minor-current.Minor, component.Name, component.MaxMinorSkip, component.UpgradeGuide))
+	}
+	for _, release := range notes {
+		if !release.Breaking || (release.Prerelease && channel != channels.Edge) {
+			continue
+		}
+		version, err := semver.ParseTolerant(release.Tag)
+		if err != nil || version.LTE(current) || version.GT(target) {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s release notes mention breaking changes: %s", release.Tag, release.URL))
+	}
+	return warnings
+}
+
+// changelog links the release notes of version, published on GitHub
+func changelog(component Component, notes []Release, version semver.Version) string {
+	for _, release := range notes {
+		if parsed, err := semver.ParseTolerant(release.Tag); err == nil && parsed.EQ(version) && release.URL != "" {
+			return release.URL
+		}
+	}
+	return fmt.Sprintf("https://github.com/%s/releases", component.Repository)
+}
+
+// sameVersion compares two versions, ignoring a leading v
+func sameVersion(a, b string) bool {
+	va, errA := semver.ParseTolerant(a)
+	vb, errB := semver.ParseTolerant(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return va.EQ(vb)
+}
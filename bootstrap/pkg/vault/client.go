@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client calls the Vault HTTP API with a token
+type Client struct {
+	address string
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for the Vault at address, tlsConfig may be nil to use the system roots
+func NewClient(address, token string, tlsConfig *tls.Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &Client{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}
+}
+
+// Address returns the Vault address the client talks to
+func (c *Client) Address() string {
+	return c.address
+}
+
+// StatusError is a non-2xx Vault reply
+type StatusError struct {
+	Code   int
+	Path   string
+	Errors []string
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("vault %s: %d: %s", e.Path, e.Code, strings.Join(e.Errors, "; "))
+	}
+	return fmt.Sprintf("vault %s: %d", e.Path, e.Code)
+}
+
+// WithToken returns a client for the same Vault authenticating with token
+func (c *Client) WithToken(token string) *Client {
+	return &Client{address: c.address, token: token, http: c.http}
+}
+
+// request calls path under /v1, decoding the data field of the reply into out when set
+func (c *Client) request(ctx context.Context, method, path string, body, out interface{}) error {
+	var reply struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := c.call(ctx, method, path, body, &reply); err != nil {
+		return err
+	}
+	if out == nil || len(reply.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(reply.Data, out)
+}
+
+// call sends a request to path under /v1 and decodes the whole reply into out when set
+func (c *Client) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault %s not reachable: %w", c.address, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var reply struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&reply)
+		return &StatusError{Code: resp.StatusCode, Path: path, Errors: reply.Errors}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("vault %s: invalid reply: %w", path, err)
+	}
+	return nil
+}
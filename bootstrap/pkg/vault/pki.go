@@ -0,0 +1,166 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PKIRole is a role certificates are issued or signed with
+type PKIRole struct {
+	Name            string
+	AllowedDomains  []string
+	AllowSubdomains bool
+	MaxTTL          string
+}
+
+// MountExists reports whether a secrets engine is mounted at path
+func (c *Client) MountExists(ctx context.Context, path string) (bool, error) {
+	var mounts map[string]interface{}
+	if err := c.request(ctx, http.MethodGet, "sys/mounts", nil, &mounts); err != nil {
+		return false, fmt.Errorf("failed to list secrets engines: %w", err)
+	}
+	_, ok := mounts[strings.Trim(path, "/")+"/"]
+	return ok, nil
+}
+
+// EnsurePKIMount mounts a PKI secrets engine at path when none is, allowing certificates up to maxTTL
+func (c *Client) EnsurePKIMount(ctx context.Context, path, maxTTL string) error {
+	exists, err := c.MountExists(ctx, path)
+	if err != nil || exists {
+		return err
+	}
+	body := map[string]interface{}{
+		"type":   "pki",
+		"config": map[string]interface{}{"max_lease_ttl": maxTTL},
+	}
+	if err := c.request(ctx, http.MethodPost, "sys/mounts/"+path, body, nil); err != nil {
+		return fmt.Errorf("failed to mount PKI at %s: %w", path, err)
+	}
+	return nil
+}
+
+// IssuingCA returns the PEM CA certificate of the PKI mount, empty when it has none yet
+func (c *Client) IssuingCA(ctx context.Context, mount string) (string, error) {
+	var data struct {
+		Certificate string `json:"certificate"`
+	}
+	err := c.request(ctx, http.MethodGet, mount+"/cert/ca", nil, &data)
+	var status *StatusError
+	if errors.As(err, &status) && (status.Code == http.StatusNotFound || status.Code == http.StatusBadRequest) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the CA of %s: %w", mount, err)
+	}
+	return strings.TrimSpace(data.Certificate), nil
+}
+
+// GenerateIntermediateCSR creates an intermediate key inside Vault and returns its PEM signing request
+func (c *Client) GenerateIntermediateCSR(ctx context.Context, mount, commonName, ttl string) (string, error) {
+	var data struct {
+		CSR string `json:"csr"`
+	}
+	body := map[string]interface{}{"common_name": commonName, "ttl": ttl}
+	if err := c.request(ctx, http.MethodPost, mount+"/intermediate/generate/internal", body, &data); err != nil {
+		return "", fmt.Errorf("failed to generate the intermediate CSR: %w", err)
+	}
+	if data.CSR == "" {
+		return "", fmt.Errorf("vault returned no CSR for %s", mount)
+	}
+	return data.CSR, nil
+}
+
+// SignIntermediate signs csr with the root CA of mount, returning the certificate followed by its issuer
+func (c *Client) SignIntermediate(ctx context.Context, mount, csr, commonName, ttl string) (string, error) {
+	var data struct {
+		Certificate string `json:"certificate"`
+		IssuingCA   string `json:"issuing_ca"`
+	}
+	body := map[string]interface{}{"csr": csr, "common_name": commonName, "ttl": ttl, "format": "pem"}
+	if err := c.request(ctx, http.MethodPost, mount+"/root/sign-intermediate", body, &data); err != nil {
+		return "", fmt.Errorf("failed to sign the intermediate CA: %w", err)
+	}
+	return strings.TrimSpace(data.Certificate) + "\n" + strings.TrimSpace(data.IssuingCA) + "\n", nil
+}
+
+// SetSignedIntermediate imports the signed intermediate chain into mount and makes it the default issuer,
+// so a re-signed intermediate takes over from the one it replaces
+func (c *Client) SetSignedIntermediate(ctx context.Context, mount, chain string) error {
+	var data struct {
+		ImportedIssuers []string `json:"imported_issuers"`
+	}
+	if err := c.request(ctx, http.MethodPost, mount+"/intermediate/set-signed", map[string]interface{}{"certificate": chain}, &data); err != nil {
+		return fmt.Errorf("failed to import the signed intermediate CA: %w", err)
+	}
+	if len(data.ImportedIssuers) == 0 {
+		return nil
+	}
+	if err := c.request(ctx, http.MethodPost, mount+"/config/issuers", map[string]interface{}{"default": data.ImportedIssuers[0]}, nil); err != nil {
+		return fmt.Errorf("failed to make the intermediate CA the default issuer: %w", err)
+	}
+	return nil
+}
+
+// ConfigureURLs publishes the issuing certificate and CRL URLs of mount under base
+func (c *Client) ConfigureURLs(ctx context.Context, mount, base string) error {
+	base = strings.TrimRight(base, "/") + "/v1/" + mount
+	body := map[string]interface{}{
+		"issuing_certificates":    []string{base + "/ca"},
+		"crl_distribution_points": []string{base + "/crl"},
+	}
+	if err := c.request(ctx, http.MethodPost, mount+"/config/urls", body, nil); err != nil {
+		return fmt.Errorf("failed to configure the URLs of %s: %w", mount, err)
+	}
+	return nil
+}
+
+// WritePKIRole creates or updates role on mount
+func (c *Client) WritePKIRole(ctx context.Context, mount string, role PKIRole) error {
+	body := map[string]interface{}{
+		"allowed_domains":    role.AllowedDomains,
+		"allow_subdomains":   role.AllowSubdomains,
+		"allow_bare_domains": true,
+		"max_ttl":            role.MaxTTL,
+		"key_type":           "ec",
+		"key_bits":           256,
+	}
+	if err := c.request(ctx, http.MethodPost, mount+"/roles/"+role.Name, body, nil); err != nil {
+		return fmt.Errorf("failed to write PKI role %s: %w", role.Name, err)
+	}
+	return nil
+}
+
+// WritePolicy creates or updates an ACL policy
+func (c *Client) WritePolicy(ctx context.Context, name, policy string) error {
+	if err := c.request(ctx, http.MethodPut, "sys/policies/acl/"+name, map[string]interface{}{"policy": policy}, nil); err != nil {
+		return fmt.Errorf("failed to write policy %s: %w", name, err)
+	}
+	return nil
+}
+
+// AuthEnabled reports whether an auth method is enabled at path
+func (c *Client) AuthEnabled(ctx context.Context, path string) (bool, error) {
+	var methods map[string]interface{}
+	if err := c.request(ctx, http.MethodGet, "sys/auth", nil, &methods); err != nil {
+		return false, fmt.Errorf("failed to list auth methods: %w", err)
+	}
+	_, ok := methods[strings.Trim(path, "/")+"/"]
+	return ok, nil
+}
+
+// WriteKubernetesRole binds a service account to policies through the kubernetes auth method at authPath
+func (c *Client) WriteKubernetesRole(ctx context.Context, authPath, name, namespace, serviceAccount string, policies []string) error {
+	body := map[string]interface{}{
+		"bound_service_account_names":      []string{serviceAccount},
+		"bound_service_account_namespaces": []string{namespace},
+		"policies":                         policies,
+		"ttl":                              "1h",
+	}
+	if err := c.request(ctx, http.MethodPost, "auth/"+authPath+"/role/"+name, body, nil); err != nil {
+		return fmt.Errorf("failed to write kubernetes auth role %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+// TokenRequest describes a token to create
+type TokenRequest struct {
+	Policies    []string `json:"policies"`
+	DisplayName string   `json:"display_name,omitempty"`
+	Period      string   `json:"period,omitempty"`
+	Renewable   bool     `json:"renewable"`
+}
+
+// Token is a Vault token and the accessor revoking or looking it up without the secret
+type Token struct {
+	Token    string
+	Accessor string
+	Policies []string
+}
+
+// SealStatus is the reply of sys/seal-status
+type SealStatus struct {
+	Type        string `json:"type"`
+	Initialized bool   `json:"initialized"`
+	Sealed      bool   `json:"sealed"`
+}
+
+// CreateToken creates a child token of the client token
+func (c *Client) CreateToken(ctx context.Context, req TokenRequest) (*Token, error) {
+	var reply struct {
+		Auth struct {
+			ClientToken string   `json:"client_token"`
+			Accessor    string   `json:"accessor"`
+			Policies    []string `json:"policies"`
+		} `json:"auth"`
+	}
+	if err := c.call(ctx, http.MethodPost, "auth/token/create", req, &reply); err != nil {
+		return nil, fmt.Errorf("failed to create token: %w", err)
+	}
+	if reply.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("vault returned no token")
+	}
+	return &Token{Token: reply.Auth.ClientToken, Accessor: reply.Auth.Accessor, Policies: reply.Auth.Policies}, nil
+}
+
+// LookupToken returns the accessor and policies of token
+func (c *Client) LookupToken(ctx context.Context, token string) (*Token, error) {
+	var data struct {
+		Accessor string   `json:"accessor"`
+		Policies []string `json:"policies"`
+	}
+	if err := c.request(ctx, http.MethodPost, "auth/token/lookup", map[string]string{"token": token}, &data); err != nil {
+		return nil, fmt.Errorf("failed to look the token up: %w", err)
+	}
+	return &Token{Token: token, Accessor: data.Accessor, Policies: data.Policies}, nil
+}
+
+// RevokeToken revokes token and its children
+func (c *Client) RevokeToken(ctx context.Context, token string) error {
+	if err := c.request(ctx, http.MethodPost, "auth/token/revoke", map[string]string{"token": token}, nil); err != nil {
+		return fmt.Errorf("failed to revoke the token: %w", err)
+	}
+	return nil
+}
+
+// TransitEncrypt encrypts plaintext with key of the transit engine at mount, proving the token may use it
+func (c *Client) TransitEncrypt(ctx context.Context, mount, key string, plaintext []byte) error {
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := c.request(ctx, http.MethodPost, mount+"/encrypt/"+key, body, nil); err != nil {
+		return fmt.Errorf("transit encrypt with %s/%s failed: %w", mount, key, err)
+	}
+	return nil
+}
+
+// SealStatus reports whether the Vault is initialized and unsealed, no token is needed
+func (c *Client) SealStatus(ctx context.Context) (*SealStatus, error) {
+	var status SealStatus
+	if err := c.call(ctx, http.MethodGet, "sys/seal-status", nil, &status); err != nil {
+		return nil, fmt.Errorf("failed to read the seal status: %w", err)
+	}
+	return &status, nil
+}
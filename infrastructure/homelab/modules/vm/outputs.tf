@@ -11,4 +11,9 @@ output "name" {
 output "ip_address" {
   description = "VM IP address"
   value       = try(proxmox_virtual_environment_vm.vm.ipv4_addresses[0][0], "")
+}
+
+output "mac_address" {
+  description = "MAC address of the first network interface, generated by Proxmox when not set"
+  value       = try(proxmox_virtual_environment_vm.vm.network_device[0].mac_address, "")
 }
\ No newline at end of file
@@ -12,10 +12,11 @@ output "vm_info" {
     for k, v in local.all_nodes : k => {
       id          = module.vms[k].vmid
       name        = module.vms[k].name
+      role        = v.machine_type
       cores       = v.cores
       memory      = v.memory
       ip          = v.ip
-      mac_address = v.mac_address
+      mac_address = module.vms[k].mac_address
       disk        = v.os_disk_size
       data_disk   = try(v.data_disk_size, null)
     }